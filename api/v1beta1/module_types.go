@@ -49,8 +49,20 @@ type Build struct {
 	// BuildArgs is an array of build variables that are provided to the image building backend.
 	BuildArgs []BuildArg `json:"buildArgs"`
 
-	// ConfigMap that holds Dockerfile contents
-	DockerfileConfigMap *v1.LocalObjectReference `json:"dockerfileConfigMap"`
+	// +optional
+	// ConfigMap that holds Dockerfile contents.
+	// If neither dockerfileConfigMap nor dockerfile is set, the Dockerfile is read from the Git repository.
+	DockerfileConfigMap *v1.LocalObjectReference `json:"dockerfileConfigMap,omitempty"`
+
+	// +optional
+	// Dockerfile contents, as an alternative to dockerfileConfigMap.
+	Dockerfile string `json:"dockerfile,omitempty"`
+
+	// +optional
+	// Git is the repository holding the build context.
+	// The Dockerfile is read from the context directory of the repository, unless dockerfileConfigMap or dockerfile is
+	// set.
+	Git *GitBuildSource `json:"git,omitempty"`
 
 	// +optional
 	// BaseImageRegistryTLS contains settings determining how to access registries of the base images in the build-process' Dockerfile.
@@ -65,6 +77,31 @@ type Build struct {
 	// +optional
 	// KanikoParams is used to customize the building process of the image.
 	KanikoParams *KanikoParams `json:"kanikoParams,omitempty"`
+
+	// +optional
+	// RetryPolicy retries failed builds. Failed builds are not retried if it is not set.
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+}
+
+// GitBuildSource is a Git repository used as the build context.
+type GitBuildSource struct {
+	// URI of the repository.
+	URI string `json:"uri"`
+
+	// +optional
+	// Ref is the branch, tag or commit to build. Defaults to the default branch of the repository.
+	// Branches and tags are resolved to a commit before building, so that pushing a new commit to a branch triggers
+	// a new build. Only commits can be used with repositories that are not served over HTTP(S).
+	Ref string `json:"ref,omitempty"`
+
+	// +optional
+	// ContextDir is the directory of the repository used as the build context. Defaults to the root of the
+	// repository.
+	ContextDir string `json:"contextDir,omitempty"`
+
+	// +optional
+	// SourceSecret is a kubernetes.io/basic-auth Secret holding the credentials used to access the repository.
+	SourceSecret *v1.LocalObjectReference `json:"sourceSecret,omitempty"`
 }
 
 type Sign struct {
@@ -85,10 +122,40 @@ type Sign struct {
 	// +optional
 	// paths inside the image for the kernel modules to sign (if ommited all kmods are signed)
 	FilesToSign []string `json:"filesToSign,omitempty"`
+
+	// +optional
+	// RetryPolicy retries failed signing Jobs. Failed signing Jobs are not retried if it is not set.
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+}
+
+// RetryPolicy defines how failed builds or signing Jobs are attempted again.
+// A new attempt replaces the failed one once the backoff has elapsed; the attempts are counted again from 1 when the
+// build or sign settings change.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	// +kubebuilder:validation:Minimum=1
+	MaxAttempts int32 `json:"maxAttempts"`
+
+	// +optional
+	// InitialBackoff is the delay between the failure of the first attempt and the creation of the second one.
+	// It doubles after each failed attempt. Defaults to 30s.
+	InitialBackoff *metav1.Duration `json:"initialBackoff,omitempty"`
+
+	// +optional
+	// MaxBackoff is the maximum delay between the failure of an attempt and the creation of the next one.
+	// Defaults to 10m.
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
+
+	// +optional
+	// RetryOn are the reasons of the failures that are retried, as reported in the status of the Module.
+	// All failures are retried if it is empty.
+	RetryOn []StageFailureReason `json:"retryOn,omitempty"`
 }
 
 // KernelMapping pairs kernel versions with a DriverContainer image.
-// Kernel versions can be matched literally or using a regular expression.
+// Kernel versions can be matched literally, using a regular expression or using a structured matcher.
+// When several mappings match a kernel, the most specific one is used: literal mappings first, then matchers with
+// the most constraints and finally regular expressions. Ties are broken by the order of the mappings.
 type KernelMapping struct {
 
 	// +optional
@@ -113,6 +180,41 @@ type KernelMapping struct {
 	// +optional
 	// Regexp is a regular expression to be match against node kernels.
 	Regexp string `json:"regexp"`
+
+	// +optional
+	// Matcher matches node kernels against a structured description of the kernel version.
+	Matcher *KernelVersionMatcher `json:"matcher,omitempty"`
+}
+
+// KernelVersionMatcher describes a set of kernels by their components.
+// A kernel such as 5.14.0-284.11.1.rt14.296.el9_2.x86_64 is made of an upstream version (5.14.0), a release
+// (284.11.1), an optional flavour (rt), a distribution (el9_2) and an architecture (x86_64).
+// All fields are optional, but at least one must be set; a kernel matches if it satisfies all of them.
+type KernelVersionMatcher struct {
+	// +optional
+	// MinVersion is the lowest matched kernel version, such as 5.14.0-284.
+	// It is inclusive and only compared up to its own precision.
+	MinVersion string `json:"minVersion,omitempty"`
+
+	// +optional
+	// MaxVersion is the highest matched kernel version, such as 5.14.
+	// It is inclusive and only compared up to its own precision: 5.14 matches all 5.14 kernels.
+	MaxVersion string `json:"maxVersion,omitempty"`
+
+	// +optional
+	// Distro is the distribution suffix of the kernel, such as el9 or el8_6.
+	// el9 matches all el9 minor releases, like el9_2.
+	Distro string `json:"distro,omitempty"`
+
+	// +optional
+	// Arch is the architecture of the kernel, such as x86_64 or aarch64.
+	// It is matched against the architecture of the nodes, or against the suffix of the kernel version if the
+	// architecture of the nodes is not known.
+	Arch string `json:"arch,omitempty"`
+
+	// +optional
+	// Flavour is the kernel flavour, such as rt or 64k.
+	Flavour string `json:"flavour,omitempty"`
 }
 
 type ModprobeArgs struct {
@@ -125,6 +227,35 @@ type ModprobeArgs struct {
 	Unload []string `json:"unload,omitempty"`
 }
 
+// ModprobeOptions are the parameters with which modprobe loads a kernel module.
+type ModprobeOptions struct {
+	// ModuleName is the name of the kernel module.
+	ModuleName string `json:"moduleName"`
+
+	// Parameters are the kernel module parameters, in the form of key=value.
+	// Their values can use the same variables as the container image.
+	// +kubebuilder:validation:MinItems=1
+	Parameters []string `json:"parameters"`
+}
+
+// InTreeModuleConflictPolicy defines what happens when an in-tree kernel module conflicting with the kernel module
+// to load is already loaded.
+// +kubebuilder:validation:Enum=Fail;Replace;Skip
+type InTreeModuleConflictPolicy string
+
+const (
+	// InTreeModuleConflictPolicyFail makes the module loader fail without loading the kernel module.
+	InTreeModuleConflictPolicyFail InTreeModuleConflictPolicy = "Fail"
+
+	// InTreeModuleConflictPolicyReplace makes the module loader unload the conflicting in-tree modules before loading
+	// the kernel module, and load them again after unloading it.
+	InTreeModuleConflictPolicyReplace InTreeModuleConflictPolicy = "Replace"
+
+	// InTreeModuleConflictPolicySkip makes the module loader leave the in-tree modules in place and not load the
+	// kernel module.
+	InTreeModuleConflictPolicySkip InTreeModuleConflictPolicy = "Skip"
+)
+
 type ModprobeSpec struct {
 	// ModuleName is the name of the Module to be loaded.
 	ModuleName string `json:"moduleName"`
@@ -145,7 +276,7 @@ type ModprobeSpec struct {
 	Args *ModprobeArgs `json:"args,omitempty"`
 
 	// If RawArgs are specified, they are passed straight to the modprobe binary; all other properties in this
-	// object are ignored.
+	// object are ignored, except ModuleName which is still used to check that the kernel module is loaded.
 	// The resulting commands will be: `modprobe ${RawArgs}`.
 	// +optional
 	RawArgs *ModprobeArgs `json:"rawArgs,omitempty"`
@@ -154,6 +285,35 @@ type ModprobeSpec struct {
 	// The firmware(s) will be copied to the host for the kernel to find them.
 	// +optional
 	FirmwarePath string `json:"firmwarePath,omitempty"`
+
+	// InTreeModulesToRemove is a list of in-tree kernel modules that conflict with ModuleName.
+	// An in-tree module named ModuleName always conflicts with it.
+	// +optional
+	InTreeModulesToRemove []string `json:"inTreeModulesToRemove,omitempty"`
+
+	// InTreeModuleConflictPolicy defines what happens when a conflicting in-tree module is loaded before ModuleName:
+	// Fail (the default) does not load ModuleName, Replace unloads the conflicting modules before loading ModuleName
+	// and loads them again after unloading it, and Skip leaves the conflicting modules in place without loading
+	// ModuleName.
+	// +optional
+	InTreeModuleConflictPolicy InTreeModuleConflictPolicy `json:"inTreeModuleConflictPolicy,omitempty"`
+
+	// ModulesLoadingOrder lists kernel modules that depend on each other without depmod knowing about it, for example
+	// independent kernel modules that must be loaded in a specific order.
+	// The first item must be ModuleName, and each item depends on the next one: for [a, b, c], c is loaded first and
+	// a last. The kernel modules are unloaded in the order of the list.
+	// +optional
+	ModulesLoadingOrder []string `json:"modulesLoadingOrder,omitempty"`
+
+	// Options are the parameters of kernel modules loaded along with ModuleName, such as its dependencies.
+	// Parameters of ModuleName itself should be set in Parameters.
+	// +optional
+	Options []ModprobeOptions `json:"options,omitempty"`
+
+	// Blacklist lists kernel modules whose aliases modprobe ignores, so that they are not loaded instead of, or along
+	// with, ModuleName.
+	// +optional
+	Blacklist []string `json:"blacklist,omitempty"`
 }
 
 type ModuleLoaderContainerSpec struct {
@@ -189,6 +349,45 @@ type ModuleLoaderContainerSpec struct {
 	// +optional
 	// RegistryTLS set the TLS configs for accessing the registry of the module-loader's image.
 	RegistryTLS TLSOptions `json:"registryTLS"`
+
+	// Compute Resources required by the module loader container.
+	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/
+	// +optional
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
+
+	// VolumeMounts is a list of volume mounts that are appended to the default ones.
+	// +optional
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// ImageDigestResolution describes when the operator resolves the tag of the module loader image to a digest.
+	// The module loader pods always run the image by digest, so that all nodes run the same build of the kernel module
+	// even if the tag is pushed again.
+	// +optional
+	ImageDigestResolution *ImageDigestResolution `json:"imageDigestResolution,omitempty"`
+}
+
+// ImageDigestPolicy describes when the tag of the module loader image is resolved to a digest.
+// +kubebuilder:validation:Enum=OnSpecChange;Periodic
+type ImageDigestPolicy string
+
+const (
+	// ImageDigestPolicyOnSpecChange resolves the tag when it is first used for a kernel version, and again every
+	// time the spec of the Module changes.
+	ImageDigestPolicyOnSpecChange ImageDigestPolicy = "OnSpecChange"
+	// ImageDigestPolicyPeriodic additionally resolves the tag again once the digest is older than the interval.
+	ImageDigestPolicyPeriodic ImageDigestPolicy = "Periodic"
+)
+
+type ImageDigestResolution struct {
+	// Policy describes when the tag is resolved again.
+	// +kubebuilder:default=OnSpecChange
+	// +optional
+	Policy ImageDigestPolicy `json:"policy,omitempty"`
+
+	// Interval is how often the tag is resolved again. Defaults to 1 hour.
+	// Only used by the Periodic policy.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
 }
 
 type ModuleLoaderSpec struct {
@@ -199,6 +398,29 @@ type ModuleLoaderSpec struct {
 	// ServiceAccountName is the name of the ServiceAccount to use to run this pod.
 	// More info: https://kubernetes.io/docs/tasks/configure-pod-container/configure-service-account/
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// +optional
+	// Labels are additional labels set on the module loader DaemonSets and their pods.
+	// Their values can use the same variables as the container image.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// +optional
+	// Annotations are additional annotations set on the module loader pods.
+	// Their values can use the same variables as the container image.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// +optional
+	// Tolerations are set on the module loader and device plugin pods.
+	// Nodes with a NoSchedule taint are only targeted by the Module if the taint is tolerated.
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+
+	// +optional
+	// Affinity is the scheduling constraints of the module loader pods, in addition to the Module's selector.
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+
+	// +optional
+	// Volumes is a list of volumes that are appended to the default ones of the module loader pods.
+	Volumes []v1.Volume `json:"volumes,omitempty"`
 }
 
 type DevicePluginContainerSpec struct {
@@ -264,6 +486,77 @@ type DevicePluginSpec struct {
 	Volumes []v1.Volume `json:"volumes,omitempty"`
 }
 
+// UpgradeStrategyType is the way module loader pods are replaced when the module loader changes.
+// +kubebuilder:validation:Enum=RollingUpdate;NodeByNode
+type UpgradeStrategyType string
+
+const (
+	// UpgradeStrategyRollingUpdate lets the DaemonSet controller replace the module loader pods.
+	UpgradeStrategyRollingUpdate UpgradeStrategyType = "RollingUpdate"
+	// UpgradeStrategyNodeByNode makes the operator replace the module loader pods in batches of nodes, waiting for
+	// the module to be ready on a batch before moving on to the next one.
+	UpgradeStrategyNodeByNode UpgradeStrategyType = "NodeByNode"
+)
+
+type UpgradeStrategy struct {
+	// Type is the upgrade strategy.
+	// +kubebuilder:default=RollingUpdate
+	// +optional
+	Type UpgradeStrategyType `json:"type,omitempty"`
+
+	// MaxParallelNodes is the number of nodes that are upgraded at the same time.
+	// Only used by the NodeByNode strategy.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxParallelNodes int32 `json:"maxParallelNodes,omitempty"`
+
+	// Drain makes the operator evict all pods that are not managed by a DaemonSet from a cordoned node before
+	// replacing its module loader pod.
+	// Only used by the NodeByNode strategy.
+	// +optional
+	Drain bool `json:"drain,omitempty"`
+
+	// NodeReadyTimeout is how long the operator waits for the module to be ready on a node before it considers the
+	// upgrade of that node as failed. Defaults to 10 minutes.
+	// Only used by the NodeByNode strategy.
+	// +optional
+	NodeReadyTimeout *metav1.Duration `json:"nodeReadyTimeout,omitempty"`
+
+	// Paused stops the operator from starting the upgrade of new nodes.
+	// Nodes that are already being upgraded are not affected.
+	// Only used by the NodeByNode strategy.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// RollbackOnFailure makes the operator go back to the previous container image if a node fails to become ready
+	// within NodeReadyTimeout.
+	// Only used by the NodeByNode strategy.
+	// +optional
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
+}
+
+// LoadMode is the way the kernel module is loaded on the nodes.
+// +kubebuilder:validation:Enum=DaemonSet;Job
+type LoadMode string
+
+const (
+	// LoadModeDaemonSet runs a module loader pod on each node for as long as the kernel module should be loaded.
+	// The kernel module is unloaded when the pod is terminated.
+	LoadModeDaemonSet LoadMode = "DaemonSet"
+	// LoadModeJob loads the kernel module with a one-shot Job on each node and records the loaded state on the node.
+	// The kernel module is unloaded by another Job when it is no longer needed on the node.
+	LoadModeJob LoadMode = "Job"
+)
+
+// BootLoadingSpec describes how the kernel module is loaded when the nodes boot, before kubelet starts.
+type BootLoadingSpec struct {
+	// MachineConfigPool is the name of the MachineConfigPool whose nodes load the kernel module at boot.
+	// The MachineConfig rendered by the KMM Operator applies to all nodes of that pool; the Module's selector should
+	// only select nodes of that pool.
+	MachineConfigPool string `json:"machineConfigPool"`
+}
+
 // ModuleSpec describes how the KMM operator should deploy a Module on those nodes that need it.
 type ModuleSpec struct {
 	// DevicePlugin allows overriding some properties of the container that deploys the device plugin on the node.
@@ -282,6 +575,38 @@ type ModuleSpec struct {
 
 	// Selector describes on which nodes the Module should be loaded and optionally built.
 	Selector map[string]string `json:"selector"`
+
+	// UpgradeStrategy describes how the module loader pods are replaced when the module loader changes.
+	// +optional
+	UpgradeStrategy *UpgradeStrategy `json:"upgradeStrategy,omitempty"`
+
+	// DependsOn is a list of Modules in the same namespace that must be loaded on a node before this Module.
+	// The module loader of this Module is only scheduled on nodes where all those Modules are ready.
+	// A Module that other Modules depend on is only unloaded from a node after its dependents.
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// LoadMode describes how the kernel module is loaded on the nodes.
+	// It cannot be changed once the Module is created.
+	// +kubebuilder:default=DaemonSet
+	// +optional
+	LoadMode LoadMode `json:"loadMode,omitempty"`
+
+	// BootLoading, if set, makes the nodes load the kernel module at boot, before kubelet starts, with a systemd unit
+	// rendered into a MachineConfig.
+	// The module loader DaemonSet still loads the kernel module on nodes that did not reboot yet, but does not unload
+	// it anymore: the kernel module stays loaded until the MachineConfig is removed and the node reboots, or until
+	// the Module is deleted.
+	// Only supported with the DaemonSet load mode.
+	// +optional
+	BootLoading *BootLoadingSpec `json:"bootLoading,omitempty"`
+
+	// BuildPriority orders the builds and sign Jobs of this Module in the queue of the KMM Operator, when the maximum
+	// number of concurrent builds is reached.
+	// Higher priorities start first; builds of the same priority start in the order in which they were queued.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BuildPriority int32 `json:"buildPriority,omitempty"`
 }
 
 // DaemonSetStatus contains the status for a daemonset deployed during
@@ -295,6 +620,170 @@ type DaemonSetStatus struct {
 	AvailableNumber int32 `json:"availableNumber,omitempty"`
 }
 
+const (
+	// ModuleConditionReady is True when the module is loaded on all the nodes that have a kernel mapping.
+	ModuleConditionReady string = "Ready"
+	// ModuleConditionProgressing is True while builds, signing jobs or module loader pods are still running.
+	ModuleConditionProgressing string = "Progressing"
+	// ModuleConditionDegraded is True when the module cannot be loaded on at least one kernel version.
+	ModuleConditionDegraded string = "Degraded"
+	// ModuleConditionBuildFailed is True when the build failed for at least one kernel version.
+	ModuleConditionBuildFailed string = "BuildFailed"
+	// ModuleConditionSignFailed is True when signing failed for at least one kernel version.
+	ModuleConditionSignFailed string = "SignFailed"
+)
+
+// StagePhase is the phase of a build or sign stage for a kernel version.
+// +kubebuilder:validation:Enum=NotRequired;Queued;InProgress;Completed;Failed;Retrying
+type StagePhase string
+
+const (
+	StagePhaseNotRequired StagePhase = "NotRequired"
+	// StagePhaseQueued means that the Build or the Job waits for the number of concurrent builds to fall below the
+	// limit of the KMM Operator.
+	StagePhaseQueued     StagePhase = "Queued"
+	StagePhaseInProgress StagePhase = "InProgress"
+	StagePhaseCompleted  StagePhase = "Completed"
+	StagePhaseFailed     StagePhase = "Failed"
+	// StagePhaseRetrying means that the last attempt failed and that another one will be made once its backoff
+	// has elapsed.
+	StagePhaseRetrying StagePhase = "Retrying"
+)
+
+// KernelVersionStatus contains the status of the module for a kernel version
+// running on at least one of the targeted nodes.
+type KernelVersionStatus struct {
+	// KernelVersion is the kernel version this status applies to.
+	KernelVersion string `json:"kernelVersion"`
+	// Arch is the architecture of the nodes running this kernel version, if known.
+	// Nodes running the same kernel version on different architectures have separate statuses.
+	// +kubebuilder:default=""
+	// +optional
+	Arch string `json:"arch,omitempty"`
+	// ContainerImage is the module loader image resolved for this kernel version.
+	// +optional
+	ContainerImage string `json:"containerImage,omitempty"`
+	// BuildPhase is the phase of the in-cluster build for this kernel version.
+	// +optional
+	BuildPhase StagePhase `json:"buildPhase,omitempty"`
+	// SignPhase is the phase of the in-cluster signing for this kernel version.
+	// +optional
+	SignPhase StagePhase `json:"signPhase,omitempty"`
+	// QueuePosition is the position, starting from 1, of the build or signing in the queue of the KMM Operator.
+	// Only set while BuildPhase or SignPhase is Queued.
+	// +optional
+	QueuePosition int32 `json:"queuePosition,omitempty"`
+	// DaemonSetName is the name of the module loader DaemonSet for this kernel version.
+	// +optional
+	DaemonSetName string `json:"daemonSetName,omitempty"`
+	// Message is a human-readable description of the state of this kernel version.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Upgrade contains the progress of the node-by-node upgrade of the module loader DaemonSet.
+	// +optional
+	Upgrade *ModuleUpgradeStatus `json:"upgrade,omitempty"`
+	// FailedNodes are the nodes on which the load or unload Job failed.
+	// Only used by the Job load mode.
+	// +optional
+	FailedNodes []string `json:"failedNodes,omitempty"`
+	// ImageDigest is the digest to which ContainerImage was resolved.
+	// The module loader runs the image by digest.
+	// +optional
+	ImageDigest *ImageDigestStatus `json:"imageDigest,omitempty"`
+	// Failure describes why the build or signing failed for this kernel version.
+	// +optional
+	Failure *StageFailure `json:"failure,omitempty"`
+}
+
+// Stage is an in-cluster stage producing the module loader image.
+// +kubebuilder:validation:Enum=Build;Sign
+type Stage string
+
+const (
+	StageBuild Stage = "Build"
+	StageSign  Stage = "Sign"
+)
+
+// StageFailureReason is the cause of a build or sign failure, as classified from its log.
+// +kubebuilder:validation:Enum=MissingKernelDevel;CompileError;PushDenied;MissingDockerfile;Unknown
+type StageFailureReason string
+
+const (
+	// StageFailureReasonMissingKernelDevel means that the kernel headers or build tree were not found.
+	StageFailureReasonMissingKernelDevel StageFailureReason = "MissingKernelDevel"
+	// StageFailureReasonCompileError means that the compiler or make failed.
+	StageFailureReasonCompileError StageFailureReason = "CompileError"
+	// StageFailureReasonPushDenied means that the registry rejected the image.
+	StageFailureReasonPushDenied StageFailureReason = "PushDenied"
+	// StageFailureReasonMissingDockerfile means that the Dockerfile ConfigMap or its dockerfile key was not found.
+	StageFailureReasonMissingDockerfile StageFailureReason = "MissingDockerfile"
+	// StageFailureReasonUnknown means that the failure could not be classified.
+	StageFailureReasonUnknown StageFailureReason = "Unknown"
+)
+
+// StageFailure describes a failed build or sign stage.
+type StageFailure struct {
+	// Stage is the stage that failed.
+	Stage Stage `json:"stage"`
+	// Reason is the classified cause of the failure.
+	Reason StageFailureReason `json:"reason"`
+	// Message is the line of the log, or the error, that explains the failure.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// PodName is the name of the pod whose log was captured, if any.
+	// +optional
+	PodName string `json:"podName,omitempty"`
+	// LogConfigMap is the name of the ConfigMap, in the namespace of the Module, that keeps the tail of the log
+	// after the pod is deleted.
+	// +optional
+	LogConfigMap string `json:"logConfigMap,omitempty"`
+}
+
+// ImageDigestStatus describes the resolution of the tag of the module loader image to a digest.
+type ImageDigestStatus struct {
+	// Digest is the digest of the image, for example sha256:<hex>.
+	Digest string `json:"digest"`
+	// ResolvedAt is when the tag was resolved.
+	ResolvedAt metav1.Time `json:"resolvedAt"`
+	// ModuleGeneration is the generation of the Module when the tag was resolved.
+	ModuleGeneration int64 `json:"moduleGeneration"`
+}
+
+// UpgradePhase is the phase of a node-by-node upgrade.
+// +kubebuilder:validation:Enum=InProgress;Paused;Completed;Failed;RollingBack;RolledBack
+type UpgradePhase string
+
+const (
+	UpgradePhaseInProgress  UpgradePhase = "InProgress"
+	UpgradePhasePaused      UpgradePhase = "Paused"
+	UpgradePhaseCompleted   UpgradePhase = "Completed"
+	UpgradePhaseFailed      UpgradePhase = "Failed"
+	UpgradePhaseRollingBack UpgradePhase = "RollingBack"
+	UpgradePhaseRolledBack  UpgradePhase = "RolledBack"
+)
+
+// ModuleUpgradeStatus contains the progress of a node-by-node upgrade.
+type ModuleUpgradeStatus struct {
+	// Phase is the phase of the upgrade.
+	Phase UpgradePhase `json:"phase"`
+	// PreviousImage is the container image that was running before the upgrade started.
+	// +optional
+	PreviousImage string `json:"previousImage,omitempty"`
+	// TargetImage is the container image the nodes are being upgraded to.
+	// +optional
+	TargetImage string `json:"targetImage,omitempty"`
+	// UpgradedNodes is the number of nodes running the up-to-date module loader.
+	UpgradedNodes int32 `json:"upgradedNodes"`
+	// TotalNodes is the number of nodes running the module loader.
+	TotalNodes int32 `json:"totalNodes"`
+	// CurrentNodes are the nodes that are being upgraded.
+	// +optional
+	CurrentNodes []string `json:"currentNodes,omitempty"`
+	// FailedNodes are the nodes on which the module did not become ready in time.
+	// +optional
+	FailedNodes []string `json:"failedNodes,omitempty"`
+}
+
 // ModuleStatus defines the observed state of Module.
 type ModuleStatus struct {
 	// DevicePlugin contains the status of the Device Plugin daemonset
@@ -302,6 +791,22 @@ type ModuleStatus struct {
 	DevicePlugin DaemonSetStatus `json:"devicePlugin,omitempty"`
 	// ModuleLoader contains the status of the ModuleLoader daemonset
 	ModuleLoader DaemonSetStatus `json:"moduleLoader"`
+	// Conditions represent the latest available observations of the Module's state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// KernelVersions contains the status of the module for each kernel version
+	// running on the targeted nodes.
+	// +listType=map
+	// +listMapKey=kernelVersion
+	// +listMapKey=arch
+	// +optional
+	KernelVersions []KernelVersionStatus `json:"kernelVersions,omitempty"`
+	// UnloadingNodes are the nodes on which the kernel module has not been confirmed to be unloaded since the Module
+	// was deleted.
+	// +optional
+	UnloadingNodes []string `json:"unloadingNodes,omitempty"`
 }
 
 //+kubebuilder:object:root=true
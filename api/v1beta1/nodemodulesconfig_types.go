@@ -0,0 +1,117 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=Loading;Loaded;Unloading;Unloaded;Failed
+type ModuleLoadState string
+
+const (
+	// ModuleLoadStateLoading means that the kernel module is being loaded on the node.
+	ModuleLoadStateLoading ModuleLoadState = "Loading"
+	// ModuleLoadStateLoaded means that the kernel module is loaded on the node.
+	ModuleLoadStateLoaded ModuleLoadState = "Loaded"
+	// ModuleLoadStateUnloading means that the kernel module is being unloaded from the node.
+	ModuleLoadStateUnloading ModuleLoadState = "Unloading"
+	// ModuleLoadStateUnloaded means that the kernel module is not loaded on the node anymore.
+	ModuleLoadStateUnloaded ModuleLoadState = "Unloaded"
+	// ModuleLoadStateFailed means that the last attempt to load or unload the kernel module failed.
+	ModuleLoadStateFailed ModuleLoadState = "Failed"
+)
+
+// NodeModuleStatus describes a Module on a node.
+type NodeModuleStatus struct {
+	// Namespace is the namespace of the Module.
+	Namespace string `json:"namespace"`
+
+	// Name is the name of the Module.
+	Name string `json:"name"`
+
+	// DesiredKernelVersion is the kernel version for which the kernel module should be loaded.
+	// It is empty if the Module does not target the node anymore.
+	// +optional
+	DesiredKernelVersion string `json:"desiredKernelVersion,omitempty"`
+
+	// DesiredContainerImage is the image containing the kernel module that should be loaded.
+	// +optional
+	DesiredContainerImage string `json:"desiredContainerImage,omitempty"`
+
+	// ActualKernelVersion is the kernel version for which the loaded kernel module was built.
+	// +optional
+	ActualKernelVersion string `json:"actualKernelVersion,omitempty"`
+
+	// ActualContainerImage is the image from which the loaded kernel module was loaded.
+	// +optional
+	ActualContainerImage string `json:"actualContainerImage,omitempty"`
+
+	// ImageDigest is the digest of the image from which the loaded kernel module was loaded, if known.
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// State is the state of the kernel module on the node.
+	State ModuleLoadState `json:"state"`
+
+	// LastTransitionTime is the last time State changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// Error is the error reported by the last attempt to load or unload the kernel module.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// NodeModulesConfigStatus is the state of the kernel modules on a node.
+type NodeModulesConfigStatus struct {
+	// Modules are the Modules targeting the node, or still loaded on it.
+	// +optional
+	// +listType=map
+	// +listMapKey=namespace
+	// +listMapKey=name
+	Modules []NodeModuleStatus `json:"modules,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// NodeModulesConfig describes the kernel modules of a node.
+// It has the name of the node and is written by the operator.
+// +kubebuilder:resource:path=nodemodulesconfigs,scope=Cluster,shortName=nmc
+// +operator-sdk:csv:customresourcedefinitions:displayName="Node Modules Config"
+type NodeModulesConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status NodeModulesConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NodeModulesConfigList is a list of NodeModulesConfig objects.
+type NodeModulesConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// List of NodeModulesConfig. More info:
+	// https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md
+	Items []NodeModulesConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeModulesConfig{}, &NodeModulesConfigList{})
+}
@@ -43,6 +43,12 @@ type PreflightValidationSpec struct {
 	// be pushed to a defined repository
 	// +optional
 	PushBuiltImage bool `json:"pushBuiltImage"`
+
+	// DTKImage is the Driver Toolkit image of KernelVersion.
+	// Its Module.symvers is used to verify the symbol versions of the kernel modules.
+	// If not set, the DTK image known to the operator for KernelVersion is used, if any.
+	// +optional
+	DTKImage string `json:"dtkImage,omitempty"`
 }
 
 type CRStatus struct {
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
@@ -45,6 +46,9 @@ import (
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/buildconfig"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
+	buildjob "github.com/rh-ecosystem-edge/kernel-module-management/internal/build/job"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/ca"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/cluster"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/cmd"
@@ -54,11 +58,13 @@ import (
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/metrics"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
 	signjob "github.com/rh-ecosystem-edge/kernel-module-management/internal/sign/job"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/statusupdater"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/webhook"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -81,9 +87,20 @@ func main() {
 
 	setupLogger := logger.WithName("setup")
 
-	var configFile string
+	var (
+		configFile           string
+		buildCacheRepository string
+	)
+
+	buildGCPolicy := buildconfig.DefaultGCPolicy
+	buildLimits := scheduler.Limits{}
 
 	flag.StringVar(&configFile, "config", "", "The path to the configuration file.")
+	flag.IntVar(&buildGCPolicy.KeepFailed, "build-gc-keep-failed", buildGCPolicy.KeepFailed, "The number of failed Builds to keep for each module and kernel.")
+	flag.DurationVar(&buildGCPolicy.TTL, "build-gc-ttl", buildGCPolicy.TTL, "How long failed Builds are kept; 0 to keep them indefinitely.")
+	flag.IntVar(&buildLimits.MaxConcurrent, "build-max-concurrency", 0, "The maximum number of Builds and sign Jobs running at the same time; 0 for no limit.")
+	flag.IntVar(&buildLimits.MaxConcurrentPerNamespace, "build-max-concurrency-per-namespace", 0, "The maximum number of Builds and sign Jobs running at the same time in a namespace; 0 for no limit.")
+	flag.StringVar(&buildCacheRepository, "build-cache-repository", "", "If not empty, the repository in which the results of builds are cached, so that they are shared between the Modules of a namespace building the same inputs; everyone who can push to it must be trusted.")
 
 	klog.InitFlags(flag.CommandLine)
 
@@ -111,6 +128,11 @@ func main() {
 
 	operatorNamespace := cmd.GetEnvOrFatalError(constants.OperatorNamespaceEnvVar, setupLogger)
 
+	enableWebhooks, err := cmd.GetBoolEnv(constants.EnableWebhooksEnvVar)
+	if err != nil {
+		cmd.FatalError(setupLogger, err, "could not determine if webhooks should be enabled")
+	}
+
 	client := mgr.GetClient()
 
 	filterAPI := filter.New(client, mgr.GetLogger())
@@ -120,6 +142,7 @@ func main() {
 	metricsAPI.Register()
 
 	buildHelperAPI := build.NewHelper()
+	gitResolver := git.NewResolver(client)
 	registryAPI := registry.NewRegistry()
 	jobHelperAPI := utils.NewJobHelper(client)
 	authFactory := auth.NewRegistryAuthGetterFactory(
@@ -128,14 +151,47 @@ func main() {
 			ctrl.GetConfigOrDie(),
 		),
 	)
+	buildCache := cache.New(buildCacheRepository, authFactory, registryAPI)
 
-	buildAPI := buildconfig.NewManager(
-		client,
-		buildconfig.NewMaker(client, buildHelperAPI, scheme, kernelOsDtkMapping),
-		buildconfig.NewOpenShiftBuildsHelper(client),
-		authFactory,
-		registryAPI,
+	ocpBuildsAvailable, err := cmd.IsGroupVersionAvailable(
+		discovery.NewDiscoveryClientForConfigOrDie(ctrl.GetConfigOrDie()),
+		buildv1.GroupVersion.String(),
 	)
+	if err != nil {
+		cmd.FatalError(setupLogger, err, "could not determine if OpenShift builds are available")
+	}
+
+	buildScheduler := scheduler.New(buildLimits, scheduler.NewLister(mgr.GetAPIReader(), ocpBuildsAvailable))
+
+	var buildAPI build.Manager
+
+	if ocpBuildsAvailable {
+		setupLogger.Info("Using OpenShift builds")
+
+		buildAPI = buildconfig.NewManager(
+			client,
+			buildconfig.NewMaker(client, buildHelperAPI, gitResolver, buildCache, scheme, kernelOsDtkMapping),
+			buildconfig.NewOpenShiftBuildsHelper(client),
+			authFactory,
+			registryAPI,
+			gitResolver,
+			buildCache,
+			buildScheduler,
+			buildGCPolicy,
+		)
+	} else {
+		setupLogger.Info("OpenShift builds are not available; using Kaniko build Jobs")
+
+		buildAPI = buildjob.NewBuildManager(
+			buildjob.NewMaker(client, buildHelperAPI, gitResolver, buildCache, scheme, kernelOsDtkMapping),
+			jobHelperAPI,
+			authFactory,
+			registryAPI,
+			gitResolver,
+			buildCache,
+			buildScheduler,
+		)
+	}
 
 	caHelper := ca.NewHelper(client, scheme)
 
@@ -144,6 +200,8 @@ func main() {
 		jobHelperAPI,
 		authFactory,
 		registryAPI,
+		gitResolver,
+		buildScheduler,
 	)
 
 	ctrlLogger := setupLogger.WithValues("name", hub.ManagedClusterModuleReconcilerName)
@@ -152,13 +210,13 @@ func main() {
 	mcmr := hub.NewManagedClusterModuleReconciler(
 		client,
 		manifestwork.NewCreator(client, scheme),
-		cluster.NewClusterAPI(client, module.NewKernelMapper(buildHelperAPI, sign.NewSignerHelper()), buildAPI, signAPI, operatorNamespace),
+		cluster.NewClusterAPI(client, module.NewKernelMapper(buildHelperAPI, sign.NewSignerHelper(), kernelOsDtkMapping), buildAPI, signAPI, operatorNamespace),
 		statusupdater.NewManagedClusterModuleStatusUpdater(client),
 		filterAPI,
 		operatorNamespace,
 	)
 
-	if err = mcmr.SetupWithManager(mgr); err != nil {
+	if err = mcmr.SetupWithManager(mgr, ocpBuildsAvailable); err != nil {
 		cmd.FatalError(ctrlLogger, err, "unable to create controller")
 	}
 
@@ -174,6 +232,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	if enableWebhooks {
+		setupLogger.Info("Enabling webhooks")
+
+		if err = webhook.NewManagedClusterModuleWebhook(buildHelperAPI, sign.NewSignerHelper()).SetupWebhookWithManager(mgr); err != nil {
+			cmd.FatalError(setupLogger, err, "unable to create webhook", "webhook", "ManagedClusterModule")
+		}
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -17,10 +17,9 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
-	"fmt"
 	"os"
-	"strconv"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	imagev1 "github.com/openshift/api/image/v1"
@@ -29,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
@@ -47,18 +47,28 @@ import (
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/buildconfig"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
+	buildjob "github.com/rh-ecosystem-edge/kernel-module-management/internal/build/job"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/cmd"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/diagnostics"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/filter"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/loadjob"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/machineconfig"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/metrics"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/nmc"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/preflight"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
 	signjob "github.com/rh-ecosystem-edge/kernel-module-management/internal/sign/job"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/statusupdater"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/upgrade"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/webhook"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -79,9 +89,22 @@ func main() {
 
 	setupLogger := logger.WithName("setup")
 
-	var configFile string
+	var (
+		configFile           string
+		firmwareClassPath    string
+		buildCacheRepository string
+	)
+
+	buildGCPolicy := buildconfig.DefaultGCPolicy
+	buildLimits := scheduler.Limits{}
 
 	flag.StringVar(&configFile, "config", "", "The path to the configuration file.")
+	flag.IntVar(&buildGCPolicy.KeepFailed, "build-gc-keep-failed", buildGCPolicy.KeepFailed, "The number of failed Builds to keep for each module and kernel.")
+	flag.DurationVar(&buildGCPolicy.TTL, "build-gc-ttl", buildGCPolicy.TTL, "How long failed Builds are kept; 0 to keep them indefinitely.")
+	flag.IntVar(&buildLimits.MaxConcurrent, "build-max-concurrency", 0, "The maximum number of Builds and sign Jobs running at the same time; 0 for no limit.")
+	flag.IntVar(&buildLimits.MaxConcurrentPerNamespace, "build-max-concurrency-per-namespace", 0, "The maximum number of Builds and sign Jobs running at the same time in a namespace; 0 for no limit.")
+	flag.StringVar(&buildCacheRepository, "build-cache-repository", "", "If not empty, the repository in which the results of builds are cached, so that they are shared between the Modules of a namespace building the same inputs; everyone who can push to it must be trusted.")
+	flag.StringVar(&firmwareClassPath, "set-firmware-class-path", "", "If not empty, the firmware search path of the kernel is set to this value on nodes where Modules ship firmware files.")
 
 	klog.InitFlags(flag.CommandLine)
 
@@ -95,12 +118,17 @@ func main() {
 
 	operatorNamespace := cmd.GetEnvOrFatalError(constants.OperatorNamespaceEnvVar, setupLogger)
 
-	managed, err := GetBoolEnv("KMM_MANAGED")
+	managed, err := cmd.GetBoolEnv("KMM_MANAGED")
 	if err != nil {
 		setupLogger.Error(err, "could not determine if we are running as managed; disabling")
 		managed = false
 	}
 
+	enableWebhooks, err := cmd.GetBoolEnv(constants.EnableWebhooksEnvVar)
+	if err != nil {
+		cmd.FatalError(setupLogger, err, "could not determine if webhooks should be enabled")
+	}
+
 	setupLogger.Info("Creating manager", "git commit", commit)
 
 	options := ctrl.Options{Scheme: scheme}
@@ -123,23 +151,56 @@ func main() {
 	metricsAPI := metrics.New()
 	metricsAPI.Register()
 	buildHelperAPI := build.NewHelper()
+	gitResolver := git.NewResolver(client)
 	registryAPI := registry.NewRegistry()
-	authFactory := auth.NewRegistryAuthGetterFactory(
-		client,
-		kubernetes.NewForConfigOrDie(
-			ctrl.GetConfigOrDie(),
-		),
+	clientset := kubernetes.NewForConfigOrDie(
+		ctrl.GetConfigOrDie(),
 	)
+	authFactory := auth.NewRegistryAuthGetterFactory(client, clientset)
+	buildCache := cache.New(buildCacheRepository, authFactory, registryAPI)
 
-	buildAPI := buildconfig.NewManager(
-		client,
-		buildconfig.NewMaker(client, buildHelperAPI, scheme, kernelOsDtkMapping),
-		buildconfig.NewOpenShiftBuildsHelper(client),
-		authFactory,
-		registryAPI,
+	jobHelperAPI := utils.NewJobHelper(client)
+
+	ocpBuildsAvailable, err := cmd.IsGroupVersionAvailable(
+		discovery.NewDiscoveryClientForConfigOrDie(ctrl.GetConfigOrDie()),
+		buildv1.GroupVersion.String(),
 	)
+	if err != nil {
+		cmd.FatalError(setupLogger, err, "could not determine if OpenShift builds are available")
+	}
+
+	buildScheduler := scheduler.New(buildLimits, scheduler.NewLister(mgr.GetAPIReader(), ocpBuildsAvailable))
+
+	var buildAPI build.Manager
+
+	if ocpBuildsAvailable {
+		setupLogger.Info("Using OpenShift builds")
+
+		buildAPI = buildconfig.NewManager(
+			client,
+			buildconfig.NewMaker(client, buildHelperAPI, gitResolver, buildCache, scheme, kernelOsDtkMapping),
+			buildconfig.NewOpenShiftBuildsHelper(client),
+			authFactory,
+			registryAPI,
+			gitResolver,
+			buildCache,
+			buildScheduler,
+			buildGCPolicy,
+		)
+	} else {
+		setupLogger.Info("OpenShift builds are not available; using Kaniko build Jobs")
+
+		buildAPI = buildjob.NewBuildManager(
+			buildjob.NewMaker(client, buildHelperAPI, gitResolver, buildCache, scheme, kernelOsDtkMapping),
+			jobHelperAPI,
+			authFactory,
+			registryAPI,
+			gitResolver,
+			buildCache,
+			buildScheduler,
+		)
+	}
 
-	jobHelperAPI := utils.NewJobHelper(client)
 	caHelper := ca.NewHelper(client, scheme)
 
 	signAPI := signjob.NewSignJobManager(
@@ -147,25 +208,52 @@ func main() {
 		jobHelperAPI,
 		authFactory,
 		registryAPI,
+		gitResolver,
+		buildScheduler,
+	)
+
+	diagnosticsAPI := diagnostics.NewDiagnoser(
+		client,
+		clientset.CoreV1(),
+		jobHelperAPI,
+		mgr.GetEventRecorderFor("kmm"),
+		scheme,
+		ocpBuildsAvailable,
 	)
 
-	daemonAPI := daemonset.NewCreator(client, constants.KernelLabel, scheme)
-	kernelAPI := module.NewKernelMapper(buildHelperAPI, sign.NewSignerHelper())
+	daemonAPI := daemonset.NewCreator(client, constants.KernelLabel, firmwareClassPath, scheme)
+	upgradeAPI := upgrade.NewUpgrader(client, daemonAPI, clientset.PolicyV1())
+	loadAPI := loadjob.NewManager(client, daemonAPI, jobHelperAPI, scheme, operatorNamespace)
+	machineConfigAPI := machineconfig.NewManager(client)
+	kernelAPI := module.NewKernelMapper(buildHelperAPI, sign.NewSignerHelper(), kernelOsDtkMapping)
+	nmcHelper := nmc.NewHelper(client, scheme)
+
+	if err = upgrade.IndexPodsByNodeName(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		cmd.FatalError(setupLogger, err, "could not index pods by node name")
+	}
 
 	mc := controllers.NewModuleReconciler(
 		client,
 		buildAPI,
 		signAPI,
+		diagnosticsAPI,
 		daemonAPI,
+		upgradeAPI,
+		loadAPI,
+		machineConfigAPI,
 		kernelAPI,
+		nmcHelper,
+		registryAPI,
+		authFactory,
 		metricsAPI,
 		filterAPI,
 		statusupdater.NewModuleStatusUpdater(client),
 		caHelper,
+		buildScheduler,
 		operatorNamespace,
 	)
 
-	if err = mc.SetupWithManager(mgr, constants.KernelLabel); err != nil {
+	if err = mc.SetupWithManager(mgr, constants.KernelLabel, ocpBuildsAvailable); err != nil {
 		cmd.FatalError(setupLogger, err, "unable to create controller", "name", controllers.ModuleReconcilerName)
 	}
 
@@ -175,13 +263,13 @@ func main() {
 		cmd.FatalError(setupLogger, err, "unable to create controller", "name", controllers.NodeKernelReconcilerName)
 	}
 
-	if err = controllers.NewPodNodeModuleReconciler(client, daemonAPI).SetupWithManager(mgr); err != nil {
+	if err = controllers.NewPodNodeModuleReconciler(client, daemonAPI, nmcHelper).SetupWithManager(mgr); err != nil {
 		cmd.FatalError(setupLogger, err, "unable to create controller", "name", controllers.PodNodeModuleReconcilerName)
 	}
 
 	preflightStatusUpdaterAPI := statusupdater.NewPreflightStatusUpdater(client)
 	preflightOCPStatusUpdaterAPI := statusupdater.NewPreflightOCPStatusUpdater(client)
-	preflightAPI := preflight.NewPreflightAPI(client, buildAPI, signAPI, registryAPI, kernelAPI, preflightStatusUpdaterAPI, authFactory)
+	preflightAPI := preflight.NewPreflightAPI(client, buildAPI, signAPI, registryAPI, kernelAPI, preflightStatusUpdaterAPI, authFactory, kernelOsDtkMapping)
 
 	if err = controllers.NewPreflightValidationReconciler(client, filterAPI, metricsAPI, preflightStatusUpdaterAPI, preflightAPI).SetupWithManager(mgr); err != nil {
 		cmd.FatalError(setupLogger, err, "unable to create controller", "name", controllers.PreflightValidationReconcilerName)
@@ -222,6 +310,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	if enableWebhooks {
+		setupLogger.Info("Enabling webhooks")
+
+		if err = webhook.NewModuleWebhook(buildHelperAPI, sign.NewSignerHelper()).SetupWebhookWithManager(mgr); err != nil {
+			cmd.FatalError(setupLogger, err, "unable to create webhook", "webhook", "Module")
+		}
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -236,18 +332,3 @@ func main() {
 		cmd.FatalError(setupLogger, err, "problem running manager")
 	}
 }
-
-func GetBoolEnv(s string) (bool, error) {
-	envValue := os.Getenv(s)
-
-	if envValue == "" {
-		return false, nil
-	}
-
-	managed, err := strconv.ParseBool(envValue)
-	if err != nil {
-		return false, fmt.Errorf("%q: invalid value for %s", envValue, s)
-	}
-
-	return managed, nil
-}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/cmd"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/worker"
+	"k8s.io/klog/v2"
+	"k8s.io/klog/v2/klogr"
+)
+
+const unloadRetryInterval = time.Second
+
+func usage() {
+	fmt.Fprintf(
+		flag.CommandLine.Output(),
+		`Usage: %s [flags] <command> [command flags]
+
+Commands:
+  install <destination>    copy this binary to destination
+  run --config <file> [--state-file <file>]
+                           load the kernel module, wait for SIGTERM and unload it
+  load --config <file> --state-file <file>
+                           load the kernel module and exit
+  unload --config <file> --state-file <file>
+                           unload the kernel module loaded by load and exit
+  ready --config <file> [--state-file <file>]
+                           exit with a non-zero status if the kernel module is not loaded
+
+Flags:
+`,
+		os.Args[0],
+	)
+
+	flag.PrintDefaults()
+}
+
+func main() {
+	klog.InitFlags(flag.CommandLine)
+
+	flag.Usage = usage
+	flag.Parse()
+
+	logger := klogr.New().WithName("kmm-worker")
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch command, args := flag.Arg(0), flag.Args()[1:]; command {
+	case "install":
+		err = install(args)
+	case "run":
+		err = run(logger, args)
+	case "load":
+		err = load(logger, args)
+	case "unload":
+		err = unload(logger, args)
+	case "ready":
+		err = ready(logger, args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		cmd.FatalError(logger, err, "Command failed", "command", flag.Arg(0))
+	}
+}
+
+// parseConfig parses args with fs, to which it adds the --config flag, and reads the configuration file.
+func parseConfig(fs *flag.FlagSet, args []string) (*worker.Config, error) {
+	configPath := fs.String("config", "", "The path to the worker configuration file.")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath == "" {
+		return nil, errors.New("--config is required")
+	}
+
+	return worker.ReadConfig(*configPath)
+}
+
+// install copies the worker binary to the path passed in args, so that it can be run from another image.
+func install(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("install takes exactly one argument, got %d", len(args))
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not find the path of the worker binary: %v", err)
+	}
+
+	in, err := os.Open(self)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %v", self, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(args[0], os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %v", args[0], err)
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("could not copy %s to %s: %v", self, args[0], err)
+	}
+
+	return out.Close()
+}
+
+func run(logger logr.Logger, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+
+	stateFile := fs.String("state-file", "", "The path to the file where the worker keeps its state across restarts.")
+
+	cfg, err := parseConfig(fs, args)
+	if err != nil {
+		return fmt.Errorf("could not read the configuration: %v", err)
+	}
+
+	w := worker.NewWorker(worker.NewModprobeRunner(logger), worker.FirmwareDir, worker.SysModuleDir, *stateFile, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err = w.LoadKmod(ctx, cfg); err != nil {
+		return err
+	}
+
+	logger.Info("Kernel module loaded; waiting for a termination signal", "name", cfg.Modprobe.ModuleName)
+
+	<-ctx.Done()
+
+	if cfg.KeepLoaded {
+		logger.Info("The kernel module is loaded at boot; keeping it loaded", "name", cfg.Modprobe.ModuleName)
+		return nil
+	}
+
+	logger.Info("Unloading the kernel module", "name", cfg.Modprobe.ModuleName)
+
+	// ctx is done; modprobe should not be interrupted until the container is killed.
+	unloadCtx := context.Background()
+
+	// modprobe fails to unload the module while dependent modules are still loaded; retry until they are unloaded,
+	// or until the container is killed at the end of the termination grace period.
+	for {
+		if err = w.UnloadKmod(unloadCtx, cfg); err == nil {
+			return nil
+		}
+
+		logger.Info("Could not unload the kernel module; retrying", "error", err, "interval", unloadRetryInterval)
+
+		time.Sleep(unloadRetryInterval)
+	}
+}
+
+// load loads the kernel module once; the state file must be kept on the host until unload runs.
+func load(logger logr.Logger, args []string) error {
+	w, cfg, err := oneShotWorker(logger, "load", args)
+	if err != nil {
+		return err
+	}
+
+	if err = w.LoadKmod(context.Background(), cfg); err != nil {
+		return err
+	}
+
+	logger.Info("Kernel module loaded", "name", cfg.Modprobe.ModuleName)
+
+	return nil
+}
+
+func unload(logger logr.Logger, args []string) error {
+	w, cfg, err := oneShotWorker(logger, "unload", args)
+	if err != nil {
+		return err
+	}
+
+	if err = w.UnloadKmod(context.Background(), cfg); err != nil {
+		return err
+	}
+
+	logger.Info("Kernel module unloaded", "name", cfg.Modprobe.ModuleName)
+
+	return nil
+}
+
+// oneShotWorker parses the arguments of the load and unload commands, which run in separate pods and share their
+// state through a file on the host.
+func oneShotWorker(logger logr.Logger, command string, args []string) (worker.Worker, *worker.Config, error) {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+
+	stateFile := fs.String("state-file", "", "The path to the file where the worker keeps its state between load and unload.")
+
+	cfg, err := parseConfig(fs, args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read the configuration: %v", err)
+	}
+
+	if *stateFile == "" {
+		return nil, nil, errors.New("--state-file is required")
+	}
+
+	return worker.NewWorker(worker.NewModprobeRunner(logger), worker.FirmwareDir, worker.SysModuleDir, *stateFile, logger), cfg, nil
+}
+
+func ready(logger logr.Logger, args []string) error {
+	fs := flag.NewFlagSet("ready", flag.ExitOnError)
+
+	stateFile := fs.String("state-file", "", "The path to the file where the run command keeps its state.")
+
+	cfg, err := parseConfig(fs, args)
+	if err != nil {
+		return fmt.Errorf("could not read the configuration: %v", err)
+	}
+
+	w := worker.NewWorker(worker.NewModprobeRunner(logger), worker.FirmwareDir, worker.SysModuleDir, *stateFile, logger)
+
+	loaded, err := w.IsLoaded(cfg)
+	if err != nil {
+		return err
+	}
+
+	if !loaded {
+		return fmt.Errorf("kernel module %s is not loaded", cfg.Modprobe.ModuleName)
+	}
+
+	return nil
+}
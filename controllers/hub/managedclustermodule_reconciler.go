@@ -19,6 +19,7 @@ package hub
 import (
 	"context"
 	"fmt"
+	"time"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -41,7 +42,14 @@ import (
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/statusupdater"
 )
 
-const ManagedClusterModuleReconcilerName = "ManagedClusterModule"
+const (
+	ManagedClusterModuleReconcilerName = "ManagedClusterModule"
+
+	// buildRequeueInterval is how often ManagedClusterModules are reconciled while their build or signing has not
+	// completed, so that queued builds start once a slot frees up and running ones keep being counted by the build
+	// scheduler.
+	buildRequeueInterval = 30 * time.Second
+)
 
 // ManagedClusterModuleReconciler reconciles a ManagedClusterModule object
 type ManagedClusterModuleReconciler struct {
@@ -117,6 +125,7 @@ func (r *ManagedClusterModuleReconciler) Reconcile(ctx context.Context, req ctrl
 		}
 		if !completedSuccessfully {
 			logger.Info("Build and Sign have not finished successfully yet; skipping ManifestWork reconciliation")
+			res.RequeueAfter = buildRequeueInterval
 			continue
 		}
 
@@ -163,12 +172,18 @@ func (r *ManagedClusterModuleReconciler) Reconcile(ctx context.Context, req ctrl
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func (r *ManagedClusterModuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+// OpenShift Builds are only watched if ownsOCPBuilds is true, as the API might not be served by the cluster.
+func (r *ManagedClusterModuleReconciler) SetupWithManager(mgr ctrl.Manager, ownsOCPBuilds bool) error {
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&hubv1beta1.ManagedClusterModule{}).
 		Owns(&workv1.ManifestWork{}).
-		Owns(&buildv1.Build{}).
-		Owns(&batchv1.Job{}).
+		Owns(&batchv1.Job{})
+
+	if ownsOCPBuilds {
+		b = b.Owns(&buildv1.Build{})
+	}
+
+	return b.
 		Watches(
 			&source.Kind{Type: &clusterv1.ManagedCluster{}},
 			handler.EnqueueRequestsFromMapFunc(r.filter.FindManagedClusterModulesForCluster),
@@ -370,7 +370,7 @@ var _ = Describe("ManagedClusterModuleReconciler_Reconcile", func() {
 
 		res, err := mr.Reconcile(context.Background(), req)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(res).To(Equal(reconcile.Result{Requeue: false}))
+		Expect(res).To(Equal(reconcile.Result{RequeueAfter: buildRequeueInterval}))
 	})
 
 	It("should create a ManifestWork if a managed cluster matches the selector and build/sign is completed", func() {
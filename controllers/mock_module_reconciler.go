@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	v1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	api "github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	loadjob "github.com/rh-ecosystem-edge/kernel-module-management/internal/loadjob"
 	v1 "k8s.io/api/apps/v1"
 	v10 "k8s.io/api/core/v1"
 	types "k8s.io/apimachinery/pkg/types"
@@ -39,18 +40,76 @@ func (m *MockmoduleReconcilerHelperAPI) EXPECT() *MockmoduleReconcilerHelperAPIM
 	return m.recorder
 }
 
+// deleteBootLoading mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) deleteBootLoading(ctx context.Context, mod *v1beta1.Module) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "deleteBootLoading", ctx, mod)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// deleteBootLoading indicates an expected call of deleteBootLoading.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) deleteBootLoading(ctx, mod interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteBootLoading", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).deleteBootLoading), ctx, mod)
+}
+
+// deleteModuleDaemonSets mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) deleteModuleDaemonSets(ctx context.Context, mod *v1beta1.Module) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "deleteModuleDaemonSets", ctx, mod)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// deleteModuleDaemonSets indicates an expected call of deleteModuleDaemonSets.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) deleteModuleDaemonSets(ctx, mod interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteModuleDaemonSets", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).deleteModuleDaemonSets), ctx, mod)
+}
+
+// diagnoseFailure mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) diagnoseFailure(ctx context.Context, mld *api.ModuleLoaderData, stage v1beta1.Stage, cause error, previous *v1beta1.StageFailure) (*v1beta1.StageFailure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "diagnoseFailure", ctx, mld, stage, cause, previous)
+	ret0, _ := ret[0].(*v1beta1.StageFailure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// diagnoseFailure indicates an expected call of diagnoseFailure.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) diagnoseFailure(ctx, mld, stage, cause, previous interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "diagnoseFailure", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).diagnoseFailure), ctx, mld, stage, cause, previous)
+}
+
 // garbageCollect mocks base method.
-func (m *MockmoduleReconcilerHelperAPI) garbageCollect(ctx context.Context, mod *v1beta1.Module, mldMappings map[string]*api.ModuleLoaderData, existingDS map[string]*v1.DaemonSet) error {
+func (m *MockmoduleReconcilerHelperAPI) garbageCollect(ctx context.Context, mod *v1beta1.Module, mldMappings map[string]*api.ModuleLoaderData, existingDS map[string]*v1.DaemonSet, dependents []string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "garbageCollect", ctx, mod, mldMappings, existingDS)
+	ret := m.ctrl.Call(m, "garbageCollect", ctx, mod, mldMappings, existingDS, dependents)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // garbageCollect indicates an expected call of garbageCollect.
-func (mr *MockmoduleReconcilerHelperAPIMockRecorder) garbageCollect(ctx, mod, mldMappings, existingDS interface{}) *gomock.Call {
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) garbageCollect(ctx, mod, mldMappings, existingDS, dependents interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "garbageCollect", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).garbageCollect), ctx, mod, mldMappings, existingDS, dependents)
+}
+
+// getDependentModules mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) getDependentModules(ctx context.Context, mod *v1beta1.Module) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getDependentModules", ctx, mod)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getDependentModules indicates an expected call of getDependentModules.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) getDependentModules(ctx, mod interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "garbageCollect", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).garbageCollect), ctx, mod, mldMappings, existingDS)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getDependentModules", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).getDependentModules), ctx, mod)
 }
 
 // getNodesListBySelector mocks base method.
@@ -99,11 +158,25 @@ func (mr *MockmoduleReconcilerHelperAPIMockRecorder) getRequestedModule(ctx, nam
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getRequestedModule", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).getRequestedModule), ctx, namespacedName)
 }
 
+// handleBootLoading mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) handleBootLoading(ctx context.Context, mod *v1beta1.Module, mlds []*api.ModuleLoaderData) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleBootLoading", ctx, mod, mlds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleBootLoading indicates an expected call of handleBootLoading.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) handleBootLoading(ctx, mod, mlds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleBootLoading", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).handleBootLoading), ctx, mod, mlds)
+}
+
 // handleBuild mocks base method.
-func (m *MockmoduleReconcilerHelperAPI) handleBuild(ctx context.Context, mld *api.ModuleLoaderData) (bool, error) {
+func (m *MockmoduleReconcilerHelperAPI) handleBuild(ctx context.Context, mld *api.ModuleLoaderData) (v1beta1.StagePhase, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "handleBuild", ctx, mld)
-	ret0, _ := ret[0].(bool)
+	ret0, _ := ret[0].(v1beta1.StagePhase)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -129,24 +202,40 @@ func (mr *MockmoduleReconcilerHelperAPIMockRecorder) handleDevicePlugin(ctx, mod
 }
 
 // handleDriverContainer mocks base method.
-func (m *MockmoduleReconcilerHelperAPI) handleDriverContainer(ctx context.Context, mld *api.ModuleLoaderData, dsByKernelVersion map[string]*v1.DaemonSet) error {
+func (m *MockmoduleReconcilerHelperAPI) handleDriverContainer(ctx context.Context, mld *api.ModuleLoaderData, dsByKernelAndArch map[string]*v1.DaemonSet) (*v1.DaemonSet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "handleDriverContainer", ctx, mld, dsByKernelVersion)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "handleDriverContainer", ctx, mld, dsByKernelAndArch)
+	ret0, _ := ret[0].(*v1.DaemonSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // handleDriverContainer indicates an expected call of handleDriverContainer.
-func (mr *MockmoduleReconcilerHelperAPIMockRecorder) handleDriverContainer(ctx, mld, dsByKernelVersion interface{}) *gomock.Call {
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) handleDriverContainer(ctx, mld, dsByKernelAndArch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleDriverContainer", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).handleDriverContainer), ctx, mld, dsByKernelVersion)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleDriverContainer", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).handleDriverContainer), ctx, mld, dsByKernelAndArch)
+}
+
+// handleLoadJobs mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) handleLoadJobs(ctx context.Context, mld *api.ModuleLoaderData, nodes []v10.Node) (*loadjob.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleLoadJobs", ctx, mld, nodes)
+	ret0, _ := ret[0].(*loadjob.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// handleLoadJobs indicates an expected call of handleLoadJobs.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) handleLoadJobs(ctx, mld, nodes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleLoadJobs", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).handleLoadJobs), ctx, mld, nodes)
 }
 
 // handleSigning mocks base method.
-func (m *MockmoduleReconcilerHelperAPI) handleSigning(ctx context.Context, mld *api.ModuleLoaderData) (bool, error) {
+func (m *MockmoduleReconcilerHelperAPI) handleSigning(ctx context.Context, mld *api.ModuleLoaderData) (v1beta1.StagePhase, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "handleSigning", ctx, mld)
-	ret0, _ := ret[0].(bool)
+	ret0, _ := ret[0].(v1beta1.StagePhase)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -157,6 +246,122 @@ func (mr *MockmoduleReconcilerHelperAPIMockRecorder) handleSigning(ctx, mld inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleSigning", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).handleSigning), ctx, mld)
 }
 
+// handleUnload mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) handleUnload(ctx context.Context, mod *v1beta1.Module, dependents []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleUnload", ctx, mod, dependents)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// handleUnload indicates an expected call of handleUnload.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) handleUnload(ctx, mod, dependents interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleUnload", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).handleUnload), ctx, mod, dependents)
+}
+
+// handleUnloadJobs mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) handleUnloadJobs(ctx context.Context, mod *v1beta1.Module, nodesToKeep []v10.Node, dependents []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleUnloadJobs", ctx, mod, nodesToKeep, dependents)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// handleUnloadJobs indicates an expected call of handleUnloadJobs.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) handleUnloadJobs(ctx, mod, nodesToKeep, dependents interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleUnloadJobs", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).handleUnloadJobs), ctx, mod, nodesToKeep, dependents)
+}
+
+// handleUpgrade mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) handleUpgrade(ctx context.Context, ds *v1.DaemonSet, mld *api.ModuleLoaderData) (*v1beta1.ModuleUpgradeStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleUpgrade", ctx, ds, mld)
+	ret0, _ := ret[0].(*v1beta1.ModuleUpgradeStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// handleUpgrade indicates an expected call of handleUpgrade.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) handleUpgrade(ctx, ds, mld interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleUpgrade", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).handleUpgrade), ctx, ds, mld)
+}
+
+// recordLoadResult mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) recordLoadResult(ctx context.Context, mld *api.ModuleLoaderData, nodes []v10.Node, res *loadjob.Result) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "recordLoadResult", ctx, mld, nodes, res)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// recordLoadResult indicates an expected call of recordLoadResult.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) recordLoadResult(ctx, mld, nodes, res interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "recordLoadResult", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).recordLoadResult), ctx, mld, nodes, res)
+}
+
+// recordUnloadProgress mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) recordUnloadProgress(ctx context.Context, mod *v1beta1.Module, unloadingNodes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "recordUnloadProgress", ctx, mod, unloadingNodes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// recordUnloadProgress indicates an expected call of recordUnloadProgress.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) recordUnloadProgress(ctx, mod, unloadingNodes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "recordUnloadProgress", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).recordUnloadProgress), ctx, mod, unloadingNodes)
+}
+
+// removeFinalizer mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) removeFinalizer(ctx context.Context, mod *v1beta1.Module) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "removeFinalizer", ctx, mod)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// removeFinalizer indicates an expected call of removeFinalizer.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) removeFinalizer(ctx, mod interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "removeFinalizer", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).removeFinalizer), ctx, mod)
+}
+
+// resolveImageDigest mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) resolveImageDigest(ctx context.Context, mld *api.ModuleLoaderData, previous *v1beta1.KernelVersionStatus) (*v1beta1.ImageDigestStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "resolveImageDigest", ctx, mld, previous)
+	ret0, _ := ret[0].(*v1beta1.ImageDigestStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// resolveImageDigest indicates an expected call of resolveImageDigest.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) resolveImageDigest(ctx, mld, previous interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "resolveImageDigest", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).resolveImageDigest), ctx, mld, previous)
+}
+
+// setFinalizer mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) setFinalizer(ctx context.Context, mod *v1beta1.Module) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "setFinalizer", ctx, mod)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// setFinalizer indicates an expected call of setFinalizer.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) setFinalizer(ctx, mod interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setFinalizer", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).setFinalizer), ctx, mod)
+}
+
 // setKMMOMetrics mocks base method.
 func (m *MockmoduleReconcilerHelperAPI) setKMMOMetrics(ctx context.Context) {
 	m.ctrl.T.Helper()
@@ -168,3 +373,17 @@ func (mr *MockmoduleReconcilerHelperAPIMockRecorder) setKMMOMetrics(ctx interfac
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setKMMOMetrics", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).setKMMOMetrics), ctx)
 }
+
+// syncNodeModulesConfigs mocks base method.
+func (m *MockmoduleReconcilerHelperAPI) syncNodeModulesConfigs(ctx context.Context, mod *v1beta1.Module, mldMappings map[string]*api.ModuleLoaderData, nodes []v10.Node) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "syncNodeModulesConfigs", ctx, mod, mldMappings, nodes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// syncNodeModulesConfigs indicates an expected call of syncNodeModulesConfigs.
+func (mr *MockmoduleReconcilerHelperAPIMockRecorder) syncNodeModulesConfigs(ctx, mod, mldMappings, nodes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "syncNodeModulesConfigs", reflect.TypeOf((*MockmoduleReconcilerHelperAPI)(nil).syncNodeModulesConfigs), ctx, mod, mldMappings, nodes)
+}
@@ -19,19 +19,30 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/ca"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/diagnostics"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/filter"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/loadjob"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/machineconfig"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/metrics"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/nmc"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/statusupdater"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/upgrade"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -39,6 +50,7 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -49,7 +61,32 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-const ModuleReconcilerName = "Module"
+const (
+	ModuleReconcilerName = "Module"
+
+	// upgradeRequeueInterval is how often modules are reconciled during a node-by-node upgrade, so that nodes
+	// failing to become ready are detected.
+	upgradeRequeueInterval = 30 * time.Second
+
+	// retryRequeueInterval is how often modules are reconciled while a failed build or signing waits for its next
+	// attempt.
+	retryRequeueInterval = 15 * time.Second
+
+	// queueRequeueInterval is how often modules are reconciled while a build or signing is queued, so that it starts
+	// once the number of concurrent builds falls below the limit.
+	queueRequeueInterval = 15 * time.Second
+
+	// buildRequeueInterval is how often modules are reconciled while a build or signing is in progress, so that it
+	// keeps being counted by the build scheduler, whose lease is longer.
+	buildRequeueInterval = time.Minute
+
+	// unloadRequeueInterval is how often deleted modules are reconciled while they may still be loaded on some nodes.
+	unloadRequeueInterval = 30 * time.Second
+
+	// defaultImageDigestInterval is how often the tag of the module loader image is resolved again with the Periodic
+	// policy, if the Module does not set an interval.
+	defaultImageDigestInterval = time.Hour
+)
 
 // ModuleReconciler reconciles a Module object
 type ModuleReconciler struct {
@@ -59,6 +96,7 @@ type ModuleReconciler struct {
 	filter            *filter.Filter
 	statusUpdaterAPI  statusupdater.ModuleStatusUpdater
 	caHelper          ca.Helper
+	schedulerAPI      scheduler.Scheduler
 	reconHelperAPI    moduleReconcilerHelperAPI
 	operatorNamespace string
 }
@@ -67,21 +105,45 @@ func NewModuleReconciler(
 	client client.Client,
 	buildAPI build.Manager,
 	signAPI sign.SignManager,
+	diagnosticsAPI diagnostics.Diagnoser,
 	daemonAPI daemonset.DaemonSetCreator,
+	upgradeAPI upgrade.Upgrader,
+	loadAPI loadjob.Manager,
+	machineConfigAPI machineconfig.Manager,
 	kernelAPI module.KernelMapper,
+	nmcHelper nmc.Helper,
+	registryAPI registry.Registry,
+	authFactory auth.RegistryAuthGetterFactory,
 	metricsAPI metrics.Metrics,
 	filter *filter.Filter,
 	statusUpdaterAPI statusupdater.ModuleStatusUpdater,
 	caHelper ca.Helper,
+	schedulerAPI scheduler.Scheduler,
 	operatorNamespace string,
 ) *ModuleReconciler {
-	reconHelperAPI := newModuleReconcilerHelper(client, buildAPI, signAPI, daemonAPI, kernelAPI, metricsAPI, operatorNamespace)
+	reconHelperAPI := newModuleReconcilerHelper(
+		client,
+		buildAPI,
+		signAPI,
+		diagnosticsAPI,
+		daemonAPI,
+		upgradeAPI,
+		loadAPI,
+		machineConfigAPI,
+		kernelAPI,
+		nmcHelper,
+		registryAPI,
+		authFactory,
+		metricsAPI,
+		operatorNamespace,
+	)
 	return &ModuleReconciler{
 		daemonAPI:         daemonAPI,
 		reconHelperAPI:    reconHelperAPI,
 		filter:            filter,
 		statusUpdaterAPI:  statusUpdaterAPI,
 		caHelper:          caHelper,
+		schedulerAPI:      schedulerAPI,
 		operatorNamespace: operatorNamespace,
 	}
 }
@@ -90,16 +152,30 @@ func NewModuleReconciler(
 //+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=modules/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=modules/finalizers,verbs=update
 //+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=create;delete;get;list;patch;watch
-//+kubebuilder:rbac:groups="core",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="core",resources=nodes,verbs=get;list;patch;watch
+//+kubebuilder:rbac:groups="core",resources=pods,verbs=delete;get;list;watch
+//+kubebuilder:rbac:groups="core",resources=pods/log,verbs=get
+//+kubebuilder:rbac:groups="core",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="core",resources=pods/eviction,verbs=create
 //+kubebuilder:rbac:groups="core",resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="core",resources=configmaps,verbs=create;delete;get;list;patch;watch
 //+kubebuilder:rbac:groups="core",resources=serviceaccounts,verbs=get;list;watch
 //+kubebuilder:rbac:groups="build.openshift.io",resources=builds,verbs=get;list;create;delete;watch;patch
-//+kubebuilder:rbac:groups="batch",resources=jobs,verbs=create;list;watch;delete
+//+kubebuilder:rbac:groups="batch",resources=jobs,verbs=create;list;watch;delete;patch
+//+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=nodemodulesconfigs,verbs=create;get;list;watch
+//+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=nodemodulesconfigs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=machineconfiguration.openshift.io,resources=machineconfigs,verbs=create;delete;get;patch
 
 // Reconcile lists all nodes and looks for kernels that match its mappings.
 // For each mapping that matches at least one node in the cluster, it creates a DaemonSet running the container image
 // on the nodes with a compatible kernel.
+// Modules using the Job load mode are loaded on each node by a one-shot Job instead, and unloaded by another Job once
+// the node does not need them anymore.
+// Deleted Modules are only released once their kernel module is confirmed to be unloaded from all nodes.
+// The desired state of the Module on each node is recorded in the NodeModulesConfig of the node.
+// The module loader image is pinned to the digest its tag resolves to, which is recorded in the status of each kernel
+// version.
+// Modules enabling boot loading also get a MachineConfig loading the kernel module from the images that are ready.
 func (r *ModuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	res := ctrl.Result{}
 
@@ -115,6 +191,14 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return res, fmt.Errorf("failed to get the requested %s KMMO CR: %w", req.NamespacedName, err)
 	}
 
+	if !mod.DeletionTimestamp.IsZero() {
+		return r.unloadDeletedModule(ctx, mod)
+	}
+
+	if err = r.reconHelperAPI.setFinalizer(ctx, mod); err != nil {
+		return res, fmt.Errorf("could not set the finalizer of module %s: %v", mod.Name, err)
+	}
+
 	if req.Namespace != r.operatorNamespace {
 		if err = r.caHelper.Sync(ctx, req.Namespace, mod); err != nil {
 			return ctrl.Result{}, fmt.Errorf("failed to synchronize CA ConfigMaps: %v", err)
@@ -133,40 +217,96 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return res, fmt.Errorf("could get kernel mappings and nodes for modules %s: %w", mod.Name, err)
 	}
 
-	dsByKernelVersion, err := r.daemonAPI.ModuleDaemonSetsByKernelVersion(ctx, mod.Name, mod.Namespace)
+	dependents, err := r.reconHelperAPI.getDependentModules(ctx, mod)
+	if err != nil {
+		return res, fmt.Errorf("could not get the modules depending on module %s: %w", mod.Name, err)
+	}
+
+	for _, mld := range mldMappings {
+		mld.Dependents = dependents
+	}
+
+	dsByKernelAndArch, err := r.daemonAPI.ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace)
 	if err != nil {
 		return res, fmt.Errorf("could not get DaemonSets for module %s: %v", mod.Name, err)
 	}
 
-	for kernelVersion, mld := range mldMappings {
-		completedSuccessfully, err := r.reconHelperAPI.handleBuild(ctx, mld)
+	adoptDaemonSetsWithoutArch(ctx, dsByKernelAndArch, mldMappings)
+
+	nodesByKernelAndArch := make(map[string][]v1.Node, len(mldMappings))
+
+	for _, node := range nodesWithMapping {
+		key := daemonset.KernelArchKey(strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+"), node.Status.NodeInfo.Architecture)
+		nodesByKernelAndArch[key] = append(nodesByKernelAndArch[key], node)
+	}
+
+	kernelVersionStatuses := make([]kmmv1beta1.KernelVersionStatus, 0, len(mldMappings))
+	readyMLDs := make([]*api.ModuleLoaderData, 0, len(mldMappings))
+	errs := make([]error, 0)
+
+	for key, mld := range mldMappings {
+		previous := findKernelVersionStatus(mod.Status.KernelVersions, mld.KernelVersion, mld.Arch)
+
+		kvStatus, err := r.reconcileKernelVersion(ctx, mld, previous, dsByKernelAndArch, nodesByKernelAndArch[key])
 		if err != nil {
-			return res, fmt.Errorf("failed to handle build for kernel version %s: %v", kernelVersion, err)
+			errs = append(errs, err)
 		}
-		mldLogger := logger.WithValues(
-			"kernel version", kernelVersion,
-			"mld", mld,
-		)
-		if !completedSuccessfully {
-			mldLogger.Info("Build has not finished successfully yet:skipping handling signing and driver container for now")
-			continue
+
+		kernelVersionStatuses = append(kernelVersionStatuses, kvStatus)
+
+		if err == nil && isStageDone(kvStatus.BuildPhase) && isStageDone(kvStatus.SignPhase) {
+			readyMLDs = append(readyMLDs, mld)
 		}
 
-		completedSuccessfully, err = r.reconHelperAPI.handleSigning(ctx, mld)
-		if err != nil {
-			return res, fmt.Errorf("failed to handle signing for kernel version %s: %v", kernelVersion, err)
+		if kvStatus.Upgrade != nil && kvStatus.Upgrade.Phase != kmmv1beta1.UpgradePhaseCompleted {
+			res.RequeueAfter = shortestRequeue(res.RequeueAfter, upgradeRequeueInterval)
 		}
-		if !completedSuccessfully {
-			mldLogger.Info("Signing has not finished successfully yet; skipping handling driver container for now")
-			continue
+
+		if kvStatus.BuildPhase == kmmv1beta1.StagePhaseRetrying || kvStatus.SignPhase == kmmv1beta1.StagePhaseRetrying {
+			res.RequeueAfter = shortestRequeue(res.RequeueAfter, retryRequeueInterval)
 		}
 
-		err = r.reconHelperAPI.handleDriverContainer(ctx, mld, dsByKernelVersion)
-		if err != nil {
-			return res, fmt.Errorf("failed to handle driver container for kernel version %s: %v", kernelVersion, err)
+		if kvStatus.BuildPhase == kmmv1beta1.StagePhaseQueued || kvStatus.SignPhase == kmmv1beta1.StagePhaseQueued {
+			res.RequeueAfter = shortestRequeue(res.RequeueAfter, queueRequeueInterval)
+		}
+
+		if kvStatus.BuildPhase == kmmv1beta1.StagePhaseInProgress || kvStatus.SignPhase == kmmv1beta1.StagePhaseInProgress {
+			res.RequeueAfter = shortestRequeue(res.RequeueAfter, buildRequeueInterval)
+		}
+
+		if interval := imageDigestInterval(mld); interval > 0 && kvStatus.ImageDigest != nil {
+			res.RequeueAfter = shortestRequeue(res.RequeueAfter, time.Until(kvStatus.ImageDigest.ResolvedAt.Add(interval)))
 		}
 	}
 
+	if mod.Spec.LoadMode == kmmv1beta1.LoadModeJob {
+		// nodes that are still being built or signed for keep the previous version of the kernel module
+		if _, err = r.reconHelperAPI.handleUnloadJobs(ctx, mod, nodesWithMapping, dependents); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unload the module from the nodes that do not need it: %v", err))
+		}
+	}
+
+	if err = r.reconHelperAPI.handleBootLoading(ctx, mod, readyMLDs); err != nil {
+		errs = append(errs, fmt.Errorf("failed to handle boot loading: %v", err))
+	}
+
+	if err = r.reconHelperAPI.syncNodeModulesConfigs(ctx, mod, mldMappings, nodesWithMapping); err != nil {
+		errs = append(errs, fmt.Errorf("failed to update the NodeModulesConfigs: %v", err))
+	}
+
+	for _, kvStatus := range unmappedKernelVersions(targetedNodes, mldMappings) {
+		kvStatus.Message = "no kernel mapping matches this kernel version"
+		kernelVersionStatuses = append(kernelVersionStatuses, kvStatus)
+	}
+
+	sort.Slice(kernelVersionStatuses, func(i, j int) bool {
+		if kernelVersionStatuses[i].KernelVersion != kernelVersionStatuses[j].KernelVersion {
+			return kernelVersionStatuses[i].KernelVersion < kernelVersionStatuses[j].KernelVersion
+		}
+
+		return kernelVersionStatuses[i].Arch < kernelVersionStatuses[j].Arch
+	})
+
 	logger.Info("Handle device plugin")
 	err = r.reconHelperAPI.handleDevicePlugin(ctx, mod)
 	if err != nil {
@@ -174,21 +314,327 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	}
 
 	logger.Info("Run garbage collection")
-	err = r.reconHelperAPI.garbageCollect(ctx, mod, mldMappings, dsByKernelVersion)
+	err = r.reconHelperAPI.garbageCollect(ctx, mod, mldMappings, dsByKernelAndArch, dependents)
 	if err != nil {
 		return res, fmt.Errorf("failed to run garbage collection: %v", err)
 	}
 
-	err = r.statusUpdaterAPI.ModuleUpdateStatus(ctx, mod, nodesWithMapping, targetedNodes, dsByKernelVersion)
+	err = r.statusUpdaterAPI.ModuleUpdateStatus(ctx, mod, nodesWithMapping, targetedNodes, dsByKernelAndArch, kernelVersionStatuses)
 	if err != nil {
 		return res, fmt.Errorf("failed to update status of the module: %w", err)
 	}
 
+	if len(errs) > 0 {
+		return res, utilerrors.NewAggregate(errs)
+	}
+
 	logger.Info("Reconcile loop finished successfully")
 
 	return res, nil
 }
 
+// unloadDeletedModule removes the pods of a deleted Module, makes sure that its kernel module is unloaded from all
+// nodes, and removes its finalizer once it is not loaded anywhere anymore.
+// The nodes on which the unload is not confirmed yet are listed in the status of the Module.
+func (r *ModuleReconciler) unloadDeletedModule(ctx context.Context, mod *kmmv1beta1.Module) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	dependents, err := r.reconHelperAPI.getDependentModules(ctx, mod)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("could not get the modules depending on module %s: %w", mod.Name, err)
+	}
+
+	// Nodes rebooting from now on should not load the kernel module anymore
+	if err = r.reconHelperAPI.deleteBootLoading(ctx, mod); err != nil {
+		return ctrl.Result{}, fmt.Errorf("could not delete the boot configuration of module %s: %v", mod.Name, err)
+	}
+
+	// The DaemonSets are only garbage-collected once the Module is gone
+	if err = r.reconHelperAPI.deleteModuleDaemonSets(ctx, mod); err != nil {
+		return ctrl.Result{}, fmt.Errorf("could not delete the DaemonSets of module %s: %v", mod.Name, err)
+	}
+
+	remaining, unloadErr := r.reconHelperAPI.handleUnload(ctx, mod, dependents)
+
+	if unloadErr == nil {
+		if err = r.reconHelperAPI.recordUnloadProgress(ctx, mod, remaining); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update the NodeModulesConfigs of module %s: %v", mod.Name, err)
+		}
+	}
+
+	if err = r.statusUpdaterAPI.ModuleUpdateUnloadingStatus(ctx, mod, remaining); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update the status of module %s: %v", mod.Name, err)
+	}
+
+	if unloadErr != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to unload the deleted module: %v", unloadErr)
+	}
+
+	if len(remaining) > 0 {
+		logger.Info("Module deleted; waiting for it to be unloaded", "nodes", remaining)
+		return ctrl.Result{RequeueAfter: unloadRequeueInterval}, nil
+	}
+
+	logger.Info("Module deleted and unloaded from all nodes; removing the finalizer")
+
+	if err = r.reconHelperAPI.removeFinalizer(ctx, mod); err != nil {
+		return ctrl.Result{}, fmt.Errorf("could not remove the finalizer of module %s: %v", mod.Name, err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileKernelVersion builds, signs and deploys the module for a single kernel version and architecture.
+// nodes are the nodes running that kernel version and architecture, and previous is the last recorded status for them,
+// if any.
+// Once the image is available, mld.ContainerImage is pinned to the digest of the image.
+// The returned status is always valid, even if an error is returned.
+func (r *ModuleReconciler) reconcileKernelVersion(
+	ctx context.Context,
+	mld *api.ModuleLoaderData,
+	previous *kmmv1beta1.KernelVersionStatus,
+	dsByKernelAndArch map[string]*appsv1.DaemonSet,
+	nodes []v1.Node) (kmmv1beta1.KernelVersionStatus, error) {
+
+	key := daemonset.KernelArchKey(mld.KernelVersion, mld.Arch)
+
+	kvStatus := kmmv1beta1.KernelVersionStatus{
+		KernelVersion:  mld.KernelVersion,
+		Arch:           mld.Arch,
+		ContainerImage: mld.ContainerImage,
+	}
+
+	mldLogger := log.FromContext(ctx).WithValues(
+		"kernel version", mld.KernelVersion,
+		"arch", mld.Arch,
+		"mld", mld,
+	)
+
+	buildPhase, err := r.reconHelperAPI.handleBuild(ctx, mld)
+	kvStatus.BuildPhase = buildPhase
+	if err != nil {
+		kvStatus.BuildPhase = kmmv1beta1.StagePhaseFailed
+		kvStatus.Message = err.Error()
+		kvStatus.Failure = r.diagnoseFailure(ctx, mld, kmmv1beta1.StageBuild, err, previous)
+		return kvStatus, fmt.Errorf("failed to handle build for kernel %s: %v", key, err)
+	}
+	if buildPhase == kmmv1beta1.StagePhaseFailed || buildPhase == kmmv1beta1.StagePhaseRetrying {
+		kvStatus.Failure = r.diagnoseFailure(ctx, mld, kmmv1beta1.StageBuild, nil, previous)
+		kvStatus.Message = fmt.Sprintf("build failed: %s: %s", kvStatus.Failure.Reason, kvStatus.Failure.Message)
+		if buildPhase == kmmv1beta1.StagePhaseRetrying {
+			kvStatus.Message += "; retrying"
+		}
+		return kvStatus, nil
+	}
+	if buildPhase == kmmv1beta1.StagePhaseQueued {
+		kvStatus.QueuePosition = int32(r.schedulerAPI.Position(scheduler.WorkloadFor(mld, utils.JobTypeBuild)))
+		kvStatus.Message = fmt.Sprintf("build queued at position %d", kvStatus.QueuePosition)
+		return kvStatus, nil
+	}
+	if !isStageDone(buildPhase) {
+		mldLogger.Info("Build has not finished successfully yet:skipping handling signing and driver container for now")
+		kvStatus.Message = "waiting for the build to complete"
+		return kvStatus, nil
+	}
+
+	signPhase, err := r.reconHelperAPI.handleSigning(ctx, mld)
+	kvStatus.SignPhase = signPhase
+	if err != nil {
+		kvStatus.SignPhase = kmmv1beta1.StagePhaseFailed
+		kvStatus.Message = err.Error()
+		kvStatus.Failure = r.diagnoseFailure(ctx, mld, kmmv1beta1.StageSign, err, previous)
+		return kvStatus, fmt.Errorf("failed to handle signing for kernel %s: %v", key, err)
+	}
+	if signPhase == kmmv1beta1.StagePhaseFailed || signPhase == kmmv1beta1.StagePhaseRetrying {
+		kvStatus.Failure = r.diagnoseFailure(ctx, mld, kmmv1beta1.StageSign, nil, previous)
+		kvStatus.Message = fmt.Sprintf("signing failed: %s: %s", kvStatus.Failure.Reason, kvStatus.Failure.Message)
+		if signPhase == kmmv1beta1.StagePhaseRetrying {
+			kvStatus.Message += "; retrying"
+		}
+		return kvStatus, nil
+	}
+	if signPhase == kmmv1beta1.StagePhaseQueued {
+		kvStatus.QueuePosition = int32(r.schedulerAPI.Position(scheduler.WorkloadFor(mld, utils.JobTypeSign)))
+		kvStatus.Message = fmt.Sprintf("signing queued at position %d", kvStatus.QueuePosition)
+		return kvStatus, nil
+	}
+	if !isStageDone(signPhase) {
+		mldLogger.Info("Signing has not finished successfully yet; skipping handling driver container for now")
+		kvStatus.Message = "waiting for signing to complete"
+		return kvStatus, nil
+	}
+
+	imageDigest, err := r.reconHelperAPI.resolveImageDigest(ctx, mld, previous)
+	kvStatus.ImageDigest = imageDigest
+	if err != nil {
+		kvStatus.Message = err.Error()
+		return kvStatus, fmt.Errorf("failed to resolve the digest of the image for kernel %s: %v", key, err)
+	}
+
+	if imageDigest != nil {
+		mld.ContainerImage = mld.ContainerImage + "@" + imageDigest.Digest
+	}
+
+	if mld.LoadMode == kmmv1beta1.LoadModeJob {
+		loadResult, err := r.reconHelperAPI.handleLoadJobs(ctx, mld, nodes)
+		if err != nil {
+			kvStatus.Message = err.Error()
+			return kvStatus, fmt.Errorf("failed to handle the load Jobs for kernel %s: %v", key, err)
+		}
+
+		if len(loadResult.FailedNodes) > 0 {
+			kvStatus.FailedNodes = loadResult.FailedNodes
+		}
+
+		kvStatus.Message = fmt.Sprintf("module loaded on %d/%d nodes", len(loadResult.LoadedNodes), len(nodes))
+
+		if err = r.reconHelperAPI.recordLoadResult(ctx, mld, nodes, loadResult); err != nil {
+			return kvStatus, fmt.Errorf("failed to record the load results for kernel %s: %v", key, err)
+		}
+
+		return kvStatus, nil
+	}
+
+	ds, err := r.reconHelperAPI.handleDriverContainer(ctx, mld, dsByKernelAndArch)
+	if err != nil {
+		kvStatus.Message = err.Error()
+		return kvStatus, fmt.Errorf("failed to handle driver container for kernel %s: %v", key, err)
+	}
+	kvStatus.DaemonSetName = ds.Name
+
+	upgradeStatus, err := r.reconHelperAPI.handleUpgrade(ctx, ds, mld)
+	kvStatus.Upgrade = upgradeStatus
+	if err != nil {
+		kvStatus.Message = err.Error()
+		return kvStatus, fmt.Errorf("failed to handle the upgrade for kernel %s: %v", key, err)
+	}
+
+	if upgradeStatus != nil && upgradeStatus.Phase != kmmv1beta1.UpgradePhaseCompleted {
+		kvStatus.Message = fmt.Sprintf("node-by-node upgrade phase: %s", upgradeStatus.Phase)
+		return kvStatus, nil
+	}
+
+	kvStatus.Message = "module loader DaemonSet is up to date"
+
+	return kvStatus, nil
+}
+
+// diagnoseFailure returns the diagnosis of the failed stage for mld.
+// Errors are only logged, as the failure itself is already reported.
+func (r *ModuleReconciler) diagnoseFailure(
+	ctx context.Context,
+	mld *api.ModuleLoaderData,
+	stage kmmv1beta1.Stage,
+	cause error,
+	previous *kmmv1beta1.KernelVersionStatus) *kmmv1beta1.StageFailure {
+
+	var previousFailure *kmmv1beta1.StageFailure
+
+	if previous != nil {
+		previousFailure = previous.Failure
+	}
+
+	failure, err := r.reconHelperAPI.diagnoseFailure(ctx, mld, stage, cause, previousFailure)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Could not diagnose the failure", "stage", stage, "kernel version", mld.KernelVersion)
+	}
+
+	return failure
+}
+
+// isStageDone returns true if the build or sign stage does not prevent the next one from running.
+func isStageDone(phase kmmv1beta1.StagePhase) bool {
+	return phase == kmmv1beta1.StagePhaseCompleted || phase == kmmv1beta1.StagePhaseNotRequired
+}
+
+// findKernelVersionStatus returns the status for kernelVersion and arch in statuses, or nil.
+func findKernelVersionStatus(statuses []kmmv1beta1.KernelVersionStatus, kernelVersion, arch string) *kmmv1beta1.KernelVersionStatus {
+	for i := range statuses {
+		if statuses[i].KernelVersion == kernelVersion && statuses[i].Arch == arch {
+			return &statuses[i]
+		}
+	}
+
+	return nil
+}
+
+// imageDigestInterval returns how often the tag of the image of mld is resolved again, or 0 if it is only resolved
+// again when the spec of the Module changes.
+func imageDigestInterval(mld *api.ModuleLoaderData) time.Duration {
+	idr := mld.ImageDigestResolution
+	if idr == nil || idr.Policy != kmmv1beta1.ImageDigestPolicyPeriodic {
+		return 0
+	}
+
+	if idr.Interval != nil && idr.Interval.Duration > 0 {
+		return idr.Interval.Duration
+	}
+
+	return defaultImageDigestInterval
+}
+
+// shortestRequeue returns the shortest positive delay out of current and d; 0 means no requeue.
+func shortestRequeue(current, d time.Duration) time.Duration {
+	if d <= 0 || (current > 0 && current < d) {
+		return current
+	}
+
+	return d
+}
+
+// adoptDaemonSetsWithoutArch re-keys the module loader DaemonSets created before they were labeled with the
+// architecture to the ModuleLoaderData of their kernel version, so that they are updated instead of being
+// garbage-collected and recreated.
+// If nodes of several architectures run that kernel version, the first architecture in lexical order adopts the
+// DaemonSet.
+func adoptDaemonSetsWithoutArch(ctx context.Context,
+	dsByKernelAndArch map[string]*appsv1.DaemonSet,
+	mldMappings map[string]*api.ModuleLoaderData) {
+	for _, key := range sets.StringKeySet(mldMappings).List() {
+		mld := mldMappings[key]
+		if mld.Arch == "" || dsByKernelAndArch[key] != nil {
+			continue
+		}
+
+		ds := dsByKernelAndArch[mld.KernelVersion]
+		if ds == nil {
+			continue
+		}
+
+		log.FromContext(ctx).Info(
+			"Adopting DaemonSet without an architecture label",
+			"name", ds.Name,
+			"kernel version", mld.KernelVersion,
+			"arch", mld.Arch,
+		)
+
+		dsByKernelAndArch[key] = ds
+		delete(dsByKernelAndArch, mld.KernelVersion)
+	}
+}
+
+// unmappedKernelVersions returns a status for each kernel version and architecture of the targeted nodes for which
+// no ModuleLoaderData could be computed.
+func unmappedKernelVersions(targetedNodes []v1.Node, mldMappings map[string]*api.ModuleLoaderData) []kmmv1beta1.KernelVersionStatus {
+	unmapped := make([]kmmv1beta1.KernelVersionStatus, 0)
+	seen := sets.NewString()
+
+	for _, node := range targetedNodes {
+		kernelVersion := strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+")
+		arch := node.Status.NodeInfo.Architecture
+		key := daemonset.KernelArchKey(kernelVersion, arch)
+
+		if _, ok := mldMappings[key]; ok || seen.Has(key) {
+			continue
+		}
+
+		seen.Insert(key)
+		unmapped = append(unmapped, kmmv1beta1.KernelVersionStatus{KernelVersion: kernelVersion, Arch: arch})
+	}
+
+	return unmapped
+}
+
 //go:generate mockgen -source=module_reconciler.go -package=controllers -destination=mock_module_reconciler.go moduleReconcilerHelperAPI
 
 type moduleReconcilerHelperAPI interface {
@@ -196,19 +642,41 @@ type moduleReconcilerHelperAPI interface {
 	setKMMOMetrics(ctx context.Context)
 	getNodesListBySelector(ctx context.Context, mod *kmmv1beta1.Module) ([]v1.Node, error)
 	getRelevantKernelMappingsAndNodes(ctx context.Context, mod *kmmv1beta1.Module, targetedNodes []v1.Node) (map[string]*api.ModuleLoaderData, []v1.Node, error)
-	handleBuild(ctx context.Context, mld *api.ModuleLoaderData) (bool, error)
-	handleSigning(ctx context.Context, mld *api.ModuleLoaderData) (bool, error)
-	handleDriverContainer(ctx context.Context, mld *api.ModuleLoaderData, dsByKernelVersion map[string]*appsv1.DaemonSet) error
+	getDependentModules(ctx context.Context, mod *kmmv1beta1.Module) ([]string, error)
+	handleBuild(ctx context.Context, mld *api.ModuleLoaderData) (kmmv1beta1.StagePhase, error)
+	handleSigning(ctx context.Context, mld *api.ModuleLoaderData) (kmmv1beta1.StagePhase, error)
+	diagnoseFailure(ctx context.Context, mld *api.ModuleLoaderData, stage kmmv1beta1.Stage, cause error, previous *kmmv1beta1.StageFailure) (*kmmv1beta1.StageFailure, error)
+	resolveImageDigest(ctx context.Context, mld *api.ModuleLoaderData, previous *kmmv1beta1.KernelVersionStatus) (*kmmv1beta1.ImageDigestStatus, error)
+	handleDriverContainer(ctx context.Context, mld *api.ModuleLoaderData, dsByKernelAndArch map[string]*appsv1.DaemonSet) (*appsv1.DaemonSet, error)
+	handleUpgrade(ctx context.Context, ds *appsv1.DaemonSet, mld *api.ModuleLoaderData) (*kmmv1beta1.ModuleUpgradeStatus, error)
+	handleLoadJobs(ctx context.Context, mld *api.ModuleLoaderData, nodes []v1.Node) (*loadjob.Result, error)
+	handleUnloadJobs(ctx context.Context, mod *kmmv1beta1.Module, nodesToKeep []v1.Node, dependents []string) ([]string, error)
+	handleBootLoading(ctx context.Context, mod *kmmv1beta1.Module, mlds []*api.ModuleLoaderData) error
+	deleteBootLoading(ctx context.Context, mod *kmmv1beta1.Module) error
+	deleteModuleDaemonSets(ctx context.Context, mod *kmmv1beta1.Module) error
+	handleUnload(ctx context.Context, mod *kmmv1beta1.Module, dependents []string) ([]string, error)
+	recordLoadResult(ctx context.Context, mld *api.ModuleLoaderData, nodes []v1.Node, res *loadjob.Result) error
+	syncNodeModulesConfigs(ctx context.Context, mod *kmmv1beta1.Module, mldMappings map[string]*api.ModuleLoaderData, nodes []v1.Node) error
+	recordUnloadProgress(ctx context.Context, mod *kmmv1beta1.Module, unloadingNodes []string) error
+	setFinalizer(ctx context.Context, mod *kmmv1beta1.Module) error
+	removeFinalizer(ctx context.Context, mod *kmmv1beta1.Module) error
 	handleDevicePlugin(ctx context.Context, mod *kmmv1beta1.Module) error
-	garbageCollect(ctx context.Context, mod *kmmv1beta1.Module, mldMappings map[string]*api.ModuleLoaderData, existingDS map[string]*appsv1.DaemonSet) error
+	garbageCollect(ctx context.Context, mod *kmmv1beta1.Module, mldMappings map[string]*api.ModuleLoaderData, existingDS map[string]*appsv1.DaemonSet, dependents []string) error
 }
 
 type moduleReconcilerHelper struct {
 	client            client.Client
 	buildAPI          build.Manager
 	signAPI           sign.SignManager
+	diagnosticsAPI    diagnostics.Diagnoser
 	daemonAPI         daemonset.DaemonSetCreator
+	upgradeAPI        upgrade.Upgrader
+	loadAPI           loadjob.Manager
+	machineConfigAPI  machineconfig.Manager
 	kernelAPI         module.KernelMapper
+	nmcHelper         nmc.Helper
+	registryAPI       registry.Registry
+	authFactory       auth.RegistryAuthGetterFactory
 	metricsAPI        metrics.Metrics
 	operatorNamespace string
 }
@@ -216,21 +684,37 @@ type moduleReconcilerHelper struct {
 func newModuleReconcilerHelper(client client.Client,
 	buildAPI build.Manager,
 	signAPI sign.SignManager,
+	diagnosticsAPI diagnostics.Diagnoser,
 	daemonAPI daemonset.DaemonSetCreator,
+	upgradeAPI upgrade.Upgrader,
+	loadAPI loadjob.Manager,
+	machineConfigAPI machineconfig.Manager,
 	kernelAPI module.KernelMapper,
+	nmcHelper nmc.Helper,
+	registryAPI registry.Registry,
+	authFactory auth.RegistryAuthGetterFactory,
 	metricsAPI metrics.Metrics,
 	operatorNamespace string) moduleReconcilerHelperAPI {
 	return &moduleReconcilerHelper{
 		client:            client,
 		buildAPI:          buildAPI,
 		signAPI:           signAPI,
+		diagnosticsAPI:    diagnosticsAPI,
 		daemonAPI:         daemonAPI,
+		upgradeAPI:        upgradeAPI,
+		loadAPI:           loadAPI,
+		machineConfigAPI:  machineConfigAPI,
 		kernelAPI:         kernelAPI,
+		nmcHelper:         nmcHelper,
+		registryAPI:       registryAPI,
+		authFactory:       authFactory,
 		metricsAPI:        metricsAPI,
 		operatorNamespace: operatorNamespace,
 	}
 }
 
+// getRelevantKernelMappingsAndNodes returns the ModuleLoaderData for each kernel version and architecture of the
+// targeted nodes, keyed by daemonset.KernelArchKey, and the nodes for which one could be computed.
 func (mrh *moduleReconcilerHelper) getRelevantKernelMappingsAndNodes(ctx context.Context,
 	mod *kmmv1beta1.Module,
 	targetedNodes []v1.Node) (map[string]*api.ModuleLoaderData, []v1.Node, error) {
@@ -242,19 +726,22 @@ func (mrh *moduleReconcilerHelper) getRelevantKernelMappingsAndNodes(ctx context
 
 	for _, node := range targetedNodes {
 		kernelVersion := strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+")
+		arch := node.Status.NodeInfo.Architecture
+		key := daemonset.KernelArchKey(kernelVersion, arch)
 
 		nodeLogger := logger.WithValues(
 			"node", node.Name,
 			"kernel version", kernelVersion,
+			"arch", arch,
 		)
 
-		if mld, ok := mldMappings[kernelVersion]; ok {
+		if mld, ok := mldMappings[key]; ok {
 			nodes = append(nodes, node)
 			nodeLogger.V(1).Info("Using cached mld mapping", "mld", mld)
 			continue
 		}
 
-		mld, err := mrh.kernelAPI.GetModuleLoaderDataForKernel(mod, kernelVersion)
+		mld, err := mrh.kernelAPI.GetModuleLoaderDataForKernel(mod, kernelVersion, arch)
 		if err != nil {
 			nodeLogger.Error(err, "failed to get and process kernel mapping")
 			continue
@@ -265,12 +752,36 @@ func (mrh *moduleReconcilerHelper) getRelevantKernelMappingsAndNodes(ctx context
 			"build", mld.Build != nil,
 		)
 
-		mldMappings[kernelVersion] = mld
+		mldMappings[key] = mld
 		nodes = append(nodes, node)
 	}
 	return mldMappings, nodes, nil
 }
 
+// getDependentModules returns the sorted names of the Modules in the same namespace that depend on mod.
+func (mrh *moduleReconcilerHelper) getDependentModules(ctx context.Context, mod *kmmv1beta1.Module) ([]string, error) {
+	mods := kmmv1beta1.ModuleList{}
+
+	if err := mrh.client.List(ctx, &mods, client.InNamespace(mod.Namespace)); err != nil {
+		return nil, fmt.Errorf("could not list modules: %v", err)
+	}
+
+	dependents := make([]string, 0)
+
+	for _, m := range mods.Items {
+		for _, dep := range m.Spec.DependsOn {
+			if dep == mod.Name {
+				dependents = append(dependents, m.Name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(dependents)
+
+	return dependents, nil
+}
+
 func (mrh *moduleReconcilerHelper) getNodesListBySelector(ctx context.Context, mod *kmmv1beta1.Module) ([]v1.Node, error) {
 	logger := log.FromContext(ctx)
 	logger.V(1).Info("Listing nodes", "selector", mod.Spec.Selector)
@@ -284,22 +795,27 @@ func (mrh *moduleReconcilerHelper) getNodesListBySelector(ctx context.Context, m
 	nodes := make([]v1.Node, 0, len(selectedNodes.Items))
 
 	for _, node := range selectedNodes.Items {
-		if isNodeSchedulable(&node) {
+		// nodes cordoned for a node-by-node upgrade of this module are still targeted
+		if isNodeSchedulable(&node, mod.Spec.ModuleLoader.Tolerations) || upgrade.IsNodeUpgrading(&node, mod.Name) {
 			nodes = append(nodes, node)
 		}
 	}
 	return nodes, nil
 }
 
-// handleBuild returns true if build is not needed or finished successfully
-func (mrh *moduleReconcilerHelper) handleBuild(ctx context.Context, mld *api.ModuleLoaderData) (bool, error) {
+// handleBuild returns the phase of the build for mld.
+// The build is not required if it is not configured, and completed if the image already exists.
+func (mrh *moduleReconcilerHelper) handleBuild(ctx context.Context, mld *api.ModuleLoaderData) (kmmv1beta1.StagePhase, error) {
 
 	shouldSync, err := mrh.buildAPI.ShouldSync(ctx, mld)
 	if err != nil {
-		return false, fmt.Errorf("could not check if build synchronization is needed: %w", err)
+		return "", fmt.Errorf("could not check if build synchronization is needed: %w", err)
 	}
 	if !shouldSync {
-		return true, nil
+		if module.ShouldBeBuilt(mld) {
+			return kmmv1beta1.StagePhaseCompleted, nil
+		}
+		return kmmv1beta1.StagePhaseNotRequired, nil
 	}
 
 	logger := log.FromContext(ctx).WithValues("kernel version", mld.KernelVersion, "image", mld.ContainerImage)
@@ -307,28 +823,33 @@ func (mrh *moduleReconcilerHelper) handleBuild(ctx context.Context, mld *api.Mod
 
 	buildStatus, err := mrh.buildAPI.Sync(buildCtx, mld, true, mld.Owner)
 	if err != nil {
-		return false, fmt.Errorf("could not synchronize the build: %w", err)
+		return "", fmt.Errorf("could not synchronize the build: %w", err)
 	}
 
-	completedSuccessfully := false
 	switch buildStatus {
-	case utils.StatusCompleted:
-		completedSuccessfully = true
 	case utils.StatusFailed:
-		logger.Info(utils.WarnString("Build job has failed. If the fix is not in Module CR, then delete job after the fix in order to restart the job"))
+		logger.Info(utils.WarnString("Build job has failed and will not be retried. If the fix is not in Module CR, then delete job after the fix in order to restart the job"))
+	case utils.StatusRetrying:
+		logger.Info("Build job has failed; waiting for the backoff to elapse before retrying")
+	case utils.StatusQueued:
+		logger.Info("Build is queued until the number of concurrent builds falls below the limit")
 	}
 
-	return completedSuccessfully, nil
+	return stagePhaseFromStatus(buildStatus), nil
 }
 
-// handleSigning returns true if signing is not needed or finished successfully
-func (mrh *moduleReconcilerHelper) handleSigning(ctx context.Context, mld *api.ModuleLoaderData) (bool, error) {
+// handleSigning returns the phase of the signing for mld.
+// Signing is not required if it is not configured, and completed if the image already exists.
+func (mrh *moduleReconcilerHelper) handleSigning(ctx context.Context, mld *api.ModuleLoaderData) (kmmv1beta1.StagePhase, error) {
 	shouldSync, err := mrh.signAPI.ShouldSync(ctx, mld)
 	if err != nil {
-		return false, fmt.Errorf("cound not check if synchronization is needed: %w", err)
+		return "", fmt.Errorf("cound not check if synchronization is needed: %w", err)
 	}
 	if !shouldSync {
-		return true, nil
+		if module.ShouldBeSigned(mld) {
+			return kmmv1beta1.StagePhaseCompleted, nil
+		}
+		return kmmv1beta1.StagePhaseNotRequired, nil
 	}
 
 	// if we need to sign AND we've built, then we must have built the intermediate image so must figure out its name
@@ -342,45 +863,386 @@ func (mrh *moduleReconcilerHelper) handleSigning(ctx context.Context, mld *api.M
 
 	signStatus, err := mrh.signAPI.Sync(signCtx, mld, previousImage, true, mld.Owner)
 	if err != nil {
-		return false, fmt.Errorf("could not synchronize the signing: %w", err)
+		return "", fmt.Errorf("could not synchronize the signing: %w", err)
 	}
 
-	completedSuccessfully := false
 	switch signStatus {
+	case utils.StatusFailed:
+		logger.Info(utils.WarnString("Sign job has failed and will not be retried. If the fix is not in Module CR, then delete job after the fix in order to restart the job"))
+	case utils.StatusRetrying:
+		logger.Info("Sign job has failed; waiting for the backoff to elapse before retrying")
+	case utils.StatusQueued:
+		logger.Info("Sign job is queued until the number of concurrent builds falls below the limit")
+	}
+
+	return stagePhaseFromStatus(signStatus), nil
+}
+
+// diagnoseFailure captures and classifies the log of the failed stage for mld.
+func (mrh *moduleReconcilerHelper) diagnoseFailure(
+	ctx context.Context,
+	mld *api.ModuleLoaderData,
+	stage kmmv1beta1.Stage,
+	cause error,
+	previous *kmmv1beta1.StageFailure) (*kmmv1beta1.StageFailure, error) {
+	return mrh.diagnosticsAPI.Diagnose(ctx, mld, stage, cause, previous)
+}
+
+func stagePhaseFromStatus(status utils.Status) kmmv1beta1.StagePhase {
+	switch status {
 	case utils.StatusCompleted:
-		completedSuccessfully = true
+		return kmmv1beta1.StagePhaseCompleted
 	case utils.StatusFailed:
-		logger.Info(utils.WarnString("Sign job has failed. If the fix is not in Module CR, then delete job after the fix in order to restart the job"))
+		return kmmv1beta1.StagePhaseFailed
+	case utils.StatusRetrying:
+		return kmmv1beta1.StagePhaseRetrying
+	case utils.StatusQueued:
+		return kmmv1beta1.StagePhaseQueued
+	default:
+		return kmmv1beta1.StagePhaseInProgress
+	}
+}
+
+// resolveImageDigest returns the digest to which the tag of the image of mld resolves.
+// The digest recorded in previous is kept if it was resolved for the same image and generation of the Module, unless
+// it is older than the interval of the Periodic policy.
+// It returns nil if the image is already referenced by digest.
+// If the tag cannot be resolved, the digest that was kept, if any, is returned along with the error.
+func (mrh *moduleReconcilerHelper) resolveImageDigest(ctx context.Context,
+	mld *api.ModuleLoaderData,
+	previous *kmmv1beta1.KernelVersionStatus) (*kmmv1beta1.ImageDigestStatus, error) {
+	if strings.Contains(mld.ContainerImage, "@") {
+		return nil, nil
+	}
+
+	generation := mld.Owner.GetGeneration()
+
+	var current *kmmv1beta1.ImageDigestStatus
+
+	if previous != nil && previous.ContainerImage == mld.ContainerImage {
+		current = previous.ImageDigest
+	}
+
+	if current != nil && current.ModuleGeneration == generation {
+		interval := imageDigestInterval(mld)
+
+		if interval == 0 || time.Since(current.ResolvedAt.Time) < interval {
+			return current, nil
+		}
+	}
+
+	digest, err := module.ImageDigest(ctx, mrh.authFactory, mrh.registryAPI, mld, mld.ContainerImage)
+	if err != nil {
+		return current, err
+	}
+
+	if current == nil || current.Digest != digest {
+		log.FromContext(ctx).Info("Resolved the module loader image", "image", mld.ContainerImage, "digest", digest)
 	}
 
-	return completedSuccessfully, nil
+	return &kmmv1beta1.ImageDigestStatus{
+		Digest:           digest,
+		ResolvedAt:       metav1.Now(),
+		ModuleGeneration: generation,
+	}, nil
 }
 
+// handleDriverContainer creates or updates the module loader DaemonSet for mld and returns it.
 func (mrh *moduleReconcilerHelper) handleDriverContainer(ctx context.Context,
 	mld *api.ModuleLoaderData,
-	dsByKernelVersion map[string]*appsv1.DaemonSet) error {
+	dsByKernelAndArch map[string]*appsv1.DaemonSet) (*appsv1.DaemonSet, error) {
 	ds := &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{Namespace: mld.Namespace},
 	}
 
-	logger := log.FromContext(ctx)
-	if existingDS := dsByKernelVersion[mld.KernelVersion]; existingDS != nil {
-		logger.Info("updating existing driver container DS", "kernel version", mld.KernelVersion, "image", mld.ContainerImage, "name", ds.Name)
+	logger := log.FromContext(ctx).WithValues("kernel version", mld.KernelVersion, "arch", mld.Arch, "image", mld.ContainerImage)
+	if existingDS := dsByKernelAndArch[daemonset.KernelArchKey(mld.KernelVersion, mld.Arch)]; existingDS != nil {
+		logger.Info("updating existing driver container DS", "name", existingDS.Name)
 		ds = existingDS
 	} else {
-		logger.Info("creating new driver container DS", "kernel version", mld.KernelVersion, "image", mld.ContainerImage)
+		logger.Info("creating new driver container DS")
 		ds.GenerateName = mld.Name + "-"
 	}
 
 	opRes, err := controllerutil.CreateOrPatch(ctx, mrh.client, ds, func() error {
-		return mrh.daemonAPI.SetDriverContainerAsDesired(ctx, ds, mld, mld.Namespace == mrh.operatorNamespace)
+		useDefaultSA := mld.Namespace == mrh.operatorNamespace
+
+		if upgrade.IsNodeByNode(mld) {
+			return mrh.upgradeAPI.SetDriverContainerAsDesired(ctx, ds, mld, useDefaultSA)
+		}
+
+		return mrh.daemonAPI.SetDriverContainerAsDesired(ctx, ds, mld, useDefaultSA)
 	})
 
-	if err == nil {
-		logger.Info("Reconciled Driver Container", "name", ds.Name, "result", opRes)
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	logger.Info("Reconciled Driver Container", "name", ds.Name, "result", opRes)
+
+	return ds, nil
+}
+
+// handleUpgrade upgrades the module loader pods of ds node by node if mld uses the NodeByNode upgrade strategy.
+// It returns nil if mld uses another strategy.
+func (mrh *moduleReconcilerHelper) handleUpgrade(ctx context.Context,
+	ds *appsv1.DaemonSet,
+	mld *api.ModuleLoaderData) (*kmmv1beta1.ModuleUpgradeStatus, error) {
+	if !upgrade.IsNodeByNode(mld) {
+		return nil, nil
+	}
+
+	return mrh.upgradeAPI.Upgrade(ctx, ds, mld)
+}
+
+// handleLoadJobs loads the kernel module of mld on nodes with one-shot Jobs.
+func (mrh *moduleReconcilerHelper) handleLoadJobs(ctx context.Context, mld *api.ModuleLoaderData, nodes []v1.Node) (*loadjob.Result, error) {
+	return mrh.loadAPI.Load(ctx, mld, nodes)
+}
+
+// handleUnloadJobs unloads the kernel module of mod from all nodes except nodesToKeep, and returns the nodes on which it
+// may still be loaded.
+func (mrh *moduleReconcilerHelper) handleUnloadJobs(ctx context.Context,
+	mod *kmmv1beta1.Module,
+	nodesToKeep []v1.Node,
+	dependents []string) ([]string, error) {
+	keep := sets.NewString()
+
+	for _, node := range nodesToKeep {
+		keep.Insert(node.Name)
+	}
+
+	return mrh.loadAPI.Unload(ctx, mod, keep, dependents)
+}
+
+// handleBootLoading renders the MachineConfig loading the kernel module at boot from the images in mlds, or deletes it
+// if the Module does not enable boot loading.
+func (mrh *moduleReconcilerHelper) handleBootLoading(ctx context.Context, mod *kmmv1beta1.Module, mlds []*api.ModuleLoaderData) error {
+	return mrh.machineConfigAPI.Sync(ctx, mod, mlds)
+}
+
+// deleteBootLoading deletes the MachineConfig loading the kernel module of mod at boot.
+func (mrh *moduleReconcilerHelper) deleteBootLoading(ctx context.Context, mod *kmmv1beta1.Module) error {
+	return mrh.machineConfigAPI.Delete(ctx, mod)
+}
+
+// deleteModuleDaemonSets deletes the ModuleLoader and device plugin DaemonSets of mod.
+func (mrh *moduleReconcilerHelper) deleteModuleDaemonSets(ctx context.Context, mod *kmmv1beta1.Module) error {
+	dsByKernelAndArch, err := mrh.daemonAPI.ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace)
+	if err != nil {
+		return fmt.Errorf("could not get the DaemonSets: %v", err)
+	}
+
+	for _, ds := range dsByKernelAndArch {
+		if !ds.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if err = mrh.client.Delete(ctx, ds); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("could not delete DaemonSet %s: %v", ds.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// handleUnload makes sure that the kernel module of the deleted mod is unloaded from the nodes on which none of its
+// pods are running anymore, and returns the nodes on which it may still be loaded.
+// The kernel module is unloaded again with a Job from nodes that are still labeled as ready once their ModuleLoader
+// pod is gone, as the pod may have failed to unload it.
+func (mrh *moduleReconcilerHelper) handleUnload(ctx context.Context, mod *kmmv1beta1.Module, dependents []string) ([]string, error) {
+	logger := log.FromContext(ctx)
+
+	nodeList := v1.NodeList{}
+
+	if err := mrh.client.List(ctx, &nodeList); err != nil {
+		return nil, fmt.Errorf("could not list nodes: %v", err)
+	}
+
+	podList := v1.PodList{}
+
+	opts := []client.ListOption{
+		client.InNamespace(mod.Namespace),
+		client.MatchingLabels{constants.ModuleNameLabel: mod.Name},
+	}
+
+	if err := mrh.client.List(ctx, &podList, opts...); err != nil {
+		return nil, fmt.Errorf("could not list the pods of module %s: %v", mod.Name, err)
+	}
+
+	nodesWithPods := sets.NewString()
+
+	for _, pod := range podList.Items {
+		nodesWithPods.Insert(pod.Spec.NodeName)
+	}
+
+	readyLabel := daemonset.GetDriverContainerNodeLabel(mod.Name)
+	remaining := make([]string, 0)
+	nodes := make([]v1.Node, 0, len(nodeList.Items))
+	mldByNode := make(map[string]*api.ModuleLoaderData)
+
+	for _, node := range nodeList.Items {
+		if nodesWithPods.Has(node.Name) {
+			remaining = append(remaining, node.Name)
+			continue
+		}
+
+		nodes = append(nodes, node)
+
+		if _, ok := node.Labels[readyLabel]; !ok {
+			continue
+		}
+
+		kernelVersion := strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+")
+
+		mld, err := mrh.kernelAPI.GetModuleLoaderDataForKernel(mod, kernelVersion, node.Status.NodeInfo.Architecture)
+		if err != nil {
+			logger.Info("Could not get the kernel mapping of the node", "node", node.Name, "error", err)
+			continue
+		}
+
+		mldByNode[node.Name] = mld
+	}
+
+	unloading, err := mrh.loadAPI.UnloadNodes(ctx, mod, nodes, mldByNode, dependents)
+
+	return append(remaining, unloading...), err
+}
+
+// recordLoadResult records the state of the kernel module of mld on nodes in their NodeModulesConfig.
+func (mrh *moduleReconcilerHelper) recordLoadResult(ctx context.Context, mld *api.ModuleLoaderData, nodes []v1.Node, res *loadjob.Result) error {
+	loaded := sets.NewString(res.LoadedNodes...)
+	failed := sets.NewString(res.FailedNodes...)
+	errs := make([]error, 0)
+
+	for _, node := range nodes {
+		st := nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateLoading}
+
+		switch {
+		case loaded.Has(node.Name):
+			st = nmc.ModuleState{
+				State:          kmmv1beta1.ModuleLoadStateLoaded,
+				KernelVersion:  mld.KernelVersion,
+				ContainerImage: mld.ContainerImage,
+			}
+		case failed.Has(node.Name):
+			st = nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateFailed, Error: res.Errors[node.Name]}
+		}
+
+		if err := mrh.nmcHelper.SetState(ctx, node.Name, mld.Namespace, mld.Name, st); err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", node.Name, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// syncNodeModulesConfigs records the kernel version and image with which mod should be loaded in the NodeModulesConfig
+// of nodes, the nodes with a kernel mapping.
+// The other nodes on which mod is recorded are marked as Unloading while they are still labeled as ready, and are
+// removed from their NodeModulesConfig afterwards.
+func (mrh *moduleReconcilerHelper) syncNodeModulesConfigs(ctx context.Context,
+	mod *kmmv1beta1.Module,
+	mldMappings map[string]*api.ModuleLoaderData,
+	nodes []v1.Node) error {
+	recorded, err := mrh.nmcHelper.ModuleNodes(ctx, mod.Namespace, mod.Name)
+	if err != nil {
+		return fmt.Errorf("could not get the nodes on which module %s is recorded: %v", mod.Name, err)
+	}
+
+	errs := make([]error, 0)
+
+	for _, node := range nodes {
+		recorded.Delete(node.Name)
+
+		mld := mldMappings[daemonset.KernelArchKey(strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+"), node.Status.NodeInfo.Architecture)]
+
+		if err = mrh.nmcHelper.SetDesired(ctx, node.Name, mod.Namespace, mod.Name, mld.KernelVersion, mld.ContainerImage); err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", node.Name, err))
+		}
+	}
+
+	readyLabel := daemonset.GetDriverContainerNodeLabel(mod.Name)
+
+	for _, nodeName := range recorded.List() {
+		node := v1.Node{}
+
+		if err = mrh.client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); client.IgnoreNotFound(err) != nil {
+			errs = append(errs, fmt.Errorf("could not get node %s: %v", nodeName, err))
+			continue
+		}
+
+		if _, ok := node.Labels[readyLabel]; ok {
+			err = mrh.setUnloading(ctx, nodeName, mod)
+		} else {
+			err = mrh.nmcHelper.RemoveModule(ctx, nodeName, mod.Namespace, mod.Name)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", nodeName, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// recordUnloadProgress marks the deleted mod as Unloading in the NodeModulesConfig of unloadingNodes, and removes it
+// from the NodeModulesConfig of the nodes from which it was unloaded.
+func (mrh *moduleReconcilerHelper) recordUnloadProgress(ctx context.Context, mod *kmmv1beta1.Module, unloadingNodes []string) error {
+	recorded, err := mrh.nmcHelper.ModuleNodes(ctx, mod.Namespace, mod.Name)
+	if err != nil {
+		return fmt.Errorf("could not get the nodes on which module %s is recorded: %v", mod.Name, err)
+	}
+
+	unloading := sets.NewString(unloadingNodes...)
+	errs := make([]error, 0)
+
+	for _, nodeName := range recorded.Union(unloading).List() {
+		if unloading.Has(nodeName) {
+			err = mrh.setUnloading(ctx, nodeName, mod)
+		} else {
+			err = mrh.nmcHelper.RemoveModule(ctx, nodeName, mod.Namespace, mod.Name)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", nodeName, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func (mrh *moduleReconcilerHelper) setUnloading(ctx context.Context, nodeName string, mod *kmmv1beta1.Module) error {
+	if err := mrh.nmcHelper.SetDesired(ctx, nodeName, mod.Namespace, mod.Name, "", ""); err != nil {
+		return err
+	}
+
+	return mrh.nmcHelper.SetState(ctx, nodeName, mod.Namespace, mod.Name, nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateUnloading})
+}
+
+// setFinalizer adds the finalizer that keeps mod until its kernel module is unloaded from all nodes.
+func (mrh *moduleReconcilerHelper) setFinalizer(ctx context.Context, mod *kmmv1beta1.Module) error {
+	if controllerutil.ContainsFinalizer(mod, constants.ModuleFinalizer) {
+		return nil
+	}
+
+	modCopy := mod.DeepCopy()
+
+	controllerutil.AddFinalizer(mod, constants.ModuleFinalizer)
+
+	return mrh.client.Patch(ctx, mod, client.MergeFrom(modCopy))
+}
+
+func (mrh *moduleReconcilerHelper) removeFinalizer(ctx context.Context, mod *kmmv1beta1.Module) error {
+	if !controllerutil.ContainsFinalizer(mod, constants.ModuleFinalizer) {
+		return nil
+	}
+
+	modCopy := mod.DeepCopy()
+
+	controllerutil.RemoveFinalizer(mod, constants.ModuleFinalizer)
+
+	return mrh.client.Patch(ctx, mod, client.MergeFrom(modCopy))
 }
 
 func (mrh *moduleReconcilerHelper) handleDevicePlugin(ctx context.Context, mod *kmmv1beta1.Module) error {
@@ -407,12 +1269,31 @@ func (mrh *moduleReconcilerHelper) handleDevicePlugin(ctx context.Context, mod *
 func (mrh *moduleReconcilerHelper) garbageCollect(ctx context.Context,
 	mod *kmmv1beta1.Module,
 	mldMappings map[string]*api.ModuleLoaderData,
-	existingDS map[string]*appsv1.DaemonSet) error {
+	existingDS map[string]*appsv1.DaemonSet,
+	dependents []string) error {
 	logger := log.FromContext(ctx)
 	// Garbage collect old DaemonSets for which there are no nodes.
-	validKernels := sets.StringKeySet(mldMappings)
+	// DaemonSets and mappings are both keyed by kernel version and architecture.
+	validKeys := sets.StringKeySet(mldMappings)
+
+	// Refuse to unload the module from nodes where modules depending on it are still loaded.
+	for key, ds := range existingDS {
+		if validKeys.Has(key) {
+			continue
+		}
+
+		loaded, err := mrh.dependentsLoadedOnNodes(ctx, ds, dependents)
+		if err != nil {
+			return fmt.Errorf("could not check for dependent modules on the nodes of DaemonSet %s: %v", ds.Name, err)
+		}
+
+		if loaded {
+			logger.Info("Not garbage-collecting DaemonSet: dependent modules are still loaded", "name", ds.Name, "dependents", dependents)
+			validKeys.Insert(key)
+		}
+	}
 
-	deleted, err := mrh.daemonAPI.GarbageCollect(ctx, existingDS, validKernels)
+	deleted, err := mrh.daemonAPI.GarbageCollect(ctx, existingDS, validKeys)
 	if err != nil {
 		return fmt.Errorf("could not garbage collect DaemonSets: %v", err)
 	}
@@ -438,6 +1319,29 @@ func (mrh *moduleReconcilerHelper) garbageCollect(ctx context.Context,
 	return nil
 }
 
+// dependentsLoadedOnNodes returns true if any of the dependent modules is ready on a node targeted by ds.
+func (mrh *moduleReconcilerHelper) dependentsLoadedOnNodes(ctx context.Context, ds *appsv1.DaemonSet, dependents []string) (bool, error) {
+	if len(dependents) == 0 {
+		return false, nil
+	}
+
+	nodes := v1.NodeList{}
+
+	if err := mrh.client.List(ctx, &nodes, client.MatchingLabels(ds.Spec.Template.Spec.NodeSelector)); err != nil {
+		return false, fmt.Errorf("could not list nodes: %v", err)
+	}
+
+	for _, node := range nodes.Items {
+		for _, dep := range dependents {
+			if _, ok := node.Labels[daemonset.GetDriverContainerNodeLabel(dep)]; ok {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
 func (mrh *moduleReconcilerHelper) setKMMOMetrics(ctx context.Context) {
 	logger := log.FromContext(ctx)
 
@@ -489,12 +1393,22 @@ func (mrh *moduleReconcilerHelper) getRequestedModule(ctx context.Context, names
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func (r *ModuleReconciler) SetupWithManager(mgr ctrl.Manager, kernelLabel string) error {
-	return ctrl.NewControllerManagedBy(mgr).
+// OpenShift Builds are only watched if ownsOCPBuilds is true, as the API might not be served by the cluster.
+func (r *ModuleReconciler) SetupWithManager(mgr ctrl.Manager, kernelLabel string, ownsOCPBuilds bool) error {
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&kmmv1beta1.Module{}).
 		Owns(&appsv1.DaemonSet{}).
-		Owns(&buildv1.Build{}).
-		Owns(&batchv1.Job{}).
+		Owns(&batchv1.Job{})
+
+	if ownsOCPBuilds {
+		b = b.Owns(&buildv1.Build{})
+	}
+
+	return b.
+		Watches(
+			&source.Kind{Type: &kmmv1beta1.Module{}},
+			handler.EnqueueRequestsFromMapFunc(r.filter.FindModuleDependencies),
+		).
 		Watches(
 			&source.Kind{Type: &v1.Node{}},
 			handler.EnqueueRequestsFromMapFunc(r.filter.FindModulesForNode),
@@ -506,15 +1420,28 @@ func (r *ModuleReconciler) SetupWithManager(mgr ctrl.Manager, kernelLabel string
 		Complete(r)
 }
 
-func isNodeSchedulable(node *v1.Node) bool {
-	for _, taint := range node.Spec.Taints {
-		if taint.Effect == v1.TaintEffectNoSchedule {
+// isNodeSchedulable returns true if all the NoSchedule taints of node are tolerated by tolerations.
+func isNodeSchedulable(node *v1.Node, tolerations []v1.Toleration) bool {
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+
+		if taint.Effect == v1.TaintEffectNoSchedule && !isTaintTolerated(taint, tolerations) {
 			return false
 		}
 	}
 	return true
 }
 
+func isTaintTolerated(taint *v1.Taint, tolerations []v1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func isModuleBuildAndSignCapable(mod *kmmv1beta1.Module) (bool, bool) {
 	buildCapable := mod.Spec.ModuleLoader.Container.Build != nil
 	signCapable := mod.Spec.ModuleLoader.Container.Sign != nil
@@ -4,20 +4,30 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/ca"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/diagnostics"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/loadjob"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/machineconfig"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/metrics"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/nmc"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/statusupdater"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/upgrade"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -26,6 +36,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -40,6 +51,7 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		mockReconHelper *MockmoduleReconcilerHelperAPI
 		mockSU          *statusupdater.MockModuleStatusUpdater
 		mockCAH         *ca.MockHelper
+		mockScheduler   *scheduler.MockScheduler
 		mr              *ModuleReconciler
 	)
 
@@ -49,12 +61,14 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		mockReconHelper = NewMockmoduleReconcilerHelperAPI(ctrl)
 		mockSU = statusupdater.NewMockModuleStatusUpdater(ctrl)
 		mockCAH = ca.NewMockHelper(ctrl)
+		mockScheduler = scheduler.NewMockScheduler(ctrl)
 
 		mr = &ModuleReconciler{
 			daemonAPI:         mockDC,
 			reconHelperAPI:    mockReconHelper,
 			statusUpdaterAPI:  mockSU,
 			caHelper:          mockCAH,
+			schedulerAPI:      mockScheduler,
 			operatorNamespace: "different namespace",
 		}
 	})
@@ -89,6 +103,7 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 
 		gomock.InOrder(
 			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
 			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(fmt.Errorf("some error")),
 		)
 
@@ -99,19 +114,20 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 
 	})
 
-	DescribeTable("check error flows", func(getModuleError, getNodesError, getMappingsError, getDSError, handleBuildError,
-		handleSignError, handleDCError, handlePluginError, gcError bool) {
+	DescribeTable("check error flows", func(getModuleError, getNodesError, getMappingsError, getDependentsError, getDSError, handlePluginError, gcError bool) {
 		mod := kmmv1beta1.Module{}
 		selectNodesList := []v1.Node{v1.Node{}}
 		kernelNodesList := []v1.Node{v1.Node{}}
-		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{}}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{KernelVersion: "kernelVersion"}}
 		kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
+		ds := appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds-name"}}
 		returnedError := fmt.Errorf("some error")
 		if getModuleError {
 			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(nil, returnedError)
 			goto executeTestFunction
 		}
 		mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil)
+		mockReconHelper.EXPECT().setFinalizer(ctx, &mod)
 		mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil)
 		mockReconHelper.EXPECT().setKMMOMetrics(ctx)
 		if getNodesError {
@@ -124,37 +140,34 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 			goto executeTestFunction
 		}
 		mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil)
-		if getDSError {
-			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, mod.Name, mod.Namespace).Return(nil, returnedError)
-			goto executeTestFunction
-		}
-		mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil)
-		if handleBuildError {
-			mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(false, returnedError)
-			goto executeTestFunction
-		}
-		mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(true, nil)
-		if handleSignError {
-			mockReconHelper.EXPECT().handleSigning(ctx, mappings["kernelVersion"]).Return(false, returnedError)
+		if getDependentsError {
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, returnedError)
 			goto executeTestFunction
 		}
-		mockReconHelper.EXPECT().handleSigning(ctx, mappings["kernelVersion"]).Return(true, nil)
-		if handleDCError {
-			mockReconHelper.EXPECT().handleDriverContainer(ctx, mappings["kernelVersion"], kernelByDS).Return(returnedError)
+		mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil)
+		if getDSError {
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(nil, returnedError)
 			goto executeTestFunction
 		}
-		mockReconHelper.EXPECT().handleDriverContainer(ctx, mappings["kernelVersion"], kernelByDS).Return(nil)
+		mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil)
+		mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseNotRequired, nil)
+		mockReconHelper.EXPECT().handleSigning(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseNotRequired, nil)
+		mockReconHelper.EXPECT().resolveImageDigest(ctx, mappings["kernelVersion"], nil)
+		mockReconHelper.EXPECT().handleDriverContainer(ctx, mappings["kernelVersion"], kernelByDS).Return(&ds, nil)
+		mockReconHelper.EXPECT().handleUpgrade(ctx, &ds, mappings["kernelVersion"]).Return(nil, nil)
+		mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{mappings["kernelVersion"]})
+		mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList)
 		if handlePluginError {
 			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(returnedError)
 			goto executeTestFunction
 		}
 		mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil)
 		if gcError {
-			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS).Return(returnedError)
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(returnedError)
 			goto executeTestFunction
 		}
-		mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS).Return(nil)
-		mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS).Return(returnedError)
+		mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil)
+		mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, gomock.Any()).Return(returnedError)
 
 	executeTestFunction:
 		res, err := mr.Reconcile(ctx, req)
@@ -163,62 +176,289 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		Expect(err).To(HaveOccurred())
 
 	},
-		Entry("getRequestedModule failed", true, false, false, false, false, false, false, false, false),
-		Entry("getNodesListBySelector failed", false, true, false, false, false, false, false, false, false),
-		Entry("getRelevantKernelMappingsAndNodes failed", false, false, true, false, false, false, false, false, false),
-		Entry("ModuleDaemonSetsByKernelVersion failed", false, false, false, true, false, false, false, false, false),
-		Entry("handleBuild failed ", false, false, false, false, true, false, false, false, false),
-		Entry("handleSig failedn", false, false, false, false, false, true, false, false, false),
-		Entry("handleDriverContainer failed", false, false, false, false, false, false, true, false, false),
-		Entry("handleDevicePlugin failed", false, false, false, false, false, false, false, true, false),
-		Entry("garbageCollect failed", false, false, false, false, false, false, false, false, true),
-		Entry("moduleUpdateStatus failed", false, false, false, false, false, false, false, false, false),
+		Entry("getRequestedModule failed", true, false, false, false, false, false, false),
+		Entry("getNodesListBySelector failed", false, true, false, false, false, false, false),
+		Entry("getRelevantKernelMappingsAndNodes failed", false, false, true, false, false, false, false),
+		Entry("getDependentModules failed", false, false, false, true, false, false, false),
+		Entry("ModuleDaemonSetsByKernelAndArch failed", false, false, false, false, true, false, false),
+		Entry("handleDevicePlugin failed", false, false, false, false, false, true, false),
+		Entry("garbageCollect failed", false, false, false, false, false, false, true),
+		Entry("moduleUpdateStatus failed", false, false, false, false, false, false, false),
+	)
+
+	DescribeTable("should update the status and return an error if a kernel version could not be handled",
+		func(handleBuildError, handleSignError bool, expectedStatus kmmv1beta1.KernelVersionStatus) {
+			mod := kmmv1beta1.Module{}
+			selectNodesList := []v1.Node{{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}}}}
+			kernelNodesList := []v1.Node{v1.Node{}}
+			mld := &api.ModuleLoaderData{ContainerImage: "some-image", KernelVersion: "kernelVersion"}
+			mappings := map[string]*api.ModuleLoaderData{"kernelVersion": mld}
+			kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
+			returnedError := fmt.Errorf("some error")
+
+			calls := []*gomock.Call{
+				mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+				mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
+				mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
+				mockReconHelper.EXPECT().setKMMOMetrics(ctx),
+				mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
+				mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
+				mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil),
+				mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			}
+
+			switch {
+			case handleBuildError:
+				calls = append(
+					calls,
+					mockReconHelper.EXPECT().handleBuild(ctx, mld).Return(kmmv1beta1.StagePhase(""), returnedError),
+					mockReconHelper.EXPECT().diagnoseFailure(ctx, mld, kmmv1beta1.StageBuild, returnedError, nil).Return(expectedStatus.Failure, nil),
+				)
+			case handleSignError:
+				calls = append(
+					calls,
+					mockReconHelper.EXPECT().handleBuild(ctx, mld).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+					mockReconHelper.EXPECT().handleSigning(ctx, mld).Return(kmmv1beta1.StagePhase(""), returnedError),
+					mockReconHelper.EXPECT().diagnoseFailure(ctx, mld, kmmv1beta1.StageSign, returnedError, nil).Return(expectedStatus.Failure, nil),
+				)
+			default:
+				calls = append(
+					calls,
+					mockReconHelper.EXPECT().handleBuild(ctx, mld).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+					mockReconHelper.EXPECT().handleSigning(ctx, mld).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+					mockReconHelper.EXPECT().resolveImageDigest(ctx, mld, nil),
+					mockReconHelper.EXPECT().handleDriverContainer(ctx, mld, kernelByDS).Return(nil, returnedError),
+				)
+			}
+
+			calls = append(
+				calls,
+				mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{}),
+				mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
+				mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
+				mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil),
+				mockSU.EXPECT().ModuleUpdateStatus(
+					ctx,
+					&mod,
+					kernelNodesList,
+					selectNodesList,
+					kernelByDS,
+					[]kmmv1beta1.KernelVersionStatus{expectedStatus},
+				).Return(nil),
+			)
+
+			gomock.InOrder(calls...)
+
+			res, err := mr.Reconcile(ctx, req)
+
+			Expect(res).To(Equal(reconcile.Result{}))
+			Expect(err).To(HaveOccurred())
+		},
+		Entry(
+			"handleBuild failed",
+			true,
+			false,
+			kmmv1beta1.KernelVersionStatus{
+				KernelVersion:  "kernelVersion",
+				ContainerImage: "some-image",
+				BuildPhase:     kmmv1beta1.StagePhaseFailed,
+				Message:        "some error",
+				Failure: &kmmv1beta1.StageFailure{
+					Stage:   kmmv1beta1.StageBuild,
+					Reason:  kmmv1beta1.StageFailureReasonUnknown,
+					Message: "some error",
+				},
+			},
+		),
+		Entry(
+			"handleSigning failed",
+			false,
+			true,
+			kmmv1beta1.KernelVersionStatus{
+				KernelVersion:  "kernelVersion",
+				ContainerImage: "some-image",
+				BuildPhase:     kmmv1beta1.StagePhaseNotRequired,
+				SignPhase:      kmmv1beta1.StagePhaseFailed,
+				Message:        "some error",
+				Failure: &kmmv1beta1.StageFailure{
+					Stage:   kmmv1beta1.StageSign,
+					Reason:  kmmv1beta1.StageFailureReasonUnknown,
+					Message: "some error",
+				},
+			},
+		),
+		Entry(
+			"handleDriverContainer failed",
+			false,
+			false,
+			kmmv1beta1.KernelVersionStatus{
+				KernelVersion:  "kernelVersion",
+				ContainerImage: "some-image",
+				BuildPhase:     kmmv1beta1.StagePhaseNotRequired,
+				SignPhase:      kmmv1beta1.StagePhaseNotRequired,
+				Message:        "some error",
+			},
+		),
 	)
 
 	It("Build has not completed successfully", func() {
 		mod := kmmv1beta1.Module{}
-		selectNodesList := []v1.Node{v1.Node{}}
+		selectNodesList := []v1.Node{{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}}}}
 		kernelNodesList := []v1.Node{v1.Node{}}
-		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{}}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{KernelVersion: "kernelVersion"}}
 		kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
 		gomock.InOrder(
 			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
 			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
 			mockReconHelper.EXPECT().setKMMOMetrics(ctx),
 			mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
 			mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
-			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
-			mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(false, nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseInProgress, nil),
+			mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{}),
+			mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
 			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
-			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS).Return(nil),
-			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS).Return(nil),
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, []kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernelVersion",
+					BuildPhase:    kmmv1beta1.StagePhaseInProgress,
+					Message:       "waiting for the build to complete",
+				},
+			}).Return(nil),
 		)
 
 		res, err := mr.Reconcile(ctx, req)
 
-		Expect(res).To(Equal(reconcile.Result{}))
+		Expect(res).To(Equal(reconcile.Result{RequeueAfter: buildRequeueInterval}))
 		Expect(err).NotTo(HaveOccurred())
 
 	})
 
+	It("Build is queued", func() {
+		mod := kmmv1beta1.Module{}
+		selectNodesList := []v1.Node{{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}}}}
+		kernelNodesList := []v1.Node{v1.Node{}}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{KernelVersion: "kernelVersion"}}
+		kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
+		gomock.InOrder(
+			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
+			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
+			mockReconHelper.EXPECT().setKMMOMetrics(ctx),
+			mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
+			mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseQueued, nil),
+			mockScheduler.EXPECT().Position(scheduler.WorkloadFor(mappings["kernelVersion"], utils.JobTypeBuild)).Return(3),
+			mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{}),
+			mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
+			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, []kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernelVersion",
+					BuildPhase:    kmmv1beta1.StagePhaseQueued,
+					QueuePosition: 3,
+					Message:       "build queued at position 3",
+				},
+			}).Return(nil),
+		)
+
+		res, err := mr.Reconcile(ctx, req)
+
+		Expect(res).To(Equal(reconcile.Result{RequeueAfter: queueRequeueInterval}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
 	It("Signing has not completed successfully", func() {
 		mod := kmmv1beta1.Module{}
-		selectNodesList := []v1.Node{v1.Node{}}
+		selectNodesList := []v1.Node{{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}}}}
+		kernelNodesList := []v1.Node{v1.Node{}}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{KernelVersion: "kernelVersion"}}
+		kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
+		gomock.InOrder(
+			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
+			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
+			mockReconHelper.EXPECT().setKMMOMetrics(ctx),
+			mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
+			mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseCompleted, nil),
+			mockReconHelper.EXPECT().handleSigning(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseInProgress, nil),
+			mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{}),
+			mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
+			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, []kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernelVersion",
+					BuildPhase:    kmmv1beta1.StagePhaseCompleted,
+					SignPhase:     kmmv1beta1.StagePhaseInProgress,
+					Message:       "waiting for signing to complete",
+				},
+			}).Return(nil),
+		)
+
+		res, err := mr.Reconcile(ctx, req)
+
+		Expect(res).To(Equal(reconcile.Result{RequeueAfter: buildRequeueInterval}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should diagnose a failed build Job", func() {
+		previousFailure := kmmv1beta1.StageFailure{
+			Stage:   kmmv1beta1.StageBuild,
+			Reason:  kmmv1beta1.StageFailureReasonUnknown,
+			PodName: "old-pod",
+		}
+		mod := kmmv1beta1.Module{
+			Status: kmmv1beta1.ModuleStatus{
+				KernelVersions: []kmmv1beta1.KernelVersionStatus{
+					{KernelVersion: "kernelVersion", Failure: &previousFailure},
+				},
+			},
+		}
+		selectNodesList := []v1.Node{{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}}}}
 		kernelNodesList := []v1.Node{v1.Node{}}
-		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{}}
+		mld := &api.ModuleLoaderData{KernelVersion: "kernelVersion"}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": mld}
 		kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
+		failure := kmmv1beta1.StageFailure{
+			Stage:        kmmv1beta1.StageBuild,
+			Reason:       kmmv1beta1.StageFailureReasonCompileError,
+			Message:      "kmod.c:1:1: error: unknown type name",
+			PodName:      "pod",
+			LogConfigMap: "log-cm",
+		}
 		gomock.InOrder(
 			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
 			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
 			mockReconHelper.EXPECT().setKMMOMetrics(ctx),
 			mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
 			mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
-			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
-			mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(true, nil),
-			mockReconHelper.EXPECT().handleSigning(ctx, mappings["kernelVersion"]).Return(false, nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			mockReconHelper.EXPECT().handleBuild(ctx, mld).Return(kmmv1beta1.StagePhaseFailed, nil),
+			mockReconHelper.EXPECT().diagnoseFailure(ctx, mld, kmmv1beta1.StageBuild, nil, &previousFailure).Return(&failure, nil),
+			mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{}),
+			mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
 			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
-			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS).Return(nil),
-			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS).Return(nil),
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, []kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernelVersion",
+					BuildPhase:    kmmv1beta1.StagePhaseFailed,
+					Message:       "build failed: CompileError: kmod.c:1:1: error: unknown type name",
+					Failure:       &failure,
+				},
+			}).Return(nil),
 		)
 
 		res, err := mr.Reconcile(ctx, req)
@@ -227,25 +467,249 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
+	It("should requeue while a failed build waits for its next attempt", func() {
+		mod := kmmv1beta1.Module{}
+		selectNodesList := []v1.Node{{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}}}}
+		kernelNodesList := []v1.Node{v1.Node{}}
+		mld := &api.ModuleLoaderData{KernelVersion: "kernelVersion"}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": mld}
+		kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
+		failure := kmmv1beta1.StageFailure{
+			Stage:   kmmv1beta1.StageBuild,
+			Reason:  kmmv1beta1.StageFailureReasonPushDenied,
+			Message: "error pushing image",
+			PodName: "pod",
+		}
+		gomock.InOrder(
+			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
+			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
+			mockReconHelper.EXPECT().setKMMOMetrics(ctx),
+			mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
+			mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			mockReconHelper.EXPECT().handleBuild(ctx, mld).Return(kmmv1beta1.StagePhaseRetrying, nil),
+			mockReconHelper.EXPECT().diagnoseFailure(ctx, mld, kmmv1beta1.StageBuild, nil, nil).Return(&failure, nil),
+			mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{}),
+			mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
+			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, []kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernelVersion",
+					BuildPhase:    kmmv1beta1.StagePhaseRetrying,
+					Message:       "build failed: PushDenied: error pushing image; retrying",
+					Failure:       &failure,
+				},
+			}).Return(nil),
+		)
+
+		res, err := mr.Reconcile(ctx, req)
+
+		Expect(res).To(Equal(reconcile.Result{RequeueAfter: retryRequeueInterval}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
 	It("Good flow", func() {
 		mod := kmmv1beta1.Module{}
-		selectNodesList := []v1.Node{v1.Node{}}
+		selectNodesList := []v1.Node{
+			{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}}},
+			{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "unmappedKernelVersion+"}}},
+		}
+		kernelNodesList := []v1.Node{v1.Node{}}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{KernelVersion: "kernelVersion"}}
+		kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
+		ds := appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds-name"}}
+		gomock.InOrder(
+			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
+			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
+			mockReconHelper.EXPECT().setKMMOMetrics(ctx),
+			mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
+			mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return([]string{"dependent"}, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+			mockReconHelper.EXPECT().handleSigning(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+			mockReconHelper.EXPECT().resolveImageDigest(ctx, mappings["kernelVersion"], nil),
+			mockReconHelper.EXPECT().handleDriverContainer(ctx, mappings["kernelVersion"], kernelByDS).Return(&ds, nil),
+			mockReconHelper.EXPECT().handleUpgrade(ctx, &ds, mappings["kernelVersion"]).Return(nil, nil),
+			mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{mappings["kernelVersion"]}),
+			mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
+			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, []string{"dependent"}).Return(nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, []kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernelVersion",
+					BuildPhase:    kmmv1beta1.StagePhaseNotRequired,
+					SignPhase:     kmmv1beta1.StagePhaseNotRequired,
+					DaemonSetName: "ds-name",
+					Message:       "module loader DaemonSet is up to date",
+				},
+				{
+					KernelVersion: "unmappedKernelVersion",
+					Message:       "no kernel mapping matches this kernel version",
+				},
+			}).Return(nil),
+		)
+
+		res, err := mr.Reconcile(ctx, req)
+
+		Expect(res).To(Equal(reconcile.Result{}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mappings["kernelVersion"].Dependents).To(Equal([]string{"dependent"}))
+	})
+
+	It("should pin the image to its digest and requeue to resolve it again", func() {
+		mod := kmmv1beta1.Module{
+			Status: kmmv1beta1.ModuleStatus{
+				KernelVersions: []kmmv1beta1.KernelVersionStatus{
+					{KernelVersion: "kernelVersion", ContainerImage: "some-image:tag"},
+				},
+			},
+		}
+		selectNodesList := []v1.Node{{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}}}}
+		kernelNodesList := []v1.Node{v1.Node{}}
+		mld := &api.ModuleLoaderData{
+			KernelVersion:  "kernelVersion",
+			ContainerImage: "some-image:tag",
+			ImageDigestResolution: &kmmv1beta1.ImageDigestResolution{
+				Policy:   kmmv1beta1.ImageDigestPolicyPeriodic,
+				Interval: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+		}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": mld}
+		kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
+		ds := appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds-name"}}
+		imageDigest := kmmv1beta1.ImageDigestStatus{Digest: "sha256:1234", ResolvedAt: metav1.Now()}
+		gomock.InOrder(
+			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
+			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
+			mockReconHelper.EXPECT().setKMMOMetrics(ctx),
+			mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
+			mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			mockReconHelper.EXPECT().handleBuild(ctx, mld).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+			mockReconHelper.EXPECT().handleSigning(ctx, mld).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+			mockReconHelper.EXPECT().resolveImageDigest(ctx, mld, &mod.Status.KernelVersions[0]).Return(&imageDigest, nil),
+			mockReconHelper.EXPECT().handleDriverContainer(ctx, mld, kernelByDS).Return(&ds, nil),
+			mockReconHelper.EXPECT().handleUpgrade(ctx, &ds, mld).Return(nil, nil),
+			mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{mld}),
+			mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
+			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, []kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion:  "kernelVersion",
+					ContainerImage: "some-image:tag",
+					BuildPhase:     kmmv1beta1.StagePhaseNotRequired,
+					SignPhase:      kmmv1beta1.StagePhaseNotRequired,
+					DaemonSetName:  "ds-name",
+					Message:        "module loader DaemonSet is up to date",
+					ImageDigest:    &imageDigest,
+				},
+			}).Return(nil),
+		)
+
+		res, err := mr.Reconcile(ctx, req)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeNumerically("~", 10*time.Minute, time.Minute))
+		Expect(mld.ContainerImage).To(Equal("some-image:tag@sha256:1234"))
+	})
+
+	It("should requeue while a node-by-node upgrade is in progress", func() {
+		mod := kmmv1beta1.Module{}
+		selectNodesList := []v1.Node{{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}}}}
 		kernelNodesList := []v1.Node{v1.Node{}}
-		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{}}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": &api.ModuleLoaderData{KernelVersion: "kernelVersion"}}
 		kernelByDS := map[string]*appsv1.DaemonSet{"kernelVersion": &appsv1.DaemonSet{}}
+		ds := appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds-name"}}
+		upgradeStatus := kmmv1beta1.ModuleUpgradeStatus{
+			Phase:        kmmv1beta1.UpgradePhaseInProgress,
+			CurrentNodes: []string{"node1"},
+		}
+		gomock.InOrder(
+			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
+			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
+			mockReconHelper.EXPECT().setKMMOMetrics(ctx),
+			mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
+			mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+			mockReconHelper.EXPECT().handleSigning(ctx, mappings["kernelVersion"]).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+			mockReconHelper.EXPECT().resolveImageDigest(ctx, mappings["kernelVersion"], nil),
+			mockReconHelper.EXPECT().handleDriverContainer(ctx, mappings["kernelVersion"], kernelByDS).Return(&ds, nil),
+			mockReconHelper.EXPECT().handleUpgrade(ctx, &ds, mappings["kernelVersion"]).Return(&upgradeStatus, nil),
+			mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{mappings["kernelVersion"]}),
+			mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
+			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, []kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernelVersion",
+					BuildPhase:    kmmv1beta1.StagePhaseNotRequired,
+					SignPhase:     kmmv1beta1.StagePhaseNotRequired,
+					DaemonSetName: "ds-name",
+					Message:       "node-by-node upgrade phase: InProgress",
+					Upgrade:       &upgradeStatus,
+				},
+			}).Return(nil),
+		)
+
+		res, err := mr.Reconcile(ctx, req)
+
+		Expect(res).To(Equal(reconcile.Result{RequeueAfter: upgradeRequeueInterval}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should load the module with Jobs when using the Job load mode", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{LoadMode: kmmv1beta1.LoadModeJob},
+		}
+		node := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status:     v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion"}},
+		}
+		selectNodesList := []v1.Node{node}
+		kernelNodesList := []v1.Node{node}
+		mld := &api.ModuleLoaderData{KernelVersion: "kernelVersion", LoadMode: kmmv1beta1.LoadModeJob}
+		mappings := map[string]*api.ModuleLoaderData{"kernelVersion": mld}
+		kernelByDS := make(map[string]*appsv1.DaemonSet)
+		loadResult := loadjob.Result{FailedNodes: []string{"node1"}}
 		gomock.InOrder(
 			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().setFinalizer(ctx, &mod),
 			mockCAH.EXPECT().Sync(ctx, namespace, &mod).Return(nil),
 			mockReconHelper.EXPECT().setKMMOMetrics(ctx),
 			mockReconHelper.EXPECT().getNodesListBySelector(ctx, &mod).Return(selectNodesList, nil),
 			mockReconHelper.EXPECT().getRelevantKernelMappingsAndNodes(ctx, &mod, selectNodesList).Return(mappings, kernelNodesList, nil),
-			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
-			mockReconHelper.EXPECT().handleBuild(ctx, mappings["kernelVersion"]).Return(true, nil),
-			mockReconHelper.EXPECT().handleSigning(ctx, mappings["kernelVersion"]).Return(true, nil),
-			mockReconHelper.EXPECT().handleDriverContainer(ctx, mappings["kernelVersion"], kernelByDS).Return(nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return(nil, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).Return(kernelByDS, nil),
+			mockReconHelper.EXPECT().handleBuild(ctx, mld).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+			mockReconHelper.EXPECT().handleSigning(ctx, mld).Return(kmmv1beta1.StagePhaseNotRequired, nil),
+			mockReconHelper.EXPECT().resolveImageDigest(ctx, mld, nil),
+			mockReconHelper.EXPECT().handleLoadJobs(ctx, mld, kernelNodesList).Return(&loadResult, nil),
+			mockReconHelper.EXPECT().recordLoadResult(ctx, mld, kernelNodesList, &loadResult),
+			mockReconHelper.EXPECT().handleUnloadJobs(ctx, &mod, kernelNodesList, nil),
+			mockReconHelper.EXPECT().handleBootLoading(ctx, &mod, []*api.ModuleLoaderData{mld}),
+			mockReconHelper.EXPECT().syncNodeModulesConfigs(ctx, &mod, mappings, kernelNodesList),
 			mockReconHelper.EXPECT().handleDevicePlugin(ctx, &mod).Return(nil),
-			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS).Return(nil),
-			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS).Return(nil),
+			mockReconHelper.EXPECT().garbageCollect(ctx, &mod, mappings, kernelByDS, nil).Return(nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, kernelNodesList, selectNodesList, kernelByDS, []kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernelVersion",
+					BuildPhase:    kmmv1beta1.StagePhaseNotRequired,
+					SignPhase:     kmmv1beta1.StagePhaseNotRequired,
+					FailedNodes:   []string{"node1"},
+					Message:       "module loaded on 0/1 nodes",
+				},
+			}).Return(nil),
 		)
 
 		res, err := mr.Reconcile(ctx, req)
@@ -253,6 +717,62 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		Expect(res).To(Equal(reconcile.Result{}))
 		Expect(err).NotTo(HaveOccurred())
 	})
+
+	DescribeTable("should unload deleted modules",
+		func(loadMode kmmv1beta1.LoadMode, remaining []string, expectedRes reconcile.Result) {
+			now := metav1.Now()
+
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Spec:       kmmv1beta1.ModuleSpec{LoadMode: loadMode},
+			}
+
+			calls := []*gomock.Call{
+				mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+				mockReconHelper.EXPECT().getDependentModules(ctx, &mod).Return([]string{"dependent"}, nil),
+				mockReconHelper.EXPECT().deleteBootLoading(ctx, &mod),
+				mockReconHelper.EXPECT().deleteModuleDaemonSets(ctx, &mod),
+				mockReconHelper.EXPECT().handleUnload(ctx, &mod, []string{"dependent"}).Return(remaining, nil),
+				mockReconHelper.EXPECT().recordUnloadProgress(ctx, &mod, remaining),
+				mockSU.EXPECT().ModuleUpdateUnloadingStatus(ctx, &mod, remaining),
+			}
+
+			if len(remaining) == 0 {
+				calls = append(calls, mockReconHelper.EXPECT().removeFinalizer(ctx, &mod))
+			}
+
+			gomock.InOrder(calls...)
+
+			res, err := mr.Reconcile(ctx, req)
+
+			Expect(res).To(Equal(expectedRes))
+			Expect(err).NotTo(HaveOccurred())
+		},
+		Entry("still loaded on some nodes", kmmv1beta1.LoadModeDaemonSet, []string{"node1"}, reconcile.Result{RequeueAfter: unloadRequeueInterval}),
+		Entry("unloaded from all nodes", kmmv1beta1.LoadModeDaemonSet, nil, reconcile.Result{}),
+		Entry("Job load mode, unloaded from all nodes", kmmv1beta1.LoadModeJob, nil, reconcile.Result{}),
+	)
+
+	It("should update the status and return an error if the deleted module could not be unloaded", func() {
+		now := metav1.Now()
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+		}
+
+		gomock.InOrder(
+			mockReconHelper.EXPECT().getRequestedModule(ctx, nsn).Return(&mod, nil),
+			mockReconHelper.EXPECT().getDependentModules(ctx, &mod),
+			mockReconHelper.EXPECT().deleteBootLoading(ctx, &mod),
+			mockReconHelper.EXPECT().deleteModuleDaemonSets(ctx, &mod),
+			mockReconHelper.EXPECT().handleUnload(ctx, &mod, nil).Return([]string{"node1"}, fmt.Errorf("some error")),
+			mockSU.EXPECT().ModuleUpdateUnloadingStatus(ctx, &mod, []string{"node1"}),
+		)
+
+		_, err := mr.Reconcile(ctx, req)
+
+		Expect(err).To(HaveOccurred())
+	})
 })
 
 var _ = Describe("ModuleReconciler_getNodesListBySelector", func() {
@@ -265,7 +785,7 @@ var _ = Describe("ModuleReconciler_getNodesListBySelector", func() {
 	BeforeEach(func() {
 		ctrl = gomock.NewController(GinkgoT())
 		clnt = client.NewMockClient(ctrl)
-		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, nil, nil, "")
+		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "")
 	})
 
 	It("list failed", func() {
@@ -309,6 +829,70 @@ var _ = Describe("ModuleReconciler_getNodesListBySelector", func() {
 		Expect(nodes).To(Equal([]v1.Node{node2, node3}))
 
 	})
+
+	It("should return nodes whose NoSchedule taints are tolerated by the module loader", func() {
+		mod := kmmv1beta1.Module{}
+		mod.Spec.ModuleLoader.Tolerations = []v1.Toleration{
+			{Key: "gpu", Operator: v1.TolerationOpEqual, Value: "true", Effect: v1.TaintEffectNoSchedule},
+		}
+		toleratedNode := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "tolerated"},
+			Spec: v1.NodeSpec{
+				Taints: []v1.Taint{{Key: "gpu", Value: "true", Effect: v1.TaintEffectNoSchedule}},
+			},
+		}
+		partiallyToleratedNode := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "partially-tolerated"},
+			Spec: v1.NodeSpec{
+				Taints: []v1.Taint{
+					{Key: "gpu", Value: "true", Effect: v1.TaintEffectNoSchedule},
+					{Key: "other", Effect: v1.TaintEffectNoSchedule},
+				},
+			},
+		}
+		clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+				list.Items = []v1.Node{toleratedNode, partiallyToleratedNode}
+				return nil
+			},
+		)
+
+		nodes, err := mhr.getNodesListBySelector(context.Background(), &mod)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(Equal([]v1.Node{toleratedNode}))
+	})
+
+	It("should return nodes cordoned for the upgrade of the module", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-module"},
+		}
+		unschedulableTaints := []v1.Taint{
+			{Key: v1.TaintNodeUnschedulable, Effect: v1.TaintEffectNoSchedule},
+		}
+		upgradingNode := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "upgrading",
+				Annotations: map[string]string{"kmm.node.kubernetes.io/test-module.upgrade-started": "2022-01-01T00:00:00Z"},
+			},
+			Spec: v1.NodeSpec{Unschedulable: true, Taints: unschedulableTaints},
+		}
+		cordonedNode := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "cordoned"},
+			Spec:       v1.NodeSpec{Unschedulable: true, Taints: unschedulableTaints},
+		}
+		clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+				list.Items = []v1.Node{upgradingNode, cordonedNode}
+				return nil
+			},
+		)
+
+		nodes, err := mhr.getNodesListBySelector(context.Background(), &mod)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(Equal([]v1.Node{upgradingNode}))
+	})
 })
 
 var _ = Describe("ModuleReconciler_getRelevantKernelMappingsAndNodes", func() {
@@ -321,7 +905,7 @@ var _ = Describe("ModuleReconciler_getRelevantKernelMappingsAndNodes", func() {
 	BeforeEach(func() {
 		ctrl = gomock.NewController(GinkgoT())
 		mockKM = module.NewMockKernelMapper(ctrl)
-		mhr = newModuleReconcilerHelper(nil, nil, nil, nil, mockKM, nil, "")
+		mhr = newModuleReconcilerHelper(nil, nil, nil, nil, nil, nil, nil, nil, mockKM, nil, nil, nil, nil, "")
 	})
 
 	node1 := v1.Node{
@@ -354,8 +938,8 @@ var _ = Describe("ModuleReconciler_getRelevantKernelMappingsAndNodes", func() {
 		expectedNodes := []v1.Node{node1, node2, node3}
 		expectedMappings := map[string]*api.ModuleLoaderData{"kernelVersion1": &mld1, "kernelVersion2": &mld2}
 		gomock.InOrder(
-			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, node1.Status.NodeInfo.KernelVersion).Return(&mld1, nil),
-			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, node2.Status.NodeInfo.KernelVersion).Return(&mld2, nil),
+			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, node1.Status.NodeInfo.KernelVersion, "").Return(&mld1, nil),
+			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, node2.Status.NodeInfo.KernelVersion, "").Return(&mld2, nil),
 		)
 
 		mappings, resNodes, err := mhr.getRelevantKernelMappingsAndNodes(context.Background(), &kmmv1beta1.Module{}, nodes)
@@ -371,8 +955,8 @@ var _ = Describe("ModuleReconciler_getRelevantKernelMappingsAndNodes", func() {
 		expectedNodes := []v1.Node{node1, node3}
 		expectedMappings := map[string]*api.ModuleLoaderData{"kernelVersion1": &mld1}
 		gomock.InOrder(
-			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, node1.Status.NodeInfo.KernelVersion).Return(&mld1, nil),
-			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, node2.Status.NodeInfo.KernelVersion).Return(nil, fmt.Errorf("some error")),
+			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, node1.Status.NodeInfo.KernelVersion, "").Return(&mld1, nil),
+			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, node2.Status.NodeInfo.KernelVersion, "").Return(nil, fmt.Errorf("some error")),
 		)
 
 		mappings, resNodes, err := mhr.getRelevantKernelMappingsAndNodes(context.Background(), &kmmv1beta1.Module{}, nodes)
@@ -383,6 +967,31 @@ var _ = Describe("ModuleReconciler_getRelevantKernelMappingsAndNodes", func() {
 
 	})
 
+	It("should compute one mapping per kernel version and architecture", func() {
+		nodeAMD64 := v1.Node{
+			Status: v1.NodeStatus{
+				NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion1", Architecture: "amd64"},
+			},
+		}
+		nodeARM64 := v1.Node{
+			Status: v1.NodeStatus{
+				NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernelVersion1", Architecture: "arm64"},
+			},
+		}
+
+		nodes := []v1.Node{nodeAMD64, nodeARM64}
+		expectedMappings := map[string]*api.ModuleLoaderData{"kernelVersion1/amd64": &mld1, "kernelVersion1/arm64": &mld2}
+		gomock.InOrder(
+			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, "kernelVersion1", "amd64").Return(&mld1, nil),
+			mockKM.EXPECT().GetModuleLoaderDataForKernel(&kmmv1beta1.Module{}, "kernelVersion1", "arm64").Return(&mld2, nil),
+		)
+
+		mappings, resNodes, err := mhr.getRelevantKernelMappingsAndNodes(context.Background(), &kmmv1beta1.Module{}, nodes)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resNodes).To(Equal(nodes))
+		Expect(mappings).To(Equal(expectedMappings))
+	})
 })
 
 var _ = Describe("ModuleReconciler_handleBuild", func() {
@@ -397,7 +1006,7 @@ var _ = Describe("ModuleReconciler_handleBuild", func() {
 		ctrl = gomock.NewController(GinkgoT())
 		mockBM = build.NewMockManager(ctrl)
 		mockMetrics = metrics.NewMockMetrics(ctrl)
-		mhr = newModuleReconcilerHelper(nil, mockBM, nil, nil, nil, mockMetrics, "")
+		mhr = newModuleReconcilerHelper(nil, mockBM, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockMetrics, "")
 	})
 
 	const (
@@ -413,9 +1022,22 @@ var _ = Describe("ModuleReconciler_handleBuild", func() {
 			mockBM.EXPECT().ShouldSync(gomock.Any(), mld).Return(false, nil),
 		)
 
-		completed, err := mhr.handleBuild(context.Background(), mld)
+		phase, err := mhr.handleBuild(context.Background(), mld)
 		Expect(err).NotTo(HaveOccurred())
-		Expect(completed).To(BeTrue())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseNotRequired))
+	})
+
+	It("should return Completed if the image was already built", func() {
+		mld := &api.ModuleLoaderData{
+			KernelVersion: kernelVersion,
+			Build:         &kmmv1beta1.Build{},
+		}
+
+		mockBM.EXPECT().ShouldSync(gomock.Any(), mld).Return(false, nil)
+
+		phase, err := mhr.handleBuild(context.Background(), mld)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseCompleted))
 	})
 
 	It("should record that a job was created when the build sync returns StatusCreated", func() {
@@ -432,10 +1054,10 @@ var _ = Describe("ModuleReconciler_handleBuild", func() {
 			mockBM.EXPECT().Sync(gomock.Any(), &mld, true, mld.Owner).Return(utils.Status(utils.StatusCreated), nil),
 		)
 
-		completed, err := mhr.handleBuild(context.Background(), &mld)
+		phase, err := mhr.handleBuild(context.Background(), &mld)
 
 		Expect(err).NotTo(HaveOccurred())
-		Expect(completed).To(BeFalse())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseInProgress))
 	})
 
 	It("should record that a job was completed, when the build sync returns StatusCompleted", func() {
@@ -452,10 +1074,48 @@ var _ = Describe("ModuleReconciler_handleBuild", func() {
 			mockBM.EXPECT().Sync(gomock.Any(), mld, true, mld.Owner).Return(utils.Status(utils.StatusCompleted), nil),
 		)
 
-		completed, err := mhr.handleBuild(context.Background(), mld)
+		phase, err := mhr.handleBuild(context.Background(), mld)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseCompleted))
+	})
+
+	It("should return Retrying while the failed build waits for its next attempt", func() {
+		mld := &api.ModuleLoaderData{
+			Name:           moduleName,
+			Namespace:      namespace,
+			ContainerImage: imageName,
+			Build:          &kmmv1beta1.Build{RetryPolicy: &kmmv1beta1.RetryPolicy{MaxAttempts: 3}},
+			KernelVersion:  kernelVersion,
+		}
+		gomock.InOrder(
+			mockBM.EXPECT().ShouldSync(gomock.Any(), mld).Return(true, nil),
+			mockBM.EXPECT().Sync(gomock.Any(), mld, true, mld.Owner).Return(utils.Status(utils.StatusRetrying), nil),
+		)
+
+		phase, err := mhr.handleBuild(context.Background(), mld)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseRetrying))
+	})
+
+	It("should return Queued while the build waits for a slot", func() {
+		mld := &api.ModuleLoaderData{
+			Name:           moduleName,
+			Namespace:      namespace,
+			ContainerImage: imageName,
+			Build:          &kmmv1beta1.Build{},
+			KernelVersion:  kernelVersion,
+		}
+		gomock.InOrder(
+			mockBM.EXPECT().ShouldSync(gomock.Any(), mld).Return(true, nil),
+			mockBM.EXPECT().Sync(gomock.Any(), mld, true, mld.Owner).Return(utils.Status(utils.StatusQueued), nil),
+		)
+
+		phase, err := mhr.handleBuild(context.Background(), mld)
 
 		Expect(err).NotTo(HaveOccurred())
-		Expect(completed).To(BeTrue())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseQueued))
 	})
 })
 
@@ -471,7 +1131,7 @@ var _ = Describe("ModuleReconciler_handleSigning", func() {
 		ctrl = gomock.NewController(GinkgoT())
 		mockSM = sign.NewMockSignManager(ctrl)
 		mockMetrics = metrics.NewMockMetrics(ctrl)
-		mhr = newModuleReconcilerHelper(nil, nil, mockSM, nil, nil, mockMetrics, "")
+		mhr = newModuleReconcilerHelper(nil, nil, mockSM, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockMetrics, "")
 	})
 
 	const (
@@ -490,16 +1150,31 @@ var _ = Describe("ModuleReconciler_handleSigning", func() {
 			mockSM.EXPECT().ShouldSync(gomock.Any(), mld).Return(false, nil),
 		)
 
-		completed, err := mhr.handleSigning(context.Background(), mld)
+		phase, err := mhr.handleSigning(context.Background(), mld)
 
 		Expect(err).NotTo(HaveOccurred())
-		Expect(completed).To(BeTrue())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseNotRequired))
 	})
 
-	It("should record that a job was created when the sign sync returns StatusCreated", func() {
-		mld := api.ModuleLoaderData{
-			Name:           moduleName,
-			Namespace:      namespace,
+	It("should return Completed if the image was already signed", func() {
+		mld := &api.ModuleLoaderData{
+			ContainerImage: imageName,
+			KernelVersion:  kernelVersion,
+			Sign:           &kmmv1beta1.Sign{},
+		}
+
+		mockSM.EXPECT().ShouldSync(gomock.Any(), mld).Return(false, nil)
+
+		phase, err := mhr.handleSigning(context.Background(), mld)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseCompleted))
+	})
+
+	It("should record that a job was created when the sign sync returns StatusCreated", func() {
+		mld := api.ModuleLoaderData{
+			Name:           moduleName,
+			Namespace:      namespace,
 			ContainerImage: imageName,
 			Sign:           &kmmv1beta1.Sign{},
 			KernelVersion:  kernelVersion,
@@ -510,10 +1185,10 @@ var _ = Describe("ModuleReconciler_handleSigning", func() {
 			mockSM.EXPECT().Sync(gomock.Any(), &mld, "", true, mld.Owner).Return(utils.Status(utils.StatusCreated), nil),
 		)
 
-		completed, err := mhr.handleSigning(context.Background(), &mld)
+		phase, err := mhr.handleSigning(context.Background(), &mld)
 
 		Expect(err).NotTo(HaveOccurred())
-		Expect(completed).To(BeFalse())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseInProgress))
 	})
 
 	It("should record that a job was completed when the sign sync returns StatusCompleted", func() {
@@ -530,10 +1205,10 @@ var _ = Describe("ModuleReconciler_handleSigning", func() {
 			mockSM.EXPECT().Sync(gomock.Any(), &mld, "", true, mld.Owner).Return(utils.Status(utils.StatusCompleted), nil),
 		)
 
-		completed, err := mhr.handleSigning(context.Background(), &mld)
+		phase, err := mhr.handleSigning(context.Background(), &mld)
 
 		Expect(err).NotTo(HaveOccurred())
-		Expect(completed).To(BeTrue())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseCompleted))
 	})
 
 	It("should run sign sync with the previous image as well when module build and sign are specified", func() {
@@ -553,10 +1228,127 @@ var _ = Describe("ModuleReconciler_handleSigning", func() {
 				Return(utils.Status(utils.StatusCompleted), nil),
 		)
 
-		completed, err := mhr.handleSigning(context.Background(), mld)
+		phase, err := mhr.handleSigning(context.Background(), mld)
 
 		Expect(err).NotTo(HaveOccurred())
-		Expect(completed).To(BeTrue())
+		Expect(phase).To(Equal(kmmv1beta1.StagePhaseCompleted))
+	})
+})
+
+var _ = Describe("ModuleReconciler_resolveImageDigest", func() {
+	const (
+		image      = "some-image:tag"
+		generation = 2
+	)
+
+	var (
+		ctx             context.Context
+		mockAuthFactory *auth.MockRegistryAuthGetterFactory
+		mockRegistry    *registry.MockRegistry
+		mhr             moduleReconcilerHelperAPI
+		mld             api.ModuleLoaderData
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		ctx = context.Background()
+		mockAuthFactory = auth.NewMockRegistryAuthGetterFactory(ctrl)
+		mockRegistry = registry.NewMockRegistry(ctrl)
+		mhr = newModuleReconcilerHelper(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockRegistry, mockAuthFactory, nil, "")
+		mld = api.ModuleLoaderData{
+			ContainerImage: image,
+			Owner:          &kmmv1beta1.Module{ObjectMeta: metav1.ObjectMeta{Generation: generation}},
+		}
+	})
+
+	It("should do nothing if the image is already referenced by digest", func() {
+		mld.ContainerImage = "some-image@sha256:1234"
+
+		res, err := mhr.resolveImageDigest(ctx, &mld, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(BeNil())
+	})
+
+	DescribeTable("should keep the previous digest while it is valid",
+		func(policy kmmv1beta1.ImageDigestPolicy, resolvedAt time.Time) {
+			mld.ImageDigestResolution = &kmmv1beta1.ImageDigestResolution{Policy: policy}
+
+			previous := kmmv1beta1.KernelVersionStatus{
+				ContainerImage: image,
+				ImageDigest: &kmmv1beta1.ImageDigestStatus{
+					Digest:           "sha256:1234",
+					ResolvedAt:       metav1.NewTime(resolvedAt),
+					ModuleGeneration: generation,
+				},
+			}
+
+			res, err := mhr.resolveImageDigest(ctx, &mld, &previous)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(previous.ImageDigest))
+		},
+		Entry("OnSpecChange", kmmv1beta1.ImageDigestPolicyOnSpecChange, time.Now().Add(-24*time.Hour)),
+		Entry("Periodic, before the interval", kmmv1beta1.ImageDigestPolicyPeriodic, time.Now().Add(-time.Minute)),
+	)
+
+	DescribeTable("should resolve the tag again",
+		func(policy kmmv1beta1.ImageDigestPolicy, previousImage string, previousGeneration int64, resolvedAt time.Time) {
+			mld.ImageDigestResolution = &kmmv1beta1.ImageDigestResolution{Policy: policy}
+
+			previous := kmmv1beta1.KernelVersionStatus{
+				ContainerImage: previousImage,
+				ImageDigest: &kmmv1beta1.ImageDigestStatus{
+					Digest:           "sha256:1234",
+					ResolvedAt:       metav1.NewTime(resolvedAt),
+					ModuleGeneration: previousGeneration,
+				},
+			}
+
+			gomock.InOrder(
+				mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+				mockRegistry.EXPECT().GetDigest(ctx, image, nil, nil).Return("sha256:5678", nil),
+			)
+
+			res, err := mhr.resolveImageDigest(ctx, &mld, &previous)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Digest).To(Equal("sha256:5678"))
+			Expect(res.ModuleGeneration).To(BeEquivalentTo(generation))
+			Expect(res.ResolvedAt.Time).To(BeTemporally("~", time.Now(), time.Minute))
+		},
+		Entry("image changed", kmmv1beta1.ImageDigestPolicyOnSpecChange, "other-image:tag", int64(generation), time.Now()),
+		Entry("spec changed", kmmv1beta1.ImageDigestPolicyOnSpecChange, image, int64(generation-1), time.Now()),
+		Entry("Periodic, after the interval", kmmv1beta1.ImageDigestPolicyPeriodic, image, int64(generation), time.Now().Add(-2*time.Hour)),
+	)
+
+	It("should resolve the tag if it was never resolved", func() {
+		gomock.InOrder(
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+			mockRegistry.EXPECT().GetDigest(ctx, image, nil, nil).Return("sha256:5678", nil),
+		)
+
+		res, err := mhr.resolveImageDigest(ctx, &mld, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Digest).To(Equal("sha256:5678"))
+	})
+
+	It("should keep the previous digest if the tag cannot be resolved", func() {
+		previous := kmmv1beta1.KernelVersionStatus{
+			ContainerImage: image,
+			ImageDigest:    &kmmv1beta1.ImageDigestStatus{Digest: "sha256:1234", ModuleGeneration: generation - 1},
+		}
+
+		gomock.InOrder(
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+			mockRegistry.EXPECT().GetDigest(ctx, image, nil, nil).Return("", fmt.Errorf("some error")),
+		)
+
+		res, err := mhr.resolveImageDigest(ctx, &mld, &previous)
+
+		Expect(err).To(HaveOccurred())
+		Expect(res).To(Equal(previous.ImageDigest))
 	})
 })
 
@@ -565,6 +1357,7 @@ var _ = Describe("ModuleReconciler_handleDriverContainer", func() {
 		ctrl        *gomock.Controller
 		clnt        *client.MockClient
 		mockDC      *daemonset.MockDaemonSetCreator
+		mockUpgrade *upgrade.MockUpgrader
 		mockMetrics *metrics.MockMetrics
 		mhr         moduleReconcilerHelperAPI
 	)
@@ -573,8 +1366,9 @@ var _ = Describe("ModuleReconciler_handleDriverContainer", func() {
 		ctrl = gomock.NewController(GinkgoT())
 		clnt = client.NewMockClient(ctrl)
 		mockDC = daemonset.NewMockDaemonSetCreator(ctrl)
+		mockUpgrade = upgrade.NewMockUpgrader(ctrl)
 		mockMetrics = metrics.NewMockMetrics(ctrl)
-		mhr = newModuleReconcilerHelper(clnt, nil, nil, mockDC, nil, mockMetrics, "namespace")
+		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, mockDC, mockUpgrade, nil, nil, nil, nil, nil, nil, mockMetrics, "namespace")
 	})
 
 	It("new daemonset", func() {
@@ -597,7 +1391,7 @@ var _ = Describe("ModuleReconciler_handleDriverContainer", func() {
 			clnt.EXPECT().Create(ctx, gomock.Any()).Return(nil),
 		)
 
-		err := mhr.handleDriverContainer(ctx, &mld, existingDS)
+		_, err := mhr.handleDriverContainer(ctx, &mld, existingDS)
 
 		Expect(err).NotTo(HaveOccurred())
 
@@ -611,17 +1405,137 @@ var _ = Describe("ModuleReconciler_handleDriverContainer", func() {
 			KernelVersion: "kernelVersion1",
 		}
 		existingDS := map[string]*appsv1.DaemonSet{
-			"kernelVersion1": &appsv1.DaemonSet{}, "kernelVersion2": &appsv1.DaemonSet{},
+			"kernelVersion1": &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds-name"}}, "kernelVersion2": &appsv1.DaemonSet{},
 		}
 		gomock.InOrder(
 			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(nil),
 			mockDC.EXPECT().SetDriverContainerAsDesired(ctx, existingDS["kernelVersion1"], &mld, true).Return(nil),
 		)
 
-		err := mhr.handleDriverContainer(ctx, &mld, existingDS)
+		ds, err := mhr.handleDriverContainer(ctx, &mld, existingDS)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Name).To(Equal("ds-name"))
+
+	})
+
+	It("should let the upgrader set the DaemonSet for the NodeByNode strategy", func() {
+		ctx := context.Background()
+		mld := api.ModuleLoaderData{
+			Name:            "name",
+			Namespace:       "other-namespace",
+			KernelVersion:   "kernelVersion1",
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+		existingDS := map[string]*appsv1.DaemonSet{
+			"kernelVersion1": &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds-name"}},
+		}
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(nil),
+			mockUpgrade.EXPECT().SetDriverContainerAsDesired(ctx, existingDS["kernelVersion1"], &mld, false).Return(nil),
+		)
+
+		_, err := mhr.handleDriverContainer(ctx, &mld, existingDS)
+
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("ModuleReconciler_handleUpgrade", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockUpgrade *upgrade.MockUpgrader
+		mhr         moduleReconcilerHelperAPI
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockUpgrade = upgrade.NewMockUpgrader(ctrl)
+		mhr = newModuleReconcilerHelper(nil, nil, nil, nil, nil, mockUpgrade, nil, nil, nil, nil, nil, nil, nil, "namespace")
+	})
+
+	ctx := context.Background()
+	ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds-name"}}
+
+	It("should do nothing if the module does not use the NodeByNode strategy", func() {
+		mld := api.ModuleLoaderData{
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyRollingUpdate},
+		}
+
+		status, err := mhr.handleUpgrade(ctx, ds, &mld)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeNil())
+	})
+
+	It("should upgrade the DaemonSet node by node", func() {
+		mld := api.ModuleLoaderData{
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+		expectedStatus := &kmmv1beta1.ModuleUpgradeStatus{Phase: kmmv1beta1.UpgradePhaseInProgress}
+
+		mockUpgrade.EXPECT().Upgrade(ctx, ds, &mld).Return(expectedStatus, nil)
+
+		status, err := mhr.handleUpgrade(ctx, ds, &mld)
 
 		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(expectedStatus))
+	})
+})
+
+var _ = Describe("ModuleReconciler_diagnoseFailure", func() {
+	It("should diagnose the failure with the previous one", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockDiag := diagnostics.NewMockDiagnoser(ctrl)
+		mhr := newModuleReconcilerHelper(nil, nil, nil, mockDiag, nil, nil, nil, nil, nil, nil, nil, nil, nil, "")
+
+		ctx := context.Background()
+		mld := &api.ModuleLoaderData{KernelVersion: "kernelVersion"}
+		cause := fmt.Errorf("some error")
+		previous := &kmmv1beta1.StageFailure{Stage: kmmv1beta1.StageSign}
+		expected := &kmmv1beta1.StageFailure{Stage: kmmv1beta1.StageSign, Reason: kmmv1beta1.StageFailureReasonPushDenied}
+
+		mockDiag.EXPECT().Diagnose(ctx, mld, kmmv1beta1.StageSign, cause, previous).Return(expected, nil)
 
+		failure, err := mhr.diagnoseFailure(ctx, mld, kmmv1beta1.StageSign, cause, previous)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure).To(Equal(expected))
+	})
+})
+
+var _ = Describe("ModuleReconciler_handleBootLoading", func() {
+	var (
+		ctrl   *gomock.Controller
+		mockMC *machineconfig.MockManager
+		mhr    moduleReconcilerHelperAPI
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockMC = machineconfig.NewMockManager(ctrl)
+		mhr = newModuleReconcilerHelper(nil, nil, nil, nil, nil, nil, nil, mockMC, nil, nil, nil, nil, nil, "")
+	})
+
+	ctx := context.Background()
+	mod := &kmmv1beta1.Module{
+		Spec: kmmv1beta1.ModuleSpec{
+			BootLoading: &kmmv1beta1.BootLoadingSpec{MachineConfigPool: "worker"},
+		},
+	}
+
+	It("should render the MachineConfig from the images that are ready", func() {
+		mlds := []*api.ModuleLoaderData{{KernelVersion: "kernelVersion"}}
+
+		mockMC.EXPECT().Sync(ctx, mod, mlds).Return(fmt.Errorf("some error"))
+
+		Expect(mhr.handleBootLoading(ctx, mod, mlds)).To(HaveOccurred())
+	})
+
+	It("should delete the MachineConfig", func() {
+		mockMC.EXPECT().Delete(ctx, mod)
+
+		Expect(mhr.deleteBootLoading(ctx, mod)).To(Succeed())
 	})
 })
 
@@ -639,7 +1553,7 @@ var _ = Describe("ModuleReconciler_handleDevicePlugin", func() {
 		clnt = client.NewMockClient(ctrl)
 		mockDC = daemonset.NewMockDaemonSetCreator(ctrl)
 		mockMetrics = metrics.NewMockMetrics(ctrl)
-		mhr = newModuleReconcilerHelper(clnt, nil, nil, mockDC, nil, mockMetrics, "namespace")
+		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, mockDC, nil, nil, nil, nil, nil, nil, nil, mockMetrics, "namespace")
 	})
 
 	It("device plugin not defined", func() {
@@ -708,9 +1622,132 @@ var _ = Describe("ModuleReconciler_handleDevicePlugin", func() {
 	})
 })
 
+var _ = Describe("ModuleReconciler_getDependentModules", func() {
+	var (
+		ctrl *gomock.Controller
+		clnt *client.MockClient
+		mhr  moduleReconcilerHelperAPI
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "")
+	})
+
+	mod := &kmmv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "core",
+			Namespace: namespace,
+		},
+	}
+
+	It("list failed", func() {
+		clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("some error"))
+
+		_, err := mhr.getDependentModules(context.Background(), mod)
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return the sorted names of the modules depending on the module", func() {
+		clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+				list.Items = []kmmv1beta1.Module{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "dependent-b"},
+						Spec:       kmmv1beta1.ModuleSpec{DependsOn: []string{"other", "core"}},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "independent"},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "dependent-a"},
+						Spec:       kmmv1beta1.ModuleSpec{DependsOn: []string{"core"}},
+					},
+				}
+				return nil
+			},
+		)
+
+		dependents, err := mhr.getDependentModules(context.Background(), mod)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dependents).To(Equal([]string{"dependent-a", "dependent-b"}))
+	})
+})
+
+var _ = Describe("ModuleReconciler_adoptDaemonSetsWithoutArch", func() {
+	const kernelVersion = "1.2.3"
+
+	legacyDS := func() *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy"},
+		}
+	}
+
+	It("should adopt a DaemonSet without an architecture label", func() {
+		ds := legacyDS()
+		dsByKernelAndArch := map[string]*appsv1.DaemonSet{kernelVersion: ds}
+		mldMappings := map[string]*api.ModuleLoaderData{
+			kernelVersion + "/amd64": {KernelVersion: kernelVersion, Arch: "amd64"},
+		}
+
+		adoptDaemonSetsWithoutArch(context.Background(), dsByKernelAndArch, mldMappings)
+
+		Expect(dsByKernelAndArch).To(Equal(map[string]*appsv1.DaemonSet{kernelVersion + "/amd64": ds}))
+	})
+
+	It("should adopt the DaemonSet for the first architecture only", func() {
+		ds := legacyDS()
+		dsByKernelAndArch := map[string]*appsv1.DaemonSet{kernelVersion: ds}
+		mldMappings := map[string]*api.ModuleLoaderData{
+			kernelVersion + "/arm64": {KernelVersion: kernelVersion, Arch: "arm64"},
+			kernelVersion + "/amd64": {KernelVersion: kernelVersion, Arch: "amd64"},
+		}
+
+		adoptDaemonSetsWithoutArch(context.Background(), dsByKernelAndArch, mldMappings)
+
+		Expect(dsByKernelAndArch).To(Equal(map[string]*appsv1.DaemonSet{kernelVersion + "/amd64": ds}))
+	})
+
+	It("should not replace a DaemonSet with an architecture label", func() {
+		ds := legacyDS()
+		archDS := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "arch"},
+		}
+		dsByKernelAndArch := map[string]*appsv1.DaemonSet{
+			kernelVersion:            ds,
+			kernelVersion + "/amd64": archDS,
+		}
+		mldMappings := map[string]*api.ModuleLoaderData{
+			kernelVersion + "/amd64": {KernelVersion: kernelVersion, Arch: "amd64"},
+		}
+
+		adoptDaemonSetsWithoutArch(context.Background(), dsByKernelAndArch, mldMappings)
+
+		Expect(dsByKernelAndArch).To(
+			Equal(map[string]*appsv1.DaemonSet{kernelVersion: ds, kernelVersion + "/amd64": archDS}),
+		)
+	})
+
+	It("should not adopt DaemonSets of other kernel versions", func() {
+		ds := legacyDS()
+		dsByKernelAndArch := map[string]*appsv1.DaemonSet{"4.5.6": ds}
+		mldMappings := map[string]*api.ModuleLoaderData{
+			kernelVersion + "/amd64": {KernelVersion: kernelVersion, Arch: "amd64"},
+		}
+
+		adoptDaemonSetsWithoutArch(context.Background(), dsByKernelAndArch, mldMappings)
+
+		Expect(dsByKernelAndArch).To(Equal(map[string]*appsv1.DaemonSet{"4.5.6": ds}))
+	})
+})
+
 var _ = Describe("ModuleReconciler_garbageCollect", func() {
 	var (
 		ctrl   *gomock.Controller
+		clnt   *client.MockClient
 		mockBM *build.MockManager
 		mockSM *sign.MockSignManager
 		mockDC *daemonset.MockDaemonSetCreator
@@ -719,10 +1756,11 @@ var _ = Describe("ModuleReconciler_garbageCollect", func() {
 
 	BeforeEach(func() {
 		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
 		mockBM = build.NewMockManager(ctrl)
 		mockSM = sign.NewMockSignManager(ctrl)
 		mockDC = daemonset.NewMockDaemonSetCreator(ctrl)
-		mhr = newModuleReconcilerHelper(nil, mockBM, mockSM, mockDC, nil, nil, "")
+		mhr = newModuleReconcilerHelper(clnt, mockBM, mockSM, nil, mockDC, nil, nil, nil, nil, nil, nil, nil, nil, "")
 	})
 
 	mod := &kmmv1beta1.Module{
@@ -746,10 +1784,374 @@ var _ = Describe("ModuleReconciler_garbageCollect", func() {
 			mockSM.EXPECT().GarbageCollect(context.Background(), mod.Name, mod.Namespace, mod).Return(nil, nil),
 		)
 
-		err := mhr.garbageCollect(context.Background(), mod, mldMappings, existingDS)
+		err := mhr.garbageCollect(context.Background(), mod, mldMappings, existingDS, nil)
 
 		Expect(err).NotTo(HaveOccurred())
 	})
+
+	DescribeTable("should keep DaemonSets running on nodes where dependent modules are loaded",
+		func(nodeLabels map[string]string, expectedKernels sets.String) {
+			mldMappings := map[string]*api.ModuleLoaderData{"kernelVersion1": &api.ModuleLoaderData{}}
+			nodeSelector := map[string]string{"kernel": "kernelVersion2"}
+			existingDS := map[string]*appsv1.DaemonSet{
+				"kernelVersion2": {
+					Spec: appsv1.DaemonSetSpec{
+						Template: v1.PodTemplateSpec{
+							Spec: v1.PodSpec{NodeSelector: nodeSelector},
+						},
+					},
+				},
+			}
+			gomock.InOrder(
+				clnt.EXPECT().List(context.Background(), &v1.NodeList{}, gomock.Any()).DoAndReturn(
+					func(_ interface{}, list *v1.NodeList, opts ...interface{}) error {
+						Expect(opts).To(ConsistOf(ctrlclient.MatchingLabels(nodeSelector)))
+						list.Items = []v1.Node{{ObjectMeta: metav1.ObjectMeta{Labels: nodeLabels}}}
+						return nil
+					},
+				),
+				mockDC.EXPECT().GarbageCollect(context.Background(), existingDS, expectedKernels).Return(nil, nil),
+				mockBM.EXPECT().GarbageCollect(context.Background(), mod.Name, mod.Namespace, mod).Return(nil, nil),
+				mockSM.EXPECT().GarbageCollect(context.Background(), mod.Name, mod.Namespace, mod).Return(nil, nil),
+			)
+
+			err := mhr.garbageCollect(context.Background(), mod, mldMappings, existingDS, []string{"dependent"})
+
+			Expect(err).NotTo(HaveOccurred())
+		},
+		Entry(
+			"dependent loaded",
+			map[string]string{"kmm.node.kubernetes.io/dependent.ready": ""},
+			sets.NewString("kernelVersion1", "kernelVersion2"),
+		),
+		Entry(
+			"dependent not loaded",
+			map[string]string{"kmm.node.kubernetes.io/other.ready": ""},
+			sets.NewString("kernelVersion1"),
+		),
+	)
+
+	It("should return an error if the nodes cannot be listed", func() {
+		existingDS := map[string]*appsv1.DaemonSet{"kernelVersion2": &appsv1.DaemonSet{}}
+
+		clnt.EXPECT().List(context.Background(), &v1.NodeList{}, gomock.Any()).Return(fmt.Errorf("some error"))
+
+		err := mhr.garbageCollect(context.Background(), mod, nil, existingDS, []string{"dependent"})
+
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ModuleReconciler_deleteModuleDaemonSets", func() {
+	var (
+		ctrl   *gomock.Controller
+		clnt   *client.MockClient
+		mockDC *daemonset.MockDaemonSetCreator
+		mhr    moduleReconcilerHelperAPI
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		mockDC = daemonset.NewMockDaemonSetCreator(ctrl)
+		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, mockDC, nil, nil, nil, nil, nil, nil, nil, nil, "")
+	})
+
+	ctx := context.Background()
+
+	mod := &kmmv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Name: "moduleName", Namespace: "namespace"},
+	}
+
+	It("should delete the DaemonSets that are not being deleted yet", func() {
+		now := metav1.Now()
+
+		ds := appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds"}}
+		deletedDS := appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "deleted-ds", DeletionTimestamp: &now}}
+
+		gomock.InOrder(
+			mockDC.
+				EXPECT().
+				ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).
+				Return(map[string]*appsv1.DaemonSet{"kernel1": &ds, "kernel2": &deletedDS}, nil),
+			clnt.EXPECT().Delete(ctx, &ds).Return(apierrors.NewNotFound(schema.GroupResource{}, ds.Name)),
+		)
+
+		Expect(
+			mhr.deleteModuleDaemonSets(ctx, mod),
+		).To(Succeed())
+	})
+
+	It("should return an error if a DaemonSet cannot be deleted", func() {
+		ds := appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds"}}
+
+		gomock.InOrder(
+			mockDC.
+				EXPECT().
+				ModuleDaemonSetsByKernelAndArch(ctx, mod.Name, mod.Namespace).
+				Return(map[string]*appsv1.DaemonSet{"kernel1": &ds}, nil),
+			clnt.EXPECT().Delete(ctx, &ds).Return(fmt.Errorf("some error")),
+		)
+
+		Expect(
+			mhr.deleteModuleDaemonSets(ctx, mod),
+		).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("ModuleReconciler_handleUnload", func() {
+	var (
+		ctrl       *gomock.Controller
+		clnt       *client.MockClient
+		mockKernel *module.MockKernelMapper
+		mockLoad   *loadjob.MockManager
+		mhr        moduleReconcilerHelperAPI
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		mockKernel = module.NewMockKernelMapper(ctrl)
+		mockLoad = loadjob.NewMockManager(ctrl)
+		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, nil, nil, mockLoad, nil, mockKernel, nil, nil, nil, nil, "")
+	})
+
+	ctx := context.Background()
+
+	mod := &kmmv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Name: "moduleName", Namespace: "namespace"},
+	}
+
+	It("should wait for the pods and unload the kernel module from the other nodes", func() {
+		readyLabels := map[string]string{daemonset.GetDriverContainerNodeLabel(mod.Name): ""}
+
+		nodeWithPod := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-with-pod", Labels: readyLabels}}
+		readyNode := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "ready-node", Labels: readyLabels},
+			Status: v1.NodeStatus{
+				NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernel+", Architecture: "amd64"},
+			},
+		}
+		otherNode := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "other-node"}}
+		mld := api.ModuleLoaderData{KernelVersion: "kernel"}
+
+		gomock.InOrder(
+			clnt.EXPECT().List(ctx, &v1.NodeList{}).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{nodeWithPod, readyNode, otherNode}
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, &v1.PodList{}, gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.PodList, _ ...interface{}) error {
+					list.Items = []v1.Pod{
+						{Spec: v1.PodSpec{NodeName: nodeWithPod.Name}},
+					}
+					return nil
+				},
+			),
+			mockKernel.EXPECT().GetModuleLoaderDataForKernel(mod, "kernel", "amd64").Return(&mld, nil),
+			mockLoad.
+				EXPECT().
+				UnloadNodes(
+					ctx,
+					mod,
+					[]v1.Node{readyNode, otherNode},
+					map[string]*api.ModuleLoaderData{readyNode.Name: &mld},
+					[]string{"dependent"},
+				).
+				Return([]string{readyNode.Name}, nil),
+		)
+
+		remaining, err := mhr.handleUnload(ctx, mod, []string{"dependent"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remaining).To(Equal([]string{nodeWithPod.Name, readyNode.Name}))
+	})
+
+	It("should return an error if the nodes cannot be listed", func() {
+		clnt.EXPECT().List(ctx, &v1.NodeList{}).Return(fmt.Errorf("some error"))
+
+		_, err := mhr.handleUnload(ctx, mod, nil)
+
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ModuleReconciler_NodeModulesConfigs", func() {
+	var (
+		ctrl    *gomock.Controller
+		clnt    *client.MockClient
+		mockNMC *nmc.MockHelper
+		mhr     moduleReconcilerHelperAPI
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		mockNMC = nmc.NewMockHelper(ctrl)
+		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, nil, nil, nil, nil, nil, mockNMC, nil, nil, nil, "")
+	})
+
+	ctx := context.Background()
+
+	mod := &kmmv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Name: "moduleName", Namespace: "namespace"},
+	}
+
+	unloading := nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateUnloading}
+
+	It("should record the results of the load Jobs", func() {
+		mld := &api.ModuleLoaderData{
+			Name:           mod.Name,
+			Namespace:      mod.Namespace,
+			KernelVersion:  "kernel",
+			ContainerImage: "image",
+		}
+		nodes := []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "loaded"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "failed"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "loading"}},
+		}
+		res := loadjob.Result{
+			LoadedNodes: []string{"loaded"},
+			FailedNodes: []string{"failed"},
+			Errors:      map[string]string{"failed": "some error"},
+		}
+
+		gomock.InOrder(
+			mockNMC.EXPECT().SetState(ctx, "loaded", mod.Namespace, mod.Name, nmc.ModuleState{
+				State:          kmmv1beta1.ModuleLoadStateLoaded,
+				KernelVersion:  "kernel",
+				ContainerImage: "image",
+			}),
+			mockNMC.EXPECT().SetState(ctx, "failed", mod.Namespace, mod.Name, nmc.ModuleState{
+				State: kmmv1beta1.ModuleLoadStateFailed,
+				Error: "some error",
+			}),
+			mockNMC.EXPECT().SetState(ctx, "loading", mod.Namespace, mod.Name, nmc.ModuleState{
+				State: kmmv1beta1.ModuleLoadStateLoading,
+			}),
+		)
+
+		Expect(
+			mhr.recordLoadResult(ctx, mld, nodes, &res),
+		).NotTo(HaveOccurred())
+	})
+
+	It("should record the desired state and release the nodes that are not targeted anymore", func() {
+		node := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "targeted"},
+			Status: v1.NodeStatus{
+				NodeInfo: v1.NodeSystemInfo{KernelVersion: "kernel+", Architecture: "amd64"},
+			},
+		}
+		mappings := map[string]*api.ModuleLoaderData{
+			daemonset.KernelArchKey("kernel", "amd64"): {KernelVersion: "kernel", ContainerImage: "image"},
+		}
+
+		gomock.InOrder(
+			mockNMC.
+				EXPECT().
+				ModuleNodes(ctx, mod.Namespace, mod.Name).
+				Return(sets.NewString("targeted", "still-ready", "unloaded"), nil),
+			mockNMC.EXPECT().SetDesired(ctx, "targeted", mod.Namespace, mod.Name, "kernel", "image"),
+			clnt.
+				EXPECT().
+				Get(ctx, types.NamespacedName{Name: "still-ready"}, &v1.Node{}).
+				DoAndReturn(func(_ interface{}, _ interface{}, n *v1.Node, _ ...ctrlclient.GetOption) error {
+					n.Labels = map[string]string{daemonset.GetDriverContainerNodeLabel(mod.Name): ""}
+					return nil
+				}),
+			mockNMC.EXPECT().SetDesired(ctx, "still-ready", mod.Namespace, mod.Name, "", ""),
+			mockNMC.EXPECT().SetState(ctx, "still-ready", mod.Namespace, mod.Name, unloading),
+			clnt.EXPECT().Get(ctx, types.NamespacedName{Name: "unloaded"}, &v1.Node{}),
+			mockNMC.EXPECT().RemoveModule(ctx, "unloaded", mod.Namespace, mod.Name),
+		)
+
+		Expect(
+			mhr.syncNodeModulesConfigs(ctx, mod, mappings, []v1.Node{node}),
+		).NotTo(HaveOccurred())
+	})
+
+	It("should record the progress of the unload of a deleted module", func() {
+		gomock.InOrder(
+			mockNMC.EXPECT().ModuleNodes(ctx, mod.Namespace, mod.Name).Return(sets.NewString("node1", "node2"), nil),
+			mockNMC.EXPECT().RemoveModule(ctx, "node1", mod.Namespace, mod.Name),
+			mockNMC.EXPECT().SetDesired(ctx, "node2", mod.Namespace, mod.Name, "", ""),
+			mockNMC.EXPECT().SetState(ctx, "node2", mod.Namespace, mod.Name, unloading),
+			mockNMC.EXPECT().SetDesired(ctx, "node3", mod.Namespace, mod.Name, "", ""),
+			mockNMC.EXPECT().SetState(ctx, "node3", mod.Namespace, mod.Name, unloading),
+		)
+
+		Expect(
+			mhr.recordUnloadProgress(ctx, mod, []string{"node2", "node3"}),
+		).NotTo(HaveOccurred())
+	})
+
+	It("should return an error if the recorded nodes cannot be listed", func() {
+		mockNMC.EXPECT().ModuleNodes(ctx, mod.Namespace, mod.Name).Return(nil, fmt.Errorf("some error"))
+
+		Expect(
+			mhr.recordUnloadProgress(ctx, mod, nil),
+		).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ModuleReconciler_setFinalizer", func() {
+	var (
+		ctrl *gomock.Controller
+		clnt *client.MockClient
+		mhr  moduleReconcilerHelperAPI
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "")
+	})
+
+	ctx := context.Background()
+
+	It("should add the finalizer", func() {
+		mod := &kmmv1beta1.Module{}
+
+		clnt.
+			EXPECT().
+			Patch(ctx, mod, gomock.Any()).
+			Do(func(_ context.Context, o ctrlclient.Object, p ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+				Expect(p.Data(o)).To(
+					Equal([]byte(`{"metadata":{"finalizers":["kmm.node.kubernetes.io/module-finalizer"]}}`)),
+				)
+			})
+
+		Expect(
+			mhr.setFinalizer(ctx, mod),
+		).To(Succeed())
+	})
+
+	It("should do nothing if the finalizer is already set", func() {
+		mod := &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Finalizers: []string{constants.ModuleFinalizer}},
+		}
+
+		Expect(
+			mhr.setFinalizer(ctx, mod),
+		).To(Succeed())
+	})
+
+	It("should remove the finalizer", func() {
+		mod := &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Finalizers: []string{constants.ModuleFinalizer}},
+		}
+
+		clnt.EXPECT().Patch(ctx, mod, gomock.Any())
+
+		Expect(
+			mhr.removeFinalizer(ctx, mod),
+		).To(Succeed())
+		Expect(mod.Finalizers).To(BeEmpty())
+	})
 })
 
 var _ = Describe("ModuleReconciler_setKMMOMetrics", func() {
@@ -764,7 +2166,7 @@ var _ = Describe("ModuleReconciler_setKMMOMetrics", func() {
 		ctrl = gomock.NewController(GinkgoT())
 		clnt = client.NewMockClient(ctrl)
 		mockMetrics = metrics.NewMockMetrics(ctrl)
-		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, nil, mockMetrics, "")
+		mhr = newModuleReconcilerHelper(clnt, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockMetrics, "")
 	})
 
 	ctx := context.Background()
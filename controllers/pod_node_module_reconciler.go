@@ -3,10 +3,13 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/filter"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/nmc"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
@@ -19,16 +22,20 @@ import (
 
 //+kubebuilder:rbac:groups="core",resources=pods,verbs=get;patch;list;watch
 //+kubebuilder:rbac:groups="core",resources=nodes,verbs=get;watch
+//+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=modules,verbs=get
+//+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=nodemodulesconfigs,verbs=create;get;list;watch
+//+kubebuilder:rbac:groups=kmm.sigs.x-k8s.io,resources=nodemodulesconfigs/status,verbs=get;update;patch
 
 const PodNodeModuleReconcilerName = "PodNodeModule"
 
 type PodNodeModuleReconciler struct {
 	client    client.Client
 	daemonAPI daemonset.DaemonSetCreator
+	nmcHelper nmc.Helper
 }
 
-func NewPodNodeModuleReconciler(client client.Client, daemonAPI daemonset.DaemonSetCreator) *PodNodeModuleReconciler {
-	return &PodNodeModuleReconciler{client: client, daemonAPI: daemonAPI}
+func NewPodNodeModuleReconciler(client client.Client, daemonAPI daemonset.DaemonSetCreator, nmcHelper nmc.Helper) *PodNodeModuleReconciler {
+	return &PodNodeModuleReconciler{client: client, daemonAPI: daemonAPI, nmcHelper: nmcHelper}
 }
 
 func (pnmr *PodNodeModuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -61,6 +68,13 @@ func (pnmr *PodNodeModuleReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		"label name", labelName,
 	)
 
+	// the state of the kernel module is recorded for ModuleLoader pods only, not for device plugin pods
+	if labelName == daemonset.GetDriverContainerNodeLabel(moduleName) {
+		if err := pnmr.recordModuleState(ctx, &pod, moduleName); err != nil {
+			return ctrl.Result{}, fmt.Errorf("could not record the state of module %s on node %s: %v", moduleName, nodeName, err)
+		}
+	}
+
 	// when Daemonset/ReplicaSet controller deletes pod, the pods state stays Ready,
 	// but its deletion timestamp is set. We use deletion timestamp to delete the label,
 	// and not wait a probable TerminationGracePeriod, since Pre-Stop hooks is run
@@ -68,10 +82,20 @@ func (pnmr *PodNodeModuleReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	// has changed not due to Daemonset termination, but due to internal state of Daemonset on
 	// cluster
 	if !podutils.IsPodReady(&pod) || !pod.DeletionTimestamp.IsZero() {
-		logger.Info("Unlabeling node")
+		moduleDeleted, err := pnmr.isModuleBeingDeleted(ctx, moduleName, pod.Namespace)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("could not check if module %s is being deleted: %v", moduleName, err)
+		}
 
-		if err := pnmr.deleteLabel(ctx, nodeName, labelName); err != nil {
-			return ctrl.Result{}, fmt.Errorf("could not unlabel node %s: %v", nodeName, err)
+		// The Module reconciler removes the labels once the kernel module is confirmed to be unloaded
+		if moduleDeleted {
+			logger.Info("Module being deleted; not unlabeling the node")
+		} else {
+			logger.Info("Unlabeling node")
+
+			if err = pnmr.deleteLabel(ctx, nodeName, labelName); err != nil {
+				return ctrl.Result{}, fmt.Errorf("could not unlabel node %s: %v", nodeName, err)
+			}
 		}
 
 		if !pod.DeletionTimestamp.IsZero() {
@@ -106,6 +130,7 @@ func (pnmr *PodNodeModuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				mgr.GetLogger().WithName("pod-readiness-changed"),
 			),
 			filter.DeletingPredicate(),
+			filter.PodContainerStatusChangedPredicate(),
 		),
 		filter.HasLabel(constants.ModuleNameLabel),
 		filter.PodHasSpecNodeName(),
@@ -137,6 +162,102 @@ func (pnmr *PodNodeModuleReconciler) addLabel(ctx context.Context, nodeName, lab
 	return pnmr.client.Patch(ctx, &node, client.MergeFrom(nodeCopy))
 }
 
+// isModuleBeingDeleted returns true if the Module exists and has a deletion timestamp.
+func (pnmr *PodNodeModuleReconciler) isModuleBeingDeleted(ctx context.Context, name, namespace string) (bool, error) {
+	mod := kmmv1beta1.Module{}
+
+	if err := pnmr.client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &mod); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	return !mod.DeletionTimestamp.IsZero(), nil
+}
+
+// recordModuleState records the state of the kernel module loaded by the ModuleLoader pod in the NodeModulesConfig of
+// its node.
+func (pnmr *PodNodeModuleReconciler) recordModuleState(ctx context.Context, pod *v1.Pod, moduleName string) error {
+	st := moduleLoaderState(pod)
+
+	if st.State == kmmv1beta1.ModuleLoadStateLoaded {
+		node := v1.Node{}
+
+		if err := pnmr.client.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, &node); err != nil {
+			return fmt.Errorf("could not get node %s: %v", pod.Spec.NodeName, err)
+		}
+
+		// ModuleLoader pods only run on nodes with the kernel version they were made for
+		st.KernelVersion = strings.TrimSuffix(node.Status.NodeInfo.KernelVersion, "+")
+	}
+
+	return pnmr.nmcHelper.SetState(ctx, pod.Spec.NodeName, pod.Namespace, moduleName, st)
+}
+
+// moduleLoaderState returns the state of the kernel module loaded by the ModuleLoader pod, without its kernel version.
+func moduleLoaderState(pod *v1.Pod) nmc.ModuleState {
+	if !pod.DeletionTimestamp.IsZero() {
+		return nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateUnloading}
+	}
+
+	if podutils.IsPodReady(pod) {
+		st := nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateLoaded}
+
+		if len(pod.Spec.Containers) > 0 {
+			st.ContainerImage = pod.Spec.Containers[0].Image
+		}
+
+		if len(pod.Status.ContainerStatuses) > 0 {
+			st.ImageDigest = imageDigest(pod.Status.ContainerStatuses[0].ImageID)
+		}
+
+		return st
+	}
+
+	for i := range pod.Status.ContainerStatuses {
+		if msg := containerError(&pod.Status.ContainerStatuses[i]); msg != "" {
+			return nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateFailed, Error: msg}
+		}
+	}
+
+	return nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateLoading}
+}
+
+// containerError returns the error that prevents the container from running, or an empty string.
+// The termination message of the container is used if it failed.
+func containerError(cs *v1.ContainerStatus) string {
+	if t := cs.State.Terminated; t != nil && t.ExitCode != 0 {
+		return terminationError(t)
+	}
+
+	w := cs.State.Waiting
+	if w == nil || w.Reason == "" || w.Reason == "ContainerCreating" || w.Reason == "PodInitializing" {
+		return ""
+	}
+
+	if t := cs.LastTerminationState.Terminated; t != nil && t.ExitCode != 0 {
+		return terminationError(t)
+	}
+
+	return fmt.Sprintf("%s: %s", w.Reason, w.Message)
+}
+
+func terminationError(t *v1.ContainerStateTerminated) string {
+	if t.Message != "" {
+		return strings.TrimSpace(t.Message)
+	}
+
+	return fmt.Sprintf("container exited with code %d: %s", t.ExitCode, t.Reason)
+}
+
+// imageDigest returns the digest part of imageID, as reported by the container runtime, or an empty string.
+func imageDigest(imageID string) string {
+	i := strings.LastIndex(imageID, "@")
+	if i == -1 {
+		return ""
+	}
+
+	return imageID[i+1:]
+}
+
 func (pnmr *PodNodeModuleReconciler) deleteFinalizer(ctx context.Context, pod *v1.Pod) error {
 	podCopy := pod.DeepCopy()
 
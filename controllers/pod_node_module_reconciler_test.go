@@ -2,15 +2,20 @@ package controllers
 
 import (
 	"context"
+	"errors"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	mock_client "github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/nmc"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -30,13 +35,15 @@ var _ = Describe("PodNodeModuleReconciler", func() {
 			kubeClient *mock_client.MockClient
 			r          *PodNodeModuleReconciler
 			mockDC     *daemonset.MockDaemonSetCreator
+			mockNMC    *nmc.MockHelper
 		)
 
 		BeforeEach(func() {
 			ctrl := gomock.NewController(GinkgoT())
 			kubeClient = mock_client.NewMockClient(ctrl)
 			mockDC = daemonset.NewMockDaemonSetCreator(ctrl)
-			r = NewPodNodeModuleReconciler(kubeClient, mockDC)
+			mockNMC = nmc.NewMockHelper(ctrl)
+			r = NewPodNodeModuleReconciler(kubeClient, mockDC, mockNMC)
 		})
 
 		ctx := context.Background()
@@ -75,6 +82,7 @@ var _ = Describe("PodNodeModuleReconciler", func() {
 						o.(*v1.Pod).Spec.NodeName = nodeName
 					}),
 				mockDC.EXPECT().GetNodeLabelFromPod(&podWithModuleName, moduleName).Return(nodeLabel),
+				kubeClient.EXPECT().Get(ctx, types.NamespacedName{Name: moduleName}, &kmmv1beta1.Module{}),
 				kubeClient.
 					EXPECT().
 					Get(ctx, types.NamespacedName{Name: nodeName}, &node).
@@ -187,6 +195,10 @@ var _ = Describe("PodNodeModuleReconciler", func() {
 						o.SetFinalizers([]string{constants.NodeLabelerFinalizer})
 					}),
 				mockDC.EXPECT().GetNodeLabelFromPod(&deletedPod, moduleName).Return(nodeLabel),
+				kubeClient.
+					EXPECT().
+					Get(ctx, types.NamespacedName{Name: moduleName}, &kmmv1beta1.Module{}).
+					Return(apierrors.NewNotFound(schema.GroupResource{}, moduleName)),
 				kubeClient.
 					EXPECT().
 					Get(ctx, types.NamespacedName{Name: nodeName}, &node).
@@ -220,5 +232,199 @@ var _ = Describe("PodNodeModuleReconciler", func() {
 			_, err := r.Reconcile(ctx, req)
 			Expect(err).NotTo(HaveOccurred())
 		})
+
+		It("should only remove the pod finalizer when the module is being deleted", func() {
+			now := metav1.Now()
+
+			deletedPod := v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: &now,
+					Finalizers:        []string{constants.NodeLabelerFinalizer},
+					Labels:            map[string]string{constants.ModuleNameLabel: moduleName},
+				},
+				Spec: v1.PodSpec{NodeName: nodeName},
+			}
+
+			gomock.InOrder(
+				kubeClient.
+					EXPECT().
+					Get(ctx, nn, &v1.Pod{}).
+					Do(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) {
+						deletedPod.DeepCopyInto(o.(*v1.Pod))
+					}),
+				mockDC.EXPECT().GetNodeLabelFromPod(&deletedPod, moduleName).Return(nodeLabel),
+				kubeClient.
+					EXPECT().
+					Get(ctx, types.NamespacedName{Name: moduleName}, &kmmv1beta1.Module{}).
+					Do(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) {
+						o.SetDeletionTimestamp(&now)
+					}),
+				kubeClient.
+					EXPECT().
+					Patch(ctx, gomock.AssignableToTypeOf(&v1.Pod{}), gomock.Any()).
+					Do(func(_ context.Context, po client.Object, p client.Patch, _ ...client.PatchOption) {
+						Expect(p.Data(po)).To(
+							Equal(
+								[]byte(`{"metadata":{"finalizers":null}}`),
+							),
+						)
+					}),
+			)
+
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should record the kernel module loaded by a ready ModuleLoader pod", func() {
+			readyLabel := daemonset.GetDriverContainerNodeLabel(moduleName)
+
+			readyPod := v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      podName,
+					Namespace: podNamespace,
+					Labels:    map[string]string{constants.ModuleNameLabel: moduleName},
+				},
+				Spec: v1.PodSpec{
+					NodeName:   nodeName,
+					Containers: []v1.Container{{Image: "example.org/repo/image:tag"}},
+				},
+				Status: v1.PodStatus{
+					Conditions:        []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+					ContainerStatuses: []v1.ContainerStatus{{ImageID: "example.org/repo/image@sha256:1234"}},
+				},
+			}
+
+			gomock.InOrder(
+				kubeClient.
+					EXPECT().
+					Get(ctx, nn, &v1.Pod{}).
+					Do(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) {
+						readyPod.DeepCopyInto(o.(*v1.Pod))
+					}),
+				mockDC.EXPECT().GetNodeLabelFromPod(&readyPod, moduleName).Return(readyLabel),
+				kubeClient.
+					EXPECT().
+					Get(ctx, types.NamespacedName{Name: nodeName}, &v1.Node{}).
+					Do(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) {
+						o.(*v1.Node).Status.NodeInfo.KernelVersion = "kernel+"
+					}),
+				mockNMC.EXPECT().SetState(ctx, nodeName, podNamespace, moduleName, nmc.ModuleState{
+					State:          kmmv1beta1.ModuleLoadStateLoaded,
+					KernelVersion:  "kernel",
+					ContainerImage: "example.org/repo/image:tag",
+					ImageDigest:    "sha256:1234",
+				}),
+				kubeClient.EXPECT().Get(ctx, types.NamespacedName{Name: nodeName}, &v1.Node{}),
+				kubeClient.EXPECT().Patch(ctx, gomock.AssignableToTypeOf(&v1.Node{}), gomock.Any()),
+			)
+
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return an error if the state of the kernel module cannot be recorded", func() {
+			now := metav1.Now()
+
+			deletedPod := v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              podName,
+					Namespace:         podNamespace,
+					DeletionTimestamp: &now,
+					Labels:            map[string]string{constants.ModuleNameLabel: moduleName},
+				},
+				Spec: v1.PodSpec{NodeName: nodeName},
+			}
+
+			gomock.InOrder(
+				kubeClient.
+					EXPECT().
+					Get(ctx, nn, &v1.Pod{}).
+					Do(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) {
+						deletedPod.DeepCopyInto(o.(*v1.Pod))
+					}),
+				mockDC.EXPECT().GetNodeLabelFromPod(&deletedPod, moduleName).Return(daemonset.GetDriverContainerNodeLabel(moduleName)),
+				mockNMC.
+					EXPECT().
+					SetState(ctx, nodeName, podNamespace, moduleName, nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateUnloading}).
+					Return(errors.New("some error")),
+			)
+
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
+
+var _ = Describe("moduleLoaderState", func() {
+	now := metav1.Now()
+
+	DescribeTable("should return the expected state",
+		func(pod *v1.Pod, expected nmc.ModuleState) {
+			Expect(moduleLoaderState(pod)).To(Equal(expected))
+		},
+		Entry(
+			"pod being deleted",
+			&v1.Pod{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now}},
+			nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateUnloading},
+		),
+		Entry(
+			"container being created",
+			&v1.Pod{
+				Status: v1.PodStatus{
+					ContainerStatuses: []v1.ContainerStatus{
+						{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+					},
+				},
+			},
+			nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateLoading},
+		),
+		Entry(
+			"image cannot be pulled",
+			&v1.Pod{
+				Status: v1.PodStatus{
+					ContainerStatuses: []v1.ContainerStatus{
+						{
+							State: v1.ContainerState{
+								Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "image not found"},
+							},
+						},
+					},
+				},
+			},
+			nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateFailed, Error: "ImagePullBackOff: image not found"},
+		),
+		Entry(
+			"modprobe failed",
+			&v1.Pod{
+				Status: v1.PodStatus{
+					ContainerStatuses: []v1.ContainerStatus{
+						{
+							State: v1.ContainerState{
+								Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+							},
+							LastTerminationState: v1.ContainerState{
+								Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Message: "modprobe: FATAL: Module not found\n"},
+							},
+						},
+					},
+				},
+			},
+			nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateFailed, Error: "modprobe: FATAL: Module not found"},
+		),
+		Entry(
+			"container exited without a termination message",
+			&v1.Pod{
+				Status: v1.PodStatus{
+					ContainerStatuses: []v1.ContainerStatus{
+						{
+							State: v1.ContainerState{
+								Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"},
+							},
+						},
+					},
+				},
+			},
+			nmc.ModuleState{State: kmmv1beta1.ModuleLoadStateFailed, Error: "container exited with code 137: OOMKilled"},
+		),
+	)
+})
@@ -199,6 +199,7 @@ func (r *PreflightValidationOCPReconciler) preparePreflightValidation(ctx contex
 		Spec: kmmv1beta1.PreflightValidationSpec{
 			KernelVersion:  kernelVersion,
 			PushBuiltImage: pvo.Spec.PushBuiltImage,
+			DTKImage:       dtkImage,
 		},
 	}
 
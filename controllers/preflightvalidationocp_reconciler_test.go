@@ -227,6 +227,7 @@ var _ = Describe("PreflightValidationOCPReconciler_getDTKFromImage", func() {
 			Expect(err).To(BeNil())
 			Expect(pv.Spec.KernelVersion).To(Equal(dtkReleaseData.KernelVersion))
 			Expect(pv.Spec.PushBuiltImage).To(Equal(pvo.Spec.PushBuiltImage))
+			Expect(pv.Spec.DTKImage).To(Equal(dtkImageReference))
 		})
 
 		It("good flow with RT kernel", func() {
@@ -15,6 +15,10 @@ import (
 type ModuleLoaderData struct {
 	// kernel version
 	KernelVersion string
+
+	// Arch is the architecture of the nodes, using the Kubernetes naming (amd64, arm64...).
+	// It is empty if the architecture is not known.
+	Arch string
 	// Repo secret for DS images
 	ImageRepoSecret *v1.LocalObjectReference
 
@@ -27,6 +31,27 @@ type ModuleLoaderData struct {
 	// Namspace
 	Namespace string
 
+	// Labels are additional labels for the DS and its pods
+	Labels map[string]string
+
+	// Annotations are additional annotations for the DS pods
+	Annotations map[string]string
+
+	// Tolerations for the DS pods
+	Tolerations []v1.Toleration
+
+	// Affinity for the DS pods
+	Affinity *v1.Affinity
+
+	// Volumes are additional volumes for the DS pods
+	Volumes []v1.Volume
+
+	// VolumeMounts are additional volume mounts for the module loader container
+	VolumeMounts []v1.VolumeMount
+
+	// Resources of the module loader container
+	Resources v1.ResourceRequirements
+
 	// service account for DS
 	ServiceAccountName string
 
@@ -48,6 +73,27 @@ type ModuleLoaderData struct {
 	// RegistryTLS set the TLS configs for accessing the registry of the module-loader's image.
 	RegistryTLS *kmmv1beta1.TLSOptions
 
+	// ImageDigestResolution describes when the tag of ContainerImage is resolved to a digest.
+	ImageDigestResolution *kmmv1beta1.ImageDigestResolution
+
+	// UpgradeStrategy describes how the module loader pods are replaced when the module loader changes.
+	UpgradeStrategy *kmmv1beta1.UpgradeStrategy
+
+	// DependsOn lists the Modules that must be ready on a node before this Module is loaded.
+	DependsOn []string
+
+	// Dependents lists the Modules that depend on this Module.
+	Dependents []string
+
+	// LoadMode describes how the kernel module is loaded on the nodes.
+	LoadMode kmmv1beta1.LoadMode
+
+	// BootLoading, if set, describes how the kernel module is also loaded when the nodes boot.
+	BootLoading *kmmv1beta1.BootLoadingSpec
+
+	// BuildPriority orders the builds and sign Jobs of the Module in the queue of the operator.
+	BuildPriority int32
+
 	// used for setting the owner field of jobs/buildconfigs
 	Owner metav1.Object
 }
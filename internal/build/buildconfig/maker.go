@@ -2,6 +2,7 @@ package buildconfig
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -10,6 +11,8 @@ import (
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	kmmbuild "github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
@@ -39,14 +42,24 @@ type Maker interface {
 type maker struct {
 	client             client.Client
 	helper             kmmbuild.Helper
+	gitResolver        git.Resolver
+	cache              cache.Cache
 	kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping
 	scheme             *runtime.Scheme
 }
 
-func NewMaker(client client.Client, helper kmmbuild.Helper, scheme *runtime.Scheme, kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping) Maker {
+func NewMaker(
+	client client.Client,
+	helper kmmbuild.Helper,
+	gitResolver git.Resolver,
+	buildCache cache.Cache,
+	scheme *runtime.Scheme,
+	kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping) Maker {
 	return &maker{
 		client:             client,
 		helper:             helper,
+		gitResolver:        gitResolver,
+		cache:              buildCache,
 		kernelOsDtkMapping: kernelOsDtkMapping,
 		scheme:             scheme,
 	}
@@ -78,16 +91,21 @@ func (m *maker) MakeBuildTemplate(
 
 	dockerfileData, err := m.getDockerfileData(ctx, kmmBuild, mld.Namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get dockerfile data from configmap: %v", err)
+		return nil, fmt.Errorf("failed to get dockerfile data: %v", err)
 	}
 
-	if strings.Contains(dockerfileData, dtkBuildArg) {
+	var dtkImage string
 
-		dtkImage, err := m.kernelOsDtkMapping.GetImage(kernelVersion)
-		if err != nil {
+	// the Dockerfile of a Git repository is not known in advance and may not use the DTK
+	if dockerfileData == "" || strings.Contains(dockerfileData, dtkBuildArg) {
+		image, err := m.kernelOsDtkMapping.GetImage(kernelVersion)
+		switch {
+		case err == nil:
+			dtkImage = image
+			overrides = append(overrides, kmmv1beta1.BuildArg{Name: dtkBuildArg, Value: dtkImage})
+		case dockerfileData != "":
 			return nil, fmt.Errorf("could not get DTK image for kernel %v: %v", kernelVersion, err)
 		}
-		overrides = append(overrides, kmmv1beta1.BuildArg{Name: dtkBuildArg, Value: dtkImage})
 	}
 
 	buildArgs := m.helper.ApplyBuildArgOverrides(
@@ -95,20 +113,44 @@ func (m *maker) MakeBuildTemplate(
 		overrides...,
 	)
 
-	buildTarget := buildv1.BuildOutput{
-		To: &v1.ObjectReference{
-			Kind: "DockerImage",
-			Name: containerImage,
-		},
-		PushSecret: mld.ImageRepoSecret,
+	sourceConfig := buildv1.BuildSource{
+		Type: buildv1.BuildSourceDockerfile,
+	}
+
+	if dockerfileData != "" {
+		sourceConfig.Dockerfile = &dockerfileData
 	}
-	if !pushImage {
-		buildTarget = buildv1.BuildOutput{}
+
+	cacheInputs := cache.Inputs{
+		Dockerfile: dockerfileData,
+		BuildArgs:  buildArgs,
+		DTKImage:   dtkImage,
+		Arch:       mld.Arch,
 	}
 
-	sourceConfig := buildv1.BuildSource{
-		Dockerfile: &dockerfileData,
-		Type:       buildv1.BuildSourceDockerfile,
+	var imageLabels []buildv1.ImageLabel
+
+	// the commit is part of the hash, so that a new commit on the branch triggers a new Build
+	if g := kmmBuild.Git; g != nil {
+		rev, err := m.gitResolver.Resolve(ctx, g, mld.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve Git ref %q: %v", g.Ref, err)
+		}
+
+		cacheInputs.GitURI = g.URI
+		cacheInputs.GitCommit = rev.Commit
+		cacheInputs.GitContextDir = g.ContextDir
+
+		sourceConfig.Type = buildv1.BuildSourceGit
+		sourceConfig.Git = &buildv1.GitBuildSource{URI: g.URI, Ref: rev.Commit}
+		sourceConfig.ContextDir = g.ContextDir
+
+		// the commit is recorded on the image, so that ShouldSync rebuilds it when the branch moves
+		imageLabels = []buildv1.ImageLabel{{Name: git.CommitLabel, Value: rev.Commit}}
+
+		if g.SourceSecret != nil {
+			sourceConfig.SourceSecret = &v1.LocalObjectReference{Name: g.SourceSecret.Name}
+		}
 	}
 
 	sourceConfigHash, err := hashstructure.Hash(sourceConfig, nil)
@@ -116,12 +158,33 @@ func (m *maker) MakeBuildTemplate(
 		return nil, fmt.Errorf("could not hash Build's Buildsource template: %v", err)
 	}
 
+	annotations := map[string]string{buildHashAnnotation: fmt.Sprintf("%d", sourceConfigHash)}
+
+	buildTarget := buildv1.BuildOutput{ImageLabels: imageLabels}
+
+	if pushImage {
+		// identical builds push to the cache repository, from which their result is copied to the container image
+		if key := m.cache.Key(mld, &cacheInputs); key != "" {
+			containerImage = m.cache.Image(key)
+			annotations[cache.KeyAnnotation] = key
+		}
+
+		buildTarget = buildv1.BuildOutput{
+			To: &v1.ObjectReference{
+				Kind: "DockerImage",
+				Name: containerImage,
+			},
+			PushSecret:  mld.ImageRepoSecret,
+			ImageLabels: imageLabels,
+		}
+	}
+
 	bc := buildv1.Build{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: mld.Name + "-",
 			Namespace:    mld.Namespace,
 			Labels:       kmmbuild.GetBuildLabels(mld),
-			Annotations:  map[string]string{buildHashAnnotation: fmt.Sprintf("%d", sourceConfigHash)},
+			Annotations:  annotations,
 		},
 		Spec: buildv1.BuildSpec{
 			CommonSpec: buildv1.CommonSpec{
@@ -135,7 +198,7 @@ func (m *maker) MakeBuildTemplate(
 					},
 				},
 				Output:         buildTarget,
-				NodeSelector:   mld.Selector,
+				NodeSelector:   module.NodeSelector(mld),
 				MountTrustedCA: pointer.Bool(true),
 			},
 		},
@@ -148,7 +211,21 @@ func (m *maker) MakeBuildTemplate(
 	return &bc, nil
 }
 
+// getDockerfileData returns the inline Dockerfile or the one of the Dockerfile ConfigMap, or an empty string if the
+// Dockerfile is read from the Git repository.
 func (m *maker) getDockerfileData(ctx context.Context, buildConfig *kmmv1beta1.Build, namespace string) (string, error) {
+	if buildConfig.Dockerfile != "" {
+		return buildConfig.Dockerfile, nil
+	}
+
+	if buildConfig.DockerfileConfigMap == nil {
+		if buildConfig.Git == nil {
+			return "", errors.New("no Dockerfile ConfigMap, inline Dockerfile nor Git repository")
+		}
+
+		return "", nil
+	}
+
 	dockerfileCM := &corev1.ConfigMap{}
 	namespacedName := types.NamespacedName{Name: buildConfig.DockerfileConfigMap.Name, Namespace: namespace}
 	err := m.client.Get(ctx, namespacedName, dockerfileCM)
@@ -21,6 +21,8 @@ import (
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
@@ -40,6 +42,7 @@ var _ = Describe("Maker_MakeBuildTemplate", func() {
 		clnt                   *client.MockClient
 		maker                  Maker
 		mockBuildHelper        *build.MockHelper
+		mockGitResolver        *git.MockResolver
 		mockKernelOSDTKMapping *syncronizedmap.MockKernelOsDtkMapping
 		ctx                    context.Context
 	)
@@ -48,8 +51,9 @@ var _ = Describe("Maker_MakeBuildTemplate", func() {
 		ctrl = gomock.NewController(GinkgoT())
 		clnt = client.NewMockClient(ctrl)
 		mockBuildHelper = build.NewMockHelper(ctrl)
+		mockGitResolver = git.NewMockResolver(ctrl)
 		mockKernelOSDTKMapping = syncronizedmap.NewMockKernelOsDtkMapping(ctrl)
-		maker = NewMaker(clnt, mockBuildHelper, scheme, mockKernelOSDTKMapping)
+		maker = NewMaker(clnt, mockBuildHelper, mockGitResolver, cache.New("", nil, nil), scheme, mockKernelOSDTKMapping)
 		ctx = context.Background()
 	})
 
@@ -240,6 +244,138 @@ var _ = Describe("Maker_MakeBuildTemplate", func() {
 			Expect(bct.Spec.CommonSpec.Strategy.DockerStrategy.BuildArgs[0].Value).To(Equal(buildArgs[0].Value))
 		})
 	})
+
+	It("should use the inline Dockerfile", func() {
+		mockBuildHelper.EXPECT().ApplyBuildArgOverrides(gomock.Any(), gomock.Any())
+
+		mld := api.ModuleLoaderData{
+			Build: &kmmv1beta1.Build{Dockerfile: dockerFile},
+			Owner: &kmmv1beta1.Module{},
+		}
+
+		bc, err := maker.MakeBuildTemplate(ctx, &mld, false, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.Spec.Source.Type).To(Equal(buildv1.BuildSourceDockerfile))
+		Expect(bc.Spec.Source.Dockerfile).To(Equal(pointer.String(dockerFile)))
+	})
+
+	It("should return an error if no source is set", func() {
+		mld := api.ModuleLoaderData{
+			Build: &kmmv1beta1.Build{},
+			Owner: &kmmv1beta1.Module{},
+		}
+
+		_, err := maker.MakeBuildTemplate(ctx, &mld, false, mld.Owner)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should push to the cache repository if the build is cached", func() {
+		mockCache := cache.NewMockCache(ctrl)
+		maker = NewMaker(clnt, mockBuildHelper, mockGitResolver, mockCache, scheme, mockKernelOSDTKMapping)
+
+		buildArgs := []kmmv1beta1.BuildArg{{Name: "KERNEL_VERSION", Value: targetKernel}}
+
+		mld := api.ModuleLoaderData{
+			Build:          &kmmv1beta1.Build{Dockerfile: dockerFile},
+			ContainerImage: containerImage,
+			KernelVersion:  targetKernel,
+			Arch:           "x86_64",
+			Owner:          &kmmv1beta1.Module{},
+		}
+
+		gomock.InOrder(
+			mockBuildHelper.EXPECT().ApplyBuildArgOverrides(gomock.Any(), gomock.Any()).Return(buildArgs),
+			mockCache.EXPECT().Key(&mld, &cache.Inputs{Dockerfile: dockerFile, BuildArgs: buildArgs, Arch: "x86_64"}).Return("some-key"),
+			mockCache.EXPECT().Image("some-key").Return("registry.example.com/cache:some-key"),
+		)
+
+		bc, err := maker.MakeBuildTemplate(ctx, &mld, true, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.Spec.Output.To.Name).To(Equal("registry.example.com/cache:some-key"))
+		Expect(bc.Annotations).To(HaveKeyWithValue(cache.KeyAnnotation, "some-key"))
+	})
+
+	Context("using a Git repository", func() {
+		const commit = "0123456789abcdef0123456789abcdef01234567"
+
+		gitSource := kmmv1beta1.GitBuildSource{
+			URI:          "https://git.example.com/org/repo.git",
+			Ref:          "main",
+			ContextDir:   "driver",
+			SourceSecret: &v1.LocalObjectReference{Name: "git-secret"},
+		}
+
+		It("should build the resolved commit", func() {
+			gomock.InOrder(
+				mockKernelOSDTKMapping.EXPECT().GetImage(targetKernel).Return("", errors.New("no DTK")),
+				mockBuildHelper.EXPECT().ApplyBuildArgOverrides(gomock.Any(), gomock.Any()),
+				mockGitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(&git.Revision{Ref: "refs/heads/main", Commit: commit}, nil),
+			)
+
+			mld := api.ModuleLoaderData{
+				Build:         &kmmv1beta1.Build{Git: &gitSource},
+				KernelVersion: targetKernel,
+				Namespace:     namespace,
+				Owner:         &kmmv1beta1.Module{},
+			}
+
+			bc, err := maker.MakeBuildTemplate(ctx, &mld, false, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+
+			expected := buildv1.BuildSource{
+				Type:         buildv1.BuildSourceGit,
+				Git:          &buildv1.GitBuildSource{URI: gitSource.URI, Ref: commit},
+				ContextDir:   "driver",
+				SourceSecret: &v1.LocalObjectReference{Name: "git-secret"},
+			}
+
+			Expect(bc.Spec.Source).To(Equal(expected))
+			Expect(bc.Spec.Output.ImageLabels).To(Equal([]buildv1.ImageLabel{{Name: git.CommitLabel, Value: commit}}))
+		})
+
+		It("should change the hash when the ref points to a new commit", func() {
+			mockKernelOSDTKMapping.EXPECT().GetImage(targetKernel).Return("", errors.New("no DTK")).Times(2)
+			mockBuildHelper.EXPECT().ApplyBuildArgOverrides(gomock.Any(), gomock.Any()).Times(2)
+			gomock.InOrder(
+				mockGitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(&git.Revision{Commit: commit}, nil),
+				mockGitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(
+					&git.Revision{Commit: "89abcdef0123456789abcdef0123456789abcdef"},
+					nil,
+				),
+			)
+
+			mld := api.ModuleLoaderData{
+				Build:         &kmmv1beta1.Build{Git: &gitSource},
+				KernelVersion: targetKernel,
+				Namespace:     namespace,
+				Owner:         &kmmv1beta1.Module{},
+			}
+
+			bc1, err := maker.MakeBuildTemplate(ctx, &mld, false, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+
+			bc2, err := maker.MakeBuildTemplate(ctx, &mld, false, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(bc1.Annotations[buildHashAnnotation]).NotTo(Equal(bc2.Annotations[buildHashAnnotation]))
+		})
+
+		It("should return an error if the ref cannot be resolved", func() {
+			gomock.InOrder(
+				mockBuildHelper.EXPECT().ApplyBuildArgOverrides(gomock.Any(), gomock.Any()),
+				mockGitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(nil, errors.New("random error")),
+			)
+
+			mld := api.ModuleLoaderData{
+				Build:     &kmmv1beta1.Build{Dockerfile: dockerFile, Git: &gitSource},
+				Namespace: namespace,
+				Owner:     &kmmv1beta1.Module{},
+			}
+
+			_, err := maker.MakeBuildTemplate(ctx, &mld, false, mld.Owner)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
 
 var _ = Describe("envVarsFromKMMBuildArgs", func() {
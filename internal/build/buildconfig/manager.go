@@ -4,32 +4,69 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
 	kmmbuild "github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/retry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 )
 
-const buildHashAnnotation = "kmm.node.kubernetes.io/last-hash"
+const (
+	buildHashAnnotation = "kmm.node.kubernetes.io/last-hash"
+
+	// buildSupersededAnnotation marks a finished Build whose spec is not current anymore.
+	// Superseded Builds are ignored by GetBuild and garbage-collected.
+	buildSupersededAnnotation = "kmm.node.kubernetes.io/superseded"
+)
 
 var (
 	errNoMatchingBuild = errors.New("no matching Build")
 )
 
+// GCPolicy defines which failed Builds are retained by GarbageCollect, so that they can be inspected.
+// Succeeded Builds are deleted as soon as their image has been pushed, like build Jobs.
+type GCPolicy struct {
+	// KeepFailed is the number of most recent failed Builds kept for each kernel.
+	KeepFailed int
+	// TTL is the duration after their completion during which failed Builds are retained.
+	// A zero value means that retained Builds never expire.
+	TTL time.Duration
+}
+
+// DefaultGCPolicy keeps the last failed Build for each kernel during one day.
+var DefaultGCPolicy = GCPolicy{
+	KeepFailed: 1,
+	TTL:        24 * time.Hour,
+}
+
 type buildManager struct {
 	client          client.Client
 	maker           Maker
 	ocpBuildsHelper OpenShiftBuildsHelper
 	authFactory     auth.RegistryAuthGetterFactory
 	registry        registry.Registry
+	gitResolver     git.Resolver
+	cache           cache.Cache
+	scheduler       scheduler.Scheduler
+	gcPolicy        GCPolicy
+	clock           clock.PassiveClock
 }
 
 func NewManager(
@@ -37,20 +74,155 @@ func NewManager(
 	maker Maker,
 	ocpBuildsHelper OpenShiftBuildsHelper,
 	authFactory auth.RegistryAuthGetterFactory,
-	registry registry.Registry) *buildManager {
+	registry registry.Registry,
+	gitResolver git.Resolver,
+	buildCache cache.Cache,
+	buildScheduler scheduler.Scheduler,
+	gcPolicy GCPolicy) *buildManager {
 	return &buildManager{
 		client:          client,
 		maker:           maker,
 		ocpBuildsHelper: ocpBuildsHelper,
 		authFactory:     authFactory,
 		registry:        registry,
+		gitResolver:     gitResolver,
+		cache:           buildCache,
+		scheduler:       buildScheduler,
+		gcPolicy:        gcPolicy,
+		clock:           clock.RealClock{},
 	}
 }
 
 func (bcm *buildManager) GarbageCollect(ctx context.Context, modName, namespace string, owner metav1.Object) ([]string, error) {
+	builds, err := bcm.ocpBuildsHelper.GetModuleBuilds(ctx, modName, namespace, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Builds for module %s: %v", modName, err)
+	}
+
+	buildsByKernel := make(map[string][]buildv1.Build)
+
+	for _, b := range builds {
+		// Builds for different architectures are retained separately
+		kernel := b.GetLabels()[constants.TargetKernelTarget] + "/" + b.GetLabels()[constants.ArchLabel]
+		buildsByKernel[kernel] = append(buildsByKernel[kernel], b)
+	}
+
+	now := bcm.clock.Now()
+	deleteNames := make([]string, 0, len(builds))
+
+	for _, kernel := range sets.StringKeySet(buildsByKernel).List() {
+		kernelBuilds := buildsByKernel[kernel]
+		expired := make([]buildv1.Build, 0, len(kernelBuilds))
+		failed := make([]buildv1.Build, 0, len(kernelBuilds))
+
+		current := currentBuild(kernelBuilds)
+
+		for _, b := range kernelBuilds {
+			isCurrent := current != nil && b.Name == current.Name
+
+			switch {
+			case b.Status.Phase == buildv1.BuildPhaseComplete:
+				// Once the image is pushed, ShouldSync skips the build; without a push, the current Build holds its
+				// result, which would otherwise be built again.
+				if !isCurrent || isPushed(&b) {
+					expired = append(expired, b)
+				}
+			case isCurrent:
+				// the current Build is still running, or is retried by Sync
+			case isFinished(&b):
+				failed = append(failed, b)
+			}
+		}
+
+		expired = append(expired, bcm.gcPolicy.expiredFailedBuilds(failed, now)...)
+
+		for _, b := range expired {
+			opts := []client.DeleteOption{
+				client.PropagationPolicy(metav1.DeletePropagationBackground),
+			}
+			if err = bcm.client.Delete(ctx, &b, opts...); err != nil {
+				return nil, fmt.Errorf("failed to delete Build %s: %v", b.Name, err)
+			}
+			deleteNames = append(deleteNames, b.Name)
+		}
+	}
+
+	return deleteNames, nil
+}
+
+// expiredFailedBuilds returns the failed Builds that should be deleted according to the policy.
+func (p GCPolicy) expiredFailedBuilds(builds []buildv1.Build, now time.Time) []buildv1.Build {
+	sorted := make([]buildv1.Build, len(builds))
+	copy(sorted, builds)
+
+	// most recently completed first
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return completionTime(&sorted[j]).Before(completionTime(&sorted[i]))
+	})
+
+	expired := make([]buildv1.Build, 0)
+
+	for i, b := range sorted {
+		if i >= p.KeepFailed || (p.TTL > 0 && now.Sub(completionTime(&b)) > p.TTL) {
+			expired = append(expired, b)
+		}
+	}
+
+	return expired
+}
+
+// isPushed returns true if b pushes its image to a registry.
+func isPushed(b *buildv1.Build) bool {
+	return b.Spec.Output.To != nil
+}
+
+func isSuperseded(b *buildv1.Build) bool {
+	return b.GetAnnotations()[buildSupersededAnnotation] == "true"
+}
+
+// currentBuild returns the Build of the latest attempt among the Builds of a kernel that are not superseded, or nil
+// if there is none.
+// Previous attempts may not be superseded yet if they could not be updated.
+func currentBuild(builds []buildv1.Build) *buildv1.Build {
+	var current *buildv1.Build
+
+	for i := range builds {
+		b := &builds[i]
+
+		if isSuperseded(b) {
+			continue
+		}
+
+		if current == nil {
+			current = b
+			continue
+		}
+
+		attempt, currentAttempt := retry.Attempt(b), retry.Attempt(current)
+
+		if attempt > currentAttempt || (attempt == currentAttempt && current.CreationTimestamp.Before(&b.CreationTimestamp)) {
+			current = b
+		}
+	}
+
+	return current
+}
+
+func isFinished(b *buildv1.Build) bool {
+	switch b.Status.Phase {
+	case buildv1.BuildPhaseComplete, buildv1.BuildPhaseFailed, buildv1.BuildPhaseError, buildv1.BuildPhaseCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+func completionTime(b *buildv1.Build) time.Time {
+	if b.Status.CompletionTimestamp != nil {
+		return b.Status.CompletionTimestamp.Time
+	}
 
-	//Garbage Collection noti (yet) implemented for Build
-	return nil, nil
+	return b.CreationTimestamp.Time
 }
 
 func (bcm *buildManager) ShouldSync(ctx context.Context, mld *api.ModuleLoaderData) (bool, error) {
@@ -59,13 +231,9 @@ func (bcm *buildManager) ShouldSync(ctx context.Context, mld *api.ModuleLoaderDa
 		return false, nil
 	}
 
-	targetImage := mld.ContainerImage
-
 	// if build AND sign are specified, then we will build an intermediate image
-	// and let sign produce the one specified in targetImage
-	if module.ShouldBeSigned(mld) {
-		targetImage = module.IntermediateImageName(mld.Name, mld.Namespace, targetImage)
-	}
+	// and let sign produce the one specified in the container image
+	targetImage := module.BuiltImage(mld)
 
 	// build is specified and targetImage is either the final image or the intermediate image
 	// tag, depending on whether sign is specified or not. Either way, if targetImage exists
@@ -75,6 +243,18 @@ func (bcm *buildManager) ShouldSync(ctx context.Context, mld *api.ModuleLoaderDa
 		return false, fmt.Errorf("failed to check existence of image %s: %w", targetImage, err)
 	}
 
+	// images built from a Git repository are rebuilt when the ref points to a new commit
+	if exists {
+		if exists, err = git.IsImageUpToDate(ctx, bcm.gitResolver, bcm.authFactory, bcm.registry, mld, targetImage); err != nil {
+			return false, fmt.Errorf("could not check if image %s is up to date: %v", targetImage, err)
+		}
+	}
+
+	// Sync is not called anymore once the image exists, so the completed build is released here
+	if exists {
+		bcm.scheduler.Release(scheduler.WorkloadFor(mld, utils.JobTypeBuild))
+	}
+
 	return !exists, nil
 }
 
@@ -87,20 +267,47 @@ func (bcm *buildManager) Sync(
 
 	logger := log.FromContext(ctx)
 
+	workload := scheduler.WorkloadFor(mld, utils.JobTypeBuild)
+
 	buildTemplate, err := bcm.maker.MakeBuildTemplate(ctx, mld, pushImage, owner)
 	if err != nil {
 		return "", fmt.Errorf("could not make Build template: %v", err)
 	}
 
+	// another build with the same inputs may already have produced the image
+	if key := buildTemplate.GetAnnotations()[cache.KeyAnnotation]; key != "" {
+		reused, err := bcm.cache.Reuse(ctx, mld, key)
+		if err != nil {
+			return "", fmt.Errorf("could not reuse the cached build %s: %v", key, err)
+		}
+
+		if reused {
+			logger.Info("Reused the result of an identical build", "key", key)
+			bcm.scheduler.Release(workload)
+			return utils.StatusCompleted, nil
+		}
+	}
+
 	build, err := bcm.ocpBuildsHelper.GetBuild(ctx, mld)
 	if err != nil {
 		if !errors.Is(err, errNoMatchingBuild) {
 			return "", fmt.Errorf("error getting the build: %v", err)
 		}
 
+		position, err := bcm.scheduler.Admit(ctx, workload, mld.BuildPriority)
+		if err != nil {
+			return "", fmt.Errorf("could not admit the build: %v", err)
+		}
+
+		if position > 0 {
+			logger.Info("The maximum number of concurrent builds is reached; queueing the Build", "position", position)
+			return utils.StatusQueued, nil
+		}
+
 		logger.Info("Creating Build")
 
 		if err = bcm.client.Create(ctx, buildTemplate); err != nil {
+			bcm.scheduler.Release(workload)
 			return "", fmt.Errorf("could not create Build: %v", err)
 		}
 
@@ -113,6 +320,17 @@ func (bcm *buildManager) Sync(
 	}
 
 	if changed {
+		// finished Builds are kept, so that GarbageCollect applies the GCPolicy to them
+		if isFinished(build) {
+			logger.Info("The module's build spec has been changed, superseding the current Build so a new one can be created", "name", build.Name)
+
+			if err = bcm.supersede(ctx, build); err != nil {
+				return "", fmt.Errorf("could not supersede Build %s: %v", build.Name, err)
+			}
+
+			return utils.StatusInProgress, nil
+		}
+
 		logger.Info("The module's build spec has been changed, deleting the current Build so a new one can be created", "name", build.Name)
 		opts := []client.DeleteOption{
 			client.PropagationPolicy(metav1.DeletePropagationBackground),
@@ -124,16 +342,72 @@ func (bcm *buildManager) Sync(
 		return utils.StatusInProgress, nil
 	}
 
+	var status utils.Status
+
 	switch build.Status.Phase {
 	case buildv1.BuildPhaseComplete:
-		return utils.StatusCompleted, nil
+		status = utils.StatusCompleted
 	case buildv1.BuildPhaseNew, buildv1.BuildPhasePending, buildv1.BuildPhaseRunning:
-		return utils.StatusInProgress, nil
-	case buildv1.BuildPhaseFailed:
-		return utils.StatusFailed, fmt.Errorf("build failed: %v", build.Status.LogSnippet)
+		status = utils.StatusInProgress
+	case buildv1.BuildPhaseFailed, buildv1.BuildPhaseError, buildv1.BuildPhaseCancelled:
+		status, err = bcm.retryBuild(ctx, mld.Build.RetryPolicy, build, buildTemplate)
 	default:
 		return "", fmt.Errorf("unknown status: %v", build.Status)
 	}
+
+	bcm.scheduler.Observe(workload, status)
+
+	return status, err
+}
+
+// retryBuild replaces the failed Build with a new attempt created from buildTemplate, if policy retries that failure
+// and its backoff has elapsed.
+// The new attempt is created before the failed Build is superseded, so that the count is never lost; until then,
+// GetBuild returns the latest attempt.
+func (bcm *buildManager) retryBuild(
+	ctx context.Context,
+	policy *kmmv1beta1.RetryPolicy,
+	failed *buildv1.Build,
+	buildTemplate *buildv1.Build) (utils.Status, error) {
+
+	retryAt, ok := retry.NextAttempt(policy, failed, completionTime(failed))
+	if !ok {
+		return utils.StatusFailed, fmt.Errorf("build failed: %v", failed.Status.LogSnippet)
+	}
+
+	if bcm.clock.Now().Before(retryAt) {
+		return utils.StatusRetrying, nil
+	}
+
+	attempt := retry.Attempt(failed) + 1
+
+	log.FromContext(ctx).Info("Retrying the failed Build", "name", failed.Name, "attempt", attempt)
+
+	retry.SetAttempt(buildTemplate, attempt)
+
+	if err := bcm.client.Create(ctx, buildTemplate); err != nil {
+		return "", fmt.Errorf("could not create attempt %d of the Build: %v", attempt, err)
+	}
+
+	if err := bcm.supersede(ctx, failed); err != nil {
+		log.FromContext(ctx).Info(utils.WarnString(fmt.Sprintf("failed to supersede the failed Build %s: %v", failed.Name, err)))
+	}
+
+	return utils.StatusCreated, nil
+}
+
+func (bcm *buildManager) supersede(ctx context.Context, build *buildv1.Build) error {
+	buildCopy := build.DeepCopy()
+
+	annotations := build.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[buildSupersededAnnotation] = "true"
+	build.SetAnnotations(annotations)
+
+	return bcm.client.Patch(ctx, build, client.MergeFrom(buildCopy))
 }
 
 func (bcm *buildManager) isBuildChanged(existingBuild *buildv1.Build, newBuild *buildv1.Build) (bool, error) {
@@ -152,6 +426,7 @@ func (bcm *buildManager) isBuildChanged(existingBuild *buildv1.Build, newBuild *
 
 type OpenShiftBuildsHelper interface {
 	GetBuild(ctx context.Context, mld *api.ModuleLoaderData) (*buildv1.Build, error)
+	GetModuleBuilds(ctx context.Context, modName, namespace string, owner metav1.Object) ([]buildv1.Build, error)
 }
 
 type openShiftBuildsHelper struct {
@@ -162,6 +437,7 @@ func NewOpenShiftBuildsHelper(client client.Client) OpenShiftBuildsHelper {
 	return &openShiftBuildsHelper{client: client}
 }
 
+// GetBuild returns the current Build of mld: the one of the latest attempt that is not superseded.
 func (osbh *openShiftBuildsHelper) GetBuild(ctx context.Context, mld *api.ModuleLoaderData) (*buildv1.Build, error) {
 	buildList := buildv1.BuildList{}
 
@@ -174,11 +450,37 @@ func (osbh *openShiftBuildsHelper) GetBuild(ctx context.Context, mld *api.Module
 		return nil, fmt.Errorf("could not list Build: %v", err)
 	}
 
-	if n := len(buildList.Items); n == 0 {
+	current := currentBuild(buildList.Items)
+	if current == nil {
 		return nil, errNoMatchingBuild
-	} else if n > 1 {
-		return nil, fmt.Errorf("expected 0 or 1 Builds, got %d", n)
 	}
 
-	return &buildList.Items[0], nil
+	return current, nil
+}
+
+// GetModuleBuilds returns all Builds for modName, regardless of the kernel they target,
+// that are controlled by owner.
+// Only the Builds labeled like the ones returned by GetBuild are considered.
+func (osbh *openShiftBuildsHelper) GetModuleBuilds(ctx context.Context, modName, namespace string, owner metav1.Object) ([]buildv1.Build, error) {
+	buildList := buildv1.BuildList{}
+
+	opts := []client.ListOption{
+		client.MatchingLabels(kmmbuild.GetModuleBuildLabels(modName)),
+		client.HasLabels{constants.TargetKernelTarget},
+		client.InNamespace(namespace),
+	}
+
+	if err := osbh.client.List(ctx, &buildList, opts...); err != nil {
+		return nil, fmt.Errorf("could not list Builds: %v", err)
+	}
+
+	ownedBuilds := make([]buildv1.Build, 0, len(buildList.Items))
+
+	for _, b := range buildList.Items {
+		if metav1.IsControlledBy(&b, owner) {
+			ownedBuilds = append(ownedBuilds, b)
+		}
+	}
+
+	return ownedBuilds, nil
 }
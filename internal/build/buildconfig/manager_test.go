@@ -3,6 +3,7 @@ package buildconfig
 import (
 	"context"
 	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -11,13 +12,19 @@ import (
 	buildv1 "github.com/openshift/api/build/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/retry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 )
 
@@ -47,7 +54,7 @@ var _ = Describe("Manager", func() {
 
 			mld := api.ModuleLoaderData{}
 
-			mgr := NewManager(clnt, nil, nil, authFactory, reg)
+			mgr := NewManager(clnt, nil, nil, authFactory, reg, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
 
 			shouldSync, err := mgr.ShouldSync(ctx, &mld)
 
@@ -69,10 +76,10 @@ var _ = Describe("Manager", func() {
 			authGetter := &auth.MockRegistryAuthGetter{}
 			gomock.InOrder(
 				authFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
-				reg.EXPECT().ImageExists(ctx, imageName, gomock.Any(), authGetter).Return(true, nil),
+				reg.EXPECT().ImageExists(ctx, imageName, "", gomock.Any(), authGetter).Return(true, nil),
 			)
 
-			mgr := NewManager(clnt, nil, nil, authFactory, reg)
+			mgr := NewManager(clnt, nil, nil, authFactory, reg, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
 
 			shouldSync, err := mgr.ShouldSync(ctx, &mld)
 
@@ -94,10 +101,10 @@ var _ = Describe("Manager", func() {
 			authGetter := &auth.MockRegistryAuthGetter{}
 			gomock.InOrder(
 				authFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
-				reg.EXPECT().ImageExists(ctx, imageName, gomock.Any(), authGetter).Return(false, errors.New("generic-registry-error")),
+				reg.EXPECT().ImageExists(ctx, imageName, "", gomock.Any(), authGetter).Return(false, errors.New("generic-registry-error")),
 			)
 
-			mgr := NewManager(clnt, nil, nil, authFactory, reg)
+			mgr := NewManager(clnt, nil, nil, authFactory, reg, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
 
 			shouldSync, err := mgr.ShouldSync(ctx, &mld)
 
@@ -120,9 +127,9 @@ var _ = Describe("Manager", func() {
 			authGetter := &auth.MockRegistryAuthGetter{}
 			gomock.InOrder(
 				authFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
-				reg.EXPECT().ImageExists(ctx, imageName, gomock.Any(), authGetter).Return(false, nil))
+				reg.EXPECT().ImageExists(ctx, imageName, "", gomock.Any(), authGetter).Return(false, nil))
 
-			mgr := NewManager(clnt, nil, nil, authFactory, reg)
+			mgr := NewManager(clnt, nil, nil, authFactory, reg, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
 
 			shouldSync, err := mgr.ShouldSync(ctx, &mld)
 
@@ -131,6 +138,188 @@ var _ = Describe("Manager", func() {
 		})
 	})
 
+	var _ = Describe("GarbageCollect", func() {
+		const (
+			moduleName = "module-name"
+			namespace  = "some-namespace"
+		)
+
+		var (
+			mockKubeClient            *client.MockClient
+			mockOpenShiftBuildsHelper *MockOpenShiftBuildsHelper
+			now                       time.Time
+		)
+
+		BeforeEach(func() {
+			ctrl := gomock.NewController(GinkgoT())
+			mockKubeClient = client.NewMockClient(ctrl)
+			mockOpenShiftBuildsHelper = NewMockOpenShiftBuildsHelper(ctrl)
+			now = time.Date(2022, time.October, 1, 12, 0, 0, 0, time.UTC)
+		})
+
+		ctx := context.Background()
+		mod := &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName, Namespace: namespace},
+		}
+
+		makeBuild := func(name, kernel string, phase buildv1.BuildPhase, completedAgo time.Duration) buildv1.Build {
+			return buildv1.Build{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   namespace,
+					Labels:      map[string]string{constants.TargetKernelTarget: kernel},
+					Annotations: map[string]string{buildSupersededAnnotation: "true"},
+				},
+				Status: buildv1.BuildStatus{
+					Phase:               phase,
+					CompletionTimestamp: &metav1.Time{Time: now.Add(-completedAgo)},
+				},
+			}
+		}
+
+		newManager := func(policy GCPolicy) *buildManager {
+			m := NewManager(mockKubeClient, nil, mockOpenShiftBuildsHelper, nil, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil), policy)
+			m.clock = testclock.NewFakePassiveClock(now)
+			return m
+		}
+
+		It("should return an error if the Builds could not be listed", func() {
+			mockOpenShiftBuildsHelper.
+				EXPECT().
+				GetModuleBuilds(ctx, moduleName, namespace, mod).
+				Return(nil, errors.New("some error"))
+
+			_, err := newManager(DefaultGCPolicy).GarbageCollect(ctx, moduleName, namespace, mod)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should delete succeeded Builds and keep the last failed one with the default policy", func() {
+			succeeded := makeBuild("succeeded", "kernel-1", buildv1.BuildPhaseComplete, time.Minute)
+			running := makeBuild("running", "kernel-1", buildv1.BuildPhaseRunning, 0)
+			lastFailed := makeBuild("last-failed", "kernel-2", buildv1.BuildPhaseFailed, time.Minute)
+			oldFailed := makeBuild("old-failed", "kernel-2", buildv1.BuildPhaseError, time.Hour)
+
+			gomock.InOrder(
+				mockOpenShiftBuildsHelper.
+					EXPECT().
+					GetModuleBuilds(ctx, moduleName, namespace, mod).
+					Return([]buildv1.Build{succeeded, running, oldFailed, lastFailed}, nil),
+				mockKubeClient.EXPECT().Delete(ctx, &succeeded, gomock.Any()),
+				mockKubeClient.EXPECT().Delete(ctx, &oldFailed, gomock.Any()),
+			)
+
+			deleted, err := newManager(DefaultGCPolicy).GarbageCollect(ctx, moduleName, namespace, mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(Equal([]string{"succeeded", "old-failed"}))
+		})
+
+		It("should delete retained failed Builds once their TTL has expired", func() {
+			recentFailed := makeBuild("recent-failed", "kernel", buildv1.BuildPhaseFailed, time.Minute)
+			expiredFailed := makeBuild("expired-failed", "kernel", buildv1.BuildPhaseFailed, 2*time.Hour)
+
+			policy := GCPolicy{KeepFailed: 2, TTL: time.Hour}
+
+			gomock.InOrder(
+				mockOpenShiftBuildsHelper.
+					EXPECT().
+					GetModuleBuilds(ctx, moduleName, namespace, mod).
+					Return([]buildv1.Build{expiredFailed, recentFailed}, nil),
+				mockKubeClient.EXPECT().Delete(ctx, &expiredFailed, gomock.Any()),
+			)
+
+			deleted, err := newManager(policy).GarbageCollect(ctx, moduleName, namespace, mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(Equal([]string{"expired-failed"}))
+		})
+
+		It("should never expire retained Builds if the TTL is 0", func() {
+			oldFailed := makeBuild("old-failed", "kernel", buildv1.BuildPhaseFailed, 365*24*time.Hour)
+
+			mockOpenShiftBuildsHelper.
+				EXPECT().
+				GetModuleBuilds(ctx, moduleName, namespace, mod).
+				Return([]buildv1.Build{oldFailed}, nil)
+
+			deleted, err := newManager(GCPolicy{KeepFailed: 1}).GarbageCollect(ctx, moduleName, namespace, mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeEmpty())
+		})
+
+		It("should delete the current Build of a kernel once it has pushed its image", func() {
+			pushed := makeBuild("pushed", "kernel", buildv1.BuildPhaseComplete, time.Minute)
+			pushed.Annotations = nil
+			pushed.Spec.Output.To = &v1.ObjectReference{Kind: "DockerImage", Name: "some-image:tag"}
+
+			gomock.InOrder(
+				mockOpenShiftBuildsHelper.
+					EXPECT().
+					GetModuleBuilds(ctx, moduleName, namespace, mod).
+					Return([]buildv1.Build{pushed}, nil),
+				mockKubeClient.EXPECT().Delete(ctx, &pushed, gomock.Any()),
+			)
+
+			deleted, err := newManager(DefaultGCPolicy).GarbageCollect(ctx, moduleName, namespace, mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(Equal([]string{"pushed"}))
+		})
+
+		It("should keep the current Build of a kernel if it did not push its image or failed", func() {
+			succeeded := makeBuild("succeeded", "kernel-1", buildv1.BuildPhaseComplete, 2*time.Hour)
+			succeeded.Annotations = nil
+			failed := makeBuild("failed", "kernel-2", buildv1.BuildPhaseFailed, 2*time.Hour)
+			failed.Annotations = nil
+			supersededFailed := makeBuild("superseded-failed", "kernel-2", buildv1.BuildPhaseFailed, time.Minute)
+
+			policy := GCPolicy{TTL: time.Hour}
+
+			gomock.InOrder(
+				mockOpenShiftBuildsHelper.
+					EXPECT().
+					GetModuleBuilds(ctx, moduleName, namespace, mod).
+					Return([]buildv1.Build{succeeded, failed, supersededFailed}, nil),
+				mockKubeClient.EXPECT().Delete(ctx, &supersededFailed, gomock.Any()),
+			)
+
+			deleted, err := newManager(policy).GarbageCollect(ctx, moduleName, namespace, mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(Equal([]string{"superseded-failed"}))
+		})
+
+		It("should delete previous attempts that could not be superseded", func() {
+			previous := makeBuild("previous", "kernel", buildv1.BuildPhaseFailed, time.Minute)
+			previous.Annotations = map[string]string{retry.AttemptAnnotation: "1"}
+			latest := makeBuild("latest", "kernel", buildv1.BuildPhaseRunning, 0)
+			latest.Annotations = map[string]string{retry.AttemptAnnotation: "2"}
+
+			gomock.InOrder(
+				mockOpenShiftBuildsHelper.
+					EXPECT().
+					GetModuleBuilds(ctx, moduleName, namespace, mod).
+					Return([]buildv1.Build{previous, latest}, nil),
+				mockKubeClient.EXPECT().Delete(ctx, &previous, gomock.Any()),
+			)
+
+			deleted, err := newManager(GCPolicy{}).GarbageCollect(ctx, moduleName, namespace, mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(Equal([]string{"previous"}))
+		})
+
+		It("should return an error if a Build could not be deleted", func() {
+			succeeded := makeBuild("succeeded", "kernel", buildv1.BuildPhaseComplete, time.Minute)
+
+			gomock.InOrder(
+				mockOpenShiftBuildsHelper.
+					EXPECT().
+					GetModuleBuilds(ctx, moduleName, namespace, mod).
+					Return([]buildv1.Build{succeeded}, nil),
+				mockKubeClient.EXPECT().Delete(ctx, &succeeded, gomock.Any()).Return(errors.New("some error")),
+			)
+
+			_, err := newManager(DefaultGCPolicy).GarbageCollect(ctx, moduleName, namespace, mod)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	var _ = Describe("Sync", func() {
 		const (
 			containerImage = "some-image-name:tag"
@@ -143,6 +332,8 @@ var _ = Describe("Manager", func() {
 			mockKubeClient            *client.MockClient
 			mockMaker                 *MockMaker
 			mockOpenShiftBuildsHelper *MockOpenShiftBuildsHelper
+			mockCache                 *cache.MockCache
+			mockScheduler             *scheduler.MockScheduler
 		)
 
 		BeforeEach(func() {
@@ -150,10 +341,148 @@ var _ = Describe("Manager", func() {
 			mockKubeClient = client.NewMockClient(ctrl)
 			mockMaker = NewMockMaker(ctrl)
 			mockOpenShiftBuildsHelper = NewMockOpenShiftBuildsHelper(ctrl)
+			mockCache = cache.NewMockCache(ctrl)
+			mockScheduler = scheduler.NewMockScheduler(ctrl)
 		})
 
 		ctx := context.Background()
 
+		Context("with a cached build", func() {
+			mld := api.ModuleLoaderData{
+				Name:           moduleName,
+				Namespace:      namespace,
+				Build:          &kmmv1beta1.Build{},
+				ContainerImage: containerImage,
+				KernelVersion:  targetKernel,
+			}
+
+			build := buildv1.Build{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{cache.KeyAnnotation: "some-key"},
+				},
+			}
+
+			It("should reuse the cached image instead of building it", func() {
+				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, mockCache, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
+
+				gomock.InOrder(
+					mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&build, nil),
+					mockCache.EXPECT().Reuse(ctx, &mld, "some-key").Return(true, nil),
+				)
+
+				status, err := m.Sync(ctx, &mld, true, mld.Owner)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status).To(Equal(utils.Status(utils.StatusCompleted)))
+			})
+
+			It("should build the image if it is not cached", func() {
+				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, mockCache, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
+
+				gomock.InOrder(
+					mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&build, nil),
+					mockCache.EXPECT().Reuse(ctx, &mld, "some-key").Return(false, nil),
+					mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(nil, errNoMatchingBuild),
+					mockKubeClient.EXPECT().Create(ctx, &build),
+				)
+
+				status, err := m.Sync(ctx, &mld, true, mld.Owner)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status).To(Equal(utils.Status(utils.StatusCreated)))
+			})
+
+			It("should return an error if the cache cannot be used", func() {
+				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, mockCache, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
+
+				gomock.InOrder(
+					mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&build, nil),
+					mockCache.EXPECT().Reuse(ctx, &mld, "some-key").Return(false, errors.New("random error")),
+				)
+
+				_, err := m.Sync(ctx, &mld, true, mld.Owner)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("with a build scheduler", func() {
+			mld := api.ModuleLoaderData{
+				Name:           moduleName,
+				Namespace:      namespace,
+				Build:          &kmmv1beta1.Build{},
+				ContainerImage: containerImage,
+				KernelVersion:  targetKernel,
+				BuildPriority:  5,
+			}
+
+			build := buildv1.Build{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{buildHashAnnotation: "some hash"},
+				},
+			}
+
+			workload := scheduler.WorkloadFor(&mld, utils.JobTypeBuild)
+
+			It("should queue the Build if it is not admitted", func() {
+				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, mockScheduler, DefaultGCPolicy)
+
+				gomock.InOrder(
+					mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&build, nil),
+					mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(nil, errNoMatchingBuild),
+					mockScheduler.EXPECT().Admit(ctx, workload, int32(5)).Return(3, nil),
+				)
+
+				status, err := m.Sync(ctx, &mld, true, mld.Owner)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status).To(Equal(utils.Status(utils.StatusQueued)))
+			})
+
+			It("should create the Build once it is admitted", func() {
+				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, mockScheduler, DefaultGCPolicy)
+
+				gomock.InOrder(
+					mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&build, nil),
+					mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(nil, errNoMatchingBuild),
+					mockScheduler.EXPECT().Admit(ctx, workload, int32(5)).Return(0, nil),
+					mockKubeClient.EXPECT().Create(ctx, &build),
+				)
+
+				status, err := m.Sync(ctx, &mld, true, mld.Owner)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status).To(Equal(utils.Status(utils.StatusCreated)))
+			})
+
+			It("should release the slot if the Build cannot be created", func() {
+				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, mockScheduler, DefaultGCPolicy)
+
+				gomock.InOrder(
+					mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&build, nil),
+					mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(nil, errNoMatchingBuild),
+					mockScheduler.EXPECT().Admit(ctx, workload, int32(5)).Return(0, nil),
+					mockKubeClient.EXPECT().Create(ctx, &build).Return(errors.New("some error")),
+					mockScheduler.EXPECT().Release(workload),
+				)
+
+				_, err := m.Sync(ctx, &mld, true, mld.Owner)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("should report the phase of the existing Build to the scheduler", func() {
+				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, mockScheduler, DefaultGCPolicy)
+
+				existing := build
+				existing.Status.Phase = buildv1.BuildPhaseRunning
+
+				gomock.InOrder(
+					mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&build, nil),
+					mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(&existing, nil),
+					mockScheduler.EXPECT().Observe(workload, utils.Status(utils.StatusInProgress)),
+				)
+
+				status, err := m.Sync(ctx, &mld, true, mld.Owner)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status).To(Equal(utils.Status(utils.StatusInProgress)))
+			})
+		})
+
 		It("should create a Build when none is present", func() {
 			const (
 				buildName      = "some-build-config"
@@ -177,7 +506,7 @@ var _ = Describe("Manager", func() {
 				KernelVersion:   targetKernel,
 			}
 
-			m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil)
+			m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
 
 			build := buildv1.Build{
 				ObjectMeta: metav1.ObjectMeta{Name: buildName},
@@ -215,7 +544,7 @@ var _ = Describe("Manager", func() {
 					KernelVersion:  targetKernel,
 				}
 
-				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil)
+				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
 
 				build := buildv1.Build{
 					ObjectMeta: metav1.ObjectMeta{
@@ -236,16 +565,201 @@ var _ = Describe("Manager", func() {
 					Expect(err).To(HaveOccurred())
 				} else {
 					Expect(err).NotTo(HaveOccurred())
-					Expect(status).To(Equal(expectedStatus))
 				}
+
+				Expect(status).To(Equal(expectedStatus))
 			},
 			Entry(nil, buildv1.BuildPhaseComplete, utils.Status(utils.StatusCompleted), false),
 			Entry(nil, buildv1.BuildPhaseNew, utils.Status(utils.StatusInProgress), false),
 			Entry(nil, buildv1.BuildPhasePending, utils.Status(utils.StatusInProgress), false),
 			Entry(nil, buildv1.BuildPhaseRunning, utils.Status(utils.StatusInProgress), false),
-			Entry(nil, buildv1.BuildPhaseFailed, utils.Status(""), true),
-			Entry(nil, buildv1.BuildPhaseCancelled, utils.Status(""), true),
+			Entry(nil, buildv1.BuildPhaseFailed, utils.Status(utils.StatusFailed), true),
+			Entry(nil, buildv1.BuildPhaseError, utils.Status(utils.StatusFailed), true),
+			Entry(nil, buildv1.BuildPhaseCancelled, utils.Status(utils.StatusFailed), true),
 		)
+
+		DescribeTable(
+			"should replace the Build when the build spec has changed",
+			func(phase buildv1.BuildPhase, supersede bool) {
+				mld := api.ModuleLoaderData{
+					Name:           moduleName,
+					Namespace:      namespace,
+					Build:          &kmmv1beta1.Build{},
+					ContainerImage: containerImage,
+					KernelVersion:  targetKernel,
+				}
+
+				m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
+
+				build := buildv1.Build{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "some-build",
+						Annotations: map[string]string{buildHashAnnotation: "old hash"},
+					},
+					Status: buildv1.BuildStatus{Phase: phase},
+				}
+
+				template := buildv1.Build{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{buildHashAnnotation: "new hash"},
+					},
+				}
+
+				gomock.InOrder(
+					mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&template, nil),
+					mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(&build, nil),
+				)
+
+				if supersede {
+					mockKubeClient.
+						EXPECT().
+						Patch(ctx, &build, gomock.Any()).
+						Do(func(_ context.Context, obj ctrlclient.Object, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+							Expect(obj.GetAnnotations()).To(HaveKeyWithValue(buildSupersededAnnotation, "true"))
+						})
+				} else {
+					mockKubeClient.EXPECT().Delete(ctx, &build, gomock.Any())
+				}
+
+				status, err := m.Sync(ctx, &mld, true, mld.Owner)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status).To(Equal(utils.Status(utils.StatusInProgress)))
+			},
+			Entry("supersede a succeeded Build", buildv1.BuildPhaseComplete, true),
+			Entry("supersede a failed Build", buildv1.BuildPhaseFailed, true),
+			Entry("delete a running Build", buildv1.BuildPhaseRunning, false),
+		)
+
+		It("should replace the failed Build with a new attempt once its backoff has elapsed", func() {
+			completed := metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+
+			mld := api.ModuleLoaderData{
+				Name:           moduleName,
+				Namespace:      namespace,
+				Build:          &kmmv1beta1.Build{RetryPolicy: &kmmv1beta1.RetryPolicy{MaxAttempts: 3}},
+				ContainerImage: containerImage,
+				KernelVersion:  targetKernel,
+			}
+
+			m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
+
+			failed := buildv1.Build{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "failed-build",
+					Annotations: map[string]string{
+						buildHashAnnotation:     "some hash",
+						retry.AttemptAnnotation: "2",
+					},
+				},
+				Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseFailed, CompletionTimestamp: &completed},
+			}
+
+			template := buildv1.Build{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{buildHashAnnotation: "some hash"},
+				},
+			}
+
+			By("waiting for the backoff")
+
+			m.clock = testclock.NewFakePassiveClock(completed.Add(retry.DefaultInitialBackoff))
+
+			gomock.InOrder(
+				mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&template, nil),
+				mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(&failed, nil),
+			)
+
+			status, err := m.Sync(ctx, &mld, true, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(utils.Status(utils.StatusRetrying)))
+
+			By("creating the third attempt")
+
+			m.clock = testclock.NewFakePassiveClock(completed.Add(2 * retry.DefaultInitialBackoff))
+
+			gomock.InOrder(
+				mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&template, nil),
+				mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(&failed, nil),
+				mockKubeClient.EXPECT().Create(ctx, &template),
+				mockKubeClient.EXPECT().Patch(ctx, &failed, gomock.Any()),
+			)
+
+			status, err = m.Sync(ctx, &mld, true, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(utils.Status(utils.StatusCreated)))
+			Expect(template.Annotations).To(HaveKeyWithValue(retry.AttemptAnnotation, "3"))
+			Expect(failed.Annotations).To(HaveKeyWithValue(buildSupersededAnnotation, "true"))
+		})
+
+		It("should report the new attempt as created even if the failed Build could not be superseded", func() {
+			mld := api.ModuleLoaderData{
+				Name:           moduleName,
+				Namespace:      namespace,
+				Build:          &kmmv1beta1.Build{RetryPolicy: &kmmv1beta1.RetryPolicy{MaxAttempts: 3}},
+				ContainerImage: containerImage,
+				KernelVersion:  targetKernel,
+			}
+
+			m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
+
+			failed := buildv1.Build{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "failed-build",
+					Annotations: map[string]string{buildHashAnnotation: "some hash"},
+				},
+				Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseFailed},
+			}
+
+			template := buildv1.Build{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{buildHashAnnotation: "some hash"},
+				},
+			}
+
+			m.clock = testclock.NewFakePassiveClock(time.Now().Add(time.Hour))
+
+			gomock.InOrder(
+				mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&template, nil),
+				mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(&failed, nil),
+				mockKubeClient.EXPECT().Create(ctx, &template),
+				mockKubeClient.EXPECT().Patch(ctx, &failed, gomock.Any()).Return(errors.New("some error")),
+			)
+
+			status, err := m.Sync(ctx, &mld, true, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(utils.Status(utils.StatusCreated)))
+		})
+
+		It("should not retry the failed Build once the maximum number of attempts is reached", func() {
+			mld := api.ModuleLoaderData{
+				Name:           moduleName,
+				Namespace:      namespace,
+				Build:          &kmmv1beta1.Build{RetryPolicy: &kmmv1beta1.RetryPolicy{MaxAttempts: 2}},
+				ContainerImage: containerImage,
+				KernelVersion:  targetKernel,
+			}
+
+			m := NewManager(mockKubeClient, mockMaker, mockOpenShiftBuildsHelper, nil, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil), DefaultGCPolicy)
+
+			failed := buildv1.Build{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						buildHashAnnotation:     "some hash",
+						retry.AttemptAnnotation: "2",
+					},
+				},
+				Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseFailed},
+			}
+
+			gomock.InOrder(
+				mockMaker.EXPECT().MakeBuildTemplate(ctx, &mld, true, mld.Owner).Return(&failed, nil),
+				mockOpenShiftBuildsHelper.EXPECT().GetBuild(ctx, &mld).Return(&failed, nil),
+			)
+
+			status, err := m.Sync(ctx, &mld, true, mld.Owner)
+			Expect(err).To(HaveOccurred())
+			Expect(status).To(Equal(utils.Status(utils.StatusFailed)))
+		})
 	})
 })
 
@@ -277,12 +791,84 @@ var _ = Describe("OpenShiftBuildsHelper_GetBuild", func() {
 		Expect(err).To(HaveOccurred())
 	})
 
-	It("should return an error if there are two Builids with the same labels", func() {
+	It("should return the latest attempt if previous ones are not superseded yet", func() {
+		previous := buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "previous",
+				Annotations:       map[string]string{retry.AttemptAnnotation: "2"},
+				CreationTimestamp: metav1.NewTime(time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)),
+			},
+		}
+
+		latest := buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "latest",
+				Annotations:       map[string]string{retry.AttemptAnnotation: "3"},
+				CreationTimestamp: metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+		}
+
 		mockKubeClient.
 			EXPECT().
 			List(ctx, &buildv1.BuildList{}, gomock.Any(), gomock.Any()).
 			Do(func(_ context.Context, bcs *buildv1.BuildList, _ ...ctrlclient.ListOption) {
-				bcs.Items = make([]buildv1.Build, 2)
+				bcs.Items = []buildv1.Build{latest, previous}
+			})
+
+		osbh := NewOpenShiftBuildsHelper(mockKubeClient)
+		mld := api.ModuleLoaderData{
+			KernelVersion: targetKernel,
+		}
+
+		res, err := osbh.GetBuild(ctx, &mld)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Name).To(Equal("latest"))
+	})
+
+	It("should ignore superseded Builds", func() {
+		current := buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{Name: "current"},
+		}
+
+		superseded := buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "superseded",
+				Annotations: map[string]string{buildSupersededAnnotation: "true"},
+			},
+		}
+
+		mockKubeClient.
+			EXPECT().
+			List(ctx, &buildv1.BuildList{}, gomock.Any(), gomock.Any()).
+			Do(func(_ context.Context, bcs *buildv1.BuildList, _ ...ctrlclient.ListOption) {
+				bcs.Items = []buildv1.Build{superseded, current}
+			})
+
+		osbh := NewOpenShiftBuildsHelper(mockKubeClient)
+		mld := api.ModuleLoaderData{
+			KernelVersion: targetKernel,
+		}
+
+		res, err := osbh.GetBuild(ctx, &mld)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(&current))
+	})
+
+	It("should return errNoMatchingBuild if all Builds are superseded", func() {
+		mockKubeClient.
+			EXPECT().
+			List(ctx, &buildv1.BuildList{}, gomock.Any(), gomock.Any()).
+			Do(func(_ context.Context, bcs *buildv1.BuildList, _ ...ctrlclient.ListOption) {
+				bcs.Items = []buildv1.Build{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        "superseded",
+							Annotations: map[string]string{buildSupersededAnnotation: "true"},
+						},
+					},
+				}
 			})
 
 		osbh := NewOpenShiftBuildsHelper(mockKubeClient)
@@ -292,7 +878,7 @@ var _ = Describe("OpenShiftBuildsHelper_GetBuild", func() {
 
 		_, err := osbh.GetBuild(ctx, &mld)
 
-		Expect(err).To(HaveOccurred())
+		Expect(err).To(MatchError(errNoMatchingBuild))
 	})
 
 	It("should work as expected", func() {
@@ -318,3 +904,76 @@ var _ = Describe("OpenShiftBuildsHelper_GetBuild", func() {
 		Expect(res).To(Equal(bc))
 	})
 })
+
+var _ = Describe("OpenShiftBuildsHelper_GetModuleBuilds", func() {
+	const (
+		moduleName = "module-name"
+		namespace  = "some-namespace"
+	)
+
+	var mockKubeClient *client.MockClient
+
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockKubeClient = client.NewMockClient(ctrl)
+	})
+
+	It("should return an error if an error occurred", func() {
+		mockKubeClient.
+			EXPECT().
+			List(ctx, &buildv1.BuildList{}, gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(errors.New("random error"))
+
+		osbh := NewOpenShiftBuildsHelper(mockKubeClient)
+
+		_, err := osbh.GetModuleBuilds(ctx, moduleName, namespace, &kmmv1beta1.Module{})
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should only return the Builds controlled by the owner", func() {
+		mod := &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName, UID: "module-uid"},
+		}
+
+		owned := buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "owned",
+				OwnerReferences: []metav1.OwnerReference{
+					{Name: moduleName, UID: "module-uid", Controller: pointer.Bool(true)},
+				},
+			},
+		}
+
+		notOwned := buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "not-owned",
+				OwnerReferences: []metav1.OwnerReference{
+					{Name: moduleName, UID: "other-uid", Controller: pointer.Bool(true)},
+				},
+			},
+		}
+
+		mockKubeClient.
+			EXPECT().
+			List(
+				ctx,
+				&buildv1.BuildList{},
+				ctrlclient.MatchingLabels{constants.ModuleNameLabel: moduleName},
+				ctrlclient.HasLabels{constants.TargetKernelTarget},
+				ctrlclient.InNamespace(namespace),
+			).
+			Do(func(_ context.Context, bl *buildv1.BuildList, _ ...ctrlclient.ListOption) {
+				bl.Items = []buildv1.Build{owned, notOwned}
+			})
+
+		osbh := NewOpenShiftBuildsHelper(mockKubeClient)
+
+		res, err := osbh.GetModuleBuilds(ctx, moduleName, namespace, mod)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal([]buildv1.Build{owned}))
+	})
+})
@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	v1 "github.com/openshift/api/build/v1"
 	api "github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	v10 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // MockOpenShiftBuildsHelper is a mock of OpenShiftBuildsHelper interface.
@@ -50,3 +51,18 @@ func (mr *MockOpenShiftBuildsHelperMockRecorder) GetBuild(ctx, mld interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBuild", reflect.TypeOf((*MockOpenShiftBuildsHelper)(nil).GetBuild), ctx, mld)
 }
+
+// GetModuleBuilds mocks base method.
+func (m *MockOpenShiftBuildsHelper) GetModuleBuilds(ctx context.Context, modName, namespace string, owner v10.Object) ([]v1.Build, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetModuleBuilds", ctx, modName, namespace, owner)
+	ret0, _ := ret[0].([]v1.Build)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetModuleBuilds indicates an expected call of GetModuleBuilds.
+func (mr *MockOpenShiftBuildsHelperMockRecorder) GetModuleBuilds(ctx, modName, namespace, owner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModuleBuilds", reflect.TypeOf((*MockOpenShiftBuildsHelper)(nil).GetModuleBuilds), ctx, modName, namespace, owner)
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+)
+
+// KeyAnnotation is the cache key of the result of a Build or a Job, which pushes it to the cache repository.
+const KeyAnnotation = "kmm.node.kubernetes.io/build-cache-key"
+
+// Inputs are everything the result of a build depends on.
+type Inputs struct {
+	Dockerfile string
+	// BuildArgs are the resolved build arguments, including the kernel version.
+	BuildArgs []kmmv1beta1.BuildArg
+	DTKImage  string
+	// GitURI, GitCommit and GitContextDir identify the build context fetched from a Git repository, if any.
+	GitURI        string
+	GitCommit     string
+	GitContextDir string
+	Arch          string
+}
+
+//go:generate mockgen -source=cache.go -package=cache -destination=mock_cache.go
+
+// Cache shares the results of identical builds across the Modules of a namespace.
+// Builds push their result to the cache repository, tagged with their key; the result is then copied to the container
+// image of every Module of the same namespace building the same inputs.
+// Keys are scoped to the namespace, so that a namespace can never make another one load a kernel module it built.
+type Cache interface {
+	// Key returns the cache key of the build of mld with inputs, or an empty string if it should not be cached.
+	Key(mld *api.ModuleLoaderData, inputs *Inputs) string
+	// Image returns the image of the cache repository holding the result of the build with key.
+	Image(key string) string
+	// Reuse copies the result of the build with key to the image built for mld, if it is in the cache repository.
+	// It returns false if it is not.
+	Reuse(ctx context.Context, mld *api.ModuleLoaderData, key string) (bool, error)
+}
+
+type cache struct {
+	repository  string
+	authFactory auth.RegistryAuthGetterFactory
+	registry    registry.Registry
+}
+
+// New returns a Cache storing build results in repository.
+// If repository is empty, no build is cached.
+func New(repository string, authFactory auth.RegistryAuthGetterFactory, registry registry.Registry) Cache {
+	return &cache{
+		repository:  repository,
+		authFactory: authFactory,
+		registry:    registry,
+	}
+}
+
+// keyData is hashed to make the cache key.
+type keyData struct {
+	Namespace      string
+	DockerfileHash string
+	BuildArgs      []kmmv1beta1.BuildArg
+	DTKImage       string
+	GitURI         string
+	GitCommit      string
+	GitContextDir  string
+	Arch           string
+}
+
+// Key returns the sha256 of inputs and of the namespace of mld.
+// Builds using secrets are not cached, because their result may depend on content that other namespaces cannot access.
+func (c *cache) Key(mld *api.ModuleLoaderData, inputs *Inputs) string {
+	if c.repository == "" {
+		return ""
+	}
+
+	if b := mld.Build; b != nil && (len(b.Secrets) > 0 || (b.Git != nil && b.Git.SourceSecret != nil)) {
+		return ""
+	}
+
+	buildArgs := make([]kmmv1beta1.BuildArg, len(inputs.BuildArgs))
+	copy(buildArgs, inputs.BuildArgs)
+
+	// the order of the build arguments does not matter
+	sort.SliceStable(buildArgs, func(i, j int) bool {
+		return buildArgs[i].Name < buildArgs[j].Name
+	})
+
+	dockerfileHash := sha256.Sum256([]byte(inputs.Dockerfile))
+
+	data := keyData{
+		Namespace:      mld.Namespace,
+		DockerfileHash: hex.EncodeToString(dockerfileHash[:]),
+		BuildArgs:      buildArgs,
+		DTKImage:       inputs.DTKImage,
+		GitURI:         inputs.GitURI,
+		GitCommit:      inputs.GitCommit,
+		GitContextDir:  inputs.GitContextDir,
+		Arch:           inputs.Arch,
+	}
+
+	// marshalling a struct of strings cannot fail
+	b, _ := json.Marshal(data)
+
+	key := sha256.Sum256(b)
+
+	return hex.EncodeToString(key[:])
+}
+
+func (c *cache) Image(key string) string {
+	return c.repository + ":" + key
+}
+
+// Reuse accesses the cache repository with the registry settings and the credentials of mld, which must give access
+// to both the cache repository and the image built for mld.
+func (c *cache) Reuse(ctx context.Context, mld *api.ModuleLoaderData, key string) (bool, error) {
+	image := c.Image(key)
+
+	exists, err := module.ImageExists(ctx, c.authFactory, c.registry, mld, image)
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence of image %s: %w", image, err)
+	}
+
+	if !exists {
+		return false, nil
+	}
+
+	err = c.registry.CopyImage(ctx, image, module.BuiltImage(mld), mld.RegistryTLS, c.authFactory.NewRegistryAuthGetterFrom(mld))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
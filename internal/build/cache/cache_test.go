@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+)
+
+const repository = "registry.example.com/kmm/cache"
+
+var _ = Describe("Key", func() {
+	inputs := Inputs{
+		Dockerfile: "FROM test",
+		BuildArgs: []kmmv1beta1.BuildArg{
+			{Name: "KERNEL_VERSION", Value: "1.2.3"},
+			{Name: "DTK_AUTO", Value: "dtk-image"},
+		},
+		DTKImage: "dtk-image",
+		Arch:     "x86_64",
+	}
+
+	var (
+		c   Cache
+		mld api.ModuleLoaderData
+	)
+
+	BeforeEach(func() {
+		c = New(repository, nil, nil)
+		mld = api.ModuleLoaderData{Build: &kmmv1beta1.Build{}}
+	})
+
+	It("should not cache builds if there is no cache repository", func() {
+		Expect(New("", nil, nil).Key(&mld, &inputs)).To(BeEmpty())
+	})
+
+	It("should not cache builds using secrets", func() {
+		mld.Build.Secrets = []v1.LocalObjectReference{{Name: "secret"}}
+		Expect(c.Key(&mld, &inputs)).To(BeEmpty())
+
+		mld.Build = &kmmv1beta1.Build{
+			Git: &kmmv1beta1.GitBuildSource{SourceSecret: &v1.LocalObjectReference{Name: "secret"}},
+		}
+		Expect(c.Key(&mld, &inputs)).To(BeEmpty())
+	})
+
+	It("should not depend on the order of the build arguments", func() {
+		reordered := inputs
+		reordered.BuildArgs = []kmmv1beta1.BuildArg{inputs.BuildArgs[1], inputs.BuildArgs[0]}
+
+		key := c.Key(&mld, &inputs)
+		Expect(key).To(HaveLen(64))
+		Expect(c.Key(&mld, &reordered)).To(Equal(key))
+	})
+
+	DescribeTable("should change with the inputs",
+		func(mutate func(*Inputs)) {
+			changed := inputs
+			changed.BuildArgs = append([]kmmv1beta1.BuildArg{}, inputs.BuildArgs...)
+			mutate(&changed)
+
+			Expect(c.Key(&mld, &changed)).NotTo(Equal(c.Key(&mld, &inputs)))
+		},
+		Entry("Dockerfile", func(i *Inputs) { i.Dockerfile = "FROM other" }),
+		Entry("build argument", func(i *Inputs) { i.BuildArgs[0].Value = "4.5.6" }),
+		Entry("DTK image", func(i *Inputs) { i.DTKImage = "other-dtk-image" }),
+		Entry("Git commit", func(i *Inputs) { i.GitCommit = "0123456789abcdef0123456789abcdef01234567" }),
+		Entry("Git context directory", func(i *Inputs) { i.GitContextDir = "driver" }),
+		Entry("architecture", func(i *Inputs) { i.Arch = "aarch64" }),
+	)
+
+	It("should change with the namespace", func() {
+		other := mld
+		other.Namespace = "other-namespace"
+
+		Expect(c.Key(&other, &inputs)).NotTo(Equal(c.Key(&mld, &inputs)))
+	})
+})
+
+var _ = Describe("Image", func() {
+	It("should tag the cache repository with the key", func() {
+		Expect(New(repository, nil, nil).Image("some-key")).To(Equal(repository + ":some-key"))
+	})
+})
+
+var _ = Describe("Reuse", func() {
+	var (
+		ctrl            *gomock.Controller
+		mockAuthFactory *auth.MockRegistryAuthGetterFactory
+		mockRegistry    *registry.MockRegistry
+		c               Cache
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockAuthFactory = auth.NewMockRegistryAuthGetterFactory(ctrl)
+		mockRegistry = registry.NewMockRegistry(ctrl)
+		c = New(repository, mockAuthFactory, mockRegistry)
+	})
+
+	mld := api.ModuleLoaderData{
+		Name:           "name",
+		Namespace:      "namespace",
+		ContainerImage: "registry.example.com/org/image:tag",
+		Arch:           "x86_64",
+		RegistryTLS:    &kmmv1beta1.TLSOptions{},
+		Sign:           &kmmv1beta1.Sign{},
+	}
+
+	It("should copy the cached image to the built image", func() {
+		gomock.InOrder(
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+			mockRegistry.EXPECT().ImageExists(context.Background(), repository+":some-key", "x86_64", mld.RegistryTLS, nil).Return(true, nil),
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+			mockRegistry.EXPECT().CopyImage(context.Background(), repository+":some-key", module.BuiltImage(&mld), mld.RegistryTLS, nil),
+		)
+
+		Expect(c.Reuse(context.Background(), &mld, "some-key")).To(BeTrue())
+	})
+
+	It("should return false if the image is not cached", func() {
+		gomock.InOrder(
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+			mockRegistry.EXPECT().ImageExists(context.Background(), repository+":some-key", "x86_64", mld.RegistryTLS, nil).Return(false, nil),
+		)
+
+		Expect(c.Reuse(context.Background(), &mld, "some-key")).To(BeFalse())
+	})
+
+	It("should return an error if the registry cannot be queried", func() {
+		gomock.InOrder(
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+			mockRegistry.EXPECT().ImageExists(context.Background(), repository+":some-key", "x86_64", mld.RegistryTLS, nil).Return(false, errors.New("random error")),
+		)
+
+		_, err := c.Reuse(context.Background(), &mld, "some-key")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error if the image cannot be copied", func() {
+		gomock.InOrder(
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+			mockRegistry.EXPECT().ImageExists(context.Background(), repository+":some-key", "x86_64", mld.RegistryTLS, nil).Return(true, nil),
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+			mockRegistry.EXPECT().CopyImage(context.Background(), repository+":some-key", module.BuiltImage(&mld), mld.RegistryTLS, nil).Return(errors.New("random error")),
+		)
+
+		_, err := c.Reuse(context.Background(), &mld, "some-key")
+		Expect(err).To(HaveOccurred())
+	})
+})
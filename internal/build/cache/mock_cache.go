@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: cache.go
+
+// Package cache is a generated GoMock package.
+package cache
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	api "github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+)
+
+// MockCache is a mock of Cache interface.
+type MockCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockCacheMockRecorder
+}
+
+// MockCacheMockRecorder is the mock recorder for MockCache.
+type MockCacheMockRecorder struct {
+	mock *MockCache
+}
+
+// NewMockCache creates a new mock instance.
+func NewMockCache(ctrl *gomock.Controller) *MockCache {
+	mock := &MockCache{ctrl: ctrl}
+	mock.recorder = &MockCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCache) EXPECT() *MockCacheMockRecorder {
+	return m.recorder
+}
+
+// Image mocks base method.
+func (m *MockCache) Image(key string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Image", key)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Image indicates an expected call of Image.
+func (mr *MockCacheMockRecorder) Image(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Image", reflect.TypeOf((*MockCache)(nil).Image), key)
+}
+
+// Key mocks base method.
+func (m *MockCache) Key(mld *api.ModuleLoaderData, inputs *Inputs) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Key", mld, inputs)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Key indicates an expected call of Key.
+func (mr *MockCacheMockRecorder) Key(mld, inputs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Key", reflect.TypeOf((*MockCache)(nil).Key), mld, inputs)
+}
+
+// Reuse mocks base method.
+func (m *MockCache) Reuse(ctx context.Context, mld *api.ModuleLoaderData, key string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reuse", ctx, mld, key)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reuse indicates an expected call of Reuse.
+func (mr *MockCacheMockRecorder) Reuse(ctx, mld, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reuse", reflect.TypeOf((*MockCache)(nil).Reuse), ctx, mld, key)
+}
@@ -0,0 +1,41 @@
+package git
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+)
+
+// CommitLabel is set on the images built from a Git repository to the commit they were built from.
+// Signing keeps the labels of the unsigned image.
+const CommitLabel = "kmm.node.kubernetes.io/git-commit"
+
+// IsImageUpToDate returns true if image was built from the commit that the Git ref of mld's build currently points
+// to, or if mld is not built from a Git repository.
+func IsImageUpToDate(
+	ctx context.Context,
+	resolver Resolver,
+	authFactory auth.RegistryAuthGetterFactory,
+	reg registry.Registry,
+	mld *api.ModuleLoaderData,
+	image string) (bool, error) {
+	if mld.Build == nil || mld.Build.Git == nil {
+		return true, nil
+	}
+
+	rev, err := resolver.Resolve(ctx, mld.Build.Git, mld.Namespace)
+	if err != nil {
+		return false, fmt.Errorf("could not resolve Git ref %q: %v", mld.Build.Git.Ref, err)
+	}
+
+	labels, err := module.ImageLabels(ctx, authFactory, reg, mld, image)
+	if err != nil {
+		return false, fmt.Errorf("could not get the labels of image %s: %v", image, err)
+	}
+
+	return labels[CommitLabel] == rev.Commit, nil
+}
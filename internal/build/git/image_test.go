@@ -0,0 +1,97 @@
+package git
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+)
+
+var _ = Describe("IsImageUpToDate", func() {
+	const (
+		image  = "registry.example.com/org/image:tag"
+		commit = "0123456789abcdef0123456789abcdef01234567"
+	)
+
+	var (
+		ctrl         *gomock.Controller
+		mockResolver *MockResolver
+		authFactory  *auth.MockRegistryAuthGetterFactory
+		reg          *registry.MockRegistry
+		mld          *api.ModuleLoaderData
+	)
+
+	gitSource := kmmv1beta1.GitBuildSource{URI: "https://git.example.com/org/repo.git", Ref: "main"}
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockResolver = NewMockResolver(ctrl)
+		authFactory = auth.NewMockRegistryAuthGetterFactory(ctrl)
+		reg = registry.NewMockRegistry(ctrl)
+		mld = &api.ModuleLoaderData{
+			Namespace: "namespace",
+			Build:     &kmmv1beta1.Build{Git: &gitSource},
+		}
+	})
+
+	imageWithLabels := func(labels map[string]string) v1.Image {
+		img, err := mutate.Config(empty.Image, v1.Config{Labels: labels})
+		Expect(err).NotTo(HaveOccurred())
+		return img
+	}
+
+	It("should return true if the image is not built from a Git repository", func() {
+		mld.Build.Git = nil
+
+		Expect(IsImageUpToDate(context.Background(), mockResolver, authFactory, reg, mld, image)).To(BeTrue())
+	})
+
+	DescribeTable("should compare the commit of the image with the one of the ref",
+		func(labels map[string]string, expected bool) {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				mockResolver.EXPECT().Resolve(ctx, &gitSource, "namespace").Return(&Revision{Ref: "refs/heads/main", Commit: commit}, nil),
+				authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
+				reg.EXPECT().GetImage(ctx, image, nil, gomock.Any()).Return(imageWithLabels(labels), nil),
+			)
+
+			Expect(IsImageUpToDate(ctx, mockResolver, authFactory, reg, mld, image)).To(Equal(expected))
+		},
+		Entry("same commit", map[string]string{CommitLabel: commit}, true),
+		Entry("previous commit", map[string]string{CommitLabel: "89abcdef0123456789abcdef0123456789abcdef"}, false),
+		Entry("no commit label", nil, false),
+	)
+
+	It("should return an error if the ref cannot be resolved", func() {
+		ctx := context.Background()
+
+		mockResolver.EXPECT().Resolve(ctx, &gitSource, "namespace").Return(nil, errors.New("some error"))
+
+		_, err := IsImageUpToDate(ctx, mockResolver, authFactory, reg, mld, image)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error if the image cannot be fetched", func() {
+		ctx := context.Background()
+
+		gomock.InOrder(
+			mockResolver.EXPECT().Resolve(ctx, &gitSource, "namespace").Return(&Revision{Commit: commit}, nil),
+			authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
+			reg.EXPECT().GetImage(ctx, image, nil, gomock.Any()).Return(nil, errors.New("some error")),
+		)
+
+		_, err := IsImageUpToDate(ctx, mockResolver, authFactory, reg, mld, image)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,220 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+)
+
+const (
+	headRef = "HEAD"
+
+	// peeledSuffix marks the commit an annotated tag points to in the ref advertisement.
+	peeledSuffix = "^{}"
+
+	requestTimeout = 30 * time.Second
+)
+
+var commitRegexp = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// Revision is a resolved Git ref.
+type Revision struct {
+	// Ref is the full name of the ref, such as refs/heads/main, or an empty string if a commit was requested.
+	Ref string
+	// Commit is the commit the ref points to.
+	Commit string
+}
+
+//go:generate mockgen -source=resolver.go -package=git -destination=mock_resolver.go
+
+// Resolver resolves Git refs to commits.
+type Resolver interface {
+	Resolve(ctx context.Context, src *kmmv1beta1.GitBuildSource, namespace string) (*Revision, error)
+}
+
+type resolver struct {
+	client     client.Client
+	httpClient *http.Client
+}
+
+func NewResolver(client client.Client) Resolver {
+	return &resolver{
+		client:     client,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// IsCommit returns true if ref is a full commit hash.
+func IsCommit(ref string) bool {
+	return commitRegexp.MatchString(ref)
+}
+
+// Resolve returns the commit src.Ref points to.
+// Commits are returned as they are; branches and tags are resolved by listing the refs of the repository over HTTP(S),
+// with the credentials of the source secret of src, if any.
+func (r *resolver) Resolve(ctx context.Context, src *kmmv1beta1.GitBuildSource, namespace string) (*Revision, error) {
+	if IsCommit(src.Ref) {
+		return &Revision{Commit: src.Ref}, nil
+	}
+
+	u, err := url.Parse(src.URI)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the URI of the repository: %v", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("only commits can be built from repositories that are not served over HTTP(S), got ref %q", src.Ref)
+	}
+
+	refs, err := r.listRefs(ctx, u, src.SourceSecret, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("could not list the refs of %s: %v", src.URI, err)
+	}
+
+	for _, name := range candidateRefs(src.Ref) {
+		// annotated tags are advertised along with the commit they point to
+		if commit, ok := refs[name+peeledSuffix]; ok {
+			return &Revision{Ref: name, Commit: commit}, nil
+		}
+
+		if commit, ok := refs[name]; ok {
+			return &Revision{Ref: name, Commit: commit}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("ref %q not found in %s", src.Ref, src.URI)
+}
+
+// candidateRefs returns the full names ref may stand for, in order of precedence.
+func candidateRefs(ref string) []string {
+	if ref == "" || ref == headRef {
+		return []string{headRef}
+	}
+
+	if strings.HasPrefix(ref, "refs/") {
+		return []string{ref}
+	}
+
+	return []string{"refs/heads/" + ref, "refs/tags/" + ref}
+}
+
+// listRefs returns the refs advertised by the repository at u using the smart HTTP protocol, by name.
+func (r *resolver) listRefs(ctx context.Context, u *url.URL, secretRef *v1.LocalObjectReference, namespace string) (map[string]string, error) {
+	infoRefs := *u
+	infoRefs.Path = strings.TrimSuffix(u.Path, "/") + "/info/refs"
+	infoRefs.RawQuery = "service=git-upload-pack"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, infoRefs.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the request: %v", err)
+	}
+
+	if secretRef != nil {
+		username, password, err := r.credentials(ctx, secretRef.Name, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		req.SetBasicAuth(username, password)
+	}
+
+	res, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return parseRefAdvertisement(res.Body)
+}
+
+func (r *resolver) credentials(ctx context.Context, name, namespace string) (string, string, error) {
+	secret := v1.Secret{}
+
+	if err := r.client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &secret); err != nil {
+		return "", "", fmt.Errorf("could not get the source secret %s: %v", name, err)
+	}
+
+	return string(secret.Data[v1.BasicAuthUsernameKey]), string(secret.Data[v1.BasicAuthPasswordKey]), nil
+}
+
+// parseRefAdvertisement parses the pkt-lines returned by the git-upload-pack service.
+func parseRefAdvertisement(r io.Reader) (map[string]string, error) {
+	br := bufio.NewReader(r)
+	refs := make(map[string]string)
+
+	for {
+		line, err := readPktLine(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return refs, nil
+			}
+
+			return nil, err
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		// flush packets and the service announcement
+		if line == "" || strings.HasPrefix(line, "# service=") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "ERR ") {
+			return nil, fmt.Errorf("server error: %s", strings.TrimPrefix(line, "ERR "))
+		}
+
+		// the capabilities follow the first ref
+		line, _, _ = strings.Cut(line, "\x00")
+
+		commit, name, ok := strings.Cut(line, " ")
+		if !ok || !IsCommit(commit) {
+			return nil, fmt.Errorf("invalid ref line %q", line)
+		}
+
+		refs[name] = commit
+	}
+}
+
+// readPktLine returns the payload of the next pkt-line; flush packets have an empty payload.
+func readPktLine(br *bufio.Reader) (string, error) {
+	prefix := make([]byte, 4)
+
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return "", err
+	}
+
+	n, err := strconv.ParseUint(string(prefix), 16, 16)
+	if err != nil {
+		return "", fmt.Errorf("invalid pkt-line length %q", prefix)
+	}
+
+	if n < 4 {
+		return "", nil
+	}
+
+	payload := make([]byte, n-4)
+
+	if _, err = io.ReadFull(br, payload); err != nil {
+		return "", fmt.Errorf("truncated pkt-line: %v", err)
+	}
+
+	return string(payload), nil
+}
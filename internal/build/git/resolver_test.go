@@ -0,0 +1,145 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+)
+
+const (
+	headCommit   = "1111111111111111111111111111111111111111"
+	branchCommit = "2222222222222222222222222222222222222222"
+	tagObject    = "3333333333333333333333333333333333333333"
+	tagCommit    = "4444444444444444444444444444444444444444"
+)
+
+func pktLine(s string) string {
+	return fmt.Sprintf("%04x%s", len(s)+4, s)
+}
+
+var _ = Describe("Resolve", func() {
+	var (
+		ctrl   *gomock.Controller
+		clnt   *client.MockClient
+		server *httptest.Server
+		r      *resolver
+		auth   string
+	)
+
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		auth = ""
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/org/kmod.git/info/refs" || req.URL.Query().Get("service") != "git-upload-pack" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			auth = req.Header.Get("Authorization")
+
+			fmt.Fprint(
+				w,
+				pktLine("# service=git-upload-pack\n"),
+				"0000",
+				pktLine(headCommit+" HEAD\x00multi_ack symref=HEAD:refs/heads/main\n"),
+				pktLine(headCommit+" refs/heads/main\n"),
+				pktLine(branchCommit+" refs/heads/v1.0\n"),
+				pktLine(tagObject+" refs/tags/v1.0\n"),
+				pktLine(tagCommit+" refs/tags/v1.0^{}\n"),
+				"0000",
+			)
+		}))
+
+		r = &resolver{client: clnt, httpClient: server.Client()}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	DescribeTable("should resolve refs to commits",
+		func(ref string, expected Revision) {
+			src := kmmv1beta1.GitBuildSource{URI: server.URL + "/org/kmod.git", Ref: ref}
+
+			rev, err := r.Resolve(ctx, &src, "ns")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*rev).To(Equal(expected))
+		},
+		Entry("default branch", "", Revision{Ref: "HEAD", Commit: headCommit}),
+		Entry("branch", "main", Revision{Ref: "refs/heads/main", Commit: headCommit}),
+		Entry("branches before tags", "v1.0", Revision{Ref: "refs/heads/v1.0", Commit: branchCommit}),
+		Entry("annotated tag", "refs/tags/v1.0", Revision{Ref: "refs/tags/v1.0", Commit: tagCommit}),
+		Entry("commit", branchCommit, Revision{Commit: branchCommit}),
+	)
+
+	It("should return an error if the ref does not exist", func() {
+		src := kmmv1beta1.GitBuildSource{URI: server.URL + "/org/kmod.git", Ref: "missing"}
+
+		_, err := r.Resolve(ctx, &src, "ns")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error if the repository does not exist", func() {
+		src := kmmv1beta1.GitBuildSource{URI: server.URL + "/org/other.git"}
+
+		_, err := r.Resolve(ctx, &src, "ns")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should only accept commits from repositories not served over HTTP(S)", func() {
+		src := kmmv1beta1.GitBuildSource{URI: "ssh://git@example.com/org/kmod.git", Ref: "main"}
+
+		_, err := r.Resolve(ctx, &src, "ns")
+		Expect(err).To(HaveOccurred())
+
+		src.Ref = headCommit
+
+		rev, err := r.Resolve(ctx, &src, "ns")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rev.Commit).To(Equal(headCommit))
+	})
+
+	It("should authenticate with the source secret", func() {
+		src := kmmv1beta1.GitBuildSource{
+			URI:          server.URL + "/org/kmod.git/",
+			SourceSecret: &v1.LocalObjectReference{Name: "git-creds"},
+		}
+
+		clnt.EXPECT().Get(ctx, types.NamespacedName{Name: "git-creds", Namespace: "ns"}, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, s *v1.Secret, _ ...ctrlclient.GetOption) error {
+				s.Data = map[string][]byte{
+					v1.BasicAuthUsernameKey: []byte("user"),
+					v1.BasicAuthPasswordKey: []byte("token"),
+				}
+				return nil
+			},
+		)
+
+		_, err := r.Resolve(ctx, &src, "ns")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(auth).To(HavePrefix("Basic "))
+	})
+})
+
+var _ = Describe("parseRefAdvertisement", func() {
+	It("should return the error reported by the server", func() {
+		_, err := parseRefAdvertisement(strings.NewReader(pktLine("ERR access denied\n")))
+		Expect(err).To(MatchError(ContainSubstring("access denied")))
+	})
+})
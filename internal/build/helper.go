@@ -56,8 +56,20 @@ func (m *helper) GetRelevantBuild(moduleBuild *kmmv1beta1.Build, mappingBuild *k
 	}
 
 	buildConfig := moduleBuild.DeepCopy()
+
+	// the Dockerfile ConfigMap and the inline Dockerfile are exclusive
 	if mappingBuild.DockerfileConfigMap != nil {
 		buildConfig.DockerfileConfigMap = mappingBuild.DockerfileConfigMap
+		buildConfig.Dockerfile = ""
+	}
+
+	if mappingBuild.Dockerfile != "" {
+		buildConfig.Dockerfile = mappingBuild.Dockerfile
+		buildConfig.DockerfileConfigMap = nil
+	}
+
+	if mappingBuild.Git != nil {
+		buildConfig.Git = mappingBuild.Git
 	}
 
 	buildConfig.BuildArgs = m.ApplyBuildArgOverrides(buildConfig.BuildArgs, mappingBuild.BuildArgs...)
@@ -65,12 +77,27 @@ func (m *helper) GetRelevantBuild(moduleBuild *kmmv1beta1.Build, mappingBuild *k
 	// [TODO] once MGMT-10832 is consolidated, this code must be revisited. We will decide which
 	// secret and how to use, and if we need to take care of repeated secrets names
 	buildConfig.Secrets = append(buildConfig.Secrets, mappingBuild.Secrets...)
+
+	if mappingBuild.RetryPolicy != nil {
+		buildConfig.RetryPolicy = mappingBuild.RetryPolicy
+	}
+
 	return buildConfig
 }
 
+// GetModuleBuildLabels returns the labels shared by all Builds of the module modName.
+func GetModuleBuildLabels(modName string) map[string]string {
+	return map[string]string{constants.ModuleNameLabel: modName}
+}
+
+// GetBuildLabels returns the labels of the Build of mld.
 func GetBuildLabels(mld *api.ModuleLoaderData) map[string]string {
-	return map[string]string{
-		constants.ModuleNameLabel:    mld.Name,
-		constants.TargetKernelTarget: mld.KernelVersion,
+	labels := GetModuleBuildLabels(mld.Name)
+	labels[constants.TargetKernelTarget] = mld.KernelVersion
+
+	if mld.Arch != "" {
+		labels[constants.ArchLabel] = mld.Arch
 	}
+
+	return labels
 }
@@ -4,6 +4,8 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -50,6 +52,34 @@ var _ = Describe("GetRelevantBuild", func() {
 		Expect(res.DockerfileConfigMap).To(Equal(mappingBuild.DockerfileConfigMap))
 		Expect(res.BaseImageRegistryTLS).To(Equal(moduleBuild.BaseImageRegistryTLS))
 	})
+
+	It("should use the retry policy of the kernel mapping if any", func() {
+		moduleBuild := &kmmv1beta1.Build{RetryPolicy: &kmmv1beta1.RetryPolicy{MaxAttempts: 2}}
+		mappingBuild := &kmmv1beta1.Build{RetryPolicy: &kmmv1beta1.RetryPolicy{MaxAttempts: 5}}
+
+		Expect(nh.GetRelevantBuild(moduleBuild, mappingBuild).RetryPolicy).To(Equal(mappingBuild.RetryPolicy))
+		Expect(nh.GetRelevantBuild(moduleBuild, &kmmv1beta1.Build{}).RetryPolicy).To(Equal(moduleBuild.RetryPolicy))
+	})
+
+	It("should replace the Dockerfile source of the module loader with the one of the kernel mapping", func() {
+		moduleBuild := &kmmv1beta1.Build{
+			DockerfileConfigMap: &v1.LocalObjectReference{Name: "module-cm"},
+			Git:                 &kmmv1beta1.GitBuildSource{URI: "https://git.example.com/module.git"},
+		}
+		mappingBuild := &kmmv1beta1.Build{
+			Dockerfile: "FROM test",
+			Git:        &kmmv1beta1.GitBuildSource{URI: "https://git.example.com/mapping.git"},
+		}
+
+		res := nh.GetRelevantBuild(moduleBuild, mappingBuild)
+		Expect(res.DockerfileConfigMap).To(BeNil())
+		Expect(res.Dockerfile).To(Equal(mappingBuild.Dockerfile))
+		Expect(res.Git).To(Equal(mappingBuild.Git))
+
+		res = nh.GetRelevantBuild(&kmmv1beta1.Build{Dockerfile: "FROM test"}, moduleBuild)
+		Expect(res.Dockerfile).To(BeEmpty())
+		Expect(res.DockerfileConfigMap).To(Equal(moduleBuild.DockerfileConfigMap))
+	})
 })
 
 var _ = Describe("ApplyBuildArgOverrides", func() {
@@ -101,3 +131,28 @@ var _ = Describe("ApplyBuildArgOverrides", func() {
 		Expect(res).To(Equal(expected))
 	})
 })
+
+var _ = Describe("GetBuildLabels", func() {
+	It("should not set the architecture label if the architecture is unknown", func() {
+		mld := api.ModuleLoaderData{Name: "module-name", KernelVersion: "1.2.3"}
+
+		Expect(
+			GetBuildLabels(&mld),
+		).To(
+			Equal(map[string]string{
+				constants.ModuleNameLabel:    "module-name",
+				constants.TargetKernelTarget: "1.2.3",
+			}),
+		)
+	})
+
+	It("should set the architecture label", func() {
+		mld := api.ModuleLoaderData{Name: "module-name", KernelVersion: "1.2.3", Arch: "arm64"}
+
+		Expect(
+			GetBuildLabels(&mld),
+		).To(
+			HaveKeyWithValue(constants.ArchLabel, "arm64"),
+		)
+	})
+})
@@ -0,0 +1,432 @@
+package buildjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mitchellh/hashstructure"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	kmmbuild "github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+const (
+	dtkBuildArg = "DTK_AUTO"
+
+	kanikoImage      = "gcr.io/kaniko-project/executor"
+	kanikoDefaultTag = "latest"
+
+	dockerfileVolumeName = "dockerfile"
+	dockerfileMountPath  = "/workspace"
+	kanikoDockerDir      = "/kaniko/.docker"
+
+	// gitDockerfileMountPath holds the Dockerfile replacing the one of a Git repository, outside of the build context.
+	gitDockerfileMountPath = "/kaniko/dockerfile"
+
+	// dockerfileAnnotation holds the inline Dockerfile, which is mounted in the build pod through the downward API.
+	dockerfileAnnotation = "kmm.node.kubernetes.io/dockerfile"
+)
+
+//go:generate mockgen -source=maker.go -package=buildjob -destination=mock_maker.go
+
+type Maker interface {
+	MakeJobTemplate(
+		ctx context.Context,
+		mld *api.ModuleLoaderData,
+		labels map[string]string,
+		pushImage bool,
+		owner metav1.Object,
+	) (*batchv1.Job, error)
+}
+
+type hashData struct {
+	Dockerfile  string
+	PodTemplate *v1.PodTemplateSpec
+}
+
+type maker struct {
+	client             client.Client
+	helper             kmmbuild.Helper
+	gitResolver        git.Resolver
+	cache              cache.Cache
+	kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping
+	scheme             *runtime.Scheme
+}
+
+func NewMaker(
+	client client.Client,
+	helper kmmbuild.Helper,
+	gitResolver git.Resolver,
+	buildCache cache.Cache,
+	scheme *runtime.Scheme,
+	kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping) Maker {
+	return &maker{
+		client:             client,
+		helper:             helper,
+		gitResolver:        gitResolver,
+		cache:              buildCache,
+		kernelOsDtkMapping: kernelOsDtkMapping,
+		scheme:             scheme,
+	}
+}
+
+func (m *maker) MakeJobTemplate(
+	ctx context.Context,
+	mld *api.ModuleLoaderData,
+	labels map[string]string,
+	pushImage bool,
+	owner metav1.Object,
+) (*batchv1.Job, error) {
+
+	kmmBuild := mld.Build
+	containerImage := mld.ContainerImage
+	kernelVersion := mld.KernelVersion
+
+	// if build AND sign are specified, then we will build an intermediate image
+	// and let sign produce the final image specified in spec.moduleLoader.container.km.containerImage
+	if module.ShouldBeSigned(mld) {
+		containerImage = module.IntermediateImageName(mld.Name, mld.Namespace, containerImage)
+	}
+
+	overrides := []kmmv1beta1.BuildArg{
+		{
+			Name:  "KERNEL_VERSION",
+			Value: kernelVersion,
+		},
+	}
+
+	dockerfileData, err := m.getDockerfileData(ctx, kmmBuild, mld.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dockerfile data: %v", err)
+	}
+
+	var dtkImage string
+
+	// the Dockerfile of a Git repository is not known in advance and may not use the DTK
+	if dockerfileData == "" || strings.Contains(dockerfileData, dtkBuildArg) {
+		image, err := m.kernelOsDtkMapping.GetImage(kernelVersion)
+		switch {
+		case err == nil:
+			dtkImage = image
+			overrides = append(overrides, kmmv1beta1.BuildArg{Name: dtkBuildArg, Value: dtkImage})
+		case dockerfileData != "":
+			return nil, fmt.Errorf("could not get DTK image for kernel %v: %v", kernelVersion, err)
+		}
+	}
+
+	buildArgs := m.helper.ApplyBuildArgOverrides(
+		kmmBuild.BuildArgs,
+		overrides...,
+	)
+
+	cacheInputs := cache.Inputs{
+		Dockerfile: dockerfileData,
+		BuildArgs:  buildArgs,
+		DTKImage:   dtkImage,
+		Arch:       mld.Arch,
+	}
+
+	var rev *git.Revision
+
+	if g := kmmBuild.Git; g != nil {
+		if rev, err = m.gitResolver.Resolve(ctx, g, mld.Namespace); err != nil {
+			return nil, fmt.Errorf("could not resolve Git ref %q: %v", g.Ref, err)
+		}
+
+		cacheInputs.GitURI = g.URI
+		cacheInputs.GitCommit = rev.Commit
+		cacheInputs.GitContextDir = g.ContextDir
+	}
+
+	annotations := make(map[string]string)
+
+	// identical builds push to the cache repository, from which their result is copied to the container image
+	if pushImage {
+		if key := m.cache.Key(mld, &cacheInputs); key != "" {
+			containerImage = m.cache.Image(key)
+			annotations[cache.KeyAnnotation] = key
+		}
+	}
+
+	args := make([]string, 0)
+
+	if pushImage {
+		args = append(args, "--destination", containerImage)
+
+		if mld.RegistryTLS != nil {
+			if mld.RegistryTLS.Insecure {
+				args = append(args, "--insecure")
+			}
+			if mld.RegistryTLS.InsecureSkipTLSVerify {
+				args = append(args, "--skip-tls-verify")
+			}
+		}
+	} else {
+		args = append(args, "--no-push")
+	}
+
+	for _, ba := range buildArgs {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", ba.Name, ba.Value))
+	}
+
+	if kmmBuild.BaseImageRegistryTLS.Insecure {
+		args = append(args, "--insecure-pull")
+	}
+
+	if kmmBuild.BaseImageRegistryTLS.InsecureSkipTLSVerify {
+		args = append(args, "--skip-tls-verify-pull")
+	}
+
+	volumes := make([]v1.Volume, 0)
+	volumeMounts := make([]v1.VolumeMount, 0)
+	podAnnotations := make(map[string]string)
+
+	dockerfileDir := dockerfileMountPath
+	if kmmBuild.Git != nil {
+		dockerfileDir = gitDockerfileMountPath
+	}
+
+	if dockerfileData != "" {
+		volumes = append(volumes, makeDockerfileVolume(kmmBuild))
+		volumeMounts = append(volumeMounts, v1.VolumeMount{
+			Name:      dockerfileVolumeName,
+			ReadOnly:  true,
+			MountPath: dockerfileDir,
+		})
+
+		if kmmBuild.DockerfileConfigMap == nil {
+			podAnnotations[dockerfileAnnotation] = dockerfileData
+		}
+	}
+
+	var env []v1.EnvVar
+
+	// the commit is part of the pod template, so that a new commit on the branch triggers a new Job
+	if g := kmmBuild.Git; g != nil {
+		gitArgs, gitEnv, err := gitContext(g, rev)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, gitArgs...)
+		env = gitEnv
+
+		if dockerfileData != "" {
+			args = append(args, "--dockerfile="+dockerfileDir+"/Dockerfile")
+		}
+	} else {
+		args = append(args, "--context=dir://"+dockerfileDir, "--dockerfile="+dockerfileDir+"/Dockerfile")
+	}
+
+	if irs := mld.ImageRepoSecret; irs != nil {
+		volumes = append(volumes, utils.MakeSecretVolume(irs, v1.DockerConfigJsonKey, "config.json"))
+		volumeMounts = append(volumeMounts, utils.MakeSecretVolumeMount(irs, kanikoDockerDir))
+	}
+
+	for i := range kmmBuild.Secrets {
+		s := &kmmBuild.Secrets[i]
+		volumes = append(volumes, utils.MakeSecretVolume(s, "", ""))
+		volumeMounts = append(volumeMounts, utils.MakeSecretVolumeMount(s, "/run/secrets/"+s.Name))
+	}
+
+	kanikoTag := kanikoDefaultTag
+	if kmmBuild.KanikoParams != nil && kmmBuild.KanikoParams.Tag != "" {
+		kanikoTag = kmmBuild.KanikoParams.Tag
+	}
+
+	specTemplate := v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Args:         args,
+					Env:          env,
+					Name:         "kaniko",
+					Image:        kanikoImage + ":" + kanikoTag,
+					VolumeMounts: volumeMounts,
+				},
+			},
+			RestartPolicy: v1.RestartPolicyNever,
+			Volumes:       volumes,
+			NodeSelector:  module.NodeSelector(mld),
+		},
+	}
+
+	if len(podAnnotations) > 0 {
+		specTemplate.Annotations = podAnnotations
+	}
+
+	specTemplateHash, err := getHashValue(dockerfileData, &specTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("could not hash job's definitions: %v", err)
+	}
+
+	annotations[constants.JobHashAnnotation] = fmt.Sprintf("%d", specTemplateHash)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: mld.Name + "-build-",
+			Namespace:    mld.Namespace,
+			Labels:       labels,
+			Annotations:  annotations,
+		},
+		Spec: batchv1.JobSpec{
+			Completions:  pointer.Int32(1),
+			Template:     specTemplate,
+			BackoffLimit: pointer.Int32(0),
+		},
+	}
+
+	if err := controllerutil.SetControllerReference(owner, job, m.scheme); err != nil {
+		return nil, fmt.Errorf("could not set the owner reference: %v", err)
+	}
+
+	return job, nil
+}
+
+// getDockerfileData returns the inline Dockerfile or the one of the Dockerfile ConfigMap, or an empty string if the
+// Dockerfile is read from the Git repository.
+func (m *maker) getDockerfileData(ctx context.Context, buildConfig *kmmv1beta1.Build, namespace string) (string, error) {
+	if buildConfig.Dockerfile != "" {
+		return buildConfig.Dockerfile, nil
+	}
+
+	if buildConfig.DockerfileConfigMap == nil {
+		if buildConfig.Git == nil {
+			return "", errors.New("no Dockerfile ConfigMap, inline Dockerfile nor Git repository")
+		}
+
+		return "", nil
+	}
+
+	dockerfileCM := &v1.ConfigMap{}
+	namespacedName := types.NamespacedName{Name: buildConfig.DockerfileConfigMap.Name, Namespace: namespace}
+	err := m.client.Get(ctx, namespacedName, dockerfileCM)
+	if err != nil {
+		return "", fmt.Errorf("failed to get dockerfile ConfigMap %s: %v", namespacedName, err)
+	}
+	data, ok := dockerfileCM.Data[constants.DockerfileCMKey]
+	if !ok {
+		return "", fmt.Errorf("invalid Dockerfile ConfigMap %s format, %s key is missing", namespacedName, constants.DockerfileCMKey)
+	}
+	return data, nil
+}
+
+// gitContext returns the kaniko arguments and environment that fetch the build context from rev of the Git
+// repository.
+func gitContext(g *kmmv1beta1.GitBuildSource, rev *git.Revision) ([]string, []v1.EnvVar, error) {
+	u, err := url.Parse(g.URI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse the URI of the repository: %v", err)
+	}
+
+	// kaniko clones the default branch when no ref is given
+	ref := rev.Ref
+	if ref == "HEAD" {
+		ref = ""
+	}
+
+	args := []string{
+		fmt.Sprintf("--context=git://%s%s#%s#%s", u.Host, u.Path, ref, rev.Commit),
+		// the commit is recorded on the image, so that ShouldSync rebuilds it when the branch moves
+		fmt.Sprintf("--label=%s=%s", git.CommitLabel, rev.Commit),
+	}
+
+	if g.ContextDir != "" {
+		args = append(args, "--context-sub-path="+g.ContextDir)
+	}
+
+	var env []v1.EnvVar
+
+	if u.Scheme == "http" {
+		env = append(env, v1.EnvVar{Name: "GIT_PULL_METHOD", Value: "http"})
+	}
+
+	if s := g.SourceSecret; s != nil {
+		env = append(
+			env,
+			makeSecretEnvVar("GIT_USERNAME", s, v1.BasicAuthUsernameKey),
+			makeSecretEnvVar("GIT_PASSWORD", s, v1.BasicAuthPasswordKey),
+		)
+	}
+
+	return args, env, nil
+}
+
+// makeDockerfileVolume returns the volume holding the Dockerfile of the Dockerfile ConfigMap, or the inline
+// Dockerfile annotated on the build pod.
+func makeDockerfileVolume(kmmBuild *kmmv1beta1.Build) v1.Volume {
+	if kmmBuild.DockerfileConfigMap != nil {
+		return v1.Volume{
+			Name: dockerfileVolumeName,
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: *kmmBuild.DockerfileConfigMap,
+					Items: []v1.KeyToPath{
+						{
+							Key:  constants.DockerfileCMKey,
+							Path: "Dockerfile",
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return v1.Volume{
+		Name: dockerfileVolumeName,
+		VolumeSource: v1.VolumeSource{
+			DownwardAPI: &v1.DownwardAPIVolumeSource{
+				Items: []v1.DownwardAPIVolumeFile{
+					{
+						Path: "Dockerfile",
+						FieldRef: &v1.ObjectFieldSelector{
+							FieldPath: fmt.Sprintf("metadata.annotations['%s']", dockerfileAnnotation),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func makeSecretEnvVar(name string, secret *v1.LocalObjectReference, key string) v1.EnvVar {
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: *secret,
+				Key:                  key,
+			},
+		},
+	}
+}
+
+func getHashValue(dockerfile string, podTemplate *v1.PodTemplateSpec) (uint64, error) {
+	dataToHash := hashData{
+		Dockerfile:  dockerfile,
+		PodTemplate: podTemplate,
+	}
+	hashValue, err := hashstructure.Hash(dataToHash, nil)
+	if err != nil {
+		return 0, fmt.Errorf("could not hash job's spec template and dockerfile: %v", err)
+	}
+	return hashValue, nil
+}
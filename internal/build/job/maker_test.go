@@ -0,0 +1,485 @@
+package buildjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
+)
+
+var _ = Describe("MakeJobTemplate", func() {
+	const (
+		containerImage = "my.registry/my/image"
+		dockerfile     = "FROM test"
+		kernelVersion  = "1.2.3"
+		moduleName     = "module-name"
+		namespace      = "some-namespace"
+		dockerfileCM   = "some-configmap"
+	)
+
+	var (
+		ctrl               *gomock.Controller
+		clnt               *client.MockClient
+		gitResolver        *git.MockResolver
+		kernelOsDtkMapping *syncronizedmap.MockKernelOsDtkMapping
+		m                  Maker
+		mld                api.ModuleLoaderData
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		gitResolver = git.NewMockResolver(ctrl)
+		kernelOsDtkMapping = syncronizedmap.NewMockKernelOsDtkMapping(ctrl)
+		m = NewMaker(clnt, build.NewHelper(), gitResolver, cache.New("", nil, nil), scheme, kernelOsDtkMapping)
+		mld = api.ModuleLoaderData{
+			Name:      moduleName,
+			Namespace: namespace,
+			Owner: &kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+			},
+			KernelVersion:  kernelVersion,
+			ContainerImage: containerImage,
+			RegistryTLS:    &kmmv1beta1.TLSOptions{},
+			Selector:       map[string]string{"arch": "x64"},
+		}
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	labels := map[string]string{
+		constants.ModuleNameLabel:    moduleName,
+		constants.TargetKernelTarget: kernelVersion,
+		constants.JobType:            "build",
+	}
+
+	mockDockerfileCM := func(data string) {
+		clnt.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: dockerfileCM, Namespace: namespace}, gomock.Any()).DoAndReturn(
+			func(_ interface{}, _ interface{}, cm *v1.ConfigMap, _ ...ctrlclient.GetOption) error {
+				cm.Data = map[string]string{constants.DockerfileCMKey: data}
+				return nil
+			},
+		)
+	}
+
+	It("should set fields correctly", func() {
+		ctx := context.Background()
+
+		mld.Build = &kmmv1beta1.Build{
+			BuildArgs:           []kmmv1beta1.BuildArg{{Name: "name1", Value: "value1"}},
+			DockerfileConfigMap: &v1.LocalObjectReference{Name: dockerfileCM},
+			Secrets:             []v1.LocalObjectReference{{Name: "build-secret"}},
+			KanikoParams:        &kmmv1beta1.KanikoParams{Tag: "debug"},
+		}
+		mld.ImageRepoSecret = &v1.LocalObjectReference{Name: "pull-push-secret"}
+
+		mockDockerfileCM(dockerfile)
+
+		job, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+
+		expectedPodTemplate := v1.PodTemplateSpec{
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{
+						Args: []string{
+							"--destination", containerImage,
+							"--build-arg", "name1=value1",
+							"--build-arg", "KERNEL_VERSION=" + kernelVersion,
+							"--context=dir:///workspace",
+							"--dockerfile=/workspace/Dockerfile",
+						},
+						Name:  "kaniko",
+						Image: "gcr.io/kaniko-project/executor:debug",
+						VolumeMounts: []v1.VolumeMount{
+							{
+								Name:      "dockerfile",
+								ReadOnly:  true,
+								MountPath: "/workspace",
+							},
+							{
+								Name:      "secret-pull-push-secret",
+								ReadOnly:  true,
+								MountPath: "/kaniko/.docker",
+							},
+							{
+								Name:      "secret-build-secret",
+								ReadOnly:  true,
+								MountPath: "/run/secrets/build-secret",
+							},
+						},
+					},
+				},
+				RestartPolicy: v1.RestartPolicyNever,
+				Volumes: []v1.Volume{
+					{
+						Name: "dockerfile",
+						VolumeSource: v1.VolumeSource{
+							ConfigMap: &v1.ConfigMapVolumeSource{
+								LocalObjectReference: v1.LocalObjectReference{Name: dockerfileCM},
+								Items: []v1.KeyToPath{
+									{
+										Key:  constants.DockerfileCMKey,
+										Path: "Dockerfile",
+									},
+								},
+							},
+						},
+					},
+					{
+						Name: "secret-pull-push-secret",
+						VolumeSource: v1.VolumeSource{
+							Secret: &v1.SecretVolumeSource{
+								SecretName: "pull-push-secret",
+								Items: []v1.KeyToPath{
+									{
+										Key:  v1.DockerConfigJsonKey,
+										Path: "config.json",
+									},
+								},
+							},
+						},
+					},
+					{
+						Name: "secret-build-secret",
+						VolumeSource: v1.VolumeSource{
+							Secret: &v1.SecretVolumeSource{SecretName: "build-secret"},
+						},
+					},
+				},
+				NodeSelector: mld.Selector,
+			},
+		}
+
+		hash, err := getHashValue(dockerfile, &expectedPodTemplate)
+		Expect(err).NotTo(HaveOccurred())
+
+		expected := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: moduleName + "-build-",
+				Namespace:    namespace,
+				Labels:       labels,
+				Annotations:  map[string]string{constants.JobHashAnnotation: fmt.Sprintf("%d", hash)},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "kmm.sigs.x-k8s.io/v1beta1",
+						Kind:               "Module",
+						Name:               moduleName,
+						Controller:         pointer.Bool(true),
+						BlockOwnerDeletion: pointer.Bool(true),
+					},
+				},
+			},
+			Spec: batchv1.JobSpec{
+				Completions:  pointer.Int32(1),
+				BackoffLimit: pointer.Int32(0),
+				Template:     expectedPodTemplate,
+			},
+		}
+
+		Expect(
+			cmp.Diff(expected, job),
+		).To(
+			BeEmpty(),
+		)
+	})
+
+	DescribeTable("should set the TLS and push flags",
+		func(pushImage bool, registryTLS, baseImageRegistryTLS kmmv1beta1.TLSOptions, expectedFlags ...string) {
+			ctx := context.Background()
+
+			mld.RegistryTLS = &registryTLS
+			mld.Build = &kmmv1beta1.Build{
+				DockerfileConfigMap:  &v1.LocalObjectReference{Name: dockerfileCM},
+				BaseImageRegistryTLS: baseImageRegistryTLS,
+			}
+
+			mockDockerfileCM(dockerfile)
+
+			job, err := m.MakeJobTemplate(ctx, &mld, labels, pushImage, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("gcr.io/kaniko-project/executor:latest"))
+			Expect(container.Args).To(ContainElements(expectedFlags))
+		},
+		Entry("no push", false, kmmv1beta1.TLSOptions{}, kmmv1beta1.TLSOptions{}, "--no-push"),
+		Entry(
+			"insecure push",
+			true,
+			kmmv1beta1.TLSOptions{Insecure: true, InsecureSkipTLSVerify: true},
+			kmmv1beta1.TLSOptions{},
+			"--insecure", "--skip-tls-verify",
+		),
+		Entry(
+			"insecure pull",
+			false,
+			kmmv1beta1.TLSOptions{},
+			kmmv1beta1.TLSOptions{Insecure: true, InsecureSkipTLSVerify: true},
+			"--insecure-pull", "--skip-tls-verify-pull",
+		),
+	)
+
+	It("should build the intermediate image if signing is required", func() {
+		ctx := context.Background()
+
+		mld.Build = &kmmv1beta1.Build{
+			DockerfileConfigMap: &v1.LocalObjectReference{Name: dockerfileCM},
+		}
+		mld.Sign = &kmmv1beta1.Sign{}
+
+		mockDockerfileCM(dockerfile)
+
+		job, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(
+			ContainElements("--destination", module.IntermediateImageName(moduleName, namespace, containerImage)),
+		)
+	})
+
+	It("should add the DTK image as a build argument if required", func() {
+		const dtkImage = "some-dtk-image"
+
+		ctx := context.Background()
+
+		mld.Build = &kmmv1beta1.Build{
+			DockerfileConfigMap: &v1.LocalObjectReference{Name: dockerfileCM},
+		}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, cm *v1.ConfigMap, _ ...ctrlclient.GetOption) error {
+					cm.Data = map[string]string{constants.DockerfileCMKey: "FROM " + dtkBuildArg}
+					return nil
+				},
+			),
+			kernelOsDtkMapping.EXPECT().GetImage(kernelVersion).Return(dtkImage, nil),
+		)
+
+		job, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(
+			ContainElements("--build-arg", dtkBuildArg+"="+dtkImage),
+		)
+	})
+
+	It("should return an error if the Dockerfile ConfigMap cannot be fetched", func() {
+		ctx := context.Background()
+
+		mld.Build = &kmmv1beta1.Build{
+			DockerfileConfigMap: &v1.LocalObjectReference{Name: dockerfileCM},
+		}
+
+		clnt.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("random error"))
+
+		_, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should push to the cache repository if the build is cached", func() {
+		ctx := context.Background()
+
+		mockCache := cache.NewMockCache(ctrl)
+		m = NewMaker(clnt, build.NewHelper(), gitResolver, mockCache, scheme, kernelOsDtkMapping)
+
+		mld.Build = &kmmv1beta1.Build{Dockerfile: dockerfile}
+		mld.Arch = "x86_64"
+
+		gomock.InOrder(
+			mockCache.EXPECT().Key(
+				&mld,
+				&cache.Inputs{
+					Dockerfile: dockerfile,
+					BuildArgs:  []kmmv1beta1.BuildArg{{Name: "KERNEL_VERSION", Value: kernelVersion}},
+					Arch:       "x86_64",
+				},
+			).Return("some-key"),
+			mockCache.EXPECT().Image("some-key").Return("registry.example.com/cache:some-key"),
+		)
+
+		job, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(
+			ContainElements("--destination", "registry.example.com/cache:some-key"),
+		)
+		Expect(job.Annotations).To(HaveKeyWithValue(cache.KeyAnnotation, "some-key"))
+	})
+
+	It("should not use the cache if the image is not pushed", func() {
+		ctx := context.Background()
+
+		m = NewMaker(clnt, build.NewHelper(), gitResolver, cache.NewMockCache(ctrl), scheme, kernelOsDtkMapping)
+
+		mld.Build = &kmmv1beta1.Build{Dockerfile: dockerfile}
+
+		job, err := m.MakeJobTemplate(ctx, &mld, labels, false, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Annotations).NotTo(HaveKey(cache.KeyAnnotation))
+	})
+
+	It("should mount the inline Dockerfile through the downward API", func() {
+		ctx := context.Background()
+
+		mld.Build = &kmmv1beta1.Build{Dockerfile: dockerfile}
+
+		job, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+
+		podTemplate := job.Spec.Template
+		Expect(podTemplate.Annotations).To(HaveKeyWithValue(dockerfileAnnotation, dockerfile))
+		Expect(podTemplate.Spec.Volumes).To(HaveLen(1))
+		Expect(podTemplate.Spec.Volumes[0].DownwardAPI).To(
+			Equal(&v1.DownwardAPIVolumeSource{
+				Items: []v1.DownwardAPIVolumeFile{
+					{
+						Path:     "Dockerfile",
+						FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.annotations['" + dockerfileAnnotation + "']"},
+					},
+				},
+			}),
+		)
+		Expect(podTemplate.Spec.Containers[0].VolumeMounts).To(
+			ContainElement(v1.VolumeMount{Name: "dockerfile", ReadOnly: true, MountPath: "/workspace"}),
+		)
+	})
+
+	Describe("building from a Git repository", func() {
+		const commit = "0123456789abcdef0123456789abcdef01234567"
+
+		gitSource := kmmv1beta1.GitBuildSource{
+			URI:          "http://git.example.com/org/repo.git",
+			Ref:          "main",
+			ContextDir:   "driver",
+			SourceSecret: &v1.LocalObjectReference{Name: "git-secret"},
+		}
+
+		It("should fetch the context from the resolved commit", func() {
+			ctx := context.Background()
+
+			mld.Build = &kmmv1beta1.Build{Git: &gitSource}
+
+			gomock.InOrder(
+				kernelOsDtkMapping.EXPECT().GetImage(kernelVersion).Return("", errors.New("no DTK")),
+				gitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(&git.Revision{Ref: "refs/heads/main", Commit: commit}, nil),
+			)
+
+			job, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Args).To(
+				ContainElements(
+					"--context=git://git.example.com/org/repo.git#refs/heads/main#"+commit,
+					"--context-sub-path=driver",
+					"--label="+git.CommitLabel+"="+commit,
+				),
+			)
+			Expect(container.Args).NotTo(ContainElement(HavePrefix("--dockerfile")))
+			Expect(container.Args).NotTo(ContainElement(HavePrefix(dtkBuildArg)))
+			Expect(container.Env).To(
+				Equal([]v1.EnvVar{
+					{Name: "GIT_PULL_METHOD", Value: "http"},
+					{
+						Name: "GIT_USERNAME",
+						ValueFrom: &v1.EnvVarSource{
+							SecretKeyRef: &v1.SecretKeySelector{
+								LocalObjectReference: v1.LocalObjectReference{Name: "git-secret"},
+								Key:                  v1.BasicAuthUsernameKey,
+							},
+						},
+					},
+					{
+						Name: "GIT_PASSWORD",
+						ValueFrom: &v1.EnvVarSource{
+							SecretKeyRef: &v1.SecretKeySelector{
+								LocalObjectReference: v1.LocalObjectReference{Name: "git-secret"},
+								Key:                  v1.BasicAuthPasswordKey,
+							},
+						},
+					},
+				}),
+			)
+			Expect(job.Spec.Template.Spec.Volumes).To(BeEmpty())
+		})
+
+		It("should use the inline Dockerfile instead of the one of the repository", func() {
+			ctx := context.Background()
+
+			mld.Build = &kmmv1beta1.Build{Dockerfile: dockerfile, Git: &gitSource}
+
+			gitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(&git.Revision{Ref: "HEAD", Commit: commit}, nil)
+
+			job, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.Template.Spec.Containers[0].Args).To(
+				ContainElements(
+					"--context=git://git.example.com/org/repo.git##"+commit,
+					"--dockerfile=/kaniko/dockerfile/Dockerfile",
+				),
+			)
+			Expect(job.Spec.Template.Spec.Containers[0].Args).NotTo(ContainElement("--context=dir:///workspace"))
+			Expect(job.Spec.Template.Spec.Containers[0].VolumeMounts).To(
+				ContainElement(v1.VolumeMount{Name: "dockerfile", ReadOnly: true, MountPath: "/kaniko/dockerfile"}),
+			)
+		})
+
+		It("should change the hash when the ref points to a new commit", func() {
+			ctx := context.Background()
+
+			mld.Build = &kmmv1beta1.Build{Dockerfile: dockerfile, Git: &gitSource}
+
+			gomock.InOrder(
+				gitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(&git.Revision{Ref: "refs/heads/main", Commit: commit}, nil),
+				gitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(
+					&git.Revision{Ref: "refs/heads/main", Commit: "89abcdef0123456789abcdef0123456789abcdef"},
+					nil,
+				),
+			)
+
+			job1, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+
+			job2, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(job1.Annotations[constants.JobHashAnnotation]).NotTo(Equal(job2.Annotations[constants.JobHashAnnotation]))
+		})
+
+		It("should return an error if the ref cannot be resolved", func() {
+			ctx := context.Background()
+
+			mld.Build = &kmmv1beta1.Build{Dockerfile: dockerfile, Git: &gitSource}
+
+			gitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(nil, errors.New("random error"))
+
+			_, err := m.MakeJobTemplate(ctx, &mld, labels, true, mld.Owner)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,193 @@
+package buildjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/retry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+type jobManager struct {
+	maker       Maker
+	jobHelper   utils.JobHelper
+	authFactory auth.RegistryAuthGetterFactory
+	registry    registry.Registry
+	gitResolver git.Resolver
+	cache       cache.Cache
+	scheduler   scheduler.Scheduler
+	clock       clock.PassiveClock
+}
+
+func NewBuildManager(
+	maker Maker,
+	jobHelper utils.JobHelper,
+	authFactory auth.RegistryAuthGetterFactory,
+	registry registry.Registry,
+	gitResolver git.Resolver,
+	buildCache cache.Cache,
+	buildScheduler scheduler.Scheduler) *jobManager {
+	return &jobManager{
+		maker:       maker,
+		jobHelper:   jobHelper,
+		authFactory: authFactory,
+		registry:    registry,
+		gitResolver: gitResolver,
+		cache:       buildCache,
+		scheduler:   buildScheduler,
+		clock:       clock.RealClock{},
+	}
+}
+
+func (jbm *jobManager) GarbageCollect(ctx context.Context, modName, namespace string, owner metav1.Object) ([]string, error) {
+	jobs, err := jbm.jobHelper.GetModuleJobs(ctx, modName, namespace, utils.JobTypeBuild, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build jobs for module %s: %v", modName, err)
+	}
+
+	deleteNames := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Status.Succeeded == 1 {
+			err = jbm.jobHelper.DeleteJob(ctx, &job)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete build job %s: %v", job.Name, err)
+			}
+			deleteNames = append(deleteNames, job.Name)
+		}
+	}
+	return deleteNames, nil
+}
+
+func (jbm *jobManager) ShouldSync(ctx context.Context, mld *api.ModuleLoaderData) (bool, error) {
+	// if there is no build specified skip
+	if !module.ShouldBeBuilt(mld) {
+		return false, nil
+	}
+
+	// if build AND sign are specified, then we will build an intermediate image
+	// and let sign produce the one specified in the container image
+	targetImage := module.BuiltImage(mld)
+
+	// build is specified and targetImage is either the final image or the intermediate image
+	// tag, depending on whether sign is specified or not. Either way, if targetImage exists
+	// we can skip building it
+	exists, err := module.ImageExists(ctx, jbm.authFactory, jbm.registry, mld, targetImage)
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence of image %s: %w", targetImage, err)
+	}
+
+	// images built from a Git repository are rebuilt when the ref points to a new commit
+	if exists {
+		if exists, err = git.IsImageUpToDate(ctx, jbm.gitResolver, jbm.authFactory, jbm.registry, mld, targetImage); err != nil {
+			return false, fmt.Errorf("could not check if image %s is up to date: %v", targetImage, err)
+		}
+	}
+
+	// Sync is not called anymore once the image exists, so the completed build is released here
+	if exists {
+		jbm.scheduler.Release(scheduler.WorkloadFor(mld, utils.JobTypeBuild))
+	}
+
+	return !exists, nil
+}
+
+func (jbm *jobManager) Sync(
+	ctx context.Context,
+	mld *api.ModuleLoaderData,
+	pushImage bool,
+	owner metav1.Object) (utils.Status, error) {
+
+	logger := log.FromContext(ctx)
+
+	logger.Info("Building in-cluster")
+
+	labels := jbm.jobHelper.JobLabels(mld.Name, mld.KernelVersion, mld.Arch, utils.JobTypeBuild)
+
+	workload := scheduler.WorkloadFor(mld, utils.JobTypeBuild)
+
+	jobTemplate, err := jbm.maker.MakeJobTemplate(ctx, mld, labels, pushImage, owner)
+	if err != nil {
+		return "", fmt.Errorf("could not make Job template: %v", err)
+	}
+
+	// another build with the same inputs may already have produced the image
+	if key := jobTemplate.GetAnnotations()[cache.KeyAnnotation]; key != "" {
+		reused, err := jbm.cache.Reuse(ctx, mld, key)
+		if err != nil {
+			return "", fmt.Errorf("could not reuse the cached build %s: %v", key, err)
+		}
+
+		if reused {
+			logger.Info("Reused the result of an identical build", "key", key)
+			jbm.scheduler.Release(workload)
+			return utils.StatusCompleted, nil
+		}
+	}
+
+	job, err := jbm.jobHelper.GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, mld.KernelVersion, mld.Arch, utils.JobTypeBuild, owner)
+	if err != nil {
+		if !errors.Is(err, utils.ErrNoMatchingJob) {
+			return "", fmt.Errorf("error getting the build job: %v", err)
+		}
+
+		position, err := jbm.scheduler.Admit(ctx, workload, mld.BuildPriority)
+		if err != nil {
+			return "", fmt.Errorf("could not admit the build: %v", err)
+		}
+
+		if position > 0 {
+			logger.Info("The maximum number of concurrent builds is reached; queueing the build", "position", position)
+			return utils.StatusQueued, nil
+		}
+
+		logger.Info("Creating job")
+		err = jbm.jobHelper.CreateJob(ctx, jobTemplate)
+		if err != nil {
+			jbm.scheduler.Release(workload)
+			return "", fmt.Errorf("could not create Build Job: %v", err)
+		}
+
+		return utils.StatusCreated, nil
+	}
+
+	changed, err := jbm.jobHelper.IsJobChanged(job, jobTemplate)
+	if err != nil {
+		return "", fmt.Errorf("could not determine if job has changed: %v", err)
+	}
+
+	if changed {
+		logger.Info("The module's build spec has been changed, deleting the current job so a new one can be created", "name", job.Name)
+		err = jbm.jobHelper.DeleteJob(ctx, job)
+		if err != nil {
+			logger.Info(utils.WarnString(fmt.Sprintf("failed to delete build job %s: %v", job.Name, err)))
+		}
+		return utils.StatusInProgress, nil
+	}
+
+	logger.Info("Returning job status", "name", job.Name, "namespace", job.Namespace)
+
+	statusmsg, err := jbm.jobHelper.GetJobStatus(job)
+	if err != nil {
+		return "", err
+	}
+
+	if statusmsg == utils.StatusFailed {
+		statusmsg, err = retry.RetryJob(ctx, jbm.jobHelper, mld.Build.RetryPolicy, job, jobTemplate, jbm.clock.Now())
+	}
+
+	jbm.scheduler.Observe(workload, statusmsg)
+
+	return statusmsg, err
+}
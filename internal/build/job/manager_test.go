@@ -0,0 +1,555 @@
+package buildjob
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/mock/gomock"
+	containerregistryv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclock "k8s.io/utils/clock/testing"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/cache"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/retry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+var _ = Describe("ShouldSync", func() {
+	var (
+		ctrl        *gomock.Controller
+		authFactory *auth.MockRegistryAuthGetterFactory
+		reg         *registry.MockRegistry
+		mgr         *jobManager
+	)
+
+	const (
+		moduleName = "module-name"
+		imageName  = "image-name"
+		namespace  = "some-namespace"
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		authFactory = auth.NewMockRegistryAuthGetterFactory(ctrl)
+		reg = registry.NewMockRegistry(ctrl)
+		mgr = NewBuildManager(nil, nil, authFactory, reg, nil, nil, scheduler.New(scheduler.Limits{}, nil))
+	})
+
+	It("should return false if there was no build section", func() {
+		shouldSync, err := mgr.ShouldSync(context.Background(), &api.ModuleLoaderData{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shouldSync).To(BeFalse())
+	})
+
+	DescribeTable("should check the existence of the right image",
+		func(sign *kmmv1beta1.Sign, exists bool) {
+			ctx := context.Background()
+
+			mld := &api.ModuleLoaderData{
+				Name:            moduleName,
+				Namespace:       namespace,
+				ImageRepoSecret: &v1.LocalObjectReference{Name: "pull-push-secret"},
+				ContainerImage:  imageName,
+				Build:           &kmmv1beta1.Build{},
+				Sign:            sign,
+			}
+
+			expectedImage := imageName
+			if sign != nil {
+				expectedImage = module.IntermediateImageName(moduleName, namespace, imageName)
+			}
+
+			gomock.InOrder(
+				authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
+				reg.EXPECT().ImageExists(ctx, expectedImage, "", nil, gomock.Any()).Return(exists, nil),
+			)
+
+			shouldSync, err := mgr.ShouldSync(ctx, mld)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shouldSync).To(Equal(!exists))
+		},
+		Entry("final image exists", nil, true),
+		Entry("final image does not exist", nil, false),
+		Entry("intermediate image exists", &kmmv1beta1.Sign{}, true),
+		Entry("intermediate image does not exist", &kmmv1beta1.Sign{}, false),
+	)
+
+	It("should release the build from the scheduler once the image exists", func() {
+		ctx := context.Background()
+
+		mockScheduler := scheduler.NewMockScheduler(ctrl)
+		mgr.scheduler = mockScheduler
+
+		mld := &api.ModuleLoaderData{
+			Name:           moduleName,
+			Namespace:      namespace,
+			ContainerImage: imageName,
+			Build:          &kmmv1beta1.Build{},
+		}
+
+		gomock.InOrder(
+			authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
+			reg.EXPECT().ImageExists(ctx, imageName, "", nil, gomock.Any()).Return(true, nil),
+			mockScheduler.EXPECT().Release(scheduler.WorkloadFor(mld, utils.JobTypeBuild)),
+		)
+
+		shouldSync, err := mgr.ShouldSync(ctx, mld)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shouldSync).To(BeFalse())
+	})
+
+	It("should rebuild an image built from a previous commit of the Git repository", func() {
+		ctx := context.Background()
+
+		gitResolver := git.NewMockResolver(ctrl)
+		mgr.gitResolver = gitResolver
+
+		gitSource := kmmv1beta1.GitBuildSource{URI: "https://git.example.com/org/repo.git", Ref: "main"}
+
+		mld := &api.ModuleLoaderData{
+			Name:           moduleName,
+			Namespace:      namespace,
+			ContainerImage: imageName,
+			Build:          &kmmv1beta1.Build{Git: &gitSource},
+		}
+
+		img, err := mutate.Config(empty.Image, containerregistryv1.Config{
+			Labels: map[string]string{git.CommitLabel: "0123456789abcdef0123456789abcdef01234567"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		gomock.InOrder(
+			authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
+			reg.EXPECT().ImageExists(ctx, imageName, "", nil, gomock.Any()).Return(true, nil),
+			gitResolver.EXPECT().Resolve(ctx, &gitSource, namespace).Return(
+				&git.Revision{Ref: "refs/heads/main", Commit: "89abcdef0123456789abcdef0123456789abcdef"},
+				nil,
+			),
+			authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
+			reg.EXPECT().GetImage(ctx, imageName, nil, gomock.Any()).Return(img, nil),
+		)
+
+		shouldSync, err := mgr.ShouldSync(ctx, mld)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shouldSync).To(BeTrue())
+	})
+
+	It("should return an error if the image check fails", func() {
+		ctx := context.Background()
+
+		mld := &api.ModuleLoaderData{
+			Name:           moduleName,
+			Namespace:      namespace,
+			ContainerImage: imageName,
+			Build:          &kmmv1beta1.Build{},
+		}
+
+		gomock.InOrder(
+			authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
+			reg.EXPECT().ImageExists(ctx, imageName, "", nil, gomock.Any()).Return(false, errors.New("generic-registry-error")),
+		)
+
+		shouldSync, err := mgr.ShouldSync(ctx, mld)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("generic-registry-error"))
+		Expect(shouldSync).To(BeFalse())
+	})
+})
+
+var _ = Describe("Sync", func() {
+	var (
+		ctrl      *gomock.Controller
+		maker     *MockMaker
+		jobhelper *utils.MockJobHelper
+		mockCache *cache.MockCache
+		mgr       *jobManager
+	)
+
+	const (
+		imageName     = "image-name"
+		namespace     = "some-namespace"
+		moduleName    = "module-name"
+		kernelVersion = "1.2.3"
+		jobName       = "some-job"
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		maker = NewMockMaker(ctrl)
+		jobhelper = utils.NewMockJobHelper(ctrl)
+		mockCache = cache.NewMockCache(ctrl)
+		mgr = NewBuildManager(maker, jobhelper, nil, nil, nil, mockCache, scheduler.New(scheduler.Limits{}, nil))
+	})
+
+	labels := map[string]string{"kmm.node.kubernetes.io/job-type": "build",
+		"kmm.node.kubernetes.io/module.name":   moduleName,
+		"kmm.node.kubernetes.io/target-kernel": kernelVersion,
+	}
+
+	mld := &api.ModuleLoaderData{
+		Name:           moduleName,
+		Namespace:      namespace,
+		ContainerImage: imageName,
+		Build:          &kmmv1beta1.Build{},
+		KernelVersion:  kernelVersion,
+	}
+
+	Context("with a cached build", func() {
+		j := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{cache.KeyAnnotation: "some-key"},
+			},
+		}
+
+		It("should reuse the cached image instead of building it", func() {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, mld, labels, true, mld.Owner).Return(&j, nil),
+				mockCache.EXPECT().Reuse(ctx, mld, "some-key").Return(true, nil),
+			)
+
+			Expect(mgr.Sync(ctx, mld, true, mld.Owner)).To(Equal(utils.Status(utils.StatusCompleted)))
+		})
+
+		It("should build the image if it is not cached", func() {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, mld, labels, true, mld.Owner).Return(&j, nil),
+				mockCache.EXPECT().Reuse(ctx, mld, "some-key").Return(false, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeBuild, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+				jobhelper.EXPECT().CreateJob(ctx, &j),
+			)
+
+			Expect(mgr.Sync(ctx, mld, true, mld.Owner)).To(Equal(utils.Status(utils.StatusCreated)))
+		})
+
+		It("should return an error if the cache cannot be used", func() {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, mld, labels, true, mld.Owner).Return(&j, nil),
+				mockCache.EXPECT().Reuse(ctx, mld, "some-key").Return(false, errors.New("random error")),
+			)
+
+			_, err := mgr.Sync(ctx, mld, true, mld.Owner)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("with a build scheduler", func() {
+		var mockScheduler *scheduler.MockScheduler
+
+		j := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{constants.JobHashAnnotation: "some hash"},
+			},
+		}
+
+		BeforeEach(func() {
+			mockScheduler = scheduler.NewMockScheduler(ctrl)
+			mgr.scheduler = mockScheduler
+		})
+
+		prioritizedMLD := *mld
+		prioritizedMLD.BuildPriority = 10
+
+		workload := scheduler.WorkloadFor(&prioritizedMLD, utils.JobTypeBuild)
+
+		It("should queue the build if it is not admitted", func() {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, &prioritizedMLD, labels, true, mld.Owner).Return(&j, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeBuild, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+				mockScheduler.EXPECT().Admit(ctx, workload, int32(10)).Return(2, nil),
+			)
+
+			Expect(mgr.Sync(ctx, &prioritizedMLD, true, mld.Owner)).To(Equal(utils.Status(utils.StatusQueued)))
+		})
+
+		It("should create the job once it is admitted", func() {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, &prioritizedMLD, labels, true, mld.Owner).Return(&j, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeBuild, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+				mockScheduler.EXPECT().Admit(ctx, workload, int32(10)).Return(0, nil),
+				jobhelper.EXPECT().CreateJob(ctx, &j),
+			)
+
+			Expect(mgr.Sync(ctx, &prioritizedMLD, true, mld.Owner)).To(Equal(utils.Status(utils.StatusCreated)))
+		})
+
+		It("should release the slot if the job cannot be created", func() {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, &prioritizedMLD, labels, true, mld.Owner).Return(&j, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeBuild, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+				mockScheduler.EXPECT().Admit(ctx, workload, int32(10)).Return(0, nil),
+				jobhelper.EXPECT().CreateJob(ctx, &j).Return(errors.New("some error")),
+				mockScheduler.EXPECT().Release(workload),
+			)
+
+			_, err := mgr.Sync(ctx, &prioritizedMLD, true, mld.Owner)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should report the status of the existing job to the scheduler", func() {
+			ctx := context.Background()
+
+			existing := j
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, &prioritizedMLD, labels, true, mld.Owner).Return(&j, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeBuild, mld.Owner).Return(&existing, nil),
+				jobhelper.EXPECT().IsJobChanged(&existing, &j).Return(false, nil),
+				jobhelper.EXPECT().GetJobStatus(&existing).Return(utils.Status(utils.StatusCompleted), nil),
+				mockScheduler.EXPECT().Observe(workload, utils.Status(utils.StatusCompleted)),
+			)
+
+			Expect(mgr.Sync(ctx, &prioritizedMLD, true, mld.Owner)).To(Equal(utils.Status(utils.StatusCompleted)))
+		})
+	})
+
+	DescribeTable("should return the correct status depending on the job status",
+		func(s batchv1.JobStatus, expectedStatus utils.Status, expectsErr bool) {
+			j := batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{"label key": "some label"},
+					Namespace:   namespace,
+					Annotations: map[string]string{constants.JobHashAnnotation: "some hash"},
+				},
+				Status: s,
+			}
+			newJob := batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{"label key": "some label"},
+					Namespace:   namespace,
+					Annotations: map[string]string{constants.JobHashAnnotation: "some hash"},
+				},
+				Status: s,
+			}
+			ctx := context.Background()
+
+			var joberr error
+			if expectsErr {
+				joberr = errors.New("some error")
+			}
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, mld, labels, true, mld.Owner).Return(&j, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeBuild, mld.Owner).Return(&newJob, nil),
+				jobhelper.EXPECT().IsJobChanged(&j, &newJob).Return(false, nil),
+				jobhelper.EXPECT().GetJobStatus(&newJob).Return(expectedStatus, joberr),
+			)
+
+			res, err := mgr.Sync(ctx, mld, true, mld.Owner)
+
+			if expectsErr {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(expectedStatus))
+		},
+		Entry("active", batchv1.JobStatus{Active: 1}, utils.Status(utils.StatusInProgress), false),
+		Entry("succeeded", batchv1.JobStatus{Succeeded: 1}, utils.Status(utils.StatusCompleted), false),
+		Entry("failed", batchv1.JobStatus{Failed: 1}, utils.Status(utils.StatusFailed), false),
+		Entry("unknown", batchv1.JobStatus{}, utils.Status(""), true),
+	)
+
+	It("should return an error if there was an error creating the job template", func() {
+		ctx := context.Background()
+
+		gomock.InOrder(
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+			maker.EXPECT().MakeJobTemplate(ctx, mld, labels, true, mld.Owner).Return(nil, errors.New("random error")),
+		)
+
+		_, err := mgr.Sync(ctx, mld, true, mld.Owner)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should create the job if there was no job with the same name", func() {
+		j := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      jobName,
+				Namespace: namespace,
+			},
+		}
+
+		ctx := context.Background()
+
+		gomock.InOrder(
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+			maker.EXPECT().MakeJobTemplate(ctx, mld, labels, true, mld.Owner).Return(&j, nil),
+			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeBuild, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+			jobhelper.EXPECT().CreateJob(ctx, &j),
+		)
+
+		status, err := mgr.Sync(ctx, mld, true, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(utils.Status(utils.StatusCreated)))
+	})
+
+	It("should delete the job if it was edited", func() {
+		j := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        jobName,
+				Namespace:   namespace,
+				Annotations: map[string]string{constants.JobHashAnnotation: "some hash"},
+			},
+		}
+
+		newJob := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        jobName,
+				Namespace:   namespace,
+				Annotations: map[string]string{constants.JobHashAnnotation: "new hash"},
+			},
+		}
+
+		ctx := context.Background()
+
+		gomock.InOrder(
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+			maker.EXPECT().MakeJobTemplate(ctx, mld, labels, true, mld.Owner).Return(&newJob, nil),
+			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeBuild, mld.Owner).Return(&j, nil),
+			jobhelper.EXPECT().IsJobChanged(&j, &newJob).Return(true, nil),
+			jobhelper.EXPECT().DeleteJob(ctx, &j).Return(nil),
+		)
+
+		status, err := mgr.Sync(ctx, mld, true, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(utils.Status(utils.StatusInProgress)))
+	})
+
+	It("should replace the failed job with a new attempt once its backoff has elapsed", func() {
+		failedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		mgr.clock = testclock.NewFakePassiveClock(failedAt.Add(retry.DefaultInitialBackoff))
+
+		retriedMLD := *mld
+		retriedMLD.Build = &kmmv1beta1.Build{RetryPolicy: &kmmv1beta1.RetryPolicy{MaxAttempts: 2}}
+
+		j := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              jobName,
+				Namespace:         namespace,
+				Annotations:       map[string]string{constants.JobHashAnnotation: "some hash"},
+				CreationTimestamp: metav1.NewTime(failedAt),
+			},
+			Status: batchv1.JobStatus{Failed: 1},
+		}
+
+		newJob := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Annotations: map[string]string{constants.JobHashAnnotation: "some hash"},
+			},
+		}
+
+		ctx := context.Background()
+
+		gomock.InOrder(
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, utils.JobTypeBuild).Return(labels),
+			maker.EXPECT().MakeJobTemplate(ctx, &retriedMLD, labels, true, mld.Owner).Return(&newJob, nil),
+			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeBuild, mld.Owner).Return(&j, nil),
+			jobhelper.EXPECT().IsJobChanged(&j, &newJob).Return(false, nil),
+			jobhelper.EXPECT().GetJobStatus(&j).Return(utils.Status(utils.StatusFailed), nil),
+			jobhelper.EXPECT().CreateJob(ctx, &newJob),
+			jobhelper.EXPECT().DeleteJob(ctx, &j),
+		)
+
+		status, err := mgr.Sync(ctx, &retriedMLD, true, mld.Owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(utils.Status(utils.StatusCreated)))
+		Expect(newJob.Annotations).To(HaveKeyWithValue(retry.AttemptAnnotation, "2"))
+	})
+})
+
+var _ = Describe("GarbageCollect", func() {
+	var (
+		ctrl      *gomock.Controller
+		jobhelper *utils.MockJobHelper
+		mgr       *jobManager
+	)
+
+	const (
+		moduleName = "module-name"
+		namespace  = "some-namespace"
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		jobhelper = utils.NewMockJobHelper(ctrl)
+		mgr = NewBuildManager(nil, jobhelper, nil, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil))
+	})
+
+	It("should only delete successful jobs", func() {
+		ctx := context.Background()
+		owner := &kmmv1beta1.Module{}
+
+		succeededJob := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "succeeded"},
+			Status:     batchv1.JobStatus{Succeeded: 1},
+		}
+		failedJob := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "failed"},
+			Status:     batchv1.JobStatus{Failed: 1},
+		}
+
+		gomock.InOrder(
+			jobhelper.EXPECT().GetModuleJobs(ctx, moduleName, namespace, utils.JobTypeBuild, owner).Return([]batchv1.Job{succeededJob, failedJob}, nil),
+			jobhelper.EXPECT().DeleteJob(ctx, &succeededJob).Return(nil),
+		)
+
+		deleted, err := mgr.GarbageCollect(ctx, moduleName, namespace, owner)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal([]string{"succeeded"}))
+	})
+
+	It("should return an error if the jobs could not be listed", func() {
+		ctx := context.Background()
+
+		jobhelper.EXPECT().GetModuleJobs(ctx, moduleName, namespace, utils.JobTypeBuild, nil).Return(nil, errors.New("random error"))
+
+		_, err := mgr.GarbageCollect(ctx, moduleName, namespace, nil)
+
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -169,7 +169,7 @@ func (c *clusterAPI) kernelMappingsByKernelVersion(
 			continue
 		}
 
-		mld, err := c.kernelAPI.GetModuleLoaderDataForKernel(mod, kernelVersion)
+		mld, err := c.kernelAPI.GetModuleLoaderDataForKernel(mod, kernelVersion, "")
 		if err != nil {
 			kernelVersionLogger.Info("no suitable container image found; skipping kernel version")
 			continue
@@ -222,7 +222,7 @@ var _ = Describe("ClusterAPI", func() {
 
 		It("should do nothing when no kernel mappings are found", func() {
 			gomock.InOrder(
-				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion).Return(nil, errors.New("generic-error")),
+				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion, "").Return(nil, errors.New("generic-error")),
 			)
 
 			c := NewClusterAPI(clnt, mockKM, mockBM, mockSM, namespace)
@@ -253,7 +253,7 @@ var _ = Describe("ClusterAPI", func() {
 
 		It("should do nothing when Build and Sign are not needed", func() {
 			gomock.InOrder(
-				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion).Return(&mld, nil),
+				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion, "").Return(&mld, nil),
 				mockBM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(false, nil),
 				mockSM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(false, nil),
 			)
@@ -267,7 +267,7 @@ var _ = Describe("ClusterAPI", func() {
 
 		It("should run build sync if needed", func() {
 			gomock.InOrder(
-				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion).Return(&mld, nil),
+				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion, "").Return(&mld, nil),
 				mockBM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(true, nil),
 				mockBM.EXPECT().Sync(gomock.Any(), &mld, true, mcm).Return(utils.Status(utils.StatusCompleted), nil),
 				mockSM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(false, nil),
@@ -282,7 +282,7 @@ var _ = Describe("ClusterAPI", func() {
 
 		It("should return an error when build sync errors", func() {
 			gomock.InOrder(
-				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion).Return(&mld, nil),
+				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion, "").Return(&mld, nil),
 				mockBM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(true, nil),
 				mockBM.EXPECT().Sync(gomock.Any(), &mld, true, mcm).Return(utils.Status(""), errors.New("test-error")),
 			)
@@ -296,7 +296,7 @@ var _ = Describe("ClusterAPI", func() {
 
 		It("should run sign sync if needed", func() {
 			gomock.InOrder(
-				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion).Return(&mld, nil),
+				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion, "").Return(&mld, nil),
 				mockBM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(false, nil),
 				mockSM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(true, nil),
 				mockSM.EXPECT().Sync(gomock.Any(), &mld, "", true, mcm).Return(utils.Status(utils.StatusInProgress), nil),
@@ -311,7 +311,7 @@ var _ = Describe("ClusterAPI", func() {
 
 		It("should return an error when sign sync errors", func() {
 			gomock.InOrder(
-				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion).Return(&mld, nil),
+				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion, "").Return(&mld, nil),
 				mockBM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(false, nil),
 				mockSM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(true, nil),
 				mockSM.EXPECT().Sync(gomock.Any(), &mld, "", true, mcm).Return(utils.Status(""), errors.New("test-error")),
@@ -326,7 +326,7 @@ var _ = Describe("ClusterAPI", func() {
 
 		It("should not run sign sync when build sync does not complete", func() {
 			gomock.InOrder(
-				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion).Return(&mld, nil),
+				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion, "").Return(&mld, nil),
 				mockBM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(true, nil),
 				mockBM.EXPECT().Sync(gomock.Any(), &mld, true, mcm).Return(utils.Status(utils.StatusInProgress), nil),
 			)
@@ -340,7 +340,7 @@ var _ = Describe("ClusterAPI", func() {
 
 		It("should run both build sync and sign sync when build is completed", func() {
 			gomock.InOrder(
-				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion).Return(&mld, nil),
+				mockKM.EXPECT().GetModuleLoaderDataForKernel(&mod, kernelVersion, "").Return(&mld, nil),
 				mockBM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(true, nil),
 				mockBM.EXPECT().Sync(gomock.Any(), &mld, true, mcm).Return(utils.Status(utils.StatusCompleted), nil),
 				mockSM.EXPECT().ShouldSync(gomock.Any(), &mld).Return(true, nil),
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"runtime/debug"
+	"strconv"
 
 	"github.com/go-logr/logr"
 )
@@ -39,3 +40,20 @@ func GitCommit() (string, error) {
 
 	return "", fmt.Errorf("%s not found in build info settings", vcsRevisionKey)
 }
+
+// GetBoolEnv parses the value of the s environment variable as a boolean.
+// An empty or unset variable is interpreted as false.
+func GetBoolEnv(s string) (bool, error) {
+	envValue := os.Getenv(s)
+
+	if envValue == "" {
+		return false, nil
+	}
+
+	val, err := strconv.ParseBool(envValue)
+	if err != nil {
+		return false, fmt.Errorf("%q: invalid value for %s", envValue, s)
+	}
+
+	return val, nil
+}
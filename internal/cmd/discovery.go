@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"fmt"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+)
+
+// IsGroupVersionAvailable returns true if the API server serves the given group version.
+func IsGroupVersionAvailable(client discovery.DiscoveryInterface, groupVersion string) (bool, error) {
+	if _, err := client.ServerResourcesForGroupVersion(groupVersion); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("could not get the server resources for %s: %v", groupVersion, err)
+	}
+
+	return true, nil
+}
@@ -5,10 +5,12 @@ const (
 	DTKImageStreamNamespace      = "openshift"
 	ModuleNameLabel              = "kmm.node.kubernetes.io/module.name"
 	NodeLabelerFinalizer         = "kmm.node.kubernetes.io/node-labeler"
+	ModuleFinalizer              = "kmm.node.kubernetes.io/module-finalizer"
 	TargetKernelTarget           = "kmm.node.kubernetes.io/target-kernel"
 	JobType                      = "kmm.node.kubernetes.io/job-type"
 	JobHashAnnotation            = "kmm.node.kubernetes.io/last-hash"
 	KernelLabel                  = "kmm.node.kubernetes.io/kernel-version.full"
+	ArchLabel                    = "kmm.node.kubernetes.io/arch"
 
 	ManagedClusterModuleNameLabel  = "kmm.node.kubernetes.io/managedclustermodule.name"
 	KernelVersionsClusterClaimName = "kernel-versions.kmm.node.kubernetes.io"
@@ -17,4 +19,5 @@ const (
 	PrivateSignDataKey             = "key"
 
 	OperatorNamespaceEnvVar = "OPERATOR_NAMESPACE"
+	EnableWebhooksEnvVar    = "ENABLE_WEBHOOKS"
 )
@@ -2,14 +2,16 @@ package daemonset
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
+	"os"
 
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/worker"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -27,38 +29,65 @@ const (
 	nodeLibModulesVolumeName       = "node-lib-modules"
 	nodeVarLibFirmwarePath         = "/var/lib/firmware"
 	nodeVarLibFirmwareVolumeName   = "node-var-lib-firmware"
+	firmwareClassPathParamPath     = "/sys/module/firmware_class/parameters/path"
+	firmwareClassPathVolumeName    = "node-firmware-class-path"
 	devicePluginKernelVersion      = ""
+	workerBinVolumeName            = "kmm-worker-bin"
+	workerBinDir                   = "/kmm-worker"
+	workerBinPath                  = workerBinDir + "/worker"
+	workerStatePath                = workerBinDir + "/state.json"
+	workerConfigAnnotation         = "kmm.node.kubernetes.io/worker-config"
+	workerConfigVolumeName         = "kmm-worker-config"
+	workerConfigDir                = "/etc/kmm-worker"
+	workerConfigFileName           = "config.json"
+	workerConfigPath               = workerConfigDir + "/" + workerConfigFileName
+	workerImageEnvVar              = "RELATED_IMAGES_WORKER"
+	workerInstallContainerName     = "install-worker"
+	workerJobStateDir              = "/run/kmm-worker"
+	workerJobStateVolumeName       = "kmm-worker-state"
+	modprobeConfigAnnotation       = "kmm.node.kubernetes.io/modprobe-config"
+	modprobeConfigFileName         = "modprobe.conf"
+	modprobeConfigPath             = "/etc/modprobe.d/kmm.conf"
+
+	ModuleLoaderContainerName = "module-loader"
 )
 
 //go:generate mockgen -source=daemonset.go -package=daemonset -destination=mock_daemonset.go
 
 type DaemonSetCreator interface {
-	GarbageCollect(ctx context.Context, existingDS map[string]*appsv1.DaemonSet, validKernels sets.String) ([]string, error)
-	ModuleDaemonSetsByKernelVersion(ctx context.Context, name, namespace string) (map[string]*appsv1.DaemonSet, error)
+	GarbageCollect(ctx context.Context, existingDS map[string]*appsv1.DaemonSet, validKeys sets.String) ([]string, error)
+	ModuleDaemonSetsByKernelAndArch(ctx context.Context, name, namespace string) (map[string]*appsv1.DaemonSet, error)
 	SetDriverContainerAsDesired(ctx context.Context, ds *appsv1.DaemonSet, mld *api.ModuleLoaderData, useDefaultSA bool) error
+	ModuleLoaderJobPodTemplate(ctx context.Context, mld *api.ModuleLoaderData, nodeName, command string, useDefaultSA bool) (*v1.PodTemplateSpec, error)
 	SetDevicePluginAsDesired(ctx context.Context, ds *appsv1.DaemonSet, mod *kmmv1beta1.Module, useDefaultSA bool) error
 	GetNodeLabelFromPod(pod *v1.Pod, moduleName string) string
 }
 
 type daemonSetGenerator struct {
-	client      client.Client
-	kernelLabel string
-	scheme      *runtime.Scheme
+	client            client.Client
+	kernelLabel       string
+	firmwareClassPath string
+	scheme            *runtime.Scheme
 }
 
-func NewCreator(client client.Client, kernelLabel string, scheme *runtime.Scheme) DaemonSetCreator {
+// NewCreator returns a DaemonSetCreator.
+// If firmwareClassPath is not empty, the module loaders of Modules shipping firmware files add it to the firmware
+// search path of the kernel.
+func NewCreator(client client.Client, kernelLabel, firmwareClassPath string, scheme *runtime.Scheme) DaemonSetCreator {
 	return &daemonSetGenerator{
-		client:      client,
-		kernelLabel: kernelLabel,
-		scheme:      scheme,
+		client:            client,
+		kernelLabel:       kernelLabel,
+		firmwareClassPath: firmwareClassPath,
+		scheme:            scheme,
 	}
 }
 
-func (dc *daemonSetGenerator) GarbageCollect(ctx context.Context, existingDS map[string]*appsv1.DaemonSet, validKernels sets.String) ([]string, error) {
+// GarbageCollect deletes the module loader DaemonSets in existingDS whose key is not in validKeys.
+func (dc *daemonSetGenerator) GarbageCollect(ctx context.Context, existingDS map[string]*appsv1.DaemonSet, validKeys sets.String) ([]string, error) {
 	deleted := make([]string, 0)
 
-	for kernelVersion, ds := range existingDS {
-		if !dc.isDevicePluginDaemonSet(ds) && !validKernels.Has(kernelVersion) {
+	for key, ds := range existingDS {
+		if !dc.isDevicePluginDaemonSet(ds) && !validKeys.Has(key) {
 			if err := dc.client.Delete(ctx, ds); err != nil {
 				return nil, fmt.Errorf("could not delete DaemonSet %s: %v", ds.Name, err)
 			}
@@ -70,26 +99,28 @@ func (dc *daemonSetGenerator) GarbageCollect(ctx context.Context, existingDS map
 	return deleted, nil
 }
 
-func (dc *daemonSetGenerator) ModuleDaemonSetsByKernelVersion(ctx context.Context, name, namespace string) (map[string]*appsv1.DaemonSet, error) {
+// ModuleDaemonSetsByKernelAndArch returns the DaemonSets of a module, keyed by KernelArchKey.
+// The device plugin DaemonSet has the key of the device plugin kernel version.
+func (dc *daemonSetGenerator) ModuleDaemonSetsByKernelAndArch(ctx context.Context, name, namespace string) (map[string]*appsv1.DaemonSet, error) {
 	dsList, err := dc.moduleDaemonSets(ctx, name, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("could not get all DaemonSets: %w", err)
 	}
 
-	dsByKernelVersion := make(map[string]*appsv1.DaemonSet, len(dsList))
+	dsByKey := make(map[string]*appsv1.DaemonSet, len(dsList))
 
 	for i := 0; i < len(dsList); i++ {
 		ds := dsList[i]
 
-		kernelVersion := ds.Labels[dc.kernelLabel]
-		if dsByKernelVersion[kernelVersion] != nil {
-			return nil, fmt.Errorf("multiple DaemonSets found for kernel %q", kernelVersion)
+		key := KernelArchKey(ds.Labels[dc.kernelLabel], ds.Labels[constants.ArchLabel])
+		if dsByKey[key] != nil {
+			return nil, fmt.Errorf("multiple DaemonSets found for kernel and architecture %q", key)
 		}
 
-		dsByKernelVersion[kernelVersion] = &ds
+		dsByKey[key] = &ds
 	}
 
-	return dsByKernelVersion, nil
+	return dsByKey, nil
 }
 
 func (dc *daemonSetGenerator) SetDriverContainerAsDesired(
@@ -102,13 +133,102 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(
 		return errors.New("ds cannot be nil")
 	}
 
+	template, standardLabels, err := dc.moduleLoaderPodTemplate(ctx, mld, useDefaultSA)
+	if err != nil {
+		return err
+	}
+
+	ds.SetLabels(
+		OverrideLabels(OverrideLabels(ds.GetLabels(), mld.Labels), standardLabels),
+	)
+
+	selector := &metav1.LabelSelector{MatchLabels: standardLabels}
+
+	// The selector of an existing DaemonSet is immutable; DaemonSets created before they were labeled with the
+	// architecture keep selecting their pods without it.
+	if ds.Spec.Selector != nil {
+		selector = ds.Spec.Selector
+	}
+
+	ds.Spec = appsv1.DaemonSetSpec{
+		Template: *template,
+		Selector: selector,
+	}
+
+	return controllerutil.SetControllerReference(mld.Owner, ds, dc.scheme)
+}
+
+// ModuleLoaderJobPodTemplate returns the template of the pod running command, load or unload, once for mld on
+// nodeName.
+// The pod keeps the state of the worker in a directory of the host that is cleared on reboot, so that the unload
+// pod can undo what the load pod did.
+func (dc *daemonSetGenerator) ModuleLoaderJobPodTemplate(
+	ctx context.Context,
+	mld *api.ModuleLoaderData,
+	nodeName string,
+	command string,
+	useDefaultSA bool,
+) (*v1.PodTemplateSpec, error) {
+	template, _, err := dc.moduleLoaderPodTemplate(ctx, mld, useDefaultSA)
+	if err != nil {
+		return nil, err
+	}
+
+	// The node is labeled by the operator once the Job has completed, not by the PodNodeModuleReconciler.
+	delete(template.Labels, constants.ModuleNameLabel)
+	template.Finalizers = nil
+
+	// The pod is bound to the node; unload pods must run even if the node does not match the selector anymore.
+	template.Spec.NodeName = nodeName
+	template.Spec.NodeSelector = nil
+	template.Spec.Affinity = nil
+	template.Spec.RestartPolicy = v1.RestartPolicyNever
+
+	hostPathDirectoryOrCreate := v1.HostPathDirectoryOrCreate
+
+	template.Spec.Volumes = append(template.Spec.Volumes, v1.Volume{
+		Name: workerJobStateVolumeName,
+		VolumeSource: v1.VolumeSource{
+			HostPath: &v1.HostPathVolumeSource{
+				Path: workerJobStateDir,
+				Type: &hostPathDirectoryOrCreate,
+			},
+		},
+	})
+
+	container := &template.Spec.Containers[0]
+
+	container.Command = []string{
+		workerBinPath,
+		command,
+		"--config",
+		workerConfigPath,
+		"--state-file",
+		fmt.Sprintf("%s/%s.%s.json", workerJobStateDir, mld.Namespace, mld.Name),
+	}
+	container.ReadinessProbe = nil
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      workerJobStateVolumeName,
+		MountPath: workerJobStateDir,
+	})
+
+	return template, nil
+}
+
+// moduleLoaderPodTemplate returns the template of the module loader pods for mld, as well as the labels that
+// identify them.
+func (dc *daemonSetGenerator) moduleLoaderPodTemplate(
+	ctx context.Context,
+	mld *api.ModuleLoaderData,
+	useDefaultSA bool,
+) (*v1.PodTemplateSpec, map[string]string, error) {
 	if mld.ContainerImage == "" {
-		return errors.New("container image cannot be empty")
+		return nil, nil, errors.New("container image cannot be empty")
 	}
 
 	kernelVersion := mld.KernelVersion
 	if kernelVersion == "" {
-		return errors.New("kernelVersion cannot be empty")
+		return nil, nil, errors.New("kernelVersion cannot be empty")
 	}
 
 	standardLabels := map[string]string{
@@ -116,35 +236,100 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(
 		dc.kernelLabel:            kernelVersion,
 	}
 
-	ds.SetLabels(
-		OverrideLabels(ds.GetLabels(), standardLabels),
-	)
+	// DaemonSets for the same kernel on different architectures must not select each other's pods
+	if mld.Arch != "" {
+		standardLabels[constants.ArchLabel] = mld.Arch
+	}
+
+	// user-provided labels cannot override the standard ones, which are used by the selector
+	podLabels := OverrideLabels(CopyMapStringString(mld.Labels), standardLabels)
 
 	nodeSelector := CopyMapStringString(mld.Selector)
 	nodeSelector[dc.kernelLabel] = kernelVersion
 
+	if mld.Arch != "" {
+		nodeSelector[v1.LabelArchStable] = mld.Arch
+	}
+
+	// Only schedule the module loader on nodes where all dependencies are already loaded.
+	for _, dep := range mld.DependsOn {
+		nodeSelector[GetDriverContainerNodeLabel(dep)] = ""
+	}
+
+	workerCfg := worker.Config{
+		Name:      mld.Name,
+		Namespace: mld.Namespace,
+		Modprobe:  mld.Modprobe,
+		// the systemd unit loading the kernel module at boot owns its lifecycle on the node
+		KeepLoaded: mld.BootLoading != nil,
+	}
+
+	if mld.Modprobe.FirmwarePath != "" {
+		workerCfg.FirmwareClassPath = dc.firmwareClassPath
+	}
+
+	workerConfig, err := json.Marshal(workerCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not encode the worker configuration: %v", err)
+	}
+
+	workerConfigItems := []v1.DownwardAPIVolumeFile{
+		{
+			Path: workerConfigFileName,
+			FieldRef: &v1.ObjectFieldSelector{
+				FieldPath: fmt.Sprintf("metadata.annotations['%s']", workerConfigAnnotation),
+			},
+		},
+	}
+
+	generatedAnnotations := map[string]string{workerConfigAnnotation: string(workerConfig)}
+
+	modprobeConfig := worker.ModprobeConfig(mld.Modprobe)
+
+	if modprobeConfig != "" {
+		workerConfigItems = append(workerConfigItems, v1.DownwardAPIVolumeFile{
+			Path: modprobeConfigFileName,
+			FieldRef: &v1.ObjectFieldSelector{
+				FieldPath: fmt.Sprintf("metadata.annotations['%s']", modprobeConfigAnnotation),
+			},
+		})
+
+		generatedAnnotations[modprobeConfigAnnotation] = modprobeConfig
+	}
+
 	nodeLibModulesPath := "/lib/modules/" + kernelVersion
 
 	hostPathDirectory := v1.HostPathDirectory
 	hostPathDirectoryOrCreate := v1.HostPathDirectoryOrCreate
 
+	workerBinVolumeMount := v1.VolumeMount{
+		Name:      workerBinVolumeName,
+		MountPath: workerBinDir,
+	}
+
+	// The worker binary is copied from the worker image into a shared volume, and then run from the ModuleLoader image.
+	installWorkerContainer := v1.Container{
+		Name:                     workerInstallContainerName,
+		Image:                    os.Getenv(workerImageEnvVar),
+		Command:                  []string{"/usr/local/bin/worker", "install", workerBinPath},
+		TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
+		VolumeMounts:             []v1.VolumeMount{workerBinVolumeMount},
+	}
+
 	container := v1.Container{
-		Command:         []string{"sleep", "infinity"},
-		Name:            "module-loader",
+		Command:         []string{workerBinPath, "run", "--config", workerConfigPath, "--state-file", workerStatePath},
+		Name:            ModuleLoaderContainerName,
 		Image:           mld.ContainerImage,
 		ImagePullPolicy: mld.ImagePullPolicy,
-		Lifecycle: &v1.Lifecycle{
-			PostStart: &v1.LifecycleHandler{
+		Resources:       mld.Resources,
+		ReadinessProbe: &v1.Probe{
+			ProbeHandler: v1.ProbeHandler{
 				Exec: &v1.ExecAction{
-					Command: MakeLoadCommand(mld.Modprobe, mld.Name),
-				},
-			},
-			PreStop: &v1.LifecycleHandler{
-				Exec: &v1.ExecAction{
-					Command: MakeUnloadCommand(mld.Modprobe, mld.Name),
+					Command: []string{workerBinPath, "ready", "--config", workerConfigPath, "--state-file", workerStatePath},
 				},
 			},
 		},
+		TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
 		SecurityContext: &v1.SecurityContext{
 			AllowPrivilegeEscalation: pointer.Bool(false),
 			Capabilities: &v1.Capabilities{
@@ -161,6 +346,12 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(
 				ReadOnly:  true,
 				MountPath: nodeLibModulesPath,
 			},
+			workerBinVolumeMount,
+			{
+				Name:      workerConfigVolumeName,
+				ReadOnly:  true,
+				MountPath: workerConfigDir,
+			},
 		},
 	}
 
@@ -174,6 +365,18 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(
 				},
 			},
 		},
+		{
+			Name: workerBinVolumeName,
+			VolumeSource: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{},
+			},
+		},
+		{
+			Name: workerConfigVolumeName,
+			VolumeSource: v1.VolumeSource{
+				DownwardAPI: &v1.DownwardAPIVolumeSource{Items: workerConfigItems},
+			},
+		},
 	}
 
 	if fw := mld.Modprobe.FirmwarePath; fw != "" {
@@ -194,36 +397,74 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(
 		}
 
 		container.VolumeMounts = append(container.VolumeMounts, firmwareVolumeMount)
+
+		if dc.firmwareClassPath != "" {
+			hostPathFile := v1.HostPathFile
+
+			volumes = append(volumes, v1.Volume{
+				Name: firmwareClassPathVolumeName,
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{
+						Path: firmwareClassPathParamPath,
+						Type: &hostPathFile,
+					},
+				},
+			})
+
+			// /sys is read-only in containers; mount the kernel parameter so that the worker can write it.
+			container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+				Name:      firmwareClassPathVolumeName,
+				MountPath: firmwareClassPathParamPath,
+			})
+		}
 	}
 
+	if modprobeConfig != "" {
+		// Mount the file alone, so that the modprobe.d configuration of the image is still used.
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+			Name:      workerConfigVolumeName,
+			ReadOnly:  true,
+			MountPath: modprobeConfigPath,
+			SubPath:   modprobeConfigFileName,
+		})
+	}
+
+	// user-provided volumes and mounts are appended to the default ones
+	volumes = append(volumes, mld.Volumes...)
+	container.VolumeMounts = append(container.VolumeMounts, mld.VolumeMounts...)
+
+	// user-provided annotations cannot override the worker and modprobe configurations
+	podAnnotations := OverrideLabels(CopyMapStringString(mld.Annotations), generatedAnnotations)
+
 	serviceAccountName := mld.ServiceAccountName
 	if serviceAccountName == "" {
 		if useDefaultSA {
 			serviceAccountName = "kmm-operator-module-loader"
 		} else {
-			log.FromContext(ctx).Info(utils.WarnString("No ServiceAccount set for the ModuleLoader DaemonSet"))
+			log.FromContext(ctx).Info(utils.WarnString("No ServiceAccount set for the ModuleLoader pods"))
 		}
 	}
 
-	ds.Spec = appsv1.DaemonSetSpec{
-		Template: v1.PodTemplateSpec{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels:     standardLabels,
-				Finalizers: []string{constants.NodeLabelerFinalizer},
-			},
-			Spec: v1.PodSpec{
-				Containers:         []v1.Container{container},
-				ImagePullSecrets:   GetPodPullSecrets(mld.ImageRepoSecret),
-				NodeSelector:       nodeSelector,
-				PriorityClassName:  "system-node-critical",
-				ServiceAccountName: serviceAccountName,
-				Volumes:            volumes,
-			},
+	template := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: podAnnotations,
+			Labels:      podLabels,
+			Finalizers:  []string{constants.NodeLabelerFinalizer},
+		},
+		Spec: v1.PodSpec{
+			Affinity:           mld.Affinity,
+			InitContainers:     []v1.Container{installWorkerContainer},
+			Containers:         []v1.Container{container},
+			ImagePullSecrets:   GetPodPullSecrets(mld.ImageRepoSecret),
+			NodeSelector:       nodeSelector,
+			PriorityClassName:  "system-node-critical",
+			ServiceAccountName: serviceAccountName,
+			Tolerations:        mld.Tolerations,
+			Volumes:            volumes,
 		},
-		Selector: &metav1.LabelSelector{MatchLabels: standardLabels},
 	}
 
-	return controllerutil.SetControllerReference(mld.Owner, ds, dc.scheme)
+	return &template, standardLabels, nil
 }
 
 func (dc *daemonSetGenerator) SetDevicePluginAsDesired(
@@ -297,9 +538,11 @@ func (dc *daemonSetGenerator) SetDevicePluginAsDesired(
 				},
 				PriorityClassName:  "system-node-critical",
 				ImagePullSecrets:   GetPodPullSecrets(mod.Spec.ImageRepoSecret),
-				NodeSelector:       map[string]string{getDriverContainerNodeLabel(mod.Name): ""},
+				NodeSelector:       map[string]string{GetDriverContainerNodeLabel(mod.Name): ""},
 				ServiceAccountName: serviceAccountName,
-				Volumes:            append([]v1.Volume{devicePluginVolume}, mod.Spec.DevicePlugin.Volumes...),
+				// the device plugin runs on the nodes where the module loader runs
+				Tolerations: mod.Spec.ModuleLoader.Tolerations,
+				Volumes:     append([]v1.Volume{devicePluginVolume}, mod.Spec.DevicePlugin.Volumes...),
 			},
 		},
 	}
@@ -310,9 +553,9 @@ func (dc *daemonSetGenerator) SetDevicePluginAsDesired(
 func (dc *daemonSetGenerator) GetNodeLabelFromPod(pod *v1.Pod, moduleName string) string {
 	kernelVersion := pod.Labels[dc.kernelLabel]
 	if kernelVersion == devicePluginKernelVersion {
-		return getDevicePluginNodeLabel(moduleName)
+		return GetDevicePluginNodeLabel(moduleName)
 	}
-	return getDriverContainerNodeLabel(moduleName)
+	return GetDriverContainerNodeLabel(moduleName)
 }
 
 func (dc *daemonSetGenerator) moduleDaemonSets(ctx context.Context, name, namespace string) ([]appsv1.DaemonSet, error) {
@@ -342,11 +585,35 @@ func CopyMapStringString(m map[string]string) map[string]string {
 	return n
 }
 
-func getDriverContainerNodeLabel(moduleName string) string {
+// IsReservedVolumeName returns true if name is used by one of the volumes that the operator adds to the module loader
+// pods.
+func IsReservedVolumeName(name string) bool {
+	switch name {
+	case nodeLibModulesVolumeName, nodeVarLibFirmwareVolumeName, firmwareClassPathVolumeName, workerBinVolumeName, workerConfigVolumeName,
+		workerJobStateVolumeName:
+		return true
+	default:
+		return false
+	}
+}
+
+// KernelArchKey returns the key identifying the module loader DaemonSet and the ModuleLoaderData of a kernel and
+// an architecture.
+func KernelArchKey(kernelVersion, arch string) string {
+	if arch == "" {
+		return kernelVersion
+	}
+
+	return kernelVersion + "/" + arch
+}
+
+// GetDriverContainerNodeLabel returns the label set on nodes on which the module is loaded.
+func GetDriverContainerNodeLabel(moduleName string) string {
 	return fmt.Sprintf("kmm.node.kubernetes.io/%s.ready", moduleName)
 }
 
-func getDevicePluginNodeLabel(moduleName string) string {
+// GetDevicePluginNodeLabel returns the label set on nodes on which the device plugin of the module is running.
+func GetDevicePluginNodeLabel(moduleName string) string {
 	return fmt.Sprintf("kmm.node.kubernetes.io/%s.device-plugin-ready", moduleName)
 }
 
@@ -377,93 +644,3 @@ func OverrideLabels(labels, overrides map[string]string) map[string]string {
 
 	return labels
 }
-
-func MakeLoadCommand(spec kmmv1beta1.ModprobeSpec, modName string) []string {
-	loadCommandShell := []string{
-		"/bin/sh",
-		"-c",
-	}
-
-	var loadCommand strings.Builder
-
-	if fw := spec.FirmwarePath; fw != "" {
-		fmt.Fprintf(&loadCommand, "cp -r %s/* %s && ", fw, nodeVarLibFirmwarePath)
-	}
-
-	loadCommand.WriteString("modprobe")
-
-	if rawArgs := spec.RawArgs; rawArgs != nil && len(rawArgs.Load) > 0 {
-		for _, arg := range rawArgs.Load {
-			loadCommand.WriteRune(' ')
-			loadCommand.WriteString(arg)
-		}
-		return append(loadCommandShell, loadCommand.String())
-	}
-
-	if args := spec.Args; args != nil && len(args.Load) > 0 {
-		for _, arg := range args.Load {
-			loadCommand.WriteRune(' ')
-			loadCommand.WriteString(arg)
-		}
-	} else {
-		loadCommand.WriteString(" -v")
-	}
-
-	if dirName := spec.DirName; dirName != "" {
-		loadCommand.WriteString(" -d " + dirName)
-	}
-
-	loadCommand.WriteString(" " + spec.ModuleName)
-
-	if params := spec.Parameters; len(params) > 0 {
-		for _, param := range params {
-			loadCommand.WriteRune(' ')
-			loadCommand.WriteString(param)
-		}
-	}
-
-	return append(loadCommandShell, loadCommand.String())
-}
-
-func MakeUnloadCommand(spec kmmv1beta1.ModprobeSpec, modName string) []string {
-	unloadCommandShell := []string{
-		"/bin/sh",
-		"-c",
-	}
-
-	var unloadCommand strings.Builder
-	unloadCommand.WriteString("modprobe")
-
-	fwUnloadCommand := ""
-	if fw := spec.FirmwarePath; fw != "" {
-		fwUnloadCommand = fmt.Sprintf(" && cd %s && find |sort -r |xargs -I{} rm -d %s/{}", fw, nodeVarLibFirmwarePath)
-	}
-
-	if rawArgs := spec.RawArgs; rawArgs != nil && len(rawArgs.Unload) > 0 {
-		for _, arg := range rawArgs.Unload {
-			unloadCommand.WriteRune(' ')
-			unloadCommand.WriteString(arg)
-			unloadCommand.WriteString(fwUnloadCommand)
-		}
-
-		return append(unloadCommandShell, unloadCommand.String())
-	}
-
-	if args := spec.Args; args != nil && len(args.Unload) > 0 {
-		for _, arg := range args.Unload {
-			unloadCommand.WriteRune(' ')
-			unloadCommand.WriteString(arg)
-		}
-	} else {
-		unloadCommand.WriteString(" -rv")
-	}
-
-	if dirName := spec.DirName; dirName != "" {
-		unloadCommand.WriteString(" -d " + dirName)
-	}
-
-	unloadCommand.WriteString(" " + spec.ModuleName)
-	unloadCommand.WriteString(fwUnloadCommand)
-
-	return append(unloadCommandShell, unloadCommand.String())
-}
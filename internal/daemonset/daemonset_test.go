@@ -3,7 +3,6 @@ package daemonset
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
@@ -35,7 +34,7 @@ var (
 )
 
 var _ = Describe("SetDriverContainerAsDesired", func() {
-	dg := NewCreator(nil, kernelLabel, scheme)
+	dg := NewCreator(nil, kernelLabel, "", scheme)
 
 	BeforeEach(func() {
 		ctrl = gomock.NewController(GinkgoT())
@@ -79,7 +78,35 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(ds.Spec.Template.Spec.Containers).To(HaveLen(1))
-		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(1))
+		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(3))
+	})
+
+	It("should keep the selector of an existing DaemonSet", func() {
+		mld := api.ModuleLoaderData{
+			Name:           moduleName,
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some image",
+			KernelVersion:  kernelVersion,
+			Arch:           "amd64",
+		}
+
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				constants.ModuleNameLabel: moduleName,
+				kernelLabel:               kernelVersion,
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			Spec: appsv1.DaemonSetSpec{Selector: selector},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Selector).To(Equal(selector))
+		Expect(ds.Labels).To(HaveKeyWithValue(constants.ArchLabel, "amd64"))
+		Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(constants.ArchLabel, "amd64"))
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue(v1.LabelArchStable, "amd64"))
 	})
 
 	It("should add the volume and volume mount for firmware if FirmwarePath is set", func() {
@@ -113,10 +140,283 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 
 		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
 		Expect(err).NotTo(HaveOccurred())
-		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(2))
-		Expect(ds.Spec.Template.Spec.Volumes[1]).To(Equal(vol))
-		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts).To(HaveLen(2))
-		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts[1]).To(Equal(volm))
+		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(4))
+		Expect(ds.Spec.Template.Spec.Volumes[3]).To(Equal(vol))
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts).To(HaveLen(4))
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts[3]).To(Equal(volm))
+	})
+
+	It("should set the firmware class path if FirmwarePath is set and the operator is configured to", func() {
+		hostPathFile := v1.HostPathFile
+		vol := v1.Volume{
+			Name: "node-firmware-class-path",
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{
+					Path: "/sys/module/firmware_class/parameters/path",
+					Type: &hostPathFile,
+				},
+			},
+		}
+
+		volm := v1.VolumeMount{
+			Name:      "node-firmware-class-path",
+			MountPath: "/sys/module/firmware_class/parameters/path",
+		}
+
+		mld := api.ModuleLoaderData{
+			Name:      moduleName,
+			Namespace: namespace,
+			Modprobe: kmmv1beta1.ModprobeSpec{
+				ModuleName:   "some-kmod",
+				FirmwarePath: "/opt/lib/firmware/example",
+			},
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some image",
+			KernelVersion:  kernelVersion,
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := NewCreator(nil, kernelLabel, "/var/lib/firmware", scheme).
+			SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(5))
+		Expect(ds.Spec.Template.Spec.Volumes[4]).To(Equal(vol))
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts).To(HaveLen(5))
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts[4]).To(Equal(volm))
+		Expect(ds.Spec.Template.Annotations).To(HaveKeyWithValue(
+			workerConfigAnnotation,
+			`{"name":"module-name","namespace":"namespace","modprobe":{"moduleName":"some-kmod",`+
+				`"firmwarePath":"/opt/lib/firmware/example"},"firmwareClassPath":"/var/lib/firmware"}`,
+		))
+	})
+
+	It("should add the pod customisations without overriding the defaults", func() {
+		tolerations := []v1.Toleration{{Key: "gpu", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}}
+		affinity := &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{
+							MatchExpressions: []v1.NodeSelectorRequirement{
+								{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}},
+							},
+						},
+					},
+				},
+			},
+		}
+		resources := v1.ResourceRequirements{
+			Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Mi")},
+		}
+		vol := v1.Volume{
+			Name:         "some-volume",
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		}
+		volm := v1.VolumeMount{Name: "some-volume", MountPath: "/some/path"}
+
+		mld := api.ModuleLoaderData{
+			Name:     moduleName,
+			Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+			Annotations: map[string]string{
+				"some-annotation":      "some-value",
+				workerConfigAnnotation: "overridden",
+			},
+			Tolerations:    tolerations,
+			Affinity:       affinity,
+			Resources:      resources,
+			Volumes:        []v1.Volume{vol},
+			VolumeMounts:   []v1.VolumeMount{volm},
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some image",
+			KernelVersion:  kernelVersion,
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		podSpec := ds.Spec.Template.Spec
+		Expect(podSpec.Tolerations).To(Equal(tolerations))
+		Expect(podSpec.Affinity).To(Equal(affinity))
+		Expect(podSpec.Volumes).To(HaveLen(4))
+		Expect(podSpec.Volumes[3]).To(Equal(vol))
+		Expect(podSpec.Containers[0].Resources).To(Equal(resources))
+		Expect(podSpec.Containers[0].VolumeMounts).To(HaveLen(4))
+		Expect(podSpec.Containers[0].VolumeMounts[3]).To(Equal(volm))
+		Expect(ds.Spec.Template.Annotations).To(Equal(map[string]string{
+			"some-annotation":      "some-value",
+			workerConfigAnnotation: `{"name":"module-name","namespace":"","modprobe":{"moduleName":"some-kmod"}}`,
+		}))
+	})
+
+	It("should mount the generated modprobe configuration", func() {
+		mld := api.ModuleLoaderData{
+			Name: moduleName,
+			Modprobe: kmmv1beta1.ModprobeSpec{
+				ModuleName:          "some-kmod",
+				ModulesLoadingOrder: []string{"some-kmod", "some-dep"},
+				Blacklist:           []string{"in-tree-kmod"},
+			},
+			Annotations:    map[string]string{modprobeConfigAnnotation: "overridden"},
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some image",
+			KernelVersion:  kernelVersion,
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		podSpec := ds.Spec.Template.Spec
+		Expect(podSpec.Volumes[2].DownwardAPI.Items).To(ContainElement(v1.DownwardAPIVolumeFile{
+			Path: "modprobe.conf",
+			FieldRef: &v1.ObjectFieldSelector{
+				FieldPath: "metadata.annotations['kmm.node.kubernetes.io/modprobe-config']",
+			},
+		}))
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(v1.VolumeMount{
+			Name:      workerConfigVolumeName,
+			ReadOnly:  true,
+			MountPath: "/etc/modprobe.d/kmm.conf",
+			SubPath:   "modprobe.conf",
+		}))
+		Expect(ds.Spec.Template.Annotations).To(HaveKeyWithValue(
+			modprobeConfigAnnotation,
+			"# Generated by KMM\nsoftdep some-kmod pre: some-dep\nblacklist in-tree-kmod\n",
+		))
+	})
+
+	It("should only schedule the module loader on nodes where the dependencies are ready", func() {
+		mld := api.ModuleLoaderData{
+			Name:           moduleName,
+			Selector:       map[string]string{"has-feature-x": "true"},
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some image",
+			KernelVersion:  kernelVersion,
+			DependsOn:      []string{"core", "other"},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{
+			"has-feature-x":                      "true",
+			kernelLabel:                          kernelVersion,
+			"kmm.node.kubernetes.io/core.ready":  "",
+			"kmm.node.kubernetes.io/other.ready": "",
+		}))
+	})
+
+	It("should add the additional labels without overriding the standard ones", func() {
+		mld := api.ModuleLoaderData{
+			Name:           moduleName,
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some image",
+			KernelVersion:  kernelVersion,
+			Labels: map[string]string{
+				"some-label":              "some-value",
+				constants.ModuleNameLabel: "other-name",
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		expectedLabels := map[string]string{
+			"some-label":              "some-value",
+			constants.ModuleNameLabel: moduleName,
+			kernelLabel:               kernelVersion,
+		}
+
+		Expect(ds.Labels).To(Equal(expectedLabels))
+		Expect(ds.Spec.Template.Labels).To(Equal(expectedLabels))
+		Expect(ds.Spec.Selector.MatchLabels).To(Equal(map[string]string{
+			constants.ModuleNameLabel: moduleName,
+			kernelLabel:               kernelVersion,
+		}))
+	})
+
+	It("should only schedule the module loader on nodes of its architecture", func() {
+		mld := api.ModuleLoaderData{
+			Name:           moduleName,
+			Selector:       map[string]string{"has-feature-x": "true"},
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some image",
+			KernelVersion:  kernelVersion,
+			Arch:           "arm64",
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{
+			"has-feature-x":      "true",
+			kernelLabel:          kernelVersion,
+			"kubernetes.io/arch": "arm64",
+		}))
+
+		expectedLabels := map[string]string{
+			constants.ModuleNameLabel: moduleName,
+			kernelLabel:               kernelVersion,
+			constants.ArchLabel:       "arm64",
+		}
+
+		Expect(ds.Labels).To(Equal(expectedLabels))
+		Expect(ds.Spec.Selector.MatchLabels).To(Equal(expectedLabels))
+	})
+
+	It("should not change the pod template when other modules start depending on the module", func() {
+		mld := api.ModuleLoaderData{
+			Name:           moduleName,
+			Modprobe:       kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some image",
+			KernelVersion:  kernelVersion,
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		mld.Dependents = []string{"dependent"}
+
+		dsWithDependents := appsv1.DaemonSet{}
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), &dsWithDependents, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dsWithDependents.Spec.Template).To(Equal(ds.Spec.Template))
+		Expect(ds.Spec.Template.Annotations).To(HaveKeyWithValue(
+			workerConfigAnnotation,
+			`{"name":"module-name","namespace":"","modprobe":{"moduleName":"some-kmod"}}`,
+		))
+	})
+
+	It("should keep the kernel module loaded if it is also loaded at boot", func() {
+		mld := api.ModuleLoaderData{
+			Name:           moduleName,
+			Modprobe:       kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some image",
+			KernelVersion:  kernelVersion,
+			BootLoading:    &kmmv1beta1.BootLoadingSpec{MachineConfigPool: "worker"},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Annotations).To(HaveKeyWithValue(
+			workerConfigAnnotation,
+			`{"name":"module-name","namespace":"","modprobe":{"moduleName":"some-kmod"},"keepLoaded":true}`,
+		))
 	})
 
 	DescribeTable("should add the default ServiceAccount to the module loader",
@@ -148,7 +448,10 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 			dsName              = "ds-name"
 			imageRepoSecretName = "image-repo-secret"
 			serviceAccountName  = "driver-service-account"
+			workerImage         = "worker-image"
 		)
+
+		GinkgoT().Setenv("RELATED_IMAGES_WORKER", workerImage)
 		fullModulesPath := "/lib/modules/" + kernelVersion
 		mod := kmmv1beta1.Module{
 			TypeMeta: metav1.TypeMeta{
@@ -209,33 +512,59 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
 				Template: v1.PodTemplateSpec{
 					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							"kmm.node.kubernetes.io/worker-config": `{"name":"module-name","namespace":"namespace","modprobe":{"moduleName":"some-kmod"}}`,
+						},
 						Finalizers: []string{constants.NodeLabelerFinalizer},
 						Labels:     podLabels,
 					},
 					Spec: v1.PodSpec{
+						InitContainers: []v1.Container{
+							{
+								Name:                     "install-worker",
+								Image:                    workerImage,
+								Command:                  []string{"/usr/local/bin/worker", "install", "/kmm-worker/worker"},
+								TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
+								VolumeMounts: []v1.VolumeMount{
+									{
+										Name:      "kmm-worker-bin",
+										MountPath: "/kmm-worker",
+									},
+								},
+							},
+						},
 						Containers: []v1.Container{
 							{
 								Name:  "module-loader",
 								Image: moduleLoaderImage,
-								Lifecycle: &v1.Lifecycle{
-									PostStart: &v1.LifecycleHandler{
-										Exec: &v1.ExecAction{
-											Command: MakeLoadCommand(mld.Modprobe, moduleName),
-										},
-									},
-									PreStop: &v1.LifecycleHandler{
+								Command: []string{
+									"/kmm-worker/worker", "run", "--config", "/etc/kmm-worker/config.json", "--state-file", "/kmm-worker/state.json",
+								},
+								ReadinessProbe: &v1.Probe{
+									ProbeHandler: v1.ProbeHandler{
 										Exec: &v1.ExecAction{
-											Command: MakeUnloadCommand(mld.Modprobe, moduleName),
+											Command: []string{
+												"/kmm-worker/worker", "ready", "--config", "/etc/kmm-worker/config.json", "--state-file", "/kmm-worker/state.json",
+											},
 										},
 									},
 								},
-								Command: []string{"sleep", "infinity"},
+								TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
 								VolumeMounts: []v1.VolumeMount{
 									{
 										Name:      "node-lib-modules",
 										ReadOnly:  true,
 										MountPath: fullModulesPath,
 									},
+									{
+										Name:      "kmm-worker-bin",
+										MountPath: "/kmm-worker",
+									},
+									{
+										Name:      "kmm-worker-config",
+										ReadOnly:  true,
+										MountPath: "/etc/kmm-worker",
+									},
 								},
 								SecurityContext: &v1.SecurityContext{
 									AllowPrivilegeEscalation: pointer.Bool(false),
@@ -268,6 +597,27 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 									},
 								},
 							},
+							{
+								Name: "kmm-worker-bin",
+								VolumeSource: v1.VolumeSource{
+									EmptyDir: &v1.EmptyDirVolumeSource{},
+								},
+							},
+							{
+								Name: "kmm-worker-config",
+								VolumeSource: v1.VolumeSource{
+									DownwardAPI: &v1.DownwardAPIVolumeSource{
+										Items: []v1.DownwardAPIVolumeFile{
+											{
+												Path: "config.json",
+												FieldRef: &v1.ObjectFieldSelector{
+													FieldPath: "metadata.annotations['kmm.node.kubernetes.io/worker-config']",
+												},
+											},
+										},
+									},
+								},
+							},
 						},
 					},
 				},
@@ -281,13 +631,13 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 		)
 	})
 
-	Describe("ModuleDaemonSetsByKernelVersion", func() {
+	Describe("ModuleDaemonSetsByKernelAndArch", func() {
 		It("should return an empty map if no DaemonSets are present", func() {
 			clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any())
 
-			dc := NewCreator(clnt, kernelLabel, scheme)
+			dc := NewCreator(clnt, kernelLabel, "", scheme)
 
-			m, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), moduleName, namespace)
+			m, err := dc.ModuleDaemonSetsByKernelAndArch(context.Background(), moduleName, namespace)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(m).To(BeEmpty())
 		})
@@ -295,9 +645,9 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 		It("should return an error if two DaemonSets are present for the same kernel", func() {
 			clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).Return(errors.New("some error"))
 
-			dc := NewCreator(clnt, kernelLabel, scheme)
+			dc := NewCreator(clnt, kernelLabel, "", scheme)
 
-			_, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), moduleName, namespace)
+			_, err := dc.ModuleDaemonSetsByKernelAndArch(context.Background(), moduleName, namespace)
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -334,19 +684,120 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 				},
 			)
 
-			dc := NewCreator(clnt, kernelLabel, scheme)
+			dc := NewCreator(clnt, kernelLabel, "", scheme)
 
-			m, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), moduleName, namespace)
+			m, err := dc.ModuleDaemonSetsByKernelAndArch(context.Background(), moduleName, namespace)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(m).To(HaveLen(2))
 			Expect(m).To(HaveKeyWithValue(kernelVersion, &ds1))
 			Expect(m).To(HaveKeyWithValue(otherKernelVersion, &ds2))
 		})
+
+		It("should key the DaemonSets by kernel and architecture", func() {
+			ds1 := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ds1",
+					Namespace: namespace,
+					Labels: map[string]string{
+						"kmm.node.kubernetes.io/module.name": moduleName,
+						kernelLabel:                          kernelVersion,
+						constants.ArchLabel:                  "amd64",
+					},
+				},
+			}
+
+			ds2 := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ds2",
+					Namespace: namespace,
+					Labels: map[string]string{
+						"kmm.node.kubernetes.io/module.name": moduleName,
+						kernelLabel:                          kernelVersion,
+						constants.ArchLabel:                  "arm64",
+					},
+				},
+			}
+
+			clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+					list.Items = append(list.Items, ds1, ds2)
+					return nil
+				},
+			)
+
+			dc := NewCreator(clnt, kernelLabel, "", scheme)
+
+			m, err := dc.ModuleDaemonSetsByKernelAndArch(context.Background(), moduleName, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(HaveLen(2))
+			Expect(m).To(HaveKeyWithValue(kernelVersion+"/amd64", &ds1))
+			Expect(m).To(HaveKeyWithValue(kernelVersion+"/arm64", &ds2))
+		})
+	})
+})
+
+var _ = Describe("ModuleLoaderJobPodTemplate", func() {
+	dg := NewCreator(nil, kernelLabel, "", scheme)
+
+	It("should return an error if the image is empty", func() {
+		_, err := dg.ModuleLoaderJobPodTemplate(context.Background(), &api.ModuleLoaderData{}, "node", "load", false)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should run the command once on the node", func() {
+		mld := api.ModuleLoaderData{
+			Name:           moduleName,
+			Namespace:      namespace,
+			Selector:       map[string]string{"has-feature-x": "true"},
+			Owner:          &kmmv1beta1.Module{},
+			ContainerImage: "some-image",
+			KernelVersion:  kernelVersion,
+			Affinity:       &v1.Affinity{},
+			DependsOn:      []string{"core"},
+		}
+
+		template, err := dg.ModuleLoaderJobPodTemplate(context.Background(), &mld, "some-node", "unload", false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(template.Labels).NotTo(HaveKey(constants.ModuleNameLabel))
+		Expect(template.Labels).To(HaveKeyWithValue(kernelLabel, kernelVersion))
+		Expect(template.Finalizers).To(BeEmpty())
+		Expect(template.Spec.NodeName).To(Equal("some-node"))
+		Expect(template.Spec.NodeSelector).To(BeEmpty())
+		Expect(template.Spec.Affinity).To(BeNil())
+		Expect(template.Spec.RestartPolicy).To(Equal(v1.RestartPolicyNever))
+
+		hostPathDirectoryOrCreate := v1.HostPathDirectoryOrCreate
+
+		Expect(template.Spec.Volumes).To(
+			ContainElement(v1.Volume{
+				Name: "kmm-worker-state",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{Path: "/run/kmm-worker", Type: &hostPathDirectoryOrCreate},
+				},
+			}),
+		)
+
+		Expect(template.Spec.Containers).To(HaveLen(1))
+
+		container := template.Spec.Containers[0]
+		Expect(container.Command).To(Equal([]string{
+			"/kmm-worker/worker",
+			"unload",
+			"--config",
+			"/etc/kmm-worker/config.json",
+			"--state-file",
+			"/run/kmm-worker/namespace.module-name.json",
+		}))
+		Expect(container.ReadinessProbe).To(BeNil())
+		Expect(container.VolumeMounts).To(
+			ContainElement(v1.VolumeMount{Name: "kmm-worker-state", MountPath: "/run/kmm-worker"}),
+		)
 	})
 })
 
 var _ = Describe("SetDevicePluginAsDesired", func() {
-	dg := NewCreator(nil, kernelLabel, scheme)
+	dg := NewCreator(nil, kernelLabel, "", scheme)
 
 	It("should return an error if the DaemonSet is nil", func() {
 		Expect(
@@ -385,6 +836,25 @@ var _ = Describe("SetDevicePluginAsDesired", func() {
 		Expect(ds.Spec.Template.Spec.Volumes[1]).To(Equal(vol))
 	})
 
+	It("should use the tolerations of the module loader", func() {
+		tolerations := []v1.Toleration{{Key: "gpu", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}}
+
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{Tolerations: tolerations},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Tolerations).To(Equal(tolerations))
+	})
+
 	DescribeTable("should add the default ServiceAccount to the device plugin",
 		func(useDefaultSA bool, expectedSA string) {
 			mod := kmmv1beta1.Module{
@@ -535,7 +1005,7 @@ var _ = Describe("SetDevicePluginAsDesired", func() {
 						},
 						ImagePullSecrets: []v1.LocalObjectReference{repoSecret},
 						NodeSelector: map[string]string{
-							getDriverContainerNodeLabel(mod.Name): "",
+							GetDriverContainerNodeLabel(mod.Name): "",
 						},
 						PriorityClassName:  "system-node-critical",
 						ServiceAccountName: serviceAccountName,
@@ -588,7 +1058,7 @@ var _ = Describe("GarbageCollect", func() {
 
 		clnt.EXPECT().Delete(context.Background(), &dsNotLegit).AnyTimes()
 
-		dc := NewCreator(clnt, kernelLabel, scheme)
+		dc := NewCreator(clnt, kernelLabel, "", scheme)
 
 		existingDS := map[string]*appsv1.DaemonSet{
 			legitKernelVersion:    &dsLegit,
@@ -607,7 +1077,7 @@ var _ = Describe("GarbageCollect", func() {
 			errors.New("client returns some error"),
 		)
 
-		dc := NewCreator(clnt, kernelLabel, scheme)
+		dc := NewCreator(clnt, kernelLabel, "", scheme)
 
 		dsNotLegit := appsv1.DaemonSet{
 			ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "namespace", Labels: map[string]string{kernelLabel: "kernel version"}},
@@ -622,7 +1092,7 @@ var _ = Describe("GarbageCollect", func() {
 	})
 })
 
-var _ = Describe("ModuleDaemonSetsByKernelVersion", func() {
+var _ = Describe("ModuleDaemonSetsByKernelAndArch", func() {
 	BeforeEach(func() {
 		ctrl = gomock.NewController(GinkgoT())
 		clnt = client.NewMockClient(ctrl)
@@ -631,9 +1101,9 @@ var _ = Describe("ModuleDaemonSetsByKernelVersion", func() {
 	It("should return an empty map if no DaemonSets are present", func() {
 		clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any())
 
-		dc := NewCreator(clnt, kernelLabel, scheme)
+		dc := NewCreator(clnt, kernelLabel, "", scheme)
 
-		m, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), moduleName, namespace)
+		m, err := dc.ModuleDaemonSetsByKernelAndArch(context.Background(), moduleName, namespace)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(m).To(BeEmpty())
 	})
@@ -667,9 +1137,9 @@ var _ = Describe("ModuleDaemonSetsByKernelVersion", func() {
 				return nil
 			},
 		)
-		dc := NewCreator(clnt, kernelLabel, scheme)
+		dc := NewCreator(clnt, kernelLabel, "", scheme)
 
-		_, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
+		_, err := dc.ModuleDaemonSetsByKernelAndArch(ctx, moduleName, namespace)
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -707,9 +1177,9 @@ var _ = Describe("ModuleDaemonSetsByKernelVersion", func() {
 			},
 		)
 
-		dc := NewCreator(clnt, kernelLabel, scheme)
+		dc := NewCreator(clnt, kernelLabel, "", scheme)
 
-		m, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
+		m, err := dc.ModuleDaemonSetsByKernelAndArch(ctx, moduleName, namespace)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(m).To(HaveLen(2))
 		Expect(m).To(HaveKeyWithValue(kernelVersion, &ds1))
@@ -747,9 +1217,9 @@ var _ = Describe("ModuleDaemonSetsByKernelVersion", func() {
 			},
 		)
 
-		dc := NewCreator(clnt, kernelLabel, scheme)
+		dc := NewCreator(clnt, kernelLabel, "", scheme)
 
-		m, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), moduleName, namespace)
+		m, err := dc.ModuleDaemonSetsByKernelAndArch(context.Background(), moduleName, namespace)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(m).To(HaveLen(2))
 		Expect(m).To(HaveKeyWithValue(kernelVersion, &ds1))
@@ -804,7 +1274,7 @@ var _ = Describe("GetNodeLabelFromPod", func() {
 	var dc DaemonSetCreator
 
 	BeforeEach(func() {
-		dc = NewCreator(clnt, kernelLabel, scheme)
+		dc = NewCreator(clnt, kernelLabel, "", scheme)
 	})
 
 	It("should return a driver container label", func() {
@@ -817,7 +1287,7 @@ var _ = Describe("GetNodeLabelFromPod", func() {
 			},
 		}
 		res := dc.GetNodeLabelFromPod(&pod, "module-name")
-		Expect(res).To(Equal(getDriverContainerNodeLabel("module-name")))
+		Expect(res).To(Equal(GetDriverContainerNodeLabel("module-name")))
 	})
 
 	It("should return a device plugin label", func() {
@@ -829,173 +1299,6 @@ var _ = Describe("GetNodeLabelFromPod", func() {
 			},
 		}
 		res := dc.GetNodeLabelFromPod(&pod, "module-name")
-		Expect(res).To(Equal(getDevicePluginNodeLabel("module-name")))
-	})
-})
-
-var _ = Describe("MakeLoadCommand", func() {
-	const (
-		kernelModuleName = "some-kmod"
-		moduleName       = "module-name"
-	)
-
-	It("should only use raw arguments if they are provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			ModuleName: kernelModuleName,
-			RawArgs: &kmmv1beta1.ModprobeArgs{
-				Load: []string{"load", "arguments"},
-			},
-		}
-
-		Expect(
-			MakeLoadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				"modprobe load arguments",
-			}),
-		)
-	})
-
-	It("should build the command from the spec as expected", func() {
-		const (
-			arg1 = "arg1"
-			arg2 = "arg2"
-			dir  = "/some-dir"
-		)
-
-		spec := kmmv1beta1.ModprobeSpec{
-			ModuleName: kernelModuleName,
-			Parameters: []string{arg1, arg2},
-			DirName:    dir,
-		}
-
-		Expect(
-			MakeLoadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -v -d %s %s %s %s", dir, kernelModuleName, arg1, arg2),
-			}),
-		)
-	})
-
-	It("should use provided arguments if provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			Args: &kmmv1beta1.ModprobeArgs{
-				Load: []string{"-z", "-k"},
-			},
-			ModuleName: kernelModuleName,
-		}
-
-		Expect(
-			MakeLoadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -z -k %s", kernelModuleName),
-			}),
-		)
-	})
-
-	It("should use the firmware path if provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			FirmwarePath: "/kmm/firmware/mymodule",
-			ModuleName:   kernelModuleName,
-		}
-
-		Expect(
-			MakeLoadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("cp -r /kmm/firmware/mymodule/* /var/lib/firmware && modprobe -v %s", kernelModuleName),
-			}),
-		)
-	})
-})
-
-var _ = Describe("MakeUnloadCommand", func() {
-	const (
-		kernelModuleName = "some-kmod"
-		moduleName       = "module-name"
-	)
-
-	It("should only use raw arguments if they are provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			ModuleName: kernelModuleName,
-			RawArgs: &kmmv1beta1.ModprobeArgs{
-				Unload: []string{"unload", "arguments"},
-			},
-		}
-
-		Expect(
-			MakeUnloadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				"modprobe unload arguments",
-			}),
-		)
-	})
-
-	It("should build the command from the spec as expected", func() {
-		const dir = "/some-dir"
-
-		spec := kmmv1beta1.ModprobeSpec{
-			ModuleName: kernelModuleName,
-			DirName:    dir,
-		}
-
-		Expect(
-			MakeUnloadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -rv -d %s %s", dir, kernelModuleName),
-			}),
-		)
-	})
-
-	It("should use provided arguments if provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			Args: &kmmv1beta1.ModprobeArgs{
-				Unload: []string{"-z", "-k"},
-			},
-			ModuleName: kernelModuleName,
-		}
-
-		Expect(
-			MakeUnloadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -z -k %s", kernelModuleName),
-			}),
-		)
-	})
-
-	It("should use the firmware path if provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			FirmwarePath: "/kmm/firmware/mymodule",
-			ModuleName:   kernelModuleName,
-		}
-
-		Expect(
-			MakeUnloadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -rv %s && cd /kmm/firmware/mymodule && find |sort -r |xargs -I{} rm -d /var/lib/firmware/{}", kernelModuleName),
-			}),
-		)
+		Expect(res).To(Equal(GetDevicePluginNodeLabel("module-name")))
 	})
 })
@@ -40,18 +40,18 @@ func (m *MockDaemonSetCreator) EXPECT() *MockDaemonSetCreatorMockRecorder {
 }
 
 // GarbageCollect mocks base method.
-func (m *MockDaemonSetCreator) GarbageCollect(ctx context.Context, existingDS map[string]*v1.DaemonSet, validKernels sets.String) ([]string, error) {
+func (m *MockDaemonSetCreator) GarbageCollect(ctx context.Context, existingDS map[string]*v1.DaemonSet, validKeys sets.String) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GarbageCollect", ctx, existingDS, validKernels)
+	ret := m.ctrl.Call(m, "GarbageCollect", ctx, existingDS, validKeys)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GarbageCollect indicates an expected call of GarbageCollect.
-func (mr *MockDaemonSetCreatorMockRecorder) GarbageCollect(ctx, existingDS, validKernels interface{}) *gomock.Call {
+func (mr *MockDaemonSetCreatorMockRecorder) GarbageCollect(ctx, existingDS, validKeys interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GarbageCollect", reflect.TypeOf((*MockDaemonSetCreator)(nil).GarbageCollect), ctx, existingDS, validKernels)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GarbageCollect", reflect.TypeOf((*MockDaemonSetCreator)(nil).GarbageCollect), ctx, existingDS, validKeys)
 }
 
 // GetNodeLabelFromPod mocks base method.
@@ -68,19 +68,34 @@ func (mr *MockDaemonSetCreatorMockRecorder) GetNodeLabelFromPod(pod, moduleName
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeLabelFromPod", reflect.TypeOf((*MockDaemonSetCreator)(nil).GetNodeLabelFromPod), pod, moduleName)
 }
 
-// ModuleDaemonSetsByKernelVersion mocks base method.
-func (m *MockDaemonSetCreator) ModuleDaemonSetsByKernelVersion(ctx context.Context, name, namespace string) (map[string]*v1.DaemonSet, error) {
+// ModuleDaemonSetsByKernelAndArch mocks base method.
+func (m *MockDaemonSetCreator) ModuleDaemonSetsByKernelAndArch(ctx context.Context, name, namespace string) (map[string]*v1.DaemonSet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ModuleDaemonSetsByKernelVersion", ctx, name, namespace)
+	ret := m.ctrl.Call(m, "ModuleDaemonSetsByKernelAndArch", ctx, name, namespace)
 	ret0, _ := ret[0].(map[string]*v1.DaemonSet)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ModuleDaemonSetsByKernelVersion indicates an expected call of ModuleDaemonSetsByKernelVersion.
-func (mr *MockDaemonSetCreatorMockRecorder) ModuleDaemonSetsByKernelVersion(ctx, name, namespace interface{}) *gomock.Call {
+// ModuleDaemonSetsByKernelAndArch indicates an expected call of ModuleDaemonSetsByKernelAndArch.
+func (mr *MockDaemonSetCreatorMockRecorder) ModuleDaemonSetsByKernelAndArch(ctx, name, namespace interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModuleDaemonSetsByKernelVersion", reflect.TypeOf((*MockDaemonSetCreator)(nil).ModuleDaemonSetsByKernelVersion), ctx, name, namespace)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModuleDaemonSetsByKernelAndArch", reflect.TypeOf((*MockDaemonSetCreator)(nil).ModuleDaemonSetsByKernelAndArch), ctx, name, namespace)
+}
+
+// ModuleLoaderJobPodTemplate mocks base method.
+func (m *MockDaemonSetCreator) ModuleLoaderJobPodTemplate(ctx context.Context, mld *api.ModuleLoaderData, nodeName, command string, useDefaultSA bool) (*v10.PodTemplateSpec, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModuleLoaderJobPodTemplate", ctx, mld, nodeName, command, useDefaultSA)
+	ret0, _ := ret[0].(*v10.PodTemplateSpec)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ModuleLoaderJobPodTemplate indicates an expected call of ModuleLoaderJobPodTemplate.
+func (mr *MockDaemonSetCreatorMockRecorder) ModuleLoaderJobPodTemplate(ctx, mld, nodeName, command, useDefaultSA interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModuleLoaderJobPodTemplate", reflect.TypeOf((*MockDaemonSetCreator)(nil).ModuleLoaderJobPodTemplate), ctx, mld, nodeName, command, useDefaultSA)
 }
 
 // SetDevicePluginAsDesired mocks base method.
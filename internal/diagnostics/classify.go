@@ -0,0 +1,75 @@
+package diagnostics
+
+import (
+	"regexp"
+	"strings"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+)
+
+// maxMessageLength is the maximum length of the message recorded for a failure.
+const maxMessageLength = 256
+
+type failurePattern struct {
+	reason kmmv1beta1.StageFailureReason
+	re     *regexp.Regexp
+}
+
+// failurePatterns are matched against each line of the log, in order; the first pattern matching any line wins.
+// Missing kernel sources are checked before compile errors, as make also reports them as errors.
+var failurePatterns = []failurePattern{
+	{
+		reason: kmmv1beta1.StageFailureReasonMissingDockerfile,
+		re:     regexp.MustCompile(`(?i)dockerfile configmap`),
+	},
+	{
+		reason: kmmv1beta1.StageFailureReasonMissingKernelDevel,
+		re: regexp.MustCompile(
+			`(?i)(/lib/modules/\S+/build\S*: no such file or directory|no package kernel-devel|` +
+				`unable to find a match: kernel-devel|kernel-devel\S* (is )?not (found|installed)|kernel headers? not found)`,
+		),
+	},
+	{
+		reason: kmmv1beta1.StageFailureReasonPushDenied,
+		re: regexp.MustCompile(
+			`(?i)(error pushing|failed to push|requested access to the resource is denied|` +
+				`unauthorized: authentication required|insufficient_scope|push.*(denied|unauthorized|forbidden))`,
+		),
+	},
+	{
+		reason: kmmv1beta1.StageFailureReasonCompileError,
+		re: regexp.MustCompile(
+			`(\.[ch]:\d+(:\d+)?: (fatal )?error:|make(\[\d+\])?: \*\*\* .*Error \d+|collect2: error|undefined reference to)`,
+		),
+	},
+}
+
+// Classify returns the reason of the failure described by log, and the line explaining it.
+// Logs that cannot be classified are Unknown, with their last non-empty line as message.
+func Classify(log string) (kmmv1beta1.StageFailureReason, string) {
+	lines := strings.Split(log, "\n")
+
+	for _, p := range failurePatterns {
+		for _, l := range lines {
+			if p.re.MatchString(l) {
+				return p.reason, truncate(strings.TrimSpace(l))
+			}
+		}
+	}
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(lines[i]); l != "" {
+			return kmmv1beta1.StageFailureReasonUnknown, truncate(l)
+		}
+	}
+
+	return kmmv1beta1.StageFailureReasonUnknown, ""
+}
+
+func truncate(s string) string {
+	if len(s) <= maxMessageLength {
+		return s
+	}
+
+	return s[:maxMessageLength-3] + "..."
+}
@@ -0,0 +1,69 @@
+package diagnostics
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+)
+
+var _ = Describe("Classify", func() {
+	DescribeTable("should classify the failure from the log",
+		func(log string, expectedReason kmmv1beta1.StageFailureReason, expectedMessage string) {
+			reason, message := Classify(log)
+
+			Expect(reason).To(Equal(expectedReason))
+			Expect(message).To(Equal(expectedMessage))
+		},
+		Entry(
+			"missing Dockerfile key",
+			"could not make Build template: invalid Dockerfile ConfigMap ns/name format, dockerfile key is missing",
+			kmmv1beta1.StageFailureReasonMissingDockerfile,
+			"could not make Build template: invalid Dockerfile ConfigMap ns/name format, dockerfile key is missing",
+		),
+		Entry(
+			"missing kernel build tree",
+			"make -C /lib/modules/5.14.0/build M=/src modules\n"+
+				"make: *** /lib/modules/5.14.0/build: No such file or directory.  Stop.\n"+
+				"error: build error: building at STEP \"RUN make\": exit status 2\n",
+			kmmv1beta1.StageFailureReasonMissingKernelDevel,
+			"make: *** /lib/modules/5.14.0/build: No such file or directory.  Stop.",
+		),
+		Entry(
+			"missing kernel-devel package",
+			"Error: Unable to find a match: kernel-devel-5.14.0-284.el9",
+			kmmv1beta1.StageFailureReasonMissingKernelDevel,
+			"Error: Unable to find a match: kernel-devel-5.14.0-284.el9",
+		),
+		Entry(
+			"compile error",
+			"  CC [M]  /src/kmod.o\n"+
+				"/src/kmod.c:12:5: error: implicit declaration of function 'foo'\n"+
+				"make[1]: *** [scripts/Makefile.build:299: /src/kmod.o] Error 1\n",
+			kmmv1beta1.StageFailureReasonCompileError,
+			"/src/kmod.c:12:5: error: implicit declaration of function 'foo'",
+		),
+		Entry(
+			"push denied",
+			"Pushing image quay.io/org/kmod:5.14.0 ...\n"+
+				"error: build error: Failed to push image: errors:\ndenied: requested access to the resource is denied\n",
+			kmmv1beta1.StageFailureReasonPushDenied,
+			"error: build error: Failed to push image: errors:",
+		),
+		Entry(
+			"unknown failure",
+			"step 1\nsomething went wrong\n\n",
+			kmmv1beta1.StageFailureReasonUnknown,
+			"something went wrong",
+		),
+		Entry("empty log", "", kmmv1beta1.StageFailureReasonUnknown, ""),
+	)
+
+	It("should truncate long messages", func() {
+		_, message := Classify(strings.Repeat("a", 1000))
+
+		Expect(message).To(HaveLen(maxMessageLength))
+		Expect(message).To(HaveSuffix("..."))
+	})
+})
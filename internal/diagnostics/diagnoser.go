@@ -0,0 +1,359 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/retry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+const (
+	// logTailLines is the number of lines captured from the end of the log of a failed pod.
+	logTailLines int64 = 100
+
+	// LogConfigMapKey is the key of the captured log in the log ConfigMap.
+	LogConfigMapKey = "log"
+
+	// buildPodNameAnnotation is set by OpenShift on Builds.
+	buildPodNameAnnotation = "openshift.io/build.pod-name"
+
+	// jobNameLabel is set by the Job controller on the pods of a Job.
+	jobNameLabel = "job-name"
+)
+
+//go:generate mockgen -source=diagnoser.go -package=diagnostics -destination=mock_diagnoser.go
+
+// Diagnoser explains the failures of the build and sign stages.
+type Diagnoser interface {
+	Diagnose(
+		ctx context.Context,
+		mld *api.ModuleLoaderData,
+		stage kmmv1beta1.Stage,
+		cause error,
+		previous *kmmv1beta1.StageFailure) (*kmmv1beta1.StageFailure, error)
+}
+
+type diagnoser struct {
+	client    client.Client
+	pods      corev1client.PodsGetter
+	jobHelper utils.JobHelper
+	recorder  record.EventRecorder
+	scheme    *runtime.Scheme
+	ocpBuilds bool
+}
+
+// NewDiagnoser returns a Diagnoser; ocpBuilds is true if builds run as OpenShift Builds rather than Jobs.
+func NewDiagnoser(
+	client client.Client,
+	pods corev1client.PodsGetter,
+	jobHelper utils.JobHelper,
+	recorder record.EventRecorder,
+	scheme *runtime.Scheme,
+	ocpBuilds bool) Diagnoser {
+	return &diagnoser{
+		client:    client,
+		pods:      pods,
+		jobHelper: jobHelper,
+		recorder:  recorder,
+		scheme:    scheme,
+		ocpBuilds: ocpBuilds,
+	}
+}
+
+// Diagnose captures the tail of the log of the failed build or sign pod for mld, keeps it in a ConfigMap owned by the
+// Module and classifies the failure.
+// cause is the error returned by the stage, if any; it is classified when no pod ran, for example when the Dockerfile
+// is missing.
+// previous is the failure recorded for the same kernel version, which is returned as is if it describes the same pod,
+// so that logs are only captured once per failed pod.
+// A Warning Event is recorded on the Module for each new failure, and its reason is recorded on the failed Build or Job
+// for the retry policy.
+// A failure is always returned, even along with an error.
+func (d *diagnoser) Diagnose(
+	ctx context.Context,
+	mld *api.ModuleLoaderData,
+	stage kmmv1beta1.Stage,
+	cause error,
+	previous *kmmv1beta1.StageFailure) (*kmmv1beta1.StageFailure, error) {
+
+	failure := &kmmv1beta1.StageFailure{Stage: stage}
+
+	if cause != nil {
+		failure.Reason, failure.Message = Classify(cause.Error())
+	} else {
+		failure.Reason = kmmv1beta1.StageFailureReasonUnknown
+	}
+
+	pod, failed, snippet, err := d.failedPod(ctx, mld, stage)
+	if err != nil {
+		return failure, fmt.Errorf("could not find the failed pod: %v", err)
+	}
+
+	if pod != nil {
+		failure.PodName = pod.Name
+
+		if previous != nil && previous.Stage == stage && previous.PodName == pod.Name {
+			return previous, d.recordReason(ctx, failed, previous.Reason)
+		}
+
+		if logs, err := d.podLogs(ctx, pod); err != nil {
+			// the pod may have been garbage-collected already
+			failure.Message = err.Error()
+		} else {
+			snippet = logs
+		}
+	}
+
+	if cause != nil {
+		snippet = strings.TrimSpace(snippet + "\n" + cause.Error())
+	}
+
+	if snippet != "" {
+		failure.Reason, failure.Message = Classify(snippet)
+
+		name, err := d.storeLog(ctx, mld, stage, snippet)
+		if err != nil {
+			return failure, fmt.Errorf("could not store the log: %v", err)
+		}
+
+		failure.LogConfigMap = name
+	}
+
+	if previous == nil || *previous != *failure {
+		d.recordEvent(mld, failure)
+	}
+
+	return failure, d.recordReason(ctx, failed, failure.Reason)
+}
+
+// recordReason annotates the failed Build or Job, if any, with the reason of its failure so that it can be retried
+// depending on it.
+func (d *diagnoser) recordReason(ctx context.Context, failed client.Object, reason kmmv1beta1.StageFailureReason) error {
+	if failed == nil || failed.GetAnnotations()[retry.FailureReasonAnnotation] == string(reason) {
+		return nil
+	}
+
+	patchFrom := client.MergeFrom(failed.DeepCopyObject().(client.Object))
+
+	annotations := failed.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[retry.FailureReasonAnnotation] = string(reason)
+	failed.SetAnnotations(annotations)
+
+	if err := d.client.Patch(ctx, failed, patchFrom); err != nil {
+		return fmt.Errorf("could not record the failure reason on %s: %v", failed.GetName(), err)
+	}
+
+	return nil
+}
+
+// failedPod returns the last failed pod of the stage, if any, the Build or Job it belongs to if the latter failed,
+// and the log snippet recorded by OpenShift on the Build.
+func (d *diagnoser) failedPod(ctx context.Context, mld *api.ModuleLoaderData, stage kmmv1beta1.Stage) (*v1.Pod, client.Object, string, error) {
+	if stage == kmmv1beta1.StageBuild && d.ocpBuilds {
+		return d.failedBuildPod(ctx, mld)
+	}
+
+	jobType := utils.JobTypeBuild
+	if stage == kmmv1beta1.StageSign {
+		jobType = utils.JobTypeSign
+	}
+
+	job, err := d.jobHelper.GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, mld.KernelVersion, mld.Arch, jobType, mld.Owner)
+	if err != nil {
+		if errors.Is(err, utils.ErrNoMatchingJob) {
+			return nil, nil, "", nil
+		}
+
+		return nil, nil, "", fmt.Errorf("could not get the %s Job: %v", jobType, err)
+	}
+
+	var failed client.Object
+
+	// the failure may not come from the Job, for example if its template could not be made
+	if job.Status.Failed > 0 {
+		failed = job
+	}
+
+	podList := v1.PodList{}
+
+	if err = d.client.List(ctx, &podList, client.InNamespace(job.Namespace), client.MatchingLabels{jobNameLabel: job.Name}); err != nil {
+		return nil, nil, "", fmt.Errorf("could not list the pods of Job %s: %v", job.Name, err)
+	}
+
+	pods := podList.Items
+
+	if len(pods) == 0 {
+		return nil, failed, "", nil
+	}
+
+	// failed pods first, most recent first
+	sort.SliceStable(pods, func(i, j int) bool {
+		iFailed, jFailed := pods[i].Status.Phase == v1.PodFailed, pods[j].Status.Phase == v1.PodFailed
+		if iFailed != jFailed {
+			return iFailed
+		}
+
+		return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp)
+	})
+
+	return &pods[0], failed, "", nil
+}
+
+func (d *diagnoser) failedBuildPod(ctx context.Context, mld *api.ModuleLoaderData) (*v1.Pod, client.Object, string, error) {
+	buildList := buildv1.BuildList{}
+
+	if err := d.client.List(ctx, &buildList, client.InNamespace(mld.Namespace), client.MatchingLabels(build.GetBuildLabels(mld))); err != nil {
+		return nil, nil, "", fmt.Errorf("could not list Builds: %v", err)
+	}
+
+	var failed *buildv1.Build
+
+	for i := range buildList.Items {
+		b := &buildList.Items[i]
+
+		if b.Status.Phase != buildv1.BuildPhaseFailed && b.Status.Phase != buildv1.BuildPhaseError {
+			continue
+		}
+
+		if failed == nil || failed.CreationTimestamp.Before(&b.CreationTimestamp) {
+			failed = b
+		}
+	}
+
+	if failed == nil {
+		return nil, nil, "", nil
+	}
+
+	snippet := failed.Status.LogSnippet
+	if snippet == "" {
+		snippet = failed.Status.Message
+	}
+
+	podName := failed.Annotations[buildPodNameAnnotation]
+	if podName == "" {
+		return nil, failed, snippet, nil
+	}
+
+	pod := v1.Pod{}
+
+	if err := d.client.Get(ctx, types.NamespacedName{Name: podName, Namespace: failed.Namespace}, &pod); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, nil, "", fmt.Errorf("could not get pod %s: %v", podName, err)
+		}
+
+		// the snippet is all that is left; the pod name still identifies the failure
+		pod.Name = podName
+		pod.Namespace = failed.Namespace
+	}
+
+	return &pod, failed, snippet, nil
+}
+
+// podLogs returns the tail of the log of the container that failed in pod.
+func (d *diagnoser) podLogs(ctx context.Context, pod *v1.Pod) (string, error) {
+	tail := logTailLines
+
+	opts := v1.PodLogOptions{
+		Container: failedContainer(pod),
+		TailLines: &tail,
+	}
+
+	b, err := d.pods.Pods(pod.Namespace).GetLogs(pod.Name, &opts).DoRaw(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not get the logs of pod %s: %v", pod.Name, err)
+	}
+
+	return string(b), nil
+}
+
+// failedContainer returns the first container of pod that exited with an error, or an empty string to let the API
+// server choose if the pod has a single container or is not known.
+func failedContainer(pod *v1.Pod) string {
+	statuses := make([]v1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+
+	for _, s := range statuses {
+		if t := s.State.Terminated; t != nil && t.ExitCode != 0 {
+			return s.Name
+		}
+	}
+
+	return ""
+}
+
+// LogConfigMapName returns the name of the ConfigMap keeping the log of the last failure of the stage for mld.
+func LogConfigMapName(mld *api.ModuleLoaderData, stage kmmv1beta1.Stage) string {
+	// kernel versions are not valid in names
+	h := fnv.New32a()
+	h.Write([]byte(mld.KernelVersion + "/" + mld.Arch))
+
+	return fmt.Sprintf("%s-%s-log-%08x", mld.Name, strings.ToLower(string(stage)), h.Sum32())
+}
+
+// storeLog writes log into the log ConfigMap of the stage, owned by the Module, and returns the name of the ConfigMap.
+func (d *diagnoser) storeLog(ctx context.Context, mld *api.ModuleLoaderData, stage kmmv1beta1.Stage, log string) (string, error) {
+	cm := v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      LogConfigMapName(mld, stage),
+			Namespace: mld.Namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrPatch(ctx, d.client, &cm, func() error {
+		labels := build.GetBuildLabels(mld)
+		labels[constants.JobType] = strings.ToLower(string(stage))
+
+		cm.Labels = labels
+		cm.Data = map[string]string{LogConfigMapKey: log}
+
+		return controllerutil.SetOwnerReference(mld.Owner, &cm, d.scheme)
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not create or patch ConfigMap %s: %v", cm.Name, err)
+	}
+
+	return cm.Name, nil
+}
+
+func (d *diagnoser) recordEvent(mld *api.ModuleLoaderData, failure *kmmv1beta1.StageFailure) {
+	owner, ok := mld.Owner.(runtime.Object)
+	if !ok {
+		return
+	}
+
+	d.recorder.Eventf(
+		owner,
+		v1.EventTypeWarning,
+		string(failure.Stage)+"Failed",
+		"%s failed for kernel %s: %s: %s",
+		failure.Stage,
+		mld.KernelVersion,
+		failure.Reason,
+		failure.Message,
+	)
+}
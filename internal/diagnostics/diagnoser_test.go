@@ -0,0 +1,280 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	buildv1 "github.com/openshift/api/build/v1"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/retry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	moduleName = "test-module"
+	namespace  = "test-namespace"
+)
+
+var _ = Describe("Diagnose", func() {
+	var (
+		ctrl     *gomock.Controller
+		clnt     *client.MockClient
+		mockJH   *utils.MockJobHelper
+		recorder *record.FakeRecorder
+		mld      *api.ModuleLoaderData
+	)
+
+	ctx := context.Background()
+	notFound := k8serrors.NewNotFound(schema.GroupResource{}, "whatever")
+
+	newDiagnoser := func(ocpBuilds bool) Diagnoser {
+		return NewDiagnoser(clnt, fake.NewSimpleClientset().CoreV1(), mockJH, recorder, scheme, ocpBuilds)
+	}
+
+	expectLogStored := func(stage kmmv1beta1.Stage, expectedLog string) {
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, ctrlclient.ObjectKey{Name: LogConfigMapName(mld, stage), Namespace: namespace}, gomock.Any()).Return(notFound),
+			clnt.EXPECT().Create(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj ctrlclient.Object, _ ...ctrlclient.CreateOption) error {
+					cm := obj.(*v1.ConfigMap)
+
+					Expect(cm.Data).To(Equal(map[string]string{LogConfigMapKey: expectedLog}))
+					Expect(cm.OwnerReferences).To(HaveLen(1))
+					Expect(cm.OwnerReferences[0].Name).To(Equal(moduleName))
+
+					return nil
+				},
+			),
+		)
+	}
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		mockJH = utils.NewMockJobHelper(ctrl)
+		recorder = record.NewFakeRecorder(10)
+
+		mld = &api.ModuleLoaderData{
+			Name:          moduleName,
+			Namespace:     namespace,
+			KernelVersion: "5.14.0",
+			Owner: &kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{Name: moduleName, Namespace: namespace},
+			},
+		}
+	})
+
+	It("should capture the log of the last failed pod of the sign Job", func() {
+		d := newDiagnoser(true)
+		job := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "sign-job", Namespace: namespace},
+			Status:     batchv1.JobStatus{Failed: 1},
+		}
+		now := metav1.Now()
+		later := metav1.NewTime(now.Add(time.Minute))
+
+		gomock.InOrder(
+			mockJH.EXPECT().GetModuleJobByKernel(ctx, moduleName, namespace, "5.14.0", "", utils.JobTypeSign, mld.Owner).Return(&job, nil),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, list *v1.PodList, _ ...ctrlclient.ListOption) error {
+					list.Items = []v1.Pod{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: namespace, CreationTimestamp: later},
+							Status:     v1.PodStatus{Phase: v1.PodRunning},
+						},
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "failed-old", Namespace: namespace, CreationTimestamp: now},
+							Status:     v1.PodStatus{Phase: v1.PodFailed},
+						},
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "failed-new", Namespace: namespace, CreationTimestamp: later},
+							Status:     v1.PodStatus{Phase: v1.PodFailed},
+						},
+					}
+
+					return nil
+				},
+			),
+		)
+
+		expectLogStored(kmmv1beta1.StageSign, "fake logs")
+		clnt.EXPECT().Patch(ctx, &job, gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj ctrlclient.Object, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) error {
+				Expect(obj.GetAnnotations()).To(HaveKeyWithValue(retry.FailureReasonAnnotation, "Unknown"))
+				return nil
+			},
+		)
+
+		failure, err := d.Diagnose(ctx, mld, kmmv1beta1.StageSign, nil, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure).To(Equal(&kmmv1beta1.StageFailure{
+			Stage:        kmmv1beta1.StageSign,
+			Reason:       kmmv1beta1.StageFailureReasonUnknown,
+			Message:      "fake logs",
+			PodName:      "failed-new",
+			LogConfigMap: LogConfigMapName(mld, kmmv1beta1.StageSign),
+		}))
+		Expect(recorder.Events).To(Receive(Equal("Warning SignFailed Sign failed for kernel 5.14.0: Unknown: fake logs")))
+	})
+
+	It("should reuse the previous failure of the same pod", func() {
+		d := newDiagnoser(false)
+		job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "build-job", Namespace: namespace}}
+		previous := &kmmv1beta1.StageFailure{
+			Stage:   kmmv1beta1.StageBuild,
+			Reason:  kmmv1beta1.StageFailureReasonCompileError,
+			PodName: "failed",
+		}
+
+		gomock.InOrder(
+			mockJH.EXPECT().GetModuleJobByKernel(ctx, moduleName, namespace, "5.14.0", "", utils.JobTypeBuild, mld.Owner).Return(&job, nil),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, list *v1.PodList, _ ...ctrlclient.ListOption) error {
+					list.Items = []v1.Pod{
+						{ObjectMeta: metav1.ObjectMeta{Name: "failed"}, Status: v1.PodStatus{Phase: v1.PodFailed}},
+					}
+
+					return nil
+				},
+			),
+		)
+
+		failure, err := d.Diagnose(ctx, mld, kmmv1beta1.StageBuild, nil, previous)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure).To(Equal(previous))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should classify the error if no pod ran", func() {
+		d := newDiagnoser(false)
+		cause := errors.New("could not make Job template: invalid Dockerfile ConfigMap ns/cm format, dockerfile key is missing")
+
+		mockJH.EXPECT().GetModuleJobByKernel(ctx, moduleName, namespace, "5.14.0", "", utils.JobTypeBuild, mld.Owner).Return(nil, utils.ErrNoMatchingJob)
+		expectLogStored(kmmv1beta1.StageBuild, cause.Error())
+
+		failure, err := d.Diagnose(ctx, mld, kmmv1beta1.StageBuild, cause, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure.Reason).To(Equal(kmmv1beta1.StageFailureReasonMissingDockerfile))
+		Expect(failure.Message).To(Equal(cause.Error()))
+		Expect(failure.PodName).To(BeEmpty())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning BuildFailed Build failed for kernel 5.14.0: MissingDockerfile")))
+	})
+
+	It("should use the log snippet of the failed OpenShift Build", func() {
+		d := newDiagnoser(true)
+		snippet := "/src/kmod.c:1:1: error: unknown type name 'foo'"
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, list *buildv1.BuildList, _ ...ctrlclient.ListOption) error {
+				list.Items = []buildv1.Build{
+					{Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseComplete}},
+					{Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseFailed, LogSnippet: snippet}},
+				}
+
+				return nil
+			},
+		)
+		expectLogStored(kmmv1beta1.StageBuild, snippet)
+		clnt.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj ctrlclient.Object, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) error {
+				Expect(obj.GetAnnotations()).To(HaveKeyWithValue(retry.FailureReasonAnnotation, "CompileError"))
+				return nil
+			},
+		)
+
+		failure, err := d.Diagnose(ctx, mld, kmmv1beta1.StageBuild, nil, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure.Reason).To(Equal(kmmv1beta1.StageFailureReasonCompileError))
+		Expect(failure.Message).To(Equal(snippet))
+	})
+
+	It("should not record the reason again on the failed Job", func() {
+		d := newDiagnoser(false)
+		job := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "build-job",
+				Namespace:   namespace,
+				Annotations: map[string]string{retry.FailureReasonAnnotation: "CompileError"},
+			},
+			Status: batchv1.JobStatus{Failed: 1},
+		}
+		previous := &kmmv1beta1.StageFailure{
+			Stage:   kmmv1beta1.StageBuild,
+			Reason:  kmmv1beta1.StageFailureReasonCompileError,
+			PodName: "failed",
+		}
+
+		gomock.InOrder(
+			mockJH.EXPECT().GetModuleJobByKernel(ctx, moduleName, namespace, "5.14.0", "", utils.JobTypeBuild, mld.Owner).Return(&job, nil),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, list *v1.PodList, _ ...ctrlclient.ListOption) error {
+					list.Items = []v1.Pod{
+						{ObjectMeta: metav1.ObjectMeta{Name: "failed"}, Status: v1.PodStatus{Phase: v1.PodFailed}},
+					}
+
+					return nil
+				},
+			),
+		)
+
+		failure, err := d.Diagnose(ctx, mld, kmmv1beta1.StageBuild, nil, previous)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure).To(Equal(previous))
+	})
+
+	It("should return the failure along with an error if the Job could not be found", func() {
+		d := newDiagnoser(false)
+		cause := errors.New("some error")
+
+		mockJH.EXPECT().GetModuleJobByKernel(ctx, moduleName, namespace, "5.14.0", "", utils.JobTypeBuild, mld.Owner).Return(nil, errors.New("random error"))
+
+		failure, err := d.Diagnose(ctx, mld, kmmv1beta1.StageBuild, cause, nil)
+
+		Expect(err).To(HaveOccurred())
+		Expect(failure).To(Equal(&kmmv1beta1.StageFailure{
+			Stage:   kmmv1beta1.StageBuild,
+			Reason:  kmmv1beta1.StageFailureReasonUnknown,
+			Message: "some error",
+		}))
+	})
+})
+
+var _ = Describe("failedContainer", func() {
+	It("should return the first container that exited with an error", func() {
+		pod := v1.Pod{
+			Status: v1.PodStatus{
+				InitContainerStatuses: []v1.ContainerStatus{
+					{Name: "git-clone", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}},
+				},
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "docker-build", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}},
+				},
+			},
+		}
+
+		Expect(failedContainer(&pod)).To(Equal("docker-build"))
+	})
+
+	It("should let the API server choose if no container failed", func() {
+		Expect(failedContainer(&v1.Pod{})).To(BeEmpty())
+	})
+})
@@ -0,0 +1,52 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: diagnoser.go
+
+// Package diagnostics is a generated GoMock package.
+package diagnostics
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	api "github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+)
+
+// MockDiagnoser is a mock of Diagnoser interface.
+type MockDiagnoser struct {
+	ctrl     *gomock.Controller
+	recorder *MockDiagnoserMockRecorder
+}
+
+// MockDiagnoserMockRecorder is the mock recorder for MockDiagnoser.
+type MockDiagnoserMockRecorder struct {
+	mock *MockDiagnoser
+}
+
+// NewMockDiagnoser creates a new mock instance.
+func NewMockDiagnoser(ctrl *gomock.Controller) *MockDiagnoser {
+	mock := &MockDiagnoser{ctrl: ctrl}
+	mock.recorder = &MockDiagnoserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDiagnoser) EXPECT() *MockDiagnoserMockRecorder {
+	return m.recorder
+}
+
+// Diagnose mocks base method.
+func (m *MockDiagnoser) Diagnose(ctx context.Context, mld *api.ModuleLoaderData, stage v1beta1.Stage, cause error, previous *v1beta1.StageFailure) (*v1beta1.StageFailure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Diagnose", ctx, mld, stage, cause, previous)
+	ret0, _ := ret[0].(*v1beta1.StageFailure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Diagnose indicates an expected call of Diagnose.
+func (mr *MockDiagnoserMockRecorder) Diagnose(ctx, mld, stage, cause, previous interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Diagnose", reflect.TypeOf((*MockDiagnoser)(nil).Diagnose), ctx, mld, stage, cause, previous)
+}
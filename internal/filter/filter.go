@@ -63,6 +63,24 @@ func clusterClaim(name string, clusterClaims []clusterv1.ManagedClusterClaim) *c
 	return nil
 }
 
+// nodeRebooted returns true for updates of nodes whose boot ID changed; the kernel modules loaded by one-shot Jobs
+// are not loaded anymore.
+var nodeRebooted predicate.Predicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldNode, ok := e.ObjectOld.(*v1.Node)
+		if !ok {
+			return false
+		}
+
+		newNode, ok := e.ObjectNew.(*v1.Node)
+		if !ok {
+			return false
+		}
+
+		return oldNode.Status.NodeInfo.BootID != newNode.Status.NodeInfo.BootID
+	},
+}
+
 type Filter struct {
 	client client.Client
 	logger logr.Logger
@@ -79,7 +97,7 @@ func (f *Filter) ModuleReconcilerNodePredicate(kernelLabel string) predicate.Pre
 	return predicate.And(
 		skipDeletions,
 		HasLabel(kernelLabel),
-		predicate.LabelChangedPredicate{},
+		predicate.Or(predicate.LabelChangedPredicate{}, nodeRebooted),
 	)
 }
 
@@ -270,6 +288,25 @@ func (f *Filter) EnqueueAllPreflightValidations(mod client.Object) []reconcile.R
 	return reqs
 }
 
+// FindModuleDependencies returns a request for each Module that mod depends on, so that they can be reconciled when
+// their list of dependents changes.
+func (f *Filter) FindModuleDependencies(mod client.Object) []reconcile.Request {
+	m, ok := mod.(*kmmv1beta1.Module)
+	if !ok {
+		f.logger.Info("Unexpected object type; not enqueuing dependencies", "type", reflect.TypeOf(mod))
+		return nil
+	}
+
+	reqs := make([]reconcile.Request, 0, len(m.Spec.DependsOn))
+
+	for _, dep := range m.Spec.DependsOn {
+		nsn := types.NamespacedName{Name: dep, Namespace: m.Namespace}
+		reqs = append(reqs, reconcile.Request{NamespacedName: nsn})
+	}
+
+	return reqs
+}
+
 // DeletingPredicate returns a predicate that returns true if the object is being deleted.
 func DeletingPredicate() predicate.Predicate {
 	return predicate.NewPredicateFuncs(func(object client.Object) bool {
@@ -314,6 +351,49 @@ func PodReadinessChangedPredicate(logger logr.Logger) predicate.Predicate {
 	}
 }
 
+// PodContainerStatusChangedPredicate returns a predicate for Update events that only returns true if a container of
+// the pod restarted, or started or stopped waiting for another reason.
+func PodContainerStatusChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod, ok := e.ObjectOld.(*v1.Pod)
+			if !ok {
+				return true
+			}
+
+			newPod, ok := e.ObjectNew.(*v1.Pod)
+			if !ok {
+				return true
+			}
+
+			return !reflect.DeepEqual(containerStates(oldPod), containerStates(newPod))
+		},
+	}
+}
+
+type containerState struct {
+	name          string
+	restartCount  int32
+	waitingReason string
+}
+
+// containerStates returns the restart count and the waiting reason of each container of pod.
+func containerStates(pod *v1.Pod) []containerState {
+	states := make([]containerState, 0, len(pod.Status.ContainerStatuses))
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		st := containerState{name: cs.Name, restartCount: cs.RestartCount}
+
+		if cs.State.Waiting != nil {
+			st.waitingReason = cs.State.Waiting.Reason
+		}
+
+		states = append(states, st)
+	}
+
+	return states
+}
+
 func PreflightReconcilerUpdatePredicate() predicate.Predicate {
 	return predicate.GenerationChangedPredicate{}
 }
@@ -150,6 +150,27 @@ var _ = Describe("ModuleReconcilerNodePredicate", func() {
 			BeTrue(),
 		)
 	})
+	It("should return true for boot ID updates", func() {
+		labels := map[string]string{kernelLabel: "1.2.3"}
+
+		ev := event.UpdateEvent{
+			ObjectOld: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Status:     v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{BootID: "boot-1"}},
+			},
+			ObjectNew: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Status:     v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{BootID: "boot-2"}},
+			},
+		}
+
+		Expect(
+			p.Update(ev),
+		).To(
+			BeTrue(),
+		)
+	})
+
 	It("should return false for label updates without the expected label", func() {
 		ev := event.UpdateEvent{
 			ObjectOld: &v1.Node{
@@ -639,6 +660,45 @@ var _ = Describe("PodHasSpecNodeName", func() {
 	)
 })
 
+var _ = Describe("PodContainerStatusChangedPredicate", func() {
+	p := PodContainerStatusChangedPredicate()
+
+	podWithStatus := func(restartCount int32, waitingReason string) *v1.Pod {
+		cs := v1.ContainerStatus{Name: "module-loader", RestartCount: restartCount}
+
+		if waitingReason != "" {
+			cs.State.Waiting = &v1.ContainerStateWaiting{Reason: waitingReason}
+		}
+
+		return &v1.Pod{
+			Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{cs}},
+		}
+	}
+
+	DescribeTable(
+		"should return the expected value",
+		func(e event.UpdateEvent, expected bool) {
+			Expect(p.Update(e)).To(Equal(expected))
+		},
+		Entry("old object is not a Pod", event.UpdateEvent{ObjectOld: &v1.Node{}}, true),
+		Entry(
+			"same container statuses",
+			event.UpdateEvent{ObjectOld: podWithStatus(1, "CrashLoopBackOff"), ObjectNew: podWithStatus(1, "CrashLoopBackOff")},
+			false,
+		),
+		Entry(
+			"container restarted",
+			event.UpdateEvent{ObjectOld: podWithStatus(1, ""), ObjectNew: podWithStatus(2, "")},
+			true,
+		),
+		Entry(
+			"container waiting for another reason",
+			event.UpdateEvent{ObjectOld: podWithStatus(0, "ContainerCreating"), ObjectNew: podWithStatus(0, "ErrImagePull")},
+			true,
+		),
+	)
+})
+
 var _ = Describe("PodReadinessChangedPredicate", func() {
 	p := PodReadinessChangedPredicate(logr.Discard())
 
@@ -822,3 +882,36 @@ var _ = Describe("ImageStreamReconcilerPredicate", func() {
 		)
 	})
 })
+
+var _ = Describe("FindModuleDependencies", func() {
+	p := New(nil, logr.Discard())
+
+	It("should return nothing if the module has no dependencies", func() {
+		Expect(
+			p.FindModuleDependencies(&kmmv1beta1.Module{}),
+		).To(
+			BeEmpty(),
+		)
+	})
+
+	It("should return a request for each dependency in the module's namespace", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dependent",
+				Namespace: "namespace",
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				DependsOn: []string{"core", "other"},
+			},
+		}
+
+		Expect(
+			p.FindModuleDependencies(&mod),
+		).To(
+			Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "core", Namespace: "namespace"}},
+				{NamespacedName: types.NamespacedName{Name: "other", Namespace: "namespace"}},
+			}),
+		)
+	})
+})
@@ -0,0 +1,726 @@
+package loadjob
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/mitchellh/hashstructure"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+const (
+	JobTypeLoad   = "load"
+	JobTypeUnload = "unload"
+
+	// jobNodeStateAnnotation is set on load Jobs to the state recorded on the node once they complete.
+	jobNodeStateAnnotation = "kmm.node.kubernetes.io/node-state"
+
+	// unloadRetryInterval is how long a failed unload Job is kept before it is replaced by a new one.
+	unloadRetryInterval = 30 * time.Second
+)
+
+// NodeState is recorded in an annotation of the node once a load Job has loaded the kernel module.
+// It describes what the unload Job must unload.
+type NodeState struct {
+	// Namespace is the namespace of the Module.
+	Namespace string `json:"namespace"`
+
+	// BootID is the boot ID of the node when the kernel module was loaded.
+	// The state is ignored after the node is rebooted.
+	BootID string `json:"bootID"`
+
+	KernelVersion  string                  `json:"kernelVersion"`
+	ContainerImage string                  `json:"containerImage"`
+	Modprobe       kmmv1beta1.ModprobeSpec `json:"modprobe"`
+}
+
+// Result describes the kernel module on the nodes passed to Load.
+type Result struct {
+	// LoadedNodes are the nodes on which the current version of the kernel module is loaded.
+	LoadedNodes []string
+
+	// FailedNodes are the nodes on which the last load or unload Job failed.
+	FailedNodes []string
+
+	// Errors are the errors reported by the failed Jobs, keyed by node name.
+	Errors map[string]string
+}
+
+//go:generate mockgen -source=manager.go -package=loadjob -destination=mock_manager.go
+
+// Manager loads and unloads kernel modules with one-shot Jobs, for Modules using the Job load mode.
+type Manager interface {
+	Load(ctx context.Context, mld *api.ModuleLoaderData, nodes []v1.Node) (*Result, error)
+	Unload(ctx context.Context, mod *kmmv1beta1.Module, keep sets.String, dependents []string) ([]string, error)
+	UnloadNodes(ctx context.Context, mod *kmmv1beta1.Module, nodes []v1.Node, mldByNode map[string]*api.ModuleLoaderData, dependents []string) ([]string, error)
+}
+
+// nodeJobs are the Jobs of a Module on a node, keyed by Job type.
+type nodeJobs map[string]*batchv1.Job
+
+type manager struct {
+	client            client.Client
+	daemonAPI         daemonset.DaemonSetCreator
+	jobHelper         utils.JobHelper
+	scheme            *runtime.Scheme
+	operatorNamespace string
+}
+
+func NewManager(
+	client client.Client,
+	daemonAPI daemonset.DaemonSetCreator,
+	jobHelper utils.JobHelper,
+	scheme *runtime.Scheme,
+	operatorNamespace string) Manager {
+	return &manager{
+		client:            client,
+		daemonAPI:         daemonAPI,
+		jobHelper:         jobHelper,
+		scheme:            scheme,
+		operatorNamespace: operatorNamespace,
+	}
+}
+
+// Load makes sure that the kernel module of mld is loaded on each of nodes.
+// If a previous version of the kernel module is recorded on a node, it is unloaded before the new one is loaded.
+// The kernel module is only loaded on nodes where the Modules it depends on are ready.
+func (m *manager) Load(ctx context.Context, mld *api.ModuleLoaderData, nodes []v1.Node) (*Result, error) {
+	jobs, err := m.jobsByNode(ctx, mld.Name, mld.Namespace, mld.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	res := Result{
+		LoadedNodes: make([]string, 0),
+		FailedNodes: make([]string, 0),
+		Errors:      make(map[string]string),
+	}
+	errs := make([]error, 0)
+
+	for i := 0; i < len(nodes); i++ {
+		node := &nodes[i]
+
+		status, err := m.loadOnNode(ctx, mld, node, jobs[node.Name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", node.Name, err))
+			continue
+		}
+
+		switch status {
+		case utils.StatusCompleted:
+			res.LoadedNodes = append(res.LoadedNodes, node.Name)
+		case utils.StatusFailed:
+			res.FailedNodes = append(res.FailedNodes, node.Name)
+			res.Errors[node.Name] = m.jobError(ctx, jobs[node.Name])
+		}
+	}
+
+	return &res, utilerrors.NewAggregate(errs)
+}
+
+// Unload unloads the kernel module of mod from all the nodes on which it is recorded, except the ones in keep.
+// It returns the names of the nodes on which the kernel module may still be loaded.
+// The kernel module is not unloaded from nodes on which dependents are still ready.
+func (m *manager) Unload(ctx context.Context, mod *kmmv1beta1.Module, keep sets.String, dependents []string) ([]string, error) {
+	nodeList := v1.NodeList{}
+
+	if err := m.client.List(ctx, &nodeList); err != nil {
+		return nil, fmt.Errorf("could not list nodes: %v", err)
+	}
+
+	jobs, err := m.jobsByNode(ctx, mod.Name, mod.Namespace, mod)
+	if err != nil {
+		return nil, err
+	}
+
+	base := moduleLoaderData(mod, dependents)
+	nodeNames := sets.NewString()
+	remaining := make([]string, 0)
+	errs := make([]error, 0)
+
+	for i := 0; i < len(nodeList.Items); i++ {
+		node := &nodeList.Items[i]
+
+		nodeNames.Insert(node.Name)
+
+		if keep.Has(node.Name) {
+			continue
+		}
+
+		loaded, err := m.unloadFromNode(ctx, base, node, jobs[node.Name], nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", node.Name, err))
+		}
+
+		if loaded {
+			remaining = append(remaining, node.Name)
+		}
+	}
+
+	// The Jobs bound to deleted nodes will never run
+	for nodeName, nj := range jobs {
+		if nodeNames.Has(nodeName) {
+			continue
+		}
+
+		for _, job := range nj {
+			if err = m.deleteJob(ctx, job); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return remaining, utilerrors.NewAggregate(errs)
+}
+
+// UnloadNodes makes sure that the kernel module of a deleted Module is not loaded on nodes anymore, and removes the
+// labels of the Module from them once it is confirmed.
+// Nodes that carry the ready label without a recorded state, such as the ones on which a ModuleLoader pod ran, are
+// unloaded with their entry in mldByNode, as the pod may have failed to unload the kernel module.
+// It returns the names of the nodes on which the unload is not confirmed yet.
+func (m *manager) UnloadNodes(
+	ctx context.Context,
+	mod *kmmv1beta1.Module,
+	nodes []v1.Node,
+	mldByNode map[string]*api.ModuleLoaderData,
+	dependents []string) ([]string, error) {
+	jobs, err := m.jobsByNode(ctx, mod.Name, mod.Namespace, mod)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make([]string, 0)
+	errs := make([]error, 0)
+
+	for i := 0; i < len(nodes); i++ {
+		node := &nodes[i]
+
+		base := moduleLoaderData(mod, dependents)
+
+		var fallback *NodeState
+
+		_, hasState := node.Annotations[nodeStateAnnotation(mod.Name)]
+		_, isReady := node.Labels[daemonset.GetDriverContainerNodeLabel(mod.Name)]
+
+		if isReady && !hasState {
+			mld := mldByNode[node.Name]
+			if mld == nil {
+				errs = append(errs, fmt.Errorf("node %s: no kernel mapping to unload the kernel module with", node.Name))
+				remaining = append(remaining, node.Name)
+				continue
+			}
+
+			base = withDependents(mld, dependents)
+			fallback = &NodeState{
+				Namespace:      mod.Namespace,
+				BootID:         node.Status.NodeInfo.BootID,
+				KernelVersion:  mld.KernelVersion,
+				ContainerImage: mld.ContainerImage,
+				Modprobe:       mld.Modprobe,
+			}
+		}
+
+		loaded, err := m.unloadFromNode(ctx, base, node, jobs[node.Name], fallback)
+		if err == nil && !loaded {
+			// also removes the device plugin label
+			err = m.setNodeState(ctx, node, mod.Name, nil)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", node.Name, err))
+		}
+
+		if loaded || err != nil {
+			remaining = append(remaining, node.Name)
+		}
+	}
+
+	return remaining, utilerrors.NewAggregate(errs)
+}
+
+func (m *manager) loadOnNode(ctx context.Context, mld *api.ModuleLoaderData, node *v1.Node, jobs nodeJobs) (utils.Status, error) {
+	logger := log.FromContext(ctx).WithValues("node", node.Name)
+
+	st, err := m.collectJobs(ctx, mld.Name, node, jobs, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if st != nil {
+		if st.Namespace != mld.Namespace {
+			return "", fmt.Errorf("kernel module already loaded by Module %s/%s", st.Namespace, mld.Name)
+		}
+
+		if st.ContainerImage == mld.ContainerImage && reflect.DeepEqual(st.Modprobe, mld.Modprobe) {
+			// e.g. a failed unload Job for a version that is no longer wanted
+			for _, job := range jobs {
+				if isJobFinished(job) {
+					if err = m.deleteJob(ctx, job); err != nil {
+						return "", err
+					}
+				}
+			}
+
+			return utils.StatusCompleted, nil
+		}
+
+		if dep := loadedDependent(node, mld.Dependents); dep != "" {
+			logger.Info("Not unloading the previous kernel module: a dependent module is still loaded", "dependent", dep)
+			return utils.StatusInProgress, nil
+		}
+
+		logger.Info("Unloading the previous kernel module", "image", st.ContainerImage)
+
+		return m.syncJob(ctx, withState(mld, st, node), node, JobTypeUnload, jobs)
+	}
+
+	for _, dep := range mld.DependsOn {
+		if _, ok := node.Labels[daemonset.GetDriverContainerNodeLabel(dep)]; !ok {
+			logger.Info("Waiting for a dependency to be loaded", "dependency", dep)
+			return utils.StatusInProgress, nil
+		}
+	}
+
+	return m.syncJob(ctx, mld, node, JobTypeLoad, jobs)
+}
+
+// unloadFromNode unloads the kernel module recorded on node, or described by fallback if no state is recorded.
+// Failed unload Jobs are retried after unloadRetryInterval.
+// It returns true if the kernel module may still be loaded.
+func (m *manager) unloadFromNode(ctx context.Context, base *api.ModuleLoaderData, node *v1.Node, jobs nodeJobs, fallback *NodeState) (bool, error) {
+	logger := log.FromContext(ctx).WithValues("node", node.Name)
+
+	st, err := m.collectJobs(ctx, base.Name, node, jobs, fallback)
+	if err != nil {
+		return true, err
+	}
+
+	if st != nil && st.Namespace != base.Namespace {
+		return false, nil
+	}
+
+	if job := jobs[JobTypeLoad]; job != nil {
+		if isJobFinished(job) {
+			// failed; nothing was loaded
+			if err = m.deleteJob(ctx, job); err != nil {
+				return true, err
+			}
+		} else {
+			logger.Info("Waiting for the load Job to finish before unloading the kernel module", "job", job.Name)
+			return true, nil
+		}
+	}
+
+	if st == nil {
+		return false, nil
+	}
+
+	if dep := loadedDependent(node, base.Dependents); dep != "" {
+		logger.Info("Not unloading the kernel module: a dependent module is still loaded", "dependent", dep)
+		return true, nil
+	}
+
+	if job := jobs[JobTypeUnload]; job != nil && job.Status.Failed > 0 {
+		if wait := unloadRetryInterval - time.Since(jobFailureTime(job)); wait > 0 {
+			logger.Info(utils.WarnString("Unload Job failed; retrying later"), "name", job.Name, "retryIn", wait)
+			return true, nil
+		}
+
+		logger.Info("Replacing the failed unload Job", "name", job.Name)
+
+		if err = m.deleteJob(ctx, job); err != nil {
+			return true, err
+		}
+
+		delete(jobs, JobTypeUnload)
+	}
+
+	if _, err = m.syncJob(ctx, withState(base, st, node), node, JobTypeUnload, jobs); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// collectJobs records the outcome of the completed Jobs of node, deletes them, and returns the state of the node.
+// fallback is used if no state is recorded on the node.
+// The state recorded before the last reboot of the node is discarded, as the kernel module is not loaded anymore.
+func (m *manager) collectJobs(ctx context.Context, moduleName string, node *v1.Node, jobs nodeJobs, fallback *NodeState) (*NodeState, error) {
+	logger := log.FromContext(ctx).WithValues("node", node.Name)
+
+	st, err := nodeState(node, moduleName)
+	if err != nil {
+		return nil, err
+	}
+
+	if st == nil {
+		st = fallback
+	}
+
+	if job := jobs[JobTypeLoad]; job != nil && job.Status.Succeeded > 0 {
+		jobState := NodeState{}
+
+		if err = json.Unmarshal([]byte(job.Annotations[jobNodeStateAnnotation]), &jobState); err != nil {
+			return nil, fmt.Errorf("could not decode the node state of Job %s: %v", job.Name, err)
+		}
+
+		logger.Info("Kernel module loaded", "image", jobState.ContainerImage)
+
+		if err = m.setNodeState(ctx, node, moduleName, &jobState); err != nil {
+			return nil, err
+		}
+
+		st = &jobState
+
+		if err = m.deleteJob(ctx, job); err != nil {
+			return nil, err
+		}
+
+		delete(jobs, JobTypeLoad)
+	}
+
+	if job := jobs[JobTypeUnload]; job != nil && (job.Status.Succeeded > 0 || (st == nil && isJobFinished(job))) {
+		if job.Status.Succeeded > 0 {
+			logger.Info("Kernel module unloaded")
+
+			if err = m.setNodeState(ctx, node, moduleName, nil); err != nil {
+				return nil, err
+			}
+
+			st = nil
+		}
+
+		if err = m.deleteJob(ctx, job); err != nil {
+			return nil, err
+		}
+
+		delete(jobs, JobTypeUnload)
+	}
+
+	if st != nil && st.BootID != node.Status.NodeInfo.BootID {
+		logger.Info("Node rebooted since the kernel module was loaded; discarding its state")
+
+		if err = m.setNodeState(ctx, node, moduleName, nil); err != nil {
+			return nil, err
+		}
+
+		st = nil
+	}
+
+	return st, nil
+}
+
+// syncJob creates the Job of jobType for mld on node if it does not exist, and returns its status.
+// Failed Jobs are only replaced once their spec changes.
+func (m *manager) syncJob(ctx context.Context, mld *api.ModuleLoaderData, node *v1.Node, jobType string, jobs nodeJobs) (utils.Status, error) {
+	logger := log.FromContext(ctx).WithValues("node", node.Name, "type", jobType)
+
+	jobTemplate, err := m.makeJob(ctx, mld, node, jobType)
+	if err != nil {
+		return "", fmt.Errorf("could not make the %s Job template: %v", jobType, err)
+	}
+
+	job := jobs[jobType]
+	if job == nil {
+		logger.Info("Creating Job")
+
+		if err = m.jobHelper.CreateJob(ctx, jobTemplate); err != nil {
+			return "", fmt.Errorf("could not create the %s Job: %v", jobType, err)
+		}
+
+		return utils.StatusCreated, nil
+	}
+
+	if !isJobFinished(job) {
+		return utils.StatusInProgress, nil
+	}
+
+	changed, err := m.jobHelper.IsJobChanged(job, jobTemplate)
+	if err != nil {
+		return "", fmt.Errorf("could not determine if Job %s has changed: %v", job.Name, err)
+	}
+
+	if !changed {
+		logger.Info(utils.WarnString("Job failed; it is retried once the Module changes or once it is deleted"), "name", job.Name)
+		return utils.StatusFailed, nil
+	}
+
+	logger.Info("The failed Job is outdated; deleting it so that a new one can be created", "name", job.Name)
+
+	if err = m.deleteJob(ctx, job); err != nil {
+		return "", err
+	}
+
+	return utils.StatusInProgress, nil
+}
+
+func (m *manager) makeJob(ctx context.Context, mld *api.ModuleLoaderData, node *v1.Node, jobType string) (*batchv1.Job, error) {
+	template, err := m.daemonAPI.ModuleLoaderJobPodTemplate(ctx, mld, node.Name, jobType, mld.Namespace == m.operatorNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := hashstructure.Hash(template, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not hash the pod template: %v", err)
+	}
+
+	st, err := json.Marshal(NodeState{
+		Namespace:      mld.Namespace,
+		BootID:         node.Status.NodeInfo.BootID,
+		KernelVersion:  mld.KernelVersion,
+		ContainerImage: mld.ContainerImage,
+		Modprobe:       mld.Modprobe,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode the node state: %v", err)
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-%s-", mld.Name, jobType),
+			Namespace:    mld.Namespace,
+			Labels:       m.jobHelper.JobLabels(mld.Name, mld.KernelVersion, mld.Arch, jobType),
+			Annotations: map[string]string{
+				constants.JobHashAnnotation: fmt.Sprintf("%d", hash),
+				jobNodeStateAnnotation:      string(st),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			Completions:  pointer.Int32(1),
+			Template:     *template,
+			BackoffLimit: pointer.Int32(0),
+		},
+	}
+
+	if err = controllerutil.SetControllerReference(mld.Owner, job, m.scheme); err != nil {
+		return nil, fmt.Errorf("could not set the owner reference: %v", err)
+	}
+
+	return job, nil
+}
+
+// jobsByNode returns the load and unload Jobs of a Module, keyed by node name.
+// Only one Job of each type is expected per node; finished duplicates are deleted.
+func (m *manager) jobsByNode(ctx context.Context, name, namespace string, owner metav1.Object) (map[string]nodeJobs, error) {
+	jobsByNode := make(map[string]nodeJobs)
+
+	for _, jobType := range []string{JobTypeLoad, JobTypeUnload} {
+		jobs, err := m.jobHelper.GetModuleJobs(ctx, name, namespace, jobType, owner)
+		if err != nil {
+			return nil, fmt.Errorf("could not get the %s Jobs of module %s: %v", jobType, name, err)
+		}
+
+		for i := 0; i < len(jobs); i++ {
+			job := &jobs[i]
+			nodeName := job.Spec.Template.Spec.NodeName
+
+			if jobsByNode[nodeName] == nil {
+				jobsByNode[nodeName] = make(nodeJobs, 2)
+			}
+
+			if jobsByNode[nodeName][jobType] == nil {
+				jobsByNode[nodeName][jobType] = job
+				continue
+			}
+
+			if isJobFinished(job) {
+				if err = m.deleteJob(ctx, job); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return jobsByNode, nil
+}
+
+// jobError returns the error reported by the failed Job in jobs.
+// It is the termination message of the container of the Job's pod if it is still available.
+func (m *manager) jobError(ctx context.Context, jobs nodeJobs) string {
+	for _, jobType := range []string{JobTypeLoad, JobTypeUnload} {
+		job := jobs[jobType]
+		if job == nil || job.Status.Failed == 0 {
+			continue
+		}
+
+		podList := v1.PodList{}
+
+		opts := []client.ListOption{
+			client.InNamespace(job.Namespace),
+			client.MatchingLabels{"job-name": job.Name},
+		}
+
+		if err := m.client.List(ctx, &podList, opts...); err != nil {
+			log.FromContext(ctx).Info("Could not list the pods of the failed Job", "name", job.Name, "error", err)
+		}
+
+		for _, pod := range podList.Items {
+			for _, cs := range pod.Status.ContainerStatuses {
+				if t := cs.State.Terminated; t != nil && t.ExitCode != 0 && t.Message != "" {
+					return t.Message
+				}
+			}
+		}
+
+		for _, c := range job.Status.Conditions {
+			if c.Type == batchv1.JobFailed && c.Status == v1.ConditionTrue && c.Message != "" {
+				return fmt.Sprintf("%s Job %s failed: %s", jobType, job.Name, c.Message)
+			}
+		}
+
+		return fmt.Sprintf("%s Job %s failed", jobType, job.Name)
+	}
+
+	return ""
+}
+
+func (m *manager) deleteJob(ctx context.Context, job *batchv1.Job) error {
+	if err := m.jobHelper.DeleteJob(ctx, job); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("could not delete Job %s: %v", job.Name, err)
+	}
+
+	return nil
+}
+
+// setNodeState records st on node and labels it as ready, or removes both and the device plugin label if st is nil.
+func (m *manager) setNodeState(ctx context.Context, node *v1.Node, moduleName string, st *NodeState) error {
+	nodeCopy := node.DeepCopy()
+
+	annotationKey := nodeStateAnnotation(moduleName)
+	labelKey := daemonset.GetDriverContainerNodeLabel(moduleName)
+
+	if st == nil {
+		devicePluginLabelKey := daemonset.GetDevicePluginNodeLabel(moduleName)
+
+		_, hasAnnotation := node.Annotations[annotationKey]
+		_, hasLabel := node.Labels[labelKey]
+		_, hasDevicePluginLabel := node.Labels[devicePluginLabelKey]
+
+		if !hasAnnotation && !hasLabel && !hasDevicePluginLabel {
+			return nil
+		}
+
+		delete(node.Annotations, annotationKey)
+		delete(node.Labels, labelKey)
+		delete(node.Labels, devicePluginLabelKey)
+	} else {
+		b, err := json.Marshal(st)
+		if err != nil {
+			return fmt.Errorf("could not encode the node state: %v", err)
+		}
+
+		node.SetAnnotations(daemonset.OverrideLabels(node.GetAnnotations(), map[string]string{annotationKey: string(b)}))
+		node.SetLabels(daemonset.OverrideLabels(node.GetLabels(), map[string]string{labelKey: ""}))
+	}
+
+	if err := m.client.Patch(ctx, node, client.MergeFrom(nodeCopy)); err != nil {
+		return fmt.Errorf("could not patch node %s: %v", node.Name, err)
+	}
+
+	return nil
+}
+
+// nodeState returns the state of moduleName recorded on node, or nil if there is none.
+func nodeState(node *v1.Node, moduleName string) (*NodeState, error) {
+	v, ok := node.Annotations[nodeStateAnnotation(moduleName)]
+	if !ok {
+		return nil, nil
+	}
+
+	st := NodeState{}
+
+	if err := json.Unmarshal([]byte(v), &st); err != nil {
+		return nil, fmt.Errorf("could not decode the state of module %s: %v", moduleName, err)
+	}
+
+	return &st, nil
+}
+
+func nodeStateAnnotation(moduleName string) string {
+	return fmt.Sprintf("kmm.node.kubernetes.io/%s.loaded", moduleName)
+}
+
+// loadedDependent returns the first of dependents that is ready on node, or an empty string.
+func loadedDependent(node *v1.Node, dependents []string) string {
+	for _, dep := range dependents {
+		if _, ok := node.Labels[daemonset.GetDriverContainerNodeLabel(dep)]; ok {
+			return dep
+		}
+	}
+
+	return ""
+}
+
+func isJobFinished(job *batchv1.Job) bool {
+	return job.Status.Succeeded > 0 || job.Status.Failed > 0
+}
+
+// jobFailureTime returns when job failed, or when it was created if that is unknown.
+func jobFailureTime(job *batchv1.Job) time.Time {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == v1.ConditionTrue {
+			return c.LastTransitionTime.Time
+		}
+	}
+
+	return job.CreationTimestamp.Time
+}
+
+// moduleLoaderData returns the parts of the ModuleLoaderData of mod that do not depend on the kernel mapping.
+// It is used to unload the kernel module from nodes that no longer have a mapping.
+func moduleLoaderData(mod *kmmv1beta1.Module, dependents []string) *api.ModuleLoaderData {
+	return &api.ModuleLoaderData{
+		Name:               mod.Name,
+		Namespace:          mod.Namespace,
+		ImageRepoSecret:    mod.Spec.ImageRepoSecret,
+		ImagePullPolicy:    mod.Spec.ModuleLoader.Container.ImagePullPolicy,
+		ServiceAccountName: mod.Spec.ModuleLoader.ServiceAccountName,
+		Tolerations:        mod.Spec.ModuleLoader.Tolerations,
+		Volumes:            mod.Spec.ModuleLoader.Volumes,
+		VolumeMounts:       mod.Spec.ModuleLoader.Container.VolumeMounts,
+		Resources:          mod.Spec.ModuleLoader.Container.Resources,
+		Dependents:         dependents,
+		LoadMode:           mod.Spec.LoadMode,
+		Owner:              mod,
+	}
+}
+
+// withDependents returns a copy of mld with dependents.
+func withDependents(mld *api.ModuleLoaderData, dependents []string) *api.ModuleLoaderData {
+	mldCopy := *mld
+
+	mldCopy.Dependents = dependents
+
+	return &mldCopy
+}
+
+// withState returns a copy of mld describing the kernel module recorded in st.
+func withState(mld *api.ModuleLoaderData, st *NodeState, node *v1.Node) *api.ModuleLoaderData {
+	stateMLD := *mld
+
+	stateMLD.Arch = node.Status.NodeInfo.Architecture
+	stateMLD.KernelVersion = st.KernelVersion
+	stateMLD.ContainerImage = st.ContainerImage
+	stateMLD.Modprobe = st.Modprobe
+
+	return &stateMLD
+}
@@ -0,0 +1,558 @@
+package loadjob
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	moduleName = "test-module"
+	namespace  = "test-namespace"
+	nodeName   = "node1"
+	bootID     = "boot-id"
+	newImage   = "new-image"
+	oldImage   = "old-image"
+)
+
+func stateAnnotations(st NodeState) map[string]string {
+	b, err := json.Marshal(st)
+	Expect(err).NotTo(HaveOccurred())
+
+	return map[string]string{nodeStateAnnotation(moduleName): string(b)}
+}
+
+func jobOnNode(name, node string) batchv1.Job {
+	return batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: batchv1.JobSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{NodeName: node},
+			},
+		},
+	}
+}
+
+var _ = Describe("Load", func() {
+	var (
+		ctrl     *gomock.Controller
+		clnt     *client.MockClient
+		mockDC   *daemonset.MockDaemonSetCreator
+		mockJH   *utils.MockJobHelper
+		m        Manager
+		mod      *kmmv1beta1.Module
+		mld      *api.ModuleLoaderData
+		node     v1.Node
+		curState NodeState
+	)
+
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		mockDC = daemonset.NewMockDaemonSetCreator(ctrl)
+		mockJH = utils.NewMockJobHelper(ctrl)
+		m = NewManager(clnt, mockDC, mockJH, scheme, "operator-namespace")
+
+		mod = &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName, Namespace: namespace},
+		}
+
+		mld = &api.ModuleLoaderData{
+			Name:           moduleName,
+			Namespace:      namespace,
+			KernelVersion:  "kernel-version",
+			ContainerImage: newImage,
+			Modprobe:       kmmv1beta1.ModprobeSpec{ModuleName: "test"},
+			LoadMode:       kmmv1beta1.LoadModeJob,
+			Owner:          mod,
+		}
+
+		node = v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Status: v1.NodeStatus{
+				NodeInfo: v1.NodeSystemInfo{BootID: bootID},
+			},
+		}
+
+		curState = NodeState{
+			Namespace:      namespace,
+			BootID:         bootID,
+			KernelVersion:  mld.KernelVersion,
+			ContainerImage: newImage,
+			Modprobe:       mld.Modprobe,
+		}
+	})
+
+	expectJobs := func(load, unload []batchv1.Job) {
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeLoad, mod).Return(load, nil)
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeUnload, mod).Return(unload, nil)
+	}
+
+	expectJobTemplate := func(jobType, image string) {
+		mockDC.
+			EXPECT().
+			ModuleLoaderJobPodTemplate(ctx, gomock.Any(), nodeName, jobType, false).
+			DoAndReturn(func(_ context.Context, mld *api.ModuleLoaderData, _, _ string, _ bool) (*v1.PodTemplateSpec, error) {
+				Expect(mld.ContainerImage).To(Equal(image))
+				return &v1.PodTemplateSpec{}, nil
+			})
+		mockJH.EXPECT().JobLabels(moduleName, gomock.Any(), gomock.Any(), jobType)
+	}
+
+	It("should create a load Job if the module is not loaded", func() {
+		expectJobs(nil, nil)
+		expectJobTemplate(JobTypeLoad, newImage)
+		mockJH.
+			EXPECT().
+			CreateJob(ctx, gomock.Any()).
+			Do(func(_ context.Context, job *batchv1.Job) {
+				st := NodeState{}
+				Expect(json.Unmarshal([]byte(job.Annotations[jobNodeStateAnnotation]), &st)).To(Succeed())
+				Expect(st).To(Equal(curState))
+				Expect(job.OwnerReferences).To(HaveLen(1))
+			})
+
+		res, err := m.Load(ctx, mld, []v1.Node{node})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(&Result{LoadedNodes: []string{}, FailedNodes: []string{}, Errors: map[string]string{}}))
+	})
+
+	It("should wait for the modules it depends on", func() {
+		mld.DependsOn = []string{"dependency"}
+
+		expectJobs(nil, nil)
+
+		res, err := m.Load(ctx, mld, []v1.Node{node})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.LoadedNodes).To(BeEmpty())
+	})
+
+	It("should record the state of a completed load Job on the node", func() {
+		job := jobOnNode("load-job", nodeName)
+		job.Annotations = map[string]string{}
+		job.Annotations[jobNodeStateAnnotation] = stateAnnotations(curState)[nodeStateAnnotation(moduleName)]
+		job.Status.Succeeded = 1
+
+		expectJobs([]batchv1.Job{job}, nil)
+
+		gomock.InOrder(
+			clnt.
+				EXPECT().
+				Patch(ctx, gomock.Any(), gomock.Any()).
+				Do(func(_ context.Context, n *v1.Node, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+					Expect(n.Annotations).To(Equal(stateAnnotations(curState)))
+					Expect(n.Labels).To(HaveKey(daemonset.GetDriverContainerNodeLabel(moduleName)))
+				}),
+			mockJH.EXPECT().DeleteJob(ctx, gomock.Any()),
+		)
+
+		res, err := m.Load(ctx, mld, []v1.Node{node})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.LoadedNodes).To(Equal([]string{nodeName}))
+	})
+
+	It("should unload the previous version of the module first", func() {
+		oldState := curState
+		oldState.ContainerImage = oldImage
+		node.Annotations = stateAnnotations(oldState)
+
+		expectJobs(nil, nil)
+		expectJobTemplate(JobTypeUnload, oldImage)
+		mockJH.EXPECT().CreateJob(ctx, gomock.Any())
+
+		res, err := m.Load(ctx, mld, []v1.Node{node})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.LoadedNodes).To(BeEmpty())
+	})
+
+	It("should not unload the previous version of the module while a dependent is loaded", func() {
+		oldState := curState
+		oldState.ContainerImage = oldImage
+		node.Annotations = stateAnnotations(oldState)
+		node.Labels = map[string]string{daemonset.GetDriverContainerNodeLabel("dependent"): ""}
+		mld.Dependents = []string{"dependent"}
+
+		expectJobs(nil, nil)
+
+		res, err := m.Load(ctx, mld, []v1.Node{node})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.LoadedNodes).To(BeEmpty())
+	})
+
+	It("should discard the state recorded before the node was rebooted", func() {
+		oldState := curState
+		oldState.BootID = "previous-boot-id"
+		node.Annotations = stateAnnotations(oldState)
+		node.Labels = map[string]string{daemonset.GetDriverContainerNodeLabel(moduleName): ""}
+
+		expectJobs(nil, nil)
+
+		gomock.InOrder(
+			clnt.
+				EXPECT().
+				Patch(ctx, gomock.Any(), gomock.Any()).
+				Do(func(_ context.Context, n *v1.Node, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+					Expect(n.Annotations).To(BeEmpty())
+					Expect(n.Labels).To(BeEmpty())
+				}),
+			mockDC.EXPECT().ModuleLoaderJobPodTemplate(ctx, gomock.Any(), nodeName, JobTypeLoad, false).Return(&v1.PodTemplateSpec{}, nil),
+			mockJH.EXPECT().JobLabels(moduleName, gomock.Any(), gomock.Any(), JobTypeLoad),
+			mockJH.EXPECT().CreateJob(ctx, gomock.Any()),
+		)
+
+		_, err := m.Load(ctx, mld, []v1.Node{node})
+
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should report nodes on which the load Job failed", func() {
+		job := jobOnNode("load-job", nodeName)
+		job.Status.Failed = 1
+
+		expectJobs([]batchv1.Job{job}, nil)
+		expectJobTemplate(JobTypeLoad, newImage)
+		mockJH.EXPECT().IsJobChanged(&job, gomock.Any()).Return(false, nil)
+		clnt.
+			EXPECT().
+			List(ctx, &v1.PodList{}, ctrlclient.InNamespace(job.Namespace), ctrlclient.MatchingLabels{"job-name": job.Name}).
+			DoAndReturn(func(_ context.Context, list *v1.PodList, _ ...ctrlclient.ListOption) error {
+				list.Items = []v1.Pod{
+					{
+						Status: v1.PodStatus{
+							ContainerStatuses: []v1.ContainerStatus{
+								{
+									State: v1.ContainerState{
+										Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Message: "modprobe: FATAL"},
+									},
+								},
+							},
+						},
+					},
+				}
+				return nil
+			})
+
+		res, err := m.Load(ctx, mld, []v1.Node{node})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.FailedNodes).To(Equal([]string{nodeName}))
+		Expect(res.Errors).To(Equal(map[string]string{nodeName: "modprobe: FATAL"}))
+	})
+
+	It("should report the failure condition of the Job if its pod is gone", func() {
+		job := jobOnNode("load-job", nodeName)
+		job.Status.Failed = 1
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Message: "Job has reached the specified backoff limit"},
+		}
+
+		expectJobs([]batchv1.Job{job}, nil)
+		expectJobTemplate(JobTypeLoad, newImage)
+		mockJH.EXPECT().IsJobChanged(&job, gomock.Any()).Return(false, nil)
+		clnt.EXPECT().List(ctx, &v1.PodList{}, gomock.Any(), gomock.Any())
+
+		res, err := m.Load(ctx, mld, []v1.Node{node})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Errors).To(Equal(map[string]string{
+			nodeName: "load Job load-job failed: Job has reached the specified backoff limit",
+		}))
+	})
+
+	It("should return an error if another Module with the same name loaded the kernel module", func() {
+		otherState := curState
+		otherState.Namespace = "other-namespace"
+		node.Annotations = stateAnnotations(otherState)
+
+		expectJobs(nil, nil)
+
+		_, err := m.Load(ctx, mld, []v1.Node{node})
+
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Unload", func() {
+	var (
+		ctrl   *gomock.Controller
+		clnt   *client.MockClient
+		mockDC *daemonset.MockDaemonSetCreator
+		mockJH *utils.MockJobHelper
+		m      Manager
+		mod    *kmmv1beta1.Module
+		st     NodeState
+	)
+
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		mockDC = daemonset.NewMockDaemonSetCreator(ctrl)
+		mockJH = utils.NewMockJobHelper(ctrl)
+		m = NewManager(clnt, mockDC, mockJH, scheme, "operator-namespace")
+
+		mod = &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName, Namespace: namespace},
+		}
+
+		st = NodeState{
+			Namespace:      namespace,
+			BootID:         bootID,
+			KernelVersion:  "kernel-version",
+			ContainerImage: oldImage,
+		}
+	})
+
+	loadedNode := func(name string) v1.Node {
+		return v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: stateAnnotations(st),
+				Labels:      map[string]string{daemonset.GetDriverContainerNodeLabel(moduleName): ""},
+			},
+			Status: v1.NodeStatus{
+				NodeInfo: v1.NodeSystemInfo{BootID: bootID},
+			},
+		}
+	}
+
+	expectNodes := func(nodes ...v1.Node) {
+		clnt.EXPECT().List(ctx, &v1.NodeList{}).DoAndReturn(
+			func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+				list.Items = nodes
+				return nil
+			},
+		)
+	}
+
+	It("should unload the kernel module from the nodes that are not kept", func() {
+		deletedNodeJob := jobOnNode("deleted-node-job", "deleted-node")
+
+		expectNodes(loadedNode("kept-node"), loadedNode(nodeName), v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "other-node"}})
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeLoad, mod).Return([]batchv1.Job{deletedNodeJob}, nil)
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeUnload, mod).Return(nil, nil)
+		mockDC.
+			EXPECT().
+			ModuleLoaderJobPodTemplate(ctx, gomock.Any(), nodeName, JobTypeUnload, false).
+			DoAndReturn(func(_ context.Context, mld *api.ModuleLoaderData, _, _ string, _ bool) (*v1.PodTemplateSpec, error) {
+				Expect(mld.ContainerImage).To(Equal(oldImage))
+				Expect(mld.KernelVersion).To(Equal("kernel-version"))
+				return &v1.PodTemplateSpec{}, nil
+			})
+		mockJH.EXPECT().JobLabels(moduleName, "kernel-version", gomock.Any(), JobTypeUnload)
+		mockJH.EXPECT().CreateJob(ctx, gomock.Any())
+		mockJH.EXPECT().DeleteJob(ctx, &deletedNodeJob)
+
+		remaining, err := m.Unload(ctx, mod, sets.NewString("kept-node"), nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remaining).To(Equal([]string{nodeName}))
+	})
+
+	It("should clear the state of the node once the unload Job succeeded", func() {
+		job := jobOnNode("unload-job", nodeName)
+		job.Status.Succeeded = 1
+
+		expectNodes(loadedNode(nodeName))
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeLoad, mod).Return(nil, nil)
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeUnload, mod).Return([]batchv1.Job{job}, nil)
+		gomock.InOrder(
+			clnt.
+				EXPECT().
+				Patch(ctx, gomock.Any(), gomock.Any()).
+				Do(func(_ context.Context, n *v1.Node, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+					Expect(n.Annotations).To(BeEmpty())
+					Expect(n.Labels).To(BeEmpty())
+				}),
+			mockJH.EXPECT().DeleteJob(ctx, gomock.Any()),
+		)
+
+		remaining, err := m.Unload(ctx, mod, sets.NewString(), nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remaining).To(BeEmpty())
+	})
+
+	It("should not unload the kernel module while a dependent is loaded", func() {
+		node := loadedNode(nodeName)
+		node.Labels[daemonset.GetDriverContainerNodeLabel("dependent")] = ""
+
+		expectNodes(node)
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeLoad, mod).Return(nil, nil)
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeUnload, mod).Return(nil, nil)
+
+		remaining, err := m.Unload(ctx, mod, sets.NewString(), []string{"dependent"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remaining).To(Equal([]string{nodeName}))
+	})
+})
+
+var _ = Describe("UnloadNodes", func() {
+	var (
+		ctrl   *gomock.Controller
+		clnt   *client.MockClient
+		mockDC *daemonset.MockDaemonSetCreator
+		mockJH *utils.MockJobHelper
+		m      Manager
+		mod    *kmmv1beta1.Module
+		mld    *api.ModuleLoaderData
+		node   v1.Node
+	)
+
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		mockDC = daemonset.NewMockDaemonSetCreator(ctrl)
+		mockJH = utils.NewMockJobHelper(ctrl)
+		m = NewManager(clnt, mockDC, mockJH, scheme, "operator-namespace")
+
+		mod = &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName, Namespace: namespace},
+		}
+
+		mld = &api.ModuleLoaderData{
+			Name:           moduleName,
+			Namespace:      namespace,
+			KernelVersion:  "kernel-version",
+			ContainerImage: newImage,
+			Owner:          mod,
+		}
+
+		node = v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: nodeName,
+				Labels: map[string]string{
+					daemonset.GetDriverContainerNodeLabel(moduleName): "",
+					daemonset.GetDevicePluginNodeLabel(moduleName):    "",
+				},
+			},
+			Status: v1.NodeStatus{
+				NodeInfo: v1.NodeSystemInfo{BootID: bootID},
+			},
+		}
+	})
+
+	expectJobs := func(unload []batchv1.Job) {
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeLoad, mod).Return(nil, nil)
+		mockJH.EXPECT().GetModuleJobs(ctx, moduleName, namespace, JobTypeUnload, mod).Return(unload, nil)
+	}
+
+	It("should create an unload Job for ready nodes without a recorded state", func() {
+		expectJobs(nil)
+		mockDC.
+			EXPECT().
+			ModuleLoaderJobPodTemplate(ctx, gomock.Any(), nodeName, JobTypeUnload, false).
+			DoAndReturn(func(_ context.Context, mld *api.ModuleLoaderData, _, _ string, _ bool) (*v1.PodTemplateSpec, error) {
+				Expect(mld.ContainerImage).To(Equal(newImage))
+				Expect(mld.Dependents).To(Equal([]string{"dependent"}))
+				return &v1.PodTemplateSpec{}, nil
+			})
+		mockJH.EXPECT().JobLabels(moduleName, "kernel-version", gomock.Any(), JobTypeUnload)
+		mockJH.EXPECT().CreateJob(ctx, gomock.Any())
+
+		remaining, err := m.UnloadNodes(ctx, mod, []v1.Node{node}, map[string]*api.ModuleLoaderData{nodeName: mld}, []string{"dependent"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remaining).To(Equal([]string{nodeName}))
+	})
+
+	It("should remove all the labels once the unload Job succeeded", func() {
+		job := jobOnNode("unload-job", nodeName)
+		job.Status.Succeeded = 1
+
+		expectJobs([]batchv1.Job{job})
+		gomock.InOrder(
+			clnt.
+				EXPECT().
+				Patch(ctx, gomock.Any(), gomock.Any()).
+				Do(func(_ context.Context, n *v1.Node, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+					Expect(n.Labels).To(BeEmpty())
+				}),
+			mockJH.EXPECT().DeleteJob(ctx, gomock.Any()),
+		)
+
+		remaining, err := m.UnloadNodes(ctx, mod, []v1.Node{node}, map[string]*api.ModuleLoaderData{nodeName: mld}, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remaining).To(BeEmpty())
+	})
+
+	It("should remove the device plugin label from nodes on which the module is not loaded", func() {
+		delete(node.Labels, daemonset.GetDriverContainerNodeLabel(moduleName))
+
+		expectJobs(nil)
+		clnt.EXPECT().Patch(ctx, gomock.Any(), gomock.Any())
+
+		remaining, err := m.UnloadNodes(ctx, mod, []v1.Node{node}, nil, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remaining).To(BeEmpty())
+		Expect(node.Labels).To(BeEmpty())
+	})
+
+	It("should return an error if a ready node has neither a recorded state nor a kernel mapping", func() {
+		expectJobs(nil)
+
+		remaining, err := m.UnloadNodes(ctx, mod, []v1.Node{node}, nil, nil)
+
+		Expect(err).To(HaveOccurred())
+		Expect(remaining).To(Equal([]string{nodeName}))
+	})
+
+	DescribeTable("should retry failed unload Jobs",
+		func(failedAgo time.Duration, retried bool) {
+			job := jobOnNode("unload-job", nodeName)
+			job.Status.Failed = 1
+			job.Status.Conditions = []batchv1.JobCondition{
+				{
+					Type:               batchv1.JobFailed,
+					Status:             v1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-failedAgo)),
+				},
+			}
+
+			expectJobs([]batchv1.Job{job})
+
+			if retried {
+				gomock.InOrder(
+					mockJH.EXPECT().DeleteJob(ctx, gomock.Any()),
+					mockDC.EXPECT().ModuleLoaderJobPodTemplate(ctx, gomock.Any(), nodeName, JobTypeUnload, false).Return(&v1.PodTemplateSpec{}, nil),
+					mockJH.EXPECT().JobLabels(moduleName, "kernel-version", gomock.Any(), JobTypeUnload),
+					mockJH.EXPECT().CreateJob(ctx, gomock.Any()),
+				)
+			}
+
+			remaining, err := m.UnloadNodes(ctx, mod, []v1.Node{node}, map[string]*api.ModuleLoaderData{nodeName: mld}, nil)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remaining).To(Equal([]string{nodeName}))
+		},
+		Entry("failed recently", time.Second, false),
+		Entry("failed before the retry interval", time.Hour, true),
+	)
+})
@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: manager.go
+
+// Package loadjob is a generated GoMock package.
+package loadjob
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	api "github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	v1 "k8s.io/api/core/v1"
+	sets "k8s.io/apimachinery/pkg/util/sets"
+)
+
+// MockManager is a mock of Manager interface.
+type MockManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockManagerMockRecorder
+}
+
+// MockManagerMockRecorder is the mock recorder for MockManager.
+type MockManagerMockRecorder struct {
+	mock *MockManager
+}
+
+// NewMockManager creates a new mock instance.
+func NewMockManager(ctrl *gomock.Controller) *MockManager {
+	mock := &MockManager{ctrl: ctrl}
+	mock.recorder = &MockManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockManager) EXPECT() *MockManagerMockRecorder {
+	return m.recorder
+}
+
+// Load mocks base method.
+func (m *MockManager) Load(ctx context.Context, mld *api.ModuleLoaderData, nodes []v1.Node) (*Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Load", ctx, mld, nodes)
+	ret0, _ := ret[0].(*Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Load indicates an expected call of Load.
+func (mr *MockManagerMockRecorder) Load(ctx, mld, nodes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockManager)(nil).Load), ctx, mld, nodes)
+}
+
+// Unload mocks base method.
+func (m *MockManager) Unload(ctx context.Context, mod *v1beta1.Module, keep sets.String, dependents []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unload", ctx, mod, keep, dependents)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Unload indicates an expected call of Unload.
+func (mr *MockManagerMockRecorder) Unload(ctx, mod, keep, dependents interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unload", reflect.TypeOf((*MockManager)(nil).Unload), ctx, mod, keep, dependents)
+}
+
+// UnloadNodes mocks base method.
+func (m *MockManager) UnloadNodes(ctx context.Context, mod *v1beta1.Module, nodes []v1.Node, mldByNode map[string]*api.ModuleLoaderData, dependents []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnloadNodes", ctx, mod, nodes, mldByNode, dependents)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnloadNodes indicates an expected call of UnloadNodes.
+func (mr *MockManagerMockRecorder) UnloadNodes(ctx, mod, nodes, mldByNode, dependents interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnloadNodes", reflect.TypeOf((*MockManager)(nil).UnloadNodes), ctx, mod, nodes, mldByNode, dependents)
+}
@@ -0,0 +1,306 @@
+package machineconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/worker"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// RoleLabel selects the MachineConfigPool a MachineConfig applies to.
+	RoleLabel = "machineconfiguration.openshift.io/role"
+
+	// ModuleNamespaceLabel is the namespace of the Module a MachineConfig was rendered for.
+	// MachineConfigs are cluster-scoped and cannot be owned by Modules.
+	ModuleNamespaceLabel = "kmm.node.kubernetes.io/module.namespace"
+
+	ignitionVersion = "3.2.0"
+
+	// authFile is the pull secret of kubelet; the pull secret of the Module is not available before the node joins
+	// the cluster.
+	authFile = "/var/lib/kubelet/config.json"
+
+	modprobeConfigDir = "/run/kmm"
+)
+
+var gvk = schema.GroupVersionKind{
+	Group:   "machineconfiguration.openshift.io",
+	Version: "v1",
+	Kind:    "MachineConfig",
+}
+
+// nodeArchToMachine maps the architectures reported by nodes to the machine hardware names returned by uname -m.
+var nodeArchToMachine = map[string]string{
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+//go:generate mockgen -source=machineconfig.go -package=machineconfig -destination=mock_machineconfig.go
+
+// Manager renders and applies the MachineConfig that loads the kernel module of a Module when the nodes boot.
+type Manager interface {
+	Sync(ctx context.Context, mod *kmmv1beta1.Module, mlds []*api.ModuleLoaderData) error
+	Delete(ctx context.Context, mod *kmmv1beta1.Module) error
+}
+
+type manager struct {
+	client client.Client
+}
+
+func NewManager(client client.Client) Manager {
+	return &manager{client: client}
+}
+
+// Name returns the name of the MachineConfig of a Module.
+// It does not depend on the MachineConfigPool, so that changing the pool replaces the MachineConfig.
+func Name(namespace, name string) string {
+	return fmt.Sprintf("99-kmm-%s-%s", namespace, name)
+}
+
+// Sync creates or updates the MachineConfig of the Module with a systemd unit loading the kernel module from the
+// image of each ModuleLoaderData in mlds, which must be built, signed and pinned already.
+// The MachineConfig is left untouched if mlds is empty, so that a kernel version being rebuilt does not make the
+// Machine Config Operator reboot the nodes twice.
+// It is deleted if the Module does not enable boot loading.
+func (m *manager) Sync(ctx context.Context, mod *kmmv1beta1.Module, mlds []*api.ModuleLoaderData) error {
+	if mod.Spec.BootLoading == nil {
+		return m.Delete(ctx, mod)
+	}
+
+	if len(mlds) == 0 {
+		return nil
+	}
+
+	config, err := renderIgnitionConfig(mod, mlds)
+	if err != nil {
+		return fmt.Errorf("could not render the Ignition configuration: %v", err)
+	}
+
+	mc := newMachineConfig(mod)
+
+	_, err = controllerutil.CreateOrPatch(ctx, m.client, mc, func() error {
+		labels := mc.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+
+		labels[RoleLabel] = mod.Spec.BootLoading.MachineConfigPool
+		labels[constants.ModuleNameLabel] = mod.Name
+		labels[ModuleNamespaceLabel] = mod.Namespace
+
+		mc.SetLabels(labels)
+
+		return unstructured.SetNestedMap(mc.Object, config, "spec", "config")
+	})
+	if err != nil {
+		return fmt.Errorf("could not create or patch MachineConfig %s: %v", mc.GetName(), err)
+	}
+
+	return nil
+}
+
+// Delete deletes the MachineConfig of the Module, if any.
+// Clusters without MachineConfigs are ignored.
+func (m *manager) Delete(ctx context.Context, mod *kmmv1beta1.Module) error {
+	mc := newMachineConfig(mod)
+
+	if err := m.client.Delete(ctx, mc); err != nil && !k8serrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return fmt.Errorf("could not delete MachineConfig %s: %v", mc.GetName(), err)
+	}
+
+	return nil
+}
+
+func newMachineConfig(mod *kmmv1beta1.Module) *unstructured.Unstructured {
+	mc := &unstructured.Unstructured{}
+	mc.SetGroupVersionKind(gvk)
+	mc.SetName(Name(mod.Namespace, mod.Name))
+
+	return mc
+}
+
+type ignitionConfig struct {
+	Ignition ignition `json:"ignition"`
+	Storage  storage  `json:"storage"`
+	Systemd  systemd  `json:"systemd"`
+}
+
+type ignition struct {
+	Version string `json:"version"`
+}
+
+type storage struct {
+	Files []file `json:"files"`
+}
+
+type file struct {
+	Path      string       `json:"path"`
+	Mode      int          `json:"mode"`
+	Overwrite bool         `json:"overwrite"`
+	Contents  fileContents `json:"contents"`
+}
+
+type fileContents struct {
+	Source string `json:"source"`
+}
+
+type systemd struct {
+	Units []unit `json:"units"`
+}
+
+type unit struct {
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled"`
+	Contents string `json:"contents"`
+}
+
+// renderIgnitionConfig returns the Ignition configuration installing the load script of the Module and the systemd
+// unit running it, as an unstructured map.
+func renderIgnitionConfig(mod *kmmv1beta1.Module, mlds []*api.ModuleLoaderData) (map[string]interface{}, error) {
+	baseName := fmt.Sprintf("kmm-%s-%s", mod.Namespace, mod.Name)
+	scriptPath := "/usr/local/bin/" + baseName + "-load"
+
+	cfg := ignitionConfig{
+		Ignition: ignition{Version: ignitionVersion},
+		Storage: storage{
+			Files: []file{
+				{
+					Path:      scriptPath,
+					Mode:      0755,
+					Overwrite: true,
+					Contents: fileContents{
+						Source: "data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte(loadScript(mod, mlds))),
+					},
+				},
+			},
+		},
+		Systemd: systemd{
+			Units: []unit{
+				{
+					Name:     baseName + ".service",
+					Enabled:  true,
+					Contents: loadUnit(mod, scriptPath),
+				},
+			},
+		},
+	}
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// loadUnit returns the systemd unit running the load script before kubelet starts.
+func loadUnit(mod *kmmv1beta1.Module, scriptPath string) string {
+	return fmt.Sprintf(`[Unit]
+Description=Load the kernel module of KMM Module %s/%s
+Wants=network-online.target
+After=network-online.target
+Before=kubelet.service
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=%s
+
+[Install]
+WantedBy=multi-user.target
+`, mod.Namespace, mod.Name, scriptPath)
+}
+
+// loadScript returns the script pulling the image built for the running kernel and loading the kernel module from it.
+// Kernels without an image are skipped: the module loader DaemonSet loads the kernel module once the node joins the
+// cluster.
+func loadScript(mod *kmmv1beta1.Module, mlds []*api.ModuleLoaderData) string {
+	sorted := make([]*api.ModuleLoaderData, len(mlds))
+	copy(sorted, mlds)
+
+	// a stable rendering prevents needless reboots of the pool
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].KernelVersion != sorted[j].KernelVersion {
+			return sorted[i].KernelVersion < sorted[j].KernelVersion
+		}
+
+		return sorted[i].Arch < sorted[j].Arch
+	})
+
+	sb := strings.Builder{}
+
+	fmt.Fprintf(&sb, `#!/bin/bash
+# Generated by KMM for Module %s/%s
+set -euo pipefail
+
+case "$(uname -r)/$(uname -m)" in
+`, mod.Namespace, mod.Name)
+
+	for _, mld := range sorted {
+		machine := "*"
+		if m, ok := nodeArchToMachine[mld.Arch]; ok {
+			machine = m
+		}
+
+		loadArgs := worker.LoadArgs(mld.Modprobe)
+		quotedArgs := make([]string, 0, len(loadArgs))
+
+		for _, a := range loadArgs {
+			quotedArgs = append(quotedArgs, shellQuote(a))
+		}
+
+		fmt.Fprintf(&sb, "  %s/%s)\n", shellQuote(mld.KernelVersion), machine)
+		fmt.Fprintf(&sb, "    image=%s\n", shellQuote(mld.ContainerImage))
+		fmt.Fprintf(&sb, "    modprobe_config=%s\n", shellQuote(worker.ModprobeConfig(mld.Modprobe)))
+		fmt.Fprintf(&sb, "    load_args=(%s)\n", strings.Join(quotedArgs, " "))
+		sb.WriteString("    ;;\n")
+	}
+
+	configPath := fmt.Sprintf("%s/%s_%s.conf", modprobeConfigDir, mod.Namespace, mod.Name)
+
+	fmt.Fprintf(&sb, `  *)
+    echo "No image for kernel $(uname -r); the kernel module will be loaded once the node joins the cluster"
+    exit 0
+    ;;
+esac
+
+podman_args=(--rm --privileged --authfile %[1]s --entrypoint modprobe)
+
+if [ -n "$modprobe_config" ]; then
+  mkdir -p %[2]s
+  printf '%%s' "$modprobe_config" > %[3]s
+  podman_args+=(-v %[3]s:/etc/modprobe.d/kmm.conf:ro)
+fi
+
+podman run "${podman_args[@]}" "$image" "${load_args[@]}"
+`, authFile, modprobeConfigDir, configPath)
+
+	return sb.String()
+}
+
+// shellQuote quotes s for bash.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,230 @@
+package machineconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	moduleName = "test-module"
+	namespace  = "test-namespace"
+)
+
+var _ = Describe("Sync", func() {
+	var (
+		ctrl *gomock.Controller
+		clnt *client.MockClient
+		m    Manager
+		mod  *kmmv1beta1.Module
+		mlds []*api.ModuleLoaderData
+	)
+
+	ctx := context.Background()
+	notFound := k8serrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: "machineconfigs"}, "whatever")
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		m = NewManager(clnt)
+
+		mod = &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName, Namespace: namespace},
+			Spec: kmmv1beta1.ModuleSpec{
+				BootLoading: &kmmv1beta1.BootLoadingSpec{MachineConfigPool: "worker"},
+			},
+		}
+
+		mlds = []*api.ModuleLoaderData{
+			{
+				Name:           moduleName,
+				Namespace:      namespace,
+				KernelVersion:  "5.14.0-284.el9.x86_64",
+				Arch:           "amd64",
+				ContainerImage: "quay.io/org/kmod@sha256:1234",
+				Modprobe:       kmmv1beta1.ModprobeSpec{ModuleName: "kmod", DirName: "/opt"},
+			},
+		}
+	})
+
+	It("should delete the MachineConfig if the Module does not enable boot loading", func() {
+		mod.Spec.BootLoading = nil
+
+		clnt.EXPECT().Delete(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj ctrlclient.Object, _ ...ctrlclient.DeleteOption) error {
+				Expect(obj.GetName()).To(Equal("99-kmm-test-namespace-test-module"))
+				return notFound
+			},
+		)
+
+		Expect(m.Sync(ctx, mod, mlds)).To(Succeed())
+	})
+
+	It("should not do anything if no image is ready yet", func() {
+		Expect(m.Sync(ctx, mod, nil)).To(Succeed())
+	})
+
+	It("should create the MachineConfig", func() {
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, ctrlclient.ObjectKey{Name: "99-kmm-test-namespace-test-module"}, gomock.Any()).Return(notFound),
+			clnt.EXPECT().Create(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj ctrlclient.Object, _ ...ctrlclient.CreateOption) error {
+					mc := obj.(*unstructured.Unstructured)
+
+					Expect(mc.GroupVersionKind()).To(Equal(gvk))
+					Expect(mc.GetLabels()).To(Equal(map[string]string{
+						RoleLabel:                 "worker",
+						constants.ModuleNameLabel: moduleName,
+						ModuleNamespaceLabel:      namespace,
+					}))
+
+					version, _, err := unstructured.NestedString(mc.Object, "spec", "config", "ignition", "version")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(version).To(Equal("3.2.0"))
+
+					units, _, err := unstructured.NestedSlice(mc.Object, "spec", "config", "systemd", "units")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(units).To(HaveLen(1))
+					Expect(units[0]).To(HaveKeyWithValue("name", "kmm-test-namespace-test-module.service"))
+					Expect(units[0]).To(HaveKeyWithValue("enabled", true))
+					Expect(units[0]).To(HaveKeyWithValue("contents", ContainSubstring("ExecStart=/usr/local/bin/kmm-test-namespace-test-module-load")))
+
+					files, _, err := unstructured.NestedSlice(mc.Object, "spec", "config", "storage", "files")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(files).To(HaveLen(1))
+					Expect(files[0]).To(HaveKeyWithValue("path", "/usr/local/bin/kmm-test-namespace-test-module-load"))
+
+					source, _, err := unstructured.NestedString(files[0].(map[string]interface{}), "contents", "source")
+					Expect(err).NotTo(HaveOccurred())
+
+					script, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(source, "data:text/plain;charset=utf-8;base64,"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(script)).To(Equal(loadScript(mod, mlds)))
+
+					return nil
+				},
+			),
+		)
+
+		Expect(m.Sync(ctx, mod, mlds)).To(Succeed())
+	})
+
+	It("should return an error if the MachineConfig could not be created", func() {
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(notFound),
+			clnt.EXPECT().Create(ctx, gomock.Any()).Return(errors.New("random error")),
+		)
+
+		Expect(m.Sync(ctx, mod, mlds)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Delete", func() {
+	var (
+		ctrl *gomock.Controller
+		clnt *client.MockClient
+		m    Manager
+		mod  *kmmv1beta1.Module
+	)
+
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		m = NewManager(clnt)
+		mod = &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName, Namespace: namespace},
+		}
+	})
+
+	It("should ignore clusters without MachineConfigs", func() {
+		clnt.EXPECT().Delete(ctx, gomock.Any()).Return(&meta.NoKindMatchError{GroupKind: gvk.GroupKind()})
+
+		Expect(m.Delete(ctx, mod)).To(Succeed())
+	})
+
+	It("should return an error if the MachineConfig could not be deleted", func() {
+		clnt.EXPECT().Delete(ctx, gomock.Any()).Return(errors.New("random error"))
+
+		Expect(m.Delete(ctx, mod)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("loadScript", func() {
+	It("should load the kernel module from the image of the running kernel", func() {
+		mod := &kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName, Namespace: namespace},
+		}
+
+		mlds := []*api.ModuleLoaderData{
+			{
+				KernelVersion:  "6.0.0",
+				ContainerImage: "quay.io/org/kmod:6.0.0@sha256:5678",
+				Modprobe: kmmv1beta1.ModprobeSpec{
+					ModuleName: "kmod",
+					Parameters: []string{"a='b c'"},
+				},
+			},
+			{
+				KernelVersion:  "5.14.0",
+				Arch:           "arm64",
+				ContainerImage: "quay.io/org/kmod:5.14.0@sha256:1234",
+				Modprobe: kmmv1beta1.ModprobeSpec{
+					ModuleName: "kmod",
+					Blacklist:  []string{"other"},
+				},
+			},
+		}
+
+		Expect(loadScript(mod, mlds)).To(Equal(`#!/bin/bash
+# Generated by KMM for Module test-namespace/test-module
+set -euo pipefail
+
+case "$(uname -r)/$(uname -m)" in
+  '5.14.0'/aarch64)
+    image='quay.io/org/kmod:5.14.0@sha256:1234'
+    modprobe_config='# Generated by KMM
+blacklist other
+'
+    load_args=('-v' 'kmod')
+    ;;
+  '6.0.0'/*)
+    image='quay.io/org/kmod:6.0.0@sha256:5678'
+    modprobe_config=''
+    load_args=('-v' 'kmod' 'a='\''b c'\''')
+    ;;
+  *)
+    echo "No image for kernel $(uname -r); the kernel module will be loaded once the node joins the cluster"
+    exit 0
+    ;;
+esac
+
+podman_args=(--rm --privileged --authfile /var/lib/kubelet/config.json --entrypoint modprobe)
+
+if [ -n "$modprobe_config" ]; then
+  mkdir -p /run/kmm
+  printf '%s' "$modprobe_config" > /run/kmm/test-namespace_test-module.conf
+  podman_args+=(-v /run/kmm/test-namespace_test-module.conf:/etc/modprobe.d/kmm.conf:ro)
+fi
+
+podman run "${podman_args[@]}" "$image" "${load_args[@]}"
+`))
+	})
+})
+
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 
+	v1 "k8s.io/api/core/v1"
+
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
@@ -37,6 +39,17 @@ func ShouldBeSigned(mld *api.ModuleLoaderData) bool {
 	return mld.Sign != nil
 }
 
+// BuiltImage returns the image built for mld: the intermediate image if it should also be signed, so that signing
+// produces the container image, or the container image otherwise.
+func BuiltImage(mld *api.ModuleLoaderData) string {
+	if ShouldBeSigned(mld) {
+		return IntermediateImageName(mld.Name, mld.Namespace, mld.ContainerImage)
+	}
+
+	return mld.ContainerImage
+}
+
+// ImageExists returns true if imageName exists for the architecture of mld.
 func ImageExists(
 	ctx context.Context,
 	authFactory auth.RegistryAuthGetterFactory,
@@ -46,10 +59,67 @@ func ImageExists(
 
 	registryAuthGetter := authFactory.NewRegistryAuthGetterFrom(mld)
 	tlsOptions := mld.RegistryTLS
-	exists, err := reg.ImageExists(ctx, imageName, tlsOptions, registryAuthGetter)
+	exists, err := reg.ImageExists(ctx, imageName, mld.Arch, tlsOptions, registryAuthGetter)
 	if err != nil {
 		return false, fmt.Errorf("could not check if the image is available: %v", err)
 	}
 
 	return exists, nil
 }
+
+// ImageDigest returns the digest of imageName, using the registry settings of mld.
+func ImageDigest(
+	ctx context.Context,
+	authFactory auth.RegistryAuthGetterFactory,
+	reg registry.Registry,
+	mld *api.ModuleLoaderData,
+	imageName string) (string, error) {
+
+	registryAuthGetter := authFactory.NewRegistryAuthGetterFrom(mld)
+	digest, err := reg.GetDigest(ctx, imageName, mld.RegistryTLS, registryAuthGetter)
+	if err != nil {
+		return "", fmt.Errorf("could not get the digest of the image: %v", err)
+	}
+
+	return digest, nil
+}
+
+// ImageLabels returns the labels of the configuration of imageName, using the registry settings of mld.
+func ImageLabels(
+	ctx context.Context,
+	authFactory auth.RegistryAuthGetterFactory,
+	reg registry.Registry,
+	mld *api.ModuleLoaderData,
+	imageName string) (map[string]string, error) {
+
+	registryAuthGetter := authFactory.NewRegistryAuthGetterFrom(mld)
+	img, err := reg.GetImage(ctx, imageName, mld.RegistryTLS, registryAuthGetter)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the image: %v", err)
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the configuration of the image: %v", err)
+	}
+
+	return cfg.Config.Labels, nil
+}
+
+// NodeSelector returns the node selector for the pods running for mld: the selector of the Module, restricted to
+// the nodes of mld's architecture if it is known.
+func NodeSelector(mld *api.ModuleLoaderData) map[string]string {
+	if mld.Arch == "" {
+		return mld.Selector
+	}
+
+	nodeSelector := make(map[string]string, len(mld.Selector)+1)
+
+	for k, v := range mld.Selector {
+		nodeSelector[k] = v
+	}
+
+	nodeSelector[v1.LabelArchStable] = mld.Arch
+
+	return nodeSelector
+}
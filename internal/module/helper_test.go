@@ -10,6 +10,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	v1 "k8s.io/api/core/v1"
 
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
@@ -49,6 +50,25 @@ var _ = Describe("IntermediateImageName", func() {
 	})
 })
 
+var _ = Describe("BuiltImage", func() {
+	It("should return the container image if the module is not signed", func() {
+		mld := api.ModuleLoaderData{ContainerImage: "registry.example.com/org/image:tag"}
+
+		Expect(BuiltImage(&mld)).To(Equal(mld.ContainerImage))
+	})
+
+	It("should return the intermediate image if the module is signed", func() {
+		mld := api.ModuleLoaderData{
+			Name:           "name",
+			Namespace:      "namespace",
+			ContainerImage: "registry.example.com/org/image:tag",
+			Sign:           &kmmv1beta1.Sign{},
+		}
+
+		Expect(BuiltImage(&mld)).To(Equal(IntermediateImageName("name", "namespace", mld.ContainerImage)))
+	})
+})
+
 var _ = Describe("ImageExists", func() {
 	const (
 		imageName = "image-name"
@@ -78,7 +98,7 @@ var _ = Describe("ImageExists", func() {
 	It("should return true if the image exists", func() {
 		gomock.InOrder(
 			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
-			mockRegistry.EXPECT().ImageExists(ctx, imageName, gomock.Any(), nil).Return(true, nil),
+			mockRegistry.EXPECT().ImageExists(ctx, imageName, "", gomock.Any(), nil).Return(true, nil),
 		)
 
 		exists, err := ImageExists(ctx, mockAuthFactory, mockRegistry, &mld, imageName)
@@ -90,7 +110,7 @@ var _ = Describe("ImageExists", func() {
 	It("should return false if the image does not exist", func() {
 		gomock.InOrder(
 			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
-			mockRegistry.EXPECT().ImageExists(ctx, imageName, gomock.Any(), nil).Return(false, nil),
+			mockRegistry.EXPECT().ImageExists(ctx, imageName, "", gomock.Any(), nil).Return(false, nil),
 		)
 
 		exists, err := ImageExists(ctx, mockAuthFactory, mockRegistry, &mld, imageName)
@@ -102,7 +122,7 @@ var _ = Describe("ImageExists", func() {
 	It("should return an error if the registry call fails", func() {
 		gomock.InOrder(
 			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
-			mockRegistry.EXPECT().ImageExists(ctx, imageName, gomock.Any(), nil).Return(false, errors.New("some-error")),
+			mockRegistry.EXPECT().ImageExists(ctx, imageName, "", gomock.Any(), nil).Return(false, errors.New("some-error")),
 		)
 
 		exists, err := ImageExists(ctx, mockAuthFactory, mockRegistry, &mld, imageName)
@@ -120,7 +140,7 @@ var _ = Describe("ImageExists", func() {
 		authGetter := &auth.MockRegistryAuthGetter{}
 		gomock.InOrder(
 			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
-			mockRegistry.EXPECT().ImageExists(ctx, imageName, gomock.Any(), authGetter).Return(false, nil),
+			mockRegistry.EXPECT().ImageExists(ctx, imageName, "", gomock.Any(), authGetter).Return(false, nil),
 		)
 
 		exists, err := ImageExists(ctx, mockAuthFactory, mockRegistry, &mld, imageName)
@@ -129,3 +149,77 @@ var _ = Describe("ImageExists", func() {
 		Expect(exists).To(BeFalse())
 	})
 })
+
+var _ = Describe("ImageDigest", func() {
+	const imageName = "image-name:some-tag"
+
+	var (
+		ctrl *gomock.Controller
+
+		mockAuthFactory *auth.MockRegistryAuthGetterFactory
+		mockRegistry    *registry.MockRegistry
+
+		mld api.ModuleLoaderData
+		ctx context.Context
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+
+		mockAuthFactory = auth.NewMockRegistryAuthGetterFactory(ctrl)
+		mockRegistry = registry.NewMockRegistry(ctrl)
+
+		mld = api.ModuleLoaderData{RegistryTLS: &kmmv1beta1.TLSOptions{Insecure: true}}
+		ctx = context.Background()
+	})
+
+	It("should return the digest of the image", func() {
+		authGetter := &auth.MockRegistryAuthGetter{}
+		gomock.InOrder(
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
+			mockRegistry.EXPECT().GetDigest(ctx, imageName, mld.RegistryTLS, authGetter).Return("sha256:1234", nil),
+		)
+
+		digest, err := ImageDigest(ctx, mockAuthFactory, mockRegistry, &mld, imageName)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(digest).To(Equal("sha256:1234"))
+	})
+
+	It("should return an error if the registry call fails", func() {
+		gomock.InOrder(
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld),
+			mockRegistry.EXPECT().GetDigest(ctx, imageName, mld.RegistryTLS, nil).Return("", errors.New("some-error")),
+		)
+
+		_, err := ImageDigest(ctx, mockAuthFactory, mockRegistry, &mld, imageName)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("some-error"))
+	})
+})
+
+var _ = Describe("NodeSelector", func() {
+	It("should return the selector of the Module if the architecture is unknown", func() {
+		mld := api.ModuleLoaderData{Selector: map[string]string{"key": "value"}}
+
+		Expect(
+			NodeSelector(&mld),
+		).To(
+			Equal(map[string]string{"key": "value"}),
+		)
+	})
+
+	It("should restrict the selector to the architecture", func() {
+		selector := map[string]string{"key": "value"}
+		mld := api.ModuleLoaderData{Selector: selector, Arch: "arm64"}
+
+		Expect(
+			NodeSelector(&mld),
+		).To(
+			Equal(map[string]string{"key": "value", "kubernetes.io/arch": "arm64"}),
+		)
+
+		Expect(selector).To(HaveLen(1))
+	})
+})
@@ -4,37 +4,41 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 )
 
 //go:generate mockgen -source=kernelmapper.go -package=module -destination=mock_kernelmapper.go KernelMapper,kernelMapperHelperAPI
 
 type KernelMapper interface {
-	GetModuleLoaderDataForKernel(mod *kmmv1beta1.Module, kernelVersion string) (*api.ModuleLoaderData, error)
+	GetModuleLoaderDataForKernel(mod *kmmv1beta1.Module, kernelVersion, arch string) (*api.ModuleLoaderData, error)
 }
 
 type kernelMapper struct {
 	helper kernelMapperHelperAPI
 }
 
-func NewKernelMapper(buildHelper build.Helper, signHelper sign.Helper) KernelMapper {
+func NewKernelMapper(buildHelper build.Helper, signHelper sign.Helper, kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping) KernelMapper {
 	return &kernelMapper{
-		helper: newKernelMapperHelper(buildHelper, signHelper),
+		helper: newKernelMapperHelper(buildHelper, signHelper, kernelOsDtkMapping),
 	}
 }
 
-func (k *kernelMapper) GetModuleLoaderDataForKernel(mod *kmmv1beta1.Module, kernelVersion string) (*api.ModuleLoaderData, error) {
+// GetModuleLoaderDataForKernel returns the ModuleLoaderData of mod for the nodes running kernelVersion on arch.
+// arch uses the Kubernetes naming; it may be empty if the architecture of the nodes is not known.
+func (k *kernelMapper) GetModuleLoaderDataForKernel(mod *kmmv1beta1.Module, kernelVersion, arch string) (*api.ModuleLoaderData, error) {
 	mappings := mod.Spec.ModuleLoader.Container.KernelMappings
-	foundMapping, err := k.helper.findKernelMapping(mappings, kernelVersion)
+	foundMapping, err := k.helper.findKernelMapping(mappings, kernelVersion, arch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find mapping for kernel %s: %v", kernelVersion, err)
 	}
-	mld, err := k.helper.prepareModuleLoaderData(foundMapping, mod, kernelVersion)
+	mld, err := k.helper.prepareModuleLoaderData(foundMapping, mod, kernelVersion, arch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare module loader data for kernel %s: %v", kernelVersion, err)
 	}
@@ -47,44 +51,171 @@ func (k *kernelMapper) GetModuleLoaderDataForKernel(mod *kmmv1beta1.Module, kern
 }
 
 type kernelMapperHelperAPI interface {
-	findKernelMapping(mappings []kmmv1beta1.KernelMapping, kernelVersion string) (*kmmv1beta1.KernelMapping, error)
-	prepareModuleLoaderData(mapping *kmmv1beta1.KernelMapping, mod *kmmv1beta1.Module, kernelVersion string) (*api.ModuleLoaderData, error)
+	findKernelMapping(mappings []kmmv1beta1.KernelMapping, kernelVersion, arch string) (*kmmv1beta1.KernelMapping, error)
+	prepareModuleLoaderData(mapping *kmmv1beta1.KernelMapping, mod *kmmv1beta1.Module, kernelVersion, arch string) (*api.ModuleLoaderData, error)
 	replaceTemplates(mld *api.ModuleLoaderData) error
 }
 
 type kernelMapperHelper struct {
-	buildHelper build.Helper
-	signHelper  sign.Helper
+	buildHelper        build.Helper
+	signHelper         sign.Helper
+	kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping
 }
 
-func newKernelMapperHelper(buildHelper build.Helper, signHelper sign.Helper) kernelMapperHelperAPI {
+func newKernelMapperHelper(buildHelper build.Helper, signHelper sign.Helper, kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping) kernelMapperHelperAPI {
 	return &kernelMapperHelper{
-		buildHelper: buildHelper,
-		signHelper:  signHelper,
+		buildHelper:        buildHelper,
+		signHelper:         signHelper,
+		kernelOsDtkMapping: kernelOsDtkMapping,
 	}
 }
 
-func (kh *kernelMapperHelper) findKernelMapping(mappings []kmmv1beta1.KernelMapping, kernelVersion string) (*kmmv1beta1.KernelMapping, error) {
-	for _, m := range mappings {
-		if m.Literal != "" && m.Literal == kernelVersion {
-			return &m, nil
+const (
+	// regexpSpecificity is the specificity of mappings matched by a regular expression, which cannot be compared.
+	regexpSpecificity = 0
+	// literalSpecificity is higher than the specificity of any matcher.
+	literalSpecificity = 6
+)
+
+// findKernelMapping returns the most specific mapping matching kernelVersion on the nodes of architecture arch.
+// Mappings with the same specificity are considered in list order.
+// If arch is empty, the architecture is taken from the suffix of kernelVersion, if any.
+func (kh *kernelMapperHelper) findKernelMapping(mappings []kmmv1beta1.KernelMapping, kernelVersion, arch string) (*kmmv1beta1.KernelMapping, error) {
+	var (
+		found            *kmmv1beta1.KernelMapping
+		foundSpecificity int
+	)
+
+	// An unparsable kernel only prevents matchers from matching.
+	kc, kcErr := utils.ParseKernelVersion(kernelVersion)
+
+	// many kernels, such as Ubuntu's, are not suffixed with their architecture
+	if kc != nil && arch != "" {
+		kc.Arch = kernelArch(arch)
+	}
+
+	for i := range mappings {
+		m := mappings[i]
+
+		specificity, matches, err := matchKernelMapping(&m, kernelVersion, kc)
+		if err != nil {
+			return nil, err
 		}
 
-		if m.Regexp == "" {
-			continue
+		if matches && (found == nil || specificity > foundSpecificity) {
+			found = &m
+			foundSpecificity = specificity
 		}
+	}
 
-		if matches, err := regexp.MatchString(m.Regexp, kernelVersion); err != nil {
-			return nil, fmt.Errorf("could not match regexp %q against kernel %q: %v", m.Regexp, kernelVersion, err)
-		} else if matches {
-			return &m, nil
+	if found == nil {
+		if kcErr != nil {
+			return nil, fmt.Errorf("no suitable mapping found: %v", kcErr)
 		}
+
+		return nil, errors.New("no suitable mapping found")
+	}
+
+	return found, nil
+}
+
+// matchKernelMapping returns whether m matches kernelVersion and how specific the match is.
+// kc may be nil if kernelVersion could not be parsed.
+func matchKernelMapping(m *kmmv1beta1.KernelMapping, kernelVersion string, kc *utils.KernelComponents) (int, bool, error) {
+	if m.Literal != "" && m.Literal == kernelVersion {
+		return literalSpecificity, true, nil
+	}
+
+	if m.Matcher != nil && kc != nil {
+		matches, err := matchKernelComponents(m.Matcher, kc)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if matches {
+			return matcherSpecificity(m.Matcher), true, nil
+		}
+	}
+
+	if m.Regexp == "" {
+		return 0, false, nil
+	}
+
+	matches, err := regexp.MatchString(m.Regexp, kernelVersion)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not match regexp %q against kernel %q: %v", m.Regexp, kernelVersion, err)
 	}
 
-	return nil, errors.New("no suitable mapping found")
+	return regexpSpecificity, matches, nil
 }
 
-func (kh *kernelMapperHelper) prepareModuleLoaderData(mapping *kmmv1beta1.KernelMapping, mod *kmmv1beta1.Module, kernelVersion string) (*api.ModuleLoaderData, error) {
+// kernelArchAliases maps the Go architecture names to the ones found in kernel versions.
+var kernelArchAliases = map[string]string{
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+}
+
+// kernelArch returns the name of arch found in kernel versions.
+func kernelArch(arch string) string {
+	if alias, ok := kernelArchAliases[arch]; ok {
+		return alias
+	}
+
+	return arch
+}
+
+func matchKernelComponents(matcher *kmmv1beta1.KernelVersionMatcher, kc *utils.KernelComponents) (bool, error) {
+	if v := matcher.MinVersion; v != "" {
+		min, err := utils.ParseKernelVersion(v)
+		if err != nil {
+			return false, fmt.Errorf("could not parse minVersion %q: %v", v, err)
+		}
+
+		if utils.CompareKernelVersions(kc.Version, min.Version) < 0 {
+			return false, nil
+		}
+	}
+
+	if v := matcher.MaxVersion; v != "" {
+		max, err := utils.ParseKernelVersion(v)
+		if err != nil {
+			return false, fmt.Errorf("could not parse maxVersion %q: %v", v, err)
+		}
+
+		if utils.CompareKernelVersions(kc.Version, max.Version) > 0 {
+			return false, nil
+		}
+	}
+
+	if d := matcher.Distro; d != "" && kc.Distro != d && !strings.HasPrefix(kc.Distro, d+"_") {
+		return false, nil
+	}
+
+	if a := matcher.Arch; a != "" && kc.Arch != kernelArch(a) {
+		return false, nil
+	}
+
+	if f := matcher.Flavour; f != "" && kc.Flavour != f {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// matcherSpecificity is the number of constraints set in matcher.
+func matcherSpecificity(matcher *kmmv1beta1.KernelVersionMatcher) int {
+	specificity := 0
+
+	for _, v := range []string{matcher.MinVersion, matcher.MaxVersion, matcher.Distro, matcher.Arch, matcher.Flavour} {
+		if v != "" {
+			specificity++
+		}
+	}
+
+	return specificity
+}
+
+func (kh *kernelMapperHelper) prepareModuleLoaderData(mapping *kmmv1beta1.KernelMapping, mod *kmmv1beta1.Module, kernelVersion, arch string) (*api.ModuleLoaderData, error) {
 	var err error
 
 	mld := &api.ModuleLoaderData{}
@@ -95,7 +226,9 @@ func (kh *kernelMapperHelper) prepareModuleLoaderData(mapping *kmmv1beta1.Kernel
 
 	// prepare the sign
 	if mapping.Sign != nil || mod.Spec.ModuleLoader.Container.Sign != nil {
-		mld.Sign, err = kh.signHelper.GetRelevantSign(mod.Spec.ModuleLoader.Container.Sign, mapping.Sign, kernelVersion)
+		templateVars := kh.templateVars(kernelVersion, arch, mod.Name, mod.Namespace)
+
+		mld.Sign, err = kh.signHelper.GetRelevantSign(mod.Spec.ModuleLoader.Container.Sign, mapping.Sign, templateVars)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get the relevant Sign configuration for kernel %s: %v", kernelVersion, err)
 		}
@@ -114,24 +247,133 @@ func (kh *kernelMapperHelper) prepareModuleLoaderData(mapping *kmmv1beta1.Kernel
 	}
 
 	mld.KernelVersion = kernelVersion
+	mld.Arch = arch
 	mld.Name = mod.Name
 	mld.Namespace = mod.Namespace
 	mld.ImageRepoSecret = mod.Spec.ImageRepoSecret
 	mld.Selector = mod.Spec.Selector
 	mld.ServiceAccountName = mod.Spec.ModuleLoader.ServiceAccountName
+	mld.Labels = mod.Spec.ModuleLoader.Labels
+	mld.Annotations = mod.Spec.ModuleLoader.Annotations
+	mld.Tolerations = mod.Spec.ModuleLoader.Tolerations
+	mld.Affinity = mod.Spec.ModuleLoader.Affinity
+	mld.Volumes = mod.Spec.ModuleLoader.Volumes
+	mld.VolumeMounts = mod.Spec.ModuleLoader.Container.VolumeMounts
+	mld.Resources = mod.Spec.ModuleLoader.Container.Resources
 	mld.Modprobe = mod.Spec.ModuleLoader.Container.Modprobe
+	mld.ImageDigestResolution = mod.Spec.ModuleLoader.Container.ImageDigestResolution
+	mld.UpgradeStrategy = mod.Spec.UpgradeStrategy
+	mld.DependsOn = mod.Spec.DependsOn
+	mld.LoadMode = mod.Spec.LoadMode
+	mld.BootLoading = mod.Spec.BootLoading
+	mld.BuildPriority = mod.Spec.BuildPriority
 	mld.Owner = mod
 
 	return mld, nil
 }
 
+// replaceTemplates substitutes the template variables in all the templated fields of mld.
+// The slices and maps are replaced rather than modified, as they may be shared with the Module.
 func (kh *kernelMapperHelper) replaceTemplates(mld *api.ModuleLoaderData) error {
-	osConfigEnvVars := utils.KernelComponentsAsEnvVars(mld.KernelVersion)
-	replacedContainerImage, err := utils.ReplaceInTemplates(osConfigEnvVars, mld.ContainerImage)
+	templateVars := kh.templateVars(mld.KernelVersion, mld.Arch, mld.Name, mld.Namespace)
+
+	replacedContainerImage, err := utils.ReplaceInTemplates(templateVars, mld.ContainerImage)
 	if err != nil {
 		return fmt.Errorf("failed to substitute templates in the ContainerImage field: %v", err)
 	}
 	mld.ContainerImage = replacedContainerImage[0]
 
+	if mld.Build != nil && len(mld.Build.BuildArgs) > 0 {
+		buildArgs := make([]kmmv1beta1.BuildArg, 0, len(mld.Build.BuildArgs))
+
+		for _, ba := range mld.Build.BuildArgs {
+			value, err := utils.ReplaceInTemplates(templateVars, ba.Value)
+			if err != nil {
+				return fmt.Errorf("failed to substitute templates in build argument %s: %v", ba.Name, err)
+			}
+
+			buildArgs = append(buildArgs, kmmv1beta1.BuildArg{Name: ba.Name, Value: value[0]})
+		}
+
+		mld.Build.BuildArgs = buildArgs
+	}
+
+	if len(mld.Modprobe.Parameters) > 0 {
+		mld.Modprobe.Parameters, err = utils.ReplaceInTemplates(templateVars, mld.Modprobe.Parameters...)
+		if err != nil {
+			return fmt.Errorf("failed to substitute templates in the modprobe parameters: %v", err)
+		}
+	}
+
+	if len(mld.Modprobe.Options) > 0 {
+		options := make([]kmmv1beta1.ModprobeOptions, 0, len(mld.Modprobe.Options))
+
+		for _, o := range mld.Modprobe.Options {
+			parameters, err := utils.ReplaceInTemplates(templateVars, o.Parameters...)
+			if err != nil {
+				return fmt.Errorf("failed to substitute templates in the modprobe options of %s: %v", o.ModuleName, err)
+			}
+
+			options = append(options, kmmv1beta1.ModprobeOptions{ModuleName: o.ModuleName, Parameters: parameters})
+		}
+
+		mld.Modprobe.Options = options
+	}
+
+	replacedDirName, err := utils.ReplaceInTemplates(templateVars, mld.Modprobe.DirName)
+	if err != nil {
+		return fmt.Errorf("failed to substitute templates in the modprobe dirName field: %v", err)
+	}
+	mld.Modprobe.DirName = replacedDirName[0]
+
+	if len(mld.Labels) > 0 {
+		labels := make(map[string]string, len(mld.Labels))
+
+		for k, v := range mld.Labels {
+			value, err := utils.ReplaceInTemplates(templateVars, v)
+			if err != nil {
+				return fmt.Errorf("failed to substitute templates in label %s: %v", k, err)
+			}
+
+			labels[k] = value[0]
+		}
+
+		mld.Labels = labels
+	}
+
+	if len(mld.Annotations) > 0 {
+		annotations := make(map[string]string, len(mld.Annotations))
+
+		for k, v := range mld.Annotations {
+			value, err := utils.ReplaceInTemplates(templateVars, v)
+			if err != nil {
+				return fmt.Errorf("failed to substitute templates in annotation %s: %v", k, err)
+			}
+
+			annotations[k] = value[0]
+		}
+
+		mld.Annotations = annotations
+	}
+
 	return nil
 }
+
+// templateVars returns the template variables for a Module, a kernel and an architecture.
+// OS_IMAGE_VERSION and RHCOS_VERSION are only set if a node running the kernel reported its OS image version.
+func (kh *kernelMapperHelper) templateVars(kernelVersion, arch, modName, modNamespace string) []string {
+	tv := utils.TemplateVars{
+		KernelVersion: kernelVersion,
+		Arch:          arch,
+		ModName:       modName,
+		ModNamespace:  modNamespace,
+	}
+
+	if kh.kernelOsDtkMapping != nil {
+		if osImageVersion, err := kh.kernelOsDtkMapping.GetOSImageVersion(kernelVersion); err == nil {
+			tv.OSImageVersion = osImageVersion
+		}
+	}
+
+	return tv.EnvVars()
+}
@@ -1,7 +1,9 @@
 package module
 
 import (
+	"errors"
 	"fmt"
+
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -9,12 +11,16 @@ import (
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 var _ = Describe("GetMergedMappingForKernel", func() {
 	const (
 		kernelVersion = "1.2.3"
+		arch          = "arm64"
 	)
 
 	var (
@@ -38,26 +44,26 @@ var _ = Describe("GetMergedMappingForKernel", func() {
 	It("good flow", func() {
 		mapping := kmmv1beta1.KernelMapping{}
 		mld := api.ModuleLoaderData{KernelVersion: kernelVersion}
-		kh.EXPECT().findKernelMapping(mod.Spec.ModuleLoader.Container.KernelMappings, kernelVersion).Return(&mapping, nil)
-		kh.EXPECT().prepareModuleLoaderData(&mapping, &mod, kernelVersion).Return(&mld, nil)
+		kh.EXPECT().findKernelMapping(mod.Spec.ModuleLoader.Container.KernelMappings, kernelVersion, arch).Return(&mapping, nil)
+		kh.EXPECT().prepareModuleLoaderData(&mapping, &mod, kernelVersion, arch).Return(&mld, nil)
 		kh.EXPECT().replaceTemplates(&mld).Return(nil)
-		res, err := km.GetModuleLoaderDataForKernel(&mod, kernelVersion)
+		res, err := km.GetModuleLoaderDataForKernel(&mod, kernelVersion, arch)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(res).To(Equal(&mld))
 	})
 
 	It("failed to find kernel mapping", func() {
-		kh.EXPECT().findKernelMapping(mod.Spec.ModuleLoader.Container.KernelMappings, kernelVersion).Return(nil, fmt.Errorf("some error"))
-		res, err := km.GetModuleLoaderDataForKernel(&mod, kernelVersion)
+		kh.EXPECT().findKernelMapping(mod.Spec.ModuleLoader.Container.KernelMappings, kernelVersion, arch).Return(nil, fmt.Errorf("some error"))
+		res, err := km.GetModuleLoaderDataForKernel(&mod, kernelVersion, arch)
 		Expect(err).To(HaveOccurred())
 		Expect(res).To(BeNil())
 	})
 
 	It("failed to merge mapping data", func() {
 		mapping := kmmv1beta1.KernelMapping{}
-		kh.EXPECT().findKernelMapping(mod.Spec.ModuleLoader.Container.KernelMappings, kernelVersion).Return(&mapping, nil)
-		kh.EXPECT().prepareModuleLoaderData(&mapping, &mod, kernelVersion).Return(nil, fmt.Errorf("some error"))
-		res, err := km.GetModuleLoaderDataForKernel(&mod, kernelVersion)
+		kh.EXPECT().findKernelMapping(mod.Spec.ModuleLoader.Container.KernelMappings, kernelVersion, arch).Return(&mapping, nil)
+		kh.EXPECT().prepareModuleLoaderData(&mapping, &mod, kernelVersion, arch).Return(nil, fmt.Errorf("some error"))
+		res, err := km.GetModuleLoaderDataForKernel(&mod, kernelVersion, arch)
 		Expect(err).To(HaveOccurred())
 		Expect(res).To(BeNil())
 	})
@@ -65,10 +71,10 @@ var _ = Describe("GetMergedMappingForKernel", func() {
 	It("failed to replace templates", func() {
 		mapping := kmmv1beta1.KernelMapping{}
 		mld := api.ModuleLoaderData{KernelVersion: kernelVersion}
-		kh.EXPECT().findKernelMapping(mod.Spec.ModuleLoader.Container.KernelMappings, kernelVersion).Return(&mapping, nil)
-		kh.EXPECT().prepareModuleLoaderData(&mapping, &mod, kernelVersion).Return(&mld, nil)
+		kh.EXPECT().findKernelMapping(mod.Spec.ModuleLoader.Container.KernelMappings, kernelVersion, arch).Return(&mapping, nil)
+		kh.EXPECT().prepareModuleLoaderData(&mapping, &mod, kernelVersion, arch).Return(&mld, nil)
 		kh.EXPECT().replaceTemplates(&mld).Return(fmt.Errorf("some error"))
-		res, err := km.GetModuleLoaderDataForKernel(&mod, kernelVersion)
+		res, err := km.GetModuleLoaderDataForKernel(&mod, kernelVersion, arch)
 		Expect(err).To(HaveOccurred())
 		Expect(res).To(BeNil())
 	})
@@ -86,7 +92,7 @@ var _ = Describe("findKernelMapping", func() {
 
 	BeforeEach(func() {
 		ctrl = gomock.NewController(GinkgoT())
-		kh = newKernelMapperHelper(nil, nil)
+		kh = newKernelMapperHelper(nil, nil, nil)
 	})
 
 	AfterEach(func() {
@@ -98,7 +104,7 @@ var _ = Describe("findKernelMapping", func() {
 			Literal: "1.2.3",
 		}
 
-		m, err := kh.findKernelMapping([]kmmv1beta1.KernelMapping{mapping}, kernelVersion)
+		m, err := kh.findKernelMapping([]kmmv1beta1.KernelMapping{mapping}, kernelVersion, "")
 		Expect(err).NotTo(HaveOccurred())
 		Expect(m).To(Equal(&mapping))
 	})
@@ -108,7 +114,7 @@ var _ = Describe("findKernelMapping", func() {
 			Regexp: `1\..*`,
 		}
 
-		m, err := kh.findKernelMapping([]kmmv1beta1.KernelMapping{mapping}, kernelVersion)
+		m, err := kh.findKernelMapping([]kmmv1beta1.KernelMapping{mapping}, kernelVersion, "")
 		Expect(err).NotTo(HaveOccurred())
 		Expect(m).To(Equal(&mapping))
 	})
@@ -118,7 +124,7 @@ var _ = Describe("findKernelMapping", func() {
 			Regexp: "invalid)",
 		}
 
-		m, err := kh.findKernelMapping([]kmmv1beta1.KernelMapping{mapping}, kernelVersion)
+		m, err := kh.findKernelMapping([]kmmv1beta1.KernelMapping{mapping}, kernelVersion, "")
 		Expect(err).To(HaveOccurred())
 		Expect(m).To(BeNil())
 	})
@@ -133,21 +139,134 @@ var _ = Describe("findKernelMapping", func() {
 			},
 		}
 
-		m, err := kh.findKernelMapping(mappings, kernelVersion)
+		m, err := kh.findKernelMapping(mappings, kernelVersion, "")
 		Expect(err).To(MatchError("no suitable mapping found"))
 		Expect(m).To(BeNil())
 	})
+
+	It("should prefer the most specific mapping over the list order", func() {
+		mappings := []kmmv1beta1.KernelMapping{
+			{ContainerImage: "regexp", Regexp: `^.+$`},
+			{ContainerImage: "distro", Matcher: &kmmv1beta1.KernelVersionMatcher{Distro: "el9"}},
+			{ContainerImage: "distro-and-arch", Matcher: &kmmv1beta1.KernelVersionMatcher{Distro: "el9", Arch: "x86_64"}},
+			{ContainerImage: "other-distro-and-arch", Matcher: &kmmv1beta1.KernelVersionMatcher{Distro: "el9_2", Arch: "amd64"}},
+		}
+
+		m, err := kh.findKernelMapping(mappings, "5.14.0-284.11.1.el9_2.x86_64", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ContainerImage).To(Equal("distro-and-arch"))
+
+		mappings = append(mappings, kmmv1beta1.KernelMapping{ContainerImage: "literal", Literal: "5.14.0-284.11.1.el9_2.x86_64"})
+
+		m, err = kh.findKernelMapping(mappings, "5.14.0-284.11.1.el9_2.x86_64", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ContainerImage).To(Equal("literal"))
+	})
+
+	It("should return an error if a matcher version is invalid", func() {
+		mapping := kmmv1beta1.KernelMapping{
+			Matcher: &kmmv1beta1.KernelVersionMatcher{MinVersion: "invalid"},
+		}
+
+		_, err := kh.findKernelMapping([]kmmv1beta1.KernelMapping{mapping}, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should match the architecture of the nodes rather than the one of the kernel", func() {
+		mappings := []kmmv1beta1.KernelMapping{
+			{ContainerImage: "arm64", Matcher: &kmmv1beta1.KernelVersionMatcher{Arch: "arm64"}},
+			{ContainerImage: "x86_64", Matcher: &kmmv1beta1.KernelVersionMatcher{Arch: "x86_64"}},
+		}
+
+		By("matching an unsuffixed kernel")
+
+		m, err := kh.findKernelMapping(mappings, "6.5.0-14-generic", "amd64")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ContainerImage).To(Equal("x86_64"))
+
+		m, err = kh.findKernelMapping(mappings, "6.5.0-14-generic", "arm64")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ContainerImage).To(Equal("arm64"))
+
+		By("falling back to the suffix of the kernel if the architecture of the nodes is unknown")
+
+		m, err = kh.findKernelMapping(mappings, "5.14.0-284.11.1.el9_2.aarch64", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ContainerImage).To(Equal("arm64"))
+
+		_, err = kh.findKernelMapping(mappings, "6.5.0-14-generic", "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("matchers",
+		func(matcher kmmv1beta1.KernelVersionMatcher, kernel string, shouldMatch bool) {
+			mapping := kmmv1beta1.KernelMapping{Matcher: &matcher}
+
+			m, err := kh.findKernelMapping([]kmmv1beta1.KernelMapping{mapping}, kernel, "")
+
+			if shouldMatch {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(m).To(Equal(&mapping))
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry(
+			"in range",
+			kmmv1beta1.KernelVersionMatcher{MinVersion: "5.14.0-284", MaxVersion: "5.14"},
+			"5.14.0-284.11.1.el9_2.x86_64",
+			true,
+		),
+		Entry(
+			"below the range",
+			kmmv1beta1.KernelVersionMatcher{MinVersion: "5.14.0-284"},
+			"5.14.0-162.6.1.el9_1.x86_64",
+			false,
+		),
+		Entry(
+			"above the range",
+			kmmv1beta1.KernelVersionMatcher{MaxVersion: "5.14"},
+			"6.0.15-300.fc37.x86_64",
+			false,
+		),
+		Entry(
+			"distro minor release",
+			kmmv1beta1.KernelVersionMatcher{Distro: "el8_6"},
+			"4.18.0-372.9.1.el8.x86_64",
+			false,
+		),
+		Entry(
+			"flavour",
+			kmmv1beta1.KernelVersionMatcher{Flavour: "rt", Arch: "x86_64"},
+			"5.14.0-284.11.1.rt14.296.el9_2.x86_64",
+			true,
+		),
+		Entry(
+			"other flavour",
+			kmmv1beta1.KernelVersionMatcher{Flavour: "64k"},
+			"5.14.0-284.11.1.el9_2.aarch64",
+			false,
+		),
+		Entry(
+			"unparsable kernel",
+			kmmv1beta1.KernelVersionMatcher{Distro: "el9"},
+			"el9",
+			false,
+		),
+	)
 })
 
 var _ = Describe("prepareModuleLoaderData", func() {
 	const (
 		kernelVersion = "1.2.3"
+		arch          = "arm64"
 	)
 
 	var (
 		ctrl        *gomock.Controller
 		buildHelper *build.MockHelper
 		signHelper  *sign.MockHelper
+		kodm        *syncronizedmap.MockKernelOsDtkMapping
 		kh          kernelMapperHelperAPI
 		mod         kmmv1beta1.Module
 		mapping     kmmv1beta1.KernelMapping
@@ -157,9 +276,27 @@ var _ = Describe("prepareModuleLoaderData", func() {
 		ctrl = gomock.NewController(GinkgoT())
 		buildHelper = build.NewMockHelper(ctrl)
 		signHelper = sign.NewMockHelper(ctrl)
-		kh = newKernelMapperHelper(buildHelper, signHelper)
+		kodm = syncronizedmap.NewMockKernelOsDtkMapping(ctrl)
+		kh = newKernelMapperHelper(buildHelper, signHelper, kodm)
 		mod = kmmv1beta1.Module{}
 		mod.Spec.ModuleLoader.Container.ContainerImage = "spec container image"
+		mod.Spec.ModuleLoader.Labels = map[string]string{"some": "label"}
+		mod.Spec.ModuleLoader.Annotations = map[string]string{"some": "annotation"}
+		mod.Spec.ModuleLoader.Tolerations = []v1.Toleration{{Key: "some-taint", Operator: v1.TolerationOpExists}}
+		mod.Spec.ModuleLoader.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{}}
+		mod.Spec.ModuleLoader.Volumes = []v1.Volume{{Name: "some-volume"}}
+		mod.Spec.ModuleLoader.Container.VolumeMounts = []v1.VolumeMount{{Name: "some-volume", MountPath: "/some/path"}}
+		mod.Spec.ModuleLoader.Container.Resources = v1.ResourceRequirements{
+			Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Mi")},
+		}
+		mod.Spec.UpgradeStrategy = &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode}
+		mod.Spec.DependsOn = []string{"core"}
+		mod.Spec.LoadMode = kmmv1beta1.LoadModeJob
+		mod.Spec.BootLoading = &kmmv1beta1.BootLoadingSpec{MachineConfigPool: "worker"}
+		mod.Spec.BuildPriority = 10
+		mod.Spec.ModuleLoader.Container.ImageDigestResolution = &kmmv1beta1.ImageDigestResolution{
+			Policy: kmmv1beta1.ImageDigestPolicyPeriodic,
+		}
 		mapping = kmmv1beta1.KernelMapping{}
 	})
 
@@ -182,14 +319,28 @@ var _ = Describe("prepareModuleLoaderData", func() {
 		}
 
 		mld := api.ModuleLoaderData{
-			Name:               mod.Name,
-			Namespace:          mod.Namespace,
-			ImageRepoSecret:    mod.Spec.ImageRepoSecret,
-			Owner:              &mod,
-			Selector:           mod.Spec.Selector,
-			ServiceAccountName: mod.Spec.ModuleLoader.ServiceAccountName,
-			Modprobe:           mod.Spec.ModuleLoader.Container.Modprobe,
-			KernelVersion:      kernelVersion,
+			Name:                  mod.Name,
+			Namespace:             mod.Namespace,
+			ImageRepoSecret:       mod.Spec.ImageRepoSecret,
+			Owner:                 &mod,
+			Selector:              mod.Spec.Selector,
+			ServiceAccountName:    mod.Spec.ModuleLoader.ServiceAccountName,
+			Labels:                mod.Spec.ModuleLoader.Labels,
+			Annotations:           mod.Spec.ModuleLoader.Annotations,
+			Tolerations:           mod.Spec.ModuleLoader.Tolerations,
+			Affinity:              mod.Spec.ModuleLoader.Affinity,
+			Volumes:               mod.Spec.ModuleLoader.Volumes,
+			VolumeMounts:          mod.Spec.ModuleLoader.Container.VolumeMounts,
+			Resources:             mod.Spec.ModuleLoader.Container.Resources,
+			Modprobe:              mod.Spec.ModuleLoader.Container.Modprobe,
+			ImageDigestResolution: mod.Spec.ModuleLoader.Container.ImageDigestResolution,
+			KernelVersion:         kernelVersion,
+			Arch:                  arch,
+			UpgradeStrategy:       mod.Spec.UpgradeStrategy,
+			DependsOn:             mod.Spec.DependsOn,
+			LoadMode:              mod.Spec.LoadMode,
+			BootLoading:           mod.Spec.BootLoading,
+			BuildPriority:         mod.Spec.BuildPriority,
 		}
 
 		if buildExistsInMapping {
@@ -221,10 +372,18 @@ var _ = Describe("prepareModuleLoaderData", func() {
 		}
 		if signExistsInMapping || SignExistsInModuleSpec {
 			mld.Sign = sign
-			signHelper.EXPECT().GetRelevantSign(mod.Spec.ModuleLoader.Container.Sign, mapping.Sign, kernelVersion).Return(sign, nil)
+			templateVars := utils.TemplateVars{
+				KernelVersion:  kernelVersion,
+				Arch:           arch,
+				OSImageVersion: "411.86.202210072320-0",
+				ModName:        mod.Name,
+				ModNamespace:   mod.Namespace,
+			}
+			kodm.EXPECT().GetOSImageVersion(kernelVersion).Return("411.86.202210072320-0", nil)
+			signHelper.EXPECT().GetRelevantSign(mod.Spec.ModuleLoader.Container.Sign, mapping.Sign, templateVars.EnvVars()).Return(sign, nil)
 		}
 
-		res, err := kh.prepareModuleLoaderData(&mapping, &mod, kernelVersion)
+		res, err := kh.prepareModuleLoaderData(&mapping, &mod, kernelVersion, arch)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(*res).To(Equal(mld))
 	},
@@ -240,44 +399,115 @@ var _ = Describe("prepareModuleLoaderData", func() {
 var _ = Describe("replaceTemplates", func() {
 	const kernelVersion = "5.8.18-100.fc31.x86_64"
 
-	kh := newKernelMapperHelper(nil, nil)
+	var (
+		ctrl *gomock.Controller
+		kodm *syncronizedmap.MockKernelOsDtkMapping
+		kh   kernelMapperHelperAPI
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		kodm = syncronizedmap.NewMockKernelOsDtkMapping(ctrl)
+		kh = newKernelMapperHelper(nil, nil, kodm)
+	})
 
 	It("error input", func() {
 		mld := api.ModuleLoaderData{
 			ContainerImage: "some image:${KERNEL_XYZ",
 			KernelVersion:  kernelVersion,
 		}
+
+		kodm.EXPECT().GetOSImageVersion(kernelVersion).Return("", errors.New("not found"))
+
 		err := kh.replaceTemplates(&mld)
 		Expect(err).To(HaveOccurred())
 	})
 
-	It("should only substitute the ContainerImage field", func() {
+	It("should return an error if a variable is unknown", func() {
 		mld := api.ModuleLoaderData{
-			ContainerImage: "some image:${KERNEL_XYZ}",
+			ContainerImage: "some image:${OS_IMAGE_VERSION}",
+			KernelVersion:  kernelVersion,
+		}
+
+		kodm.EXPECT().GetOSImageVersion(kernelVersion).Return("", errors.New("not found"))
+
+		err := kh.replaceTemplates(&mld)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should substitute all the templated fields", func() {
+		buildArgs := []kmmv1beta1.BuildArg{
+			{Name: "name1", Value: "value1"},
+			{Name: "kernel version", Value: "${KERNEL_FULL_VERSION}"},
+		}
+		parameters := []string{"param=${MOD_NAME}"}
+		options := []kmmv1beta1.ModprobeOptions{{ModuleName: "dep", Parameters: []string{"kernel=${KERNEL_FULL_VERSION}"}}}
+		labels := map[string]string{"os": "${RHCOS_VERSION}"}
+		annotations := map[string]string{"kernel": "${KERNEL_FULL_VERSION}"}
+
+		mld := api.ModuleLoaderData{
+			Name:           "name",
+			Namespace:      "namespace",
+			ContainerImage: "some image:${KERNEL_XYZ}-${ARCH}-${OS_IMAGE_VERSION}",
 			Build: &kmmv1beta1.Build{
-				BuildArgs: []kmmv1beta1.BuildArg{
-					{Name: "name1", Value: "value1"},
-					{Name: "kernel version", Value: "${KERNEL_FULL_VERSION}"},
-				},
+				BuildArgs:           buildArgs,
 				DockerfileConfigMap: &v1.LocalObjectReference{},
 			},
+			Modprobe: kmmv1beta1.ModprobeSpec{
+				DirName:    "/opt/${MOD_NAMESPACE}",
+				Parameters: parameters,
+				Options:    options,
+			},
+			Labels:        labels,
+			Annotations:   annotations,
 			KernelVersion: kernelVersion,
 		}
 		expectMld := api.ModuleLoaderData{
-			ContainerImage: "some image:5.8.18",
+			Name:           "name",
+			Namespace:      "namespace",
+			ContainerImage: "some image:5.8.18-amd64-411.86.202210072320-0",
 			Build: &kmmv1beta1.Build{
 				BuildArgs: []kmmv1beta1.BuildArg{
 					{Name: "name1", Value: "value1"},
-					{Name: "kernel version", Value: "${KERNEL_FULL_VERSION}"},
+					{Name: "kernel version", Value: kernelVersion},
 				},
 				DockerfileConfigMap: &v1.LocalObjectReference{},
 			},
+			Modprobe: kmmv1beta1.ModprobeSpec{
+				DirName:    "/opt/namespace",
+				Parameters: []string{"param=name"},
+				Options:    []kmmv1beta1.ModprobeOptions{{ModuleName: "dep", Parameters: []string{"kernel=" + kernelVersion}}},
+			},
+			Labels:        map[string]string{"os": "411.86"},
+			Annotations:   map[string]string{"kernel": kernelVersion},
 			KernelVersion: kernelVersion,
 		}
 
+		kodm.EXPECT().GetOSImageVersion(kernelVersion).Return("411.86.202210072320-0", nil)
+
 		err := kh.replaceTemplates(&mld)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(mld).To(Equal(expectMld))
+
+		// the slices and maps shared with the Module should not be modified
+		Expect(buildArgs[1].Value).To(Equal("${KERNEL_FULL_VERSION}"))
+		Expect(parameters[0]).To(Equal("param=${MOD_NAME}"))
+		Expect(options[0].Parameters[0]).To(Equal("kernel=${KERNEL_FULL_VERSION}"))
+		Expect(labels["os"]).To(Equal("${RHCOS_VERSION}"))
+		Expect(annotations["kernel"]).To(Equal("${KERNEL_FULL_VERSION}"))
 	})
 
+	It("should use the architecture of the nodes", func() {
+		mld := api.ModuleLoaderData{
+			ContainerImage: "some image:${KERNEL_XYZ}-${ARCH}",
+			KernelVersion:  kernelVersion,
+			Arch:           "arm64",
+		}
+
+		kodm.EXPECT().GetOSImageVersion(kernelVersion).Return("", errors.New("not found"))
+
+		err := kh.replaceTemplates(&mld)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mld.ContainerImage).To(Equal("some image:5.8.18-arm64"))
+	})
 })
@@ -36,18 +36,18 @@ func (m *MockKernelMapper) EXPECT() *MockKernelMapperMockRecorder {
 }
 
 // GetModuleLoaderDataForKernel mocks base method.
-func (m *MockKernelMapper) GetModuleLoaderDataForKernel(mod *v1beta1.Module, kernelVersion string) (*api.ModuleLoaderData, error) {
+func (m *MockKernelMapper) GetModuleLoaderDataForKernel(mod *v1beta1.Module, kernelVersion, arch string) (*api.ModuleLoaderData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetModuleLoaderDataForKernel", mod, kernelVersion)
+	ret := m.ctrl.Call(m, "GetModuleLoaderDataForKernel", mod, kernelVersion, arch)
 	ret0, _ := ret[0].(*api.ModuleLoaderData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetModuleLoaderDataForKernel indicates an expected call of GetModuleLoaderDataForKernel.
-func (mr *MockKernelMapperMockRecorder) GetModuleLoaderDataForKernel(mod, kernelVersion interface{}) *gomock.Call {
+func (mr *MockKernelMapperMockRecorder) GetModuleLoaderDataForKernel(mod, kernelVersion, arch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModuleLoaderDataForKernel", reflect.TypeOf((*MockKernelMapper)(nil).GetModuleLoaderDataForKernel), mod, kernelVersion)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModuleLoaderDataForKernel", reflect.TypeOf((*MockKernelMapper)(nil).GetModuleLoaderDataForKernel), mod, kernelVersion, arch)
 }
 
 // MockkernelMapperHelperAPI is a mock of kernelMapperHelperAPI interface.
@@ -74,33 +74,33 @@ func (m *MockkernelMapperHelperAPI) EXPECT() *MockkernelMapperHelperAPIMockRecor
 }
 
 // findKernelMapping mocks base method.
-func (m *MockkernelMapperHelperAPI) findKernelMapping(mappings []v1beta1.KernelMapping, kernelVersion string) (*v1beta1.KernelMapping, error) {
+func (m *MockkernelMapperHelperAPI) findKernelMapping(mappings []v1beta1.KernelMapping, kernelVersion, arch string) (*v1beta1.KernelMapping, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "findKernelMapping", mappings, kernelVersion)
+	ret := m.ctrl.Call(m, "findKernelMapping", mappings, kernelVersion, arch)
 	ret0, _ := ret[0].(*v1beta1.KernelMapping)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // findKernelMapping indicates an expected call of findKernelMapping.
-func (mr *MockkernelMapperHelperAPIMockRecorder) findKernelMapping(mappings, kernelVersion interface{}) *gomock.Call {
+func (mr *MockkernelMapperHelperAPIMockRecorder) findKernelMapping(mappings, kernelVersion, arch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "findKernelMapping", reflect.TypeOf((*MockkernelMapperHelperAPI)(nil).findKernelMapping), mappings, kernelVersion)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "findKernelMapping", reflect.TypeOf((*MockkernelMapperHelperAPI)(nil).findKernelMapping), mappings, kernelVersion, arch)
 }
 
 // prepareModuleLoaderData mocks base method.
-func (m *MockkernelMapperHelperAPI) prepareModuleLoaderData(mapping *v1beta1.KernelMapping, mod *v1beta1.Module, kernelVersion string) (*api.ModuleLoaderData, error) {
+func (m *MockkernelMapperHelperAPI) prepareModuleLoaderData(mapping *v1beta1.KernelMapping, mod *v1beta1.Module, kernelVersion, arch string) (*api.ModuleLoaderData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "prepareModuleLoaderData", mapping, mod, kernelVersion)
+	ret := m.ctrl.Call(m, "prepareModuleLoaderData", mapping, mod, kernelVersion, arch)
 	ret0, _ := ret[0].(*api.ModuleLoaderData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // prepareModuleLoaderData indicates an expected call of prepareModuleLoaderData.
-func (mr *MockkernelMapperHelperAPIMockRecorder) prepareModuleLoaderData(mapping, mod, kernelVersion interface{}) *gomock.Call {
+func (mr *MockkernelMapperHelperAPIMockRecorder) prepareModuleLoaderData(mapping, mod, kernelVersion, arch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "prepareModuleLoaderData", reflect.TypeOf((*MockkernelMapperHelperAPI)(nil).prepareModuleLoaderData), mapping, mod, kernelVersion)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "prepareModuleLoaderData", reflect.TypeOf((*MockkernelMapperHelperAPI)(nil).prepareModuleLoaderData), mapping, mod, kernelVersion, arch)
 }
 
 // replaceTemplates mocks base method.
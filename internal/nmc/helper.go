@@ -0,0 +1,259 @@
+package nmc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+)
+
+// ModuleState is the state of a kernel module observed on a node.
+type ModuleState struct {
+	State kmmv1beta1.ModuleLoadState
+
+	// KernelVersion, ContainerImage and ImageDigest describe the loaded kernel module.
+	// They are only used if State is Loaded.
+	KernelVersion  string
+	ContainerImage string
+	ImageDigest    string
+
+	// Error is the error reported by the attempt to load or unload the kernel module.
+	Error string
+}
+
+//go:generate mockgen -source=helper.go -package=nmc -destination=mock_helper.go
+
+// Helper records the state of Modules in the NodeModulesConfig of each node.
+// The NodeModulesConfig of a node is created when the first Module is recorded on it, and is owned by the node.
+type Helper interface {
+	SetDesired(ctx context.Context, nodeName, namespace, name, kernelVersion, containerImage string) error
+	SetState(ctx context.Context, nodeName, namespace, name string, st ModuleState) error
+	RemoveModule(ctx context.Context, nodeName, namespace, name string) error
+	ModuleNodes(ctx context.Context, namespace, name string) (sets.String, error)
+}
+
+type helperImpl struct {
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+func NewHelper(client client.Client, scheme *runtime.Scheme) Helper {
+	return &helperImpl{
+		client: client,
+		scheme: scheme,
+	}
+}
+
+// SetDesired records the kernel version and image with which the Module should be loaded on the node.
+// Empty values mean that the Module does not target the node anymore.
+// A Module that was not recorded on the node yet is recorded as Loading.
+func (h *helperImpl) SetDesired(ctx context.Context, nodeName, namespace, name, kernelVersion, containerImage string) error {
+	return h.updateModule(ctx, nodeName, namespace, name, func(s *kmmv1beta1.NodeModuleStatus) *kmmv1beta1.NodeModuleStatus {
+		if s == nil {
+			if kernelVersion == "" {
+				return nil
+			}
+
+			s = newModuleStatus(namespace, name, kmmv1beta1.ModuleLoadStateLoading)
+		}
+
+		s.DesiredKernelVersion = kernelVersion
+		s.DesiredContainerImage = containerImage
+
+		return s
+	})
+}
+
+// SetState records the state of the Module observed on the node.
+// Unloaded Modules that were not recorded on the node are ignored.
+func (h *helperImpl) SetState(ctx context.Context, nodeName, namespace, name string, st ModuleState) error {
+	return h.updateModule(ctx, nodeName, namespace, name, func(s *kmmv1beta1.NodeModuleStatus) *kmmv1beta1.NodeModuleStatus {
+		if s == nil {
+			if st.State == kmmv1beta1.ModuleLoadStateUnloaded {
+				return nil
+			}
+
+			s = newModuleStatus(namespace, name, st.State)
+		}
+
+		if s.State != st.State {
+			s.State = st.State
+			s.LastTransitionTime = metav1.Now()
+		}
+
+		switch st.State {
+		case kmmv1beta1.ModuleLoadStateLoaded:
+			s.ActualKernelVersion = st.KernelVersion
+			s.ActualContainerImage = st.ContainerImage
+			s.ImageDigest = st.ImageDigest
+		case kmmv1beta1.ModuleLoadStateUnloaded:
+			s.ActualKernelVersion = ""
+			s.ActualContainerImage = ""
+			s.ImageDigest = ""
+		}
+
+		s.Error = st.Error
+
+		return s
+	})
+}
+
+// RemoveModule removes the Module from the NodeModulesConfig of the node.
+func (h *helperImpl) RemoveModule(ctx context.Context, nodeName, namespace, name string) error {
+	return h.updateModule(ctx, nodeName, namespace, name, func(_ *kmmv1beta1.NodeModuleStatus) *kmmv1beta1.NodeModuleStatus {
+		return nil
+	})
+}
+
+// ModuleNodes returns the names of the nodes on which the Module is recorded.
+func (h *helperImpl) ModuleNodes(ctx context.Context, namespace, name string) (sets.String, error) {
+	nmcList := kmmv1beta1.NodeModulesConfigList{}
+
+	if err := h.client.List(ctx, &nmcList); err != nil {
+		return nil, fmt.Errorf("could not list NodeModulesConfigs: %v", err)
+	}
+
+	nodes := sets.NewString()
+
+	for _, nmc := range nmcList.Items {
+		if findModule(nmc.Status.Modules, namespace, name) != -1 {
+			nodes.Insert(nmc.Name)
+		}
+	}
+
+	return nodes, nil
+}
+
+// updateModule replaces the status of the Module in the NodeModulesConfig of the node with the one returned by
+// mutate, or removes it if mutate returns nil.
+// mutate receives a copy of the current status, or nil if the Module is not recorded on the node.
+// The NodeModulesConfig is created if needed, unless the node does not exist anymore.
+func (h *helperImpl) updateModule(
+	ctx context.Context,
+	nodeName, namespace, name string,
+	mutate func(s *kmmv1beta1.NodeModuleStatus) *kmmv1beta1.NodeModuleStatus) error {
+	nmc := kmmv1beta1.NodeModulesConfig{}
+	exists := true
+
+	if err := h.client.Get(ctx, types.NamespacedName{Name: nodeName}, &nmc); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("could not get NodeModulesConfig %s: %v", nodeName, err)
+		}
+
+		exists = false
+	}
+
+	idx := findModule(nmc.Status.Modules, namespace, name)
+
+	var current *kmmv1beta1.NodeModuleStatus
+
+	if idx != -1 {
+		current = nmc.Status.Modules[idx].DeepCopy()
+	}
+
+	updated := mutate(current)
+
+	if updated == nil && idx == -1 {
+		return nil
+	}
+
+	if updated != nil && idx != -1 && reflect.DeepEqual(*updated, nmc.Status.Modules[idx]) {
+		return nil
+	}
+
+	if !exists {
+		created, err := h.create(ctx, nodeName)
+		if err != nil || created == nil {
+			return err
+		}
+
+		nmc = *created
+	}
+
+	nmcCopy := nmc.DeepCopy()
+
+	switch {
+	case updated == nil:
+		nmc.Status.Modules = append(nmc.Status.Modules[:idx], nmc.Status.Modules[idx+1:]...)
+	case idx == -1:
+		nmc.Status.Modules = append(nmc.Status.Modules, *updated)
+
+		sort.Slice(nmc.Status.Modules, func(i, j int) bool {
+			if nmc.Status.Modules[i].Namespace != nmc.Status.Modules[j].Namespace {
+				return nmc.Status.Modules[i].Namespace < nmc.Status.Modules[j].Namespace
+			}
+
+			return nmc.Status.Modules[i].Name < nmc.Status.Modules[j].Name
+		})
+	default:
+		nmc.Status.Modules[idx] = *updated
+	}
+
+	// both reconcilers write the status; the optimistic lock prevents them from overwriting each other's changes
+	patch := client.MergeFromWithOptions(nmcCopy, client.MergeFromWithOptimisticLock{})
+
+	if err := h.client.Status().Patch(ctx, &nmc, patch); err != nil {
+		return fmt.Errorf("could not patch the status of NodeModulesConfig %s: %v", nodeName, err)
+	}
+
+	return nil
+}
+
+// create creates the NodeModulesConfig of the node, owned by the node.
+// It returns nil if the node does not exist.
+func (h *helperImpl) create(ctx context.Context, nodeName string) (*kmmv1beta1.NodeModulesConfig, error) {
+	node := v1.Node{}
+
+	if err := h.client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("could not get node %s: %v", nodeName, err)
+	}
+
+	nmc := kmmv1beta1.NodeModulesConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+	}
+
+	if err := controllerutil.SetOwnerReference(&node, &nmc, h.scheme); err != nil {
+		return nil, fmt.Errorf("could not set node %s as the owner of its NodeModulesConfig: %v", nodeName, err)
+	}
+
+	if err := h.client.Create(ctx, &nmc); err != nil {
+		return nil, fmt.Errorf("could not create NodeModulesConfig %s: %v", nodeName, err)
+	}
+
+	return &nmc, nil
+}
+
+func newModuleStatus(namespace, name string, state kmmv1beta1.ModuleLoadState) *kmmv1beta1.NodeModuleStatus {
+	return &kmmv1beta1.NodeModuleStatus{
+		Namespace:          namespace,
+		Name:               name,
+		State:              state,
+		LastTransitionTime: metav1.Now(),
+	}
+}
+
+// findModule returns the index of the Module in statuses, or -1.
+func findModule(statuses []kmmv1beta1.NodeModuleStatus, namespace, name string) int {
+	for i, s := range statuses {
+		if s.Namespace == namespace && s.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,312 @@
+package nmc
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+)
+
+const (
+	nodeName  = "node-name"
+	namespace = "namespace"
+	name      = "module-name"
+)
+
+var _ = Describe("helperImpl", func() {
+	var (
+		ctrl     *gomock.Controller
+		clnt     *client.MockClient
+		statusWr *client.MockStatusWriter
+		h        Helper
+	)
+
+	ctx := context.Background()
+	nmcNsn := types.NamespacedName{Name: nodeName}
+	notFound := k8serrors.NewNotFound(schema.GroupResource{}, nodeName)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		statusWr = client.NewMockStatusWriter(ctrl)
+		h = NewHelper(clnt, scheme)
+	})
+
+	expectNMC := func(statuses ...kmmv1beta1.NodeModuleStatus) {
+		clnt.
+			EXPECT().
+			Get(ctx, nmcNsn, &kmmv1beta1.NodeModulesConfig{}).
+			DoAndReturn(func(_ context.Context, _ types.NamespacedName, nmc *kmmv1beta1.NodeModulesConfig, _ ...ctrlclient.GetOption) error {
+				nmc.Name = nodeName
+				nmc.Status.Modules = statuses
+				return nil
+			})
+	}
+
+	expectStatusPatch := func(check func(statuses []kmmv1beta1.NodeModuleStatus)) {
+		clnt.EXPECT().Status().Return(statusWr)
+		statusWr.
+			EXPECT().
+			Patch(ctx, gomock.Any(), gomock.Any()).
+			Do(func(_ context.Context, nmc *kmmv1beta1.NodeModulesConfig, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+				check(nmc.Status.Modules)
+			})
+	}
+
+	Describe("SetDesired", func() {
+		It("should create the NodeModulesConfig owned by the node", func() {
+			gomock.InOrder(
+				clnt.EXPECT().Get(ctx, nmcNsn, &kmmv1beta1.NodeModulesConfig{}).Return(notFound),
+				clnt.
+					EXPECT().
+					Get(ctx, types.NamespacedName{Name: nodeName}, &v1.Node{}).
+					DoAndReturn(func(_ context.Context, _ types.NamespacedName, node *v1.Node, _ ...ctrlclient.GetOption) error {
+						node.Name = nodeName
+						return nil
+					}),
+				clnt.
+					EXPECT().
+					Create(ctx, gomock.Any()).
+					Do(func(_ context.Context, nmc *kmmv1beta1.NodeModulesConfig, _ ...ctrlclient.CreateOption) {
+						Expect(nmc.Name).To(Equal(nodeName))
+						Expect(nmc.OwnerReferences).To(HaveLen(1))
+						Expect(nmc.OwnerReferences[0].Kind).To(Equal("Node"))
+						Expect(nmc.OwnerReferences[0].Name).To(Equal(nodeName))
+					}),
+			)
+
+			expectStatusPatch(func(statuses []kmmv1beta1.NodeModuleStatus) {
+				Expect(statuses).To(HaveLen(1))
+				Expect(statuses[0].Namespace).To(Equal(namespace))
+				Expect(statuses[0].Name).To(Equal(name))
+				Expect(statuses[0].DesiredKernelVersion).To(Equal("kernel"))
+				Expect(statuses[0].DesiredContainerImage).To(Equal("image"))
+				Expect(statuses[0].State).To(Equal(kmmv1beta1.ModuleLoadStateLoading))
+				Expect(statuses[0].LastTransitionTime.IsZero()).To(BeFalse())
+			})
+
+			Expect(
+				h.SetDesired(ctx, nodeName, namespace, name, "kernel", "image"),
+			).NotTo(HaveOccurred())
+		})
+
+		It("should not create the NodeModulesConfig of a node that does not exist", func() {
+			gomock.InOrder(
+				clnt.EXPECT().Get(ctx, nmcNsn, &kmmv1beta1.NodeModulesConfig{}).Return(notFound),
+				clnt.EXPECT().Get(ctx, types.NamespacedName{Name: nodeName}, &v1.Node{}).Return(notFound),
+			)
+
+			Expect(
+				h.SetDesired(ctx, nodeName, namespace, name, "kernel", "image"),
+			).NotTo(HaveOccurred())
+		})
+
+		It("should do nothing if the Module does not target a node on which it is not recorded", func() {
+			clnt.EXPECT().Get(ctx, nmcNsn, &kmmv1beta1.NodeModulesConfig{}).Return(notFound)
+
+			Expect(
+				h.SetDesired(ctx, nodeName, namespace, name, "", ""),
+			).NotTo(HaveOccurred())
+		})
+
+		It("should not patch the status if nothing changed", func() {
+			expectNMC(kmmv1beta1.NodeModuleStatus{
+				Namespace:             namespace,
+				Name:                  name,
+				DesiredKernelVersion:  "kernel",
+				DesiredContainerImage: "image",
+				State:                 kmmv1beta1.ModuleLoadStateLoaded,
+			})
+
+			Expect(
+				h.SetDesired(ctx, nodeName, namespace, name, "kernel", "image"),
+			).NotTo(HaveOccurred())
+		})
+
+		It("should keep the state and the other Modules", func() {
+			other := kmmv1beta1.NodeModuleStatus{Namespace: namespace, Name: "a-module", State: kmmv1beta1.ModuleLoadStateLoaded}
+
+			expectNMC(
+				other,
+				kmmv1beta1.NodeModuleStatus{
+					Namespace:             namespace,
+					Name:                  name,
+					DesiredKernelVersion:  "kernel",
+					DesiredContainerImage: "image",
+					State:                 kmmv1beta1.ModuleLoadStateLoaded,
+				},
+			)
+
+			expectStatusPatch(func(statuses []kmmv1beta1.NodeModuleStatus) {
+				Expect(statuses).To(Equal([]kmmv1beta1.NodeModuleStatus{
+					other,
+					{
+						Namespace:             namespace,
+						Name:                  name,
+						DesiredKernelVersion:  "kernel",
+						DesiredContainerImage: "new-image",
+						State:                 kmmv1beta1.ModuleLoadStateLoaded,
+					},
+				}))
+			})
+
+			Expect(
+				h.SetDesired(ctx, nodeName, namespace, name, "kernel", "new-image"),
+			).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("SetState", func() {
+		It("should record the loaded kernel module", func() {
+			lastTransitionTime := metav1.NewTime(metav1.Now().Add(-time.Hour))
+
+			expectNMC(kmmv1beta1.NodeModuleStatus{
+				Namespace:          namespace,
+				Name:               name,
+				State:              kmmv1beta1.ModuleLoadStateFailed,
+				LastTransitionTime: lastTransitionTime,
+				Error:              "some error",
+			})
+
+			expectStatusPatch(func(statuses []kmmv1beta1.NodeModuleStatus) {
+				Expect(statuses).To(HaveLen(1))
+				Expect(statuses[0].State).To(Equal(kmmv1beta1.ModuleLoadStateLoaded))
+				Expect(statuses[0].ActualKernelVersion).To(Equal("kernel"))
+				Expect(statuses[0].ActualContainerImage).To(Equal("image"))
+				Expect(statuses[0].ImageDigest).To(Equal("sha256:1234"))
+				Expect(statuses[0].Error).To(BeEmpty())
+				Expect(statuses[0].LastTransitionTime.After(lastTransitionTime.Time)).To(BeTrue())
+			})
+
+			st := ModuleState{
+				State:          kmmv1beta1.ModuleLoadStateLoaded,
+				KernelVersion:  "kernel",
+				ContainerImage: "image",
+				ImageDigest:    "sha256:1234",
+			}
+
+			Expect(
+				h.SetState(ctx, nodeName, namespace, name, st),
+			).NotTo(HaveOccurred())
+		})
+
+		It("should keep the transition time if the state did not change", func() {
+			lastTransitionTime := metav1.NewTime(metav1.Now().Add(-time.Hour))
+
+			expectNMC(kmmv1beta1.NodeModuleStatus{
+				Namespace:          namespace,
+				Name:               name,
+				State:              kmmv1beta1.ModuleLoadStateFailed,
+				LastTransitionTime: lastTransitionTime,
+				Error:              "some error",
+			})
+
+			expectStatusPatch(func(statuses []kmmv1beta1.NodeModuleStatus) {
+				Expect(statuses).To(HaveLen(1))
+				Expect(statuses[0].Error).To(Equal("other error"))
+				Expect(statuses[0].LastTransitionTime).To(Equal(lastTransitionTime))
+			})
+
+			st := ModuleState{State: kmmv1beta1.ModuleLoadStateFailed, Error: "other error"}
+
+			Expect(
+				h.SetState(ctx, nodeName, namespace, name, st),
+			).NotTo(HaveOccurred())
+		})
+
+		It("should clear the loaded kernel module once it is unloaded", func() {
+			expectNMC(kmmv1beta1.NodeModuleStatus{
+				Namespace:            namespace,
+				Name:                 name,
+				ActualKernelVersion:  "kernel",
+				ActualContainerImage: "image",
+				ImageDigest:          "sha256:1234",
+				State:                kmmv1beta1.ModuleLoadStateUnloading,
+			})
+
+			expectStatusPatch(func(statuses []kmmv1beta1.NodeModuleStatus) {
+				Expect(statuses).To(HaveLen(1))
+				Expect(statuses[0].State).To(Equal(kmmv1beta1.ModuleLoadStateUnloaded))
+				Expect(statuses[0].ActualKernelVersion).To(BeEmpty())
+				Expect(statuses[0].ActualContainerImage).To(BeEmpty())
+				Expect(statuses[0].ImageDigest).To(BeEmpty())
+			})
+
+			Expect(
+				h.SetState(ctx, nodeName, namespace, name, ModuleState{State: kmmv1beta1.ModuleLoadStateUnloaded}),
+			).NotTo(HaveOccurred())
+		})
+
+		It("should ignore unloaded Modules that are not recorded on the node", func() {
+			expectNMC()
+
+			Expect(
+				h.SetState(ctx, nodeName, namespace, name, ModuleState{State: kmmv1beta1.ModuleLoadStateUnloaded}),
+			).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("RemoveModule", func() {
+		It("should remove the Module from the NodeModulesConfig", func() {
+			other := kmmv1beta1.NodeModuleStatus{Namespace: namespace, Name: "other-module"}
+
+			expectNMC(kmmv1beta1.NodeModuleStatus{Namespace: namespace, Name: name}, other)
+			expectStatusPatch(func(statuses []kmmv1beta1.NodeModuleStatus) {
+				Expect(statuses).To(Equal([]kmmv1beta1.NodeModuleStatus{other}))
+			})
+
+			Expect(
+				h.RemoveModule(ctx, nodeName, namespace, name),
+			).NotTo(HaveOccurred())
+		})
+
+		It("should do nothing if the NodeModulesConfig does not exist", func() {
+			clnt.EXPECT().Get(ctx, nmcNsn, &kmmv1beta1.NodeModulesConfig{}).Return(notFound)
+
+			Expect(
+				h.RemoveModule(ctx, nodeName, namespace, name),
+			).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("ModuleNodes", func() {
+		It("should return the nodes on which the Module is recorded", func() {
+			clnt.
+				EXPECT().
+				List(ctx, &kmmv1beta1.NodeModulesConfigList{}).
+				DoAndReturn(func(_ context.Context, list *kmmv1beta1.NodeModulesConfigList, _ ...ctrlclient.ListOption) error {
+					list.Items = []kmmv1beta1.NodeModulesConfig{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+							Status: kmmv1beta1.NodeModulesConfigStatus{
+								Modules: []kmmv1beta1.NodeModuleStatus{{Namespace: namespace, Name: name}},
+							},
+						},
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+							Status: kmmv1beta1.NodeModulesConfigStatus{
+								Modules: []kmmv1beta1.NodeModuleStatus{{Namespace: "other-namespace", Name: name}},
+							},
+						},
+					}
+					return nil
+				})
+
+			nodes, err := h.ModuleNodes(ctx, namespace, name)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nodes.List()).To(Equal([]string{"node1"}))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: helper.go
+
+// Package nmc is a generated GoMock package.
+package nmc
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	sets "k8s.io/apimachinery/pkg/util/sets"
+)
+
+// MockHelper is a mock of Helper interface.
+type MockHelper struct {
+	ctrl     *gomock.Controller
+	recorder *MockHelperMockRecorder
+}
+
+// MockHelperMockRecorder is the mock recorder for MockHelper.
+type MockHelperMockRecorder struct {
+	mock *MockHelper
+}
+
+// NewMockHelper creates a new mock instance.
+func NewMockHelper(ctrl *gomock.Controller) *MockHelper {
+	mock := &MockHelper{ctrl: ctrl}
+	mock.recorder = &MockHelperMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHelper) EXPECT() *MockHelperMockRecorder {
+	return m.recorder
+}
+
+// ModuleNodes mocks base method.
+func (m *MockHelper) ModuleNodes(ctx context.Context, namespace, name string) (sets.String, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModuleNodes", ctx, namespace, name)
+	ret0, _ := ret[0].(sets.String)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ModuleNodes indicates an expected call of ModuleNodes.
+func (mr *MockHelperMockRecorder) ModuleNodes(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModuleNodes", reflect.TypeOf((*MockHelper)(nil).ModuleNodes), ctx, namespace, name)
+}
+
+// RemoveModule mocks base method.
+func (m *MockHelper) RemoveModule(ctx context.Context, nodeName, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveModule", ctx, nodeName, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveModule indicates an expected call of RemoveModule.
+func (mr *MockHelperMockRecorder) RemoveModule(ctx, nodeName, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveModule", reflect.TypeOf((*MockHelper)(nil).RemoveModule), ctx, nodeName, namespace, name)
+}
+
+// SetDesired mocks base method.
+func (m *MockHelper) SetDesired(ctx context.Context, nodeName, namespace, name, kernelVersion, containerImage string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDesired", ctx, nodeName, namespace, name, kernelVersion, containerImage)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDesired indicates an expected call of SetDesired.
+func (mr *MockHelperMockRecorder) SetDesired(ctx, nodeName, namespace, name, kernelVersion, containerImage interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDesired", reflect.TypeOf((*MockHelper)(nil).SetDesired), ctx, nodeName, namespace, name, kernelVersion, containerImage)
+}
+
+// SetState mocks base method.
+func (m *MockHelper) SetState(ctx context.Context, nodeName, namespace, name string, st ModuleState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetState", ctx, nodeName, namespace, name, st)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetState indicates an expected call of SetState.
+func (mr *MockHelperMockRecorder) SetState(ctx, nodeName, namespace, name, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetState", reflect.TypeOf((*MockHelper)(nil).SetState), ctx, nodeName, namespace, name, st)
+}
@@ -90,18 +90,18 @@ func (mr *MockpreflightHelperAPIMockRecorder) verifyBuild(ctx, pv, mld interface
 }
 
 // verifyImage mocks base method.
-func (m *MockpreflightHelperAPI) verifyImage(ctx context.Context, mld *api.ModuleLoaderData) (bool, string) {
+func (m *MockpreflightHelperAPI) verifyImage(ctx context.Context, pv *v1beta1.PreflightValidation, mld *api.ModuleLoaderData) (bool, string) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "verifyImage", ctx, mld)
+	ret := m.ctrl.Call(m, "verifyImage", ctx, pv, mld)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(string)
 	return ret0, ret1
 }
 
 // verifyImage indicates an expected call of verifyImage.
-func (mr *MockpreflightHelperAPIMockRecorder) verifyImage(ctx, mld interface{}) *gomock.Call {
+func (mr *MockpreflightHelperAPIMockRecorder) verifyImage(ctx, pv, mld interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "verifyImage", reflect.TypeOf((*MockpreflightHelperAPI)(nil).verifyImage), ctx, mld)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "verifyImage", reflect.TypeOf((*MockpreflightHelperAPI)(nil).verifyImage), ctx, pv, mld)
 }
 
 // verifySign mocks base method.
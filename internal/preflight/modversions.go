@@ -0,0 +1,230 @@
+package preflight
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	modinfoSection  = ".modinfo"
+	versionsSection = "__versions"
+
+	// ksymtabPrefix prefixes the name of the symbol table entry of each symbol exported by a module.
+	ksymtabPrefix = "__ksymtab_"
+
+	// modversionInfoSize is the size of struct modversion_info in the kernel: an unsigned long CRC followed by
+	// the NUL-terminated symbol name.
+	modversionInfoSize = 64
+
+	maxReportedSymbols = 10
+)
+
+type kmodInfo struct {
+	vermagic string
+	// versions maps the symbols used by the module to their CRC.
+	versions map[string]uint64
+	// exports are the symbols exported by the module for other modules to use.
+	exports []string
+}
+
+// parseKmod extracts the vermagic, the symbol versions and the exported symbols from the ELF contents of a kernel
+// module.
+// Modules built without CONFIG_MODVERSIONS have no symbol versions.
+func parseKmod(data []byte) (*kmodInfo, error) {
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ELF file: %v", err)
+	}
+	defer f.Close()
+
+	modinfo := f.Section(modinfoSection)
+	if modinfo == nil {
+		return nil, fmt.Errorf("section %s not found", modinfoSection)
+	}
+
+	modinfoData, err := modinfo.Data()
+	if err != nil {
+		return nil, fmt.Errorf("could not read section %s: %v", modinfoSection, err)
+	}
+
+	info := kmodInfo{versions: make(map[string]uint64)}
+
+	for _, entry := range bytes.Split(modinfoData, []byte{0}) {
+		if v := bytes.TrimPrefix(entry, []byte("vermagic=")); len(v) != len(entry) {
+			info.vermagic = string(v)
+			break
+		}
+	}
+
+	if info.vermagic == "" {
+		return nil, errors.New("vermagic not found in the module information")
+	}
+
+	if info.exports, err = exportedSymbols(f); err != nil {
+		return nil, err
+	}
+
+	versions := f.Section(versionsSection)
+	if versions == nil {
+		return &info, nil
+	}
+
+	versionsData, err := versions.Data()
+	if err != nil {
+		return nil, fmt.Errorf("could not read section %s: %v", versionsSection, err)
+	}
+
+	if len(versionsData)%modversionInfoSize != 0 {
+		return nil, fmt.Errorf("invalid size %d for section %s", len(versionsData), versionsSection)
+	}
+
+	crcSize := 8
+	if f.Class == elf.ELFCLASS32 {
+		crcSize = 4
+	}
+
+	for i := 0; i < len(versionsData); i += modversionInfoSize {
+		entry := versionsData[i : i+modversionInfoSize]
+
+		var crc uint64
+		if crcSize == 8 {
+			crc = f.ByteOrder.Uint64(entry[:crcSize])
+		} else {
+			crc = uint64(f.ByteOrder.Uint32(entry[:crcSize]))
+		}
+
+		name := entry[crcSize:]
+		if idx := bytes.IndexByte(name, 0); idx >= 0 {
+			name = name[:idx]
+		}
+
+		// CRCs are 32 bits wide, even when stored in an unsigned long
+		info.versions[string(name)] = crc & 0xffffffff
+	}
+
+	return &info, nil
+}
+
+// exportedSymbols returns the symbols exported by a kernel module, found from the __ksymtab_ entries of its symbol
+// table.
+func exportedSymbols(f *elf.File) ([]string, error) {
+	symbols, err := f.Symbols()
+	if err != nil {
+		if errors.Is(err, elf.ErrNoSymbols) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("could not read the symbol table: %v", err)
+	}
+
+	exports := make([]string, 0)
+
+	for _, s := range symbols {
+		if elf.ST_TYPE(s.Info) == elf.STT_SECTION {
+			continue
+		}
+
+		if name := strings.TrimPrefix(s.Name, ksymtabPrefix); name != s.Name && name != "" {
+			exports = append(exports, name)
+		}
+	}
+
+	return exports, nil
+}
+
+// parseModuleSymvers parses the contents of a Module.symvers file and returns the CRC of each exported symbol.
+// Each line has the following format: 0x<CRC>\t<symbol>\t<module>\t<export type>[\t<namespace>].
+func parseModuleSymvers(data []byte) (map[string]uint64, error) {
+	symbols := make(map[string]uint64)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected at least 2 fields, got %d", lineNum, len(fields))
+		}
+
+		crc, err := strconv.ParseUint(fields[0], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid CRC %q: %v", lineNum, fields[0], err)
+		}
+
+		symbols[fields[1]] = crc & 0xffffffff
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read Module.symvers: %v", err)
+	}
+
+	return symbols, nil
+}
+
+// checkModVersions verifies that the kernel module described by info can be loaded by kernelVersion, whose exported
+// symbols are described by symvers.
+// Symbols that the kernel does not export but that are in imageExports, the symbols exported by the other modules
+// shipped in the same image, are not reported as missing: those modules are built together, so their CRCs match.
+// It returns an empty string if the module is compatible, or a description of the incompatibilities otherwise.
+func checkModVersions(info *kmodInfo, kernelVersion string, symvers map[string]uint64, imageExports map[string]bool) string {
+	problems := make([]string, 0)
+
+	if fields := strings.Fields(info.vermagic); len(fields) == 0 || fields[0] != kernelVersion {
+		problems = append(problems, fmt.Sprintf("vermagic %q does not match kernel version %s", info.vermagic, kernelVersion))
+	}
+
+	missing := make([]string, 0)
+	mismatched := make([]string, 0)
+
+	for symbol, crc := range info.versions {
+		kernelCRC, ok := symvers[symbol]
+		if !ok {
+			if !imageExports[symbol] {
+				missing = append(missing, symbol)
+			}
+
+			continue
+		}
+
+		if kernelCRC != crc {
+			mismatched = append(mismatched, symbol)
+		}
+	}
+
+	if len(missing) > 0 {
+		problems = append(problems, "symbols missing from the kernel: "+formatSymbols(missing))
+	}
+
+	if len(mismatched) > 0 {
+		problems = append(problems, "symbols with a CRC mismatch: "+formatSymbols(mismatched))
+	}
+
+	return strings.Join(problems, "; ")
+}
+
+func formatSymbols(symbols []string) string {
+	sort.Strings(symbols)
+
+	if len(symbols) <= maxReportedSymbols {
+		return strings.Join(symbols, ", ")
+	}
+
+	return fmt.Sprintf(
+		"%s and %d more",
+		strings.Join(symbols[:maxReportedSymbols], ", "),
+		len(symbols)-maxReportedSymbols,
+	)
+}
@@ -0,0 +1,259 @@
+package preflight
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type elfSection struct {
+	name string
+	typ  elf.SectionType
+	data []byte
+}
+
+type symbolVersion struct {
+	name string
+	crc  uint64
+}
+
+// buildKmod returns a minimal little-endian ELF64 relocatable file with the .modinfo and __versions sections of a
+// kernel module.
+func buildKmod(modinfo string, versions []symbolVersion) []byte {
+	return buildKmodWithExports(modinfo, versions, nil)
+}
+
+// buildKmodWithExports is like buildKmod, but also adds a symbol table with an __ksymtab_ entry for each symbol in
+// exports.
+func buildKmodWithExports(modinfo string, versions []symbolVersion, exports []string) []byte {
+	versionsData := make([]byte, 0, len(versions)*modversionInfoSize)
+	for _, v := range versions {
+		entry := make([]byte, modversionInfoSize)
+		binary.LittleEndian.PutUint64(entry, v.crc)
+		copy(entry[8:], v.name)
+		versionsData = append(versionsData, entry...)
+	}
+
+	sections := []elfSection{
+		{name: modinfoSection, typ: elf.SHT_PROGBITS, data: []byte(modinfo)},
+		{name: versionsSection, typ: elf.SHT_PROGBITS, data: versionsData},
+		{name: ".shstrtab", typ: elf.SHT_STRTAB},
+	}
+
+	if len(exports) > 0 {
+		strtab := []byte{0}
+		symtab := bytes.Buffer{}
+
+		// The first entry of a symbol table is always the undefined symbol
+		Expect(binary.Write(&symtab, binary.LittleEndian, elf.Sym64{})).To(Succeed())
+
+		for _, e := range exports {
+			sym := elf.Sym64{
+				Name: uint32(len(strtab)),
+				Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_OBJECT),
+			}
+			Expect(binary.Write(&symtab, binary.LittleEndian, sym)).To(Succeed())
+
+			strtab = append(strtab, ksymtabPrefix+e...)
+			strtab = append(strtab, 0)
+		}
+
+		// .symtab links to .strtab, which follows it; section indices start at 1 because of the null section header.
+		sections = append(
+			sections[:len(sections)-1],
+			elfSection{name: ".symtab", typ: elf.SHT_SYMTAB, data: symtab.Bytes()},
+			elfSection{name: ".strtab", typ: elf.SHT_STRTAB, data: strtab},
+			sections[len(sections)-1],
+		)
+	}
+
+	shstrtab := []byte{0}
+	nameOffsets := make([]uint32, 0, len(sections))
+
+	for _, s := range sections {
+		nameOffsets = append(nameOffsets, uint32(len(shstrtab)))
+		shstrtab = append(shstrtab, s.name...)
+		shstrtab = append(shstrtab, 0)
+	}
+
+	sections[len(sections)-1].data = shstrtab
+
+	const headerSize = 64
+
+	body := bytes.Buffer{}
+	headers := []elf.Section64{{}}
+
+	for i, s := range sections {
+		h := elf.Section64{
+			Name:      nameOffsets[i],
+			Type:      uint32(s.typ),
+			Off:       uint64(headerSize + body.Len()),
+			Size:      uint64(len(s.data)),
+			Addralign: 1,
+		}
+
+		if s.typ == elf.SHT_SYMTAB {
+			h.Link = uint32(i + 2)
+			h.Info = 1
+			h.Entsize = uint64(binary.Size(elf.Sym64{}))
+		}
+
+		headers = append(headers, h)
+		body.Write(s.data)
+	}
+
+	ident := [elf.EI_NIDENT]byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT)}
+
+	header := elf.Header64{
+		Ident:     ident,
+		Type:      uint16(elf.ET_REL),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Shoff:     uint64(headerSize + body.Len()),
+		Ehsize:    headerSize,
+		Shentsize: uint16(binary.Size(elf.Section64{})),
+		Shnum:     uint16(len(headers)),
+		Shstrndx:  uint16(len(headers) - 1),
+	}
+
+	out := bytes.Buffer{}
+	Expect(binary.Write(&out, binary.LittleEndian, header)).To(Succeed())
+	out.Write(body.Bytes())
+	Expect(binary.Write(&out, binary.LittleEndian, headers)).To(Succeed())
+
+	return out.Bytes()
+}
+
+var _ = Describe("parseKmod", func() {
+	It("should return an error if the data is not an ELF file", func() {
+		_, err := parseKmod([]byte("not an ELF file"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error if vermagic is missing", func() {
+		_, err := parseKmod(buildKmod("license=GPL\x00", nil))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return vermagic and the symbol versions", func() {
+		data := buildKmod(
+			"license=GPL\x00vermagic=5.14.0-284.el9.x86_64 SMP preempt mod_unload modversions \x00name=simple_kmod\x00",
+			[]symbolVersion{
+				{name: "module_layout", crc: 0x12345678},
+				{name: "printk", crc: 0xdeadbeef},
+			},
+		)
+
+		info, err := parseKmod(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.vermagic).To(Equal("5.14.0-284.el9.x86_64 SMP preempt mod_unload modversions "))
+		Expect(info.versions).To(Equal(map[string]uint64{
+			"module_layout": 0x12345678,
+			"printk":        0xdeadbeef,
+		}))
+	})
+
+	It("should return the exported symbols", func() {
+		data := buildKmodWithExports(
+			"vermagic=5.14.0-284.el9.x86_64 SMP\x00",
+			[]symbolVersion{{name: "printk", crc: 0xdeadbeef}},
+			[]string{"some_export", "other_export"},
+		)
+
+		info, err := parseKmod(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.exports).To(ConsistOf("some_export", "other_export"))
+	})
+})
+
+var _ = Describe("parseModuleSymvers", func() {
+	It("should return an error if a line is invalid", func() {
+		_, err := parseModuleSymvers([]byte("0x12345678\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error if a CRC is invalid", func() {
+		_, err := parseModuleSymvers([]byte("notacrc\tprintk\tvmlinux\tEXPORT_SYMBOL\t\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return the CRC of all symbols", func() {
+		const symvers = "0x12345678\tmodule_layout\tvmlinux\tEXPORT_SYMBOL\t\n" +
+			"0xdeadbeef\tprintk\tvmlinux\tEXPORT_SYMBOL\t\n" +
+			"\n" +
+			"0x00000001\tsome_symbol\tdrivers/some/module\tEXPORT_SYMBOL_GPL\tSOME_NS\n"
+
+		symbols, err := parseModuleSymvers([]byte(symvers))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(symbols).To(Equal(map[string]uint64{
+			"module_layout": 0x12345678,
+			"printk":        0xdeadbeef,
+			"some_symbol":   1,
+		}))
+	})
+})
+
+var _ = Describe("checkModVersions", func() {
+	const kernel = "5.14.0-284.el9.x86_64"
+
+	symvers := map[string]uint64{
+		"module_layout": 0x12345678,
+		"printk":        0xdeadbeef,
+	}
+
+	DescribeTable("should report incompatibilities",
+		func(vermagic string, versions map[string]uint64, expected string) {
+			info := &kmodInfo{vermagic: vermagic, versions: versions}
+
+			Expect(checkModVersions(info, kernel, symvers, map[string]bool{"image_symbol": true})).To(Equal(expected))
+		},
+		Entry(
+			"compatible module",
+			kernel+" SMP mod_unload modversions ",
+			map[string]uint64{"module_layout": 0x12345678, "printk": 0xdeadbeef},
+			"",
+		),
+		Entry(
+			"module without symbol versions",
+			kernel+" SMP mod_unload ",
+			map[string]uint64{},
+			"",
+		),
+		Entry(
+			"vermagic mismatch",
+			"5.14.0-70.el9.x86_64 SMP mod_unload modversions ",
+			map[string]uint64{"printk": 0xdeadbeef},
+			`vermagic "5.14.0-70.el9.x86_64 SMP mod_unload modversions " does not match kernel version `+kernel,
+		),
+		Entry(
+			"missing and mismatched symbols",
+			kernel+" SMP mod_unload modversions ",
+			map[string]uint64{"module_layout": 0x1, "printk": 0xdeadbeef, "b_symbol": 0x2, "a_symbol": 0x3},
+			"symbols missing from the kernel: a_symbol, b_symbol; symbols with a CRC mismatch: module_layout",
+		),
+		Entry(
+			"symbol exported by another module of the image",
+			kernel+" SMP mod_unload modversions ",
+			map[string]uint64{"printk": 0xdeadbeef, "image_symbol": 0x1},
+			"",
+		),
+	)
+
+	It("should truncate long symbol lists", func() {
+		info := &kmodInfo{
+			vermagic: kernel,
+			versions: map[string]uint64{
+				"s00": 0, "s01": 0, "s02": 0, "s03": 0, "s04": 0, "s05": 0, "s06": 0, "s07": 0, "s08": 0, "s09": 0, "s10": 0, "s11": 0,
+			},
+		}
+
+		Expect(
+			checkModVersions(info, kernel, symvers, nil),
+		).To(
+			Equal("symbols missing from the kernel: s00, s01, s02, s03, s04, s05, s06, s07, s08, s09 and 2 more"),
+		)
+	})
+})
@@ -1,8 +1,13 @@
 package preflight
 
 import (
+	"archive/tar"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
 
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
@@ -12,6 +17,7 @@ import (
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/statusupdater"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 	ctrlruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -22,8 +28,13 @@ const (
 	VerificationStatusReasonNoDaemonSet        = "Verification successful, no driver-container present in the recipe"
 	VerificationStatusReasonUnknown            = "Verification has not started yet"
 	VerificationStatusReasonVerified           = "Verification successful (%s), this Module will not be verified again in this Preflight CR"
+
+	symversFileName = "Module.symvers"
 )
 
+// errFileFound is used to stop walking the files of an image once the requested file was found.
+var errFileFound = errors.New("file found")
+
 //go:generate mockgen -source=preflight.go -package=preflight -destination=mock_preflight_api.go PreflightAPI, preflightHelperAPI
 
 type PreflightAPI interface {
@@ -37,8 +48,9 @@ func NewPreflightAPI(
 	registryAPI registry.Registry,
 	kernelAPI module.KernelMapper,
 	statusUpdater statusupdater.PreflightStatusUpdater,
-	authFactory auth.RegistryAuthGetterFactory) PreflightAPI {
-	helper := newPreflightHelper(client, buildAPI, signAPI, registryAPI, authFactory)
+	authFactory auth.RegistryAuthGetterFactory,
+	kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping) PreflightAPI {
+	helper := newPreflightHelper(client, buildAPI, signAPI, registryAPI, authFactory, kernelOsDtkMapping)
 	return &preflight{
 		kernelAPI:     kernelAPI,
 		statusUpdater: statusUpdater,
@@ -55,7 +67,7 @@ type preflight struct {
 func (p *preflight) PreflightUpgradeCheck(ctx context.Context, pv *kmmv1beta1.PreflightValidation, mod *kmmv1beta1.Module) (bool, string) {
 	log := ctrlruntime.LoggerFrom(ctx)
 	kernelVersion := pv.Spec.KernelVersion
-	mld, err := p.kernelAPI.GetModuleLoaderDataForKernel(mod, kernelVersion)
+	mld, err := p.kernelAPI.GetModuleLoaderDataForKernel(mod, kernelVersion, "")
 	if err != nil {
 		return false, fmt.Sprintf("failed to process kernel mapping in the module %s for kernel version %s", mod.Name, kernelVersion)
 	}
@@ -65,7 +77,7 @@ func (p *preflight) PreflightUpgradeCheck(ctx context.Context, pv *kmmv1beta1.Pr
 		log.Info(utils.WarnString("failed to update the stage of Module CR in preflight to image stage"), "module", mld.Name, "error", err)
 	}
 
-	verified, msg := p.helper.verifyImage(ctx, mld)
+	verified, msg := p.helper.verifyImage(ctx, pv, mld)
 	if verified {
 		return true, msg
 	}
@@ -99,30 +111,38 @@ func (p *preflight) PreflightUpgradeCheck(ctx context.Context, pv *kmmv1beta1.Pr
 }
 
 type preflightHelperAPI interface {
-	verifyImage(ctx context.Context, mld *api.ModuleLoaderData) (bool, string)
+	verifyImage(ctx context.Context, pv *kmmv1beta1.PreflightValidation, mld *api.ModuleLoaderData) (bool, string)
 	verifyBuild(ctx context.Context, pv *kmmv1beta1.PreflightValidation, mld *api.ModuleLoaderData) (bool, string)
 	verifySign(ctx context.Context, pv *kmmv1beta1.PreflightValidation, mld *api.ModuleLoaderData) (bool, string)
 }
 
 type preflightHelper struct {
-	client      client.Client
-	registryAPI registry.Registry
-	buildAPI    build.Manager
-	signAPI     sign.SignManager
-	authFactory auth.RegistryAuthGetterFactory
+	client             client.Client
+	registryAPI        registry.Registry
+	buildAPI           build.Manager
+	signAPI            sign.SignManager
+	authFactory        auth.RegistryAuthGetterFactory
+	kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping
 }
 
-func newPreflightHelper(client client.Client, buildAPI build.Manager, signAPI sign.SignManager, registryAPI registry.Registry, authFactory auth.RegistryAuthGetterFactory) preflightHelperAPI {
+func newPreflightHelper(
+	client client.Client,
+	buildAPI build.Manager,
+	signAPI sign.SignManager,
+	registryAPI registry.Registry,
+	authFactory auth.RegistryAuthGetterFactory,
+	kernelOsDtkMapping syncronizedmap.KernelOsDtkMapping) preflightHelperAPI {
 	return &preflightHelper{
-		client:      client,
-		buildAPI:    buildAPI,
-		signAPI:     signAPI,
-		registryAPI: registryAPI,
-		authFactory: authFactory,
+		client:             client,
+		buildAPI:           buildAPI,
+		signAPI:            signAPI,
+		registryAPI:        registryAPI,
+		authFactory:        authFactory,
+		kernelOsDtkMapping: kernelOsDtkMapping,
 	}
 }
 
-func (p *preflightHelper) verifyImage(ctx context.Context, mld *api.ModuleLoaderData) (bool, string) {
+func (p *preflightHelper) verifyImage(ctx context.Context, pv *kmmv1beta1.PreflightValidation, mld *api.ModuleLoaderData) (bool, string) {
 	log := ctrlruntime.LoggerFrom(ctx)
 	image := mld.ContainerImage
 	moduleFileName := mld.Modprobe.ModuleName + ".ko"
@@ -145,7 +165,13 @@ func (p *preflightHelper) verifyImage(ctx context.Context, mld *api.ModuleLoader
 
 		// check kernel module file present in the directory of the kernel lib modules
 		if p.registryAPI.VerifyModuleExists(layer, baseDir, kernelVersion, moduleFileName) {
-			return true, fmt.Sprintf(VerificationStatusReasonVerified, "image accessible and verified")
+			if mld.Modprobe.FirmwarePath != "" {
+				if ok, msg := p.verifyFirmware(ctx, mld); !ok {
+					return false, msg
+				}
+			}
+
+			return p.verifyModVersions(ctx, pv, mld)
 		}
 		log.V(1).Info("module is not present in the current layer", "image", image, "module file name", moduleFileName, "kernel", kernelVersion, "dir", baseDir)
 	}
@@ -154,6 +180,243 @@ func (p *preflightHelper) verifyImage(ctx context.Context, mld *api.ModuleLoader
 	return false, fmt.Sprintf("image %s does not contain kernel module for kernel %s on any layer", image, kernelVersion)
 }
 
+// verifyFirmware checks that the image contains firmware files in the firmware directory of the Module.
+func (p *preflightHelper) verifyFirmware(ctx context.Context, mld *api.ModuleLoaderData) (bool, string) {
+	log := ctrlruntime.LoggerFrom(ctx)
+	firmwarePath := mld.Modprobe.FirmwarePath
+
+	found, err := p.hasFilesInImageDir(ctx, mld.ContainerImage, firmwarePath, mld.RegistryTLS, p.authFactory.NewRegistryAuthGetterFrom(mld))
+	if err != nil {
+		log.Info("could not look for firmware files in the image", "image", mld.ContainerImage, "path", firmwarePath, "error", err)
+		return false, fmt.Sprintf("failed to look for firmware files in %s in image %s: %v", firmwarePath, mld.ContainerImage, err)
+	}
+
+	if !found {
+		log.Info("firmware files not present in the image", "image", mld.ContainerImage, "path", firmwarePath)
+		return false, fmt.Sprintf("image %s does not contain any firmware file in %s", mld.ContainerImage, firmwarePath)
+	}
+
+	return true, ""
+}
+
+// verifyModVersions checks the vermagic and the symbol versions of the kernel module in the image against the
+// Module.symvers file of the target kernel, found in its DTK image.
+// If no DTK image is known for the target kernel, symbol versions are not checked.
+func (p *preflightHelper) verifyModVersions(ctx context.Context, pv *kmmv1beta1.PreflightValidation, mld *api.ModuleLoaderData) (bool, string) {
+	log := ctrlruntime.LoggerFrom(ctx)
+	kernelVersion := mld.KernelVersion
+
+	dtkImage := pv.Spec.DTKImage
+	if dtkImage == "" {
+		var err error
+
+		dtkImage, err = p.kernelOsDtkMapping.GetImage(kernelVersion)
+		if err != nil {
+			log.Info("no DTK image for kernel, not checking symbol versions", "kernel", kernelVersion, "error", err)
+			return true, fmt.Sprintf(VerificationStatusReasonVerified, "image accessible and verified, symbol versions not checked")
+		}
+	}
+
+	modulesDir := filepath.Join("/", mld.Modprobe.DirName, "lib/modules", kernelVersion)
+	modulePath := filepath.Join(modulesDir, mld.Modprobe.ModuleName+".ko")
+
+	files, err := p.getModuleFilesFromImage(ctx, mld.ContainerImage, modulesDir, mld.RegistryTLS, p.authFactory.NewRegistryAuthGetterFrom(mld))
+	if err != nil {
+		log.Info("could not read the kernel modules from the image", "image", mld.ContainerImage, "path", modulesDir, "error", err)
+		return false, fmt.Sprintf("failed to read kernel module %s from image %s: %v", modulePath, mld.ContainerImage, err)
+	}
+
+	kmod, ok := files[modulePath]
+	if !ok {
+		log.Info("kernel module not found in the image", "image", mld.ContainerImage, "path", modulePath)
+		return false, fmt.Sprintf("failed to read kernel module %s from image %s: file not found", modulePath, mld.ContainerImage)
+	}
+
+	symversPath := filepath.Join("/usr/src/kernels", kernelVersion, symversFileName)
+
+	symversData, err := p.getFileFromImage(ctx, dtkImage, symversPath, nil, p.authFactory.NewClusterAuthGetter())
+	if err != nil {
+		log.Info("could not read Module.symvers from the DTK image", "image", dtkImage, "path", symversPath, "error", err)
+		return false, fmt.Sprintf("failed to read %s from DTK image %s: %v", symversPath, dtkImage, err)
+	}
+
+	info, err := parseKmod(kmod)
+	if err != nil {
+		return false, fmt.Sprintf("failed to parse kernel module %s from image %s: %v", modulePath, mld.ContainerImage, err)
+	}
+
+	symvers, err := parseModuleSymvers(symversData)
+	if err != nil {
+		return false, fmt.Sprintf("failed to parse %s from DTK image %s: %v", symversPath, dtkImage, err)
+	}
+
+	imageExports := make(map[string]bool)
+
+	for path, data := range files {
+		switch {
+		case path == modulePath:
+			continue
+		case filepath.Base(path) == symversFileName:
+			bundled, err := parseModuleSymvers(data)
+			if err != nil {
+				return false, fmt.Sprintf("failed to parse %s from image %s: %v", path, mld.ContainerImage, err)
+			}
+
+			for symbol := range bundled {
+				imageExports[symbol] = true
+			}
+		default:
+			other, err := parseKmod(data)
+			if err != nil {
+				log.Info("could not parse kernel module, ignoring its exported symbols", "image", mld.ContainerImage, "path", path, "error", err)
+				continue
+			}
+
+			for _, symbol := range other.exports {
+				imageExports[symbol] = true
+			}
+		}
+	}
+
+	if problems := checkModVersions(info, kernelVersion, symvers, imageExports); problems != "" {
+		log.Info("kernel module is not compatible with the kernel", "image", mld.ContainerImage, "kernel", kernelVersion, "problems", problems)
+		return false, fmt.Sprintf("kernel module %s in image %s is not compatible with kernel %s: %s", modulePath, mld.ContainerImage, kernelVersion, problems)
+	}
+
+	return true, fmt.Sprintf(VerificationStatusReasonVerified, "image accessible and verified, symbol versions match the kernel")
+}
+
+// hasFilesInImageDir returns true if image contains at least one regular file under dir.
+func (p *preflightHelper) hasFilesInImageDir(
+	ctx context.Context,
+	image string,
+	dir string,
+	tlsOptions *kmmv1beta1.TLSOptions,
+	registryAuthGetter auth.RegistryAuthGetter) (bool, error) {
+	img, err := p.registryAPI.GetImage(ctx, image, tlsOptions, registryAuthGetter)
+	if err != nil {
+		return false, fmt.Errorf("could not get image %s: %v", image, err)
+	}
+
+	// in layers headers, there is no root prefix
+	prefix := strings.TrimPrefix(filepath.Clean(dir), "/") + "/"
+
+	fn := func(filename string, header *tar.Header, _ io.Reader, _ []interface{}) error {
+		if header.Typeflag == tar.TypeReg && strings.HasPrefix(strings.TrimPrefix(filepath.Clean(filename), "/"), prefix) {
+			return errFileFound
+		}
+
+		return nil
+	}
+
+	err = p.registryAPI.WalkFilesInImage(img, fn)
+	if errors.Is(err, errFileFound) {
+		return true, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("could not walk the files of image %s: %v", image, err)
+	}
+
+	return false, nil
+}
+
+// getModuleFilesFromImage returns the contents of the kernel modules and of the Module.symvers files found under dir
+// in image, keyed by their absolute path, as seen from its top-most layer.
+func (p *preflightHelper) getModuleFilesFromImage(
+	ctx context.Context,
+	image string,
+	dir string,
+	tlsOptions *kmmv1beta1.TLSOptions,
+	registryAuthGetter auth.RegistryAuthGetter) (map[string][]byte, error) {
+	img, err := p.registryAPI.GetImage(ctx, image, tlsOptions, registryAuthGetter)
+	if err != nil {
+		return nil, fmt.Errorf("could not get image %s: %v", image, err)
+	}
+
+	// in layers headers, there is no root prefix
+	prefix := strings.TrimPrefix(filepath.Clean(dir), "/") + "/"
+
+	files := make(map[string][]byte)
+
+	fn := func(filename string, header *tar.Header, tarreader io.Reader, _ []interface{}) error {
+		name := strings.TrimPrefix(filepath.Clean(filename), "/")
+
+		if header.Typeflag != tar.TypeReg || !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		if filepath.Ext(name) != ".ko" && filepath.Base(name) != symversFileName {
+			return nil
+		}
+
+		path := "/" + name
+
+		// Layers are walked from the top-most one; lower layers do not override what was already found.
+		if _, ok := files[path]; ok {
+			return nil
+		}
+
+		data, err := p.registryAPI.ExtractBytesFromTar(header.Size, tarreader)
+		if err != nil {
+			return fmt.Errorf("could not extract %s: %v", filename, err)
+		}
+
+		files[path] = data
+
+		return nil
+	}
+
+	if err = p.registryAPI.WalkFilesInImage(img, fn); err != nil {
+		return nil, fmt.Errorf("could not walk the files of image %s: %v", image, err)
+	}
+
+	return files, nil
+}
+
+// getFileFromImage returns the contents of the file at path in image, as seen from its top-most layer.
+func (p *preflightHelper) getFileFromImage(
+	ctx context.Context,
+	image string,
+	path string,
+	tlsOptions *kmmv1beta1.TLSOptions,
+	registryAuthGetter auth.RegistryAuthGetter) ([]byte, error) {
+	img, err := p.registryAPI.GetImage(ctx, image, tlsOptions, registryAuthGetter)
+	if err != nil {
+		return nil, fmt.Errorf("could not get image %s: %v", image, err)
+	}
+
+	// in layers headers, there is no root prefix
+	path = strings.TrimPrefix(filepath.Clean(path), "/")
+
+	var contents []byte
+
+	fn := func(filename string, header *tar.Header, tarreader io.Reader, _ []interface{}) error {
+		if strings.TrimPrefix(filepath.Clean(filename), "/") != path || header.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		data, err := p.registryAPI.ExtractBytesFromTar(header.Size, tarreader)
+		if err != nil {
+			return fmt.Errorf("could not extract %s: %v", filename, err)
+		}
+
+		contents = data
+
+		return errFileFound
+	}
+
+	if err = p.registryAPI.WalkFilesInImage(img, fn); err != nil && !errors.Is(err, errFileFound) {
+		return nil, fmt.Errorf("could not walk the files of image %s: %v", image, err)
+	}
+
+	if contents == nil {
+		return nil, fmt.Errorf("file %s not found in image %s", path, image)
+	}
+
+	return contents, nil
+}
+
 func (p *preflightHelper) verifyBuild(ctx context.Context, pv *kmmv1beta1.PreflightValidation, mld *api.ModuleLoaderData) (bool, string) {
 	log := ctrlruntime.LoggerFrom(ctx)
 	// at this stage we know that eiher mapping Build or Container build are defined
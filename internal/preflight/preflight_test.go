@@ -1,11 +1,15 @@
 package preflight
 
 import (
+	"archive/tar"
+	"bytes"
 	context "context"
 	"fmt"
+	"io"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	v1stream "github.com/google/go-containerregistry/pkg/v1/stream"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -18,6 +22,7 @@ import (
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/statusupdater"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/syncronizedmap"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -101,7 +106,7 @@ var _ = Describe("preflight_PreflightUpgradeCheck", func() {
 
 	It("Failed to process mapping", func() {
 		mod.Spec.ModuleLoader.Container.KernelMappings = []kmmv1beta1.KernelMapping{}
-		mockKernelAPI.EXPECT().GetModuleLoaderDataForKernel(mod, kernelVersion).Return(nil, fmt.Errorf("some error"))
+		mockKernelAPI.EXPECT().GetModuleLoaderDataForKernel(mod, kernelVersion, "").Return(nil, fmt.Errorf("some error"))
 
 		res, message := p.PreflightUpgradeCheck(context.Background(), pv, mod)
 
@@ -126,9 +131,9 @@ var _ = Describe("preflight_PreflightUpgradeCheck", func() {
 			mld.Sign = &kmmv1beta1.Sign{}
 		}
 
-		mockKernelAPI.EXPECT().GetModuleLoaderDataForKernel(mod, kernelVersion).Return(&mld, nil)
+		mockKernelAPI.EXPECT().GetModuleLoaderDataForKernel(mod, kernelVersion, "").Return(&mld, nil)
 		mockStatusUpdater.EXPECT().PreflightSetVerificationStage(context.Background(), pv, mld.Name, kmmv1beta1.VerificationStageImage).Return(nil)
-		preflightHelper.EXPECT().verifyImage(ctx, &mld).Return(imageVerified, "image message")
+		preflightHelper.EXPECT().verifyImage(ctx, pv, &mld).Return(imageVerified, "image message")
 		if !imageVerified {
 			if buildExists {
 				mockStatusUpdater.EXPECT().PreflightSetVerificationStage(context.Background(), pv, mld.Name, kmmv1beta1.VerificationStageBuild).Return(nil)
@@ -203,6 +208,7 @@ var _ = Describe("preflightHelper_verifyImage", func() {
 		mockRegistryAPI *registry.MockRegistry
 		mockAuthFactory *auth.MockRegistryAuthGetterFactory
 		authGetter      *auth.MockRegistryAuthGetter
+		mockSKODM       *syncronizedmap.MockKernelOsDtkMapping
 		clnt            *client.MockClient
 		ph              *preflightHelper
 	)
@@ -213,10 +219,12 @@ var _ = Describe("preflightHelper_verifyImage", func() {
 		mockRegistryAPI = registry.NewMockRegistry(ctrl)
 		mockAuthFactory = auth.NewMockRegistryAuthGetterFactory(ctrl)
 		authGetter = &auth.MockRegistryAuthGetter{}
+		mockSKODM = syncronizedmap.NewMockKernelOsDtkMapping(ctrl)
 		ph = &preflightHelper{
-			client:      clnt,
-			registryAPI: mockRegistryAPI,
-			authFactory: mockAuthFactory,
+			client:             clnt,
+			registryAPI:        mockRegistryAPI,
+			authFactory:        mockAuthFactory,
+			kernelOsDtkMapping: mockSKODM,
 		}
 
 	})
@@ -239,12 +247,13 @@ var _ = Describe("preflightHelper_verifyImage", func() {
 			mockRegistryAPI.EXPECT().GetLayersDigests(context.Background(), containerImage, gomock.Any(), gomock.Any()).Return(digests, repoConfig, nil),
 			mockRegistryAPI.EXPECT().GetLayerByDigest(digests[1], repoConfig).Return(&digestLayer, nil),
 			mockRegistryAPI.EXPECT().VerifyModuleExists(&digestLayer, "/opt", kernelVersion, "simple-kmod.ko").Return(true),
+			mockSKODM.EXPECT().GetImage(kernelVersion).Return("", fmt.Errorf("some error")),
 		)
 
-		res, message := ph.verifyImage(context.Background(), &mld)
+		res, message := ph.verifyImage(context.Background(), pv, &mld)
 
 		Expect(res).To(BeTrue())
-		Expect(message).To(Equal(fmt.Sprintf(VerificationStatusReasonVerified, "image accessible and verified")))
+		Expect(message).To(Equal(fmt.Sprintf(VerificationStatusReasonVerified, "image accessible and verified, symbol versions not checked")))
 	})
 
 	It("get layers digest failed", func() {
@@ -258,7 +267,7 @@ var _ = Describe("preflightHelper_verifyImage", func() {
 			mockRegistryAPI.EXPECT().GetLayersDigests(context.Background(), containerImage, gomock.Any(), gomock.Any()).Return(nil, nil, fmt.Errorf("some error")),
 		)
 
-		res, message := ph.verifyImage(context.Background(), &mld)
+		res, message := ph.verifyImage(context.Background(), pv, &mld)
 
 		Expect(res).To(BeFalse())
 		Expect(message).To(Equal(fmt.Sprintf("image %s inaccessible or does not exists", containerImage)))
@@ -277,7 +286,7 @@ var _ = Describe("preflightHelper_verifyImage", func() {
 			mockRegistryAPI.EXPECT().GetLayerByDigest(digests[1], repoConfig).Return(nil, fmt.Errorf("some error")),
 		)
 
-		res, message := ph.verifyImage(context.Background(), &mld)
+		res, message := ph.verifyImage(context.Background(), pv, &mld)
 
 		Expect(res).To(BeFalse())
 		Expect(message).To(Equal(fmt.Sprintf("image %s, layer %s is inaccessible", containerImage, digests[1])))
@@ -299,12 +308,293 @@ var _ = Describe("preflightHelper_verifyImage", func() {
 			mockRegistryAPI.EXPECT().VerifyModuleExists(&digestLayer, "/opt", kernelVersion, "simple-kmod.ko").Return(false),
 		)
 
-		res, message := ph.verifyImage(context.Background(), &mld)
+		res, message := ph.verifyImage(context.Background(), pv, &mld)
 
 		Expect(res).To(BeFalse())
 		Expect(message).To(Equal(fmt.Sprintf("image %s does not contain kernel module for kernel %s on any layer", containerImage, kernelVersion)))
 	})
 
+	DescribeTable("firmware files",
+		func(files []string, expectedRes bool, expectedMessage string) {
+			mld := api.ModuleLoaderData{
+				ContainerImage: containerImage,
+				Modprobe: kmmv1beta1.ModprobeSpec{
+					ModuleName:   "simple-kmod",
+					DirName:      "/opt",
+					FirmwarePath: "/firmware",
+				},
+				KernelVersion: kernelVersion,
+			}
+			digests := []string{"digest0"}
+			repoConfig := &registry.RepoPullConfig{}
+			digestLayer := v1stream.Layer{}
+			walk := func(_ v1.Image, fn func(string, *tar.Header, io.Reader, []interface{}) error, _ ...interface{}) error {
+				for _, name := range files {
+					if err := fn(name, &tar.Header{Name: name, Typeflag: tar.TypeReg}, nil, nil); err != nil {
+						return fmt.Errorf("died processing file %s: %w", name, err)
+					}
+				}
+
+				return nil
+			}
+
+			calls := []*gomock.Call{
+				mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
+				mockRegistryAPI.EXPECT().GetLayersDigests(context.Background(), containerImage, gomock.Any(), gomock.Any()).Return(digests, repoConfig, nil),
+				mockRegistryAPI.EXPECT().GetLayerByDigest(digests[0], repoConfig).Return(&digestLayer, nil),
+				mockRegistryAPI.EXPECT().VerifyModuleExists(&digestLayer, "/opt", kernelVersion, "simple-kmod.ko").Return(true),
+				mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
+				mockRegistryAPI.EXPECT().GetImage(context.Background(), containerImage, gomock.Any(), authGetter).Return(nil, nil),
+				mockRegistryAPI.EXPECT().WalkFilesInImage(nil, gomock.Any()).DoAndReturn(walk),
+			}
+
+			if expectedRes {
+				calls = append(calls, mockSKODM.EXPECT().GetImage(kernelVersion).Return("", fmt.Errorf("some error")))
+			}
+
+			gomock.InOrder(calls...)
+
+			res, message := ph.verifyImage(context.Background(), pv, &mld)
+
+			Expect(res).To(Equal(expectedRes))
+			Expect(message).To(Equal(expectedMessage))
+		},
+		Entry(
+			"present",
+			[]string{"opt/lib/modules/simple-kmod.ko", "firmware/vendor/fw.bin"},
+			true,
+			fmt.Sprintf(VerificationStatusReasonVerified, "image accessible and verified, symbol versions not checked"),
+		),
+		Entry(
+			"missing",
+			[]string{"opt/lib/modules/simple-kmod.ko", "firmware-other/fw.bin"},
+			false,
+			fmt.Sprintf("image %s does not contain any firmware file in /firmware", containerImage),
+		),
+	)
+})
+
+var _ = Describe("preflightHelper_verifyModVersions", func() {
+	const (
+		dtkImage    = "dtk image"
+		kernel      = "5.14.0-284.el9.x86_64"
+		modulePath  = "opt/lib/modules/" + kernel + "/simple-kmod.ko"
+		symversPath = "usr/src/kernels/" + kernel + "/Module.symvers"
+	)
+
+	var (
+		ctrl            *gomock.Controller
+		mockRegistryAPI *registry.MockRegistry
+		mockAuthFactory *auth.MockRegistryAuthGetterFactory
+		authGetter      *auth.MockRegistryAuthGetter
+		mockSKODM       *syncronizedmap.MockKernelOsDtkMapping
+		ph              *preflightHelper
+		mld             api.ModuleLoaderData
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockRegistryAPI = registry.NewMockRegistry(ctrl)
+		mockAuthFactory = auth.NewMockRegistryAuthGetterFactory(ctrl)
+		authGetter = &auth.MockRegistryAuthGetter{}
+		mockSKODM = syncronizedmap.NewMockKernelOsDtkMapping(ctrl)
+		ph = &preflightHelper{
+			registryAPI:        mockRegistryAPI,
+			authFactory:        mockAuthFactory,
+			kernelOsDtkMapping: mockSKODM,
+		}
+		mld = api.ModuleLoaderData{
+			ContainerImage: containerImage,
+			Modprobe:       mod.Spec.ModuleLoader.Container.Modprobe,
+			KernelVersion:  kernel,
+		}
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	type imageFile struct {
+		path     string
+		contents []byte
+	}
+
+	// expectImageFiles makes WalkFilesInImage present files, after an unrelated one, to the walk function.
+	expectImageFiles := func(files ...imageFile) []*gomock.Call {
+		return []*gomock.Call{
+			mockRegistryAPI.
+				EXPECT().
+				WalkFilesInImage(nil, gomock.Any()).
+				DoAndReturn(func(_ v1.Image, fn func(string, *tar.Header, io.Reader, []interface{}) error, _ ...interface{}) error {
+					for _, f := range append([]imageFile{{path: "some/other/file"}}, files...) {
+						header := &tar.Header{Name: f.path, Typeflag: tar.TypeReg, Size: int64(len(f.contents))}
+						if err := fn(f.path, header, bytes.NewReader(f.contents), nil); err != nil {
+							return fmt.Errorf("died processing file %s: %w", f.path, err)
+						}
+					}
+
+					return nil
+				}),
+			mockRegistryAPI.
+				EXPECT().
+				ExtractBytesFromTar(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ int64, r io.Reader) ([]byte, error) {
+					return io.ReadAll(r)
+				}).
+				Times(len(files)),
+		}
+	}
+
+	// expectFile makes WalkFilesInImage present a single file at path with contents to the walk function.
+	expectFile := func(path string, contents []byte) []*gomock.Call {
+		return expectImageFiles(imageFile{path: path, contents: contents})
+	}
+
+	expectFiles := func(kmod, symvers []byte) {
+		calls := []*gomock.Call{
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
+			mockRegistryAPI.EXPECT().GetImage(context.Background(), containerImage, gomock.Any(), authGetter).Return(nil, nil),
+		}
+		calls = append(calls, expectFile(modulePath, kmod)...)
+		calls = append(
+			calls,
+			mockAuthFactory.EXPECT().NewClusterAuthGetter().Return(authGetter),
+			mockRegistryAPI.EXPECT().GetImage(context.Background(), dtkImage, gomock.Any(), authGetter).Return(nil, nil),
+		)
+		calls = append(calls, expectFile(symversPath, symvers)...)
+
+		gomock.InOrder(calls...)
+	}
+
+	It("should use the DTK image known for the kernel if none is set in the PreflightValidation", func() {
+		kmod := buildKmod("vermagic="+kernel+" SMP\x00", []symbolVersion{{name: "printk", crc: 0xdeadbeef}})
+
+		mockSKODM.EXPECT().GetImage(kernel).Return(dtkImage, nil)
+		expectFiles(kmod, []byte("0xdeadbeef\tprintk\tvmlinux\tEXPORT_SYMBOL\t\n"))
+
+		res, message := ph.verifyModVersions(context.Background(), pv, &mld)
+
+		Expect(res).To(BeTrue())
+		Expect(message).To(Equal(fmt.Sprintf(VerificationStatusReasonVerified, "image accessible and verified, symbol versions match the kernel")))
+	})
+
+	It("should report incompatible modules", func() {
+		pv.Spec.DTKImage = dtkImage
+		kmod := buildKmod("vermagic="+kernel+" SMP\x00", []symbolVersion{{name: "printk", crc: 0x1}})
+
+		expectFiles(kmod, []byte("0xdeadbeef\tprintk\tvmlinux\tEXPORT_SYMBOL\t\n"))
+
+		res, message := ph.verifyModVersions(context.Background(), pv, &mld)
+
+		Expect(res).To(BeFalse())
+		Expect(message).To(Equal(
+			fmt.Sprintf("kernel module /%s in image %s is not compatible with kernel %s: symbols with a CRC mismatch: printk", modulePath, containerImage, kernel),
+		))
+	})
+
+	It("should not report the symbols exported by another kernel module of the image as missing", func() {
+		pv.Spec.DTKImage = dtkImage
+		kmod := buildKmod(
+			"vermagic="+kernel+" SMP\x00",
+			[]symbolVersion{{name: "printk", crc: 0xdeadbeef}, {name: "dep_function", crc: 0x1234}},
+		)
+		dep := buildKmodWithExports("vermagic="+kernel+" SMP\x00", nil, []string{"dep_function"})
+
+		calls := []*gomock.Call{
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
+			mockRegistryAPI.EXPECT().GetImage(context.Background(), containerImage, gomock.Any(), authGetter).Return(nil, nil),
+		}
+		calls = append(
+			calls,
+			expectImageFiles(
+				imageFile{path: modulePath, contents: kmod},
+				imageFile{path: "opt/lib/modules/" + kernel + "/extra/dep-kmod.ko", contents: dep},
+			)...,
+		)
+		calls = append(
+			calls,
+			mockAuthFactory.EXPECT().NewClusterAuthGetter().Return(authGetter),
+			mockRegistryAPI.EXPECT().GetImage(context.Background(), dtkImage, gomock.Any(), authGetter).Return(nil, nil),
+		)
+		calls = append(calls, expectFile(symversPath, []byte("0xdeadbeef\tprintk\tvmlinux\tEXPORT_SYMBOL\t\n"))...)
+		gomock.InOrder(calls...)
+
+		res, message := ph.verifyModVersions(context.Background(), pv, &mld)
+
+		Expect(res).To(BeTrue())
+		Expect(message).To(Equal(fmt.Sprintf(VerificationStatusReasonVerified, "image accessible and verified, symbol versions match the kernel")))
+	})
+
+	It("should not report the symbols in the Module.symvers file of the image as missing", func() {
+		pv.Spec.DTKImage = dtkImage
+		kmod := buildKmod(
+			"vermagic="+kernel+" SMP\x00",
+			[]symbolVersion{{name: "printk", crc: 0xdeadbeef}, {name: "dep_function", crc: 0x1234}},
+		)
+
+		calls := []*gomock.Call{
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
+			mockRegistryAPI.EXPECT().GetImage(context.Background(), containerImage, gomock.Any(), authGetter).Return(nil, nil),
+		}
+		calls = append(
+			calls,
+			expectImageFiles(
+				imageFile{path: modulePath, contents: kmod},
+				imageFile{
+					path:     "opt/lib/modules/" + kernel + "/Module.symvers",
+					contents: []byte("0x00001234\tdep_function\tdep-kmod\tEXPORT_SYMBOL_GPL\t\n"),
+				},
+			)...,
+		)
+		calls = append(
+			calls,
+			mockAuthFactory.EXPECT().NewClusterAuthGetter().Return(authGetter),
+			mockRegistryAPI.EXPECT().GetImage(context.Background(), dtkImage, gomock.Any(), authGetter).Return(nil, nil),
+		)
+		calls = append(calls, expectFile(symversPath, []byte("0xdeadbeef\tprintk\tvmlinux\tEXPORT_SYMBOL\t\n"))...)
+		gomock.InOrder(calls...)
+
+		res, _ := ph.verifyModVersions(context.Background(), pv, &mld)
+
+		Expect(res).To(BeTrue())
+	})
+
+	It("should fail if the kernel module is not found in the image", func() {
+		pv.Spec.DTKImage = dtkImage
+
+		gomock.InOrder(
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
+			mockRegistryAPI.EXPECT().GetImage(context.Background(), containerImage, gomock.Any(), authGetter).Return(nil, nil),
+			mockRegistryAPI.EXPECT().WalkFilesInImage(nil, gomock.Any()).Return(nil),
+		)
+
+		res, message := ph.verifyModVersions(context.Background(), pv, &mld)
+
+		Expect(res).To(BeFalse())
+		Expect(message).To(ContainSubstring("failed to read kernel module /" + modulePath))
+	})
+
+	It("should fail if the DTK image cannot be pulled", func() {
+		pv.Spec.DTKImage = dtkImage
+		kmod := buildKmod("vermagic="+kernel+" SMP\x00", nil)
+
+		calls := []*gomock.Call{
+			mockAuthFactory.EXPECT().NewRegistryAuthGetterFrom(&mld).Return(authGetter),
+			mockRegistryAPI.EXPECT().GetImage(context.Background(), containerImage, gomock.Any(), authGetter).Return(nil, nil),
+		}
+		calls = append(calls, expectFile(modulePath, kmod)...)
+		calls = append(
+			calls,
+			mockAuthFactory.EXPECT().NewClusterAuthGetter().Return(authGetter),
+			mockRegistryAPI.EXPECT().GetImage(context.Background(), dtkImage, gomock.Any(), authGetter).Return(nil, fmt.Errorf("some error")),
+		)
+		gomock.InOrder(calls...)
+
+		res, message := ph.verifyModVersions(context.Background(), pv, &mld)
+
+		Expect(res).To(BeFalse())
+		Expect(message).To(ContainSubstring("failed to read /" + symversPath + " from DTK image " + dtkImage))
+	})
 })
 
 var _ = Describe("preflightHelper_verifyBuild", func() {
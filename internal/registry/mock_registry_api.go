@@ -56,6 +56,20 @@ func (mr *MockRegistryMockRecorder) AddLayerToImage(tarfile, image interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddLayerToImage", reflect.TypeOf((*MockRegistry)(nil).AddLayerToImage), tarfile, image)
 }
 
+// CopyImage mocks base method.
+func (m *MockRegistry) CopyImage(ctx context.Context, src, dst string, tlsOptions *v1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CopyImage", ctx, src, dst, tlsOptions, registryAuthGetter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CopyImage indicates an expected call of CopyImage.
+func (mr *MockRegistryMockRecorder) CopyImage(ctx, src, dst, tlsOptions, registryAuthGetter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyImage", reflect.TypeOf((*MockRegistry)(nil).CopyImage), ctx, src, dst, tlsOptions, registryAuthGetter)
+}
+
 // ExtractBytesFromTar mocks base method.
 func (m *MockRegistry) ExtractBytesFromTar(size int64, tarreader io.Reader) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -85,6 +99,21 @@ func (mr *MockRegistryMockRecorder) ExtractFileToFile(destination, header, tarre
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractFileToFile", reflect.TypeOf((*MockRegistry)(nil).ExtractFileToFile), destination, header, tarreader)
 }
 
+// GetDigest mocks base method.
+func (m *MockRegistry) GetDigest(ctx context.Context, image string, tlsOptions *v1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDigest", ctx, image, tlsOptions, registryAuthGetter)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDigest indicates an expected call of GetDigest.
+func (mr *MockRegistryMockRecorder) GetDigest(ctx, image, tlsOptions, registryAuthGetter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDigest", reflect.TypeOf((*MockRegistry)(nil).GetDigest), ctx, image, tlsOptions, registryAuthGetter)
+}
+
 // GetHeaderDataFromLayer mocks base method.
 func (m *MockRegistry) GetHeaderDataFromLayer(layer v1.Layer, headerName string) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -100,6 +129,21 @@ func (mr *MockRegistryMockRecorder) GetHeaderDataFromLayer(layer, headerName int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeaderDataFromLayer", reflect.TypeOf((*MockRegistry)(nil).GetHeaderDataFromLayer), layer, headerName)
 }
 
+// GetImage mocks base method.
+func (m *MockRegistry) GetImage(ctx context.Context, image string, tlsOptions *v1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (v1.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImage", ctx, image, tlsOptions, registryAuthGetter)
+	ret0, _ := ret[0].(v1.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImage indicates an expected call of GetImage.
+func (mr *MockRegistryMockRecorder) GetImage(ctx, image, tlsOptions, registryAuthGetter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockRegistry)(nil).GetImage), ctx, image, tlsOptions, registryAuthGetter)
+}
+
 // GetImageByName mocks base method.
 func (m *MockRegistry) GetImageByName(imageName string, auth authn.Authenticator, insecure, skipTLSVerify bool) (v1.Image, error) {
 	m.ctrl.T.Helper()
@@ -162,18 +206,18 @@ func (mr *MockRegistryMockRecorder) GetLayersDigests(ctx, image, tlsOptions, reg
 }
 
 // ImageExists mocks base method.
-func (m *MockRegistry) ImageExists(ctx context.Context, image string, tlsOptions *v1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (bool, error) {
+func (m *MockRegistry) ImageExists(ctx context.Context, image, arch string, tlsOptions *v1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ImageExists", ctx, image, tlsOptions, registryAuthGetter)
+	ret := m.ctrl.Call(m, "ImageExists", ctx, image, arch, tlsOptions, registryAuthGetter)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ImageExists indicates an expected call of ImageExists.
-func (mr *MockRegistryMockRecorder) ImageExists(ctx, image, tlsOptions, registryAuthGetter interface{}) *gomock.Call {
+func (mr *MockRegistryMockRecorder) ImageExists(ctx, image, arch, tlsOptions, registryAuthGetter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImageExists", reflect.TypeOf((*MockRegistry)(nil).ImageExists), ctx, image, tlsOptions, registryAuthGetter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImageExists", reflect.TypeOf((*MockRegistry)(nil).ImageExists), ctx, image, arch, tlsOptions, registryAuthGetter)
 }
 
 // LastLayer mocks base method.
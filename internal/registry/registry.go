@@ -31,6 +31,9 @@ const (
 	modulesLocationPath = "lib/modules"
 )
 
+// errArchNotFound is returned when a multi-architecture image does not contain an image for the requested architecture.
+var errArchNotFound = errors.New("architecture not found in the manifest list")
+
 type DriverToolkitEntry struct {
 	ImageURL            string `json:"imageURL"`
 	KernelFullVersion   string `json:"kernelFullVersion"`
@@ -46,7 +49,7 @@ type RepoPullConfig struct {
 //go:generate mockgen -source=registry.go -package=registry -destination=mock_registry_api.go
 
 type Registry interface {
-	ImageExists(ctx context.Context, image string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (bool, error)
+	ImageExists(ctx context.Context, image, arch string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (bool, error)
 	VerifyModuleExists(layer v1.Layer, pathPrefix, kernelVersion, moduleFileName string) bool
 	GetLayersDigests(ctx context.Context, image string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) ([]string, *RepoPullConfig, error)
 	GetLayerByDigest(digest string, pullConfig *RepoPullConfig) (v1.Layer, error)
@@ -59,6 +62,9 @@ type Registry interface {
 	GetHeaderDataFromLayer(layer v1.Layer, headerName string) ([]byte, error)
 	WriteImageByName(imageName string, image v1.Image, auth authn.Authenticator, insecure bool, skipTLSVerify bool) error
 	GetImageByName(imageName string, auth authn.Authenticator, insecure bool, skipTLSVerify bool) (v1.Image, error)
+	GetImage(ctx context.Context, image string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (v1.Image, error)
+	GetDigest(ctx context.Context, image string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (string, error)
+	CopyImage(ctx context.Context, src, dst string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) error
 }
 
 type registry struct {
@@ -68,20 +74,58 @@ func NewRegistry() Registry {
 	return &registry{}
 }
 
-func (r *registry) ImageExists(ctx context.Context, image string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (bool, error) {
-	_, _, err := r.getImageManifest(ctx, image, tlsOptions, registryAuthGetter)
+// ImageExists returns true if image exists for arch.
+// For multi-architecture images, the manifest list must contain an image for arch; single-architecture images
+// are assumed to be built for arch.
+// If arch is empty, the architecture of the operator is used.
+func (r *registry) ImageExists(ctx context.Context, image, arch string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (bool, error) {
+	_, _, err := r.getImageManifest(ctx, image, arch, tlsOptions, registryAuthGetter)
 	if err != nil {
 		te := &transport.Error{}
 		if errors.As(err, &te) && te.StatusCode == http.StatusNotFound {
 			return false, nil
 		}
+		if errors.Is(err, errArchNotFound) {
+			return false, nil
+		}
 		return false, fmt.Errorf("could not get image %s: %w", image, err)
 	}
 	return true, nil
 }
 
+// GetDigest returns the digest of the manifest that image refers to.
+// For multi-architecture images, this is the digest of the manifest list.
+func (r *registry) GetDigest(ctx context.Context, image string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (string, error) {
+	pullConfig, err := r.getPullOptions(ctx, image, tlsOptions, registryAuthGetter)
+	if err != nil {
+		return "", fmt.Errorf("failed to get pull options for image %s: %w", image, err)
+	}
+
+	digest, err := crane.Digest(image, pullConfig.authOptions...)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the digest of image %s: %w", image, err)
+	}
+
+	return digest, nil
+}
+
+// CopyImage copies all the platforms of src to dst, which may be in another repository.
+// Both images are accessed with the same options.
+func (r *registry) CopyImage(ctx context.Context, src, dst string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) error {
+	pullConfig, err := r.getPullOptions(ctx, src, tlsOptions, registryAuthGetter)
+	if err != nil {
+		return fmt.Errorf("failed to get pull options for image %s: %w", src, err)
+	}
+
+	if err = crane.Copy(src, dst, pullConfig.authOptions...); err != nil {
+		return fmt.Errorf("failed to copy image %s to %s: %w", src, dst, err)
+	}
+
+	return nil
+}
+
 func (r *registry) GetLayersDigests(ctx context.Context, image string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) ([]string, *RepoPullConfig, error) {
-	manifest, pullConfig, err := r.getImageManifest(ctx, image, tlsOptions, registryAuthGetter)
+	manifest, pullConfig, err := r.getImageManifest(ctx, image, "", tlsOptions, registryAuthGetter)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get manifest from image %s: %w", image, err)
 	}
@@ -124,8 +168,9 @@ func (r *registry) getPullOptions(ctx context.Context, image string, tlsOptions
 	var repo string
 	if hash := strings.Split(image, "@"); len(hash) > 1 {
 		repo = hash[0]
-	} else if tag := strings.Split(image, ":"); len(tag) > 1 {
-		repo = tag[0]
+	} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		// the tag is after the last slash; a colon before it separates the registry host from its port
+		repo = image[:i]
 	}
 
 	if repo == "" {
@@ -166,12 +211,12 @@ func (r *registry) getPullOptions(ctx context.Context, image string, tlsOptions
 	return &RepoPullConfig{repo: repo, authOptions: options}, nil
 }
 
-func (r *registry) getImageManifest(ctx context.Context, image string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) ([]byte, *RepoPullConfig, error) {
+func (r *registry) getImageManifest(ctx context.Context, image, arch string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) ([]byte, *RepoPullConfig, error) {
 	pullConfig, err := r.getPullOptions(ctx, image, tlsOptions, registryAuthGetter)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get pull options for image %s: %w", image, err)
 	}
-	manifest, err := r.getManifestStreamFromImage(image, pullConfig.repo, pullConfig.authOptions)
+	manifest, err := r.getManifestStreamFromImage(image, arch, pullConfig.repo, pullConfig.authOptions)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get manifest stream from image %s: %w", image, err)
 	}
@@ -179,7 +224,7 @@ func (r *registry) getImageManifest(ctx context.Context, image string, tlsOption
 	return manifest, pullConfig, nil
 }
 
-func (r *registry) getManifestStreamFromImage(image, repo string, options []crane.Option) ([]byte, error) {
+func (r *registry) getManifestStreamFromImage(image, arch, repo string, options []crane.Option) ([]byte, error) {
 	manifest, err := crane.Manifest(image, options...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get crane manifest from image %s: %w", image, err)
@@ -198,8 +243,8 @@ func (r *registry) getManifestStreamFromImage(image, repo string, options []cran
 		return nil, fmt.Errorf("mediaType is missing from the image %s manifest", image)
 	}
 
-	if strings.Contains(imageMediaType, "manifest.list") {
-		archDigest, err := r.getImageDigestFromMultiImage(manifest)
+	if strings.Contains(imageMediaType, "manifest.list") || strings.Contains(imageMediaType, "image.index") {
+		archDigest, err := r.getImageDigestFromMultiImage(manifest, arch)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get arch digets from multi arch image: %w", err)
 		}
@@ -270,8 +315,13 @@ func (r *registry) getHeaderReaderFromLayer(layer v1.Layer, headerName string) (
 	return nil, nil, fmt.Errorf("header %s not found in the layer", headerName)
 }
 
-func (r *registry) getImageDigestFromMultiImage(manifestListStream []byte) (string, error) {
-	arch := runtime.GOARCH
+// getImageDigestFromMultiImage returns the digest of the image for arch in a manifest list.
+// If arch is empty, the architecture of the operator is used.
+func (r *registry) getImageDigestFromMultiImage(manifestListStream []byte, arch string) (string, error) {
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+
 	manifestList := v1.IndexManifest{}
 
 	if err := json.Unmarshal(manifestListStream, &manifestList); err != nil {
@@ -282,7 +332,7 @@ func (r *registry) getImageDigestFromMultiImage(manifestListStream []byte) (stri
 			return manifest.Digest.Algorithm + ":" + manifest.Digest.Hex, nil
 		}
 	}
-	return "", fmt.Errorf("Failed to find manifest for architecture %s", arch)
+	return "", fmt.Errorf("failed to find manifest for architecture %s: %w", arch, errArchNotFound)
 }
 
 func (r *registry) AddLayerToImage(tarfile string, image v1.Image) (v1.Image, error) {
@@ -354,7 +404,7 @@ func (r *registry) WalkFilesInImage(image v1.Image, fn func(filename string, hea
 			}
 			err = fn(header.Name, header, tarreader, data)
 			if err != nil {
-				return fmt.Errorf("died processing file %s: %v", header.Name, err)
+				return fmt.Errorf("died processing file %s: %w", header.Name, err)
 			}
 		}
 	}
@@ -454,3 +504,18 @@ func (r *registry) GetImageByName(imageName string, auth authn.Authenticator, in
 
 	return img, nil
 }
+
+// GetImage returns image, pulled with the same options as the other images of the module.
+func (r *registry) GetImage(ctx context.Context, image string, tlsOptions *kmmv1beta1.TLSOptions, registryAuthGetter auth.RegistryAuthGetter) (v1.Image, error) {
+	pullConfig, err := r.getPullOptions(ctx, image, tlsOptions, registryAuthGetter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull options for image %s: %w", image, err)
+	}
+
+	img, err := crane.Pull(image, pullConfig.authOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("could not get image %s: %w", image, err)
+	}
+
+	return img, nil
+}
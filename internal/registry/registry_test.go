@@ -2,6 +2,7 @@ package registry
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-containerregistry/pkg/authn"
@@ -58,7 +60,7 @@ var _ = Describe("ImageExists", func() {
 
 		It("should fail if the image name isn't valid", func() {
 
-			_, err = reg.ImageExists(ctx, invalidImage, &kmmv1beta1.TLSOptions{}, nil)
+			_, err = reg.ImageExists(ctx, invalidImage, "", &kmmv1beta1.TLSOptions{}, nil)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("does not contain hash or tag"))
@@ -68,7 +70,7 @@ var _ = Describe("ImageExists", func() {
 
 			mockRegistryAuthGetter.EXPECT().GetKeyChain(ctx).Return(nil, errors.New("some error"))
 
-			_, err = reg.ImageExists(ctx, validImage, &kmmv1beta1.TLSOptions{}, mockRegistryAuthGetter)
+			_, err = reg.ImageExists(ctx, validImage, "", &kmmv1beta1.TLSOptions{}, mockRegistryAuthGetter)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("cannot get keychain from the registry auth getter"))
@@ -93,7 +95,7 @@ var _ = Describe("ImageExists", func() {
 			u := mustParseURL(server.URL)
 
 			image := fmt.Sprintf("%s/%s/%s:%s", u.Host, validImageOrg, validImageName, validImageTag)
-			_, err = reg.ImageExists(ctx, image, &kmmv1beta1.TLSOptions{}, nil)
+			_, err = reg.ImageExists(ctx, image, "", &kmmv1beta1.TLSOptions{}, nil)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to get crane manifest from image"))
@@ -108,7 +110,7 @@ var _ = Describe("ImageExists", func() {
 			u := mustParseURL(server.URL)
 
 			image := fmt.Sprintf("%s/%s/%s:%s", u.Host, validImageOrg, validImageName, validImageTag)
-			_, err = reg.ImageExists(ctx, image, &kmmv1beta1.TLSOptions{}, nil)
+			_, err = reg.ImageExists(ctx, image, "", &kmmv1beta1.TLSOptions{}, nil)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to unmarshal crane manifest"))
@@ -134,7 +136,7 @@ var _ = Describe("ImageExists", func() {
 			u := mustParseURL(server.URL)
 
 			image := fmt.Sprintf("%s/%s/%s:%s", u.Host, validImageOrg, validImageName, validImageTag)
-			_, err = reg.ImageExists(ctx, image, &kmmv1beta1.TLSOptions{}, nil)
+			_, err = reg.ImageExists(ctx, image, "", &kmmv1beta1.TLSOptions{}, nil)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("mediaType is missing from the image"))
@@ -150,7 +152,7 @@ var _ = Describe("ImageExists", func() {
 		u := mustParseURL(server.URL)
 
 		image := fmt.Sprintf("%s/%s/%s:%s", u.Host, validImageOrg, validImageName, validImageTag)
-		_, err := reg.ImageExists(ctx, image, &kmmv1beta1.TLSOptions{}, nil)
+		_, err := reg.ImageExists(ctx, image, "", &kmmv1beta1.TLSOptions{}, nil)
 
 		Expect(err).ToNot(HaveOccurred())
 	})
@@ -173,15 +175,56 @@ var _ = Describe("ImageExists", func() {
 		var err error
 		image := fmt.Sprintf("%s/%s/%s:%s", u.Host, validImageOrg, validImageName, validImageTag)
 		if withRegistryAuthGetter {
-			_, err = reg.ImageExists(ctx, image, &kmmv1beta1.TLSOptions{}, mockRegistryAuthGetter)
+			_, err = reg.ImageExists(ctx, image, "", &kmmv1beta1.TLSOptions{}, mockRegistryAuthGetter)
 		} else {
-			_, err = reg.ImageExists(ctx, image, &kmmv1beta1.TLSOptions{}, nil)
+			_, err = reg.ImageExists(ctx, image, "", &kmmv1beta1.TLSOptions{}, nil)
 		}
 		Expect(err).ToNot(HaveOccurred())
 	},
 		Entry("with public registry", false),
 		Entry("with private registry", true),
 	)
+
+	DescribeTable("should check the platforms of manifest lists", func(arch string, expected bool) {
+		imageManifest, err := os.ReadFile("testdata/image_manifest.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		imageDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(imageManifest))
+
+		manifestList, err := json.Marshal(map[string]interface{}{
+			"schemaVersion": 2,
+			"mediaType":     "application/vnd.docker.distribution.manifest.list.v2+json",
+			"manifests": []map[string]interface{}{
+				{
+					"mediaType": "application/vnd.oci.image.manifest.v1+json",
+					"size":      len(imageManifest),
+					"digest":    imageDigest,
+					"platform":  map[string]string{"architecture": "arm64", "os": "linux"},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, imageDigest) {
+				_, err = w.Write(imageManifest)
+			} else {
+				_, err = w.Write(manifestList)
+			}
+			Expect(err).NotTo(HaveOccurred())
+		}))
+		defer server.Close()
+		u := mustParseURL(server.URL)
+
+		image := fmt.Sprintf("%s/%s/%s:%s", u.Host, validImageOrg, validImageName, validImageTag)
+
+		exists, err := reg.ImageExists(ctx, image, arch, &kmmv1beta1.TLSOptions{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(Equal(expected))
+	},
+		Entry("architecture in the list", "arm64", true),
+		Entry("architecture not in the list", "amd64", false),
+	)
 })
 
 var _ = Describe("GetLayersDigests", func() {
@@ -223,7 +266,7 @@ var _ = Describe("GetLayersDigests", func() {
 
 		It("should fail if the image name isn't valid", func() {
 
-			_, err = reg.ImageExists(ctx, invalidImage, &kmmv1beta1.TLSOptions{}, nil)
+			_, err = reg.ImageExists(ctx, invalidImage, "", &kmmv1beta1.TLSOptions{}, nil)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("does not contain hash or tag"))
@@ -233,7 +276,7 @@ var _ = Describe("GetLayersDigests", func() {
 
 			mockRegistryAuthGetter.EXPECT().GetKeyChain(ctx).Return(nil, errors.New("some error"))
 
-			_, err = reg.ImageExists(ctx, validImage, &kmmv1beta1.TLSOptions{}, mockRegistryAuthGetter)
+			_, err = reg.ImageExists(ctx, validImage, "", &kmmv1beta1.TLSOptions{}, mockRegistryAuthGetter)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("cannot get keychain from the registry auth getter"))
@@ -335,6 +378,129 @@ var _ = Describe("GetLayersDigests", func() {
 	)
 })
 
+var _ = Describe("GetImage", func() {
+	var (
+		ctx context.Context
+		reg Registry
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		reg = NewRegistry()
+	})
+
+	It("should fail if the image name isn't valid", func() {
+		_, err := reg.GetImage(ctx, "non-valid-image-name", &kmmv1beta1.TLSOptions{}, nil)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to get pull options for image"))
+	})
+
+	It("should fail if the image cannot be pulled", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+		u := mustParseURL(server.URL)
+
+		_, err := reg.GetImage(ctx, u.Host+"/org/image-name:some-tag", &kmmv1beta1.TLSOptions{}, nil)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("could not get image"))
+	})
+})
+
+var _ = Describe("GetDigest", func() {
+	var (
+		ctx context.Context
+		reg Registry
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		reg = NewRegistry()
+	})
+
+	It("should fail if the image name isn't valid", func() {
+		_, err := reg.GetDigest(ctx, "non-valid-image-name", &kmmv1beta1.TLSOptions{}, nil)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to get pull options for image"))
+	})
+
+	It("should fail if the image does not exist", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+		u := mustParseURL(server.URL)
+
+		_, err := reg.GetDigest(ctx, u.Host+"/org/image-name:some-tag", &kmmv1beta1.TLSOptions{}, nil)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to get the digest of image"))
+	})
+
+	It("should return the digest of the manifest", func() {
+		manifest, err := os.ReadFile("testdata/image_manifest.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", digest)
+			w.Header().Set("Content-Length", fmt.Sprint(len(manifest)))
+
+			if r.Method == http.MethodHead {
+				return
+			}
+
+			_, err := w.Write(manifest)
+			Expect(err).NotTo(HaveOccurred())
+		}))
+		defer server.Close()
+		u := mustParseURL(server.URL)
+
+		res, err := reg.GetDigest(ctx, u.Host+"/org/image-name:some-tag", &kmmv1beta1.TLSOptions{}, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(digest))
+	})
+})
+
+var _ = Describe("CopyImage", func() {
+	var (
+		ctx context.Context
+		reg Registry
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		reg = NewRegistry()
+	})
+
+	It("should fail if the image name isn't valid", func() {
+		err := reg.CopyImage(ctx, "non-valid-image-name", "registry.example.com/org/image:tag", &kmmv1beta1.TLSOptions{}, nil)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to get pull options for image"))
+	})
+
+	It("should fail if the source image does not exist", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+		u := mustParseURL(server.URL)
+
+		err := reg.CopyImage(ctx, u.Host+"/org/image-name:some-tag", u.Host+"/org/other-image:some-tag", &kmmv1beta1.TLSOptions{}, nil)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to copy image"))
+	})
+})
+
 var _ = Describe("VerifyModuleExists", func() {
 	reg := NewRegistry()
 
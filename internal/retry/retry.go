@@ -0,0 +1,161 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+const (
+	// AttemptAnnotation is the attempt a Build or a Job was created for, starting from 1.
+	// Builds and Jobs created from a new template do not have it, which resets the count when the spec changes.
+	AttemptAnnotation = "kmm.node.kubernetes.io/attempt"
+
+	// FailureReasonAnnotation is the reason of the failure of a Build or a Job, as classified from its log.
+	FailureReasonAnnotation = "kmm.node.kubernetes.io/failure-reason"
+
+	DefaultInitialBackoff = 30 * time.Second
+	DefaultMaxBackoff     = 10 * time.Minute
+)
+
+// Attempt returns the attempt obj was created for.
+func Attempt(obj metav1.Object) int32 {
+	attempt, err := strconv.ParseInt(obj.GetAnnotations()[AttemptAnnotation], 10, 32)
+	if err != nil || attempt < 1 {
+		return 1
+	}
+
+	return int32(attempt)
+}
+
+// SetAttempt records on obj the attempt it is created for.
+func SetAttempt(obj metav1.Object, attempt int32) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[AttemptAnnotation] = strconv.FormatInt(int64(attempt), 10)
+
+	obj.SetAnnotations(annotations)
+}
+
+// Backoff returns the delay between the failure of attempt and the creation of the next one.
+func Backoff(policy *kmmv1beta1.RetryPolicy, attempt int32) time.Duration {
+	initial, max := DefaultInitialBackoff, DefaultMaxBackoff
+
+	if policy.InitialBackoff != nil {
+		initial = policy.InitialBackoff.Duration
+	}
+
+	if policy.MaxBackoff != nil {
+		max = policy.MaxBackoff.Duration
+	}
+
+	backoff := initial
+
+	for i := int32(1); i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+
+	if backoff > max {
+		return max
+	}
+
+	return backoff
+}
+
+// NextAttempt returns when the failed obj, which failed at failedAt, should be replaced by a new attempt.
+// It returns false if policy does not retry that failure: if policy is nil, if the maximum number of attempts is
+// reached, or if the failure reason recorded on obj is not one that is retried.
+// Failures are not retried until their reason is recorded if the policy only retries some reasons.
+func NextAttempt(policy *kmmv1beta1.RetryPolicy, obj metav1.Object, failedAt time.Time) (time.Time, bool) {
+	if policy == nil {
+		return time.Time{}, false
+	}
+
+	attempt := Attempt(obj)
+
+	if attempt >= policy.MaxAttempts {
+		return time.Time{}, false
+	}
+
+	if len(policy.RetryOn) > 0 {
+		reason := kmmv1beta1.StageFailureReason(obj.GetAnnotations()[FailureReasonAnnotation])
+
+		if !isRetriedOn(policy.RetryOn, reason) {
+			return time.Time{}, false
+		}
+	}
+
+	return failedAt.Add(Backoff(policy, attempt)), true
+}
+
+func isRetriedOn(reasons []kmmv1beta1.StageFailureReason, reason kmmv1beta1.StageFailureReason) bool {
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JobFailedAt returns when job failed.
+func JobFailedAt(job *batchv1.Job) time.Time {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == v1.ConditionTrue {
+			return c.LastTransitionTime.Time
+		}
+	}
+
+	return job.CreationTimestamp.Time
+}
+
+// RetryJob replaces the failed Job with a new attempt created from jobTemplate, if policy retries that failure and
+// its backoff has elapsed at now.
+// It returns StatusFailed if the Job is not retried, StatusRetrying while the backoff has not elapsed and
+// StatusCreated once the new attempt is created.
+// The new attempt is created before the failed Job is deleted, so that the count is never lost.
+func RetryJob(
+	ctx context.Context,
+	jobHelper utils.JobHelper,
+	policy *kmmv1beta1.RetryPolicy,
+	failed *batchv1.Job,
+	jobTemplate *batchv1.Job,
+	now time.Time) (utils.Status, error) {
+
+	retryAt, ok := NextAttempt(policy, failed, JobFailedAt(failed))
+	if !ok {
+		return utils.StatusFailed, nil
+	}
+
+	if now.Before(retryAt) {
+		return utils.StatusRetrying, nil
+	}
+
+	attempt := Attempt(failed) + 1
+
+	log.FromContext(ctx).Info("Retrying the failed job", "name", failed.Name, "attempt", attempt)
+
+	SetAttempt(jobTemplate, attempt)
+
+	if err := jobHelper.CreateJob(ctx, jobTemplate); err != nil {
+		return "", fmt.Errorf("could not create attempt %d: %v", attempt, err)
+	}
+
+	if err := jobHelper.DeleteJob(ctx, failed); err != nil {
+		return "", fmt.Errorf("could not delete the failed job %s: %v", failed.Name, err)
+	}
+
+	return utils.StatusCreated, nil
+}
@@ -0,0 +1,180 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+var _ = Describe("Attempt", func() {
+	It("should return 1 if the annotation is missing or invalid", func() {
+		Expect(Attempt(&batchv1.Job{})).To(BeEquivalentTo(1))
+
+		job := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{AttemptAnnotation: "not-a-number"},
+			},
+		}
+
+		Expect(Attempt(&job)).To(BeEquivalentTo(1))
+	})
+
+	It("should return the attempt that was set", func() {
+		job := batchv1.Job{}
+
+		SetAttempt(&job, 3)
+
+		Expect(Attempt(&job)).To(BeEquivalentTo(3))
+	})
+})
+
+var _ = Describe("Backoff", func() {
+	DescribeTable("should double the backoff after each attempt, up to the maximum",
+		func(policy kmmv1beta1.RetryPolicy, attempt int32, expected time.Duration) {
+			Expect(Backoff(&policy, attempt)).To(Equal(expected))
+		},
+		Entry("default, first attempt", kmmv1beta1.RetryPolicy{}, int32(1), DefaultInitialBackoff),
+		Entry("default, third attempt", kmmv1beta1.RetryPolicy{}, int32(3), 4*DefaultInitialBackoff),
+		Entry("default, capped", kmmv1beta1.RetryPolicy{}, int32(50), DefaultMaxBackoff),
+		Entry(
+			"custom",
+			kmmv1beta1.RetryPolicy{
+				InitialBackoff: &metav1.Duration{Duration: time.Second},
+				MaxBackoff:     &metav1.Duration{Duration: 5 * time.Second},
+			},
+			int32(4),
+			5*time.Second,
+		),
+	)
+})
+
+var _ = Describe("NextAttempt", func() {
+	failedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newJob := func(attempt int32, reason kmmv1beta1.StageFailureReason) *batchv1.Job {
+		job := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{FailureReasonAnnotation: string(reason)},
+			},
+		}
+
+		SetAttempt(&job, attempt)
+
+		return &job
+	}
+
+	It("should not retry without a policy", func() {
+		_, ok := NextAttempt(nil, newJob(1, kmmv1beta1.StageFailureReasonUnknown), failedAt)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should not retry once the maximum number of attempts is reached", func() {
+		_, ok := NextAttempt(&kmmv1beta1.RetryPolicy{MaxAttempts: 3}, newJob(3, kmmv1beta1.StageFailureReasonUnknown), failedAt)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should retry any failure after the backoff if no reason is listed", func() {
+		retryAt, ok := NextAttempt(&kmmv1beta1.RetryPolicy{MaxAttempts: 3}, newJob(2, ""), failedAt)
+		Expect(ok).To(BeTrue())
+		Expect(retryAt).To(Equal(failedAt.Add(2 * DefaultInitialBackoff)))
+	})
+
+	It("should only retry the listed reasons", func() {
+		policy := kmmv1beta1.RetryPolicy{
+			MaxAttempts: 3,
+			RetryOn:     []kmmv1beta1.StageFailureReason{kmmv1beta1.StageFailureReasonPushDenied},
+		}
+
+		_, ok := NextAttempt(&policy, newJob(1, kmmv1beta1.StageFailureReasonPushDenied), failedAt)
+		Expect(ok).To(BeTrue())
+
+		_, ok = NextAttempt(&policy, newJob(1, kmmv1beta1.StageFailureReasonCompileError), failedAt)
+		Expect(ok).To(BeFalse())
+
+		_, ok = NextAttempt(&policy, newJob(1, ""), failedAt)
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("JobFailedAt", func() {
+	It("should return the time of the Failed condition", func() {
+		created := metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+		failed := metav1.NewTime(created.Add(time.Minute))
+
+		job := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+		}
+
+		Expect(JobFailedAt(&job)).To(Equal(created.Time))
+
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: v1.ConditionTrue, LastTransitionTime: failed},
+		}
+
+		Expect(JobFailedAt(&job)).To(Equal(failed.Time))
+	})
+})
+
+var _ = Describe("RetryJob", func() {
+	var (
+		ctrl      *gomock.Controller
+		jobHelper *utils.MockJobHelper
+		failed    *batchv1.Job
+		template  *batchv1.Job
+	)
+
+	ctx := context.Background()
+	failedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := &kmmv1beta1.RetryPolicy{MaxAttempts: 3}
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		jobHelper = utils.NewMockJobHelper(ctrl)
+
+		failed = &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "failed", CreationTimestamp: metav1.NewTime(failedAt)},
+		}
+		template = &batchv1.Job{}
+	})
+
+	It("should return StatusFailed if the Job is not retried", func() {
+		status, err := RetryJob(ctx, jobHelper, nil, failed, template, failedAt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeEquivalentTo(utils.StatusFailed))
+	})
+
+	It("should return StatusRetrying until the backoff has elapsed", func() {
+		status, err := RetryJob(ctx, jobHelper, policy, failed, template, failedAt.Add(time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeEquivalentTo(utils.StatusRetrying))
+	})
+
+	It("should create the next attempt and delete the failed Job", func() {
+		gomock.InOrder(
+			jobHelper.EXPECT().CreateJob(ctx, template),
+			jobHelper.EXPECT().DeleteJob(ctx, failed),
+		)
+
+		status, err := RetryJob(ctx, jobHelper, policy, failed, template, failedAt.Add(DefaultInitialBackoff))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeEquivalentTo(utils.StatusCreated))
+		Expect(Attempt(template)).To(BeEquivalentTo(2))
+	})
+
+	It("should keep the failed Job if the next attempt could not be created", func() {
+		jobHelper.EXPECT().CreateJob(ctx, template).Return(errors.New("random error"))
+
+		_, err := RetryJob(ctx, jobHelper, policy, failed, template, failedAt.Add(DefaultInitialBackoff))
+		Expect(err).To(HaveOccurred())
+	})
+})
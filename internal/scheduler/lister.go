@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+// Lister returns the workloads that are running in the cluster.
+type Lister func(ctx context.Context) ([]Workload, error)
+
+// NewLister returns a Lister of the build and sign Jobs that have not finished and, if ocpBuilds is true, of the
+// OpenShift Builds that have not finished.
+func NewLister(reader client.Reader, ocpBuilds bool) Lister {
+	return func(ctx context.Context) ([]Workload, error) {
+		workloads, err := runningJobs(ctx, reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ocpBuilds {
+			return workloads, nil
+		}
+
+		builds, err := runningBuilds(ctx, reader)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(workloads, builds...), nil
+	}
+}
+
+func runningJobs(ctx context.Context, reader client.Reader) ([]Workload, error) {
+	jobList := batchv1.JobList{}
+
+	if err := reader.List(ctx, &jobList, client.HasLabels{constants.JobType, constants.ModuleNameLabel, constants.TargetKernelTarget}); err != nil {
+		return nil, fmt.Errorf("could not list Jobs: %v", err)
+	}
+
+	workloads := make([]Workload, 0, len(jobList.Items))
+
+	for _, j := range jobList.Items {
+		jobType := j.Labels[constants.JobType]
+
+		if jobType != utils.JobTypeBuild && jobType != utils.JobTypeSign {
+			continue
+		}
+
+		if j.Status.Succeeded > 0 || j.Status.Failed > 0 {
+			continue
+		}
+
+		workloads = append(workloads, workloadFromLabels(jobType, j.Namespace, j.Labels))
+	}
+
+	return workloads, nil
+}
+
+func runningBuilds(ctx context.Context, reader client.Reader) ([]Workload, error) {
+	buildList := buildv1.BuildList{}
+
+	if err := reader.List(ctx, &buildList, client.HasLabels{constants.ModuleNameLabel, constants.TargetKernelTarget}); err != nil {
+		return nil, fmt.Errorf("could not list Builds: %v", err)
+	}
+
+	workloads := make([]Workload, 0, len(buildList.Items))
+
+	for _, b := range buildList.Items {
+		switch b.Status.Phase {
+		case buildv1.BuildPhaseNew, buildv1.BuildPhasePending, buildv1.BuildPhaseRunning:
+			workloads = append(workloads, workloadFromLabels(utils.JobTypeBuild, b.Namespace, b.Labels))
+		}
+	}
+
+	return workloads, nil
+}
+
+// workloadFromLabels returns the workload of type jobType identified by the labels of its Job or Build.
+func workloadFromLabels(jobType, namespace string, labels map[string]string) Workload {
+	return Workload{
+		Type:          jobType,
+		Namespace:     namespace,
+		Name:          labels[constants.ModuleNameLabel],
+		KernelVersion: labels[constants.TargetKernelTarget],
+		Arch:          labels[constants.ArchLabel],
+	}
+}
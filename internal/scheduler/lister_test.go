@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	buildv1 "github.com/openshift/api/build/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+var _ = Describe("NewLister", func() {
+	var (
+		ctrl *gomock.Controller
+		clnt *client.MockClient
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+	})
+
+	objectMeta := func(name, jobType string) metav1.ObjectMeta {
+		labels := map[string]string{
+			constants.ModuleNameLabel:    name,
+			constants.TargetKernelTarget: "some-kernel",
+			constants.ArchLabel:          "x86_64",
+		}
+
+		if jobType != "" {
+			labels[constants.JobType] = jobType
+		}
+
+		return metav1.ObjectMeta{Name: name + "-" + jobType, Namespace: "ns", Labels: labels}
+	}
+
+	jobs := []batchv1.Job{
+		{ObjectMeta: objectMeta("a", utils.JobTypeBuild), Status: batchv1.JobStatus{Active: 1}},
+		{ObjectMeta: objectMeta("b", utils.JobTypeSign)},
+		{ObjectMeta: objectMeta("c", utils.JobTypeBuild), Status: batchv1.JobStatus{Succeeded: 1}},
+		{ObjectMeta: objectMeta("d", utils.JobTypeSign), Status: batchv1.JobStatus{Failed: 1}},
+		{ObjectMeta: objectMeta("e", "other")},
+	}
+
+	expectJobs := func() *gomock.Call {
+		return clnt.
+			EXPECT().
+			List(context.Background(), &batchv1.JobList{}, gomock.Any()).
+			DoAndReturn(func(_ context.Context, list *batchv1.JobList, _ ...interface{}) error {
+				list.Items = jobs
+				return nil
+			})
+	}
+
+	It("should return the build and sign Jobs that have not finished", func() {
+		expectJobs()
+
+		workloads, err := NewLister(clnt, false)(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workloads).To(ConsistOf(
+			workload("ns", "a"),
+			Workload{Type: utils.JobTypeSign, Namespace: "ns", Name: "b", KernelVersion: "some-kernel", Arch: "x86_64"},
+		))
+	})
+
+	It("should also return the OpenShift Builds that have not finished", func() {
+		builds := []buildv1.Build{
+			{ObjectMeta: objectMeta("f", ""), Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseRunning}},
+			{ObjectMeta: objectMeta("g", ""), Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseComplete}},
+		}
+
+		gomock.InOrder(
+			expectJobs(),
+			clnt.
+				EXPECT().
+				List(context.Background(), &buildv1.BuildList{}, gomock.Any()).
+				DoAndReturn(func(_ context.Context, list *buildv1.BuildList, _ ...interface{}) error {
+					list.Items = builds
+					return nil
+				}),
+		)
+
+		workloads, err := NewLister(clnt, true)(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workloads).To(ContainElement(workload("ns", "f")))
+		Expect(workloads).NotTo(ContainElement(workload("ns", "g")))
+		Expect(workloads).To(HaveLen(3))
+	})
+
+	It("should return an error if the Jobs cannot be listed", func() {
+		clnt.EXPECT().List(context.Background(), &batchv1.JobList{}, gomock.Any()).Return(errors.New("some error"))
+
+		_, err := NewLister(clnt, true)(context.Background())
+		Expect(err).To(HaveOccurred())
+	})
+})
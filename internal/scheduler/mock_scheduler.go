@@ -0,0 +1,89 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: scheduler.go
+
+// Package scheduler is a generated GoMock package.
+package scheduler
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	utils "github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+// MockScheduler is a mock of Scheduler interface.
+type MockScheduler struct {
+	ctrl     *gomock.Controller
+	recorder *MockSchedulerMockRecorder
+}
+
+// MockSchedulerMockRecorder is the mock recorder for MockScheduler.
+type MockSchedulerMockRecorder struct {
+	mock *MockScheduler
+}
+
+// NewMockScheduler creates a new mock instance.
+func NewMockScheduler(ctrl *gomock.Controller) *MockScheduler {
+	mock := &MockScheduler{ctrl: ctrl}
+	mock.recorder = &MockSchedulerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScheduler) EXPECT() *MockSchedulerMockRecorder {
+	return m.recorder
+}
+
+// Admit mocks base method.
+func (m *MockScheduler) Admit(ctx context.Context, w Workload, priority int32) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Admit", ctx, w, priority)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Admit indicates an expected call of Admit.
+func (mr *MockSchedulerMockRecorder) Admit(ctx, w, priority interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Admit", reflect.TypeOf((*MockScheduler)(nil).Admit), ctx, w, priority)
+}
+
+// Observe mocks base method.
+func (m *MockScheduler) Observe(w Workload, status utils.Status) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Observe", w, status)
+}
+
+// Observe indicates an expected call of Observe.
+func (mr *MockSchedulerMockRecorder) Observe(w, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Observe", reflect.TypeOf((*MockScheduler)(nil).Observe), w, status)
+}
+
+// Position mocks base method.
+func (m *MockScheduler) Position(w Workload) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Position", w)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Position indicates an expected call of Position.
+func (mr *MockSchedulerMockRecorder) Position(w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Position", reflect.TypeOf((*MockScheduler)(nil).Position), w)
+}
+
+// Release mocks base method.
+func (m *MockScheduler) Release(w Workload) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Release", w)
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockSchedulerMockRecorder) Release(w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockScheduler)(nil).Release), w)
+}
@@ -0,0 +1,269 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+// LeaseDuration is how long a running or queued workload is remembered after it was last seen.
+// Workloads are seen each time their Module is reconciled; a Module deleted during its build releases its slot once
+// the lease has expired.
+const LeaseDuration = 5 * time.Minute
+
+// Workload is a Build or a sign Job of a Module for a kernel version.
+type Workload struct {
+	// Type is either utils.JobTypeBuild or utils.JobTypeSign.
+	Type          string
+	Namespace     string
+	Name          string
+	KernelVersion string
+	Arch          string
+}
+
+// WorkloadFor returns the workload of type jobType for mld.
+func WorkloadFor(mld *api.ModuleLoaderData, jobType string) Workload {
+	return Workload{
+		Type:          jobType,
+		Namespace:     mld.Namespace,
+		Name:          mld.Name,
+		KernelVersion: mld.KernelVersion,
+		Arch:          mld.Arch,
+	}
+}
+
+// Limits are the maximum numbers of concurrent Builds and sign Jobs.
+// A zero value means no limit.
+type Limits struct {
+	MaxConcurrent             int
+	MaxConcurrentPerNamespace int
+}
+
+//go:generate mockgen -source=scheduler.go -package=scheduler -destination=mock_scheduler.go
+
+// Scheduler limits the number of Builds and sign Jobs running concurrently.
+// Workloads that cannot start are queued by decreasing priority, then in the order in which they were first seen.
+type Scheduler interface {
+	// Admit returns 0 if w may start now, in which case it is counted as running until it is released.
+	// Otherwise, w is queued with priority and its position in the queue, starting from 1, is returned.
+	// The first call counts the workloads already running in the cluster, e.g. before a restart, as running.
+	Admit(ctx context.Context, w Workload, priority int32) (int, error)
+	// Observe records the status of the existing Build or Job of w.
+	// w is released once it has completed or failed for good, and counted as running otherwise, including while it
+	// waits for its next attempt.
+	Observe(w Workload, status utils.Status)
+	// Release frees the slot of w, or removes it from the queue.
+	Release(w Workload)
+	// Position returns the position of w in the queue, starting from 1, or 0 if it is not queued.
+	Position(w Workload) int
+}
+
+type queued struct {
+	priority int32
+	seq      uint64
+	expires  time.Time
+}
+
+type scheduler struct {
+	limits Limits
+	lister Lister
+	clock  clock.PassiveClock
+
+	mu       sync.Mutex
+	restored bool
+	running  map[Workload]time.Time
+	queue    map[Workload]*queued
+	seq      uint64
+}
+
+// New returns a Scheduler enforcing limits.
+// Slots are only tracked in memory; lister, if not nil, lists the workloads that are already running so that the
+// limits still hold after a restart.
+// If limits has no maximum, all workloads are admitted right away and nothing is tracked.
+func New(limits Limits, lister Lister) Scheduler {
+	return &scheduler{
+		limits:  limits,
+		lister:  lister,
+		clock:   clock.RealClock{},
+		running: make(map[Workload]time.Time),
+		queue:   make(map[Workload]*queued),
+	}
+}
+
+func (s *scheduler) unlimited() bool {
+	return s.limits.MaxConcurrent <= 0 && s.limits.MaxConcurrentPerNamespace <= 0
+}
+
+func (s *scheduler) Admit(ctx context.Context, w Workload, priority int32) (int, error) {
+	if s.unlimited() {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	if err := s.restore(ctx, now); err != nil {
+		return 0, err
+	}
+
+	s.expire(now)
+
+	if _, ok := s.running[w]; ok {
+		s.running[w] = now.Add(LeaseDuration)
+		return 0, nil
+	}
+
+	q, ok := s.queue[w]
+	if !ok {
+		s.seq++
+		q = &queued{seq: s.seq}
+		s.queue[w] = q
+	}
+
+	q.priority = priority
+	q.expires = now.Add(LeaseDuration)
+
+	admitted, position := s.rank(w)
+	if !admitted {
+		return position, nil
+	}
+
+	delete(s.queue, w)
+	s.running[w] = now.Add(LeaseDuration)
+
+	return 0, nil
+}
+
+func (s *scheduler) Observe(w Workload, status utils.Status) {
+	if s.unlimited() {
+		return
+	}
+
+	if status == utils.StatusCompleted || status == utils.StatusFailed {
+		s.Release(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.queue, w)
+	s.running[w] = s.clock.Now().Add(LeaseDuration)
+}
+
+func (s *scheduler) Release(w Workload) {
+	if s.unlimited() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.queue, w)
+	delete(s.running, w)
+}
+
+func (s *scheduler) Position(w Workload) int {
+	if s.unlimited() {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(s.clock.Now())
+
+	if _, ok := s.queue[w]; !ok {
+		return 0
+	}
+
+	_, position := s.rank(w)
+
+	return position
+}
+
+// restore counts the workloads listed by the lister as running, once.
+func (s *scheduler) restore(ctx context.Context, now time.Time) error {
+	if s.restored || s.lister == nil {
+		return nil
+	}
+
+	workloads, err := s.lister(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list the running workloads: %v", err)
+	}
+
+	for _, w := range workloads {
+		s.running[w] = now.Add(LeaseDuration)
+	}
+
+	s.restored = true
+
+	return nil
+}
+
+// expire forgets the workloads whose lease has expired.
+func (s *scheduler) expire(now time.Time) {
+	for w, expires := range s.running {
+		if now.After(expires) {
+			delete(s.running, w)
+		}
+	}
+
+	for w, q := range s.queue {
+		if now.After(q.expires) {
+			delete(s.queue, w)
+		}
+	}
+}
+
+// rank walks the queue in order and returns whether the queued w may start, along with its position.
+// The workloads ahead of w that may start reserve a slot, so that w does not take it from them; those that cannot
+// start because their namespace is full do not prevent w from starting in another namespace.
+func (s *scheduler) rank(w Workload) (bool, int) {
+	ordered := make([]Workload, 0, len(s.queue))
+	for qw := range s.queue {
+		ordered = append(ordered, qw)
+	}
+
+	sort.Slice(ordered, func(i, j int) bool {
+		qi, qj := s.queue[ordered[i]], s.queue[ordered[j]]
+		if qi.priority != qj.priority {
+			return qi.priority > qj.priority
+		}
+		return qi.seq < qj.seq
+	})
+
+	total := len(s.running)
+	perNamespace := make(map[string]int)
+
+	for rw := range s.running {
+		perNamespace[rw.Namespace]++
+	}
+
+	for i, qw := range ordered {
+		canStart := (s.limits.MaxConcurrent <= 0 || total < s.limits.MaxConcurrent) &&
+			(s.limits.MaxConcurrentPerNamespace <= 0 || perNamespace[qw.Namespace] < s.limits.MaxConcurrentPerNamespace)
+
+		if qw == w {
+			return canStart, i + 1
+		}
+
+		if canStart {
+			total++
+			perNamespace[qw.Namespace]++
+		}
+	}
+
+	// w is always queued when rank is called
+	return false, 0
+}
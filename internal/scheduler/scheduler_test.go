@@ -0,0 +1,195 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	testclock "k8s.io/utils/clock/testing"
+
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+func workload(namespace, name string) Workload {
+	return Workload{
+		Type:          utils.JobTypeBuild,
+		Namespace:     namespace,
+		Name:          name,
+		KernelVersion: "some-kernel",
+		Arch:          "x86_64",
+	}
+}
+
+var _ = Describe("WorkloadFor", func() {
+	It("should identify the workload of the ModuleLoaderData", func() {
+		mld := &api.ModuleLoaderData{
+			Name:          "name",
+			Namespace:     "namespace",
+			KernelVersion: "some-kernel",
+			Arch:          "x86_64",
+		}
+
+		Expect(
+			WorkloadFor(mld, utils.JobTypeBuild),
+		).To(
+			Equal(workload("namespace", "name")),
+		)
+	})
+})
+
+var _ = Describe("Scheduler", func() {
+	var (
+		clk    *testclock.FakePassiveClock
+		lister Lister
+		s      *scheduler
+	)
+
+	ctx := context.Background()
+
+	newScheduler := func(limits Limits) {
+		s = New(limits, lister).(*scheduler)
+		s.clock = clk
+	}
+
+	BeforeEach(func() {
+		clk = testclock.NewFakePassiveClock(time.Now())
+		lister = nil
+	})
+
+	It("should admit everything without limits", func() {
+		newScheduler(Limits{})
+
+		for i := 0; i < 10; i++ {
+			Expect(s.Admit(ctx, workload("ns", fmt.Sprintf("mod%d", i)), 0)).To(Equal(0))
+		}
+
+		Expect(s.running).To(BeEmpty())
+	})
+
+	It("should queue workloads beyond the global limit in order", func() {
+		newScheduler(Limits{MaxConcurrent: 1})
+
+		Expect(s.Admit(ctx, workload("ns1", "a"), 0)).To(Equal(0))
+		Expect(s.Admit(ctx, workload("ns2", "b"), 0)).To(Equal(1))
+		Expect(s.Admit(ctx, workload("ns3", "c"), 0)).To(Equal(2))
+
+		By("admitting again a running workload")
+		Expect(s.Admit(ctx, workload("ns1", "a"), 0)).To(Equal(0))
+
+		By("not letting a later workload take the freed slot")
+		s.Release(workload("ns1", "a"))
+		Expect(s.Admit(ctx, workload("ns3", "c"), 0)).To(Equal(2))
+		Expect(s.Position(workload("ns3", "c"))).To(Equal(2))
+		Expect(s.Admit(ctx, workload("ns2", "b"), 0)).To(Equal(0))
+		Expect(s.Position(workload("ns2", "b"))).To(Equal(0))
+		Expect(s.Position(workload("ns3", "c"))).To(Equal(1))
+	})
+
+	It("should start workloads with a higher priority first", func() {
+		newScheduler(Limits{MaxConcurrent: 1})
+
+		Expect(s.Admit(ctx, workload("ns", "a"), 0)).To(Equal(0))
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(1))
+		Expect(s.Admit(ctx, workload("ns", "c"), 10)).To(Equal(1))
+		Expect(s.Position(workload("ns", "b"))).To(Equal(2))
+
+		s.Release(workload("ns", "a"))
+
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(2))
+		Expect(s.Admit(ctx, workload("ns", "c"), 10)).To(Equal(0))
+	})
+
+	It("should let other namespaces start when a namespace is full", func() {
+		newScheduler(Limits{MaxConcurrent: 3, MaxConcurrentPerNamespace: 1})
+
+		Expect(s.Admit(ctx, workload("ns1", "a"), 0)).To(Equal(0))
+		Expect(s.Admit(ctx, workload("ns1", "b"), 0)).To(Equal(1))
+		Expect(s.Admit(ctx, workload("ns2", "c"), 0)).To(Equal(0))
+		Expect(s.Admit(ctx, workload("ns3", "d"), 0)).To(Equal(0))
+		Expect(s.Admit(ctx, workload("ns4", "e"), 0)).To(Equal(2))
+	})
+
+	It("should count running workloads", func() {
+		newScheduler(Limits{MaxConcurrent: 1})
+
+		Expect(s.Admit(ctx, workload("ns", "a"), 0)).To(Equal(0))
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(1))
+
+		By("counting a workload that was already created")
+		s.Release(workload("ns", "a"))
+		s.Observe(workload("ns", "c"), utils.StatusInProgress)
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(1))
+
+		By("holding the slot while the workload waits for its next attempt")
+		s.Observe(workload("ns", "c"), utils.StatusRetrying)
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(1))
+
+		By("releasing the slot once the workload has completed")
+		s.Observe(workload("ns", "c"), utils.StatusCompleted)
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(0))
+	})
+
+	It("should release workloads that failed for good", func() {
+		newScheduler(Limits{MaxConcurrentPerNamespace: 1})
+
+		Expect(s.Admit(ctx, workload("ns", "a"), 0)).To(Equal(0))
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(1))
+
+		s.Observe(workload("ns", "a"), utils.StatusFailed)
+
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(0))
+	})
+
+	It("should forget workloads whose lease has expired", func() {
+		newScheduler(Limits{MaxConcurrent: 1})
+
+		Expect(s.Admit(ctx, workload("ns", "a"), 0)).To(Equal(0))
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(1))
+		Expect(s.Admit(ctx, workload("ns", "c"), 0)).To(Equal(2))
+
+		clk.SetTime(clk.Now().Add(LeaseDuration / 2))
+		Expect(s.Admit(ctx, workload("ns", "c"), 0)).To(Equal(2))
+
+		clk.SetTime(clk.Now().Add(LeaseDuration/2 + time.Second))
+		Expect(s.Position(workload("ns", "b"))).To(Equal(0))
+		Expect(s.Admit(ctx, workload("ns", "c"), 0)).To(Equal(0))
+	})
+
+	It("should count the workloads that were already running as running", func() {
+		calls := 0
+		lister = func(_ context.Context) ([]Workload, error) {
+			calls++
+			return []Workload{workload("ns", "a")}, nil
+		}
+
+		newScheduler(Limits{MaxConcurrent: 1})
+
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(1))
+
+		By("listing the running workloads only once")
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(1))
+		Expect(calls).To(Equal(1))
+
+		s.Release(workload("ns", "a"))
+		Expect(s.Admit(ctx, workload("ns", "b"), 0)).To(Equal(0))
+	})
+
+	It("should return an error until the running workloads can be listed", func() {
+		var listErr error = errors.New("some error")
+		lister = func(_ context.Context) ([]Workload, error) {
+			return nil, listErr
+		}
+
+		newScheduler(Limits{MaxConcurrent: 1})
+
+		_, err := s.Admit(ctx, workload("ns", "a"), 0)
+		Expect(err).To(HaveOccurred())
+
+		listErr = nil
+		Expect(s.Admit(ctx, workload("ns", "a"), 0)).To(Equal(0))
+	})
+})
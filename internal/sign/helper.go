@@ -8,7 +8,7 @@ import (
 //go:generate mockgen -source=helper.go -package=sign -destination=mock_helper.go
 
 type Helper interface {
-	GetRelevantSign(moduleSign *kmmv1beta1.Sign, mappingSign *kmmv1beta1.Sign, kernel string) (*kmmv1beta1.Sign, error)
+	GetRelevantSign(moduleSign *kmmv1beta1.Sign, mappingSign *kmmv1beta1.Sign, templateVars []string) (*kmmv1beta1.Sign, error)
 }
 
 type helper struct {
@@ -18,7 +18,9 @@ func NewSignerHelper() Helper {
 	return &helper{}
 }
 
-func (m *helper) GetRelevantSign(moduleSign *kmmv1beta1.Sign, mappingSign *kmmv1beta1.Sign, kernel string) (*kmmv1beta1.Sign, error) {
+// GetRelevantSign merges the Sign settings of the Module and of the kernel mapping, and substitutes templateVars in
+// UnsignedImage and FilesToSign.
+func (m *helper) GetRelevantSign(moduleSign *kmmv1beta1.Sign, mappingSign *kmmv1beta1.Sign, templateVars []string) (*kmmv1beta1.Sign, error) {
 	var signConfig *kmmv1beta1.Sign
 	if moduleSign == nil {
 		// km.Sign cannot be nil in case mod.Sign is nil, checked above
@@ -40,14 +42,17 @@ func (m *helper) GetRelevantSign(moduleSign *kmmv1beta1.Sign, mappingSign *kmmv1
 		}
 		//append (not overwrite) any files in the km to the defaults
 		signConfig.FilesToSign = append(signConfig.FilesToSign, mappingSign.FilesToSign...)
+
+		if mappingSign.RetryPolicy != nil {
+			signConfig.RetryPolicy = mappingSign.RetryPolicy
+		}
 	}
-	osConfigEnvVars := utils.KernelComponentsAsEnvVars(kernel)
-	unsignedImage, err := utils.ReplaceInTemplates(osConfigEnvVars, signConfig.UnsignedImage)
+	unsignedImage, err := utils.ReplaceInTemplates(templateVars, signConfig.UnsignedImage)
 	if err != nil {
 		return nil, err
 	}
 	signConfig.UnsignedImage = unsignedImage[0]
-	filesToSign, err := utils.ReplaceInTemplates(osConfigEnvVars, signConfig.FilesToSign...)
+	filesToSign, err := utils.ReplaceInTemplates(templateVars, signConfig.FilesToSign...)
 	if err != nil {
 		return nil, err
 	}
@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -36,7 +37,7 @@ var _ = Describe("GetRelevantSign", func() {
 	}
 
 	DescribeTable("should set fields correctly", func(moduleSign *kmmv1beta1.Sign, mappingSign *kmmv1beta1.Sign) {
-		actual, err := h.GetRelevantSign(moduleSign, mappingSign, kernelVersion)
+		actual, err := h.GetRelevantSign(moduleSign, mappingSign, utils.KernelComponentsAsEnvVars(kernelVersion))
 		Expect(err).NotTo(HaveOccurred())
 		Expect(
 			cmp.Diff(expected, actual),
@@ -137,7 +138,7 @@ var _ = Describe("GetRelevantSign", func() {
 	}
 
 	DescribeTable("should set fields correctly", func(moduleSign *kmmv1beta1.Sign, mappingSign *kmmv1beta1.Sign) {
-		actual, _ := h.GetRelevantSign(moduleSign, mappingSign, kernelVersion)
+		actual, _ := h.GetRelevantSign(moduleSign, mappingSign, utils.KernelComponentsAsEnvVars(kernelVersion))
 		Expect(
 			cmp.Diff(expected, actual),
 		).To(
@@ -166,3 +167,12 @@ var _ = Describe("GetRelevantSign", func() {
 		),
 	)
 })
+
+var _ = Describe("GetRelevantSign", func() {
+	It("should return an error if an unknown variable is used", func() {
+		moduleSign := &kmmv1beta1.Sign{UnsignedImage: "my.registry/my/image:${UNKNOWN}"}
+
+		_, err := NewSignerHelper().GetRelevantSign(moduleSign, nil, utils.KernelComponentsAsEnvVars("1.2.3"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -6,12 +6,16 @@ import (
 	"fmt"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/retry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 )
 
@@ -20,18 +24,26 @@ type signJobManager struct {
 	jobHelper   utils.JobHelper
 	authFactory auth.RegistryAuthGetterFactory
 	registry    registry.Registry
+	gitResolver git.Resolver
+	scheduler   scheduler.Scheduler
+	clock       clock.PassiveClock
 }
 
 func NewSignJobManager(
 	signer Signer,
 	jobHelper utils.JobHelper,
 	authFactory auth.RegistryAuthGetterFactory,
-	registry registry.Registry) *signJobManager {
+	registry registry.Registry,
+	gitResolver git.Resolver,
+	signScheduler scheduler.Scheduler) *signJobManager {
 	return &signJobManager{
 		signer:      signer,
 		jobHelper:   jobHelper,
 		authFactory: authFactory,
 		registry:    registry,
+		gitResolver: gitResolver,
+		scheduler:   signScheduler,
+		clock:       clock.RealClock{},
 	}
 }
 
@@ -66,6 +78,18 @@ func (jbm *signJobManager) ShouldSync(ctx context.Context, mld *api.ModuleLoader
 		return false, fmt.Errorf("failed to check existence of image %s: %w", mld.ContainerImage, err)
 	}
 
+	// images built from a Git repository are signed again once they have been rebuilt from a new commit
+	if exists {
+		if exists, err = git.IsImageUpToDate(ctx, jbm.gitResolver, jbm.authFactory, jbm.registry, mld, mld.ContainerImage); err != nil {
+			return false, fmt.Errorf("could not check if image %s is up to date: %v", mld.ContainerImage, err)
+		}
+	}
+
+	// Sync is not called anymore once the image exists, so the completed signing is released here
+	if exists {
+		jbm.scheduler.Release(scheduler.WorkloadFor(mld, utils.JobTypeSign))
+	}
+
 	return !exists, nil
 }
 
@@ -80,22 +104,35 @@ func (jbm *signJobManager) Sync(
 
 	logger.Info("Signing in-cluster")
 
-	labels := jbm.jobHelper.JobLabels(mld.Name, mld.KernelVersion, "sign")
+	labels := jbm.jobHelper.JobLabels(mld.Name, mld.KernelVersion, mld.Arch, "sign")
+
+	workload := scheduler.WorkloadFor(mld, utils.JobTypeSign)
 
 	jobTemplate, err := jbm.signer.MakeJobTemplate(ctx, mld, labels, imageToSign, pushImage, owner)
 	if err != nil {
 		return "", fmt.Errorf("could not make Job template: %v", err)
 	}
 
-	job, err := jbm.jobHelper.GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, mld.KernelVersion, utils.JobTypeSign, owner)
+	job, err := jbm.jobHelper.GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, mld.KernelVersion, mld.Arch, utils.JobTypeSign, owner)
 	if err != nil {
 		if !errors.Is(err, utils.ErrNoMatchingJob) {
 			return "", fmt.Errorf("error getting the signing job: %v", err)
 		}
 
+		position, err := jbm.scheduler.Admit(ctx, workload, mld.BuildPriority)
+		if err != nil {
+			return "", fmt.Errorf("could not admit the signing: %v", err)
+		}
+
+		if position > 0 {
+			logger.Info("The maximum number of concurrent builds is reached; queueing the signing", "position", position)
+			return utils.StatusQueued, nil
+		}
+
 		logger.Info("Creating job")
 		err = jbm.jobHelper.CreateJob(ctx, jobTemplate)
 		if err != nil {
+			jbm.scheduler.Release(workload)
 			return "", fmt.Errorf("could not create Signing Job: %v", err)
 		}
 
@@ -123,5 +160,11 @@ func (jbm *signJobManager) Sync(
 		return "", err
 	}
 
-	return statusmsg, nil
+	if statusmsg == utils.StatusFailed {
+		statusmsg, err = retry.RetryJob(ctx, jbm.jobHelper, mld.Sign.RetryPolicy, job, jobTemplate, jbm.clock.Now())
+	}
+
+	jbm.scheduler.Observe(workload, statusmsg)
+
+	return statusmsg, err
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -12,12 +13,14 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclock "k8s.io/utils/clock/testing"
 
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/auth"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/registry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/scheduler"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 )
 
@@ -40,7 +43,7 @@ var _ = Describe("ShouldSync", func() {
 		ctrl = gomock.NewController(GinkgoT())
 		authFactory = auth.NewMockRegistryAuthGetterFactory(ctrl)
 		reg = registry.NewMockRegistry(ctrl)
-		mgr = NewSignJobManager(nil, nil, authFactory, reg)
+		mgr = NewSignJobManager(nil, nil, authFactory, reg, nil, scheduler.New(scheduler.Limits{}, nil))
 	})
 
 	It("should return false if there was not sign section", func() {
@@ -66,7 +69,7 @@ var _ = Describe("ShouldSync", func() {
 
 		gomock.InOrder(
 			authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
-			reg.EXPECT().ImageExists(ctx, imageName, nil, gomock.Any()).Return(true, nil),
+			reg.EXPECT().ImageExists(ctx, imageName, "", nil, gomock.Any()).Return(true, nil),
 		)
 
 		shouldSync, err := mgr.ShouldSync(ctx, mld)
@@ -88,7 +91,7 @@ var _ = Describe("ShouldSync", func() {
 
 		gomock.InOrder(
 			authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
-			reg.EXPECT().ImageExists(ctx, imageName, nil, gomock.Any()).Return(false, errors.New("generic-registry-error")),
+			reg.EXPECT().ImageExists(ctx, imageName, "", nil, gomock.Any()).Return(false, errors.New("generic-registry-error")),
 		)
 
 		shouldSync, err := mgr.ShouldSync(ctx, mld)
@@ -111,7 +114,7 @@ var _ = Describe("ShouldSync", func() {
 
 		gomock.InOrder(
 			authFactory.EXPECT().NewRegistryAuthGetterFrom(mld),
-			reg.EXPECT().ImageExists(ctx, imageName, nil, gomock.Any()).Return(false, nil),
+			reg.EXPECT().ImageExists(ctx, imageName, "", nil, gomock.Any()).Return(false, nil),
 		)
 
 		shouldSync, err := mgr.ShouldSync(ctx, mld)
@@ -143,7 +146,7 @@ var _ = Describe("Sync", func() {
 		ctrl = gomock.NewController(GinkgoT())
 		maker = NewMockSigner(ctrl)
 		jobhelper = utils.NewMockJobHelper(ctrl)
-		mgr = NewSignJobManager(maker, jobhelper, nil, nil)
+		mgr = NewSignJobManager(maker, jobhelper, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil))
 	})
 
 	labels := map[string]string{"kmm.node.kubernetes.io/job-type": "sign",
@@ -159,6 +162,83 @@ var _ = Describe("Sync", func() {
 		KernelVersion:  kernelVersion,
 	}
 
+	Context("with a build scheduler", func() {
+		var mockScheduler *scheduler.MockScheduler
+
+		j := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{constants.JobHashAnnotation: "some hash"},
+			},
+		}
+
+		BeforeEach(func() {
+			mockScheduler = scheduler.NewMockScheduler(ctrl)
+			mgr.scheduler = mockScheduler
+		})
+
+		workload := scheduler.WorkloadFor(mld, utils.JobTypeSign)
+
+		It("should queue the signing if it is not admitted", func() {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).Return(&j, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+				mockScheduler.EXPECT().Admit(ctx, workload, int32(0)).Return(1, nil),
+			)
+
+			Expect(mgr.Sync(ctx, mld, previousImageName, true, mld.Owner)).To(Equal(utils.Status(utils.StatusQueued)))
+		})
+
+		It("should create the job once it is admitted", func() {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).Return(&j, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+				mockScheduler.EXPECT().Admit(ctx, workload, int32(0)).Return(0, nil),
+				jobhelper.EXPECT().CreateJob(ctx, &j),
+			)
+
+			Expect(mgr.Sync(ctx, mld, previousImageName, true, mld.Owner)).To(Equal(utils.Status(utils.StatusCreated)))
+		})
+
+		It("should release the slot if the job cannot be created", func() {
+			ctx := context.Background()
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).Return(&j, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+				mockScheduler.EXPECT().Admit(ctx, workload, int32(0)).Return(0, nil),
+				jobhelper.EXPECT().CreateJob(ctx, &j).Return(errors.New("some error")),
+				mockScheduler.EXPECT().Release(workload),
+			)
+
+			_, err := mgr.Sync(ctx, mld, previousImageName, true, mld.Owner)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should report the status of the existing job to the scheduler", func() {
+			ctx := context.Background()
+
+			existing := j
+
+			gomock.InOrder(
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
+				maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).Return(&j, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(&existing, nil),
+				jobhelper.EXPECT().IsJobChanged(&existing, &j).Return(false, nil),
+				jobhelper.EXPECT().GetJobStatus(&existing).Return(utils.Status(utils.StatusInProgress), nil),
+				mockScheduler.EXPECT().Observe(workload, utils.Status(utils.StatusInProgress)),
+			)
+
+			Expect(mgr.Sync(ctx, mld, previousImageName, true, mld.Owner)).To(Equal(utils.Status(utils.StatusInProgress)))
+		})
+	})
+
 	DescribeTable("should return the correct status depending on the job status",
 		func(s batchv1.JobStatus, jobStatus utils.Status, expectsErr bool) {
 			j := batchv1.Job{
@@ -191,9 +271,9 @@ var _ = Describe("Sync", func() {
 			}
 
 			gomock.InOrder(
-				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, "sign").Return(labels),
+				jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
 				maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).Return(&j, nil),
-				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, utils.JobTypeSign, mld.Owner).Return(&newJob, nil),
+				jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(&newJob, nil),
 				jobhelper.EXPECT().IsJobChanged(&j, &newJob).Return(false, nil),
 				jobhelper.EXPECT().GetJobStatus(&newJob).Return(jobStatus, joberr),
 			)
@@ -217,7 +297,7 @@ var _ = Describe("Sync", func() {
 		ctx := context.Background()
 
 		gomock.InOrder(
-			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, "sign").Return(labels),
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
 			maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).
 				Return(nil, errors.New("random error")),
 		)
@@ -243,9 +323,9 @@ var _ = Describe("Sync", func() {
 		}
 
 		gomock.InOrder(
-			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, "sign").Return(labels),
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
 			maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).Return(&j, nil),
-			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, utils.JobTypeSign, mld.Owner).Return(nil, errors.New("random error")),
+			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(nil, errors.New("random error")),
 		)
 
 		Expect(
@@ -269,9 +349,9 @@ var _ = Describe("Sync", func() {
 		}
 
 		gomock.InOrder(
-			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, "sign").Return(labels),
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
 			maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).Return(&j, nil),
-			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, utils.JobTypeSign, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
 			jobhelper.EXPECT().CreateJob(ctx, &j).Return(errors.New("unable to create job")),
 		)
 
@@ -297,9 +377,9 @@ var _ = Describe("Sync", func() {
 		}
 
 		gomock.InOrder(
-			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, "sign").Return(labels),
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
 			maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).Return(&j, nil),
-			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, utils.JobTypeSign, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
+			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(nil, utils.ErrNoMatchingJob),
 			jobhelper.EXPECT().CreateJob(ctx, &j).Return(nil),
 		)
 
@@ -326,9 +406,9 @@ var _ = Describe("Sync", func() {
 		}
 
 		gomock.InOrder(
-			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, "sign").Return(labels),
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
 			maker.EXPECT().MakeJobTemplate(ctx, mld, labels, previousImageName, true, mld.Owner).Return(&newJob, nil),
-			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, utils.JobTypeSign, mld.Owner).Return(&newJob, nil),
+			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(&newJob, nil),
 			jobhelper.EXPECT().IsJobChanged(&newJob, &newJob).Return(true, nil),
 			jobhelper.EXPECT().DeleteJob(ctx, &newJob).Return(nil),
 		)
@@ -339,6 +419,40 @@ var _ = Describe("Sync", func() {
 			Equal(utils.Status(utils.StatusInProgress)),
 		)
 	})
+
+	It("should wait for the backoff before retrying the failed job", func() {
+		ctx := context.Background()
+		failedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		mgr.clock = testclock.NewFakePassiveClock(failedAt.Add(time.Second))
+
+		retriedMLD := *mld
+		retriedMLD.Sign = &kmmv1beta1.Sign{RetryPolicy: &kmmv1beta1.RetryPolicy{MaxAttempts: 2}}
+
+		j := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              jobName,
+				Namespace:         namespace,
+				Annotations:       map[string]string{constants.JobHashAnnotation: "some hash"},
+				CreationTimestamp: metav1.NewTime(failedAt),
+			},
+			Status: batchv1.JobStatus{Failed: 1},
+		}
+
+		gomock.InOrder(
+			jobhelper.EXPECT().JobLabels(mld.Name, kernelVersion, mld.Arch, "sign").Return(labels),
+			maker.EXPECT().MakeJobTemplate(ctx, &retriedMLD, labels, previousImageName, true, mld.Owner).Return(&j, nil),
+			jobhelper.EXPECT().GetModuleJobByKernel(ctx, mld.Name, mld.Namespace, kernelVersion, mld.Arch, utils.JobTypeSign, mld.Owner).Return(&j, nil),
+			jobhelper.EXPECT().IsJobChanged(&j, &j).Return(false, nil),
+			jobhelper.EXPECT().GetJobStatus(&j).Return(utils.Status(utils.StatusFailed), nil),
+		)
+
+		Expect(
+			mgr.Sync(ctx, &retriedMLD, previousImageName, true, mld.Owner),
+		).To(
+			Equal(utils.Status(utils.StatusRetrying)),
+		)
+	})
 })
 
 var _ = Describe("GarbageCollect", func() {
@@ -351,7 +465,7 @@ var _ = Describe("GarbageCollect", func() {
 	BeforeEach(func() {
 		ctrl = gomock.NewController(GinkgoT())
 		jobhelper = utils.NewMockJobHelper(ctrl)
-		mgr = NewSignJobManager(nil, jobhelper, nil, nil)
+		mgr = NewSignJobManager(nil, jobhelper, nil, nil, nil, scheduler.New(scheduler.Limits{}, nil))
 	})
 
 	mld := api.ModuleLoaderData{
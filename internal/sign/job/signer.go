@@ -19,6 +19,7 @@ import (
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/ca"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/constants"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/module"
 	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
 )
 
@@ -206,7 +207,7 @@ func (s *signer) MakeJobTemplate(
 			},
 			RestartPolicy: v1.RestartPolicyNever,
 			Volumes:       volumes,
-			NodeSelector:  mld.Selector,
+			NodeSelector:  module.NodeSelector(mld),
 		},
 	}
 
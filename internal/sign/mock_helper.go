@@ -35,16 +35,16 @@ func (m *MockHelper) EXPECT() *MockHelperMockRecorder {
 }
 
 // GetRelevantSign mocks base method.
-func (m *MockHelper) GetRelevantSign(moduleSign, mappingSign *v1beta1.Sign, kernel string) (*v1beta1.Sign, error) {
+func (m *MockHelper) GetRelevantSign(moduleSign, mappingSign *v1beta1.Sign, templateVars []string) (*v1beta1.Sign, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRelevantSign", moduleSign, mappingSign, kernel)
+	ret := m.ctrl.Call(m, "GetRelevantSign", moduleSign, mappingSign, templateVars)
 	ret0, _ := ret[0].(*v1beta1.Sign)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetRelevantSign indicates an expected call of GetRelevantSign.
-func (mr *MockHelperMockRecorder) GetRelevantSign(moduleSign, mappingSign, kernel interface{}) *gomock.Call {
+func (mr *MockHelperMockRecorder) GetRelevantSign(moduleSign, mappingSign, templateVars interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRelevantSign", reflect.TypeOf((*MockHelper)(nil).GetRelevantSign), moduleSign, mappingSign, kernel)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRelevantSign", reflect.TypeOf((*MockHelper)(nil).GetRelevantSign), moduleSign, mappingSign, templateVars)
 }
@@ -41,17 +41,31 @@ func (m *MockModuleStatusUpdater) EXPECT() *MockModuleStatusUpdaterMockRecorder
 }
 
 // ModuleUpdateStatus mocks base method.
-func (m *MockModuleStatusUpdater) ModuleUpdateStatus(ctx context.Context, mod *v1beta10.Module, kernelMappingNodes, targetedNodes []v10.Node, dsByKernelVersion map[string]*v1.DaemonSet) error {
+func (m *MockModuleStatusUpdater) ModuleUpdateStatus(ctx context.Context, mod *v1beta10.Module, kernelMappingNodes, targetedNodes []v10.Node, dsByKernelAndArch map[string]*v1.DaemonSet, kernelVersionStatuses []v1beta10.KernelVersionStatus) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ModuleUpdateStatus", ctx, mod, kernelMappingNodes, targetedNodes, dsByKernelVersion)
+	ret := m.ctrl.Call(m, "ModuleUpdateStatus", ctx, mod, kernelMappingNodes, targetedNodes, dsByKernelAndArch, kernelVersionStatuses)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // ModuleUpdateStatus indicates an expected call of ModuleUpdateStatus.
-func (mr *MockModuleStatusUpdaterMockRecorder) ModuleUpdateStatus(ctx, mod, kernelMappingNodes, targetedNodes, dsByKernelVersion interface{}) *gomock.Call {
+func (mr *MockModuleStatusUpdaterMockRecorder) ModuleUpdateStatus(ctx, mod, kernelMappingNodes, targetedNodes, dsByKernelAndArch, kernelVersionStatuses interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModuleUpdateStatus", reflect.TypeOf((*MockModuleStatusUpdater)(nil).ModuleUpdateStatus), ctx, mod, kernelMappingNodes, targetedNodes, dsByKernelVersion)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModuleUpdateStatus", reflect.TypeOf((*MockModuleStatusUpdater)(nil).ModuleUpdateStatus), ctx, mod, kernelMappingNodes, targetedNodes, dsByKernelAndArch, kernelVersionStatuses)
+}
+
+// ModuleUpdateUnloadingStatus mocks base method.
+func (m *MockModuleStatusUpdater) ModuleUpdateUnloadingStatus(ctx context.Context, mod *v1beta10.Module, unloadingNodes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModuleUpdateUnloadingStatus", ctx, mod, unloadingNodes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ModuleUpdateUnloadingStatus indicates an expected call of ModuleUpdateUnloadingStatus.
+func (mr *MockModuleStatusUpdaterMockRecorder) ModuleUpdateUnloadingStatus(ctx, mod, unloadingNodes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModuleUpdateUnloadingStatus", reflect.TypeOf((*MockModuleStatusUpdater)(nil).ModuleUpdateUnloadingStatus), ctx, mod, unloadingNodes)
 }
 
 // MockManagedClusterModuleStatusUpdater is a mock of ManagedClusterModuleStatusUpdater interface.
@@ -3,10 +3,13 @@ package statusupdater
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	workv1 "open-cluster-management.io/api/work/v1"
@@ -21,7 +24,9 @@ import (
 
 type ModuleStatusUpdater interface {
 	ModuleUpdateStatus(ctx context.Context, mod *kmmv1beta1.Module, kernelMappingNodes []v1.Node,
-		targetedNodes []v1.Node, dsByKernelVersion map[string]*appsv1.DaemonSet) error
+		targetedNodes []v1.Node, dsByKernelAndArch map[string]*appsv1.DaemonSet,
+		kernelVersionStatuses []kmmv1beta1.KernelVersionStatus) error
+	ModuleUpdateUnloadingStatus(ctx context.Context, mod *kmmv1beta1.Module, unloadingNodes []string) error
 }
 
 //go:generate mockgen -source=statusupdater.go -package=statusupdater -destination=mock_statusupdater.go
@@ -90,20 +95,32 @@ func (m *moduleStatusUpdater) ModuleUpdateStatus(ctx context.Context,
 	mod *kmmv1beta1.Module,
 	kernelMappingNodes []v1.Node,
 	targetedNodes []v1.Node,
-	dsByKernelVersion map[string]*appsv1.DaemonSet) error {
+	dsByKernelAndArch map[string]*appsv1.DaemonSet,
+	kernelVersionStatuses []kmmv1beta1.KernelVersionStatus) error {
 
 	nodesMatchingSelectorNumber := int32(len(targetedNodes))
 	numDesired := int32(len(kernelMappingNodes))
 	var numAvailableDevicePlugin int32
 	var numAvailableKernelModule int32
-	for kernelVersion, ds := range dsByKernelVersion {
-		if daemonset.IsDevicePluginKernelVersion(kernelVersion) {
+	for key, ds := range dsByKernelAndArch {
+		if daemonset.IsDevicePluginKernelVersion(key) {
 			numAvailableDevicePlugin += ds.Status.NumberAvailable
 		} else {
 			numAvailableKernelModule += ds.Status.NumberAvailable
 		}
 	}
 
+	// In the Job load mode, nodes are labeled once the load Job has completed.
+	if mod.Spec.LoadMode == kmmv1beta1.LoadModeJob {
+		readyLabel := daemonset.GetDriverContainerNodeLabel(mod.Name)
+
+		for _, node := range kernelMappingNodes {
+			if _, ok := node.Labels[readyLabel]; ok {
+				numAvailableKernelModule++
+			}
+		}
+	}
+
 	unmodifiedMod := mod.DeepCopy()
 
 	mod.Status.ModuleLoader.NodesMatchingSelectorNumber = nodesMatchingSelectorNumber
@@ -114,9 +131,184 @@ func (m *moduleStatusUpdater) ModuleUpdateStatus(ctx context.Context,
 		mod.Status.DevicePlugin.DesiredNumber = numDesired
 		mod.Status.DevicePlugin.AvailableNumber = numAvailableDevicePlugin
 	}
+	mod.Status.KernelVersions = kernelVersionStatuses
+
+	for _, c := range moduleConditions(kernelVersionStatuses, dsByKernelAndArch, numDesired, numAvailableKernelModule) {
+		c.ObservedGeneration = mod.Generation
+		meta.SetStatusCondition(&mod.Status.Conditions, c)
+	}
+
+	return m.client.Status().Patch(ctx, mod, client.MergeFrom(unmodifiedMod))
+}
+
+// ModuleUpdateUnloadingStatus records the nodes on which the kernel module of a deleted Module has not been confirmed
+// to be unloaded yet.
+func (m *moduleStatusUpdater) ModuleUpdateUnloadingStatus(ctx context.Context, mod *kmmv1beta1.Module, unloadingNodes []string) error {
+	unmodifiedMod := mod.DeepCopy()
+
+	nodes := make([]string, len(unloadingNodes))
+	copy(nodes, unloadingNodes)
+	sort.Strings(nodes)
+
+	mod.Status.UnloadingNodes = nodes
+
+	readyCond := metav1.Condition{
+		Type:               kmmv1beta1.ModuleConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Unloading",
+		Message:            fmt.Sprintf("module deleted; waiting for it to be unloaded from %d nodes", len(nodes)),
+		ObservedGeneration: mod.Generation,
+	}
+
+	meta.SetStatusCondition(&mod.Status.Conditions, readyCond)
+
 	return m.client.Status().Patch(ctx, mod, client.MergeFrom(unmodifiedMod))
 }
 
+// moduleConditions computes the Module conditions from the per-kernel statuses and the module loader DaemonSets.
+func moduleConditions(
+	kernelVersionStatuses []kmmv1beta1.KernelVersionStatus,
+	dsByKernelAndArch map[string]*appsv1.DaemonSet,
+	numDesired int32,
+	numAvailable int32) []metav1.Condition {
+
+	var buildFailed, signFailed, upgradeFailed, loadFailed, unmapped, building, signing []string
+
+	for _, kvs := range kernelVersionStatuses {
+		kernel := daemonset.KernelArchKey(kvs.KernelVersion, kvs.Arch)
+
+		if kvs.Upgrade != nil && kvs.Upgrade.Phase == kmmv1beta1.UpgradePhaseFailed {
+			upgradeFailed = append(upgradeFailed, kernel)
+		}
+
+		loadFailed = append(loadFailed, kvs.FailedNodes...)
+
+		switch {
+		case kvs.BuildPhase == "":
+			unmapped = append(unmapped, kernel)
+		case kvs.BuildPhase == kmmv1beta1.StagePhaseFailed:
+			buildFailed = append(buildFailed, kernel)
+		case isStageOngoing(kvs.BuildPhase):
+			building = append(building, kernel)
+		case kvs.SignPhase == kmmv1beta1.StagePhaseFailed:
+			signFailed = append(signFailed, kernel)
+		case isStageOngoing(kvs.SignPhase):
+			signing = append(signing, kernel)
+		}
+	}
+
+	rollingOut := make([]string, 0)
+
+	for key, ds := range dsByKernelAndArch {
+		if daemonset.IsDevicePluginKernelVersion(key) {
+			continue
+		}
+
+		if ds.Status.UpdatedNumberScheduled < ds.Status.DesiredNumberScheduled || ds.Status.NumberAvailable < ds.Status.DesiredNumberScheduled {
+			rollingOut = append(rollingOut, key)
+		}
+	}
+
+	sort.Strings(rollingOut)
+
+	buildFailedCond := metav1.Condition{
+		Type:   kmmv1beta1.ModuleConditionBuildFailed,
+		Status: metav1.ConditionFalse,
+		Reason: "NoBuildFailure",
+	}
+	if len(buildFailed) > 0 {
+		buildFailedCond.Status = metav1.ConditionTrue
+		buildFailedCond.Reason = "BuildFailed"
+		buildFailedCond.Message = "build failed for kernel versions: " + strings.Join(buildFailed, ", ")
+	}
+
+	signFailedCond := metav1.Condition{
+		Type:   kmmv1beta1.ModuleConditionSignFailed,
+		Status: metav1.ConditionFalse,
+		Reason: "NoSignFailure",
+	}
+	if len(signFailed) > 0 {
+		signFailedCond.Status = metav1.ConditionTrue
+		signFailedCond.Reason = "SignFailed"
+		signFailedCond.Message = "signing failed for kernel versions: " + strings.Join(signFailed, ", ")
+	}
+
+	degradedCond := metav1.Condition{
+		Type:   kmmv1beta1.ModuleConditionDegraded,
+		Status: metav1.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	switch {
+	case len(buildFailed) > 0:
+		degradedCond.Status = metav1.ConditionTrue
+		degradedCond.Reason = buildFailedCond.Reason
+		degradedCond.Message = buildFailedCond.Message
+	case len(signFailed) > 0:
+		degradedCond.Status = metav1.ConditionTrue
+		degradedCond.Reason = signFailedCond.Reason
+		degradedCond.Message = signFailedCond.Message
+	case len(upgradeFailed) > 0:
+		degradedCond.Status = metav1.ConditionTrue
+		degradedCond.Reason = "UpgradeFailed"
+		degradedCond.Message = "node-by-node upgrade failed for kernel versions: " + strings.Join(upgradeFailed, ", ")
+	case len(loadFailed) > 0:
+		sort.Strings(loadFailed)
+
+		degradedCond.Status = metav1.ConditionTrue
+		degradedCond.Reason = "LoadJobFailed"
+		degradedCond.Message = "load or unload Jobs failed on nodes: " + strings.Join(loadFailed, ", ")
+	case len(unmapped) > 0:
+		degradedCond.Status = metav1.ConditionTrue
+		degradedCond.Reason = "NoKernelMapping"
+		degradedCond.Message = "no kernel mapping for kernel versions: " + strings.Join(unmapped, ", ")
+	}
+
+	progressingCond := metav1.Condition{
+		Type:   kmmv1beta1.ModuleConditionProgressing,
+		Status: metav1.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	switch {
+	case len(building) > 0:
+		progressingCond.Status = metav1.ConditionTrue
+		progressingCond.Reason = "BuildInProgress"
+		progressingCond.Message = "building for kernel versions: " + strings.Join(building, ", ")
+	case len(signing) > 0:
+		progressingCond.Status = metav1.ConditionTrue
+		progressingCond.Reason = "SignInProgress"
+		progressingCond.Message = "signing for kernel versions: " + strings.Join(signing, ", ")
+	case len(rollingOut) > 0:
+		progressingCond.Status = metav1.ConditionTrue
+		progressingCond.Reason = "DaemonSetRollingOut"
+		progressingCond.Message = "module loader pods are rolling out for kernel versions: " + strings.Join(rollingOut, ", ")
+	}
+
+	readyCond := metav1.Condition{
+		Type:    kmmv1beta1.ModuleConditionReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ModuleLoaded",
+		Message: fmt.Sprintf("module loaded on %d/%d nodes", numAvailable, numDesired),
+	}
+	switch {
+	case degradedCond.Status == metav1.ConditionTrue:
+		readyCond.Status = metav1.ConditionFalse
+		readyCond.Reason = "Degraded"
+	case progressingCond.Status == metav1.ConditionTrue:
+		readyCond.Status = metav1.ConditionFalse
+		readyCond.Reason = "Progressing"
+	case numAvailable < numDesired:
+		readyCond.Status = metav1.ConditionFalse
+		readyCond.Reason = "ModuleNotLoaded"
+	}
+
+	return []metav1.Condition{readyCond, progressingCond, degradedCond, buildFailedCond, signFailedCond}
+}
+
+// isStageOngoing returns true if the build or signing is queued, running or waiting for its next attempt.
+func isStageOngoing(phase kmmv1beta1.StagePhase) bool {
+	return phase == kmmv1beta1.StagePhaseQueued || phase == kmmv1beta1.StagePhaseInProgress || phase == kmmv1beta1.StagePhaseRetrying
+}
+
 func (m *managedClusterModuleStatusUpdater) ManagedClusterModuleUpdateStatus(ctx context.Context,
 	mcm *hubv1beta1.ManagedClusterModule,
 	ownedManifestWorks []workv1.ManifestWork) error {
@@ -2,6 +2,7 @@ package statusupdater
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/golang/mock/gomock"
@@ -9,9 +10,11 @@ import (
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	workv1 "open-cluster-management.io/api/work/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	hubv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api-hub/v1beta1"
 	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
@@ -66,7 +69,7 @@ var _ = Describe("module status update", func() {
 			clnt.EXPECT().Status().Return(statusWrite)
 			statusWrite.EXPECT().Patch(context.Background(), mod, gomock.Any()).Return(nil)
 
-			res := su.ModuleUpdateStatus(context.Background(), mod, mappingsNodes, targetedNodes, dsMap)
+			res := su.ModuleUpdateStatus(context.Background(), mod, mappingsNodes, targetedNodes, dsMap, nil)
 
 			Expect(res).To(BeNil())
 			Expect(mod.Status.ModuleLoader.NodesMatchingSelectorNumber).To(Equal(int32(len(targetedNodes))))
@@ -107,6 +110,281 @@ var _ = Describe("module status update", func() {
 			true,
 		),
 	)
+
+	It("should count the labeled nodes in the Job load mode", func() {
+		mod.Spec.LoadMode = kmmv1beta1.LoadModeJob
+
+		readyLabels := map[string]string{daemonset.GetDriverContainerNodeLabel(name): ""}
+		nodes := []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: readyLabels}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+		}
+
+		statusWrite := client.NewMockStatusWriter(ctrl)
+		clnt.EXPECT().Status().Return(statusWrite)
+		statusWrite.EXPECT().Patch(context.Background(), mod, gomock.Any()).Return(nil)
+
+		Expect(
+			su.ModuleUpdateStatus(context.Background(), mod, nodes, nodes, nil, nil),
+		).To(
+			Succeed(),
+		)
+
+		Expect(mod.Status.ModuleLoader.DesiredNumber).To(BeEquivalentTo(2))
+		Expect(mod.Status.ModuleLoader.AvailableNumber).To(BeEquivalentTo(1))
+	})
+
+	It("should list the nodes on which a deleted module is being unloaded", func() {
+		statusWrite := client.NewMockStatusWriter(ctrl)
+		clnt.EXPECT().Status().Return(statusWrite)
+		statusWrite.EXPECT().Patch(context.Background(), mod, gomock.Any()).Return(nil)
+
+		Expect(
+			su.ModuleUpdateUnloadingStatus(context.Background(), mod, []string{"node-2", "node-1"}),
+		).To(
+			Succeed(),
+		)
+
+		Expect(mod.Status.UnloadingNodes).To(Equal([]string{"node-1", "node-2"}))
+
+		readyCond := meta.FindStatusCondition(mod.Status.Conditions, kmmv1beta1.ModuleConditionReady)
+		Expect(readyCond).NotTo(BeNil())
+		Expect(readyCond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(readyCond.Reason).To(Equal("Unloading"))
+	})
+
+	It("should write the statuses of a kernel version running on several architectures", func() {
+		kernelVersionStatuses := []kmmv1beta1.KernelVersionStatus{
+			{KernelVersion: "kernel", Arch: "x86_64", BuildPhase: kmmv1beta1.StagePhaseCompleted},
+			{KernelVersion: "kernel", Arch: "aarch64", BuildPhase: kmmv1beta1.StagePhaseInProgress},
+		}
+
+		statusWrite := client.NewMockStatusWriter(ctrl)
+		clnt.EXPECT().Status().Return(statusWrite)
+		statusWrite.
+			EXPECT().
+			Patch(context.Background(), mod, gomock.Any()).
+			DoAndReturn(func(_ context.Context, obj ctrlclient.Object, patch ctrlclient.Patch, _ ...ctrlclient.PatchOption) error {
+				data, err := patch.Data(obj)
+				Expect(err).NotTo(HaveOccurred())
+
+				patched := kmmv1beta1.Module{}
+				Expect(json.Unmarshal(data, &patched)).To(Succeed())
+				Expect(patched.Status.KernelVersions).To(HaveLen(2))
+
+				return nil
+			})
+
+		Expect(
+			su.ModuleUpdateStatus(context.Background(), mod, nil, nil, nil, kernelVersionStatuses),
+		).To(
+			Succeed(),
+		)
+	})
+
+	It("should set the kernel versions and conditions", func() {
+		mod.Generation = 3
+
+		kernelVersionStatuses := []kmmv1beta1.KernelVersionStatus{
+			{
+				KernelVersion:  "kernel-1",
+				ContainerImage: "image-1",
+				BuildPhase:     kmmv1beta1.StagePhaseFailed,
+				Message:        "some error",
+			},
+			{
+				KernelVersion:  "kernel-2",
+				ContainerImage: "image-2",
+				BuildPhase:     kmmv1beta1.StagePhaseNotRequired,
+				SignPhase:      kmmv1beta1.StagePhaseInProgress,
+			},
+		}
+
+		statusWrite := client.NewMockStatusWriter(ctrl)
+		clnt.EXPECT().Status().Return(statusWrite)
+		statusWrite.EXPECT().Patch(context.Background(), mod, gomock.Any()).Return(nil)
+
+		err := su.ModuleUpdateStatus(context.Background(), mod, nil, nil, nil, kernelVersionStatuses)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(mod.Status.KernelVersions).To(Equal(kernelVersionStatuses))
+
+		expectedConditions := map[string]metav1.ConditionStatus{
+			kmmv1beta1.ModuleConditionReady:       metav1.ConditionFalse,
+			kmmv1beta1.ModuleConditionProgressing: metav1.ConditionTrue,
+			kmmv1beta1.ModuleConditionDegraded:    metav1.ConditionTrue,
+			kmmv1beta1.ModuleConditionBuildFailed: metav1.ConditionTrue,
+			kmmv1beta1.ModuleConditionSignFailed:  metav1.ConditionFalse,
+		}
+
+		Expect(mod.Status.Conditions).To(HaveLen(len(expectedConditions)))
+
+		for _, c := range mod.Status.Conditions {
+			Expect(c.Status).To(Equal(expectedConditions[c.Type]), "condition %s", c.Type)
+			Expect(c.ObservedGeneration).To(BeEquivalentTo(3))
+		}
+
+		Expect(
+			meta.FindStatusCondition(mod.Status.Conditions, kmmv1beta1.ModuleConditionBuildFailed).Message,
+		).To(
+			ContainSubstring("kernel-1"),
+		)
+	})
+
+	DescribeTable("computing the Module conditions",
+		func(kernelVersionStatuses []kmmv1beta1.KernelVersionStatus, dsMap map[string]*appsv1.DaemonSet, numDesired, numAvailable int32, expected map[string]string) {
+			conditions := moduleConditions(kernelVersionStatuses, dsMap, numDesired, numAvailable)
+
+			reasons := make(map[string]string, len(conditions))
+
+			for _, c := range conditions {
+				reasons[c.Type] = c.Reason
+			}
+
+			for t, r := range expected {
+				Expect(reasons).To(HaveKeyWithValue(t, r))
+			}
+		},
+		Entry(
+			"all modules loaded",
+			[]kmmv1beta1.KernelVersionStatus{
+				{KernelVersion: "kernel", BuildPhase: kmmv1beta1.StagePhaseCompleted, SignPhase: kmmv1beta1.StagePhaseNotRequired},
+			},
+			map[string]*appsv1.DaemonSet{
+				"kernel": {Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberAvailable: 2}},
+			},
+			int32(2),
+			int32(2),
+			map[string]string{
+				kmmv1beta1.ModuleConditionReady:       "ModuleLoaded",
+				kmmv1beta1.ModuleConditionProgressing: "AsExpected",
+				kmmv1beta1.ModuleConditionDegraded:    "AsExpected",
+			},
+		),
+		Entry(
+			"DaemonSet rolling out",
+			[]kmmv1beta1.KernelVersionStatus{
+				{KernelVersion: "kernel", BuildPhase: kmmv1beta1.StagePhaseNotRequired, SignPhase: kmmv1beta1.StagePhaseNotRequired},
+			},
+			map[string]*appsv1.DaemonSet{
+				"kernel": {Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, UpdatedNumberScheduled: 1, NumberAvailable: 2}},
+			},
+			int32(2),
+			int32(2),
+			map[string]string{
+				kmmv1beta1.ModuleConditionReady:       "Progressing",
+				kmmv1beta1.ModuleConditionProgressing: "DaemonSetRollingOut",
+			},
+		),
+		Entry(
+			"signing failed",
+			[]kmmv1beta1.KernelVersionStatus{
+				{KernelVersion: "kernel", BuildPhase: kmmv1beta1.StagePhaseCompleted, SignPhase: kmmv1beta1.StagePhaseFailed},
+			},
+			nil,
+			int32(1),
+			int32(0),
+			map[string]string{
+				kmmv1beta1.ModuleConditionReady:      "Degraded",
+				kmmv1beta1.ModuleConditionDegraded:   "SignFailed",
+				kmmv1beta1.ModuleConditionSignFailed: "SignFailed",
+			},
+		),
+		Entry(
+			"build retrying",
+			[]kmmv1beta1.KernelVersionStatus{
+				{KernelVersion: "kernel", BuildPhase: kmmv1beta1.StagePhaseRetrying},
+			},
+			nil,
+			int32(1),
+			int32(0),
+			map[string]string{
+				kmmv1beta1.ModuleConditionProgressing: "BuildInProgress",
+				kmmv1beta1.ModuleConditionBuildFailed: "NoBuildFailure",
+			},
+		),
+		Entry(
+			"signing queued",
+			[]kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernel",
+					BuildPhase:    kmmv1beta1.StagePhaseCompleted,
+					SignPhase:     kmmv1beta1.StagePhaseQueued,
+					QueuePosition: 2,
+				},
+			},
+			nil,
+			int32(1),
+			int32(0),
+			map[string]string{
+				kmmv1beta1.ModuleConditionProgressing: "SignInProgress",
+				kmmv1beta1.ModuleConditionSignFailed:  "NoSignFailure",
+			},
+		),
+		Entry(
+			"node-by-node upgrade failed",
+			[]kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernel",
+					BuildPhase:    kmmv1beta1.StagePhaseNotRequired,
+					SignPhase:     kmmv1beta1.StagePhaseNotRequired,
+					Upgrade:       &kmmv1beta1.ModuleUpgradeStatus{Phase: kmmv1beta1.UpgradePhaseFailed},
+				},
+			},
+			map[string]*appsv1.DaemonSet{
+				"kernel": {Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, UpdatedNumberScheduled: 1, NumberAvailable: 1}},
+			},
+			int32(2),
+			int32(1),
+			map[string]string{
+				kmmv1beta1.ModuleConditionReady:    "Degraded",
+				kmmv1beta1.ModuleConditionDegraded: "UpgradeFailed",
+			},
+		),
+		Entry(
+			"load Job failed",
+			[]kmmv1beta1.KernelVersionStatus{
+				{
+					KernelVersion: "kernel",
+					BuildPhase:    kmmv1beta1.StagePhaseNotRequired,
+					SignPhase:     kmmv1beta1.StagePhaseNotRequired,
+					FailedNodes:   []string{"node-1"},
+				},
+			},
+			nil,
+			int32(2),
+			int32(1),
+			map[string]string{
+				kmmv1beta1.ModuleConditionReady:    "Degraded",
+				kmmv1beta1.ModuleConditionDegraded: "LoadJobFailed",
+			},
+		),
+		Entry(
+			"no kernel mapping",
+			[]kmmv1beta1.KernelVersionStatus{
+				{KernelVersion: "kernel", Message: "no kernel mapping matches this kernel version"},
+			},
+			nil,
+			int32(0),
+			int32(0),
+			map[string]string{
+				kmmv1beta1.ModuleConditionReady:    "Degraded",
+				kmmv1beta1.ModuleConditionDegraded: "NoKernelMapping",
+			},
+		),
+		Entry(
+			"module not loaded on all nodes",
+			[]kmmv1beta1.KernelVersionStatus{
+				{KernelVersion: "kernel", BuildPhase: kmmv1beta1.StagePhaseNotRequired, SignPhase: kmmv1beta1.StagePhaseNotRequired},
+			},
+			nil,
+			int32(2),
+			int32(1),
+			map[string]string{
+				kmmv1beta1.ModuleConditionReady: "ModuleNotLoaded",
+			},
+		),
+	)
 })
 
 var _ = Describe("ManagedClusterModule status update", func() {
@@ -48,6 +48,21 @@ func (mr *MockKernelOsDtkMappingMockRecorder) GetImage(kernelVersion interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockKernelOsDtkMapping)(nil).GetImage), kernelVersion)
 }
 
+// GetOSImageVersion mocks base method.
+func (m *MockKernelOsDtkMapping) GetOSImageVersion(kernelVersion string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOSImageVersion", kernelVersion)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOSImageVersion indicates an expected call of GetOSImageVersion.
+func (mr *MockKernelOsDtkMappingMockRecorder) GetOSImageVersion(kernelVersion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOSImageVersion", reflect.TypeOf((*MockKernelOsDtkMapping)(nil).GetOSImageVersion), kernelVersion)
+}
+
 // SetImageStreamInfo mocks base method.
 func (m *MockKernelOsDtkMapping) SetImageStreamInfo(osImage, dtkImage string) {
 	m.ctrl.T.Helper()
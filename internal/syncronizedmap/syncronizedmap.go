@@ -11,6 +11,7 @@ type KernelOsDtkMapping interface {
 	SetNodeInfo(kernelVersion, osImage string)
 	SetImageStreamInfo(osImage, dtkImage string)
 	GetImage(kernelVersion string) (string, error)
+	GetOSImageVersion(kernelVersion string) (string, error)
 }
 
 type kernelOsDtkMapping struct {
@@ -46,6 +47,18 @@ func (skom *kernelOsDtkMapping) SetImageStreamInfo(osImage, dtkImage string) {
 	skom.osToDtk[osImage] = dtkImage
 }
 
+func (skom *kernelOsDtkMapping) GetOSImageVersion(kernelVersion string) (string, error) {
+
+	skom.kernelToOsMutex.RLock()
+	defer skom.kernelToOsMutex.RUnlock()
+
+	osImage, ok := skom.kernelToOs[kernelVersion]
+	if !ok {
+		return "", fmt.Errorf("could not find kernel %s in kernel --> OS mapping", kernelVersion)
+	}
+	return osImage, nil
+}
+
 func (skom *kernelOsDtkMapping) GetImage(kernelVersion string) (string, error) {
 
 	skom.kernelToOsMutex.RLock()
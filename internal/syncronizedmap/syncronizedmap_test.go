@@ -7,7 +7,7 @@ import (
 
 // testing multiple methods at once because some of them doesn't have much to test
 // the the others are easier to test using other methods.
-var _ = Describe("SetNodeInfo+SetImageStreamInfo+GetImage", func() {
+var _ = Describe("SetNodeInfo+SetImageStreamInfo+GetImage+GetOSImageVersion", func() {
 
 	const (
 		kernelVersion  = "kernel-1.2.3"
@@ -28,6 +28,10 @@ var _ = Describe("SetNodeInfo+SetImageStreamInfo+GetImage", func() {
 		_, err := kodm.GetImage("kernel-non-existing")
 
 		Expect(err).To(HaveOccurred())
+
+		_, err = kodm.GetOSImageVersion("kernel-non-existing")
+
+		Expect(err).To(HaveOccurred())
 	})
 
 	It("should return an error if the kernel exist in the map but the OS mapping doesn't", func() {
@@ -48,5 +52,10 @@ var _ = Describe("SetNodeInfo+SetImageStreamInfo+GetImage", func() {
 
 		Expect(err).NotTo(HaveOccurred())
 		Expect(image).To(Equal(dtkImage))
+
+		osImage, err := kodm.GetOSImageVersion(kernelVersion)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(osImage).To(Equal(osImageVersion))
 	})
 })
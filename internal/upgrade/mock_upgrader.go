@@ -0,0 +1,67 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: upgrader.go
+
+// Package upgrade is a generated GoMock package.
+package upgrade
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	api "github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	v1 "k8s.io/api/apps/v1"
+)
+
+// MockUpgrader is a mock of Upgrader interface.
+type MockUpgrader struct {
+	ctrl     *gomock.Controller
+	recorder *MockUpgraderMockRecorder
+}
+
+// MockUpgraderMockRecorder is the mock recorder for MockUpgrader.
+type MockUpgraderMockRecorder struct {
+	mock *MockUpgrader
+}
+
+// NewMockUpgrader creates a new mock instance.
+func NewMockUpgrader(ctrl *gomock.Controller) *MockUpgrader {
+	mock := &MockUpgrader{ctrl: ctrl}
+	mock.recorder = &MockUpgraderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUpgrader) EXPECT() *MockUpgraderMockRecorder {
+	return m.recorder
+}
+
+// SetDriverContainerAsDesired mocks base method.
+func (m *MockUpgrader) SetDriverContainerAsDesired(ctx context.Context, ds *v1.DaemonSet, mld *api.ModuleLoaderData, useDefaultSA bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDriverContainerAsDesired", ctx, ds, mld, useDefaultSA)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDriverContainerAsDesired indicates an expected call of SetDriverContainerAsDesired.
+func (mr *MockUpgraderMockRecorder) SetDriverContainerAsDesired(ctx, ds, mld, useDefaultSA interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDriverContainerAsDesired", reflect.TypeOf((*MockUpgrader)(nil).SetDriverContainerAsDesired), ctx, ds, mld, useDefaultSA)
+}
+
+// Upgrade mocks base method.
+func (m *MockUpgrader) Upgrade(ctx context.Context, ds *v1.DaemonSet, mld *api.ModuleLoaderData) (*v1beta1.ModuleUpgradeStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upgrade", ctx, ds, mld)
+	ret0, _ := ret[0].(*v1beta1.ModuleUpgradeStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upgrade indicates an expected call of Upgrade.
+func (mr *MockUpgraderMockRecorder) Upgrade(ctx, ds, mld interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upgrade", reflect.TypeOf((*MockUpgrader)(nil).Upgrade), ctx, ds, mld)
+}
@@ -0,0 +1,524 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	policyv1client "k8s.io/client-go/kubernetes/typed/policy/v1"
+	"k8s.io/kubectl/pkg/util/podutils"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	previousImageAnnotation   = "kmm.node.kubernetes.io/previous-container-image"
+	rolledBackImageAnnotation = "kmm.node.kubernetes.io/rolled-back-container-image"
+	templateHashAnnotation    = "kmm.node.kubernetes.io/module-loader-hash"
+
+	DefaultNodeReadyTimeout = 10 * time.Minute
+
+	podNodeNameField = "spec.nodeName"
+)
+
+//go:generate mockgen -source=upgrader.go -package=upgrade -destination=mock_upgrader.go
+
+// Upgrader replaces the module loader pods node by node for modules that use the NodeByNode upgrade strategy.
+type Upgrader interface {
+	SetDriverContainerAsDesired(ctx context.Context, ds *appsv1.DaemonSet, mld *api.ModuleLoaderData, useDefaultSA bool) error
+	Upgrade(ctx context.Context, ds *appsv1.DaemonSet, mld *api.ModuleLoaderData) (*kmmv1beta1.ModuleUpgradeStatus, error)
+}
+
+type upgrader struct {
+	client    client.Client
+	daemonAPI daemonset.DaemonSetCreator
+	evictions policyv1client.EvictionsGetter
+	clock     clock.PassiveClock
+}
+
+func NewUpgrader(client client.Client, daemonAPI daemonset.DaemonSetCreator, evictions policyv1client.EvictionsGetter) Upgrader {
+	return &upgrader{
+		client:    client,
+		daemonAPI: daemonAPI,
+		evictions: evictions,
+		clock:     clock.RealClock{},
+	}
+}
+
+// IsNodeByNode returns true if mld uses the NodeByNode upgrade strategy.
+func IsNodeByNode(mld *api.ModuleLoaderData) bool {
+	return mld.UpgradeStrategy != nil && mld.UpgradeStrategy.Type == kmmv1beta1.UpgradeStrategyNodeByNode
+}
+
+// IndexPodsByNodeName indexes pods by the name of their node, which is required to drain nodes.
+func IndexPodsByNodeName(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &v1.Pod{}, podNodeNameField, func(obj client.Object) []string {
+		return []string{obj.(*v1.Pod).Spec.NodeName}
+	})
+}
+
+// IsNodeUpgrading returns true if the module loader of moduleName is being upgraded on node.
+// Such nodes are cordoned, by the operator unless they already were.
+func IsNodeUpgrading(node *v1.Node, moduleName string) bool {
+	_, ok := node.Annotations[nodeUpgradeAnnotation(moduleName)]
+	return ok
+}
+
+// SetDriverContainerAsDesired sets ds as the module loader DaemonSet for mld, with pods that are only replaced
+// by Upgrade.
+// If the upgrade to mld's container image was rolled back, ds keeps running the previous image.
+func (u *upgrader) SetDriverContainerAsDesired(ctx context.Context, ds *appsv1.DaemonSet, mld *api.ModuleLoaderData, useDefaultSA bool) error {
+	currentImage := moduleLoaderImage(ds)
+
+	annotations := ds.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	targetMLD := *mld
+
+	if rolledBackImage, ok := annotations[rolledBackImageAnnotation]; ok && rolledBackImage == mld.ContainerImage {
+		targetMLD.ContainerImage = annotations[previousImageAnnotation]
+	} else {
+		if currentImage != "" && currentImage != mld.ContainerImage {
+			annotations[previousImageAnnotation] = currentImage
+		}
+
+		delete(annotations, rolledBackImageAnnotation)
+	}
+
+	if err := u.daemonAPI.SetDriverContainerAsDesired(ctx, ds, &targetMLD, useDefaultSA); err != nil {
+		return err
+	}
+
+	hash, err := templateHash(&ds.Spec.Template)
+	if err != nil {
+		return fmt.Errorf("could not hash the pod template: %v", err)
+	}
+
+	ds.SetAnnotations(annotations)
+	ds.Spec.Template.SetAnnotations(
+		daemonset.OverrideLabels(ds.Spec.Template.GetAnnotations(), map[string]string{templateHashAnnotation: hash}),
+	)
+	ds.Spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType}
+
+	return nil
+}
+
+// Upgrade replaces the outdated module loader pods of ds, a few nodes at a time.
+// Each node is cordoned and optionally drained before its module loader pod is deleted.
+// It is uncordoned once the new module loader pod is ready and the module is loaded.
+// If a node does not become ready in time, no further node is upgraded and, if enabled, the previous image is
+// restored.
+func (u *upgrader) Upgrade(ctx context.Context, ds *appsv1.DaemonSet, mld *api.ModuleLoaderData) (*kmmv1beta1.ModuleUpgradeStatus, error) {
+	logger := log.FromContext(ctx).WithValues("daemonset", ds.Name)
+
+	strategy := mld.UpgradeStrategy
+
+	_, rollingBack := ds.GetAnnotations()[rolledBackImageAnnotation]
+
+	nodes, err := u.daemonSetNodes(ctx, ds)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the nodes of DaemonSet %s: %v", ds.Name, err)
+	}
+
+	podsByNode, err := u.daemonSetPodsByNode(ctx, ds)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the pods of DaemonSet %s: %v", ds.Name, err)
+	}
+
+	hash := ds.Spec.Template.Annotations[templateHashAnnotation]
+	readyLabel := daemonset.GetDriverContainerNodeLabel(mld.Name)
+	timeout := nodeReadyTimeout(strategy)
+
+	status := &kmmv1beta1.ModuleUpgradeStatus{
+		PreviousImage: ds.GetAnnotations()[previousImageAnnotation],
+		TargetImage:   moduleLoaderImage(ds),
+		TotalNodes:    int32(len(nodes)),
+	}
+
+	candidates := make([]*v1.Node, 0)
+
+	// outdated nodes that were cordoned by someone else, and that are not upgraded until they are uncordoned
+	cordoned := 0
+
+	for i := 0; i < len(nodes); i++ {
+		node := &nodes[i]
+		pods := podsByNode[node.Name]
+		outdated := hasOutdatedPod(pods, hash)
+
+		if !IsNodeUpgrading(node, mld.Name) {
+			if !outdated {
+				status.UpgradedNodes++
+			} else if !node.Spec.Unschedulable {
+				candidates = append(candidates, node)
+			} else {
+				logger.Info("Waiting for the node to be uncordoned before upgrading it", "node", node.Name)
+				cordoned++
+			}
+
+			continue
+		}
+
+		nodeLogger := logger.WithValues("node", node.Name)
+
+		if _, ok := node.Labels[readyLabel]; ok && !outdated && hasReadyPod(pods, hash) {
+			nodeLogger.Info("Module is ready; uncordoning the node")
+
+			if err = u.unmarkNodeUpgrading(ctx, node, mld.Name); err != nil {
+				return nil, fmt.Errorf("could not uncordon node %s: %v", node.Name, err)
+			}
+
+			status.UpgradedNodes++
+			continue
+		}
+
+		startTime, err := time.Parse(time.RFC3339, node.Annotations[nodeUpgradeAnnotation(mld.Name)])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the upgrade start time of node %s: %v", node.Name, err)
+		}
+
+		restarted, err := u.upgradeNode(ctx, node, pods, hash, strategy.Drain)
+		if err != nil {
+			return nil, fmt.Errorf("could not upgrade node %s: %v", node.Name, err)
+		}
+
+		if restarted {
+			// the pod template changed since the upgrade of this node started: give the new pod a full timeout
+			if err = u.markNodeUpgrading(ctx, node, mld.Name); err != nil {
+				return nil, fmt.Errorf("could not restart the upgrade of node %s: %v", node.Name, err)
+			}
+		} else if u.clock.Since(startTime) > timeout {
+			nodeLogger.Info("Module did not become ready in time", "timeout", timeout)
+			status.FailedNodes = append(status.FailedNodes, node.Name)
+			continue
+		}
+
+		status.CurrentNodes = append(status.CurrentNodes, node.Name)
+	}
+
+	if len(status.FailedNodes) > 0 {
+		status.Phase = kmmv1beta1.UpgradePhaseFailed
+
+		if strategy.RollbackOnFailure && !rollingBack && status.PreviousImage != "" {
+			logger.Info("Upgrade failed; rolling back", "image", status.PreviousImage, "failed nodes", status.FailedNodes)
+
+			if err = u.setDaemonSetAnnotation(ctx, ds, rolledBackImageAnnotation, status.TargetImage); err != nil {
+				return nil, fmt.Errorf("could not roll back DaemonSet %s: %v", ds.Name, err)
+			}
+
+			status.Phase = kmmv1beta1.UpgradePhaseRollingBack
+		}
+
+		return status, nil
+	}
+
+	if !strategy.Paused {
+		for _, node := range candidates {
+			if len(status.CurrentNodes) >= int(maxParallelNodes(strategy)) {
+				break
+			}
+
+			logger.Info("Starting the upgrade of a node", "node", node.Name)
+
+			if err = u.markNodeUpgrading(ctx, node, mld.Name); err != nil {
+				return nil, fmt.Errorf("could not cordon node %s: %v", node.Name, err)
+			}
+
+			if _, err = u.upgradeNode(ctx, node, podsByNode[node.Name], hash, strategy.Drain); err != nil {
+				return nil, fmt.Errorf("could not upgrade node %s: %v", node.Name, err)
+			}
+
+			status.CurrentNodes = append(status.CurrentNodes, node.Name)
+		}
+	}
+
+	pending := len(status.CurrentNodes) > 0 || len(candidates) > 0 || cordoned > 0
+
+	switch {
+	case !pending && rollingBack:
+		status.Phase = kmmv1beta1.UpgradePhaseRolledBack
+	case !pending:
+		if status.PreviousImage != "" {
+			if err = u.setDaemonSetAnnotation(ctx, ds, previousImageAnnotation, ""); err != nil {
+				return nil, fmt.Errorf("could not complete the upgrade of DaemonSet %s: %v", ds.Name, err)
+			}
+
+			status.PreviousImage = ""
+		}
+
+		status.Phase = kmmv1beta1.UpgradePhaseCompleted
+	case strategy.Paused:
+		status.Phase = kmmv1beta1.UpgradePhasePaused
+	case rollingBack:
+		status.Phase = kmmv1beta1.UpgradePhaseRollingBack
+	default:
+		status.Phase = kmmv1beta1.UpgradePhaseInProgress
+	}
+
+	return status, nil
+}
+
+// upgradeNode drains node if needed and deletes its outdated module loader pods, which lets the DaemonSet
+// controller create new ones once the module is unloaded.
+// It returns true if at least one pod was deleted.
+func (u *upgrader) upgradeNode(ctx context.Context, node *v1.Node, pods []v1.Pod, hash string, drain bool) (bool, error) {
+	if drain {
+		drained, err := u.drainNode(ctx, node.Name)
+		if err != nil {
+			return false, fmt.Errorf("could not drain the node: %v", err)
+		}
+
+		if !drained {
+			log.FromContext(ctx).Info("Waiting for the node to be drained", "node", node.Name)
+			return false, nil
+		}
+	}
+
+	deleted := false
+
+	for i := 0; i < len(pods); i++ {
+		pod := &pods[i]
+
+		if pod.Annotations[templateHashAnnotation] == hash || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		// the module is unloaded by the PreStop hook of the pod; the DaemonSet controller only creates a new pod
+		// once it has terminated.
+		if err := u.client.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+			return false, fmt.Errorf("could not delete pod %s: %v", pod.Name, err)
+		}
+
+		deleted = true
+	}
+
+	return deleted, nil
+}
+
+// drainNode evicts all pods that are not managed by a DaemonSet from the node.
+// It returns true once no such pod is running on the node.
+func (u *upgrader) drainNode(ctx context.Context, nodeName string) (bool, error) {
+	podList := v1.PodList{}
+
+	if err := u.client.List(ctx, &podList, client.MatchingFields{podNodeNameField: nodeName}); err != nil {
+		return false, fmt.Errorf("could not list pods: %v", err)
+	}
+
+	drained := true
+
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName != nodeName || !isEvictable(&pod) {
+			continue
+		}
+
+		drained = false
+
+		if !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		eviction := &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		}
+
+		if err := u.evictions.Evictions(pod.Namespace).Evict(ctx, eviction); err != nil {
+			// TooManyRequests is returned when the eviction would violate a PodDisruptionBudget.
+			if k8serrors.IsTooManyRequests(err) || k8serrors.IsNotFound(err) {
+				continue
+			}
+
+			return false, fmt.Errorf("could not evict pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+
+	return drained, nil
+}
+
+// markNodeUpgrading records the start of the upgrade of node and cordons it.
+// If node is not cordoned yet, it also records that the operator cordoned it, so that it is only uncordoned then.
+func (u *upgrader) markNodeUpgrading(ctx context.Context, node *v1.Node, moduleName string) error {
+	nodeCopy := node.DeepCopy()
+
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string, 2)
+	}
+
+	node.Annotations[nodeUpgradeAnnotation(moduleName)] = u.clock.Now().UTC().Format(time.RFC3339)
+
+	if !node.Spec.Unschedulable {
+		node.Annotations[nodeCordonedAnnotation(moduleName)] = "true"
+		node.Spec.Unschedulable = true
+	}
+
+	return u.client.Patch(ctx, node, client.MergeFrom(nodeCopy))
+}
+
+// unmarkNodeUpgrading records the end of the upgrade of node, and uncordons it if it was cordoned by the operator.
+func (u *upgrader) unmarkNodeUpgrading(ctx context.Context, node *v1.Node, moduleName string) error {
+	nodeCopy := node.DeepCopy()
+
+	if node.Annotations[nodeCordonedAnnotation(moduleName)] == "true" {
+		node.Spec.Unschedulable = false
+	}
+
+	delete(node.Annotations, nodeUpgradeAnnotation(moduleName))
+	delete(node.Annotations, nodeCordonedAnnotation(moduleName))
+
+	return u.client.Patch(ctx, node, client.MergeFrom(nodeCopy))
+}
+
+// setDaemonSetAnnotation sets the annotation key of ds to value, or removes it if value is empty.
+func (u *upgrader) setDaemonSetAnnotation(ctx context.Context, ds *appsv1.DaemonSet, key, value string) error {
+	dsCopy := ds.DeepCopy()
+
+	if value == "" {
+		delete(ds.Annotations, key)
+	} else {
+		ds.SetAnnotations(daemonset.OverrideLabels(ds.GetAnnotations(), map[string]string{key: value}))
+	}
+
+	return u.client.Patch(ctx, ds, client.MergeFrom(dsCopy))
+}
+
+// daemonSetNodes returns the nodes matching the node selector of ds, sorted by name.
+func (u *upgrader) daemonSetNodes(ctx context.Context, ds *appsv1.DaemonSet) ([]v1.Node, error) {
+	nodeList := v1.NodeList{}
+
+	if err := u.client.List(ctx, &nodeList, client.MatchingLabels(ds.Spec.Template.Spec.NodeSelector)); err != nil {
+		return nil, fmt.Errorf("could not list nodes: %v", err)
+	}
+
+	sort.Slice(nodeList.Items, func(i, j int) bool {
+		return nodeList.Items[i].Name < nodeList.Items[j].Name
+	})
+
+	return nodeList.Items, nil
+}
+
+func (u *upgrader) daemonSetPodsByNode(ctx context.Context, ds *appsv1.DaemonSet) (map[string][]v1.Pod, error) {
+	podList := v1.PodList{}
+
+	opts := []client.ListOption{
+		client.InNamespace(ds.Namespace),
+		client.MatchingLabels(ds.Spec.Selector.MatchLabels),
+	}
+
+	if err := u.client.List(ctx, &podList, opts...); err != nil {
+		return nil, fmt.Errorf("could not list pods: %v", err)
+	}
+
+	podsByNode := make(map[string][]v1.Pod)
+
+	for _, pod := range podList.Items {
+		if !metav1.IsControlledBy(&pod, ds) {
+			continue
+		}
+
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	return podsByNode, nil
+}
+
+func hasOutdatedPod(pods []v1.Pod, hash string) bool {
+	for _, pod := range pods {
+		if pod.Annotations[templateHashAnnotation] != hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasReadyPod(pods []v1.Pod, hash string) bool {
+	for i := 0; i < len(pods); i++ {
+		pod := &pods[i]
+
+		if pod.Annotations[templateHashAnnotation] == hash && pod.DeletionTimestamp.IsZero() && podutils.IsPodReady(pod) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isEvictable returns true if pod should be evicted when draining its node.
+// Pods managed by a DaemonSet tolerate cordoned nodes and would be recreated immediately.
+func isEvictable(pod *v1.Pod) bool {
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return false
+	}
+
+	if _, ok := pod.Annotations[v1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+		return false
+	}
+
+	return true
+}
+
+func moduleLoaderImage(ds *appsv1.DaemonSet) string {
+	for _, c := range ds.Spec.Template.Spec.Containers {
+		if c.Name == daemonset.ModuleLoaderContainerName {
+			return c.Image
+		}
+	}
+
+	return ""
+}
+
+func maxParallelNodes(strategy *kmmv1beta1.UpgradeStrategy) int32 {
+	if strategy.MaxParallelNodes < 1 {
+		return 1
+	}
+
+	return strategy.MaxParallelNodes
+}
+
+func nodeReadyTimeout(strategy *kmmv1beta1.UpgradeStrategy) time.Duration {
+	if strategy.NodeReadyTimeout == nil {
+		return DefaultNodeReadyTimeout
+	}
+
+	return strategy.NodeReadyTimeout.Duration
+}
+
+func nodeUpgradeAnnotation(moduleName string) string {
+	return fmt.Sprintf("kmm.node.kubernetes.io/%s.upgrade-started", moduleName)
+}
+
+func nodeCordonedAnnotation(moduleName string) string {
+	return fmt.Sprintf("kmm.node.kubernetes.io/%s.upgrade-cordoned", moduleName)
+}
+
+func templateHash(template *v1.PodTemplateSpec) (string, error) {
+	b, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+
+	h := fnv.New64a()
+
+	if _, err = h.Write(b); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(h.Sum64(), 10), nil
+}
@@ -0,0 +1,618 @@
+package upgrade
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/api"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/client"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	testclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	moduleName   = "test-module"
+	namespace    = "test-namespace"
+	currentHash  = "current-hash"
+	outdatedHash = "outdated-hash"
+	newImage     = "new-image"
+	oldImage     = "old-image"
+)
+
+var _ = Describe("IsNodeByNode", func() {
+	It("should return false if there is no upgrade strategy", func() {
+		Expect(
+			IsNodeByNode(&api.ModuleLoaderData{}),
+		).To(BeFalse())
+	})
+
+	It("should return false for the RollingUpdate strategy", func() {
+		mld := api.ModuleLoaderData{
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyRollingUpdate},
+		}
+
+		Expect(
+			IsNodeByNode(&mld),
+		).To(BeFalse())
+	})
+
+	It("should return true for the NodeByNode strategy", func() {
+		mld := api.ModuleLoaderData{
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+
+		Expect(
+			IsNodeByNode(&mld),
+		).To(BeTrue())
+	})
+})
+
+var _ = Describe("SetDriverContainerAsDesired", func() {
+	var (
+		ctrl   *gomock.Controller
+		mockDC *daemonset.MockDaemonSetCreator
+		u      *upgrader
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockDC = daemonset.NewMockDaemonSetCreator(ctrl)
+		u = NewUpgrader(nil, mockDC, nil).(*upgrader)
+	})
+
+	ctx := context.Background()
+
+	setImage := func(_ context.Context, ds *appsv1.DaemonSet, mld *api.ModuleLoaderData, _ bool) error {
+		ds.Spec = appsv1.DaemonSetSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{Name: daemonset.ModuleLoaderContainerName, Image: mld.ContainerImage},
+					},
+				},
+			},
+		}
+
+		return nil
+	}
+
+	It("should use the OnDelete strategy and set the template hash", func() {
+		ds := appsv1.DaemonSet{}
+		mld := api.ModuleLoaderData{ContainerImage: newImage}
+
+		mockDC.EXPECT().SetDriverContainerAsDesired(ctx, &ds, &mld, true).DoAndReturn(setImage)
+
+		err := u.SetDriverContainerAsDesired(ctx, &ds, &mld, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ds.Spec.UpdateStrategy.Type).To(Equal(appsv1.OnDeleteDaemonSetStrategyType))
+		Expect(ds.Spec.Template.Annotations).To(HaveKey(templateHashAnnotation))
+		Expect(ds.Annotations).NotTo(HaveKey(previousImageAnnotation))
+	})
+
+	It("should record the previous image when the image changes", func() {
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{rolledBackImageAnnotation: "some-other-image"},
+			},
+		}
+		mld := api.ModuleLoaderData{ContainerImage: oldImage}
+
+		mockDC.EXPECT().SetDriverContainerAsDesired(ctx, &ds, gomock.Any(), false).DoAndReturn(setImage).Times(2)
+
+		err := u.SetDriverContainerAsDesired(ctx, &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		oldHash := ds.Spec.Template.Annotations[templateHashAnnotation]
+
+		mld.ContainerImage = newImage
+
+		err = u.SetDriverContainerAsDesired(ctx, &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ds.Annotations).To(HaveKeyWithValue(previousImageAnnotation, oldImage))
+		Expect(ds.Annotations).NotTo(HaveKey(rolledBackImageAnnotation))
+		Expect(ds.Spec.Template.Annotations[templateHashAnnotation]).NotTo(Equal(oldHash))
+	})
+
+	It("should keep the previous image if the upgrade was rolled back", func() {
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					previousImageAnnotation:   oldImage,
+					rolledBackImageAnnotation: newImage,
+				},
+			},
+		}
+		mld := api.ModuleLoaderData{Name: moduleName, ContainerImage: newImage}
+		expectedMLD := api.ModuleLoaderData{Name: moduleName, ContainerImage: oldImage}
+
+		mockDC.EXPECT().SetDriverContainerAsDesired(ctx, &ds, &expectedMLD, false).DoAndReturn(setImage)
+
+		err := u.SetDriverContainerAsDesired(ctx, &ds, &mld, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ds.Annotations).To(HaveKeyWithValue(previousImageAnnotation, oldImage))
+		Expect(ds.Annotations).To(HaveKeyWithValue(rolledBackImageAnnotation, newImage))
+		Expect(moduleLoaderImage(&ds)).To(Equal(oldImage))
+	})
+})
+
+var _ = Describe("Upgrade", func() {
+	var (
+		ctrl          *gomock.Controller
+		clnt          *client.MockClient
+		fakeClientSet *fake.Clientset
+		u             *upgrader
+	)
+
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	readyLabel := daemonset.GetDriverContainerNodeLabel(moduleName)
+	upgradeAnnotation := nodeUpgradeAnnotation(moduleName)
+	cordonedAnnotation := nodeCordonedAnnotation(moduleName)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+		fakeClientSet = fake.NewSimpleClientset()
+		u = NewUpgrader(clnt, nil, fakeClientSet.PolicyV1()).(*upgrader)
+		u.clock = testclock.NewFakePassiveClock(now)
+	})
+
+	ctx := context.Background()
+
+	newDS := func(annotations map[string]string) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "ds",
+				Namespace:   namespace,
+				UID:         "ds-uid",
+				Annotations: annotations,
+			},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "module-loader"},
+				},
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{templateHashAnnotation: currentHash},
+					},
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{Name: daemonset.ModuleLoaderContainerName, Image: newImage},
+						},
+						NodeSelector: map[string]string{"kernel": "some-kernel"},
+					},
+				},
+			},
+		}
+	}
+
+	newPod := func(ds *appsv1.DaemonSet, nodeName, hash string, ready bool) v1.Pod {
+		readyStatus := v1.ConditionFalse
+		if ready {
+			readyStatus = v1.ConditionTrue
+		}
+
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-" + nodeName,
+				Namespace:   namespace,
+				Annotations: map[string]string{templateHashAnnotation: hash},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "DaemonSet", Name: ds.Name, UID: ds.UID, Controller: pointer.Bool(true)},
+				},
+			},
+			Spec: v1.PodSpec{NodeName: nodeName},
+			Status: v1.PodStatus{
+				Conditions: []v1.PodCondition{
+					{Type: v1.PodReady, Status: readyStatus},
+				},
+			},
+		}
+	}
+
+	upgradingNode := func(name string, startTime time.Time, labels map[string]string) v1.Node {
+		return v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+				Annotations: map[string]string{
+					upgradeAnnotation:  startTime.Format(time.RFC3339),
+					cordonedAnnotation: "true",
+				},
+			},
+			Spec: v1.NodeSpec{Unschedulable: true},
+		}
+	}
+
+	expectLists := func(nodes []v1.Node, pods []v1.Pod) *gomock.Call {
+		listNodes := clnt.
+			EXPECT().
+			List(ctx, gomock.AssignableToTypeOf(&v1.NodeList{}), gomock.Any()).
+			DoAndReturn(func(_ context.Context, list *v1.NodeList, _ ...ctrlclient.ListOption) error {
+				list.Items = nodes
+				return nil
+			})
+
+		return clnt.
+			EXPECT().
+			List(ctx, gomock.AssignableToTypeOf(&v1.PodList{}), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, list *v1.PodList, _ ...ctrlclient.ListOption) error {
+				list.Items = pods
+				return nil
+			}).
+			After(listNodes)
+	}
+
+	expectNodePatch := func(nodeName string, unschedulable bool, startTime string) *gomock.Call {
+		return clnt.
+			EXPECT().
+			Patch(ctx, gomock.AssignableToTypeOf(&v1.Node{}), gomock.Any()).
+			Do(func(_ context.Context, obj ctrlclient.Object, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+				node := obj.(*v1.Node)
+
+				Expect(node.Name).To(Equal(nodeName))
+				Expect(node.Spec.Unschedulable).To(Equal(unschedulable))
+
+				if startTime == "" {
+					Expect(node.Annotations).NotTo(HaveKey(upgradeAnnotation))
+					Expect(node.Annotations).NotTo(HaveKey(cordonedAnnotation))
+				} else {
+					Expect(node.Annotations).To(HaveKeyWithValue(upgradeAnnotation, startTime))
+					Expect(node.Annotations).To(HaveKeyWithValue(cordonedAnnotation, "true"))
+				}
+			})
+	}
+
+	It("should cordon the first outdated node and delete its module loader pod", func() {
+		ds := newDS(map[string]string{previousImageAnnotation: oldImage})
+		nodes := []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		}
+		pods := []v1.Pod{
+			newPod(ds, "node1", outdatedHash, true),
+			newPod(ds, "node2", outdatedHash, true),
+		}
+		mld := api.ModuleLoaderData{
+			Name:            moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+
+		gomock.InOrder(
+			expectLists(nodes, pods),
+			expectNodePatch("node1", true, now.Format(time.RFC3339)),
+			clnt.EXPECT().Delete(ctx, &pods[0]),
+		)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status).To(Equal(&kmmv1beta1.ModuleUpgradeStatus{
+			Phase:         kmmv1beta1.UpgradePhaseInProgress,
+			PreviousImage: oldImage,
+			TargetImage:   newImage,
+			TotalNodes:    2,
+			CurrentNodes:  []string{"node1"},
+		}))
+	})
+
+	It("should not start the upgrade of new nodes while paused", func() {
+		ds := newDS(map[string]string{previousImageAnnotation: oldImage})
+		nodes := []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		}
+		pods := []v1.Pod{
+			newPod(ds, "node1", outdatedHash, true),
+		}
+		mld := api.ModuleLoaderData{
+			Name: moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{
+				Type:   kmmv1beta1.UpgradeStrategyNodeByNode,
+				Paused: true,
+			},
+		}
+
+		expectLists(nodes, pods)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status.Phase).To(Equal(kmmv1beta1.UpgradePhasePaused))
+		Expect(status.CurrentNodes).To(BeEmpty())
+	})
+
+	It("should wait for the module to be ready on a node being upgraded", func() {
+		ds := newDS(map[string]string{previousImageAnnotation: oldImage})
+		nodes := []v1.Node{
+			upgradingNode("node1", now.Add(-time.Minute), nil),
+			{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+		}
+		pods := []v1.Pod{
+			newPod(ds, "node1", currentHash, false),
+			newPod(ds, "node2", outdatedHash, true),
+		}
+		mld := api.ModuleLoaderData{
+			Name:            moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+
+		expectLists(nodes, pods)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status.Phase).To(Equal(kmmv1beta1.UpgradePhaseInProgress))
+		Expect(status.CurrentNodes).To(Equal([]string{"node1"}))
+	})
+
+	It("should uncordon the node once the module is ready and complete the upgrade", func() {
+		ds := newDS(map[string]string{previousImageAnnotation: oldImage})
+		nodes := []v1.Node{
+			upgradingNode("node1", now.Add(-time.Minute), map[string]string{readyLabel: ""}),
+			{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+		}
+		pods := []v1.Pod{
+			newPod(ds, "node1", currentHash, true),
+			newPod(ds, "node2", currentHash, true),
+		}
+		mld := api.ModuleLoaderData{
+			Name:            moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+
+		gomock.InOrder(
+			expectLists(nodes, pods),
+			expectNodePatch("node1", false, ""),
+			clnt.
+				EXPECT().
+				Patch(ctx, ds, gomock.Any()).
+				Do(func(_ context.Context, obj ctrlclient.Object, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+					Expect(obj.GetAnnotations()).NotTo(HaveKey(previousImageAnnotation))
+				}),
+		)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status).To(Equal(&kmmv1beta1.ModuleUpgradeStatus{
+			Phase:         kmmv1beta1.UpgradePhaseCompleted,
+			TargetImage:   newImage,
+			UpgradedNodes: 2,
+			TotalNodes:    2,
+		}))
+	})
+
+	It("should not uncordon a node that was already cordoned when its upgrade started", func() {
+		ds := newDS(nil)
+		node := upgradingNode("node1", now.Add(-time.Minute), map[string]string{readyLabel: ""})
+		delete(node.Annotations, cordonedAnnotation)
+		pods := []v1.Pod{
+			newPod(ds, "node1", currentHash, true),
+		}
+		mld := api.ModuleLoaderData{
+			Name:            moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+
+		gomock.InOrder(
+			expectLists([]v1.Node{node}, pods),
+			expectNodePatch("node1", true, ""),
+		)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Phase).To(Equal(kmmv1beta1.UpgradePhaseCompleted))
+	})
+
+	It("should not complete the upgrade while an outdated node is cordoned", func() {
+		ds := newDS(map[string]string{previousImageAnnotation: oldImage})
+		nodes := []v1.Node{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+				Spec:       v1.NodeSpec{Unschedulable: true},
+			},
+			{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+		}
+		pods := []v1.Pod{
+			newPod(ds, "node1", outdatedHash, true),
+			newPod(ds, "node2", currentHash, true),
+		}
+		mld := api.ModuleLoaderData{
+			Name:            moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+
+		expectLists(nodes, pods)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status).To(Equal(&kmmv1beta1.ModuleUpgradeStatus{
+			Phase:         kmmv1beta1.UpgradePhaseInProgress,
+			PreviousImage: oldImage,
+			TargetImage:   newImage,
+			UpgradedNodes: 1,
+			TotalNodes:    2,
+		}))
+	})
+
+	It("should fail if the module does not become ready in time", func() {
+		ds := newDS(map[string]string{previousImageAnnotation: oldImage})
+		nodes := []v1.Node{
+			upgradingNode("node1", now.Add(-2*time.Minute), nil),
+			{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+		}
+		pods := []v1.Pod{
+			newPod(ds, "node1", currentHash, false),
+			newPod(ds, "node2", outdatedHash, true),
+		}
+		mld := api.ModuleLoaderData{
+			Name: moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{
+				Type:             kmmv1beta1.UpgradeStrategyNodeByNode,
+				NodeReadyTimeout: &metav1.Duration{Duration: time.Minute},
+			},
+		}
+
+		expectLists(nodes, pods)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status.Phase).To(Equal(kmmv1beta1.UpgradePhaseFailed))
+		Expect(status.FailedNodes).To(Equal([]string{"node1"}))
+		Expect(status.CurrentNodes).To(BeEmpty())
+	})
+
+	It("should roll back if the module does not become ready in time", func() {
+		ds := newDS(map[string]string{previousImageAnnotation: oldImage})
+		nodes := []v1.Node{
+			upgradingNode("node1", now.Add(-DefaultNodeReadyTimeout-time.Second), nil),
+		}
+		pods := []v1.Pod{
+			newPod(ds, "node1", currentHash, false),
+		}
+		mld := api.ModuleLoaderData{
+			Name: moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{
+				Type:              kmmv1beta1.UpgradeStrategyNodeByNode,
+				RollbackOnFailure: true,
+			},
+		}
+
+		gomock.InOrder(
+			expectLists(nodes, pods),
+			clnt.
+				EXPECT().
+				Patch(ctx, ds, gomock.Any()).
+				Do(func(_ context.Context, obj ctrlclient.Object, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+					Expect(obj.GetAnnotations()).To(HaveKeyWithValue(rolledBackImageAnnotation, newImage))
+				}),
+		)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status.Phase).To(Equal(kmmv1beta1.UpgradePhaseRollingBack))
+		Expect(status.FailedNodes).To(Equal([]string{"node1"}))
+	})
+
+	It("should restart the upgrade of a node if the pod template changed", func() {
+		ds := newDS(map[string]string{
+			previousImageAnnotation:   oldImage,
+			rolledBackImageAnnotation: "failed-image",
+		})
+		nodes := []v1.Node{
+			upgradingNode("node1", now.Add(-DefaultNodeReadyTimeout-time.Second), nil),
+		}
+		pods := []v1.Pod{
+			newPod(ds, "node1", outdatedHash, false),
+		}
+		mld := api.ModuleLoaderData{
+			Name:            moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+
+		gomock.InOrder(
+			expectLists(nodes, pods),
+			clnt.EXPECT().Delete(ctx, &pods[0]),
+			expectNodePatch("node1", true, now.Format(time.RFC3339)),
+		)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status.Phase).To(Equal(kmmv1beta1.UpgradePhaseRollingBack))
+		Expect(status.CurrentNodes).To(Equal([]string{"node1"}))
+	})
+
+	It("should report a completed rollback", func() {
+		ds := newDS(map[string]string{
+			previousImageAnnotation:   oldImage,
+			rolledBackImageAnnotation: "failed-image",
+		})
+		nodes := []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		}
+		pods := []v1.Pod{
+			newPod(ds, "node1", currentHash, true),
+		}
+		mld := api.ModuleLoaderData{
+			Name:            moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode},
+		}
+
+		expectLists(nodes, pods)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status.Phase).To(Equal(kmmv1beta1.UpgradePhaseRolledBack))
+		Expect(status.UpgradedNodes).To(BeEquivalentTo(1))
+	})
+
+	It("should drain the node before deleting the module loader pod", func() {
+		ds := newDS(nil)
+		nodes := []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		}
+		moduleLoaderPod := newPod(ds, "node1", outdatedHash, true)
+		workloadPod := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "workload", Namespace: "other-namespace"},
+			Spec:       v1.PodSpec{NodeName: "node1"},
+		}
+		otherNodePod := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-node", Namespace: "other-namespace"},
+			Spec:       v1.PodSpec{NodeName: "node2"},
+		}
+		mld := api.ModuleLoaderData{
+			Name: moduleName,
+			UpgradeStrategy: &kmmv1beta1.UpgradeStrategy{
+				Type:  kmmv1beta1.UpgradeStrategyNodeByNode,
+				Drain: true,
+			},
+		}
+
+		evicted := make([]types.NamespacedName, 0)
+
+		fakeClientSet.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+			evicted = append(evicted, types.NamespacedName{Namespace: eviction.Namespace, Name: eviction.Name})
+			return true, nil, nil
+		})
+
+		gomock.InOrder(
+			expectLists(nodes, []v1.Pod{moduleLoaderPod}),
+			expectNodePatch("node1", true, now.Format(time.RFC3339)),
+			clnt.
+				EXPECT().
+				List(ctx, gomock.AssignableToTypeOf(&v1.PodList{}), ctrlclient.MatchingFields{"spec.nodeName": "node1"}).
+				DoAndReturn(func(_ context.Context, list *v1.PodList, _ ...ctrlclient.ListOption) error {
+					list.Items = []v1.Pod{moduleLoaderPod, workloadPod, otherNodePod}
+					return nil
+				}),
+		)
+
+		status, err := u.Upgrade(ctx, ds, &mld)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status.Phase).To(Equal(kmmv1beta1.UpgradePhaseInProgress))
+		Expect(evicted).To(Equal([]types.NamespacedName{{Namespace: "other-namespace", Name: "workload"}}))
+	})
+})
@@ -22,6 +22,12 @@ const (
 	StatusCreated    = "created"
 	StatusInProgress = "in progress"
 	StatusFailed     = "failed"
+	// StatusRetrying means that the last attempt failed and that a new one will be created once its backoff has
+	// elapsed.
+	StatusRetrying = "retrying"
+	// StatusQueued means that the Build or the Job was not created yet, because the maximum number of concurrent
+	// builds and sign Jobs is reached.
+	StatusQueued = "queued"
 )
 
 var ErrNoMatchingJob = errors.New("no matching job")
@@ -30,8 +36,8 @@ var ErrNoMatchingJob = errors.New("no matching job")
 
 type JobHelper interface {
 	IsJobChanged(existingJob *batchv1.Job, newJob *batchv1.Job) (bool, error)
-	JobLabels(modName string, targetKernel string, jobType string) map[string]string
-	GetModuleJobByKernel(ctx context.Context, modName, namespace, targetKernel, jobType string, owner metav1.Object) (*batchv1.Job, error)
+	JobLabels(modName, targetKernel, targetArch, jobType string) map[string]string
+	GetModuleJobByKernel(ctx context.Context, modName, namespace, targetKernel, targetArch, jobType string, owner metav1.Object) (*batchv1.Job, error)
 	GetModuleJobs(ctx context.Context, modName, namespace, jobType string, owner metav1.Object) ([]batchv1.Job, error)
 	DeleteJob(ctx context.Context, job *batchv1.Job) error
 	CreateJob(ctx context.Context, jobTemplate *batchv1.Job) error
@@ -60,12 +66,14 @@ func (jh *jobHelper) IsJobChanged(existingJob *batchv1.Job, newJob *batchv1.Job)
 	return true, nil
 }
 
-func (jh *jobHelper) JobLabels(modName string, targetKernel string, jobType string) map[string]string {
-	return moduleKernelLabels(modName, targetKernel, jobType)
+// JobLabels returns the labels of the jobs for a module, a kernel and an architecture.
+// targetArch may be empty if the architecture is not known.
+func (jh *jobHelper) JobLabels(modName, targetKernel, targetArch, jobType string) map[string]string {
+	return moduleKernelLabels(modName, targetKernel, targetArch, jobType)
 }
 
-func (jh *jobHelper) GetModuleJobByKernel(ctx context.Context, modName, namespace, targetKernel, jobType string, owner metav1.Object) (*batchv1.Job, error) {
-	matchLabels := moduleKernelLabels(modName, targetKernel, jobType)
+func (jh *jobHelper) GetModuleJobByKernel(ctx context.Context, modName, namespace, targetKernel, targetArch, jobType string, owner metav1.Object) (*batchv1.Job, error) {
+	matchLabels := moduleKernelLabels(modName, targetKernel, targetArch, jobType)
 	jobs, err := jh.getJobs(ctx, namespace, matchLabels)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get module %s, jobs by kernel %s: %v", modName, targetKernel, err)
@@ -139,9 +147,12 @@ func (jh *jobHelper) getJobs(ctx context.Context, namespace string, labels map[s
 	return jobList.Items, nil
 }
 
-func moduleKernelLabels(moduleName, targetKernel, jobType string) map[string]string {
+func moduleKernelLabels(moduleName, targetKernel, targetArch, jobType string) map[string]string {
 	labels := moduleLabels(moduleName, jobType)
 	labels[constants.TargetKernelTarget] = targetKernel
+	if targetArch != "" {
+		labels[constants.ArchLabel] = targetArch
+	}
 	return labels
 }
 
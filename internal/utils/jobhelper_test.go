@@ -35,11 +35,19 @@ var _ = Describe("JobLabels", func() {
 			ObjectMeta: metav1.ObjectMeta{Name: "moduleName"},
 		}
 		mgr := NewJobHelper(clnt)
-		labels := mgr.JobLabels(mod.Name, "targetKernel", "jobType")
+		labels := mgr.JobLabels(mod.Name, "targetKernel", "", "jobType")
 
 		Expect(labels).To(HaveKeyWithValue(constants.ModuleNameLabel, "moduleName"))
 		Expect(labels).To(HaveKeyWithValue(constants.TargetKernelTarget, "targetKernel"))
 		Expect(labels).To(HaveKeyWithValue(constants.JobType, "jobType"))
+		Expect(labels).NotTo(HaveKey(constants.ArchLabel))
+	})
+
+	It("should add the architecture to the job labels", func() {
+		mgr := NewJobHelper(clnt)
+		labels := mgr.JobLabels("moduleName", "targetKernel", "arm64", "jobType")
+
+		Expect(labels).To(HaveKeyWithValue(constants.ArchLabel, "arm64"))
 	})
 })
 
@@ -87,7 +95,7 @@ var _ = Describe("GetModuleJobByKernel", func() {
 			},
 		)
 
-		job, err := jh.GetModuleJobByKernel(ctx, mod.Name, mod.Namespace, "targetKernel", "jobType", &mod)
+		job, err := jh.GetModuleJobByKernel(ctx, mod.Name, mod.Namespace, "targetKernel", "", "jobType", &mod)
 
 		Expect(job).To(Equal(&j))
 		Expect(err).NotTo(HaveOccurred())
@@ -113,7 +121,7 @@ var _ = Describe("GetModuleJobByKernel", func() {
 
 		clnt.EXPECT().List(ctx, &jobList, opts).Return(errors.New("random error"))
 
-		_, err := jh.GetModuleJobByKernel(ctx, mod.Name, mod.Namespace, "targetKernel", "jobType", &mod)
+		_, err := jh.GetModuleJobByKernel(ctx, mod.Name, mod.Namespace, "targetKernel", "", "jobType", &mod)
 
 		Expect(err).To(HaveOccurred())
 	})
@@ -155,7 +163,7 @@ var _ = Describe("GetModuleJobByKernel", func() {
 			},
 		)
 
-		_, err = jh.GetModuleJobByKernel(ctx, mod.Name, mod.Namespace, "targetKernel", "jobType", &mod)
+		_, err = jh.GetModuleJobByKernel(ctx, mod.Name, mod.Namespace, "targetKernel", "", "jobType", &mod)
 
 		Expect(err).To(HaveOccurred())
 	})
@@ -202,7 +210,7 @@ var _ = Describe("GetModuleJobByKernel", func() {
 			},
 		)
 
-		job, err := jh.GetModuleJobByKernel(ctx, mod.Name, mod.Namespace, "targetKernel", "jobType", &mod)
+		job, err := jh.GetModuleJobByKernel(ctx, mod.Name, mod.Namespace, "targetKernel", "", "jobType", &mod)
 
 		Expect(err).NotTo(HaveOccurred())
 		Expect(job).To(Equal(&j1))
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var (
+	kernelDistroRegexp = regexp.MustCompile(`^(el|fc)\d+(_\d+)?$`)
+	kernelRTRegexp     = regexp.MustCompile(`^rt\d*$`)
+
+	kernelArchs = sets.NewString("aarch64", "armv7hl", "i686", "ppc64le", "riscv64", "s390x", "x86_64")
+)
+
+// KernelComponents holds the comparable components of a kernel version string.
+type KernelComponents struct {
+	// Version holds the numeric components of the upstream version followed by those of the release.
+	// 5.14.0-284.11.1.el9_2.x86_64 gives [5 14 0 284 11 1].
+	Version []int
+
+	// Flavour is the kernel flavour, such as rt, 64k or generic.
+	Flavour string
+
+	// Distro is the distribution suffix, such as el9_2 or fc37.
+	Distro string
+
+	// Arch is the architecture, such as x86_64.
+	Arch string
+}
+
+// ParseKernelVersion splits a kernel version string such as 5.14.0-284.11.1.rt14.296.el9_2.x86_64 into its
+// components, using the same separators as KernelComponentsAsEnvVars.
+// The numeric components that follow the first non-numeric one (like the RT release) are not part of the version.
+func ParseKernelVersion(kernel string) (*KernelComponents, error) {
+	kc := KernelComponents{}
+
+	kernel = strings.TrimSuffix(kernel, "+")
+
+	// 64k kernels are suffixed with +64k
+	if i := strings.Index(kernel, "+"); i >= 0 {
+		kc.Flavour = kernel[i+1:]
+		kernel = kernel[:i]
+	}
+
+	inVersion := true
+
+	for _, token := range kernelRegexp.Split(kernel, -1) {
+		if token == "" {
+			return nil, fmt.Errorf("kernel %q has an empty component", kernel)
+		}
+
+		if n, err := strconv.Atoi(token); err == nil {
+			if inVersion {
+				kc.Version = append(kc.Version, n)
+			}
+
+			continue
+		}
+
+		if len(kc.Version) == 0 {
+			return nil, fmt.Errorf("kernel %q does not start with a number", kernel)
+		}
+
+		inVersion = false
+
+		switch {
+		case kernelArchs.Has(token):
+			kc.Arch = token
+		case kernelDistroRegexp.MatchString(token):
+			kc.Distro = token
+		case kernelRTRegexp.MatchString(token):
+			kc.Flavour = "rt"
+		case kc.Flavour == "":
+			kc.Flavour = token
+		}
+	}
+
+	if len(kc.Version) == 0 {
+		return nil, errors.New("empty kernel version")
+	}
+
+	return &kc, nil
+}
+
+// CompareKernelVersions compares version against bound, only up to the precision of bound.
+// It returns a negative number if version is lower, 0 if they are equal and a positive number if version is higher.
+// For instance, 5.14.0-284.11.1 is equal to 5.14 and higher than 5.14.0-283.
+func CompareKernelVersions(version, bound []int) int {
+	for i, b := range bound {
+		if i >= len(version) {
+			return -1
+		}
+
+		if version[i] != b {
+			return version[i] - b
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseKernelVersion", func() {
+	DescribeTable("should parse valid kernels",
+		func(kernel string, expected KernelComponents) {
+			Expect(ParseKernelVersion(kernel)).To(Equal(&expected))
+		},
+		Entry(
+			"RHEL",
+			"5.14.0-284.11.1.el9_2.x86_64",
+			KernelComponents{Version: []int{5, 14, 0, 284, 11, 1}, Distro: "el9_2", Arch: "x86_64"},
+		),
+		Entry(
+			"RHEL RT",
+			"5.14.0-284.11.1.rt14.296.el9_2.x86_64",
+			KernelComponents{Version: []int{5, 14, 0, 284, 11, 1}, Flavour: "rt", Distro: "el9_2", Arch: "x86_64"},
+		),
+		Entry(
+			"RHEL 64k",
+			"5.14.0-284.11.1.el9_2.aarch64+64k",
+			KernelComponents{Version: []int{5, 14, 0, 284, 11, 1}, Flavour: "64k", Distro: "el9_2", Arch: "aarch64"},
+		),
+		Entry(
+			"Fedora",
+			"6.0.15-300.fc37.x86_64",
+			KernelComponents{Version: []int{6, 0, 15, 300}, Distro: "fc37", Arch: "x86_64"},
+		),
+		Entry(
+			"Ubuntu",
+			"5.15.0-76-generic",
+			KernelComponents{Version: []int{5, 15, 0, 76}, Flavour: "generic"},
+		),
+		Entry(
+			"trailing plus sign",
+			"5.14.0+",
+			KernelComponents{Version: []int{5, 14, 0}},
+		),
+		Entry(
+			"partial version",
+			"5.14",
+			KernelComponents{Version: []int{5, 14}},
+		),
+	)
+
+	DescribeTable("should return an error for invalid kernels",
+		func(kernel string) {
+			_, err := ParseKernelVersion(kernel)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty", ""),
+		Entry("not starting with a number", "el9.x86_64"),
+		Entry("empty component", "5..14"),
+	)
+})
+
+var _ = Describe("CompareKernelVersions", func() {
+	DescribeTable("should compare up to the precision of the bound",
+		func(version, bound []int, expectedSign int) {
+			res := CompareKernelVersions(version, bound)
+
+			switch expectedSign {
+			case -1:
+				Expect(res).To(BeNumerically("<", 0))
+			case 0:
+				Expect(res).To(BeZero())
+			case 1:
+				Expect(res).To(BeNumerically(">", 0))
+			}
+		},
+		Entry("equal", []int{5, 14, 0, 284}, []int{5, 14, 0, 284}, 0),
+		Entry("equal up to the precision of the bound", []int{5, 14, 0, 284, 11, 1}, []int{5, 14}, 0),
+		Entry("lower", []int{5, 14, 0, 283, 99}, []int{5, 14, 0, 284}, -1),
+		Entry("higher", []int{6, 0, 15}, []int{5, 14}, 1),
+		Entry("less precise than the bound", []int{5, 14}, []int{5, 14, 0}, -1),
+	)
+})
@@ -80,18 +80,18 @@ func (mr *MockJobHelperMockRecorder) GetJobStatus(job interface{}) *gomock.Call
 }
 
 // GetModuleJobByKernel mocks base method.
-func (m *MockJobHelper) GetModuleJobByKernel(ctx context.Context, modName, namespace, targetKernel, jobType string, owner v10.Object) (*v1.Job, error) {
+func (m *MockJobHelper) GetModuleJobByKernel(ctx context.Context, modName, namespace, targetKernel, targetArch, jobType string, owner v10.Object) (*v1.Job, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetModuleJobByKernel", ctx, modName, namespace, targetKernel, jobType, owner)
+	ret := m.ctrl.Call(m, "GetModuleJobByKernel", ctx, modName, namespace, targetKernel, targetArch, jobType, owner)
 	ret0, _ := ret[0].(*v1.Job)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetModuleJobByKernel indicates an expected call of GetModuleJobByKernel.
-func (mr *MockJobHelperMockRecorder) GetModuleJobByKernel(ctx, modName, namespace, targetKernel, jobType, owner interface{}) *gomock.Call {
+func (mr *MockJobHelperMockRecorder) GetModuleJobByKernel(ctx, modName, namespace, targetKernel, targetArch, jobType, owner interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModuleJobByKernel", reflect.TypeOf((*MockJobHelper)(nil).GetModuleJobByKernel), ctx, modName, namespace, targetKernel, jobType, owner)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModuleJobByKernel", reflect.TypeOf((*MockJobHelper)(nil).GetModuleJobByKernel), ctx, modName, namespace, targetKernel, targetArch, jobType, owner)
 }
 
 // GetModuleJobs mocks base method.
@@ -125,15 +125,15 @@ func (mr *MockJobHelperMockRecorder) IsJobChanged(existingJob, newJob interface{
 }
 
 // JobLabels mocks base method.
-func (m *MockJobHelper) JobLabels(modName, targetKernel, jobType string) map[string]string {
+func (m *MockJobHelper) JobLabels(modName, targetKernel, targetArch, jobType string) map[string]string {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "JobLabels", modName, targetKernel, jobType)
+	ret := m.ctrl.Call(m, "JobLabels", modName, targetKernel, targetArch, jobType)
 	ret0, _ := ret[0].(map[string]string)
 	return ret0
 }
 
 // JobLabels indicates an expected call of JobLabels.
-func (mr *MockJobHelperMockRecorder) JobLabels(modName, targetKernel, jobType interface{}) *gomock.Call {
+func (mr *MockJobHelperMockRecorder) JobLabels(modName, targetKernel, targetArch, jobType interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JobLabels", reflect.TypeOf((*MockJobHelper)(nil).JobLabels), modName, targetKernel, jobType)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JobLabels", reflect.TypeOf((*MockJobHelper)(nil).JobLabels), modName, targetKernel, targetArch, jobType)
 }
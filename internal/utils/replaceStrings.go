@@ -16,6 +16,81 @@ const (
 
 var kernelRegexp = regexp.MustCompile("[.,-]")
 
+// kernelArchToGoArch maps the architectures found in kernel versions to their Kubernetes names.
+var kernelArchToGoArch = map[string]string{
+	"aarch64": "arm64",
+	"x86_64":  "amd64",
+}
+
+// TemplateVarNames lists all the variables that can be used in the templated fields of a Module.
+var TemplateVarNames = []string{
+	"KERNEL_FULL_VERSION",
+	"KERNEL_VERSION",
+	"KERNEL_XYZ",
+	"KERNEL_X",
+	"KERNEL_Y",
+	"KERNEL_Z",
+	"KERNEL_FLAVOUR",
+	"ARCH",
+	"RHEL_VERSION",
+	"OS_IMAGE_VERSION",
+	"RHCOS_VERSION",
+	"MOD_NAME",
+	"MOD_NAMESPACE",
+}
+
+// TemplateVars holds the data the template variables are computed from.
+type TemplateVars struct {
+	KernelVersion string
+
+	// Arch is the architecture of the nodes, using the Kubernetes naming.
+	// If empty, it is derived from the kernel version when possible.
+	Arch string
+
+	// OSImageVersion is the version of the OS image run by the nodes, such as 413.92.202305231734-0.
+	// It is only known on OpenShift clusters.
+	OSImageVersion string
+
+	ModName      string
+	ModNamespace string
+}
+
+// EnvVars returns the template variables in the form expected by ReplaceInTemplates.
+// Variables that cannot be determined are not set, so that using them in a template results in an error.
+func (tv *TemplateVars) EnvVars() []string {
+	envvars := make([]string, 0)
+
+	for _, v := range KernelComponentsAsEnvVars(tv.KernelVersion) {
+		// the architecture of the nodes prevails over the one found in the kernel version
+		if tv.Arch != "" && strings.HasPrefix(v, "ARCH=") {
+			continue
+		}
+
+		envvars = append(envvars, v)
+	}
+
+	if tv.Arch != "" {
+		envvars = append(envvars, "ARCH="+tv.Arch)
+	}
+
+	envvars = append(
+		envvars,
+		"MOD_NAME="+tv.ModName,
+		"MOD_NAMESPACE="+tv.ModNamespace,
+	)
+
+	if v := tv.OSImageVersion; v != "" {
+		envvars = append(envvars, "OS_IMAGE_VERSION="+v)
+
+		// 413.92.202305231734-0 is RHCOS 413.92
+		if fields := strings.SplitN(v, ".", 3); len(fields) == 3 {
+			envvars = append(envvars, "RHCOS_VERSION="+fields[0]+"."+fields[1])
+		}
+	}
+
+	return envvars
+}
+
 func KernelComponentsAsEnvVars(kernel string) []string {
 	osConfigFieldsList := kernelRegexp.Split(kernel, -1)
 
@@ -28,11 +103,33 @@ func KernelComponentsAsEnvVars(kernel string) []string {
 		"KERNEL_Z=" + osConfigFieldsList[kernelVersionPatchIdx],
 	}
 
+	kc, err := ParseKernelVersion(kernel)
+	if err != nil {
+		return envvars
+	}
+
+	envvars = append(envvars, "KERNEL_FLAVOUR="+kc.Flavour)
+
+	if arch := kc.Arch; arch != "" {
+		if goArch, ok := kernelArchToGoArch[arch]; ok {
+			arch = goArch
+		}
+
+		envvars = append(envvars, "ARCH="+arch)
+	}
+
+	// el9_2 is RHEL 9.2
+	if strings.HasPrefix(kc.Distro, "el") {
+		envvars = append(envvars, "RHEL_VERSION="+strings.ReplaceAll(strings.TrimPrefix(kc.Distro, "el"), "_", "."))
+	}
+
 	return envvars
 }
 
+// ReplaceInTemplates substitutes the variables in templates.
+// Using a variable that is not in envvars is an error; $$ can be used to produce a literal $.
 func ReplaceInTemplates(envvars []string, templates ...string) ([]string, error) {
-	parser := parse.New("mapping", envvars, &parse.Restrictions{})
+	parser := parse.New("mapping", envvars, parse.NoUnset)
 
 	replacedStrings := make([]string, 0, len(templates))
 
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"strings"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -16,10 +18,90 @@ var _ = Describe("KernelComponentsAsEnvVars", func() {
 			"KERNEL_X=6",
 			"KERNEL_Y=0",
 			"KERNEL_Z=15",
+			"KERNEL_FLAVOUR=",
+			"ARCH=amd64",
 		}
 
 		Expect(KernelComponentsAsEnvVars(kernelVersion)).To(Equal(expected))
 	})
+
+	It("should add the RHEL version and the flavour", func() {
+		Expect(
+			KernelComponentsAsEnvVars("5.14.0-284.11.1.rt14.296.el9_2.x86_64"),
+		).To(
+			ContainElements("KERNEL_FLAVOUR=rt", "ARCH=amd64", "RHEL_VERSION=9.2"),
+		)
+	})
+})
+
+var _ = Describe("TemplateVars", func() {
+	It("should set all the variables if everything is known", func() {
+		tv := TemplateVars{
+			KernelVersion:  "5.14.0-284.11.1.el9_2.aarch64+64k",
+			OSImageVersion: "413.92.202305231734-0",
+			ModName:        "name",
+			ModNamespace:   "namespace",
+		}
+
+		envvars := tv.EnvVars()
+
+		names := make([]string, 0, len(envvars))
+
+		for _, v := range envvars {
+			names = append(names, strings.SplitN(v, "=", 2)[0])
+		}
+
+		Expect(names).To(ConsistOf(TemplateVarNames))
+		Expect(envvars).To(
+			ContainElements(
+				"KERNEL_FLAVOUR=64k",
+				"ARCH=arm64",
+				"RHEL_VERSION=9.2",
+				"OS_IMAGE_VERSION=413.92.202305231734-0",
+				"RHCOS_VERSION=413.92",
+				"MOD_NAME=name",
+				"MOD_NAMESPACE=namespace",
+			),
+		)
+	})
+
+	It("should not set the OS variables if the OS image version is unknown", func() {
+		tv := TemplateVars{KernelVersion: "5.15.0-76-generic"}
+
+		Expect(
+			tv.EnvVars(),
+		).NotTo(
+			ContainElement(
+				Or(
+					HavePrefix("OS_IMAGE_VERSION="),
+					HavePrefix("RHCOS_VERSION="),
+					HavePrefix("RHEL_VERSION="),
+					HavePrefix("ARCH="),
+				),
+			),
+		)
+	})
+
+	It("should prefer the architecture of the nodes to the one in the kernel version", func() {
+		tv := TemplateVars{
+			KernelVersion: "5.14.0-284.11.1.el9_2.x86_64",
+			Arch:          "arm64",
+		}
+
+		envvars := tv.EnvVars()
+
+		Expect(envvars).To(ContainElement("ARCH=arm64"))
+		Expect(envvars).NotTo(ContainElement("ARCH=amd64"))
+	})
+
+	It("should set ARCH from the nodes if the kernel version does not contain it", func() {
+		tv := TemplateVars{
+			KernelVersion: "5.15.0-76-generic",
+			Arch:          "arm64",
+		}
+
+		Expect(tv.EnvVars()).To(ContainElement("ARCH=arm64"))
+	})
 })
 
 var _ = Describe("ReplaceInTemplates", func() {
@@ -40,4 +122,13 @@ var _ = Describe("ReplaceInTemplates", func() {
 
 		Expect(ReplaceInTemplates(vars, templates...)).To(Equal(expected))
 	})
+
+	It("should return an error if a variable is not set", func() {
+		_, err := ReplaceInTemplates([]string{"A=AAA"}, "$A", "${UNKNOWN}")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should allow escaping the dollar sign", func() {
+		Expect(ReplaceInTemplates(nil, "$${NOT_A_VARIABLE}")).To(Equal([]string{"${NOT_A_VARIABLE}"}))
+	})
 })
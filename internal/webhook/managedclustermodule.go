@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	hubv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api-hub/v1beta1"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
+)
+
+//+kubebuilder:webhook:path=/mutate-hub-kmm-sigs-x-k8s-io-v1beta1-managedclustermodule,mutating=true,failurePolicy=fail,sideEffects=None,groups=hub.kmm.sigs.x-k8s.io,resources=managedclustermodules,verbs=create;update,versions=v1beta1,name=mmanagedclustermodule.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-hub-kmm-sigs-x-k8s-io-v1beta1-managedclustermodule,mutating=false,failurePolicy=fail,sideEffects=None,groups=hub.kmm.sigs.x-k8s.io,resources=managedclustermodules,verbs=create;update,versions=v1beta1,name=vmanagedclustermodule.kb.io,admissionReviewVersions=v1
+
+// ManagedClusterModuleWebhook defaults and validates ManagedClusterModule objects.
+// The embedded ModuleSpec is handled exactly like a Module's spec.
+type ManagedClusterModuleWebhook struct {
+	helper *moduleSpecHelper
+}
+
+func NewManagedClusterModuleWebhook(buildHelper build.Helper, signHelper sign.Helper) *ManagedClusterModuleWebhook {
+	return &ManagedClusterModuleWebhook{
+		helper: &moduleSpecHelper{
+			buildHelper: buildHelper,
+			signHelper:  signHelper,
+		},
+	}
+}
+
+func (w *ManagedClusterModuleWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.
+		NewWebhookManagedBy(mgr).
+		For(&hubv1beta1.ManagedClusterModule{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+func (w *ManagedClusterModuleWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	mcm, ok := obj.(*hubv1beta1.ManagedClusterModule)
+	if !ok {
+		return fmt.Errorf("expected a ManagedClusterModule, got %T", obj)
+	}
+
+	defaultModuleSpec(&mcm.Spec.ModuleSpec)
+
+	return nil
+}
+
+func (w *ManagedClusterModuleWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return w.validate(obj, nil)
+}
+
+func (w *ManagedClusterModuleWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	old, ok := oldObj.(*hubv1beta1.ManagedClusterModule)
+	if !ok {
+		return fmt.Errorf("expected a ManagedClusterModule, got %T", oldObj)
+	}
+
+	return w.validate(newObj, &old.Spec.ModuleSpec)
+}
+
+func (w *ManagedClusterModuleWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validate validates obj; oldSpec is the spec of the object being updated, or nil on creation.
+// Updates are not validated if the object is being deleted or if its spec is unchanged.
+func (w *ManagedClusterModuleWebhook) validate(obj runtime.Object, oldSpec *kmmv1beta1.ModuleSpec) error {
+	mcm, ok := obj.(*hubv1beta1.ManagedClusterModule)
+	if !ok {
+		return fmt.Errorf("expected a ManagedClusterModule, got %T", obj)
+	}
+
+	if oldSpec != nil && (mcm.DeletionTimestamp != nil || isSpecUnchanged(oldSpec, &mcm.Spec.ModuleSpec)) {
+		return nil
+	}
+
+	fldPath := field.NewPath("spec", "moduleSpec")
+
+	errs := w.helper.validateModuleSpec(mcm.Name, &mcm.Spec.ModuleSpec, fldPath)
+
+	if oldSpec != nil {
+		errs = append(errs, validateModuleSpecUpdate(oldSpec, &mcm.Spec.ModuleSpec, fldPath)...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(hubv1beta1.GroupVersion.WithKind("ManagedClusterModule").GroupKind(), mcm.Name, errs)
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
+)
+
+//+kubebuilder:webhook:path=/mutate-kmm-sigs-x-k8s-io-v1beta1-module,mutating=true,failurePolicy=fail,sideEffects=None,groups=kmm.sigs.x-k8s.io,resources=modules,verbs=create;update,versions=v1beta1,name=mmodule.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-kmm-sigs-x-k8s-io-v1beta1-module,mutating=false,failurePolicy=fail,sideEffects=None,groups=kmm.sigs.x-k8s.io,resources=modules,verbs=create;update,versions=v1beta1,name=vmodule.kb.io,admissionReviewVersions=v1
+
+// ModuleWebhook defaults and validates Module objects.
+type ModuleWebhook struct {
+	helper *moduleSpecHelper
+}
+
+func NewModuleWebhook(buildHelper build.Helper, signHelper sign.Helper) *ModuleWebhook {
+	return &ModuleWebhook{
+		helper: &moduleSpecHelper{
+			buildHelper: buildHelper,
+			signHelper:  signHelper,
+		},
+	}
+}
+
+func (w *ModuleWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.
+		NewWebhookManagedBy(mgr).
+		For(&kmmv1beta1.Module{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+func (w *ModuleWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	mod, ok := obj.(*kmmv1beta1.Module)
+	if !ok {
+		return fmt.Errorf("expected a Module, got %T", obj)
+	}
+
+	defaultModuleSpec(&mod.Spec)
+
+	return nil
+}
+
+func (w *ModuleWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return w.validate(obj, nil)
+}
+
+func (w *ModuleWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	old, ok := oldObj.(*kmmv1beta1.Module)
+	if !ok {
+		return fmt.Errorf("expected a Module, got %T", oldObj)
+	}
+
+	return w.validate(newObj, &old.Spec)
+}
+
+func (w *ModuleWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validate validates obj; oldSpec is the spec of the object being updated, or nil on creation.
+// Updates are not validated if the object is being deleted or if its spec is unchanged.
+func (w *ModuleWebhook) validate(obj runtime.Object, oldSpec *kmmv1beta1.ModuleSpec) error {
+	mod, ok := obj.(*kmmv1beta1.Module)
+	if !ok {
+		return fmt.Errorf("expected a Module, got %T", obj)
+	}
+
+	if oldSpec != nil && (mod.DeletionTimestamp != nil || isSpecUnchanged(oldSpec, &mod.Spec)) {
+		return nil
+	}
+
+	fldPath := field.NewPath("spec")
+
+	errs := w.helper.validateModuleSpec(mod.Name, &mod.Spec, fldPath)
+
+	if oldSpec != nil {
+		errs = append(errs, validateModuleSpecUpdate(oldSpec, &mod.Spec, fldPath)...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(kmmv1beta1.GroupVersion.WithKind("Module").GroupKind(), mod.Name, errs)
+}
@@ -0,0 +1,734 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	hubv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api-hub/v1beta1"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
+)
+
+func validModule() *kmmv1beta1.Module {
+	return &kmmv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Name: "module-name", Namespace: "namespace"},
+		Spec: kmmv1beta1.ModuleSpec{
+			ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+				Container: kmmv1beta1.ModuleLoaderContainerSpec{
+					ContainerImage: "registry.example.com/org/image:tag",
+					KernelMappings: []kmmv1beta1.KernelMapping{
+						{Literal: "1.2.3"},
+						{Regexp: "^.+$"},
+					},
+					Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("ModuleWebhook_Default", func() {
+	w := NewModuleWebhook(build.NewHelper(), sign.NewSignerHelper())
+
+	It("should set the default values", func() {
+		mod := validModule()
+		mod.Spec.DevicePlugin = &kmmv1beta1.DevicePluginSpec{
+			Container: kmmv1beta1.DevicePluginContainerSpec{Image: "registry.example.com/org/device-plugin"},
+		}
+
+		Expect(
+			w.Default(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+
+		Expect(mod.Spec.ModuleLoader.Container.ImagePullPolicy).To(Equal(v1.PullIfNotPresent))
+		Expect(mod.Spec.ModuleLoader.Container.Modprobe.DirName).To(Equal("/opt"))
+		Expect(mod.Spec.DevicePlugin.Container.ImagePullPolicy).To(Equal(v1.PullAlways))
+	})
+
+	It("should leave the pull policy empty if the images have different defaults", func() {
+		mod := validModule()
+		mod.Spec.ModuleLoader.Container.KernelMappings[0].ContainerImage = "registry.example.com/org/image"
+
+		Expect(
+			w.Default(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+
+		Expect(mod.Spec.ModuleLoader.Container.ImagePullPolicy).To(BeEmpty())
+	})
+
+	It("should not overwrite user values", func() {
+		mod := validModule()
+		mod.Spec.ModuleLoader.Container.ImagePullPolicy = v1.PullNever
+		mod.Spec.ModuleLoader.Container.Modprobe.DirName = "/some/dir"
+
+		Expect(
+			w.Default(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+
+		Expect(mod.Spec.ModuleLoader.Container.ImagePullPolicy).To(Equal(v1.PullNever))
+		Expect(mod.Spec.ModuleLoader.Container.Modprobe.DirName).To(Equal("/some/dir"))
+	})
+
+	It("should return an error for unexpected types", func() {
+		Expect(
+			w.Default(context.Background(), &v1.Pod{}),
+		).To(
+			HaveOccurred(),
+		)
+	})
+})
+
+var _ = Describe("defaultPullPolicy", func() {
+	DescribeTable("should return the kubelet default",
+		func(expected v1.PullPolicy, images ...string) {
+			Expect(defaultPullPolicy(images...)).To(Equal(expected))
+		},
+		Entry("no images", v1.PullIfNotPresent),
+		Entry("tagged image", v1.PullIfNotPresent, "registry.example.com/org/image:tag"),
+		Entry("image pinned to a digest", v1.PullIfNotPresent, "registry.example.com/org/image@sha256:1234"),
+		Entry("untagged image", v1.PullAlways, "registry.example.com/org/image"),
+		Entry("untagged image on a registry with a port", v1.PullAlways, "registry.example.com:5000/org/image"),
+		Entry("latest tag", v1.PullAlways, "registry.example.com/org/image:latest"),
+		Entry("all images are untagged", v1.PullAlways, "image", "other-image:latest"),
+		Entry("the images have different defaults", v1.PullPolicy(""), "image:tag", "other-image"),
+		Entry("empty images are ignored", v1.PullAlways, "", "image"),
+	)
+})
+
+var _ = Describe("ModuleWebhook_Validate", func() {
+	w := NewModuleWebhook(build.NewHelper(), sign.NewSignerHelper())
+
+	It("should accept a valid Module", func() {
+		mod := validModule()
+
+		Expect(
+			w.ValidateCreate(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+
+		Expect(
+			w.ValidateUpdate(context.Background(), mod, mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	It("should accept a mapping overriding the build and sign settings of the Module", func() {
+		mod := validModule()
+		mod.Spec.ModuleLoader.Container.Build = &kmmv1beta1.Build{}
+		mod.Spec.ModuleLoader.Container.Sign = &kmmv1beta1.Sign{KeySecret: &v1.LocalObjectReference{Name: "key"}}
+		mod.Spec.ModuleLoader.Container.KernelMappings = []kmmv1beta1.KernelMapping{
+			{
+				Literal: "1.2.3",
+				Build: &kmmv1beta1.Build{
+					DockerfileConfigMap: &v1.LocalObjectReference{Name: "dockerfile"},
+				},
+				Sign: &kmmv1beta1.Sign{CertSecret: &v1.LocalObjectReference{Name: "cert"}},
+			},
+		}
+
+		Expect(
+			w.ValidateCreate(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	It("should accept a build from a Git repository", func() {
+		mod := validModule()
+		mod.Spec.ModuleLoader.Container.Build = &kmmv1beta1.Build{
+			Dockerfile: "FROM test",
+			Git: &kmmv1beta1.GitBuildSource{
+				URI: "ssh://git@git.example.com/org/repo.git",
+				Ref: "0123456789abcdef0123456789abcdef01234567",
+			},
+		}
+
+		Expect(
+			w.ValidateCreate(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	It("should accept a mapping using a matcher", func() {
+		mod := validModule()
+		mod.Spec.ModuleLoader.Container.KernelMappings = []kmmv1beta1.KernelMapping{
+			{
+				Matcher: &kmmv1beta1.KernelVersionMatcher{MinVersion: "5.14.0-284", MaxVersion: "5.14", Distro: "el9"},
+			},
+		}
+
+		Expect(
+			w.ValidateCreate(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	It("should accept all the known template variables", func() {
+		mod := validModule()
+		mod.Spec.ModuleLoader.Container.ContainerImage = "registry.example.com/org/image:${KERNEL_FULL_VERSION}-${ARCH}-${OS_IMAGE_VERSION}"
+		mod.Spec.ModuleLoader.Container.Modprobe.Parameters = []string{"name=$MOD_NAME", "ns=${MOD_NAMESPACE}", "literal=$$1"}
+		mod.Spec.ModuleLoader.Labels = map[string]string{"rhel": "${RHEL_VERSION}"}
+
+		Expect(
+			w.ValidateCreate(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	DescribeTable("should reject an invalid Module",
+		func(mutate func(*kmmv1beta1.Module), expectedField string) {
+			mod := validModule()
+			mutate(mod)
+
+			err := w.ValidateCreate(context.Background(), mod)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(expectedField))
+		},
+		Entry(
+			"mapping without literal nor regexp",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[0].Literal = ""
+			},
+			"spec.moduleLoader.container.kernelMappings[0]",
+		),
+		Entry(
+			"mapping with both literal and regexp",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[0].Regexp = "^1"
+			},
+			"spec.moduleLoader.container.kernelMappings[0].regexp",
+		),
+		Entry(
+			"mapping with both literal and matcher",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[0].Matcher = &kmmv1beta1.KernelVersionMatcher{Distro: "el9"}
+			},
+			"spec.moduleLoader.container.kernelMappings[0].matcher",
+		),
+		Entry(
+			"empty matcher",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[1] = kmmv1beta1.KernelMapping{Matcher: &kmmv1beta1.KernelVersionMatcher{}}
+			},
+			"spec.moduleLoader.container.kernelMappings[1].matcher",
+		),
+		Entry(
+			"invalid matcher version",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[1] = kmmv1beta1.KernelMapping{
+					Matcher: &kmmv1beta1.KernelVersionMatcher{MinVersion: "el9"},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[1].matcher.minVersion",
+		),
+		Entry(
+			"matcher with an empty range",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[1] = kmmv1beta1.KernelMapping{
+					Matcher: &kmmv1beta1.KernelVersionMatcher{MinVersion: "5.14.0-284", MaxVersion: "5.14.0-162"},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[1].matcher.maxVersion",
+		),
+		Entry(
+			"invalid regexp",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[1].Regexp = "("
+			},
+			"spec.moduleLoader.container.kernelMappings[1].regexp",
+		),
+		Entry(
+			"no container image",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.ContainerImage = ""
+			},
+			"spec.moduleLoader.container.kernelMappings[0].containerImage",
+		),
+		Entry(
+			"build without a Dockerfile",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[1].Build = &kmmv1beta1.Build{}
+			},
+			"spec.moduleLoader.container.kernelMappings[1].build.dockerfileConfigMap",
+		),
+		Entry(
+			"build with both a Dockerfile ConfigMap and an inline Dockerfile",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Build = &kmmv1beta1.Build{
+					DockerfileConfigMap: &v1.LocalObjectReference{Name: "dockerfile"},
+					Dockerfile:          "FROM test",
+				}
+			},
+			"spec.moduleLoader.container.build.dockerfile",
+		),
+		Entry(
+			"build from a Git repository without a URI",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[1].Build = &kmmv1beta1.Build{
+					Git: &kmmv1beta1.GitBuildSource{Ref: "main"},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[1].build.git.uri",
+		),
+		Entry(
+			"build from a branch of a Git repository served over SSH",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[1].Build = &kmmv1beta1.Build{
+					Git: &kmmv1beta1.GitBuildSource{URI: "ssh://git@git.example.com/org/repo.git", Ref: "main"},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[1].build.git.ref",
+		),
+		Entry(
+			"sign without a key",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Sign = &kmmv1beta1.Sign{
+					UnsignedImage: "some-image",
+					CertSecret:    &v1.LocalObjectReference{Name: "cert"},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[0].sign.keySecret",
+		),
+		Entry(
+			"sign without a certificate",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Sign = &kmmv1beta1.Sign{
+					UnsignedImage: "some-image",
+					KeySecret:     &v1.LocalObjectReference{Name: "key"},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[0].sign.certSecret",
+		),
+		Entry(
+			"sign without build nor unsigned image",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Sign = &kmmv1beta1.Sign{
+					KeySecret:  &v1.LocalObjectReference{Name: "key"},
+					CertSecret: &v1.LocalObjectReference{Name: "cert"},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[0].sign.unsignedImage",
+		),
+		Entry(
+			"no module name",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Modprobe.ModuleName = ""
+			},
+			"spec.moduleLoader.container.modprobe.moduleName",
+		),
+		Entry(
+			"negative node ready timeout",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.UpgradeStrategy = &kmmv1beta1.UpgradeStrategy{
+					Type:             kmmv1beta1.UpgradeStrategyNodeByNode,
+					NodeReadyTimeout: &metav1.Duration{Duration: -time.Minute},
+				}
+			},
+			"spec.upgradeStrategy.nodeReadyTimeout",
+		),
+		Entry(
+			"unknown variable in the container image",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.ContainerImage = "registry.example.com/org/image:${UNKNOWN}"
+			},
+			"spec.moduleLoader.container.containerImage",
+		),
+		Entry(
+			"unknown variable in a build argument",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[0].Build = &kmmv1beta1.Build{
+					BuildArgs:           []kmmv1beta1.BuildArg{{Name: "arg", Value: "$UNKNOWN"}},
+					DockerfileConfigMap: &v1.LocalObjectReference{Name: "dockerfile"},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[0].build.buildArgs[0].value",
+		),
+		Entry(
+			"unknown variable in the modprobe parameters",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Modprobe.Parameters = []string{"param=${UNKNOWN}"}
+			},
+			"spec.moduleLoader.container.modprobe.parameters[0]",
+		),
+		Entry(
+			"loading order not starting with the module name",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Modprobe.ModulesLoadingOrder = []string{"some-dep", "some-kmod"}
+			},
+			"spec.moduleLoader.container.modprobe.modulesLoadingOrder[0]",
+		),
+		Entry(
+			"duplicate module in the loading order",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Modprobe.ModulesLoadingOrder = []string{"some-kmod", "some-dep", "some-dep"}
+			},
+			"spec.moduleLoader.container.modprobe.modulesLoadingOrder[2]",
+		),
+		Entry(
+			"whitespace in the module name of options",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Modprobe.Options = []kmmv1beta1.ModprobeOptions{
+					{ModuleName: "some dep", Parameters: []string{"a=b"}},
+				}
+			},
+			"spec.moduleLoader.container.modprobe.options[0].moduleName",
+		),
+		Entry(
+			"line break in an option",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Modprobe.Options = []kmmv1beta1.ModprobeOptions{
+					{ModuleName: "some-dep", Parameters: []string{"a=b\nblacklist other"}},
+				}
+			},
+			"spec.moduleLoader.container.modprobe.options[0].parameters[0]",
+		),
+		Entry(
+			"unknown variable in an option",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Modprobe.Options = []kmmv1beta1.ModprobeOptions{
+					{ModuleName: "some-dep", Parameters: []string{"a=${UNKNOWN}"}},
+				}
+			},
+			"spec.moduleLoader.container.modprobe.options[0].parameters[0]",
+		),
+		Entry(
+			"blacklisted module name",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Modprobe.Blacklist = []string{"other-kmod", "some-kmod"}
+			},
+			"spec.moduleLoader.container.modprobe.blacklist[1]",
+		),
+		Entry(
+			"unknown variable in a label",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Labels = map[string]string{"some-label": "${UNKNOWN}"}
+			},
+			"spec.moduleLoader.labels[some-label]",
+		),
+		Entry(
+			"unknown variable in an annotation",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Annotations = map[string]string{"some-annotation": "${UNKNOWN}"}
+			},
+			"spec.moduleLoader.annotations[some-annotation]",
+		),
+		Entry(
+			"volume name reserved for the operator",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Volumes = []v1.Volume{{Name: "node-lib-modules"}}
+			},
+			"spec.moduleLoader.volumes[0].name",
+		),
+		Entry(
+			"unknown variable in the sign settings",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Sign = &kmmv1beta1.Sign{
+					UnsignedImage: "registry.example.com/org/image:${UNKNOWN}",
+					KeySecret:     &v1.LocalObjectReference{Name: "key"},
+					CertSecret:    &v1.LocalObjectReference{Name: "cert"},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[0].sign",
+		),
+		Entry(
+			"dependency on itself",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.DependsOn = []string{"module-name"}
+			},
+			"spec.dependsOn[0]",
+		),
+		Entry(
+			"duplicate dependency",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.DependsOn = []string{"core", "core"}
+			},
+			"spec.dependsOn[1]",
+		),
+		Entry(
+			"invalid dependency name",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.DependsOn = []string{"Not_A_Name"}
+			},
+			"spec.dependsOn[0]",
+		),
+		Entry(
+			"node-by-node upgrade in the Job load mode",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.LoadMode = kmmv1beta1.LoadModeJob
+				mod.Spec.UpgradeStrategy = &kmmv1beta1.UpgradeStrategy{Type: kmmv1beta1.UpgradeStrategyNodeByNode}
+			},
+			"spec.upgradeStrategy.type",
+		),
+		Entry(
+			"boot loading without a MachineConfigPool",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.BootLoading = &kmmv1beta1.BootLoadingSpec{}
+			},
+			"spec.bootLoading.machineConfigPool",
+		),
+		Entry(
+			"boot loading with an invalid MachineConfigPool",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.BootLoading = &kmmv1beta1.BootLoadingSpec{MachineConfigPool: "not a pool"}
+			},
+			"spec.bootLoading.machineConfigPool",
+		),
+		Entry(
+			"boot loading in the Job load mode",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.LoadMode = kmmv1beta1.LoadModeJob
+				mod.Spec.BootLoading = &kmmv1beta1.BootLoadingSpec{MachineConfigPool: "worker"}
+			},
+			"spec.bootLoading",
+		),
+		Entry(
+			"boot loading with firmware",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Modprobe.FirmwarePath = "/firmware"
+				mod.Spec.BootLoading = &kmmv1beta1.BootLoadingSpec{MachineConfigPool: "worker"}
+			},
+			"spec.moduleLoader.container.modprobe.firmwarePath",
+		),
+		Entry(
+			"build retry policy without attempts",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.Build = &kmmv1beta1.Build{
+					DockerfileConfigMap: &v1.LocalObjectReference{Name: "dockerfile"},
+					RetryPolicy:         &kmmv1beta1.RetryPolicy{},
+				}
+			},
+			"spec.moduleLoader.container.build.retryPolicy.maxAttempts",
+		),
+		Entry(
+			"sign retry policy with a maximum backoff lower than the initial one",
+			func(mod *kmmv1beta1.Module) {
+				mod.Spec.ModuleLoader.Container.KernelMappings[0].Sign = &kmmv1beta1.Sign{
+					RetryPolicy: &kmmv1beta1.RetryPolicy{
+						MaxAttempts:    3,
+						InitialBackoff: &metav1.Duration{Duration: time.Minute},
+						MaxBackoff:     &metav1.Duration{Duration: time.Second},
+					},
+				}
+			},
+			"spec.moduleLoader.container.kernelMappings[0].sign.retryPolicy.maxBackoff",
+		),
+	)
+
+	Context("with a Module that does not pass the current validation", func() {
+		var invalidMod *kmmv1beta1.Module
+
+		BeforeEach(func() {
+			invalidMod = validModule()
+			invalidMod.Spec.ModuleLoader.Container.KernelMappings[0].Literal = ""
+		})
+
+		It("should accept metadata changes", func() {
+			mod := invalidMod.DeepCopy()
+			mod.Finalizers = []string{"some-finalizer"}
+
+			Expect(
+				w.ValidateUpdate(context.Background(), invalidMod, mod),
+			).NotTo(
+				HaveOccurred(),
+			)
+		})
+
+		It("should accept the defaults set by the mutating webhook", func() {
+			mod := invalidMod.DeepCopy()
+
+			Expect(
+				w.Default(context.Background(), mod),
+			).NotTo(
+				HaveOccurred(),
+			)
+
+			Expect(
+				w.ValidateUpdate(context.Background(), invalidMod, mod),
+			).NotTo(
+				HaveOccurred(),
+			)
+		})
+
+		It("should accept the removal of the finalizer once the Module is being deleted", func() {
+			invalidMod.Finalizers = []string{"some-finalizer"}
+			now := metav1.Now()
+			invalidMod.DeletionTimestamp = &now
+
+			mod := invalidMod.DeepCopy()
+			mod.Finalizers = nil
+
+			Expect(
+				w.ValidateUpdate(context.Background(), invalidMod, mod),
+			).NotTo(
+				HaveOccurred(),
+			)
+		})
+
+		It("should reject spec changes", func() {
+			mod := invalidMod.DeepCopy()
+			mod.Spec.ModuleLoader.Container.ContainerImage = "registry.example.com/org/other-image:tag"
+
+			err := w.ValidateUpdate(context.Background(), invalidMod, mod)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+
+	It("should reject a change of the load mode", func() {
+		oldMod := validModule()
+		mod := validModule()
+		mod.Spec.LoadMode = kmmv1beta1.LoadModeJob
+
+		err := w.ValidateUpdate(context.Background(), oldMod, mod)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.loadMode"))
+	})
+
+	It("should accept setting the default load mode explicitly", func() {
+		oldMod := validModule()
+		mod := validModule()
+		mod.Spec.LoadMode = kmmv1beta1.LoadModeDaemonSet
+
+		Expect(
+			w.ValidateUpdate(context.Background(), oldMod, mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	It("should accept a modprobe configuration", func() {
+		mod := validModule()
+		mod.Spec.ModuleLoader.Container.Modprobe.ModulesLoadingOrder = []string{"some-kmod", "some-dep"}
+		mod.Spec.ModuleLoader.Container.Modprobe.Options = []kmmv1beta1.ModprobeOptions{
+			{ModuleName: "some-dep", Parameters: []string{"kver=${KERNEL_FULL_VERSION}", `name="a b"`}},
+		}
+		mod.Spec.ModuleLoader.Container.Modprobe.Blacklist = []string{"in-tree-kmod"}
+
+		Expect(
+			w.ValidateCreate(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	It("should accept boot loading", func() {
+		mod := validModule()
+		mod.Spec.BootLoading = &kmmv1beta1.BootLoadingSpec{MachineConfigPool: "worker"}
+
+		Expect(
+			w.ValidateCreate(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	It("should accept raw modprobe arguments", func() {
+		mod := validModule()
+		mod.Spec.ModuleLoader.Container.Modprobe = kmmv1beta1.ModprobeSpec{
+			ModuleName: "some_kmod",
+			RawArgs:    &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod"}},
+		}
+
+		Expect(
+			w.ValidateCreate(context.Background(), mod),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	It("should require the module name with raw modprobe arguments", func() {
+		mod := validModule()
+		mod.Spec.ModuleLoader.Container.Modprobe = kmmv1beta1.ModprobeSpec{
+			RawArgs: &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod"}},
+		}
+
+		Expect(
+			w.ValidateCreate(context.Background(), mod),
+		).To(
+			MatchError(ContainSubstring("moduleName")),
+		)
+	})
+
+	It("should always accept deletions", func() {
+		Expect(
+			w.ValidateDelete(context.Background(), &kmmv1beta1.Module{}),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+})
+
+var _ = Describe("ManagedClusterModuleWebhook", func() {
+	w := NewManagedClusterModuleWebhook(build.NewHelper(), sign.NewSignerHelper())
+
+	It("should default the ModuleSpec", func() {
+		mcm := &hubv1beta1.ManagedClusterModule{
+			Spec: hubv1beta1.ManagedClusterModuleSpec{ModuleSpec: validModule().Spec},
+		}
+
+		Expect(
+			w.Default(context.Background(), mcm),
+		).NotTo(
+			HaveOccurred(),
+		)
+
+		Expect(mcm.Spec.ModuleSpec.ModuleLoader.Container.ImagePullPolicy).To(Equal(v1.PullIfNotPresent))
+	})
+
+	It("should validate the ModuleSpec", func() {
+		mcm := &hubv1beta1.ManagedClusterModule{
+			ObjectMeta: metav1.ObjectMeta{Name: "mcm"},
+			Spec:       hubv1beta1.ManagedClusterModuleSpec{ModuleSpec: validModule().Spec},
+		}
+
+		Expect(
+			w.ValidateCreate(context.Background(), mcm),
+		).NotTo(
+			HaveOccurred(),
+		)
+
+		oldMCM := mcm.DeepCopy()
+		mcm.Spec.ModuleSpec.ModuleLoader.Container.KernelMappings[0].Literal = ""
+
+		err := w.ValidateUpdate(context.Background(), oldMCM, mcm)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.moduleSpec.moduleLoader.container.kernelMappings[0]"))
+	})
+
+	It("should not validate a ManagedClusterModule being deleted", func() {
+		mcm := &hubv1beta1.ManagedClusterModule{
+			ObjectMeta: metav1.ObjectMeta{Name: "mcm"},
+			Spec:       hubv1beta1.ManagedClusterModuleSpec{ModuleSpec: validModule().Spec},
+		}
+		mcm.Spec.ModuleSpec.ModuleLoader.Container.KernelMappings[0].Literal = ""
+
+		oldMCM := mcm.DeepCopy()
+		oldMCM.Finalizers = []string{"some-finalizer"}
+
+		now := metav1.Now()
+		mcm.DeletionTimestamp = &now
+
+		Expect(
+			w.ValidateUpdate(context.Background(), oldMCM, mcm),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+})
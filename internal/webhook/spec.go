@@ -0,0 +1,573 @@
+package webhook
+
+import (
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/build/git"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/daemonset"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/retry"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/sign"
+	"github.com/rh-ecosystem-edge/kernel-module-management/internal/utils"
+)
+
+const defaultModprobeDirName = "/opt"
+
+// placeholderTemplateVars sets all the template variables to a dummy value, so that only the unknown variables and
+// the syntax errors are reported when validating templates; the rendered values are not validated.
+var placeholderTemplateVars = func() []string {
+	vars := make([]string, 0, len(utils.TemplateVarNames))
+
+	for _, name := range utils.TemplateVarNames {
+		vars = append(vars, name+"=placeholder")
+	}
+
+	return vars
+}()
+
+type moduleSpecHelper struct {
+	buildHelper build.Helper
+	signHelper  sign.Helper
+}
+
+// defaultModuleSpec sets the default values of the fields that were left empty by the user.
+func defaultModuleSpec(spec *kmmv1beta1.ModuleSpec) {
+	container := &spec.ModuleLoader.Container
+
+	if container.ImagePullPolicy == "" {
+		images := []string{container.ContainerImage}
+
+		for _, km := range container.KernelMappings {
+			images = append(images, km.ContainerImage)
+		}
+
+		container.ImagePullPolicy = defaultPullPolicy(images...)
+	}
+
+	if container.Modprobe.DirName == "" {
+		container.Modprobe.DirName = defaultModprobeDirName
+	}
+
+	if dp := spec.DevicePlugin; dp != nil && dp.Container.ImagePullPolicy == "" {
+		dp.Container.ImagePullPolicy = defaultPullPolicy(dp.Container.Image)
+	}
+}
+
+// defaultPullPolicy returns the pull policy shared by the defaults of all images.
+// If the images have different defaults, it returns an empty policy so that each pod is defaulted according to its
+// own image.
+func defaultPullPolicy(images ...string) v1.PullPolicy {
+	var policy v1.PullPolicy
+
+	for _, image := range images {
+		if image == "" {
+			continue
+		}
+
+		imagePolicy := imagePullPolicy(image)
+
+		if policy != "" && policy != imagePolicy {
+			return ""
+		}
+
+		policy = imagePolicy
+	}
+
+	if policy == "" {
+		return v1.PullIfNotPresent
+	}
+
+	return policy
+}
+
+// imagePullPolicy mimics the kubelet defaulting: images that are not pinned to a digest and that use the latest or
+// no tag are always pulled.
+func imagePullPolicy(image string) v1.PullPolicy {
+	if strings.Contains(image, "@") {
+		return v1.PullIfNotPresent
+	}
+
+	lastSlash := strings.LastIndex(image, "/")
+	tagSep := strings.LastIndex(image, ":")
+
+	if tagSep <= lastSlash || image[tagSep+1:] == "latest" {
+		return v1.PullAlways
+	}
+
+	return v1.PullIfNotPresent
+}
+
+// validateModuleSpec returns all the errors found in spec, name being the name of the Module it belongs to.
+// The Build and Sign settings of each kernel mapping are merged with the Module's ones, the same way the
+// reconciler does, before being validated.
+func (h *moduleSpecHelper) validateModuleSpec(name string, spec *kmmv1beta1.ModuleSpec, fldPath *field.Path) field.ErrorList {
+	container := spec.ModuleLoader.Container
+	containerPath := fldPath.Child("moduleLoader", "container")
+
+	errs := h.validateModprobe(container.Modprobe, containerPath.Child("modprobe"))
+
+	errs = append(errs, validateTemplates(containerPath.Child("containerImage"), container.ContainerImage)...)
+	errs = append(errs, validateBuildArgsTemplates(container.Build, containerPath.Child("build"))...)
+
+	if container.Build != nil {
+		errs = append(errs, validateBuildSource(container.Build, containerPath.Child("build"))...)
+		errs = append(errs, validateRetryPolicy(container.Build.RetryPolicy, containerPath.Child("build", "retryPolicy"))...)
+	}
+
+	if container.Sign != nil {
+		errs = append(errs, validateRetryPolicy(container.Sign.RetryPolicy, containerPath.Child("sign", "retryPolicy"))...)
+	}
+
+	for k, v := range spec.ModuleLoader.Labels {
+		errs = append(errs, validateTemplates(fldPath.Child("moduleLoader", "labels").Key(k), v)...)
+	}
+
+	for k, v := range spec.ModuleLoader.Annotations {
+		errs = append(errs, validateTemplates(fldPath.Child("moduleLoader", "annotations").Key(k), v)...)
+	}
+
+	for i, vol := range spec.ModuleLoader.Volumes {
+		if daemonset.IsReservedVolumeName(vol.Name) {
+			errs = append(
+				errs,
+				field.Invalid(fldPath.Child("moduleLoader", "volumes").Index(i).Child("name"), vol.Name, "reserved for the operator"),
+			)
+		}
+	}
+
+	for i, km := range container.KernelMappings {
+		errs = append(errs, h.validateKernelMapping(&container, &km, containerPath.Child("kernelMappings").Index(i))...)
+	}
+
+	if us := spec.UpgradeStrategy; us != nil && us.NodeReadyTimeout != nil && us.NodeReadyTimeout.Duration <= 0 {
+		errs = append(
+			errs,
+			field.Invalid(fldPath.Child("upgradeStrategy", "nodeReadyTimeout"), us.NodeReadyTimeout.Duration.String(), "must be positive"),
+		)
+	}
+
+	if us := spec.UpgradeStrategy; us != nil && us.Type == kmmv1beta1.UpgradeStrategyNodeByNode && loadMode(spec) != kmmv1beta1.LoadModeDaemonSet {
+		errs = append(
+			errs,
+			field.Invalid(fldPath.Child("upgradeStrategy", "type"), us.Type, "only supported by the DaemonSet load mode"),
+		)
+	}
+
+	errs = append(errs, validateDependsOn(name, spec.DependsOn, fldPath.Child("dependsOn"))...)
+	errs = append(errs, validateBootLoading(spec, fldPath)...)
+
+	return errs
+}
+
+// validateBootLoading returns the errors of the boot loading configuration.
+// The kernel module is loaded at boot from the systemd unit, which does not copy firmware files to the host.
+func validateBootLoading(spec *kmmv1beta1.ModuleSpec, specPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	bl := spec.BootLoading
+	if bl == nil {
+		return errs
+	}
+
+	fldPath := specPath.Child("bootLoading")
+	poolPath := fldPath.Child("machineConfigPool")
+
+	if bl.MachineConfigPool == "" {
+		errs = append(errs, field.Required(poolPath, "the MachineConfigPool loading the kernel module at boot"))
+	} else {
+		for _, msg := range validation.IsValidLabelValue(bl.MachineConfigPool) {
+			errs = append(errs, field.Invalid(poolPath, bl.MachineConfigPool, msg))
+		}
+	}
+
+	if loadMode(spec) != kmmv1beta1.LoadModeDaemonSet {
+		errs = append(errs, field.Invalid(fldPath, "", "only supported by the DaemonSet load mode"))
+	}
+
+	if fp := spec.ModuleLoader.Container.Modprobe.FirmwarePath; fp != "" {
+		errs = append(
+			errs,
+			field.Invalid(specPath.Child("moduleLoader", "container", "modprobe", "firmwarePath"), fp, "not supported with boot loading"),
+		)
+	}
+
+	return errs
+}
+
+// validateModuleSpecUpdate returns the errors caused by changing oldSpec into spec.
+func validateModuleSpecUpdate(oldSpec, spec *kmmv1beta1.ModuleSpec, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	// The kernel modules loaded in one mode cannot be unloaded in the other one.
+	if loadMode(oldSpec) != loadMode(spec) {
+		errs = append(errs, field.Forbidden(fldPath.Child("loadMode"), "cannot be changed"))
+	}
+
+	return errs
+}
+
+// isSpecUnchanged returns true if spec only differs from oldSpec by the defaults set by the mutating webhook.
+// Updates that do not change the spec, such as adding or removing a finalizer, are not validated again, so that objects
+// created before a validation rule was introduced can still be reconciled and deleted.
+func isSpecUnchanged(oldSpec, spec *kmmv1beta1.ModuleSpec) bool {
+	if reflect.DeepEqual(oldSpec, spec) {
+		return true
+	}
+
+	defaulted := oldSpec.DeepCopy()
+	defaultModuleSpec(defaulted)
+
+	return reflect.DeepEqual(defaulted, spec)
+}
+
+// loadMode returns the load mode of spec, which is DaemonSet if it was not set.
+func loadMode(spec *kmmv1beta1.ModuleSpec) kmmv1beta1.LoadMode {
+	if spec.LoadMode == "" {
+		return kmmv1beta1.LoadModeDaemonSet
+	}
+
+	return spec.LoadMode
+}
+
+func validateDependsOn(name string, dependsOn []string, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	seen := sets.NewString()
+
+	for i, dep := range dependsOn {
+		depPath := fldPath.Index(i)
+
+		switch {
+		case dep == name:
+			errs = append(errs, field.Invalid(depPath, dep, "a module cannot depend on itself"))
+		case seen.Has(dep):
+			errs = append(errs, field.Duplicate(depPath, dep))
+		default:
+			for _, msg := range validation.IsDNS1123Subdomain(dep) {
+				errs = append(errs, field.Invalid(depPath, dep, msg))
+			}
+		}
+
+		seen.Insert(dep)
+	}
+
+	return errs
+}
+
+func (h *moduleSpecHelper) validateModprobe(modprobe kmmv1beta1.ModprobeSpec, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	// the module name is used to check that the kernel module is loaded, including when rawArgs is set
+	if modprobe.ModuleName == "" {
+		errs = append(errs, field.Required(fldPath.Child("moduleName"), "required"))
+	}
+
+	errs = append(errs, validateTemplates(fldPath.Child("dirName"), modprobe.DirName)...)
+
+	for i, p := range modprobe.Parameters {
+		errs = append(errs, validateTemplates(fldPath.Child("parameters").Index(i), p)...)
+	}
+
+	errs = append(errs, validateModulesLoadingOrder(modprobe, fldPath.Child("modulesLoadingOrder"))...)
+
+	for i, o := range modprobe.Options {
+		optPath := fldPath.Child("options").Index(i)
+
+		errs = append(errs, validateKernelModuleName(optPath.Child("moduleName"), o.ModuleName)...)
+
+		for j, p := range o.Parameters {
+			paramPath := optPath.Child("parameters").Index(j)
+
+			// each parameter is written on the options line of the modprobe configuration
+			if strings.ContainsAny(p, "\r\n") {
+				errs = append(errs, field.Invalid(paramPath, p, "must not contain line breaks"))
+			}
+
+			errs = append(errs, validateTemplates(paramPath, p)...)
+		}
+	}
+
+	for i, b := range modprobe.Blacklist {
+		bPath := fldPath.Child("blacklist").Index(i)
+
+		if b == modprobe.ModuleName {
+			errs = append(errs, field.Invalid(bPath, b, "cannot blacklist the kernel module to load"))
+			continue
+		}
+
+		errs = append(errs, validateKernelModuleName(bPath, b)...)
+	}
+
+	return errs
+}
+
+// validateModulesLoadingOrder checks that the loading order starts with the kernel module to load and does not list
+// any kernel module twice.
+func validateModulesLoadingOrder(modprobe kmmv1beta1.ModprobeSpec, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if len(modprobe.ModulesLoadingOrder) == 0 {
+		return errs
+	}
+
+	if first := modprobe.ModulesLoadingOrder[0]; first != modprobe.ModuleName {
+		errs = append(errs, field.Invalid(fldPath.Index(0), first, "must be the same as moduleName"))
+	}
+
+	seen := sets.NewString()
+
+	for i, m := range modprobe.ModulesLoadingOrder {
+		if seen.Has(m) {
+			errs = append(errs, field.Duplicate(fldPath.Index(i), m))
+			continue
+		}
+
+		seen.Insert(m)
+
+		errs = append(errs, validateKernelModuleName(fldPath.Index(i), m)...)
+	}
+
+	return errs
+}
+
+// validateKernelModuleName checks that name can be written in the modprobe configuration.
+func validateKernelModuleName(fldPath *field.Path, name string) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch {
+	case name == "":
+		errs = append(errs, field.Required(fldPath, "must not be empty"))
+	case strings.ContainsAny(name, " \t\r\n"):
+		errs = append(errs, field.Invalid(fldPath, name, "must not contain whitespace"))
+	}
+
+	return errs
+}
+
+func (h *moduleSpecHelper) validateKernelMapping(
+	container *kmmv1beta1.ModuleLoaderContainerSpec,
+	km *kmmv1beta1.KernelMapping,
+	fldPath *field.Path) field.ErrorList {
+
+	errs := field.ErrorList{}
+
+	set := 0
+
+	for _, isSet := range []bool{km.Literal != "", km.Regexp != "", km.Matcher != nil} {
+		if isSet {
+			set++
+		}
+	}
+
+	switch {
+	case set == 0:
+		errs = append(errs, field.Required(fldPath, "one of literal, regexp or matcher must be set"))
+	case set > 1 && km.Regexp != "":
+		errs = append(errs, field.Invalid(fldPath.Child("regexp"), km.Regexp, "literal, regexp and matcher are mutually exclusive"))
+	case set > 1:
+		errs = append(errs, field.Invalid(fldPath.Child("matcher"), km.Matcher, "literal and matcher are mutually exclusive"))
+	case km.Regexp != "":
+		if _, err := regexp.Compile(km.Regexp); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("regexp"), km.Regexp, err.Error()))
+		}
+	case km.Matcher != nil:
+		errs = append(errs, validateKernelVersionMatcher(km.Matcher, fldPath.Child("matcher"))...)
+	}
+
+	if km.ContainerImage == "" && container.ContainerImage == "" {
+		errs = append(
+			errs,
+			field.Required(fldPath.Child("containerImage"), "must be set if the module loader container has no containerImage"),
+		)
+	}
+
+	errs = append(errs, validateTemplates(fldPath.Child("containerImage"), km.ContainerImage)...)
+	errs = append(errs, validateBuildArgsTemplates(km.Build, fldPath.Child("build"))...)
+
+	if km.Build != nil {
+		errs = append(errs, validateBuildSource(km.Build, fldPath.Child("build"))...)
+		errs = append(errs, validateRetryPolicy(km.Build.RetryPolicy, fldPath.Child("build", "retryPolicy"))...)
+	}
+
+	if km.Sign != nil {
+		errs = append(errs, validateRetryPolicy(km.Sign.RetryPolicy, fldPath.Child("sign", "retryPolicy"))...)
+	}
+
+	shouldBeBuilt := km.Build != nil || container.Build != nil
+
+	if shouldBeBuilt {
+		b := h.buildHelper.GetRelevantBuild(container.Build, km.Build)
+
+		if (b.DockerfileConfigMap == nil || b.DockerfileConfigMap.Name == "") && b.Dockerfile == "" && b.Git == nil {
+			errs = append(
+				errs,
+				field.Required(
+					fldPath.Child("build", "dockerfileConfigMap"),
+					"one of dockerfileConfigMap, dockerfile or git is required for in-cluster builds",
+				),
+			)
+		}
+	}
+
+	if km.Sign != nil || container.Sign != nil {
+		s, err := h.signHelper.GetRelevantSign(container.Sign, km.Sign, placeholderTemplateVars)
+		if err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("sign"), km.Sign, err.Error()))
+			return errs
+		}
+
+		signPath := fldPath.Child("sign")
+
+		if s.KeySecret == nil || s.KeySecret.Name == "" {
+			errs = append(errs, field.Required(signPath.Child("keySecret"), "required for in-cluster signing"))
+		}
+
+		if s.CertSecret == nil || s.CertSecret.Name == "" {
+			errs = append(errs, field.Required(signPath.Child("certSecret"), "required for in-cluster signing"))
+		}
+
+		if !shouldBeBuilt && s.UnsignedImage == "" {
+			errs = append(errs, field.Required(signPath.Child("unsignedImage"), "required if the image is not built in-cluster"))
+		}
+	}
+
+	return errs
+}
+
+func validateKernelVersionMatcher(matcher *kmmv1beta1.KernelVersionMatcher, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if *matcher == (kmmv1beta1.KernelVersionMatcher{}) {
+		return append(errs, field.Required(fldPath, "at least one field must be set"))
+	}
+
+	var min, max *utils.KernelComponents
+
+	if v := matcher.MinVersion; v != "" {
+		var err error
+
+		if min, err = utils.ParseKernelVersion(v); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("minVersion"), v, err.Error()))
+		}
+	}
+
+	if v := matcher.MaxVersion; v != "" {
+		var err error
+
+		if max, err = utils.ParseKernelVersion(v); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("maxVersion"), v, err.Error()))
+		}
+	}
+
+	if min != nil && max != nil && utils.CompareKernelVersions(min.Version, max.Version) > 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("maxVersion"), matcher.MaxVersion, "must not be lower than minVersion"))
+	}
+
+	return errs
+}
+
+// validateTemplates reports the unknown variables and the syntax errors in templates.
+func validateTemplates(fldPath *field.Path, templates ...string) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for _, t := range templates {
+		if _, err := utils.ReplaceInTemplates(placeholderTemplateVars, t); err != nil {
+			errs = append(errs, field.Invalid(fldPath, t, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+// validateBuildSource returns the errors of the Dockerfile and Git sources of b.
+func validateBuildSource(b *kmmv1beta1.Build, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if b.DockerfileConfigMap != nil && b.Dockerfile != "" {
+		errs = append(errs, field.Invalid(fldPath.Child("dockerfile"), b.Dockerfile, "dockerfileConfigMap and dockerfile are mutually exclusive"))
+	}
+
+	g := b.Git
+	if g == nil {
+		return errs
+	}
+
+	gitPath := fldPath.Child("git")
+
+	if g.URI == "" {
+		return append(errs, field.Required(gitPath.Child("uri"), "required to build from a Git repository"))
+	}
+
+	u, err := url.Parse(g.URI)
+	if err != nil {
+		return append(errs, field.Invalid(gitPath.Child("uri"), g.URI, err.Error()))
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" && !git.IsCommit(g.Ref) {
+		errs = append(
+			errs,
+			field.Invalid(gitPath.Child("ref"), g.Ref, "must be a full commit hash for repositories that are not served over HTTP(S)"),
+		)
+	}
+
+	return errs
+}
+
+// validateRetryPolicy returns the errors of a build or sign retry policy.
+func validateRetryPolicy(policy *kmmv1beta1.RetryPolicy, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if policy == nil {
+		return errs
+	}
+
+	if policy.MaxAttempts < 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("maxAttempts"), policy.MaxAttempts, "must be at least 1"))
+	}
+
+	initial, max := retry.DefaultInitialBackoff, retry.DefaultMaxBackoff
+
+	if b := policy.InitialBackoff; b != nil {
+		initial = b.Duration
+
+		if initial <= 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("initialBackoff"), b.Duration.String(), "must be positive"))
+		}
+	}
+
+	if b := policy.MaxBackoff; b != nil {
+		max = b.Duration
+
+		if max <= 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("maxBackoff"), b.Duration.String(), "must be positive"))
+		}
+	}
+
+	if initial > 0 && max > 0 && max < initial {
+		errs = append(errs, field.Invalid(fldPath.Child("maxBackoff"), max.String(), "must not be lower than initialBackoff"))
+	}
+
+	return errs
+}
+
+func validateBuildArgsTemplates(b *kmmv1beta1.Build, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if b == nil {
+		return errs
+	}
+
+	for i, ba := range b.BuildArgs {
+		errs = append(errs, validateTemplates(fldPath.Child("buildArgs").Index(i).Child("value"), ba.Value)...)
+	}
+
+	return errs
+}
@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+)
+
+// Config is the configuration of the worker.
+// It is mounted into the module loader container.
+type Config struct {
+	// Name is the name of the Module.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the Module.
+	Namespace string `json:"namespace"`
+
+	// Modprobe describes the kernel module to load and unload.
+	Modprobe kmmv1beta1.ModprobeSpec `json:"modprobe"`
+
+	// FirmwareClassPath, if set, is written to the firmware_class.path kernel parameter before loading the kernel
+	// module, so that the kernel also looks for firmware files in that directory.
+	FirmwareClassPath string `json:"firmwareClassPath,omitempty"`
+
+	// KeepLoaded prevents the run command from unloading the kernel module when it is terminated.
+	// It is set when the kernel module is also loaded at boot: it then stays loaded across restarts and upgrades of
+	// the module loader pod, until the node reboots without the boot configuration.
+	KeepLoaded bool `json:"keepLoaded,omitempty"`
+}
+
+func ReadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+
+	cfg := Config{}
+
+	if err = json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("could not decode %s: %v", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// LoadArgs returns the arguments passed to modprobe to load the kernel module described by spec.
+func LoadArgs(spec kmmv1beta1.ModprobeSpec) []string {
+	if rawArgs := spec.RawArgs; rawArgs != nil && len(rawArgs.Load) > 0 {
+		return rawArgs.Load
+	}
+
+	args := make([]string, 0)
+
+	if a := spec.Args; a != nil && len(a.Load) > 0 {
+		args = append(args, a.Load...)
+	} else {
+		args = append(args, "-v")
+	}
+
+	if dirName := spec.DirName; dirName != "" {
+		args = append(args, "-d", dirName)
+	}
+
+	args = append(args, spec.ModuleName)
+
+	return append(args, spec.Parameters...)
+}
+
+// UnloadArgs returns the arguments passed to modprobe to unload the kernel module described by spec.
+func UnloadArgs(spec kmmv1beta1.ModprobeSpec) []string {
+	if rawArgs := spec.RawArgs; rawArgs != nil && len(rawArgs.Unload) > 0 {
+		return rawArgs.Unload
+	}
+
+	args := make([]string, 0)
+
+	if a := spec.Args; a != nil && len(a.Unload) > 0 {
+		args = append(args, a.Unload...)
+	} else {
+		args = append(args, "-rv")
+	}
+
+	if dirName := spec.DirName; dirName != "" {
+		args = append(args, "-d", dirName)
+	}
+
+	return append(args, spec.ModuleName)
+}
+
+// ModprobeConfig returns the modprobe.d configuration used to load and unload the kernel module described by spec, or
+// an empty string if spec does not need one.
+// ModulesLoadingOrder is rendered as soft dependencies, so that modprobe loads the list in reverse order and unloads
+// it in order.
+func ModprobeConfig(spec kmmv1beta1.ModprobeSpec) string {
+	if spec.RawArgs != nil {
+		return ""
+	}
+
+	lines := make([]string, 0)
+
+	for i := 0; i < len(spec.ModulesLoadingOrder)-1; i++ {
+		lines = append(lines, fmt.Sprintf("softdep %s pre: %s", spec.ModulesLoadingOrder[i], spec.ModulesLoadingOrder[i+1]))
+	}
+
+	for _, o := range spec.Options {
+		lines = append(lines, fmt.Sprintf("options %s %s", o.ModuleName, strings.Join(o.Parameters, " ")))
+	}
+
+	for _, b := range spec.Blacklist {
+		lines = append(lines, "blacklist "+b)
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return "# Generated by KMM\n" + strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+)
+
+const (
+	kernelModuleName = "some-kmod"
+	moduleName       = "some-module"
+	namespace        = "some-namespace"
+)
+
+var _ = Describe("ReadConfig", func() {
+	It("should return an error if the file does not exist", func() {
+		_, err := ReadConfig(filepath.Join(GinkgoT().TempDir(), "config.json"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error if the file is not valid JSON", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.json")
+		Expect(os.WriteFile(path, []byte("not json"), 0600)).To(Succeed())
+
+		_, err := ReadConfig(path)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should decode the configuration", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.json")
+		data := `{"name":"some-module","namespace":"some-namespace","modprobe":{"moduleName":"some-kmod","dirName":"/opt"},` +
+			`"firmwareClassPath":"/var/lib/firmware","keepLoaded":true}`
+
+		Expect(os.WriteFile(path, []byte(data), 0600)).To(Succeed())
+
+		cfg, err := ReadConfig(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg).To(Equal(&Config{
+			Name:              moduleName,
+			Namespace:         namespace,
+			Modprobe:          kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName, DirName: "/opt"},
+			FirmwareClassPath: "/var/lib/firmware",
+			KeepLoaded:        true,
+		}))
+	})
+})
+
+var _ = Describe("LoadArgs", func() {
+	It("should only use raw arguments if they are provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName: kernelModuleName,
+			RawArgs: &kmmv1beta1.ModprobeArgs{
+				Load: []string{"load", "arguments"},
+			},
+		}
+
+		Expect(LoadArgs(spec)).To(Equal([]string{"load", "arguments"}))
+	})
+
+	It("should build the arguments from the spec as expected", func() {
+		const (
+			arg1 = "arg1"
+			arg2 = "arg2"
+			dir  = "/some-dir"
+		)
+
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName: kernelModuleName,
+			Parameters: []string{arg1, arg2},
+			DirName:    dir,
+		}
+
+		Expect(LoadArgs(spec)).To(Equal([]string{"-v", "-d", dir, kernelModuleName, arg1, arg2}))
+	})
+
+	It("should use provided arguments if provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Args: &kmmv1beta1.ModprobeArgs{
+				Load: []string{"-z", "-k"},
+			},
+			ModuleName: kernelModuleName,
+		}
+
+		Expect(LoadArgs(spec)).To(Equal([]string{"-z", "-k", kernelModuleName}))
+	})
+})
+
+var _ = Describe("UnloadArgs", func() {
+	It("should only use raw arguments if they are provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName: kernelModuleName,
+			RawArgs: &kmmv1beta1.ModprobeArgs{
+				Unload: []string{"unload", "arguments"},
+			},
+		}
+
+		Expect(UnloadArgs(spec)).To(Equal([]string{"unload", "arguments"}))
+	})
+
+	It("should build the arguments from the spec as expected", func() {
+		const dir = "/some-dir"
+
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName: kernelModuleName,
+			DirName:    dir,
+		}
+
+		Expect(UnloadArgs(spec)).To(Equal([]string{"-rv", "-d", dir, kernelModuleName}))
+	})
+
+	It("should use provided arguments if provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Args: &kmmv1beta1.ModprobeArgs{
+				Unload: []string{"-z", "-k"},
+			},
+			ModuleName: kernelModuleName,
+		}
+
+		Expect(UnloadArgs(spec)).To(Equal([]string{"-z", "-k", kernelModuleName}))
+	})
+})
+
+var _ = Describe("ModprobeConfig", func() {
+	It("should return an empty configuration if there is nothing to configure", func() {
+		Expect(
+			ModprobeConfig(kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName, Parameters: []string{"a=b"}}),
+		).To(
+			BeEmpty(),
+		)
+	})
+
+	It("should return an empty configuration if raw arguments are provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			RawArgs:   &kmmv1beta1.ModprobeArgs{Load: []string{"some-kmod"}},
+			Blacklist: []string{"other-kmod"},
+		}
+
+		Expect(ModprobeConfig(spec)).To(BeEmpty())
+	})
+
+	It("should render the loading order, the options and the blacklist", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName:          kernelModuleName,
+			ModulesLoadingOrder: []string{kernelModuleName, "dep-a", "dep-b"},
+			Options: []kmmv1beta1.ModprobeOptions{
+				{ModuleName: "dep-a", Parameters: []string{"x=1", "y=2"}},
+			},
+			Blacklist: []string{"in-tree-kmod"},
+		}
+
+		Expect(ModprobeConfig(spec)).To(Equal(`# Generated by KMM
+softdep some-kmod pre: dep-a
+softdep dep-a pre: dep-b
+options dep-a x=1 y=2
+blacklist in-tree-kmod
+`))
+	})
+})
@@ -0,0 +1,369 @@
+package worker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+const (
+	// firmwareStoreDir is the directory, relative to the firmware directory of the host, where the firmware files of
+	// each Module are stored in <namespace>/<name>.
+	firmwareStoreDir = "kmm"
+
+	firmwareLockFile = ".lock"
+)
+
+// moduleFirmwareDir returns the directory where the firmware files of the Module described by cfg are stored.
+func (w *worker) moduleFirmwareDir(cfg *Config) string {
+	return filepath.Join(w.firmwareDir, firmwareStoreDir, cfg.Namespace, cfg.Name)
+}
+
+// installFirmware copies the firmware files of the Module into their own directory, so that Modules shipping files
+// with the same name do not overwrite each other.
+// The kernel looks up firmware files by their path relative to the firmware directory: for each file, a symbolic link
+// pointing to the copy is created at that path, unless a file already exists there.
+// If the path is already provided by another Module with the same contents, the existing link is kept; the copies act
+// as reference counts, and removeFirmware only removes the link once no Module provides the file anymore. An error is
+// returned if the other Module provides different contents, since only one of them can be loaded by the kernel.
+func (w *worker) installFirmware(cfg *Config) error {
+	if cfg.Namespace == "" || cfg.Name == "" {
+		return errors.New("the namespace and the name of the Module are required to install firmware files")
+	}
+
+	unlock, err := w.lockFirmware()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	src := cfg.Modprobe.FirmwarePath
+	dst := w.moduleFirmwareDir(cfg)
+
+	w.logger.Info("Copying firmware files", "source", src, "destination", dst)
+
+	// the files of a previous version of the Module that are not shipped anymore must not stay linked
+	previousFiles, err := regularFiles(dst)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not list the files in %s: %v", dst, err)
+	}
+
+	if err = os.RemoveAll(dst); err != nil {
+		return fmt.Errorf("could not clean %s: %v", dst, err)
+	}
+
+	if err = copyDir(src, dst); err != nil {
+		return fmt.Errorf("could not copy %s to %s: %v", src, dst, err)
+	}
+
+	files, err := regularFiles(dst)
+	if err != nil {
+		return fmt.Errorf("could not list the files in %s: %v", dst, err)
+	}
+
+	shipped := make(map[string]bool, len(files))
+
+	for _, rel := range files {
+		shipped[rel] = true
+	}
+
+	for _, rel := range previousFiles {
+		if !shipped[rel] {
+			if err = w.unlinkFirmwareFile(dst, rel); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, rel := range files {
+		link := filepath.Join(w.firmwareDir, rel)
+
+		if _, err = os.Lstat(link); err == nil {
+			if !w.isStoreLink(link) {
+				w.logger.Info("Firmware file already present on the host and not managed by KMM; leaving it", "path", rel)
+				continue
+			}
+
+			target, _ := os.Readlink(link)
+
+			same, err := sameContents(target, filepath.Join(dst, rel))
+
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				// the link was left dangling by a Module whose files were removed
+				if err = os.Remove(link); err != nil {
+					return fmt.Errorf("could not remove the dangling link %s: %v", link, err)
+				}
+			case err != nil:
+				return fmt.Errorf("could not compare %s to the existing firmware file: %v", rel, err)
+			case same:
+				w.logger.V(1).Info("Firmware file already provided by another Module", "path", rel, "provider", target)
+				continue
+			default:
+				return fmt.Errorf("firmware file %s is already provided with different contents by %s", rel, target)
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("could not check %s: %v", link, err)
+		}
+
+		if err = os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			return fmt.Errorf("could not create the parent directory of %s: %v", link, err)
+		}
+
+		if err = os.Symlink(filepath.Join(dst, rel), link); err != nil {
+			return fmt.Errorf("could not link %s: %v", link, err)
+		}
+	}
+
+	if p := cfg.FirmwareClassPath; p != "" {
+		if err = w.setFirmwareClassPath(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeFirmware removes the firmware files of the Module.
+// Links to this Module's copies are pointed to the copy of another Module providing the same file, if any; otherwise
+// they are removed along with the directories left empty.
+func (w *worker) removeFirmware(cfg *Config) error {
+	unlock, err := w.lockFirmware()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dir := w.moduleFirmwareDir(cfg)
+
+	files, err := regularFiles(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("could not list the files in %s: %v", dir, err)
+	}
+
+	w.logger.Info("Removing firmware files", "directory", dir)
+
+	if err = os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("could not remove %s: %v", dir, err)
+	}
+
+	for _, rel := range files {
+		if err = w.unlinkFirmwareFile(dir, rel); err != nil {
+			return err
+		}
+	}
+
+	removeEmptyParents(filepath.Dir(dir), filepath.Join(w.firmwareDir, firmwareStoreDir))
+
+	return nil
+}
+
+// unlinkFirmwareFile releases the link to the file rel of the Module whose copies are in dir, once that copy has
+// been removed.
+// If the link points to that copy, it is pointed to the copy of another Module still providing the file, if any;
+// otherwise it is removed along with the directories left empty.
+func (w *worker) unlinkFirmwareFile(dir, rel string) error {
+	link := filepath.Join(w.firmwareDir, rel)
+
+	target, err := os.Readlink(link)
+	if err != nil || target != filepath.Join(dir, rel) {
+		// not ours
+		return nil
+	}
+
+	if err = os.Remove(link); err != nil {
+		return fmt.Errorf("could not remove %s: %v", link, err)
+	}
+
+	providers, err := filepath.Glob(filepath.Join(w.firmwareDir, firmwareStoreDir, "*", "*", rel))
+	if err != nil {
+		return fmt.Errorf("could not look for other providers of %s: %v", rel, err)
+	}
+
+	if len(providers) > 0 {
+		sort.Strings(providers)
+
+		w.logger.V(1).Info("Firmware file still provided by another Module", "path", rel, "provider", providers[0])
+
+		if err = os.Symlink(providers[0], link); err != nil {
+			return fmt.Errorf("could not link %s: %v", link, err)
+		}
+
+		return nil
+	}
+
+	removeEmptyParents(filepath.Dir(link), w.firmwareDir)
+
+	return nil
+}
+
+func (w *worker) isStoreLink(path string) bool {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(target, filepath.Join(w.firmwareDir, firmwareStoreDir)+string(filepath.Separator))
+}
+
+// lockFirmware serializes the changes made to the firmware directory by the workers running on the same node.
+func (w *worker) lockFirmware() (func(), error) {
+	storeDir := filepath.Join(w.firmwareDir, firmwareStoreDir)
+
+	if err := os.MkdirAll(storeDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create %s: %v", storeDir, err)
+	}
+
+	lockPath := filepath.Join(storeDir, firmwareLockFile)
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %v", lockPath, err)
+	}
+
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not lock %s: %v", lockPath, err)
+	}
+
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// setFirmwareClassPath sets the firmware_class.path kernel parameter, which adds a directory to the firmware search
+// path of the kernel.
+// The parameter only holds one directory: an error is returned if it is already set to another one, which was not
+// configured by KMM.
+func (w *worker) setFirmwareClassPath(value string) error {
+	paramPath := filepath.Join(w.sysModuleDir, "firmware_class", "parameters", "path")
+
+	current, err := os.ReadFile(paramPath)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %v", paramPath, err)
+	}
+
+	if c := strings.TrimSpace(string(current)); c == value {
+		return nil
+	} else if c != "" {
+		return fmt.Errorf("the firmware search path is already set to %s; refusing to override it with %s", c, value)
+	}
+
+	if err = os.WriteFile(paramPath, []byte(value), 0644); err != nil {
+		return fmt.Errorf("could not write %s: %v", paramPath, err)
+	}
+
+	return nil
+}
+
+// sameContents returns true if the files at a and b have the same contents.
+func sameContents(a, b string) (bool, error) {
+	ca, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+
+	cb, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(ca, cb), nil
+}
+
+// regularFiles returns the paths, relative to dir, of all regular files under dir.
+func regularFiles(dir string) ([]string, error) {
+	files := make([]string, 0)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, rel)
+
+		return nil
+	})
+
+	return files, err
+}
+
+// removeEmptyParents removes dir and its parents, up to but excluding root, as long as they are empty.
+func removeEmptyParents(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if err := os.Remove(dir); err != nil {
+			// most likely not empty
+			return
+		}
+
+		dir = filepath.Dir(dir)
+	}
+}
+
+// copyDir recursively copies the contents of src into dst.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
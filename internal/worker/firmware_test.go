@@ -0,0 +1,190 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+)
+
+var _ = Describe("firmware", func() {
+	var (
+		firmwareDir  string
+		sysModuleDir string
+		w            *worker
+	)
+
+	BeforeEach(func() {
+		firmwareDir = GinkgoT().TempDir()
+		sysModuleDir = GinkgoT().TempDir()
+		w = NewWorker(nil, firmwareDir, sysModuleDir, "", logr.Discard()).(*worker)
+	})
+
+	// makeConfig returns the Config of a Module named name that ships one firmware file at rel.
+	makeConfig := func(name, rel, contents string) *Config {
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, rel)
+
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+
+		return &Config{
+			Name:      name,
+			Namespace: namespace,
+			Modprobe:  kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName, FirmwarePath: dir},
+		}
+	}
+
+	Describe("installFirmware", func() {
+		It("should return an error if the Module name is missing", func() {
+			cfg := makeConfig("", "fw.bin", "fw")
+
+			Expect(w.installFirmware(cfg)).NotTo(Succeed())
+		})
+
+		It("should copy the files into the Module directory and link them", func() {
+			cfg := makeConfig(moduleName, "vendor/fw.bin", "fw")
+
+			Expect(w.installFirmware(cfg)).To(Succeed())
+
+			stored := filepath.Join(firmwareDir, "kmm", namespace, moduleName, "vendor", "fw.bin")
+			Expect(stored).To(BeARegularFile())
+			Expect(os.Readlink(filepath.Join(firmwareDir, "vendor", "fw.bin"))).To(Equal(stored))
+		})
+
+		It("should not replace a file that is not managed by KMM", func() {
+			cfg := makeConfig(moduleName, "fw.bin", "new")
+			Expect(os.WriteFile(filepath.Join(firmwareDir, "fw.bin"), []byte("host"), 0644)).To(Succeed())
+
+			Expect(w.installFirmware(cfg)).To(Succeed())
+			Expect(os.ReadFile(filepath.Join(firmwareDir, "fw.bin"))).To(Equal([]byte("host")))
+		})
+
+		It("should keep the link of the first Module providing a file", func() {
+			cfg1 := makeConfig("module-1", "fw.bin", "fw")
+			cfg2 := makeConfig("module-2", "fw.bin", "fw")
+
+			Expect(w.installFirmware(cfg1)).To(Succeed())
+			Expect(w.installFirmware(cfg2)).To(Succeed())
+
+			Expect(os.Readlink(filepath.Join(firmwareDir, "fw.bin"))).To(Equal(filepath.Join(firmwareDir, "kmm", namespace, "module-1", "fw.bin")))
+			Expect(filepath.Join(firmwareDir, "kmm", namespace, "module-2", "fw.bin")).To(BeARegularFile())
+		})
+
+		It("should return an error if another Module provides a file with different contents", func() {
+			cfg1 := makeConfig("module-1", "fw.bin", "fw1")
+			cfg2 := makeConfig("module-2", "fw.bin", "fw2")
+
+			Expect(w.installFirmware(cfg1)).To(Succeed())
+			Expect(w.installFirmware(cfg2)).To(MatchError(ContainSubstring("different contents")))
+
+			Expect(os.ReadFile(filepath.Join(firmwareDir, "fw.bin"))).To(Equal([]byte("fw1")))
+		})
+
+		It("should replace a dangling link", func() {
+			link := filepath.Join(firmwareDir, "fw.bin")
+			Expect(os.Symlink(filepath.Join(firmwareDir, "kmm", namespace, "removed", "fw.bin"), link)).To(Succeed())
+
+			Expect(w.installFirmware(makeConfig(moduleName, "fw.bin", "fw"))).To(Succeed())
+			Expect(os.ReadFile(link)).To(Equal([]byte("fw")))
+		})
+
+		It("should unlink the files that a new version of the Module does not ship anymore", func() {
+			Expect(w.installFirmware(makeConfig(moduleName, "old.bin", "old"))).To(Succeed())
+			Expect(w.installFirmware(makeConfig(moduleName, "new.bin", "new"))).To(Succeed())
+
+			Expect(filepath.Join(firmwareDir, "old.bin")).NotTo(BeAnExistingFile())
+			Expect(os.ReadFile(filepath.Join(firmwareDir, "new.bin"))).To(Equal([]byte("new")))
+		})
+
+		It("should set the firmware class path", func() {
+			paramDir := filepath.Join(sysModuleDir, "firmware_class", "parameters")
+			Expect(os.MkdirAll(paramDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(paramDir, "path"), []byte("\n"), 0644)).To(Succeed())
+
+			cfg := makeConfig(moduleName, "fw.bin", "fw")
+			cfg.FirmwareClassPath = "/var/lib/firmware"
+
+			Expect(w.installFirmware(cfg)).To(Succeed())
+			Expect(os.ReadFile(filepath.Join(paramDir, "path"))).To(Equal([]byte("/var/lib/firmware")))
+		})
+
+		It("should not override a firmware class path set to another directory", func() {
+			paramDir := filepath.Join(sysModuleDir, "firmware_class", "parameters")
+			Expect(os.MkdirAll(paramDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(paramDir, "path"), []byte("/opt/firmware\n"), 0644)).To(Succeed())
+
+			cfg := makeConfig(moduleName, "fw.bin", "fw")
+			cfg.FirmwareClassPath = "/var/lib/firmware"
+
+			Expect(w.installFirmware(cfg)).NotTo(Succeed())
+			Expect(os.ReadFile(filepath.Join(paramDir, "path"))).To(Equal([]byte("/opt/firmware\n")))
+		})
+
+		It("should return an error if the firmware class path cannot be read", func() {
+			cfg := makeConfig(moduleName, "fw.bin", "fw")
+			cfg.FirmwareClassPath = "/var/lib/firmware"
+
+			Expect(w.installFirmware(cfg)).NotTo(Succeed())
+		})
+	})
+
+	Describe("removeFirmware", func() {
+		It("should do nothing if the Module has no firmware files", func() {
+			Expect(w.removeFirmware(makeConfig(moduleName, "fw.bin", "fw"))).To(Succeed())
+		})
+
+		It("should remove the files, links and empty directories", func() {
+			cfg := makeConfig(moduleName, "vendor/fw.bin", "fw")
+
+			Expect(w.installFirmware(cfg)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(firmwareDir, "unrelated.bin"), nil, 0644)).To(Succeed())
+
+			Expect(w.removeFirmware(cfg)).To(Succeed())
+
+			Expect(filepath.Join(firmwareDir, "vendor")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(firmwareDir, "kmm", namespace)).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(firmwareDir, "unrelated.bin")).To(BeARegularFile())
+		})
+
+		It("should not remove directories that are not empty", func() {
+			cfg := makeConfig(moduleName, "vendor/fw.bin", "fw")
+
+			Expect(w.installFirmware(cfg)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(firmwareDir, "vendor", "unrelated.bin"), nil, 0644)).To(Succeed())
+
+			Expect(w.removeFirmware(cfg)).To(Succeed())
+
+			Expect(filepath.Join(firmwareDir, "vendor", "fw.bin")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(firmwareDir, "vendor", "unrelated.bin")).To(BeARegularFile())
+		})
+
+		It("should link the file to another Module still providing it", func() {
+			cfg1 := makeConfig("module-1", "fw.bin", "fw")
+			cfg2 := makeConfig("module-2", "fw.bin", "fw")
+
+			Expect(w.installFirmware(cfg1)).To(Succeed())
+			Expect(w.installFirmware(cfg2)).To(Succeed())
+
+			Expect(w.removeFirmware(cfg1)).To(Succeed())
+			Expect(os.Readlink(filepath.Join(firmwareDir, "fw.bin"))).To(Equal(filepath.Join(firmwareDir, "kmm", namespace, "module-2", "fw.bin")))
+
+			Expect(w.removeFirmware(cfg2)).To(Succeed())
+			Expect(filepath.Join(firmwareDir, "fw.bin")).NotTo(BeAnExistingFile())
+		})
+
+		It("should keep the link of another Module", func() {
+			cfg1 := makeConfig("module-1", "fw.bin", "fw")
+			cfg2 := makeConfig("module-2", "fw.bin", "fw")
+
+			Expect(w.installFirmware(cfg1)).To(Succeed())
+			Expect(w.installFirmware(cfg2)).To(Succeed())
+
+			Expect(w.removeFirmware(cfg2)).To(Succeed())
+			Expect(os.Readlink(filepath.Join(firmwareDir, "fw.bin"))).To(Equal(filepath.Join(firmwareDir, "kmm", namespace, "module-1", "fw.bin")))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: worker.go
+
+// Package worker is a generated GoMock package.
+package worker
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockWorker is a mock of Worker interface.
+type MockWorker struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkerMockRecorder
+}
+
+// MockWorkerMockRecorder is the mock recorder for MockWorker.
+type MockWorkerMockRecorder struct {
+	mock *MockWorker
+}
+
+// NewMockWorker creates a new mock instance.
+func NewMockWorker(ctrl *gomock.Controller) *MockWorker {
+	mock := &MockWorker{ctrl: ctrl}
+	mock.recorder = &MockWorkerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWorker) EXPECT() *MockWorkerMockRecorder {
+	return m.recorder
+}
+
+// IsLoaded mocks base method.
+func (m *MockWorker) IsLoaded(cfg *Config) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsLoaded", cfg)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsLoaded indicates an expected call of IsLoaded.
+func (mr *MockWorkerMockRecorder) IsLoaded(cfg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLoaded", reflect.TypeOf((*MockWorker)(nil).IsLoaded), cfg)
+}
+
+// LoadKmod mocks base method.
+func (m *MockWorker) LoadKmod(ctx context.Context, cfg *Config) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadKmod", ctx, cfg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LoadKmod indicates an expected call of LoadKmod.
+func (mr *MockWorkerMockRecorder) LoadKmod(ctx, cfg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadKmod", reflect.TypeOf((*MockWorker)(nil).LoadKmod), ctx, cfg)
+}
+
+// UnloadKmod mocks base method.
+func (m *MockWorker) UnloadKmod(ctx context.Context, cfg *Config) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnloadKmod", ctx, cfg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnloadKmod indicates an expected call of UnloadKmod.
+func (mr *MockWorkerMockRecorder) UnloadKmod(ctx, cfg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnloadKmod", reflect.TypeOf((*MockWorker)(nil).UnloadKmod), ctx, cfg)
+}
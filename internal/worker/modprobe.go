@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"github.com/go-logr/logr"
+)
+
+//go:generate mockgen -source=modprobe.go -package=worker -destination=mock_modprobe.go
+
+type ModprobeRunner interface {
+	Run(ctx context.Context, args ...string) error
+}
+
+type modprobeRunner struct {
+	logger logr.Logger
+}
+
+func NewModprobeRunner(logger logr.Logger) ModprobeRunner {
+	return &modprobeRunner{logger: logger}
+}
+
+// Run executes modprobe with args.
+// The output of modprobe is logged, and included in the returned error if modprobe fails.
+func (mr *modprobeRunner) Run(ctx context.Context, args ...string) error {
+	mr.logger.Info("Running modprobe", "args", args)
+
+	out, err := exec.CommandContext(ctx, "modprobe", args...).CombinedOutput()
+
+	mr.logger.Info("modprobe output", "output", string(out))
+
+	if err != nil {
+		return fmt.Errorf("modprobe %v failed: %v: %s", args, err, out)
+	}
+
+	return nil
+}
@@ -0,0 +1,337 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+)
+
+const (
+	FirmwareDir  = "/var/lib/firmware"
+	SysModuleDir = "/sys/module"
+
+	// restoreTimeout bounds how long LoadKmod tries to reload the in-tree modules after failing to load the kernel
+	// module.
+	restoreTimeout = 2 * time.Minute
+)
+
+//go:generate mockgen -source=worker.go -package=worker -destination=mock_worker.go
+
+type Worker interface {
+	LoadKmod(ctx context.Context, cfg *Config) error
+	UnloadKmod(ctx context.Context, cfg *Config) error
+	IsLoaded(cfg *Config) (bool, error)
+}
+
+// state is what the worker needs to remember between loading and unloading the kernel module.
+// It is persisted so that it survives restarts of the container.
+type state struct {
+	// Skipped is true if the kernel module was not loaded because of a conflicting in-tree module.
+	Skipped bool `json:"skipped,omitempty"`
+
+	// RemovedInTreeModules are the in-tree modules that were unloaded before loading the kernel module, in order.
+	RemovedInTreeModules []string `json:"removedInTreeModules,omitempty"`
+}
+
+type worker struct {
+	mr           ModprobeRunner
+	firmwareDir  string
+	sysModuleDir string
+	stateFile    string
+	logger       logr.Logger
+}
+
+// NewWorker returns a Worker that persists its state in stateFile.
+func NewWorker(mr ModprobeRunner, firmwareDir, sysModuleDir, stateFile string, logger logr.Logger) Worker {
+	return &worker{
+		mr:           mr,
+		firmwareDir:  firmwareDir,
+		sysModuleDir: sysModuleDir,
+		stateFile:    stateFile,
+		logger:       logger,
+	}
+}
+
+// LoadKmod handles the in-tree modules conflicting with the kernel module according to the conflict policy, copies the
+// firmware files, if any, to the firmware directory of the host and loads the kernel module.
+func (w *worker) LoadKmod(ctx context.Context, cfg *Config) error {
+	st, err := w.readState()
+	if err != nil {
+		return err
+	}
+
+	// A previous container of the same pod may already have handled the conflicts.
+	if !st.Skipped && len(st.RemovedInTreeModules) == 0 {
+		conflicts, err := w.loadedInTreeModules(cfg.Modprobe)
+		if err != nil {
+			return err
+		}
+
+		if len(conflicts) > 0 {
+			policy := cfg.Modprobe.InTreeModuleConflictPolicy
+
+			w.logger.Info("Found conflicting in-tree modules", "modules", conflicts, "policy", policy)
+
+			switch policy {
+			case kmmv1beta1.InTreeModuleConflictPolicyReplace:
+				for _, m := range conflicts {
+					if err = w.mr.Run(ctx, "-rv", m); err != nil {
+						return fmt.Errorf("could not unload in-tree module %s: %v", m, err)
+					}
+
+					st.RemovedInTreeModules = append(st.RemovedInTreeModules, m)
+
+					if err = w.writeState(st); err != nil {
+						return err
+					}
+				}
+			case kmmv1beta1.InTreeModuleConflictPolicySkip:
+				w.logger.Info("Not loading the kernel module", "name", cfg.Modprobe.ModuleName)
+
+				st.Skipped = true
+
+				return w.writeState(st)
+			default:
+				return fmt.Errorf(
+					"in-tree modules %v conflict with %s; set the conflict policy to Replace to unload them",
+					conflicts,
+					cfg.Modprobe.ModuleName,
+				)
+			}
+		}
+	}
+
+	if st.Skipped {
+		return nil
+	}
+
+	if err = w.copyFirmwareAndLoad(ctx, cfg); err != nil {
+		// Leave the node the way we found it, even if ctx was cancelled while loading the kernel module.
+		restoreCtx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+		defer cancel()
+
+		if restoreErr := w.restoreInTreeModules(restoreCtx, st); restoreErr != nil {
+			w.logger.Info("Could not restore the in-tree modules", "error", restoreErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (w *worker) copyFirmwareAndLoad(ctx context.Context, cfg *Config) error {
+	if cfg.Modprobe.FirmwarePath != "" {
+		if err := w.installFirmware(cfg); err != nil {
+			return fmt.Errorf("could not install the firmware files: %v", err)
+		}
+	}
+
+	if err := w.mr.Run(ctx, LoadArgs(cfg.Modprobe)...); err != nil {
+		return fmt.Errorf("could not load kernel module %s: %v", cfg.Modprobe.ModuleName, err)
+	}
+
+	return nil
+}
+
+// UnloadKmod unloads the kernel module if it is loaded, removes the firmware files it copied, if any, from the firmware directory
+// of the host, and loads the in-tree modules that LoadKmod unloaded.
+func (w *worker) UnloadKmod(ctx context.Context, cfg *Config) error {
+	st, err := w.readState()
+	if err != nil {
+		return err
+	}
+
+	if st.Skipped {
+		w.logger.Info("The kernel module was not loaded; leaving the in-tree modules in place")
+		return w.removeState()
+	}
+
+	// The kernel module may already have been unloaded, e.g. by a previous container; an in-tree module with the
+	// same name is not ours to unload.
+	_, outOfTree, err := w.moduleState(cfg.Modprobe.ModuleName)
+	if err != nil {
+		return err
+	}
+
+	if outOfTree {
+		if err = w.mr.Run(ctx, UnloadArgs(cfg.Modprobe)...); err != nil {
+			return fmt.Errorf("could not unload kernel module %s: %v", cfg.Modprobe.ModuleName, err)
+		}
+	} else {
+		w.logger.Info("The kernel module is not loaded; nothing to unload", "name", cfg.Modprobe.ModuleName)
+	}
+
+	if cfg.Modprobe.FirmwarePath != "" {
+		if err = w.removeFirmware(cfg); err != nil {
+			return fmt.Errorf("could not remove the firmware files: %v", err)
+		}
+	}
+
+	return w.restoreInTreeModules(ctx, st)
+}
+
+// IsLoaded returns true if the out-of-tree kernel module is loaded.
+// An in-tree module with the same name, such as one left in place by the Skip conflict policy or restored after a
+// failed load, does not count.
+// The name of the kernel module is required, even if it is loaded with raw modprobe arguments.
+func (w *worker) IsLoaded(cfg *Config) (bool, error) {
+	if cfg.Modprobe.ModuleName == "" {
+		return false, errors.New("the name of the kernel module is required to check if it is loaded")
+	}
+
+	st, err := w.readState()
+	if err != nil {
+		return false, err
+	}
+
+	if st.Skipped {
+		return false, nil
+	}
+
+	loaded, outOfTree, err := w.moduleState(cfg.Modprobe.ModuleName)
+	if err != nil {
+		return false, err
+	}
+
+	return loaded && outOfTree, nil
+}
+
+// restoreInTreeModules loads the in-tree modules in st, in the reverse order in which they were unloaded, and removes
+// the state file.
+func (w *worker) restoreInTreeModules(ctx context.Context, st *state) error {
+	for i := len(st.RemovedInTreeModules) - 1; i >= 0; i-- {
+		m := st.RemovedInTreeModules[i]
+
+		w.logger.Info("Loading in-tree module", "name", m)
+
+		if err := w.mr.Run(ctx, "-v", m); err != nil {
+			return fmt.Errorf("could not load in-tree module %s: %v", m, err)
+		}
+
+		st.RemovedInTreeModules = st.RemovedInTreeModules[:i]
+
+		if err := w.writeState(st); err != nil {
+			return err
+		}
+	}
+
+	return w.removeState()
+}
+
+// loadedInTreeModules returns the in-tree modules conflicting with spec.ModuleName that are currently loaded.
+// Out-of-tree modules, such as the ones loaded by a previous container of the same pod, are tainted with O.
+func (w *worker) loadedInTreeModules(spec kmmv1beta1.ModprobeSpec) ([]string, error) {
+	candidates := append([]string{spec.ModuleName}, spec.InTreeModulesToRemove...)
+	seen := make(map[string]bool, len(candidates))
+	loaded := make([]string, 0)
+
+	for _, c := range candidates {
+		// the kernel always uses underscores in module names
+		name := strings.ReplaceAll(c, "-", "_")
+
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+
+		isLoaded, outOfTree, err := w.moduleState(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !isLoaded || outOfTree {
+			continue
+		}
+
+		loaded = append(loaded, name)
+	}
+
+	return loaded, nil
+}
+
+// moduleState returns whether the module called name is loaded, and if so whether it is an out-of-tree module.
+func (w *worker) moduleState(name string) (bool, bool, error) {
+	// the kernel always uses underscores in module names
+	name = strings.ReplaceAll(name, "-", "_")
+
+	dir := filepath.Join(w.sysModuleDir, name)
+
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, false, nil
+		}
+
+		return false, false, fmt.Errorf("could not check if %s is loaded: %v", name, err)
+	}
+
+	taint, err := os.ReadFile(filepath.Join(dir, "taint"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, false, fmt.Errorf("could not read the taint of %s: %v", name, err)
+	}
+
+	return true, bytes.ContainsRune(taint, 'O'), nil
+}
+
+func (w *worker) readState() (*state, error) {
+	st := state{}
+
+	if w.stateFile == "" {
+		return &st, nil
+	}
+
+	b, err := os.ReadFile(w.stateFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &st, nil
+		}
+
+		return nil, fmt.Errorf("could not read the state from %s: %v", w.stateFile, err)
+	}
+
+	if err = json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("could not decode the state from %s: %v", w.stateFile, err)
+	}
+
+	return &st, nil
+}
+
+func (w *worker) writeState(st *state) error {
+	if w.stateFile == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("could not encode the state: %v", err)
+	}
+
+	if err = os.WriteFile(w.stateFile, b, 0600); err != nil {
+		return fmt.Errorf("could not write the state to %s: %v", w.stateFile, err)
+	}
+
+	return nil
+}
+
+func (w *worker) removeState() error {
+	if w.stateFile == "" {
+		return nil
+	}
+
+	if err := os.Remove(w.stateFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not remove %s: %v", w.stateFile, err)
+	}
+
+	return nil
+}
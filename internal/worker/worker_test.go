@@ -0,0 +1,340 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kmmv1beta1 "github.com/rh-ecosystem-edge/kernel-module-management/api/v1beta1"
+)
+
+var _ = Describe("worker", func() {
+	var (
+		ctx          context.Context
+		mr           *MockModprobeRunner
+		firmwareDir  string
+		sysModuleDir string
+		stateFile    string
+		w            Worker
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		ctx = context.Background()
+		mr = NewMockModprobeRunner(ctrl)
+		firmwareDir = GinkgoT().TempDir()
+		sysModuleDir = GinkgoT().TempDir()
+		stateFile = filepath.Join(GinkgoT().TempDir(), "state.json")
+		w = NewWorker(mr, firmwareDir, sysModuleDir, stateFile, logr.Discard())
+	})
+
+	// makeFirmware creates a firmware directory with a file at its root and one in a subdirectory.
+	makeFirmware := func() string {
+		dir := GinkgoT().TempDir()
+
+		Expect(os.MkdirAll(filepath.Join(dir, "subdir"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "fw.bin"), []byte("fw"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "subdir", "other-fw.bin"), []byte("other-fw"), 0644)).To(Succeed())
+
+		return dir
+	}
+
+	Describe("LoadKmod", func() {
+		It("should run modprobe", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			mr.EXPECT().Run(ctx, "-v", kernelModuleName)
+
+			Expect(w.LoadKmod(ctx, cfg)).To(Succeed())
+		})
+
+		It("should return an error if modprobe fails", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			mr.EXPECT().Run(ctx, "-v", kernelModuleName).Return(errors.New("some error"))
+
+			Expect(w.LoadKmod(ctx, cfg)).NotTo(Succeed())
+		})
+
+		It("should install the firmware files before running modprobe", func() {
+			cfg := &Config{
+				Name:      moduleName,
+				Namespace: namespace,
+				Modprobe:  kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName, FirmwarePath: makeFirmware()},
+			}
+
+			mr.EXPECT().Run(ctx, "-v", kernelModuleName).Do(func(_ context.Context, _ ...string) {
+				Expect(os.ReadFile(filepath.Join(firmwareDir, "fw.bin"))).To(Equal([]byte("fw")))
+				Expect(os.ReadFile(filepath.Join(firmwareDir, "subdir", "other-fw.bin"))).To(Equal([]byte("other-fw")))
+			})
+
+			Expect(w.LoadKmod(ctx, cfg)).To(Succeed())
+		})
+
+		It("should return an error if the firmware files cannot be copied", func() {
+			cfg := &Config{
+				Name:      moduleName,
+				Namespace: namespace,
+				Modprobe:  kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName, FirmwarePath: "/non/existent/path"},
+			}
+
+			Expect(w.LoadKmod(ctx, cfg)).NotTo(Succeed())
+		})
+	})
+
+	// loadModule simulates a loaded module in sysfs.
+	loadModule := func(name, taint string) {
+		dir := filepath.Join(sysModuleDir, name)
+
+		Expect(os.Mkdir(dir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "taint"), []byte(taint+"\n"), 0644)).To(Succeed())
+	}
+
+	Describe("in-tree module conflicts", func() {
+		It("should fail by default if an in-tree module with the same name is loaded", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			loadModule("some_kmod", "")
+
+			Expect(w.LoadKmod(ctx, cfg)).NotTo(Succeed())
+		})
+
+		It("should not consider out-of-tree modules as conflicts", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			loadModule("some_kmod", "O")
+
+			mr.EXPECT().Run(ctx, "-v", kernelModuleName)
+
+			Expect(w.LoadKmod(ctx, cfg)).To(Succeed())
+		})
+
+		It("should replace the in-tree modules and restore them after unloading", func() {
+			cfg := &Config{
+				Modprobe: kmmv1beta1.ModprobeSpec{
+					ModuleName:                 kernelModuleName,
+					InTreeModulesToRemove:      []string{"ice", "not-loaded", "other"},
+					InTreeModuleConflictPolicy: kmmv1beta1.InTreeModuleConflictPolicyReplace,
+				},
+			}
+
+			loadModule("ice", "")
+			loadModule("other", "")
+
+			gomock.InOrder(
+				mr.EXPECT().Run(ctx, "-rv", "ice"),
+				mr.EXPECT().Run(ctx, "-rv", "other"),
+				mr.EXPECT().Run(ctx, "-v", kernelModuleName),
+			)
+
+			Expect(w.LoadKmod(ctx, cfg)).To(Succeed())
+			Expect(os.ReadFile(stateFile)).To(MatchJSON(`{"removedInTreeModules":["ice","other"]}`))
+
+			loadModule("some_kmod", "O")
+
+			gomock.InOrder(
+				mr.EXPECT().Run(ctx, "-rv", kernelModuleName),
+				mr.EXPECT().Run(ctx, "-v", "other"),
+				mr.EXPECT().Run(ctx, "-v", "ice"),
+			)
+
+			Expect(w.UnloadKmod(ctx, cfg)).To(Succeed())
+			Expect(stateFile).NotTo(BeAnExistingFile())
+		})
+
+		It("should restore the in-tree modules if the kernel module cannot be loaded", func() {
+			cfg := &Config{
+				Modprobe: kmmv1beta1.ModprobeSpec{
+					ModuleName:                 kernelModuleName,
+					InTreeModulesToRemove:      []string{"ice"},
+					InTreeModuleConflictPolicy: kmmv1beta1.InTreeModuleConflictPolicyReplace,
+				},
+			}
+
+			loadModule("ice", "")
+
+			gomock.InOrder(
+				mr.EXPECT().Run(ctx, "-rv", "ice"),
+				mr.EXPECT().Run(ctx, "-v", kernelModuleName).Return(errors.New("some error")),
+				mr.EXPECT().Run(gomock.Any(), "-v", "ice"),
+			)
+
+			Expect(w.LoadKmod(ctx, cfg)).NotTo(Succeed())
+			Expect(stateFile).NotTo(BeAnExistingFile())
+		})
+
+		It("should restore the in-tree modules if the context is cancelled while loading the kernel module", func() {
+			cfg := &Config{
+				Modprobe: kmmv1beta1.ModprobeSpec{
+					ModuleName:                 kernelModuleName,
+					InTreeModulesToRemove:      []string{"ice"},
+					InTreeModuleConflictPolicy: kmmv1beta1.InTreeModuleConflictPolicyReplace,
+				},
+			}
+
+			loadModule("ice", "")
+
+			loadCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			gomock.InOrder(
+				mr.EXPECT().Run(loadCtx, "-rv", "ice"),
+				mr.EXPECT().Run(loadCtx, "-v", kernelModuleName).DoAndReturn(func(ctx context.Context, _ ...string) error {
+					cancel()
+					return ctx.Err()
+				}),
+				mr.EXPECT().Run(gomock.Any(), "-v", "ice").DoAndReturn(func(ctx context.Context, _ ...string) error {
+					return ctx.Err()
+				}),
+			)
+
+			Expect(w.LoadKmod(loadCtx, cfg)).NotTo(Succeed())
+			Expect(stateFile).NotTo(BeAnExistingFile())
+		})
+
+		It("should not check conflicts again if the in-tree modules were already replaced", func() {
+			cfg := &Config{
+				Modprobe: kmmv1beta1.ModprobeSpec{
+					ModuleName:            kernelModuleName,
+					InTreeModulesToRemove: []string{"ice"},
+				},
+			}
+
+			loadModule("ice", "")
+			Expect(os.WriteFile(stateFile, []byte(`{"removedInTreeModules":["ice"]}`), 0600)).To(Succeed())
+
+			mr.EXPECT().Run(ctx, "-v", kernelModuleName)
+
+			Expect(w.LoadKmod(ctx, cfg)).To(Succeed())
+		})
+
+		It("should neither load nor unload anything if the policy is Skip", func() {
+			cfg := &Config{
+				Modprobe: kmmv1beta1.ModprobeSpec{
+					ModuleName:                 kernelModuleName,
+					InTreeModulesToRemove:      []string{"ice"},
+					InTreeModuleConflictPolicy: kmmv1beta1.InTreeModuleConflictPolicySkip,
+				},
+			}
+
+			loadModule("ice", "")
+
+			Expect(w.LoadKmod(ctx, cfg)).To(Succeed())
+			Expect(w.UnloadKmod(ctx, cfg)).To(Succeed())
+			Expect(stateFile).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("UnloadKmod", func() {
+		It("should run modprobe", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			loadModule("some_kmod", "O")
+
+			mr.EXPECT().Run(ctx, "-rv", kernelModuleName)
+
+			Expect(w.UnloadKmod(ctx, cfg)).To(Succeed())
+		})
+
+		It("should not run modprobe if the kernel module is not loaded", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			Expect(w.UnloadKmod(ctx, cfg)).To(Succeed())
+		})
+
+		It("should not unload an in-tree module with the same name", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			loadModule("some_kmod", "")
+
+			Expect(w.UnloadKmod(ctx, cfg)).To(Succeed())
+		})
+
+		It("should not remove firmware files if modprobe fails", func() {
+			cfg := &Config{
+				Name:      moduleName,
+				Namespace: namespace,
+				Modprobe:  kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName, FirmwarePath: makeFirmware()},
+			}
+
+			gomock.InOrder(
+				mr.EXPECT().Run(ctx, "-v", kernelModuleName),
+				mr.EXPECT().Run(ctx, "-rv", kernelModuleName).Return(errors.New("some error")),
+			)
+
+			Expect(w.LoadKmod(ctx, cfg)).To(Succeed())
+			loadModule("some_kmod", "O")
+			Expect(w.UnloadKmod(ctx, cfg)).NotTo(Succeed())
+			Expect(filepath.Join(firmwareDir, "fw.bin")).To(BeARegularFile())
+		})
+
+		It("should remove the firmware files after running modprobe", func() {
+			cfg := &Config{
+				Name:      moduleName,
+				Namespace: namespace,
+				Modprobe:  kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName, FirmwarePath: makeFirmware()},
+			}
+
+			gomock.InOrder(
+				mr.EXPECT().Run(ctx, "-v", kernelModuleName),
+				mr.EXPECT().Run(ctx, "-rv", kernelModuleName),
+			)
+
+			Expect(w.LoadKmod(ctx, cfg)).To(Succeed())
+			loadModule("some_kmod", "O")
+			Expect(w.UnloadKmod(ctx, cfg)).To(Succeed())
+			Expect(filepath.Join(firmwareDir, "fw.bin")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(firmwareDir, "kmm", namespace)).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("IsLoaded", func() {
+		It("should return false if the module is not in sysfs", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			Expect(w.IsLoaded(cfg)).To(BeFalse())
+		})
+
+		It("should return true if the out-of-tree module is in sysfs", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			loadModule("some_kmod", "O")
+
+			Expect(w.IsLoaded(cfg)).To(BeTrue())
+		})
+
+		It("should return false if an in-tree module with the same name is in sysfs", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			loadModule("some_kmod", "")
+
+			Expect(w.IsLoaded(cfg)).To(BeFalse())
+		})
+
+		It("should return false if loading the module was skipped", func() {
+			cfg := &Config{Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}}
+
+			loadModule("some_kmod", "O")
+			Expect(os.WriteFile(stateFile, []byte(`{"skipped":true}`), 0600)).To(Succeed())
+
+			Expect(w.IsLoaded(cfg)).To(BeFalse())
+		})
+
+		It("should return an error if the module name is empty", func() {
+			cfg := &Config{
+				Modprobe: kmmv1beta1.ModprobeSpec{
+					RawArgs: &kmmv1beta1.ModprobeArgs{Load: []string{"-v", kernelModuleName}},
+				},
+			}
+
+			loaded, err := w.IsLoaded(cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(loaded).To(BeFalse())
+		})
+	})
+})